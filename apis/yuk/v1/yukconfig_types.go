@@ -22,8 +22,14 @@ import (
 
 // YukConfigSpec defines the desired state of YukConfig
 type YukConfigSpec struct {
-	// Repository defines the configuration for the repository to monitor
-	Repository RepositoryConfig `json:"repository"`
+	// Repository defines the configuration for the repository to monitor. It may be omitted
+	// when Sources are set.
+	Repository RepositoryConfig `json:"repository,omitempty"`
+
+	// Sources are additional named repositories to monitor. Update targets select one by
+	// name; targets without a source use Repository, or the first source when Repository
+	// is not set.
+	Sources []ImageSource `json:"sources,omitempty"`
 
 	// Git defines the configuration for Git operations
 	Git GitConfig `json:"git"`
@@ -34,30 +40,267 @@ type YukConfigSpec struct {
 	// CheckInterval defines how often to check for updates (default: 5m)
 	CheckInterval *metav1.Duration `json:"checkInterval,omitempty"`
 
+	// PostUpdateRequeue is the delay of the check following a pushed update, verifying that
+	// the change propagated; later checks follow CheckInterval again. When unset, the
+	// check following an update waits for CheckInterval.
+	PostUpdateRequeue *metav1.Duration `json:"postUpdateRequeue,omitempty"`
+
 	// Disabled can be used to temporarily disable this configuration
 	Disabled bool `json:"disabled,omitempty"`
+
+	// UpdateStrategy selects what happens when a new tag is found: "autoPush" (default),
+	// "pullRequest", "approval", "audit" or "dryRun"
+	UpdateStrategy string `json:"updateStrategy,omitempty"`
+
+	// DryRun previews updates without committing or pushing them: the diff of each target
+	// file is recorded in status.pendingChanges. It takes precedence over UpdateStrategy.
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// PinnedThreshold enables pinned detection: if the latest tag has not changed for this
+	// long, the PossiblyPinned condition is set (e.g. the tag filter stopped matching new releases)
+	PinnedThreshold *metav1.Duration `json:"pinnedThreshold,omitempty"`
+
+	// Notifications configures notifications about updates and failures
+	Notifications *NotificationsConfig `json:"notifications,omitempty"`
+
+	// VerifySignature only promotes images with a cosign signature made with the given key.
+	// Images are verified after the latest tags are resolved and before any file is
+	// updated; an unsigned image fails the update. Supported for "ecr" and "oci"
+	// repositories.
+	VerifySignature *SignatureVerificationConfig `json:"verifySignature,omitempty"`
+
+	// HistoryLimit is the number of updates kept in status.history, oldest first dropped
+	// (default: 10, at most 50). 0 disables the history.
+	HistoryLimit *int32 `json:"historyLimit,omitempty"`
+
+	// DeployedWorkload references the live workload running the image. Its tag is recorded
+	// in status.deployedTag on every check, and lag metrics measure how far the deployed
+	// tag, rather than the tag in Git, is behind the latest tag.
+	DeployedWorkload *WorkloadReference `json:"deployedWorkload,omitempty"`
+
+	// UpdateWindow restricts when updates are pushed or proposed for review. A new tag found
+	// outside of the window is reported in status.latestTag, and the update is deferred
+	// until the window opens. Forced updates, rollbacks and dry runs ignore the window.
+	UpdateWindow *UpdateWindow `json:"updateWindow,omitempty"`
+
+	// MaxConsecutiveFailures is the number of reconciles failing in a row after which checks
+	// stop and the Degraded condition is set (default: unlimited). Checks resume when the spec
+	// changes or a reconcile is requested.
+	MaxConsecutiveFailures *int32 `json:"maxConsecutiveFailures,omitempty"`
+}
+
+// UpdateWindow is a recurring time range during which updates are allowed
+type UpdateWindow struct {
+	// Days are the days of the week the window opens on ("Mon" to "Sun"; default: every day)
+	Days []string `json:"days,omitempty"`
+
+	// Start is the time of day the window opens, "HH:MM" (default: "00:00")
+	Start string `json:"start,omitempty"`
+
+	// End is the time of day the window closes, "HH:MM" (default: "24:00"). A window ending
+	// at or before its start closes on the following day.
+	End string `json:"end,omitempty"`
+
+	// TimeZone is the IANA time zone of Start and End, e.g. "Europe/Berlin" (default: "UTC")
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
+// Kinds of workloads a WorkloadReference may select
+const (
+	WorkloadKindDeployment  = "Deployment"
+	WorkloadKindStatefulSet = "StatefulSet"
+)
+
+// WorkloadReference selects a container of a workload in the namespace of the YukConfig
+type WorkloadReference struct {
+	// Kind of the workload: "Deployment" or "StatefulSet"
+	Kind string `json:"kind"`
+
+	// Name of the workload
+	Name string `json:"name"`
+
+	// Container is the name of the container running the image (default: the first container)
+	Container string `json:"container,omitempty"`
+}
+
+// Update strategies of a YukConfig
+const (
+	// UpdateStrategyAutoPush commits updates and pushes them to the configured branch
+	UpdateStrategyAutoPush = "autoPush"
+
+	// UpdateStrategyPullRequest pushes updates to a review branch instead of the configured branch
+	UpdateStrategyPullRequest = "pullRequest"
+
+	// UpdateStrategyApproval pushes an update once ApprovedTagAnnotation is set to its tag
+	UpdateStrategyApproval = "approval"
+
+	// UpdateStrategyAudit only reports available updates
+	UpdateStrategyAudit = "audit"
+
+	// UpdateStrategyDryRun applies updates to a clone without committing them
+	UpdateStrategyDryRun = "dryRun"
+)
+
+// ApprovedTagAnnotation approves an update to the given tag under the approval strategy
+const ApprovedTagAnnotation = "yuk.rebelops.io/approved-tag"
+
+// PausedAnnotation pauses reconciliation when set to "true", like Disabled but without
+// changing the spec
+const PausedAnnotation = "yuk.rebelops.io/paused"
+
+// ReconcileRequestAnnotation requests an immediate check, bypassing the check interval,
+// whenever its value (e.g. a timestamp) changes. The handled value is recorded in
+// Status.LastHandledReconcileAt.
+const ReconcileRequestAnnotation = "yuk.rebelops.io/reconcile"
+
+// CheckIntervalAnnotation overrides Spec.CheckInterval with a duration (e.g. "30m"), e.g. to
+// slow down checks during an incident without changing the spec. Invalid durations are
+// ignored.
+const CheckIntervalAnnotation = "yuk.rebelops.io/check-interval"
+
+// RollbackAnnotation reverts the last pushed update to Status.PreviousTag when set to
+// "true". It is removed once the rollback is pushed.
+const RollbackAnnotation = "yuk.rebelops.io/rollback"
+
+// MaxHistoryLimit is the largest supported Spec.HistoryLimit, so the status history stays
+// well within the size limit of Kubernetes objects
+const MaxHistoryLimit = 50
+
+// ForceUpdateAnnotation writes the latest tags to the update targets when set to "true",
+// even when the current tag is the latest, e.g. to correct manual edits of the target
+// files. It is removed once the forced update is done.
+const ForceUpdateAnnotation = "yuk.rebelops.io/force-update"
+
+// CleanupFinalizer is added to every YukConfig so that the review branches it pushed are
+// deleted, closing their pull requests, before the YukConfig is removed
+const CleanupFinalizer = "yuk.rebelops.io/cleanup"
+
+// Repository types of a RepositoryConfig
+const (
+	// RepositoryTypeECR monitors an AWS ECR repository
+	RepositoryTypeECR = "ecr"
+
+	// RepositoryTypeOCI monitors a repository of a registry implementing the OCI
+	// distribution spec
+	RepositoryTypeOCI = "oci"
+
+	// RepositoryTypeGHCR monitors an image in the GitHub Container Registry
+	RepositoryTypeGHCR = "ghcr"
+
+	// RepositoryTypeGAR monitors a Docker image in Google Artifact Registry
+	RepositoryTypeGAR = "gar"
+
+	// RepositoryTypeACR monitors an image in Azure Container Registry
+	RepositoryTypeACR = "acr"
+
+	// RepositoryTypeQuay monitors a repository on Quay (quay.io or a self-hosted registry)
+	RepositoryTypeQuay = "quay"
+)
+
 // RepositoryConfig defines the repository to monitor
 type RepositoryConfig struct {
-	// Type defines the type of repository (currently only "ecr")
+	// Type defines the type of repository: "ecr", "oci", "ghcr", "gar", "acr" or "quay"
 	Type string `json:"type"`
 
 	// ECR configuration (when type is "ecr")
 	ECR *ECRConfig `json:"ecr,omitempty"`
+
+	// OCI configuration (when type is "oci")
+	OCI *OCIConfig `json:"oci,omitempty"`
+
+	// GHCR configuration (when type is "ghcr")
+	GHCR *GHCRConfig `json:"ghcr,omitempty"`
+
+	// GAR configuration (when type is "gar")
+	GAR *GARConfig `json:"gar,omitempty"`
+
+	// ACR configuration (when type is "acr")
+	ACR *ACRConfig `json:"acr,omitempty"`
+
+	// Quay configuration (when type is "quay")
+	Quay *QuayConfig `json:"quay,omitempty"`
+}
+
+// ImageSource is a named repository to monitor
+type ImageSource struct {
+	// Name identifies the source in update targets and status
+	Name string `json:"name"`
+
+	RepositoryConfig `json:",inline"`
 }
 
 // ECRConfig defines AWS ECR specific configuration
 type ECRConfig struct {
-	// Region is the AWS region where the ECR repository is located
-	Region string `json:"region"`
+	// Region is the AWS region where the ECR repository is located. When empty, it is
+	// derived from the image reference at the first update target (see RepositoryName).
+	Region string `json:"region,omitempty"`
 
-	// RepositoryName is the name of the ECR repository
-	RepositoryName string `json:"repositoryName"`
+	// RepositoryName is the name of the ECR repository. When empty, it is derived from the
+	// image reference (e.g. "<account>.dkr.ecr.<region>.amazonaws.com/<repository>:<tag>")
+	// at the first update target, which must be an imageTagOnly target of Repository. A
+	// region or repository name that is set must match the derived one.
+	RepositoryName string `json:"repositoryName,omitempty"`
+
+	// RegistryID is the AWS account ID of the registry, for a repository in another account
+	// than the credentials (default: the registry of the credentials' account). When the
+	// repository is derived from the image reference, it is derived from its account too.
+	RegistryID string `json:"registryID,omitempty"`
 
 	// TagFilter allows filtering tags (regex pattern)
 	TagFilter string `json:"tagFilter,omitempty"`
 
+	// SelectExpression is a CEL expression evaluated per tag to rank tags; the tag with the
+	// greatest result is selected. It has access to tag, groups (the tag filter's capture
+	// groups) and pushedAt. When empty, tags are ordered by SortStrategy.
+	SelectExpression string `json:"selectExpression,omitempty"`
+
+	// SortStrategy selects how tags are ordered when no SelectExpression is set: "lexical"
+	// (default), "semver" or "pushtime". With "semver", tags that are not valid semantic
+	// versions rank below all valid ones; "pushtime" selects the most recently pushed image.
+	SortStrategy string `json:"sortStrategy,omitempty"`
+
+	// VersionConstraint is a semantic version range (e.g. ">=1.2.0 <2.0.0") applied after
+	// TagFilter. Tags that are not semantic versions are excluded, and the highest version
+	// within the range is selected unless SortStrategy is "pushtime".
+	VersionConstraint string `json:"versionConstraint,omitempty"`
+
+	// TagPriority lists tags in order of preference, e.g. channel tags such as "stable",
+	// "beta" and "edge". When set, the first listed tag that exists in the repository is
+	// selected instead of sorting tags, and tags that are not listed are never selected.
+	// It cannot be combined with SelectExpression or VersionConstraint.
+	TagPriority []string `json:"tagPriority,omitempty"`
+
+	// ExcludeTags lists tags that are never selected, e.g. floating tags such as "latest" or
+	// "stable". Entries are regex patterns that must match the whole tag, so plain tag names
+	// exclude exactly that tag. They are applied after TagFilter.
+	ExcludeTags []string `json:"excludeTags,omitempty"`
+
+	// ExcludePrereleases excludes tags that are semantic versions with a pre-release part
+	// (e.g. "1.2.0-rc.1")
+	ExcludePrereleases bool `json:"excludePrereleases,omitempty"`
+
+	// ManifestListsOnly only selects tags of multi-platform images (Docker manifest lists
+	// and OCI image indexes), ignoring the tags of single-platform images
+	ManifestListsOnly bool `json:"manifestListsOnly,omitempty"`
+
+	// ExcludeArtifacts never selects the tags of attestations, signatures, SBOMs and other
+	// artifacts that are not container images, e.g. cosign's "sha256-<digest>.att" tags
+	ExcludeArtifacts bool `json:"excludeArtifacts,omitempty"`
+
+	// MinPushAge excludes images pushed more recently than this, e.g. to let releases soak
+	MinPushAge *metav1.Duration `json:"minPushAge,omitempty"`
+
+	// MaxPushAge excludes images pushed longer ago than this, so old tags that sort above
+	// current releases are never selected
+	MaxPushAge *metav1.Duration `json:"maxPushAge,omitempty"`
+
+	// RoleARN is an IAM role assumed to access the repository, e.g. in a shared ECR
+	// account. The role is assumed with the credentials configured in Auth.
+	RoleARN string `json:"roleARN,omitempty"`
+
+	// ExternalID is passed when assuming RoleARN, if the role's trust policy requires one
+	ExternalID string `json:"externalID,omitempty"`
+
 	// Authentication configuration
 	Auth ECRAuthConfig `json:"auth,omitempty"`
 }
@@ -74,6 +317,187 @@ type ECRAuthConfig struct {
 	SecretAccessKeyRef *SecretKeySelector `json:"secretAccessKeyRef,omitempty"`
 }
 
+// OCIConfig defines configuration for registries implementing the OCI distribution spec
+// (e.g. Harbor)
+type OCIConfig struct {
+	// Registry is the registry host with an optional port (e.g. "harbor.example.com")
+	Registry string `json:"registry"`
+
+	// RepositoryName is the name of the repository in the registry (e.g. "project/app")
+	RepositoryName string `json:"repositoryName"`
+
+	// TagFilter allows filtering tags (regex pattern)
+	TagFilter string `json:"tagFilter,omitempty"`
+
+	// SortStrategy selects how tags are ordered: "lexical" (default) or "semver"
+	SortStrategy string `json:"sortStrategy,omitempty"`
+
+	// Insecure talks to the registry over plain HTTP
+	Insecure bool `json:"insecure,omitempty"`
+
+	// TLS configures how the registry certificate is verified (default: the system trust
+	// store)
+	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// Authentication configuration
+	Auth OCIAuthConfig `json:"auth,omitempty"`
+}
+
+// TLSConfig configures how the certificate of a registry is verified
+type TLSConfig struct {
+	// CARef references PEM-encoded CA certificates trusted in addition to the system trust
+	// store, e.g. a corporate CA signing the registry certificate
+	CARef *SecretKeySelector `json:"caRef,omitempty"`
+
+	// InsecureSkipVerify disables verification of the registry certificate. Only use it as
+	// an escape hatch; prefer CARef.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// OCIAuthConfig defines authentication for OCI registries
+type OCIAuthConfig struct {
+	// Username for basic authentication
+	Username string `json:"username,omitempty"`
+
+	// PasswordRef references the password (or robot account secret) for basic authentication
+	PasswordRef *SecretKeySelector `json:"passwordRef,omitempty"`
+}
+
+// GHCRConfig defines configuration for an image in the GitHub Container Registry
+type GHCRConfig struct {
+	// Owner is the user or organization owning the image (e.g. "rebelopsio")
+	Owner string `json:"owner"`
+
+	// Image is the name of the image (e.g. "yuk" for ghcr.io/rebelopsio/yuk)
+	Image string `json:"image"`
+
+	// TagFilter allows filtering tags (regex pattern)
+	TagFilter string `json:"tagFilter,omitempty"`
+
+	// SortStrategy selects how tags are ordered: "lexical" (default) or "semver"
+	SortStrategy string `json:"sortStrategy,omitempty"`
+
+	// TokenRef references a GitHub token with the read:packages scope. Public images
+	// are read anonymously when it is not set.
+	TokenRef *SecretKeySelector `json:"tokenRef,omitempty"`
+
+	// TLS configures how the registry certificate is verified, e.g. behind a
+	// TLS-intercepting proxy (default: the system trust store)
+	TLS *TLSConfig `json:"tls,omitempty"`
+}
+
+// GARConfig defines configuration for a Docker image in Google Artifact Registry. The
+// controller authenticates with Application Default Credentials, e.g. GKE Workload
+// Identity.
+type GARConfig struct {
+	// Project is the Google Cloud project of the repository
+	Project string `json:"project"`
+
+	// Location is the region or multi-region of the repository (e.g. "us-central1", or
+	// "us" for gcr.io repositories hosted by Artifact Registry)
+	Location string `json:"location"`
+
+	// Repository is the name of the Artifact Registry repository
+	Repository string `json:"repository"`
+
+	// Image is the name of the image in the repository (e.g. "my-app" for
+	// us-central1-docker.pkg.dev/my-project/my-repo/my-app)
+	Image string `json:"image"`
+
+	// TagFilter allows filtering tags (regex pattern)
+	TagFilter string `json:"tagFilter,omitempty"`
+
+	// SortStrategy selects how tags are ordered: "lexical" (default), "semver" or
+	// "pushtime"
+	SortStrategy string `json:"sortStrategy,omitempty"`
+}
+
+// ACRConfig defines configuration for an image in Azure Container Registry
+type ACRConfig struct {
+	// Registry is the name of the registry (e.g. "myregistry" for myregistry.azurecr.io)
+	// or its login server
+	Registry string `json:"registry"`
+
+	// Image is the name of the image in the registry (e.g. "team/my-app")
+	Image string `json:"image"`
+
+	// TagFilter allows filtering tags (regex pattern)
+	TagFilter string `json:"tagFilter,omitempty"`
+
+	// SortStrategy selects how tags are ordered: "lexical" (default), "semver" or
+	// "pushtime"
+	SortStrategy string `json:"sortStrategy,omitempty"`
+
+	// Authentication configuration
+	Auth ACRAuthConfig `json:"auth,omitempty"`
+}
+
+// ACRAuthConfig defines authentication for Azure Container Registry. Without a client
+// secret, the controller authenticates with the managed identity of its node.
+type ACRAuthConfig struct {
+	// ClientID is the client ID of a service principal with ClientSecretRef, otherwise of
+	// the user-assigned managed identity to use (default: the system-assigned identity)
+	ClientID string `json:"clientID,omitempty"`
+
+	// ClientSecretRef references the client secret of the service principal ClientID
+	ClientSecretRef *SecretKeySelector `json:"clientSecretRef,omitempty"`
+}
+
+// QuayConfig defines configuration for a repository on Quay
+type QuayConfig struct {
+	// Registry is the host of a self-hosted Quay registry (default: "quay.io")
+	Registry string `json:"registry,omitempty"`
+
+	// Namespace is the organization or user owning the repository (e.g. "rebelops")
+	Namespace string `json:"namespace"`
+
+	// Repository is the name of the repository (e.g. "my-app" for quay.io/rebelops/my-app)
+	Repository string `json:"repository"`
+
+	// TagFilter allows filtering tags (regex pattern)
+	TagFilter string `json:"tagFilter,omitempty"`
+
+	// SortStrategy selects how tags are ordered: "lexical" (default), "semver" or
+	// "pushtime"
+	SortStrategy string `json:"sortStrategy,omitempty"`
+
+	// Authentication configuration
+	Auth QuayAuthConfig `json:"auth,omitempty"`
+}
+
+// QuayAuthConfig defines authentication for Quay. Public repositories are read anonymously
+// without credentials.
+type QuayAuthConfig struct {
+	// Username is the name of the robot account (e.g. "rebelops+yuk")
+	Username string `json:"username,omitempty"`
+
+	// PasswordRef references the token of the robot account
+	PasswordRef *SecretKeySelector `json:"passwordRef,omitempty"`
+}
+
+// NotificationsConfig defines where and when notifications are sent
+type NotificationsConfig struct {
+	// Slack posts notifications to a Slack incoming webhook
+	Slack *SlackConfig `json:"slack,omitempty"`
+
+	// Events selects what is notified: "update" when an update is pushed and "error" when
+	// a reconcile fails (default: both)
+	Events []string `json:"events,omitempty"`
+}
+
+// SignatureVerificationConfig defines how image signatures are verified
+type SignatureVerificationConfig struct {
+	// PublicKeyRef references the PEM-encoded cosign public key (e.g. cosign.pub) the
+	// images must be signed with
+	PublicKeyRef SecretKeySelector `json:"publicKeyRef"`
+}
+
+// SlackConfig defines a Slack incoming webhook
+type SlackConfig struct {
+	// WebhookURLRef references the webhook URL
+	WebhookURLRef SecretKeySelector `json:"webhookURLRef"`
+}
+
 // GitConfig defines Git repository configuration
 type GitConfig struct {
 	// Repository URL (e.g., https://github.com/owner/repo.git)
@@ -85,14 +509,69 @@ type GitConfig struct {
 	// Authentication configuration
 	Auth GitAuthConfig `json:"auth"`
 
-	// CommitMessage template for updates
+	// CommitMessage is a Go template for the commit message of updates. It has access to
+	// .OldTag, .NewTag (or .Tag), .Repository, .File, .TargetDirs, .Name and .Namespace
+	// (default: "Update container image to {{ .NewTag }}").
 	CommitMessage string `json:"commitMessage,omitempty"`
 
-	// Email for git commits
-	Email string `json:"email"`
+	// Email for git commits. When empty, it is derived from the authenticated identity
+	// (e.g. the GitHub App bot) or the controller default.
+	Email string `json:"email,omitempty"`
 
-	// Name for git commits
-	Name string `json:"name"`
+	// Name for git commits. When empty, it is derived from the authenticated identity
+	// (e.g. the GitHub App bot) or the controller default.
+	Name string `json:"name,omitempty"`
+
+	// PullRequest opens a GitHub pull request for updates instead of pushing to Branch
+	PullRequest *PullRequestConfig `json:"pullRequest,omitempty"`
+
+	// MaxPushRetries is how many times a push rejected because Branch moved is rebased
+	// onto the remote branch and retried, with exponential backoff (default: 3; 0 disables)
+	MaxPushRetries *int32 `json:"maxPushRetries,omitempty"`
+
+	// CloneDepth is the number of commits of Branch cloned (default: 1; 0 clones the full
+	// history)
+	CloneDepth *int32 `json:"cloneDepth,omitempty"`
+
+	// AmendCommits amends the HEAD commit of Branch instead of adding a commit when it was
+	// authored and committed by Yuk's commit identity for this YukConfig (its Yuk-Config
+	// trailer) and changed the same files, so frequent updates leave a single commit. This
+	// rewrites the published history of Branch: the amended commit is force pushed with a
+	// lease, so commits pushed by others in the meantime are never overwritten, but anyone
+	// who pulled the replaced commit has to rebase. Branch protection must allow force pushes.
+	AmendCommits bool `json:"amendCommits,omitempty"`
+
+	// SparseCheckout checks out only the directories containing the update targets
+	// instead of the whole tree, for large repositories
+	SparseCheckout bool `json:"sparseCheckout,omitempty"`
+
+	// SigningKeyRef references an armored GPG private key used to sign commits
+	SigningKeyRef *SecretKeySelector `json:"signingKeyRef,omitempty"`
+
+	// SigningKeyPassphraseRef references the passphrase of a protected signing key
+	SigningKeyPassphraseRef *SecretKeySelector `json:"signingKeyPassphraseRef,omitempty"`
+}
+
+// PullRequestConfig defines how pull requests are opened for updates
+type PullRequestConfig struct {
+	// Enabled opens a pull request for every update. It selects the pullRequest update
+	// strategy when UpdateStrategy is not set.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// BaseBranch is the branch pull requests are opened against (default: Branch)
+	BaseBranch string `json:"baseBranch,omitempty"`
+
+	// TitleTemplate is a Go template for the pull request title. It has access to .Tag,
+	// .PreviousTag, .Name, .Namespace and .Branch.
+	TitleTemplate string `json:"titleTemplate,omitempty"`
+
+	// BodyTemplate is a Go template for the pull request body, with the same fields as
+	// TitleTemplate
+	BodyTemplate string `json:"bodyTemplate,omitempty"`
+
+	// APIURL is the GitHub API URL, e.g. for GitHub Enterprise Server (default:
+	// https://api.github.com)
+	APIURL string `json:"apiURL,omitempty"`
 }
 
 // GitAuthConfig defines authentication for Git operations
@@ -102,18 +581,156 @@ type GitAuthConfig struct {
 
 	// SSHKey reference for SSH authentication
 	SSHKeyRef *SecretKeySelector `json:"sshKeyRef,omitempty"`
+
+	// KnownHostsRef references known_hosts entries the host key of an SSH repository is
+	// verified against (default: the known hosts file of the controller). Unknown host
+	// keys are rejected.
+	KnownHostsRef *SecretKeySelector `json:"knownHostsRef,omitempty"`
+
+	// GitHubApp authenticates as a GitHub App installation with short-lived tokens
+	// instead of a personal access token
+	GitHubApp *GitHubAppAuth `json:"githubApp,omitempty"`
+}
+
+// GitHubAppAuth defines authentication as an installation of a GitHub App
+type GitHubAppAuth struct {
+	// AppID is the ID of the GitHub App
+	AppID int64 `json:"appID"`
+
+	// InstallationID is the ID of the app's installation on the repository's owner
+	InstallationID int64 `json:"installationID"`
+
+	// PrivateKeyRef references the PEM encoded private key of the GitHub App
+	PrivateKeyRef SecretKeySelector `json:"privateKeyRef"`
+
+	// APIURL is the GitHub API URL installation tokens are minted with, e.g. for GitHub
+	// Enterprise Server (default: https://api.github.com)
+	APIURL string `json:"apiURL,omitempty"`
 }
 
+// Update modes of an UpdateTarget
+const (
+	// UpdateModeYAMLPath updates the value at YAMLPath (default). With ImageTagOnly, it
+	// behaves like UpdateModeImageTag.
+	UpdateModeYAMLPath = "yamlPath"
+
+	// UpdateModeImageTag updates only the tag (or digest) of the image reference at YAMLPath
+	UpdateModeImageTag = "imageTag"
+
+	// UpdateModeLiteral writes the resolved value verbatim at YAMLPath, without parsing the
+	// current value as an image reference (e.g. a chart version or a checksum)
+	UpdateModeLiteral = "literal"
+
+	// UpdateModeArgoApplication updates the Helm parameter or Kustomize image selected by
+	// Name in the source(s) of an Argo CD Application
+	UpdateModeArgoApplication = "argoApplication"
+
+	// UpdateModeHelmImage updates the tag key of the Helm values image block selected by
+	// YAMLPath, leaving its repository key as is
+	UpdateModeHelmImage = "helmImage"
+
+	// UpdateModeKustomizeImage sets the newTag of the entry selected by Name in the images
+	// list of a kustomization.yaml, adding the entry if there is none
+	UpdateModeKustomizeImage = "kustomizeImage"
+)
+
 // UpdateTarget defines what to update in the Git repository
 type UpdateTarget struct {
-	// File path in the Git repository
+	// File path in the Git repository. A glob pattern (e.g. "overlays/*/deployment.yaml")
+	// applies the target to every matching file.
 	File string `json:"file"`
 
-	// YAMLPath defines the YAML key to update (e.g., "spec.template.spec.containers[0].image")
-	YAMLPath string `json:"yamlPath"`
+	// Branch is the branch the file is written to (default: the Git branch). The targets of
+	// each branch are cloned, committed and pushed separately, and a failure on one branch
+	// does not prevent updating the others.
+	Branch string `json:"branch,omitempty"`
+
+	// Mode selects how the file is updated: "yamlPath" (default), "imageTag", "literal",
+	// "argoApplication", "helmImage" or "kustomizeImage"
+	Mode string `json:"mode,omitempty"`
+
+	// YAMLPath defines the YAML key to update (e.g., "spec.template.spec.containers[0].image").
+	// In helmImage mode, it selects the image block instead (e.g., "image").
+	// Required in yamlPath, imageTag, literal and helmImage modes.
+	YAMLPath string `json:"yamlPath,omitempty"`
+
+	// PathSyntax is the syntax of YAMLPath and NestedYAMLPath: "dotted" (default) or
+	// "jsonpath", which supports selecting array elements by a field, e.g.
+	// `spec.template.spec.containers[?(@.name=="app")].image`
+	PathSyntax string `json:"pathSyntax,omitempty"`
+
+	// TagKey is the key of the tag in the image block in helmImage mode (default: "tag")
+	TagKey string `json:"tagKey,omitempty"`
+
+	// RepositoryKey is the key of the repository in the image block in helmImage mode. When
+	// set, its value must name the repository of the source (e.g. "registry/team/my-app" for
+	// repository "team/my-app"); a mismatch fails the update with reason ValidationError.
+	RepositoryKey string `json:"repositoryKey,omitempty"`
 
-	// ImageTagOnly indicates whether to update only the tag part of an image reference
+	// Name selects the entry to update in modes that update named entries, e.g. the Helm
+	// parameter or Kustomize image name in argoApplication mode, or the image name in
+	// kustomizeImage mode
+	Name string `json:"name,omitempty"`
+
+	// ImageTagOnly indicates whether to update only the tag part of an image reference. In
+	// yamlPath mode, it is kept for compatibility and maps to mode "imageTag"; in
+	// argoApplication mode, it applies to parameters holding a full image reference.
 	ImageTagOnly bool `json:"imageTagOnly,omitempty"`
+
+	// ByDigest writes the digest of the latest tag (e.g. "sha256:...") instead of the tag,
+	// and updates the target when the tag is pushed again. In imageTag mode (or with
+	// ImageTagOnly), the digest of the image reference is replaced (e.g.
+	// "registry/repo@sha256:..."), keeping the repository and an optional tag.
+	ByDigest bool `json:"byDigest,omitempty"`
+
+	// Format is the format of the file: "yaml", "json", "toml" or "env" (default: detected
+	// from the extension .json, .toml or .env, "yaml" otherwise). JSON files are
+	// re-serialized with sorted keys; in env files, YAMLPath is the variable name.
+	Format string `json:"format,omitempty"`
+
+	// NestedYAMLPath treats the string value at YAMLPath as an embedded YAML document
+	// (e.g. a ConfigMap data key) and applies the update at this path within it
+	NestedYAMLPath string `json:"nestedYAMLPath,omitempty"`
+
+	// Source is the name of the source whose latest tag is written to this target
+	// (default: Repository)
+	Source string `json:"source,omitempty"`
+
+	// TagFilter overrides the repository tag filter for this target (regex pattern)
+	TagFilter string `json:"tagFilter,omitempty"`
+
+	// SortStrategy overrides the sort strategy of the source repository for this target:
+	// "lexical", "semver" or, for ECR, GAR, ACR and Quay, "pushtime". Ignored when the
+	// repository sets a SelectExpression.
+	SortStrategy string `json:"sortStrategy,omitempty"`
+
+	// ExpectedValuePattern is a regex pattern the current value at the path must match
+	// before it is replaced; a mismatch fails the update with reason ValidationError
+	ExpectedValuePattern string `json:"expectedValuePattern,omitempty"`
+
+	// CommitMessage overrides the commit message template of GitConfig for updates of
+	// this target
+	CommitMessage string `json:"commitMessage,omitempty"`
+
+	// Optional skips the target when its file, path or named entry does not exist instead
+	// of failing the update, e.g. for overlays that do not all set the image. Skipped
+	// targets are listed in Status.SkippedTargets.
+	Optional bool `json:"optional,omitempty"`
+
+	// Values are further values derived from the image written to this target, each written
+	// to its own path of the same file, e.g. its digest to an annotation. They are left
+	// unchanged by rollbacks.
+	Values []TargetValue `json:"values,omitempty"`
+}
+
+// TargetValue is a value derived from the image written to an update target
+type TargetValue struct {
+	// YAMLPath is the path the value is written to, in the PathSyntax of the target
+	YAMLPath string `json:"yamlPath"`
+
+	// Value is a Go template of the value. It has access to .Tag, .Digest and .PushedAt
+	// (RFC 3339, empty for repositories other than ECR).
+	Value string `json:"value"`
 }
 
 // SecretKeySelector selects a key of a Secret
@@ -125,6 +742,104 @@ type SecretKeySelector struct {
 	Key string `json:"key"`
 }
 
+// TargetStatus defines the observed state of an update target with its own tag filter
+type TargetStatus struct {
+	// File path in the Git repository
+	File string `json:"file"`
+
+	// YAMLPath of the target
+	YAMLPath string `json:"yamlPath"`
+
+	// CurrentTag is the tag last written to this target
+	CurrentTag string `json:"currentTag,omitempty"`
+
+	// LatestTag is the latest tag matching this target's tag filter
+	LatestTag string `json:"latestTag,omitempty"`
+
+	// CurrentDigest is the digest last written to this target (byDigest targets)
+	CurrentDigest string `json:"currentDigest,omitempty"`
+
+	// LatestDigest is the digest of the latest tag (byDigest targets)
+	LatestDigest string `json:"latestDigest,omitempty"`
+}
+
+// PendingChange is the change a dry run would make to a file
+type PendingChange struct {
+	// File path in the Git repository
+	File string `json:"file"`
+
+	// Branch is the branch of the file, when it is not the Git branch
+	Branch string `json:"branch,omitempty"`
+
+	// Diff is the unified diff of the file
+	Diff string `json:"diff"`
+}
+
+// SkippedTarget is an optional update target skipped by the last update
+type SkippedTarget struct {
+	// File path in the Git repository
+	File string `json:"file"`
+
+	// YAMLPath of the target
+	YAMLPath string `json:"yamlPath,omitempty"`
+
+	// Name of the entry of the target, in modes updating named entries
+	Name string `json:"name,omitempty"`
+
+	// Branch is the branch of the file, when it is not the Git branch
+	Branch string `json:"branch,omitempty"`
+
+	// Message describes what is missing
+	Message string `json:"message"`
+}
+
+// SourceStatus defines the observed state of a named source
+type SourceStatus struct {
+	// Name of the source
+	Name string `json:"name"`
+
+	// CurrentTag is the tag of the source last written to its targets
+	CurrentTag string `json:"currentTag,omitempty"`
+
+	// LatestTag is the latest tag found in the source
+	LatestTag string `json:"latestTag,omitempty"`
+}
+
+// DerivedRepository is an ECR repository derived from the image reference at the first
+// update target
+type DerivedRepository struct {
+	// Region is the AWS region of the repository
+	Region string `json:"region"`
+
+	// RepositoryName is the name of the repository
+	RepositoryName string `json:"repositoryName"`
+
+	// RegistryID is the AWS account ID of the registry
+	RegistryID string `json:"registryID,omitempty"`
+
+	// ObservedGeneration is the generation of the YukConfig the repository was derived for;
+	// the image reference is read again when the generation changes
+	ObservedGeneration int64 `json:"observedGeneration"`
+}
+
+// UpdateRecord is an update pushed by Yuk, kept in the status history
+type UpdateRecord struct {
+	// Time is when the update was pushed
+	Time metav1.Time `json:"time"`
+
+	// OldTag is the tag the update replaced
+	OldTag string `json:"oldTag,omitempty"`
+
+	// NewTag is the tag the update wrote
+	NewTag string `json:"newTag"`
+
+	// CommitSHA is the pushed commit
+	CommitSHA string `json:"commitSHA,omitempty"`
+
+	// FilesChanged lists the files whose content changed
+	FilesChanged []string `json:"filesChanged,omitempty"`
+}
+
 // YukConfigStatus defines the observed state of YukConfig
 type YukConfigStatus struct {
 	// LastChecked is the timestamp of the last repository check
@@ -133,12 +848,87 @@ type YukConfigStatus struct {
 	// LastUpdate is the timestamp of the last successful update
 	LastUpdate *metav1.Time `json:"lastUpdate,omitempty"`
 
+	// LastCommitSHA is the commit last pushed to the configured branch
+	LastCommitSHA string `json:"lastCommitSHA,omitempty"`
+
+	// LastTimeToUpdate is the time from the push of the image of the last pushed update to
+	// its commit. It is unset when the registry does not report push times.
+	LastTimeToUpdate *metav1.Duration `json:"lastTimeToUpdate,omitempty"`
+
+	// LastChangedFileCount is the number of files whose content changed in the last update;
+	// targets already holding the new value are not counted
+	LastChangedFileCount int32 `json:"lastChangedFileCount,omitempty"`
+
 	// CurrentTag is the current tag/version being monitored
 	CurrentTag string `json:"currentTag,omitempty"`
 
+	// PreviousTag is the tag CurrentTag replaced in the last pushed update, restored by a rollback
+	PreviousTag string `json:"previousTag,omitempty"`
+
+	// RolledBackTag is the tag reverted by the last rollback. It is not updated to again;
+	// updates resume with the next newer tag.
+	RolledBackTag string `json:"rolledBackTag,omitempty"`
+
 	// LatestTag is the latest tag found in the repository
 	LatestTag string `json:"latestTag,omitempty"`
 
+	// ProposedTag is the tag last pushed to a review branch (pullRequest strategy) or
+	// previewed (dryRun strategy)
+	ProposedTag string `json:"proposedTag,omitempty"`
+
+	// ProposedValuesHash is a hash of the values of all update targets last proposed or
+	// previewed, so changes of any source, tag filter or digest are proposed again
+	ProposedValuesHash string `json:"proposedValuesHash,omitempty"`
+
+	// PullRequestURL is the URL of the pull request last opened for an update
+	PullRequestURL string `json:"pullRequestURL,omitempty"`
+
+	// ReviewBranches are the review branches pushed for proposed updates, deleted along with
+	// the YukConfig
+	ReviewBranches []string `json:"reviewBranches,omitempty"`
+
+	// PendingChanges are the changes the last dry run would make to the target files
+	PendingChanges []PendingChange `json:"pendingChanges,omitempty"`
+
+	// SkippedTargets are the optional update targets skipped by the last update because
+	// their file, path or named entry does not exist
+	SkippedTargets []SkippedTarget `json:"skippedTargets,omitempty"`
+
+	// LatestTagFirstSeen is the timestamp when the current LatestTag was first observed
+	LatestTagFirstSeen *metav1.Time `json:"latestTagFirstSeen,omitempty"`
+
+	// LastHandledReconcileAt is the value of the reconcile annotation last handled
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
+
+	// Sources tracks the tags of the named sources
+	Sources []SourceStatus `json:"sources,omitempty"`
+
+	// Targets tracks the tags of update targets that override the tag filter
+	Targets []TargetStatus `json:"targets,omitempty"`
+
+	// History lists the last updates pushed to the Git repository, oldest first, up to
+	// Spec.HistoryLimit entries
+	History []UpdateRecord `json:"history,omitempty"`
+
+	// DeployedTag is the tag (or digest) of the image run by Spec.DeployedWorkload at the last
+	// check
+	DeployedTag string `json:"deployedTag,omitempty"`
+
+	// VerificationPending is set by a pushed update when Spec.PostUpdateRequeue is set, and
+	// cleared by the next check, which follows after Spec.PostUpdateRequeue
+	VerificationPending bool `json:"verificationPending,omitempty"`
+
+	// UpdateDeferredUntil is when Spec.UpdateWindow opens next, set while an available update
+	// is deferred to it
+	UpdateDeferredUntil *metav1.Time `json:"updateDeferredUntil,omitempty"`
+
+	// DerivedRepository is the ECR repository of Spec.Repository derived from the image
+	// reference at the first update target, when its region or repository name is not set
+	DerivedRepository *DerivedRepository `json:"derivedRepository,omitempty"`
+
+	// ConsecutiveFailures is the number of reconciles that failed in a row since the last success
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+
 	// Conditions represent the latest available observations of the YukConfig's state
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
@@ -153,6 +943,7 @@ type YukConfigStatus struct {
 //+kubebuilder:printcolumn:name="Current Tag",type="string",JSONPath=".status.currentTag"
 //+kubebuilder:printcolumn:name="Latest Tag",type="string",JSONPath=".status.latestTag"
 //+kubebuilder:printcolumn:name="Last Update",type="date",JSONPath=".status.lastUpdate"
+//+kubebuilder:printcolumn:name="Commit",type="string",JSONPath=".status.lastCommitSHA"
 
 // YukConfig is the Schema for the yukconfigs API
 type YukConfig struct {
@@ -0,0 +1,81 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// DefaultBranch is the branch updated when Git.Branch is not set
+	DefaultBranch = "main"
+
+	// DefaultCheckInterval is the check interval when CheckInterval is not set
+	DefaultCheckInterval = 5 * time.Minute
+
+	// DefaultCommitMessage is the commit message template when Git.CommitMessage is not set
+	DefaultCommitMessage = "Update container image to {{ .NewTag }}"
+)
+
+// SetDefaults fills in the fields of a YukConfig the controller otherwise defaults when
+// reconciling it, so the stored object shows the effective configuration. Fields that are
+// set are left untouched.
+func SetDefaults(yukConfig *YukConfig) {
+	spec := &yukConfig.Spec
+	if spec.Git.Branch == "" {
+		spec.Git.Branch = DefaultBranch
+	}
+	if spec.CheckInterval == nil {
+		spec.CheckInterval = &metav1.Duration{Duration: DefaultCheckInterval}
+	}
+	if spec.Git.CommitMessage == "" {
+		spec.Git.CommitMessage = DefaultCommitMessage
+	}
+}
+
+// +kubebuilder:webhook:path=/mutate-yuk-rebelops-io-v1-yukconfig,mutating=true,failurePolicy=fail,sideEffects=None,groups=yuk.rebelops.io,resources=yukconfigs,verbs=create;update,versions=v1,name=myukconfig.yuk.rebelops.io,admissionReviewVersions=v1
+
+// YukConfigDefaulter is the defaulting admission webhook of YukConfigs
+type YukConfigDefaulter struct{}
+
+var _ admission.CustomDefaulter = &YukConfigDefaulter{}
+
+// Default applies SetDefaults to an admitted YukConfig
+func (d *YukConfigDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	yukConfig, ok := obj.(*YukConfig)
+	if !ok {
+		return fmt.Errorf("expected a YukConfig but got %T", obj)
+	}
+	SetDefaults(yukConfig)
+	return nil
+}
+
+// SetupWebhookWithManager registers the defaulting webhook with the manager's webhook
+// server at /mutate-yuk-rebelops-io-v1-yukconfig
+func SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&YukConfig{}).
+		WithDefaulter(&YukConfigDefaulter{}).
+		Complete()
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestYukConfigDefaulter_Default(t *testing.T) {
+	tests := []struct {
+		name                  string
+		spec                  YukConfigSpec
+		expectedBranch        string
+		expectedCheckInterval time.Duration
+		expectedCommitMessage string
+	}{
+		{
+			name: "sparse",
+			spec: YukConfigSpec{
+				Git: GitConfig{Repository: "https://github.com/example/manifests.git"},
+			},
+			expectedBranch:        DefaultBranch,
+			expectedCheckInterval: DefaultCheckInterval,
+			expectedCommitMessage: DefaultCommitMessage,
+		},
+		{
+			name: "set",
+			spec: YukConfigSpec{
+				Git: GitConfig{
+					Repository:    "https://github.com/example/manifests.git",
+					Branch:        "production",
+					CommitMessage: "Deploy {{ .NewTag }}",
+				},
+				CheckInterval: &metav1.Duration{Duration: time.Hour},
+			},
+			expectedBranch:        "production",
+			expectedCheckInterval: time.Hour,
+			expectedCommitMessage: "Deploy {{ .NewTag }}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yukConfig := &YukConfig{Spec: tt.spec}
+			if err := (&YukConfigDefaulter{}).Default(context.Background(), yukConfig); err != nil {
+				t.Fatalf("Default() error = %v", err)
+			}
+
+			if yukConfig.Spec.Git.Branch != tt.expectedBranch {
+				t.Errorf("Expected branch %q, got %q", tt.expectedBranch, yukConfig.Spec.Git.Branch)
+			}
+			if yukConfig.Spec.CheckInterval == nil || yukConfig.Spec.CheckInterval.Duration != tt.expectedCheckInterval {
+				t.Errorf("Expected check interval %v, got %v", tt.expectedCheckInterval, yukConfig.Spec.CheckInterval)
+			}
+			if yukConfig.Spec.Git.CommitMessage != tt.expectedCommitMessage {
+				t.Errorf("Expected commit message %q, got %q", tt.expectedCommitMessage, yukConfig.Spec.Git.CommitMessage)
+			}
+			if yukConfig.Spec.Git.Repository != tt.spec.Git.Repository {
+				t.Errorf("Expected repository %q, got %q", tt.spec.Git.Repository, yukConfig.Spec.Git.Repository)
+			}
+		})
+	}
+}
+
+func TestYukConfigDefaulter_Default_WrongType(t *testing.T) {
+	if err := (&YukConfigDefaulter{}).Default(context.Background(), &corev1.Secret{}); err == nil {
+		t.Error("Expected an error for an object that is not a YukConfig")
+	}
+}
@@ -17,8 +17,17 @@ limitations under the License.
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	// Embed the time zone database, as update windows may name a time zone and the
+	// controller image does not ship one
+	_ "time/tzdata"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -30,10 +39,17 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
 	"github.com/rebelopsio/yuk/pkg/controllers"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	"github.com/rebelopsio/yuk/pkg/gar"
+	"github.com/rebelopsio/yuk/pkg/git"
 	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+	"github.com/rebelopsio/yuk/pkg/receiver"
+	"github.com/rebelopsio/yuk/pkg/version"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -42,33 +58,125 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+// maxConcurrentReconcilesEnv sets the default of --max-concurrent-reconciles
+const maxConcurrentReconcilesEnv = "YUK_MAX_CONCURRENT_RECONCILES"
+
+// cloneDirEnv sets the default of --clone-dir
+const cloneDirEnv = "YUK_CLONE_DIR"
+
+// webhookSecretEnv holds the shared secret required by the push notification receiver
+const webhookSecretEnv = "YUK_WEBHOOK_SECRET"
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(yukv1.AddToScheme(scheme))
-
-	// Register custom metrics
-	yukmetrics.RegisterMetrics()
 	//+kubebuilder:scaffold:scheme
 }
 
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var leaderElectionID string
+	var leaderElectionNamespace string
 	var probeAddr string
 	var logLevel string
+	var logFormat string
+	var cloneDir string
+	var reuseClones bool
+	var orphanedCloneMaxAge time.Duration
+	var sshKnownHosts string
+	var webhookAddr string
+	var enableDefaultingWebhook bool
+	var defaultingWebhookPort int
+	var defaultingWebhookCertDir string
+	var snsTopicARNs string
+	var reconcileSummary bool
+	var maxConcurrentReconciles int
+	var ecrCacheTTL time.Duration
+	var minCheckInterval time.Duration
+	var requeueJitter float64
+	var reconcileStaleAfter time.Duration
+	var maxFailureBackoff time.Duration
+	var checkRate float64
+	var checkBurst int
+	var repositoryCheckRate float64
+	var repositoryCheckBurst int
+	var reconciliationBuckets string
+	var repositoryCheckBuckets string
+	var gitOperationBuckets string
+	var timeToUpdateBuckets string
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&leaderElectionID, "leader-election-id", "yuk.rebelops.io",
+		"Name of the Lease used for leader election. Replicas sharing it elect a single leader.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "",
+		"Namespace of the leader election Lease. Defaults to the namespace the controller runs in.")
 	flag.StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	flag.StringVar(&logFormat, "log-format", "",
+		"Log encoding (json, console), overriding --zap-encoder. Defaults to json, or console with --log-level debug. JSON lines carry the namespace, name and repository of the reconciled YukConfig as fields.")
+	flag.StringVar(&cloneDir, "clone-dir", os.Getenv(cloneDirEnv),
+		"Directory Git repositories are cloned into. Defaults to $"+cloneDirEnv+" or the system temp directory (honors TMPDIR).")
+	flag.BoolVar(&reuseClones, "reuse-clones", false,
+		"Keep clones in --clone-dir between reconciles and refresh them with git fetch instead of cloning again, e.g. on a persistent volume.")
+	flag.DurationVar(&orphanedCloneMaxAge, "orphaned-clone-max-age", 30*time.Minute,
+		"Clone directories older than this are removed at startup as left over from a previous run.")
+	flag.StringVar(&sshKnownHosts, "ssh-known-hosts", "",
+		"known_hosts file verifying the host keys of SSH Git repositories of YukConfigs without knownHostsRef. Defaults to ~/.ssh/known_hosts.")
+	flag.StringVar(&webhookAddr, "webhook-bind-address", "",
+		"The address the push notification receiver and the /reconcile endpoint bind to. Leave empty to disable the receiver. "+
+			"Requests must present the shared secret in $"+webhookSecretEnv+", which is required when the receiver is enabled.")
+	flag.BoolVar(&enableDefaultingWebhook, "enable-defaulting-webhook", false,
+		"Serve the admission webhook defaulting the branch, check interval and commit message of YukConfigs. "+
+			"Requires a MutatingWebhookConfiguration pointing at the controller and a serving certificate.")
+	flag.IntVar(&defaultingWebhookPort, "defaulting-webhook-port", 9444,
+		"The port the defaulting admission webhook serves TLS on.")
+	flag.StringVar(&defaultingWebhookCertDir, "defaulting-webhook-cert-dir", "",
+		"Directory holding tls.crt and tls.key of the defaulting admission webhook. Defaults to <temp dir>/k8s-webhook-server/serving-certs.")
+	flag.StringVar(&snsTopicARNs, "sns-topic-arns", "",
+		"Comma-separated list of SNS topic ARNs accepted by the receiver. Accepts all topics when empty.")
+	flag.BoolVar(&reconcileSummary, "reconcile-summary", false,
+		"Write a single-line JSON summary of each reconcile to stdout, prefixed with "+controllers.SummaryMarker+".")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", envInt(maxConcurrentReconcilesEnv, 1),
+		"Number of YukConfigs reconciled in parallel. Defaults to $"+maxConcurrentReconcilesEnv+" or 1.")
+	flag.DurationVar(&ecrCacheTTL, "ecr-cache-ttl", 30*time.Second,
+		"How long ECR image lookups are shared between YukConfigs monitoring the same repository. Set to 0 to disable.")
+	flag.DurationVar(&minCheckInterval, "min-check-interval", 10*time.Second,
+		"Shortest check interval allowed; shorter checkInterval values are raised to it.")
+	flag.Float64Var(&requeueJitter, "requeue-jitter", 0.1,
+		"Fraction of the check interval by which scheduled checks are spread out in either direction, between 0 and 1.")
+	flag.DurationVar(&reconcileStaleAfter, "reconcile-stale-after", 0,
+		"Fail the health and readiness probes when a scheduled check is overdue and no check succeeded for this long. Set to 0 to disable.")
+	flag.DurationVar(&maxFailureBackoff, "max-failure-backoff", time.Hour,
+		"Longest delay between checks of a YukConfig failing permanently; the delay doubles from its check interval with every consecutive failure. Set to 0 to disable.")
+	flag.Float64Var(&checkRate, "check-rate", 0,
+		"Repository checks per second allowed across all YukConfigs; further checks are deferred. Disabled when 0 (the default).")
+	flag.IntVar(&checkBurst, "check-burst", 20,
+		"Repository checks allowed in a burst across all YukConfigs.")
+	flag.Float64Var(&repositoryCheckRate, "repository-check-rate", 0,
+		"Checks per second allowed of each repository, shared by all YukConfigs monitoring it. Disabled when 0 (the default).")
+	flag.IntVar(&repositoryCheckBurst, "repository-check-burst", 5,
+		"Checks of each repository allowed in a burst.")
+	flag.StringVar(&reconciliationBuckets, "reconciliation-duration-buckets", "",
+		"Comma-separated bucket upper bounds in seconds of the reconciliation duration histogram. Defaults to 0.1,0.5,1,2.5,5,10,30,60.")
+	flag.StringVar(&repositoryCheckBuckets, "repository-check-duration-buckets", "",
+		"Comma-separated bucket upper bounds in seconds of the repository check duration histogram. Defaults to 0.1,0.5,1,2.5,5,10,30.")
+	flag.StringVar(&gitOperationBuckets, "git-operation-duration-buckets", "",
+		"Comma-separated bucket upper bounds in seconds of the Git operation duration histogram. Defaults to 0.5,1,2.5,5,10,30,60,120.")
+	flag.StringVar(&timeToUpdateBuckets, "time-to-update-buckets", "",
+		"Comma-separated bucket upper bounds in seconds of the time to update histogram. Defaults to 60,300,600,1800,3600,7200,21600,86400.")
 
 	opts := zap.Options{
 		Development: false,
 	}
 
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
 	// Set log level based on flag
 	switch logLevel {
 	case "debug":
@@ -81,31 +189,170 @@ func main() {
 		// Default level with error
 	}
 
-	opts.BindFlags(flag.CommandLine)
-	flag.Parse()
+	// The log format takes precedence over --zap-encoder when set; otherwise the encoder
+	// follows --zap-encoder, or the console encoder in development mode (--log-level debug)
+	zapOpts := []zap.Opts{zap.UseFlagOptions(&opts)}
+	switch logFormat {
+	case "":
+		// Keep the encoder of the zap options
+	case "json":
+		zapOpts = append(zapOpts, zap.JSONEncoder())
+	case "console":
+		zapOpts = append(zapOpts, zap.ConsoleEncoder())
+	default:
+		// Not logged, as the logger is not set up yet
+		fmt.Fprintf(os.Stderr, "invalid log format %q: must be json or console\n", logFormat)
+		os.Exit(1)
+	}
+	ctrl.SetLogger(zap.New(zapOpts...))
+
+	if requeueJitter < 0 || requeueJitter >= 1 {
+		setupLog.Error(errors.New("must be at least 0 and less than 1"), "invalid requeue jitter", "requeueJitter", requeueJitter)
+		os.Exit(1)
+	}
+
+	// Register custom metrics, with the configured histogram buckets
+	var metricsOpts []yukmetrics.Option
+	for _, histogram := range []struct {
+		flag   string
+		value  string
+		option func([]float64) yukmetrics.Option
+	}{
+		{"reconciliation-duration-buckets", reconciliationBuckets, yukmetrics.WithReconciliationBuckets},
+		{"repository-check-duration-buckets", repositoryCheckBuckets, yukmetrics.WithRepositoryCheckBuckets},
+		{"git-operation-duration-buckets", gitOperationBuckets, yukmetrics.WithGitOperationBuckets},
+		{"time-to-update-buckets", timeToUpdateBuckets, yukmetrics.WithTimeToUpdateBuckets},
+	} {
+		if histogram.value == "" {
+			continue
+		}
+		buckets, err := yukmetrics.ParseBuckets(histogram.value)
+		if err != nil {
+			setupLog.Error(err, "invalid histogram buckets", "flag", histogram.flag)
+			os.Exit(1)
+		}
+		metricsOpts = append(metricsOpts, histogram.option(buckets))
+	}
+	yukmetrics.RegisterMetrics(metricsOpts...)
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	// Make sure clones have somewhere to go and reclaim leftovers from previous runs
+	if err := git.ValidateBaseDir(cloneDir); err != nil {
+		setupLog.Error(err, "invalid clone directory", "cloneDir", cloneDir)
+		os.Exit(1)
+	}
+	if sshKnownHosts != "" {
+		if _, err := os.Stat(sshKnownHosts); err != nil {
+			setupLog.Error(err, "invalid SSH known hosts file", "sshKnownHosts", sshKnownHosts)
+			os.Exit(1)
+		}
+	}
+	if removed, err := git.CleanupOrphanedClones(cloneDir, orphanedCloneMaxAge); err != nil {
+		setupLog.Error(err, "unable to clean up orphaned clone directories", "cloneDir", cloneDir)
+	} else {
+		setupLog.Info("reclaimed orphaned clone directories", "count", removed, "cloneDir", cloneDir, "maxAge", orphanedCloneMaxAge)
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "yuk.rebelops.io",
+		Scheme:                  scheme,
+		Metrics:                 metricsserver.Options{BindAddress: metricsAddr},
+		HealthProbeBindAddress:  probeAddr,
+		LeaderElection:          enableLeaderElection,
+		LeaderElectionID:        leaderElectionID,
+		LeaderElectionNamespace: leaderElectionNamespace,
+		// The process exits when the manager stops, so a new leader can take over
+		// without waiting for the lease to expire
+		LeaderElectionReleaseOnCancel: true,
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Port:    defaultingWebhookPort,
+			CertDir: defaultingWebhookCertDir,
+		}),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	// Push notifications trigger immediate reconciles when the receiver is enabled
+	var trigger *controllers.ReconcileTrigger
+	if webhookAddr != "" {
+		if os.Getenv(webhookSecretEnv) == "" {
+			setupLog.Error(receiver.ErrNoSharedSecret, "set $"+webhookSecretEnv+" to enable the receiver")
+			os.Exit(1)
+		}
+		trigger = controllers.NewReconcileTrigger()
+	}
+
+	// ECR lookups are shared between YukConfigs to avoid throttling
+	var ecrCache *ecr.Cache
+	if ecrCacheTTL > 0 {
+		ecrCache = ecr.NewCache(ecrCacheTTL)
+	}
+
+	// The probes report a controller that stopped reconciling successfully
+	var health *controllers.HealthChecker
+	if reconcileStaleAfter > 0 {
+		health = controllers.NewHealthChecker(reconcileStaleAfter)
+	}
+
+	// Reconcile summaries are an opt-in stable contract for log-based pipelines
+	var summary *controllers.SummaryWriter
+	if reconcileSummary {
+		summary = controllers.NewSummaryWriter(os.Stdout)
+	}
+
 	if err = (&controllers.YukConfigReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:       mgr.GetClient(),
+		Scheme:       mgr.GetScheme(),
+		CloneBaseDir: cloneDir,
+		ReuseClones:  reuseClones,
+		Trigger:      trigger,
+		Summary:      summary,
+
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		ECRCache:                ecrCache,
+		SSHKnownHostsFile:       sshKnownHosts,
+		GitHubAppCache:          git.NewAppCache(),
+		GARTokenSource:          gar.NewTokenSource(),
+		MinCheckInterval:        minCheckInterval,
+		RequeueJitter:           requeueJitter,
+		MaxFailureBackoff:       maxFailureBackoff,
+		Health:                  health,
+		CheckRateLimiter: controllers.NewCheckRateLimiter(checkRate, checkBurst,
+			repositoryCheckRate, repositoryCheckBurst),
+		RepositoryLocks: controllers.NewRepositoryLocks(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "YukConfig")
 		os.Exit(1)
 	}
+
+	if trigger != nil {
+		var topicARNs []string
+		if snsTopicARNs != "" {
+			topicARNs = strings.Split(snsTopicARNs, ",")
+		}
+
+		if err := mgr.Add(receiver.NewReceiver(webhookAddr, mgr.GetClient(), trigger,
+			receiver.WithAllowedTopicARNs(topicARNs),
+			receiver.WithSharedSecret(os.Getenv(webhookSecretEnv)))); err != nil {
+			setupLog.Error(err, "unable to set up push notification receiver")
+			os.Exit(1)
+		}
+	}
+	// The stored YukConfigs show the defaults applied by the controller
+	if enableDefaultingWebhook {
+		if err := yukv1.SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "YukConfig")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
+	// Standby replicas report yuk_leader 0 until they are elected
+	if err := mgr.Add(&controllers.LeaderGauge{}); err != nil {
+		setupLog.Error(err, "unable to set up leader gauge")
+		os.Exit(1)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -114,10 +361,34 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if health != nil {
+		// The staleness window starts once this replica is elected
+		if err := mgr.Add(health); err != nil {
+			setupLog.Error(err, "unable to set up reconcile health checker")
+			os.Exit(1)
+		}
+		if err := mgr.AddHealthzCheck("reconcile", health.Check); err != nil {
+			setupLog.Error(err, "unable to set up reconcile health check")
+			os.Exit(1)
+		}
+		if err := mgr.AddReadyzCheck("reconcile", health.Check); err != nil {
+			setupLog.Error(err, "unable to set up reconcile ready check")
+			os.Exit(1)
+		}
+	}
 
-	setupLog.Info("starting manager")
+	setupLog.Info("starting manager", "version", version.Version, "commit", version.Commit, "goVersion", version.GoVersion())
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// envInt returns the integer value of the environment variable, or def when it is unset or invalid
+func envInt(name string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// yukctl is a command line tool for YukConfig manifests and the YukConfigs of a cluster.
+// Installed as kubectl-yuk, it runs as a kubectl plugin, e.g. "kubectl yuk status".
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/validation"
+)
+
+// Exit codes
+const (
+	exitOK      = 0
+	exitInvalid = 1
+	exitError   = 1
+	exitUsage   = 2
+)
+
+const usage = `Usage: yukctl <command> [arguments]
+
+Commands:
+  validate FILE...              Validate the YukConfig manifests in each file without applying them
+  status [-n NAMESPACE | -A]    Summarize the sync state of the YukConfigs in the cluster
+  prune-branches --repository URL [--prefix PREFIX] [--older-than DURATION] [--delete]
+                                List the review branches of a GitHub repository without an open
+                                pull request, and delete them with --delete ($GITHUB_TOKEN)
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command in args and returns the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "validate":
+		return validate(args[1:], stdout, stderr)
+	case "status":
+		return status(args[1:], stdout, stderr)
+	case "prune-branches":
+		return pruneBranches(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+}
+
+// validate validates the YukConfig manifests in each file. Documents of other kinds are
+// skipped; a file without any YukConfig is an error.
+func validate(files []string, stdout, stderr io.Writer) int {
+	if len(files) == 0 {
+		fmt.Fprintf(stderr, "validate requires at least one file\n\n%s", usage)
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, file := range files {
+		yukConfigs, err := loadFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", file, err)
+			code = exitInvalid
+			continue
+		}
+
+		for _, yukConfig := range yukConfigs {
+			errs := validation.ValidateYukConfig(yukConfig)
+			if len(errs) == 0 {
+				fmt.Fprintf(stdout, "%s: YukConfig %s is valid\n", file, yukConfig.Name)
+				continue
+			}
+
+			code = exitInvalid
+			for _, err := range errs {
+				fmt.Fprintf(stderr, "%s: YukConfig %s: %v\n", file, yukConfig.Name, err)
+			}
+		}
+	}
+
+	return code
+}
+
+// loadFile reads the YukConfigs of a YAML or JSON manifest file
+func loadFile(file string) ([]*yukv1.YukConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	yukConfigs, err := loadYukConfigs(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(yukConfigs) == 0 {
+		return nil, errors.New("no YukConfig found")
+	}
+
+	return yukConfigs, nil
+}
+
+// loadYukConfigs decodes the YukConfigs of a multi-document manifest. Unknown fields are
+// rejected, so misspelled keys are reported instead of being silently ignored.
+func loadYukConfigs(data []byte) ([]*yukv1.YukConfig, error) {
+	var yukConfigs []*yukv1.YukConfig
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+
+	for i := 1; ; i++ {
+		var document json.RawMessage
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				return yukConfigs, nil
+			}
+			return nil, fmt.Errorf("failed to parse document %d: %w", i, err)
+		}
+
+		if len(document) == 0 || string(document) == "null" {
+			continue
+		}
+
+		var typeMeta struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+		}
+		if err := json.Unmarshal(document, &typeMeta); err != nil {
+			return nil, fmt.Errorf("failed to parse document %d: %w", i, err)
+		}
+		if typeMeta.Kind != "YukConfig" {
+			continue
+		}
+		if typeMeta.APIVersion != yukv1.GroupVersion.String() {
+			return nil, fmt.Errorf("document %d: unsupported apiVersion %q, expected %q", i, typeMeta.APIVersion, yukv1.GroupVersion.String())
+		}
+
+		yukConfig := &yukv1.YukConfig{}
+		strict := json.NewDecoder(bytes.NewReader(document))
+		strict.DisallowUnknownFields()
+		if err := strict.Decode(yukConfig); err != nil {
+			return nil, fmt.Errorf("failed to decode YukConfig in document %d: %w", i, err)
+		}
+		yukConfigs = append(yukConfigs, yukConfig)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_Validate(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		stdout       []string
+		stderr       []string
+	}{
+		{
+			name:         "valid example",
+			args:         []string{"validate", "../../examples/yukconfig.yaml"},
+			expectedCode: exitOK,
+			stdout:       []string{"YukConfig my-app-config is valid"},
+		},
+		{
+			name:         "invalid manifest",
+			args:         []string{"validate", "testdata/invalid.yaml"},
+			expectedCode: exitInvalid,
+			stderr: []string{
+				"YukConfig broken: spec.repository.ecr: Forbidden",
+				"YukConfig broken: spec.repository.oci: Required value",
+				"YukConfig broken: spec.updateTargets[1].name: Required value",
+			},
+		},
+		{
+			name:         "unknown field",
+			args:         []string{"validate", "testdata/unknown-field.yaml"},
+			expectedCode: exitInvalid,
+			stderr:       []string{`unknown field "yamlPth"`},
+		},
+		{
+			name:         "no YukConfig",
+			args:         []string{"validate", "../../examples/deployment.yaml"},
+			expectedCode: exitInvalid,
+			stderr:       []string{"no YukConfig found"},
+		},
+		{
+			name:         "missing file",
+			args:         []string{"validate", "testdata/missing.yaml"},
+			expectedCode: exitInvalid,
+			stderr:       []string{"failed to read file"},
+		},
+		{
+			name:         "valid and invalid files",
+			args:         []string{"validate", "../../examples/yukconfig.yaml", "testdata/invalid.yaml"},
+			expectedCode: exitInvalid,
+			stdout:       []string{"YukConfig my-app-config is valid"},
+			stderr:       []string{"YukConfig broken"},
+		},
+		{
+			name:         "no files",
+			args:         []string{"validate"},
+			expectedCode: exitUsage,
+			stderr:       []string{"validate requires at least one file"},
+		},
+		{
+			name:         "unknown command",
+			args:         []string{"apply"},
+			expectedCode: exitUsage,
+			stderr:       []string{`unknown command "apply"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			code := run(tt.args, &stdout, &stderr)
+			if code != tt.expectedCode {
+				t.Errorf("Expected exit code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			for _, expected := range tt.stdout {
+				if !strings.Contains(stdout.String(), expected) {
+					t.Errorf("Expected stdout to contain %q, got %q", expected, stdout.String())
+				}
+			}
+			for _, expected := range tt.stderr {
+				if !strings.Contains(stderr.String(), expected) {
+					t.Errorf("Expected stderr to contain %q, got %q", expected, stderr.String())
+				}
+			}
+		})
+	}
+}
+
+func TestLoadYukConfigs(t *testing.T) {
+	tests := []struct {
+		name          string
+		manifest      string
+		expectedNames []string
+		shouldErr     bool
+	}{
+		{
+			name: "multiple documents",
+			manifest: `apiVersion: yuk.rebelops.io/v1
+kind: YukConfig
+metadata:
+  name: first
+---
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: other
+---
+apiVersion: yuk.rebelops.io/v1
+kind: YukConfig
+metadata:
+  name: second
+`,
+			expectedNames: []string{"first", "second"},
+		},
+		{
+			name:          "json",
+			manifest:      `{"apiVersion": "yuk.rebelops.io/v1", "kind": "YukConfig", "metadata": {"name": "first"}}`,
+			expectedNames: []string{"first"},
+		},
+		{
+			name:      "unsupported api version",
+			manifest:  "apiVersion: yuk.rebelops.io/v2\nkind: YukConfig\n",
+			shouldErr: true,
+		},
+		{
+			name:      "malformed yaml",
+			manifest:  "kind: YukConfig\nspec: [\n",
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yukConfigs, err := loadYukConfigs([]byte(tt.manifest))
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load YukConfigs: %v", err)
+			}
+
+			if len(yukConfigs) != len(tt.expectedNames) {
+				t.Fatalf("Expected %d YukConfigs, got %d", len(tt.expectedNames), len(yukConfigs))
+			}
+			for i, name := range tt.expectedNames {
+				if yukConfigs[i].Name != name {
+					t.Errorf("Expected YukConfig %s, got %s", name, yukConfigs[i].Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/git"
+)
+
+// branchPruner finds and deletes stale review branches
+type branchPruner interface {
+	StaleBranches(ctx context.Context, prefix string, minAge time.Duration, now time.Time) ([]git.StaleBranch, error)
+	DeleteBranches(ctx context.Context, branches []string) ([]string, error)
+}
+
+// newBranchPruner returns a Git client of the repository authenticated with the token.
+// Replaced by tests.
+var newBranchPruner = func(repository, token, apiURL string) branchPruner {
+	opts := []git.Option{git.WithToken(token)}
+	if apiURL != "" {
+		opts = append(opts, git.WithGitHubAPIURL(apiURL))
+	}
+	return git.NewClient(yukv1.GitConfig{Repository: repository}, opts...)
+}
+
+// pruneBranches lists the review branches of a GitHub repository without an open pull
+// request and older than a minimum age, and deletes them with --delete
+func pruneBranches(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("prune-branches", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	repository := flags.String("repository", "", "URL of the GitHub repository (required)")
+	prefix := flags.String("prefix", "yuk/", "Prefix of the review branches")
+	olderThan := flags.Duration("older-than", 7*24*time.Hour, "Minimum age of the head commit of the branches deleted")
+	apiURL := flags.String("github-api-url", "", "GitHub API URL, e.g. for GitHub Enterprise Server (default: "+git.DefaultGitHubAPIURL+")")
+	del := flags.Bool("delete", false, "Delete the branches instead of only listing them")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "prune-branches takes no arguments\n\n%s", usage)
+		return exitUsage
+	}
+	if *repository == "" {
+		fmt.Fprintf(stderr, "prune-branches requires --repository\n\n%s", usage)
+		return exitUsage
+	}
+	if *prefix == "" {
+		fmt.Fprintf(stderr, "prune-branches requires a --prefix, so other branches are never deleted\n\n%s", usage)
+		return exitUsage
+	}
+
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		fmt.Fprintln(stderr, "GITHUB_TOKEN must be set to a token allowed to read pull requests and delete branches")
+		return exitError
+	}
+
+	ctx := context.Background()
+	pruner := newBranchPruner(*repository, token, *apiURL)
+	now := time.Now()
+	stale, err := pruner.StaleBranches(ctx, *prefix, *olderThan, now)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitError
+	}
+
+	if len(stale) == 0 {
+		fmt.Fprintf(stderr, "No stale branches with prefix %s found\n", *prefix)
+		return exitOK
+	}
+	printStaleBranches(stdout, stale, now)
+
+	if !*del {
+		fmt.Fprintf(stderr, "\n%s would be deleted; rerun with --delete to delete them\n", branchCount(len(stale)))
+		return exitOK
+	}
+
+	names := make([]string, 0, len(stale))
+	for _, branch := range stale {
+		names = append(names, branch.Name)
+	}
+	deleted, err := pruner.DeleteBranches(ctx, names)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitError
+	}
+	fmt.Fprintf(stderr, "\nDeleted %s: %s\n", branchCount(len(deleted)), strings.Join(deleted, ", "))
+	return exitOK
+}
+
+// printStaleBranches writes a table of the stale branches with their last pull request
+func printStaleBranches(w io.Writer, branches []git.StaleBranch, now time.Time) {
+	table := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(table, "BRANCH\tPULL REQUEST\tLAST COMMIT")
+
+	for _, branch := range branches {
+		pullRequest := "<none>"
+		if pr := branch.PullRequest; pr != nil {
+			state := "closed"
+			if pr.MergedAt != nil {
+				state = "merged"
+			}
+			pullRequest = fmt.Sprintf("#%d (%s)", pr.Number, state)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s ago\n", branch.Name, pullRequest, duration.HumanDuration(now.Sub(branch.LastCommit)))
+	}
+
+	table.Flush()
+}
+
+// branchCount returns the number of branches as a phrase, e.g. "1 branch"
+func branchCount(n int) string {
+	if n == 1 {
+		return "1 branch"
+	}
+	return fmt.Sprintf("%d branches", n)
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rebelopsio/yuk/pkg/git"
+)
+
+// recordingPruner finds stale branches with a Git client and records the deleted ones
+type recordingPruner struct {
+	branchPruner
+	deleted []string
+}
+
+func (p *recordingPruner) DeleteBranches(ctx context.Context, branches []string) ([]string, error) {
+	p.deleted = append(p.deleted, branches...)
+	return branches, nil
+}
+
+// newFakeGitHub serves the branches, pull requests and commits of example/repo: a merged
+// and an open review branch, both 10 days old, and a feature branch
+func newFakeGitHub(t *testing.T) *httptest.Server {
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	merged := old.Add(time.Hour)
+	pulls := map[string][]git.PullRequest{
+		"yuk/my-app-v1.1.0-abc": {{Number: 7, State: "closed", MergedAt: &merged}},
+		"yuk/my-app-v1.2.0-def": {{Number: 8, State: "open"}},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ghp_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.URL.Path == "/repos/example/repo/branches":
+			_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"name": "main", "commit": map[string]string{"sha": "a"}},
+				{"name": "feature/login", "commit": map[string]string{"sha": "b"}},
+				{"name": "yuk/my-app-v1.1.0-abc", "commit": map[string]string{"sha": "c"}},
+				{"name": "yuk/my-app-v1.2.0-def", "commit": map[string]string{"sha": "d"}},
+			})
+		case r.URL.Path == "/repos/example/repo/pulls":
+			prs := pulls[strings.TrimPrefix(r.URL.Query().Get("head"), "example:")]
+			if prs == nil {
+				prs = []git.PullRequest{}
+			}
+			_ = json.NewEncoder(w).Encode(prs)
+		case strings.HasPrefix(r.URL.Path, "/repos/example/repo/commits/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"commit": map[string]interface{}{"committer": map[string]interface{}{"date": old}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRun_PruneBranches(t *testing.T) {
+	server := newFakeGitHub(t)
+
+	tests := []struct {
+		name            string
+		args            []string
+		token           string
+		expectedCode    int
+		expectedDeleted []string
+		stdout          []string
+		stderr          []string
+	}{
+		{
+			name:         "dry run by default",
+			args:         []string{"prune-branches", "--repository", "https://github.com/example/repo.git"},
+			token:        "ghp_test",
+			expectedCode: exitOK,
+			stdout:       []string{"yuk/my-app-v1.1.0-abc", "#7 (merged)", "10d ago"},
+			stderr:       []string{"1 branch would be deleted; rerun with --delete to delete them"},
+		},
+		{
+			name:            "delete",
+			args:            []string{"prune-branches", "--repository", "https://github.com/example/repo.git", "--delete"},
+			token:           "ghp_test",
+			expectedCode:    exitOK,
+			expectedDeleted: []string{"yuk/my-app-v1.1.0-abc"},
+			stderr:          []string{"Deleted 1 branch: yuk/my-app-v1.1.0-abc"},
+		},
+		{
+			name:         "younger than the minimum age",
+			args:         []string{"prune-branches", "--repository", "https://github.com/example/repo.git", "--older-than", "720h", "--delete"},
+			token:        "ghp_test",
+			expectedCode: exitOK,
+			stderr:       []string{"No stale branches with prefix yuk/ found"},
+		},
+		{
+			name:         "missing repository",
+			args:         []string{"prune-branches"},
+			token:        "ghp_test",
+			expectedCode: exitUsage,
+			stderr:       []string{"prune-branches requires --repository"},
+		},
+		{
+			name:         "missing token",
+			args:         []string{"prune-branches", "--repository", "https://github.com/example/repo.git"},
+			expectedCode: exitError,
+			stderr:       []string{"GITHUB_TOKEN must be set"},
+		},
+		{
+			name:         "rejected token",
+			args:         []string{"prune-branches", "--repository", "https://github.com/example/repo.git"},
+			token:        "ghp_wrong",
+			expectedCode: exitError,
+			stderr:       []string{"failed to list branches: GitHub API returned 401"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_TOKEN", tt.token)
+
+			pruner := &recordingPruner{}
+			original := newBranchPruner
+			t.Cleanup(func() { newBranchPruner = original })
+			newBranchPruner = func(repository, token, apiURL string) branchPruner {
+				pruner.branchPruner = original(repository, token, server.URL)
+				return pruner
+			}
+
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, &stdout, &stderr)
+			if code != tt.expectedCode {
+				t.Errorf("Expected exit code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			if strings.Join(pruner.deleted, ",") != strings.Join(tt.expectedDeleted, ",") {
+				t.Errorf("Expected deleted branches %v, got %v", tt.expectedDeleted, pruner.deleted)
+			}
+			for _, expected := range tt.stdout {
+				if !strings.Contains(stdout.String(), expected) {
+					t.Errorf("Expected stdout to contain %q, got %q", expected, stdout.String())
+				}
+			}
+			for _, expected := range tt.stderr {
+				if !strings.Contains(stderr.String(), expected) {
+					t.Errorf("Expected stderr to contain %q, got %q", expected, stderr.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/ghcr"
+	"github.com/rebelopsio/yuk/pkg/quay"
+)
+
+// newClient returns a client of the cluster and the namespace of the kubeconfig context,
+// loaded from $KUBECONFIG or ~/.kube/config, or the in-cluster configuration. Replaced
+// by tests.
+var newClient = func() (client.Client, string, error) {
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{})
+
+	namespace, _, err := loader.Namespace()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	config, err := loader.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := yukv1.AddToScheme(scheme); err != nil {
+		return nil, "", err
+	}
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create client: %w", err)
+	}
+	return c, namespace, nil
+}
+
+// status prints the sync state of the YukConfigs of a namespace, or of all namespaces
+func status(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	namespace := flags.String("n", "", "Namespace of the YukConfigs (default: the namespace of the kubeconfig context)")
+	flags.StringVar(namespace, "namespace", "", "Namespace of the YukConfigs (default: the namespace of the kubeconfig context)")
+	allNamespaces := flags.Bool("A", false, "List the YukConfigs of all namespaces")
+	flags.BoolVar(allNamespaces, "all-namespaces", false, "List the YukConfigs of all namespaces")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "status takes no arguments\n\n%s", usage)
+		return exitUsage
+	}
+
+	c, contextNamespace, err := newClient()
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitError
+	}
+
+	var opts []client.ListOption
+	if !*allNamespaces {
+		if *namespace == "" {
+			*namespace = contextNamespace
+		}
+		opts = append(opts, client.InNamespace(*namespace))
+	}
+
+	var yukConfigs yukv1.YukConfigList
+	if err := c.List(context.Background(), &yukConfigs, opts...); err != nil {
+		fmt.Fprintf(stderr, "failed to list YukConfigs: %v\n", err)
+		return exitError
+	}
+
+	if len(yukConfigs.Items) == 0 {
+		if *allNamespaces {
+			fmt.Fprintln(stderr, "No YukConfigs found")
+		} else {
+			fmt.Fprintf(stderr, "No YukConfigs found in namespace %s\n", *namespace)
+		}
+		return exitOK
+	}
+
+	printStatus(stdout, yukConfigs.Items, *allNamespaces, time.Now())
+	return exitOK
+}
+
+// printStatus writes a table of the sync state of the YukConfigs, sorted by namespace and
+// name. The columns follow the printer columns of the CRD.
+func printStatus(w io.Writer, yukConfigs []yukv1.YukConfig, allNamespaces bool, now time.Time) {
+	sort.Slice(yukConfigs, func(i, j int) bool {
+		if yukConfigs[i].Namespace != yukConfigs[j].Namespace {
+			return yukConfigs[i].Namespace < yukConfigs[j].Namespace
+		}
+		return yukConfigs[i].Name < yukConfigs[j].Name
+	})
+
+	table := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	if allNamespaces {
+		fmt.Fprint(table, "NAMESPACE\t")
+	}
+	fmt.Fprintln(table, "NAME\tREPOSITORY\tCURRENT TAG\tLATEST TAG\tLAST UPDATE\tREADY")
+
+	for _, yukConfig := range yukConfigs {
+		if allNamespaces {
+			fmt.Fprintf(table, "%s\t", yukConfig.Namespace)
+		}
+
+		lastUpdate := "<none>"
+		if yukConfig.Status.LastUpdate != nil {
+			lastUpdate = duration.HumanDuration(now.Sub(yukConfig.Status.LastUpdate.Time)) + " ago"
+		}
+		ready := "Unknown"
+		if condition := meta.FindStatusCondition(yukConfig.Status.Conditions, "Ready"); condition != nil {
+			ready = string(condition.Status)
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", yukConfig.Name,
+			orNone(displayRepository(&yukConfig)), orNone(yukConfig.Status.CurrentTag),
+			orNone(yukConfig.Status.LatestTag), lastUpdate, ready)
+	}
+	table.Flush()
+}
+
+// displayRepository returns the name of the repository of the default source: the spec
+// repository, or the first named source when the repository is not set
+func displayRepository(yukConfig *yukv1.YukConfig) string {
+	repository := &yukConfig.Spec.Repository
+	if repository.Type == "" && len(yukConfig.Spec.Sources) > 0 {
+		repository = &yukConfig.Spec.Sources[0].RepositoryConfig
+	}
+
+	switch {
+	case repository.Type == yukv1.RepositoryTypeOCI && repository.OCI != nil:
+		return repository.OCI.Registry + "/" + repository.OCI.RepositoryName
+	case repository.Type == yukv1.RepositoryTypeGHCR && repository.GHCR != nil:
+		return "ghcr.io/" + ghcr.RepositoryName(repository.GHCR.Owner, repository.GHCR.Image)
+	case repository.Type == yukv1.RepositoryTypeGAR && repository.GAR != nil:
+		return repository.GAR.Repository + "/" + repository.GAR.Image
+	case repository.Type == yukv1.RepositoryTypeACR && repository.ACR != nil:
+		return repository.ACR.Image
+	case repository.Type == yukv1.RepositoryTypeQuay && repository.Quay != nil:
+		return quay.Registry(repository.Quay.Registry) + "/" + quay.RepositoryName(repository.Quay.Namespace, repository.Quay.Repository)
+	case repository.ECR != nil:
+		return repository.ECR.RepositoryName
+	default:
+		return ""
+	}
+}
+
+// orNone returns the value, or <none> when it is empty
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+var statusNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// statusFixtures returns YukConfigs of two namespaces in different states
+func statusFixtures() []yukv1.YukConfig {
+	lastUpdate := metav1.NewTime(statusNow.Add(-90 * time.Minute))
+	return []yukv1.YukConfig{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"},
+			Spec: yukv1.YukConfigSpec{
+				Repository: yukv1.RepositoryConfig{
+					Type: yukv1.RepositoryTypeGHCR,
+					GHCR: &yukv1.GHCRConfig{Owner: "RebelOpsIO", Image: "web"},
+				},
+			},
+			Status: yukv1.YukConfigStatus{
+				CurrentTag: "v1.0.0",
+				LatestTag:  "v1.1.0",
+				Conditions: []metav1.Condition{{Type: "Ready", Status: metav1.ConditionFalse, Reason: "Failed"}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod"},
+			Spec: yukv1.YukConfigSpec{
+				Repository: yukv1.RepositoryConfig{
+					Type: yukv1.RepositoryTypeECR,
+					ECR:  &yukv1.ECRConfig{Region: "us-east-1", RepositoryName: "api"},
+				},
+			},
+			Status: yukv1.YukConfigStatus{
+				CurrentTag: "v2.3.0",
+				LatestTag:  "v2.3.0",
+				LastUpdate: &lastUpdate,
+				Conditions: []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Synchronized"}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+			Spec: yukv1.YukConfigSpec{
+				Sources: []yukv1.ImageSource{{
+					Name: "worker",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: yukv1.RepositoryTypeOCI,
+						OCI:  &yukv1.OCIConfig{Registry: "harbor.example.com", RepositoryName: "team/worker"},
+					},
+				}},
+			},
+		},
+	}
+}
+
+func TestPrintStatus(t *testing.T) {
+	tests := []struct {
+		name          string
+		allNamespaces bool
+		expected      string
+	}{
+		{
+			name: "namespace",
+			expected: `NAME     REPOSITORY                       CURRENT TAG   LATEST TAG   LAST UPDATE   READY
+worker   harbor.example.com/team/worker   <none>        <none>       <none>        Unknown
+api      api                              v2.3.0        v2.3.0       90m ago       True
+web      ghcr.io/rebelopsio/web           v1.0.0        v1.1.0       <none>        False
+`,
+		},
+		{
+			name:          "all namespaces",
+			allNamespaces: true,
+			expected: `NAMESPACE   NAME     REPOSITORY                       CURRENT TAG   LATEST TAG   LAST UPDATE   READY
+default     worker   harbor.example.com/team/worker   <none>        <none>       <none>        Unknown
+prod        api      api                              v2.3.0        v2.3.0       90m ago       True
+prod        web      ghcr.io/rebelopsio/web           v1.0.0        v1.1.0       <none>        False
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			printStatus(&out, statusFixtures(), tt.allNamespaces, statusNow)
+			if out.String() != tt.expected {
+				t.Errorf("Expected table:\n%s\ngot:\n%s", tt.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestRun_Status(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := yukv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add scheme: %v", err)
+	}
+	var objects []client.Object
+	for _, yukConfig := range statusFixtures() {
+		objects = append(objects, &yukConfig)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	tests := []struct {
+		name         string
+		args         []string
+		clientErr    error
+		expectedCode int
+		stdout       []string
+		absent       []string
+		stderr       []string
+	}{
+		{
+			name:         "context namespace",
+			args:         []string{"status"},
+			expectedCode: exitOK,
+			stdout:       []string{"worker"},
+			absent:       []string{"api", "NAMESPACE"},
+		},
+		{
+			name:         "namespace",
+			args:         []string{"status", "-n", "prod"},
+			expectedCode: exitOK,
+			stdout:       []string{"api", "web"},
+			absent:       []string{"worker"},
+		},
+		{
+			name:         "all namespaces",
+			args:         []string{"status", "--all-namespaces"},
+			expectedCode: exitOK,
+			stdout:       []string{"NAMESPACE", "worker", "api", "web"},
+		},
+		{
+			name:         "empty namespace",
+			args:         []string{"status", "--namespace", "staging"},
+			expectedCode: exitOK,
+			stderr:       []string{"No YukConfigs found in namespace staging"},
+		},
+		{
+			name:         "unreachable cluster",
+			args:         []string{"status"},
+			clientErr:    errors.New("failed to load kubeconfig: no configuration"),
+			expectedCode: exitError,
+			stderr:       []string{"failed to load kubeconfig"},
+		},
+		{
+			name:         "unexpected argument",
+			args:         []string{"status", "prod"},
+			expectedCode: exitUsage,
+			stderr:       []string{"status takes no arguments"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := newClient
+			t.Cleanup(func() { newClient = original })
+			newClient = func() (client.Client, string, error) {
+				if tt.clientErr != nil {
+					return nil, "", tt.clientErr
+				}
+				return c, "default", nil
+			}
+
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, &stdout, &stderr)
+			if code != tt.expectedCode {
+				t.Errorf("Expected exit code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			for _, expected := range tt.stdout {
+				if !strings.Contains(stdout.String(), expected) {
+					t.Errorf("Expected stdout to contain %q, got %q", expected, stdout.String())
+				}
+			}
+			for _, unexpected := range tt.absent {
+				if strings.Contains(stdout.String(), unexpected) {
+					t.Errorf("Expected stdout not to contain %q, got %q", unexpected, stdout.String())
+				}
+			}
+			for _, expected := range tt.stderr {
+				if !strings.Contains(stderr.String(), expected) {
+					t.Errorf("Expected stderr to contain %q, got %q", expected, stderr.String())
+				}
+			}
+		})
+	}
+}
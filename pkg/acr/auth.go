@@ -0,0 +1,194 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultIMDSEndpoint is the token endpoint of the Azure Instance Metadata Service
+const DefaultIMDSEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+// Resource is the Azure resource Microsoft Entra ID tokens are requested for. ACR
+// exchanges tokens of the Azure Resource Manager audience for registry refresh tokens.
+const Resource = "https://management.azure.com/"
+
+// tokenExpiryDelta is how long before their expiry Entra ID tokens are renewed
+const tokenExpiryDelta = 5 * time.Minute
+
+// AADTokenSource returns Microsoft Entra ID (formerly Azure AD) access tokens that are
+// exchanged for ACR refresh tokens
+type AADTokenSource interface {
+	AADToken(ctx context.Context) (string, error)
+}
+
+// ManagedIdentityTokenSource requests Microsoft Entra ID tokens of the managed identity of
+// the node from the Azure Instance Metadata Service. Tokens are reused until shortly before
+// they expire.
+type ManagedIdentityTokenSource struct {
+	endpoint   string
+	clientID   string
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewManagedIdentityTokenSource creates a token source for a managed identity. The client
+// ID selects a user-assigned identity; the system-assigned identity is used when empty.
+func NewManagedIdentityTokenSource(clientID string) *ManagedIdentityTokenSource {
+	return &ManagedIdentityTokenSource{
+		endpoint:   DefaultIMDSEndpoint,
+		clientID:   clientID,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AADToken implements AADTokenSource
+func (s *ManagedIdentityTokenSource) AADToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Add(tokenExpiryDelta).Before(s.expiry) {
+		return s.token, nil
+	}
+
+	query := url.Values{}
+	query.Set("api-version", "2018-02-01")
+	query.Set("resource", Resource)
+	if s.clientID != "" {
+		query.Set("client_id", s.clientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata", "true")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request managed identity token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to request managed identity token: %w", newAPIError(resp))
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresOn   string `json:"expires_on"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode managed identity token: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", fmt.Errorf("managed identity token response has no access token")
+	}
+
+	s.token = body.AccessToken
+	s.expiry = time.Time{}
+	if expiresOn, err := strconv.ParseInt(body.ExpiresOn, 10, 64); err == nil {
+		s.expiry = time.Unix(expiresOn, 0)
+	}
+
+	return s.token, nil
+}
+
+// tokenResponse is a response of the ACR token endpoints
+type tokenResponse struct {
+	RefreshToken string `json:"refresh_token"`
+	AccessToken  string `json:"access_token"`
+}
+
+// accessToken returns a registry access token with the given scope, e.g.
+// "repository:team/my-app:metadata_read"
+func (c *Client) accessToken(ctx context.Context, scope string) (string, error) {
+	if c.clientID != "" {
+		return c.servicePrincipalToken(ctx, scope)
+	}
+	return c.exchangeToken(ctx, scope)
+}
+
+// servicePrincipalToken requests an access token with the client ID and secret of a
+// service principal, like docker login does
+func (c *Client) servicePrincipalToken(ctx context.Context, scope string) (string, error) {
+	query := url.Values{}
+	query.Set("service", c.service)
+	query.Set("scope", scope)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/oauth2/token?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(c.clientID, c.clientSecret)
+
+	var token tokenResponse
+	if err := c.do(req, &token); err != nil {
+		return "", fmt.Errorf("failed to request registry token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("registry token response has no access token")
+	}
+	return token.AccessToken, nil
+}
+
+// exchangeToken exchanges a Microsoft Entra ID token for an ACR refresh token, then the
+// refresh token for an access token
+func (c *Client) exchangeToken(ctx context.Context, scope string) (string, error) {
+	aadToken, err := c.tokenSource.AADToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var refresh tokenResponse
+	err = c.postForm(ctx, "/oauth2/exchange", url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {c.service},
+		"access_token": {aadToken},
+	}, &refresh)
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange managed identity token: %w", err)
+	}
+	if refresh.RefreshToken == "" {
+		return "", fmt.Errorf("token exchange response has no refresh token")
+	}
+
+	var access tokenResponse
+	err = c.postForm(ctx, "/oauth2/token", url.Values{
+		"grant_type":    {"refresh_token"},
+		"service":       {c.service},
+		"scope":         {scope},
+		"refresh_token": {refresh.RefreshToken},
+	}, &access)
+	if err != nil {
+		return "", fmt.Errorf("failed to request registry token: %w", err)
+	}
+	if access.AccessToken == "" {
+		return "", fmt.Errorf("registry token response has no access token")
+	}
+	return access.AccessToken, nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestManagedIdentityTokenSource(t *testing.T) {
+	requests := 0
+	imds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.Header.Get("Metadata") != "true" || query.Get("resource") != Resource || query.Get("client_id") != "identity-client-id" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_request", "error_description": "Required metadata header not specified"})
+			return
+		}
+		requests++
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"access_token": fmt.Sprintf("aad-token-%d", requests),
+			"expires_on":   fmt.Sprint(time.Now().Add(time.Hour).Unix()),
+		})
+	}))
+	defer imds.Close()
+
+	tokenSource := NewManagedIdentityTokenSource("identity-client-id")
+	tokenSource.endpoint = imds.URL
+
+	for i := 0; i < 3; i++ {
+		token, err := tokenSource.AADToken(context.Background())
+		if err != nil {
+			t.Fatalf("AADToken failed: %v", err)
+		}
+		if token != "aad-token-1" {
+			t.Errorf("Expected aad-token-1, got %s", token)
+		}
+	}
+
+	// The token is reused until shortly before it expires
+	if requests != 1 {
+		t.Errorf("Expected 1 token request, got %d", requests)
+	}
+	tokenSource.expiry = time.Now().Add(time.Minute)
+	if token, err := tokenSource.AADToken(context.Background()); err != nil || token != "aad-token-2" {
+		t.Errorf("Expected a renewed token, got %s (%v)", token, err)
+	}
+
+	// Requests without the client ID of the identity are rejected
+	other := NewManagedIdentityTokenSource("")
+	other.endpoint = imds.URL
+	if _, err := other.AADToken(context.Background()); err == nil {
+		t.Error("Expected an error for a rejected token request")
+	}
+}
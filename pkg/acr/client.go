@@ -0,0 +1,324 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package acr lists tags of images in Azure Container Registry
+package acr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+// DomainSuffix is the domain of ACR login servers in the Azure public cloud
+const DomainSuffix = ".azurecr.io"
+
+// pageSize is the number of tags requested per page
+const pageSize = 100
+
+// Client lists tags of images in an Azure Container Registry through the ACR tags API
+// (/acr/v1/<image>/_tags). Registry access tokens are requested with the client ID and
+// secret of a service principal, or exchanged for a Microsoft Entra ID token of a managed
+// identity.
+type Client struct {
+	service      string
+	endpoint     string
+	httpClient   *http.Client
+	tokenSource  AADTokenSource
+	clientID     string
+	clientSecret string
+	sortStrategy tagsort.Strategy
+}
+
+// Option configures optional behavior of a Client
+type Option func(*Client)
+
+// WithSortStrategy orders tags by the given strategy instead of lexical order
+func WithSortStrategy(strategy tagsort.Strategy) Option {
+	return func(c *Client) {
+		c.sortStrategy = strategy
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to talk to the registry
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithServicePrincipal authenticates with the client ID and secret of a service principal
+// with the AcrPull role
+func WithServicePrincipal(clientID, clientSecret string) Option {
+	return func(c *Client) {
+		c.clientID = clientID
+		c.clientSecret = clientSecret
+	}
+}
+
+// WithTokenSource exchanges the Microsoft Entra ID tokens of the given token source for
+// registry tokens instead of those of the system-assigned managed identity
+func WithTokenSource(tokenSource AADTokenSource) Option {
+	return func(c *Client) {
+		c.tokenSource = tokenSource
+	}
+}
+
+// WithEndpoint talks to another URL than https://<login server>, e.g. a test server
+func WithEndpoint(endpoint string) Option {
+	return func(c *Client) {
+		c.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// NewClient creates a new client of the registry, given by name (e.g. "myregistry") or
+// login server (e.g. "myregistry.azurecr.io")
+func NewClient(registry string, opts ...Option) *Client {
+	loginServer := LoginServer(registry)
+	c := &Client{
+		service:    loginServer,
+		endpoint:   "https://" + loginServer,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.clientID == "" && c.tokenSource == nil {
+		c.tokenSource = NewManagedIdentityTokenSource("")
+	}
+
+	return c
+}
+
+// LoginServer returns the login server of a registry given by name or login server
+func LoginServer(registry string) string {
+	registry = strings.ToLower(strings.TrimSpace(registry))
+	if strings.Contains(registry, ".") {
+		return registry
+	}
+	return registry + DomainSuffix
+}
+
+// tagCandidate is a tag with the time it was created
+type tagCandidate struct {
+	tag         string
+	digest      string
+	createdTime time.Time
+}
+
+// GetLatestTag retrieves the latest tag of the image
+func (c *Client) GetLatestTag(ctx context.Context, image, tagFilter string) (string, error) {
+	latestTags, err := c.GetLatestTags(ctx, image, []string{tagFilter})
+	if err != nil {
+		return "", err
+	}
+
+	return latestTags[tagFilter], nil
+}
+
+// GetLatestTags retrieves the latest tag for each of the given tag filters, listing the
+// image's tags only once. The result is keyed by tag filter.
+func (c *Client) GetLatestTags(ctx context.Context, image string, tagFilters []string) (map[string]string, error) {
+	// Validate the sort strategy before listing tags so invalid configurations fail fast
+	if err := tagsort.Validate(c.sortStrategy, tagsort.Semver, tagsort.PushTime); err != nil {
+		return nil, err
+	}
+
+	candidates, err := c.listTags(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no tags found in repository %s", image)
+	}
+
+	sortCandidates := make([]tagsort.Candidate, len(candidates))
+	for i, candidate := range candidates {
+		sortCandidates[i] = tagsort.Candidate{Tag: candidate.tag, PushedAt: candidate.createdTime}
+	}
+
+	latestTags := make(map[string]string, len(tagFilters))
+	for _, tagFilter := range tagFilters {
+		if _, done := latestTags[tagFilter]; done {
+			continue
+		}
+
+		tag, err := tagsort.Select(sortCandidates, tagFilter, c.sortStrategy)
+		if err != nil {
+			return nil, fmt.Errorf("%w in repository %s", err, image)
+		}
+		latestTags[tagFilter] = tag
+	}
+
+	return latestTags, nil
+}
+
+// GetImageDigest returns the digest of the manifest the tag of the image points to
+func (c *Client) GetImageDigest(ctx context.Context, image, tag string) (string, error) {
+	candidates, err := c.listTags(ctx, image)
+	if err != nil {
+		return "", err
+	}
+
+	for _, candidate := range candidates {
+		if candidate.tag == tag {
+			return candidate.digest, nil
+		}
+	}
+
+	return "", fmt.Errorf("tag %s not found in repository %s", tag, image)
+}
+
+// tagList is a page of the tags API response
+type tagList struct {
+	Tags []struct {
+		Name        string    `json:"name"`
+		Digest      string    `json:"digest"`
+		CreatedTime time.Time `json:"createdTime"`
+	} `json:"tags"`
+}
+
+// listTags lists all tags of the image, following the Link headers of the pages
+func (c *Client) listTags(ctx context.Context, image string) ([]tagCandidate, error) {
+	token, err := c.accessToken(ctx, fmt.Sprintf("repository:%s:metadata_read", image))
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []tagCandidate
+	next := fmt.Sprintf("/acr/v1/%s/_tags?n=%d", image, pageSize)
+
+	for next != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+next, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		var page tagList
+		if next, err = c.doPage(req, &page); err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", image, err)
+		}
+
+		for _, tag := range page.Tags {
+			candidates = append(candidates, tagCandidate{tag: tag.Name, digest: tag.Digest, createdTime: tag.CreatedTime})
+		}
+	}
+
+	return candidates, nil
+}
+
+// doPage sends a request, decodes the JSON response into v and returns the path of the
+// next page from the Link header, or an empty string on the last page
+func (c *Client) doPage(req *http.Request, v interface{}) (string, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", newAPIError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", err
+	}
+
+	return nextLink(resp.Header.Get("Link")), nil
+}
+
+// nextLink returns the target of a Link header with rel="next", e.g.
+// `</acr/v1/my-app/_tags?last=v1.2.0&n=100>; rel="next"`
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, found := strings.Cut(link, ";")
+		if !found || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		target = strings.Trim(strings.TrimSpace(target), "<>")
+		if parsed, err := url.Parse(target); err == nil && parsed.IsAbs() {
+			return parsed.RequestURI()
+		}
+		return target
+	}
+	return ""
+}
+
+// do sends a request and decodes the JSON response into v
+func (c *Client) do(req *http.Request, v interface{}) error {
+	_, err := c.doPage(req, v)
+	return err
+}
+
+// postForm posts a form to a path of the registry and decodes the JSON response into v
+func (c *Client) postForm(ctx context.Context, path string, form url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return c.do(req, v)
+}
+
+// APIError is an unsuccessful response of the registry or the Instance Metadata Service
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("azure container registry returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("azure container registry returned %d: %s", e.StatusCode, e.Message)
+}
+
+// HTTPStatusCode returns the HTTP status code of the response
+func (e *APIError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
+// newAPIError reads the error details of an unsuccessful response
+func newAPIError(resp *http.Response) *APIError {
+	var body struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	_ = json.Unmarshal(data, &body)
+
+	message := strings.TrimSpace(body.Error + " " + body.ErrorDescription)
+	if len(body.Errors) > 0 {
+		message = strings.TrimSpace(body.Errors[0].Code + " " + body.Errors[0].Message)
+	}
+
+	return &APIError{StatusCode: resp.StatusCode, Message: message}
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+const testService = "myregistry.azurecr.io"
+
+// staticTokenSource returns a fixed Microsoft Entra ID token
+type staticTokenSource string
+
+func (s staticTokenSource) AADToken(ctx context.Context) (string, error) {
+	return string(s), nil
+}
+
+// fakeRegistry implements the ACR token exchange and serves the tags of the image
+// team/my-app over two pages
+type fakeRegistry struct {
+	tagRequests int
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	unauthorized := func(message string) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"errors": []map[string]string{{"code": "UNAUTHORIZED", "message": message}},
+		})
+	}
+	_ = r.ParseForm()
+
+	switch r.URL.Path {
+	case "/oauth2/exchange":
+		if r.Method != http.MethodPost || r.PostForm.Get("grant_type") != "access_token" ||
+			r.PostForm.Get("service") != testService || r.PostForm.Get("access_token") != "aad-token" {
+			unauthorized("invalid access token")
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"refresh_token": "refresh-token"})
+
+	case "/oauth2/token":
+		scope := r.Form.Get("scope")
+		if r.Form.Get("service") != testService || !strings.HasPrefix(scope, "repository:") || !strings.HasSuffix(scope, ":metadata_read") {
+			unauthorized("invalid scope")
+			return
+		}
+		username, password, basic := r.BasicAuth()
+		switch {
+		case r.Method == http.MethodGet && basic && username == "sp-client" && password == "sp-secret":
+		case r.Method == http.MethodPost && r.PostForm.Get("grant_type") == "refresh_token" && r.PostForm.Get("refresh_token") == "refresh-token":
+		default:
+			unauthorized("invalid credentials")
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "access-token"})
+
+	case "/acr/v1/team/my-app/_tags":
+		f.tagRequests++
+		if r.Header.Get("Authorization") != "Bearer access-token" {
+			unauthorized("authentication required")
+			return
+		}
+
+		tag := func(name, digest, createdTime string) map[string]string {
+			return map[string]string{"name": name, "digest": digest, "createdTime": createdTime}
+		}
+		if r.URL.Query().Get("last") == "" {
+			w.Header().Set("Link", `</acr/v1/team/my-app/_tags?last=v1.10.0&n=100>; rel="next"`)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"registry":  testService,
+				"imageName": "team/my-app",
+				"tags": []interface{}{
+					tag("latest", "sha256:bbb", "2024-03-01T00:00:00Z"),
+					tag("v1.10.0", "sha256:bbb", "2024-03-01T00:00:00Z"),
+				},
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"registry":  testService,
+			"imageName": "team/my-app",
+			"tags": []interface{}{
+				tag("v1.9.0", "sha256:aaa", "2024-01-01T00:00:00Z"),
+				tag("v1.9.1", "sha256:ccc", "2024-02-01T00:00:00Z"),
+			},
+		})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"errors": []map[string]string{{"code": "NAME_UNKNOWN", "message": "repository name not known to registry"}},
+		})
+	}
+}
+
+func newTestClient(t *testing.T, opts ...Option) (*Client, *fakeRegistry) {
+	t.Helper()
+
+	registry := &fakeRegistry{}
+	server := httptest.NewServer(registry)
+	t.Cleanup(server.Close)
+
+	opts = append([]Option{
+		WithEndpoint(server.URL),
+		WithTokenSource(staticTokenSource("aad-token")),
+	}, opts...)
+	return NewClient("myregistry", opts...), registry
+}
+
+func TestClient_GetLatestTags(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []Option
+		sortStrategy tagsort.Strategy
+		tagFilter    string
+		expected     string
+	}{
+		{
+			name:      "lexical",
+			tagFilter: `^v`,
+			expected:  "v1.9.1",
+		},
+		{
+			name:         "semver",
+			sortStrategy: tagsort.Semver,
+			tagFilter:    `^v`,
+			expected:     "v1.10.0",
+		},
+		{
+			name:         "push time with filter",
+			sortStrategy: tagsort.PushTime,
+			tagFilter:    `^v1\.9\.`,
+			expected:     "v1.9.1",
+		},
+		{
+			name:         "service principal",
+			opts:         []Option{WithServicePrincipal("sp-client", "sp-secret")},
+			sortStrategy: tagsort.Semver,
+			tagFilter:    `^v`,
+			expected:     "v1.10.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, registry := newTestClient(t, append(tt.opts, WithSortStrategy(tt.sortStrategy))...)
+
+			latestTags, err := client.GetLatestTags(context.Background(), "team/my-app", []string{tt.tagFilter, tt.tagFilter})
+			if err != nil {
+				t.Fatalf("GetLatestTags failed: %v", err)
+			}
+			if latestTags[tt.tagFilter] != tt.expected {
+				t.Errorf("Expected latest tag %s, got %s", tt.expected, latestTags[tt.tagFilter])
+			}
+
+			// Both pages are listed once for all filters
+			if registry.tagRequests != 2 {
+				t.Errorf("Expected 2 tag requests, got %d", registry.tagRequests)
+			}
+		})
+	}
+}
+
+func TestClient_GetLatestTag_NoMatch(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	_, err := client.GetLatestTag(context.Background(), "team/my-app", `^release-`)
+	if err == nil || err.Error() != "no tags found matching filter in repository team/my-app" {
+		t.Errorf("Expected a no matching tags error, got %v", err)
+	}
+}
+
+func TestClient_GetLatestTags_InvalidSortStrategy(t *testing.T) {
+	client, registry := newTestClient(t, WithSortStrategy("newest"))
+
+	if _, err := client.GetLatestTags(context.Background(), "team/my-app", []string{""}); err == nil {
+		t.Error("Expected an error for an unsupported sort strategy")
+	}
+	if registry.tagRequests != 0 {
+		t.Errorf("Expected no requests, got %d", registry.tagRequests)
+	}
+}
+
+func TestClient_GetImageDigest(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	digest, err := client.GetImageDigest(context.Background(), "team/my-app", "latest")
+	if err != nil {
+		t.Fatalf("GetImageDigest failed: %v", err)
+	}
+	if digest != "sha256:bbb" {
+		t.Errorf("Expected digest sha256:bbb, got %s", digest)
+	}
+
+	if _, err := client.GetImageDigest(context.Background(), "team/my-app", "v0.1.0"); err == nil {
+		t.Error("Expected an error for an unknown tag")
+	}
+}
+
+func TestClient_APIError(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []Option
+		image          string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "rejected managed identity token",
+			opts:           []Option{WithTokenSource(staticTokenSource("expired"))},
+			image:          "team/my-app",
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "azure container registry returned 401: UNAUTHORIZED invalid access token",
+		},
+		{
+			name:           "rejected service principal",
+			opts:           []Option{WithServicePrincipal("sp-client", "wrong")},
+			image:          "team/my-app",
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "azure container registry returned 401: UNAUTHORIZED invalid credentials",
+		},
+		{
+			name:           "unknown image",
+			image:          "other",
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "azure container registry returned 404: NAME_UNKNOWN repository name not known to registry",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t, tt.opts...)
+
+			_, err := client.GetLatestTag(context.Background(), tt.image, "")
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected an APIError, got %v", err)
+			}
+			if apiErr.HTTPStatusCode() != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, apiErr.HTTPStatusCode())
+			}
+			if apiErr.Error() != tt.expectedError {
+				t.Errorf("Expected error %q, got %q", tt.expectedError, apiErr.Error())
+			}
+		})
+	}
+}
+
+func TestLoginServer(t *testing.T) {
+	tests := map[string]string{
+		"myregistry":               "myregistry.azurecr.io",
+		"MyRegistry.azurecr.io":    "myregistry.azurecr.io",
+		"myregistry.azurecr.cn":    "myregistry.azurecr.cn",
+		" myregistry.azurecr.us\n": "myregistry.azurecr.us",
+	}
+
+	for registry, expected := range tests {
+		if loginServer := LoginServer(registry); loginServer != expected {
+			t.Errorf("Expected login server %s for %q, got %s", expected, registry, loginServer)
+		}
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := map[string]string{
+		"": "",
+		`</acr/v1/my-app/_tags?last=v1&n=100>; rel="next"`:                        "/acr/v1/my-app/_tags?last=v1&n=100",
+		`<https://myregistry.azurecr.io/acr/v1/my-app/_tags?last=v1>; rel="next"`: "/acr/v1/my-app/_tags?last=v1",
+		`</acr/v1/my-app/_tags?n=100>; rel="prev"`:                                "",
+	}
+
+	for header, expected := range tests {
+		if next := nextLink(header); next != expected {
+			t.Errorf("Expected next link %q for %q, got %q", expected, header, next)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aws/smithy-go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/git"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+)
+
+// ConditionAuthReady reports whether the credentials of the registries and the Git
+// repository are accepted
+const ConditionAuthReady = "AuthReady"
+
+// Reasons of the AuthReady condition
+const (
+	// ReasonAuthenticated means the last reconcile succeeded with the configured credentials
+	ReasonAuthenticated = "Authenticated"
+
+	// ReasonCredentialsMissing means a secret or secret key holding credentials does not exist
+	ReasonCredentialsMissing = "CredentialsMissing"
+
+	// ReasonRegistryAuthFailed means a registry rejected the credentials, e.g. an expired
+	// IRSA role session or a revoked token
+	ReasonRegistryAuthFailed = "RegistryAuthFailed"
+
+	// ReasonGitAuthFailed means the Git remote or the GitHub API rejected the credentials
+	ReasonGitAuthFailed = "GitAuthFailed"
+)
+
+// authErrorCodes are the AWS error codes of rejected or expired credentials
+var authErrorCodes = map[string]bool{
+	"AccessDenied":                true,
+	"AccessDeniedException":       true,
+	"UnauthorizedException":       true,
+	"ExpiredToken":                true,
+	"ExpiredTokenException":       true,
+	"InvalidClientTokenId":        true,
+	"UnrecognizedClientException": true,
+	"InvalidIdentityToken":        true,
+}
+
+// authFailureReason returns the AuthReady reason of an error caused by missing or rejected
+// credentials, or an empty string for other errors
+func authFailureReason(err error) string {
+	if errors.Is(err, errMissingCredentials) {
+		return ReasonCredentialsMissing
+	}
+	if errors.Is(err, git.ErrAuthentication) {
+		return ReasonGitAuthFailed
+	}
+
+	var gitHubErr *git.APIError
+	if errors.As(err, &gitHubErr) {
+		if gitHubErr.StatusCode == http.StatusUnauthorized || gitHubErr.StatusCode == http.StatusForbidden {
+			return ReasonGitAuthFailed
+		}
+		return ""
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && authErrorCodes[apiErr.ErrorCode()] {
+		return ReasonRegistryAuthFailed
+	}
+
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		if status := statusErr.HTTPStatusCode(); status == http.StatusUnauthorized || status == http.StatusForbidden {
+			return ReasonRegistryAuthFailed
+		}
+	}
+
+	return ""
+}
+
+// isAuthError reports whether an error is caused by missing or rejected credentials
+func isAuthError(err error) bool {
+	return authFailureReason(err) != ""
+}
+
+// gitErrorType returns the metrics error type of a failed Git update: auth for rejected
+// credentials, git otherwise
+func gitErrorType(err error) yukmetrics.ErrorType {
+	if isAuthError(err) {
+		return yukmetrics.ErrorTypeAuth
+	}
+	return yukmetrics.ErrorTypeGit
+}
+
+// setAuthFailure sets the AuthReady condition to False for an error caused by missing or
+// rejected credentials; other errors leave it unchanged
+func (r *YukConfigReconciler) setAuthFailure(yukConfig *yukv1.YukConfig, message string, err error) {
+	if reason := authFailureReason(err); reason != "" {
+		r.setCondition(yukConfig, ConditionAuthReady, metav1.ConditionFalse, reason, message)
+	}
+}
+
+// setAuthenticated sets the AuthReady condition to True after a successful reconcile
+func (r *YukConfigReconciler) setAuthenticated(yukConfig *yukv1.YukConfig) {
+	r.setCondition(yukConfig, ConditionAuthReady, metav1.ConditionTrue, ReasonAuthenticated,
+		"The registry and Git credentials were accepted")
+}
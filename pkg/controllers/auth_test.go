@@ -0,0 +1,224 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	"github.com/rebelopsio/yuk/pkg/git"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+	"github.com/rebelopsio/yuk/pkg/oci"
+)
+
+func TestAuthFailureReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "missing secret",
+			err:      fmt.Errorf("failed to read secret: %w", errMissingCredentials),
+			expected: ReasonCredentialsMissing,
+		},
+		{
+			name:     "ECR access denied",
+			err:      fmt.Errorf("failed to describe images: %w", &smithy.GenericAPIError{Code: "AccessDeniedException"}),
+			expected: ReasonRegistryAuthFailed,
+		},
+		{
+			name:     "expired ECR token",
+			err:      &smithy.GenericAPIError{Code: "ExpiredTokenException"},
+			expected: ReasonRegistryAuthFailed,
+		},
+		{
+			name:     "registry 403",
+			err:      fmt.Errorf("failed to list tags: %w", &oci.APIError{StatusCode: 403}),
+			expected: ReasonRegistryAuthFailed,
+		},
+		{
+			name:     "git authentication",
+			err:      fmt.Errorf("failed to clone repository: %w", git.ErrAuthentication),
+			expected: ReasonGitAuthFailed,
+		},
+		{
+			name:     "GitHub API 401",
+			err:      &git.APIError{StatusCode: 401, Message: "Bad credentials"},
+			expected: ReasonGitAuthFailed,
+		},
+		{
+			name: "GitHub API 422",
+			err:  &git.APIError{StatusCode: 422, Message: "Validation Failed"},
+		},
+		{
+			name: "ECR repository not found",
+			err:  &smithy.GenericAPIError{Code: "RepositoryNotFoundException"},
+		},
+		{
+			name: "registry 404",
+			err:  &oci.APIError{StatusCode: 404},
+		},
+		{
+			name: "push conflict",
+			err:  git.ErrPushConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reason := authFailureReason(tt.err); reason != tt.expected {
+				t.Errorf("Expected reason %q, got %q", tt.expected, reason)
+			}
+		})
+	}
+}
+
+// deniedTagResolver rejects every ECR request like an expired IRSA role session
+type deniedTagResolver struct{}
+
+func (deniedTagResolver) GetLatestTags(ctx context.Context, repositoryName string, tagFilters []string) (map[string]string, error) {
+	return nil, fmt.Errorf("failed to describe images: %w", &smithy.GenericAPIError{
+		Code:    "AccessDeniedException",
+		Message: "not authorized to perform ecr:DescribeImages",
+	})
+}
+
+func (deniedTagResolver) GetImageDigest(ctx context.Context, repositoryName, tag string) (string, error) {
+	return "", errors.New("unexpected digest lookup")
+}
+
+// deniedGitOperator is a Git client whose credentials are rejected by the remote
+type deniedGitOperator struct {
+	*fakeGitOperator
+}
+
+func (d deniedGitOperator) Clone(ctx context.Context) (string, error) {
+	d.clones++
+	return "", fmt.Errorf("failed to clone repository: %w", git.ErrAuthentication)
+}
+
+func TestYukConfigReconciler_Reconcile_AuthFailure(t *testing.T) {
+	tests := []struct {
+		name           string
+		resolver       TagResolver
+		gitOperator    func(t *testing.T) GitOperator
+		expectedReason string
+	}{
+		{
+			name:     "ECR credentials rejected",
+			resolver: deniedTagResolver{},
+			gitOperator: func(t *testing.T) GitOperator {
+				return newFakeGitOperator(t, map[string]string{"values.yaml": "image:\n    tag: v1.0.0\n"})
+			},
+			expectedReason: ReasonRegistryAuthFailed,
+		},
+		{
+			name:     "Git credentials rejected",
+			resolver: &fakeTagResolver{latestTags: map[string]string{"my-app:": "v1.1.0"}},
+			gitOperator: func(t *testing.T) GitOperator {
+				return deniedGitOperator{newFakeGitOperator(t, map[string]string{"values.yaml": "image:\n    tag: v1.0.0\n"})}
+			},
+			expectedReason: ReasonGitAuthFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = clientgoscheme.AddToScheme(scheme)
+			_ = yukv1.AddToScheme(scheme)
+
+			name := "auth-" + tt.expectedReason
+			yukConfig := &yukv1.YukConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: yukv1.YukConfigSpec{
+					Repository: yukv1.RepositoryConfig{
+						Type: RepositoryTypeECR,
+						ECR:  &yukv1.ECRConfig{Region: "us-west-2", RepositoryName: "my-app"},
+					},
+					Git:           yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+					UpdateTargets: []yukv1.UpdateTarget{{File: "values.yaml", YAMLPath: "image.tag"}},
+				},
+				Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+			}
+
+			gitOperator := tt.gitOperator(t)
+			reconciler := &YukConfigReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+					WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(10),
+				NewECRClient: func(region string, opts ...ecr.Option) TagResolver {
+					return tt.resolver
+				},
+				NewGitClient: func(config yukv1.GitConfig, opts ...git.Option) GitOperator {
+					return gitOperator
+				},
+			}
+
+			authErrors := yukmetrics.ErrorsTotal.With(prometheus.Labels{
+				"error_type": string(yukmetrics.ErrorTypeAuth),
+				"namespace":  "default",
+				"name":       name,
+			})
+			before := testutil.ToFloat64(authErrors)
+
+			ctx := context.Background()
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: "default"}}
+			if _, err := reconciler.Reconcile(ctx, req); err != nil {
+				t.Fatalf("Reconcile failed: %v", err)
+			}
+
+			if got := testutil.ToFloat64(authErrors) - before; got != 1 {
+				t.Errorf("Expected 1 auth error to be counted, got %v", got)
+			}
+
+			updated := &yukv1.YukConfig{}
+			if err := reconciler.Get(ctx, req.NamespacedName, updated); err != nil {
+				t.Fatalf("Failed to get YukConfig: %v", err)
+			}
+			var authReady *metav1.Condition
+			for i := range updated.Status.Conditions {
+				if updated.Status.Conditions[i].Type == ConditionAuthReady {
+					authReady = &updated.Status.Conditions[i]
+				}
+			}
+			if authReady == nil {
+				t.Fatalf("Expected an %s condition, got %v", ConditionAuthReady, updated.Status.Conditions)
+			}
+			if authReady.Status != metav1.ConditionFalse || authReady.Reason != tt.expectedReason {
+				t.Errorf("Expected %s False with reason %s, got %s %s", ConditionAuthReady, tt.expectedReason, authReady.Status, authReady.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/yaml"
+)
+
+// targetBranch returns the branch an update target is written to, or an empty string for
+// the configured branch
+func targetBranch(yukConfig *yukv1.YukConfig, target yukv1.UpdateTarget) string {
+	branch := yukConfig.Spec.Git.Branch
+	if branch == "" {
+		branch = yukv1.DefaultBranch
+	}
+	if target.Branch == "" || target.Branch == branch {
+		return ""
+	}
+	return target.Branch
+}
+
+// targetBranches returns the distinct branches the update targets are written to: the
+// configured branch, as an empty string, followed by the other branches in target order
+func targetBranches(yukConfig *yukv1.YukConfig) []string {
+	var branches []string
+	seen := make(map[string]bool)
+	for _, target := range yukConfig.Spec.UpdateTargets {
+		if branch := targetBranch(yukConfig, target); !seen[branch] {
+			branches = append(branches, branch)
+			seen[branch] = true
+		}
+	}
+	sort.SliceStable(branches, func(i, j int) bool { return branches[i] == "" && branches[j] != "" })
+	return branches
+}
+
+// updateFiles updates the target files with the new image tags. targetTags holds the
+// value for each update target, from its source: its tag, or its digest when the target
+// is pinned by digest; newTag is the default source's latest tag. Targets with an empty
+// value are left unchanged. targetImages holds the image each target's further values
+// are rendered from; when nil, the further values are left unchanged. The action selects whether the changes are pushed to the
+// configured branch (with a revert commit message for a rollback), pushed to a review
+// branch or, for a dry run, not committed at all; a dry run reports the diff of each
+// changed file.
+//
+// Targets written to other branches are cloned, committed and pushed separately, with a
+// client of newGitClient per branch. A failure on one branch does not prevent updating
+// the others; the failures of all branches are returned together. The Git repository is
+// locked while it is updated; errRepositoryBusy is returned when it is locked already.
+func (r *YukConfigReconciler) updateFiles(ctx context.Context, yukConfig *yukv1.YukConfig, newGitClient gitClientFactory, yamlUpdater *yaml.Updater, newTag string, targetTags []string, targetImages []targetImage, action UpdateAction) (*updateOutcome, error) {
+	unlock, err := r.lockRepository(yukConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	branches := targetBranches(yukConfig)
+	outcome := &updateOutcome{}
+	var committed []string
+	var errs []error
+
+	for _, branch := range branches {
+		branchOutcome, err := r.updateBranch(ctx, yukConfig, newGitClient(branch), yamlUpdater, branch, newTag, targetTags, targetImages, action)
+		if err != nil {
+			if len(branches) == 1 {
+				return nil, err
+			}
+			errs = append(errs, fmt.Errorf("branch %s: %w", branchName(yukConfig, branch), err))
+			continue
+		}
+
+		outcome.FilesChanged = append(outcome.FilesChanged, branchOutcome.FilesChanged...)
+		outcome.PendingChanges = append(outcome.PendingChanges, branchOutcome.PendingChanges...)
+		outcome.ReviewBranches = append(outcome.ReviewBranches, branchOutcome.ReviewBranches...)
+		outcome.SkippedTargets = append(outcome.SkippedTargets, branchOutcome.SkippedTargets...)
+		if branchOutcome.Commit != "" {
+			committed = append(committed, branchOutcome.FilesChanged...)
+			if outcome.Commit == "" {
+				outcome.Commit = branchOutcome.Commit
+			}
+		}
+		if outcome.PullRequestURL == "" {
+			outcome.PullRequestURL = branchOutcome.PullRequestURL
+		}
+	}
+
+	if len(committed) > 0 {
+		recordChangedFiles(yukConfig, committed)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return outcome, nil
+}
+
+// branchName returns the name of a branch returned by targetBranches
+func branchName(yukConfig *yukv1.YukConfig, branch string) string {
+	if branch != "" {
+		return branch
+	}
+	if yukConfig.Spec.Git.Branch != "" {
+		return yukConfig.Spec.Git.Branch
+	}
+	return yukv1.DefaultBranch
+}
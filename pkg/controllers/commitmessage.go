@@ -0,0 +1,186 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// EventReasonInvalidCommitMessage is emitted when a commit message template cannot be
+// rendered and the default message is used instead
+const EventReasonInvalidCommitMessage = "InvalidCommitMessage"
+
+// commitMessageData is the data available to commit message templates
+type commitMessageData struct {
+	// OldTag is the tag (or digest, for byDigest targets) replaced in the target
+	OldTag string
+
+	// NewTag is the tag (or digest, for byDigest targets) written to the target
+	NewTag string
+
+	// Tag is NewTag
+	Tag string
+
+	// Repository is the name of the target's source repository
+	Repository string
+
+	// File is the path of the target file in the Git repository
+	File string
+
+	// TargetDirs are the directories of the files changed by the commit
+	TargetDirs directoryList
+
+	// Name and Namespace identify the YukConfig
+	Name      string
+	Namespace string
+}
+
+// directoryList is a sorted list of directories, rendered comma-separated in templates
+type directoryList []string
+
+// String joins the directories with commas
+func (d directoryList) String() string {
+	return strings.Join(d, ", ")
+}
+
+// changedDirectories returns the distinct directories of the changed files, sorted. Files
+// at the root of the repository are in "."
+func changedDirectories(files []string) directoryList {
+	seen := make(map[string]bool)
+	var dirs directoryList
+	for _, file := range files {
+		if dir := path.Dir(file); !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// commitMessage renders the commit message of an update changing changedFiles. Each
+// updated target contributes its own template, or the config's; targets without either
+// contribute the default message for newTag. Distinct messages are joined with blank
+// lines, so targets sharing a template that does not depend on the target produce a
+// single message. A template that cannot be rendered falls back to the default message
+// with a warning event.
+func (r *YukConfigReconciler) commitMessage(ctx context.Context, yukConfig *yukv1.YukConfig, newTag string, targetTags, changedFiles []string) string {
+	var messages []string
+	seen := make(map[string]bool)
+	targetDirs := changedDirectories(changedFiles)
+
+	for i, target := range yukConfig.Spec.UpdateTargets {
+		if targetTags[i] == "" {
+			continue
+		}
+
+		data := commitMessageData{
+			OldTag:     previousTargetTag(yukConfig, target),
+			NewTag:     targetTags[i],
+			Tag:        targetTags[i],
+			Repository: repositoryName(sourceRepository(yukConfig, targetSource(yukConfig, target))),
+			File:       target.File,
+			TargetDirs: targetDirs,
+			Name:       yukConfig.Name,
+			Namespace:  yukConfig.Namespace,
+		}
+
+		text := target.CommitMessage
+		if text == "" {
+			text = yukConfig.Spec.Git.CommitMessage
+		}
+		if text == "" {
+			text = yukv1.DefaultCommitMessage
+		}
+		// The default message, also set by the defaulting webhook, names the tag of the update
+		if text == yukv1.DefaultCommitMessage {
+			data.NewTag, data.Tag = newTag, newTag
+		}
+
+		message, err := renderCommitMessage(text, data)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "Invalid commit message template, using the default message", "file", target.File)
+			r.recordEvent(yukConfig, corev1.EventTypeWarning, EventReasonInvalidCommitMessage,
+				"Invalid commit message template for %s, using the default message: %v", target.File, err)
+			message, _ = renderCommitMessage(yukv1.DefaultCommitMessage, data)
+		}
+
+		if message = strings.TrimSpace(message); message != "" && !seen[message] {
+			seen[message] = true
+			messages = append(messages, message)
+		}
+	}
+
+	if len(messages) == 0 {
+		return fmt.Sprintf("Update container image to %s", newTag)
+	}
+	return strings.Join(messages, "\n\n")
+}
+
+// renderCommitMessage renders a commit message template
+func renderCommitMessage(text string, data commitMessageData) (string, error) {
+	tmpl, err := template.New("commitMessage").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid commit message template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render commit message template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// previousTargetTag returns the tag (or digest) last written to a target: the target's
+// own status for targets with a tag filter override or pinned by digest, the status of
+// its named source, or the current tag
+func previousTargetTag(yukConfig *yukv1.YukConfig, target yukv1.UpdateTarget) string {
+	if target.TagFilter != "" || target.ByDigest {
+		for _, status := range yukConfig.Status.Targets {
+			if status.File == target.File && status.YAMLPath == target.YAMLPath {
+				if target.ByDigest {
+					return status.CurrentDigest
+				}
+				return status.CurrentTag
+			}
+		}
+		return ""
+	}
+
+	if source := targetSource(yukConfig, target); source != defaultSource(yukConfig).name {
+		for _, status := range yukConfig.Status.Sources {
+			if status.Name == source {
+				return status.CurrentTag
+			}
+		}
+		return ""
+	}
+
+	return yukConfig.Status.CurrentTag
+}
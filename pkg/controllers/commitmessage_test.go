@@ -0,0 +1,199 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestYukConfigReconciler_commitMessage(t *testing.T) {
+	tests := []struct {
+		name            string
+		commitMessage   string
+		targetMessages  []string
+		targetTags      []string
+		expected        string
+		expectedWarning bool
+	}{
+		{
+			name:       "default message",
+			targetTags: []string{"v1.1.0", "v1.1.0"},
+			expected:   "Update container image to v1.1.0",
+		},
+		{
+			name:          "defaulted message",
+			commitMessage: yukv1.DefaultCommitMessage,
+			targetTags:    []string{"v1.1.0", "v1.1.0-worker"},
+			expected:      "Update container image to v1.1.0",
+		},
+		{
+			name:          "literal message",
+			commitMessage: "Bump my-app",
+			targetTags:    []string{"v1.1.0", "v1.1.0"},
+			expected:      "Bump my-app",
+		},
+		{
+			name:          "templated message",
+			commitMessage: "chore({{ .Repository }}): {{ .OldTag }} -> {{ .NewTag }} in {{ .File }}",
+			targetTags:    []string{"v1.1.0", ""},
+			expected:      "chore(my-app): v1.0.0 -> v1.1.0 in apps/api/values.yaml",
+		},
+		{
+			name:           "per-target override",
+			commitMessage:  "Update {{ .Name }} to {{ .Tag }}",
+			targetMessages: []string{"", "Update worker in {{ .Namespace }} to {{ .NewTag }}"},
+			targetTags:     []string{"v1.1.0", "v1.1.0"},
+			expected:       "Update my-config to v1.1.0\n\nUpdate worker in default to v1.1.0",
+		},
+		{
+			name:          "target directories",
+			commitMessage: "chore({{ .TargetDirs }}): update to {{ .NewTag }}",
+			targetTags:    []string{"v1.1.0", "v1.1.0"},
+			expected:      "chore(apps/api, apps/worker): update to v1.1.0",
+		},
+		{
+			name:          "ranged target directories",
+			commitMessage: "Update{{ range .TargetDirs }} [{{ . }}]{{ end }}",
+			targetTags:    []string{"v1.1.0", ""},
+			expected:      "Update [apps/api] [apps/worker]",
+		},
+		{
+			name:            "parse error falls back to the default",
+			commitMessage:   "Update to {{ .NewTag",
+			targetTags:      []string{"v1.1.0", "v1.1.0"},
+			expected:        "Update container image to v1.1.0",
+			expectedWarning: true,
+		},
+		{
+			name:            "unknown field falls back to the default",
+			commitMessage:   "Update to {{ .Version }}",
+			targetTags:      []string{"v1.1.0", "v1.1.0"},
+			expected:        "Update container image to v1.1.0",
+			expectedWarning: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &YukConfigReconciler{Recorder: recorder}
+
+			yukConfig := &yukv1.YukConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-config", Namespace: "default"},
+				Spec: yukv1.YukConfigSpec{
+					Repository: yukv1.RepositoryConfig{
+						Type: RepositoryTypeECR,
+						ECR:  &yukv1.ECRConfig{Region: "us-west-2", RepositoryName: "my-app"},
+					},
+					Git: yukv1.GitConfig{CommitMessage: tt.commitMessage},
+					UpdateTargets: []yukv1.UpdateTarget{
+						{File: "apps/api/values.yaml", YAMLPath: "image.tag"},
+						{File: "apps/worker/values.yaml", YAMLPath: "image.tag"},
+					},
+				},
+				Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+			}
+			for i, message := range tt.targetMessages {
+				yukConfig.Spec.UpdateTargets[i].CommitMessage = message
+			}
+
+			changed := []string{"apps/worker/values.yaml", "apps/api/values.yaml", "apps/api/values.yaml"}
+			message := reconciler.commitMessage(context.Background(), yukConfig, "v1.1.0", tt.targetTags, changed)
+			if message != tt.expected {
+				t.Errorf("Expected message %q, got %q", tt.expected, message)
+			}
+
+			warned := false
+			for len(recorder.Events) > 0 {
+				if event := <-recorder.Events; strings.HasPrefix(event, "Warning "+EventReasonInvalidCommitMessage) {
+					warned = true
+				}
+			}
+			if warned != tt.expectedWarning {
+				t.Errorf("Expected warning %v, got %v", tt.expectedWarning, warned)
+			}
+		})
+	}
+}
+
+func TestChangedDirectories(t *testing.T) {
+	dirs := changedDirectories([]string{"apps/team-b/values.yaml", "values.yaml", "apps/team-a/deploy.yaml", "apps/team-b/kustomization.yaml"})
+
+	expected := "., apps/team-a, apps/team-b"
+	if dirs.String() != expected {
+		t.Errorf("Expected directories %q, got %q", expected, dirs.String())
+	}
+}
+
+func TestPreviousTargetTag(t *testing.T) {
+	yukConfig := &yukv1.YukConfig{
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{Type: RepositoryTypeECR},
+			Sources:    []yukv1.ImageSource{{Name: "sidecar"}},
+		},
+		Status: yukv1.YukConfigStatus{
+			CurrentTag: "v1.0.0",
+			Sources:    []yukv1.SourceStatus{{Name: "sidecar", CurrentTag: "v2.0.0"}},
+			Targets: []yukv1.TargetStatus{
+				{File: "stable.yaml", YAMLPath: "image.tag", CurrentTag: "v0.9.0"},
+				{File: "pinned.yaml", YAMLPath: "image.digest", CurrentDigest: "sha256:abc"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		target   yukv1.UpdateTarget
+		expected string
+	}{
+		{
+			name:     "default source",
+			target:   yukv1.UpdateTarget{File: "values.yaml", YAMLPath: "image.tag"},
+			expected: "v1.0.0",
+		},
+		{
+			name:     "named source",
+			target:   yukv1.UpdateTarget{File: "values.yaml", YAMLPath: "sidecar.tag", Source: "sidecar"},
+			expected: "v2.0.0",
+		},
+		{
+			name:     "target tag filter",
+			target:   yukv1.UpdateTarget{File: "stable.yaml", YAMLPath: "image.tag", TagFilter: "^v0"},
+			expected: "v0.9.0",
+		},
+		{
+			name:     "digest",
+			target:   yukv1.UpdateTarget{File: "pinned.yaml", YAMLPath: "image.digest", ByDigest: true},
+			expected: "sha256:abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tag := previousTargetTag(yukConfig, tt.target); tag != tt.expected {
+				t.Errorf("Expected previous tag %q, got %q", tt.expected, tag)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/yaml"
+)
+
+// EventReasonDeployedTagUnavailable is emitted when the image of the deployed workload
+// cannot be read
+const EventReasonDeployedTagUnavailable = "DeployedTagUnavailable"
+
+// recordDeployedTag records the tag run by the deployed workload in the status. A
+// workload that cannot be read clears the deployed tag with a warning event, without
+// failing the check.
+func (r *YukConfigReconciler) recordDeployedTag(ctx context.Context, yukConfig *yukv1.YukConfig) {
+	if yukConfig.Spec.DeployedWorkload == nil {
+		yukConfig.Status.DeployedTag = ""
+		return
+	}
+
+	tag, err := r.deployedTag(ctx, yukConfig)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to read the deployed tag")
+		r.recordEvent(yukConfig, corev1.EventTypeWarning, EventReasonDeployedTagUnavailable,
+			"Failed to read the deployed tag: %v", err)
+		yukConfig.Status.DeployedTag = ""
+		return
+	}
+	yukConfig.Status.DeployedTag = tag
+}
+
+// deployedTag returns the tag of the image run by the deployed workload, its digest when
+// the image is pinned by digest only, or "latest" when it has neither
+func (r *YukConfigReconciler) deployedTag(ctx context.Context, yukConfig *yukv1.YukConfig) (string, error) {
+	workload := yukConfig.Spec.DeployedWorkload
+	key := types.NamespacedName{Namespace: yukConfig.Namespace, Name: workload.Name}
+
+	var podSpec corev1.PodSpec
+	switch workload.Kind {
+	case yukv1.WorkloadKindDeployment:
+		var deployment appsv1.Deployment
+		if err := r.Get(ctx, key, &deployment); err != nil {
+			return "", fmt.Errorf("failed to get deployment %s: %w", workload.Name, err)
+		}
+		podSpec = deployment.Spec.Template.Spec
+	case yukv1.WorkloadKindStatefulSet:
+		var statefulSet appsv1.StatefulSet
+		if err := r.Get(ctx, key, &statefulSet); err != nil {
+			return "", fmt.Errorf("failed to get statefulset %s: %w", workload.Name, err)
+		}
+		podSpec = statefulSet.Spec.Template.Spec
+	default:
+		return "", fmt.Errorf("unsupported workload kind: %s", workload.Kind)
+	}
+
+	image, err := containerImage(podSpec.Containers, workload.Container)
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w", workload.Kind, workload.Name, err)
+	}
+
+	_, tag, digest := yaml.SplitImageReference(image)
+	switch {
+	case tag != "":
+		return tag, nil
+	case digest != "":
+		return digest, nil
+	}
+	return "latest", nil
+}
+
+// containerImage returns the image of the named container, or of the first container
+// when name is empty
+func containerImage(containers []corev1.Container, name string) (string, error) {
+	for _, container := range containers {
+		if name == "" || container.Name == name {
+			return container.Image, nil
+		}
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("no containers")
+	}
+	return "", fmt.Errorf("container %s not found", name)
+}
+
+// lagTag returns the tag measured against the latest tag by the lag metrics: the deployed
+// tag when a deployed workload is configured and could be read, the current tag otherwise
+func lagTag(yukConfig *yukv1.YukConfig) string {
+	if yukConfig.Spec.DeployedWorkload != nil && yukConfig.Status.DeployedTag != "" {
+		return yukConfig.Status.DeployedTag
+	}
+	return yukConfig.Status.CurrentTag
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestYukConfigReconciler_deployedTag(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	podSpec := func(images ...string) corev1.PodTemplateSpec {
+		var containers []corev1.Container
+		for i, image := range images {
+			containers = append(containers, corev1.Container{Name: []string{"app", "proxy"}[i], Image: image})
+		}
+		return corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: containers}}
+	}
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-app", Namespace: "default"},
+				Spec:       appsv1.DeploymentSpec{Template: podSpec("registry.local:5000/my-app:v1.0.0", "envoy:v1.30")},
+			},
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "pinned", Namespace: "default"},
+				Spec:       appsv1.DeploymentSpec{Template: podSpec("my-app@" + digest)},
+			},
+			&appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-db", Namespace: "default"},
+				Spec:       appsv1.StatefulSetSpec{Template: podSpec("postgres:16.2")},
+			},
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "elsewhere", Namespace: "other"},
+				Spec:       appsv1.DeploymentSpec{Template: podSpec("my-app:v2.0.0")},
+			},
+		).Build(),
+		Scheme: scheme,
+	}
+
+	tests := []struct {
+		name          string
+		workload      yukv1.WorkloadReference
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "first container of a deployment",
+			workload: yukv1.WorkloadReference{Kind: yukv1.WorkloadKindDeployment, Name: "my-app"},
+			expected: "v1.0.0",
+		},
+		{
+			name:     "named container",
+			workload: yukv1.WorkloadReference{Kind: yukv1.WorkloadKindDeployment, Name: "my-app", Container: "proxy"},
+			expected: "v1.30",
+		},
+		{
+			name:     "pinned by digest",
+			workload: yukv1.WorkloadReference{Kind: yukv1.WorkloadKindDeployment, Name: "pinned"},
+			expected: digest,
+		},
+		{
+			name:     "statefulset",
+			workload: yukv1.WorkloadReference{Kind: yukv1.WorkloadKindStatefulSet, Name: "my-db"},
+			expected: "16.2",
+		},
+		{
+			name:          "unknown container",
+			workload:      yukv1.WorkloadReference{Kind: yukv1.WorkloadKindDeployment, Name: "my-app", Container: "worker"},
+			expectedError: "Deployment my-app: container worker not found",
+		},
+		{
+			name:          "other namespace",
+			workload:      yukv1.WorkloadReference{Kind: yukv1.WorkloadKindDeployment, Name: "elsewhere"},
+			expectedError: "failed to get deployment elsewhere",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yukConfig := &yukv1.YukConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-config", Namespace: "default"},
+				Spec:       yukv1.YukConfigSpec{DeployedWorkload: &tt.workload},
+			}
+
+			tag, err := reconciler.deployedTag(context.Background(), yukConfig)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if tag != tt.expected {
+				t.Errorf("Expected deployed tag %q, got %q", tt.expected, tag)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_recordDeployedTag(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-app", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "my-app:v1.0.0"}},
+			}}},
+		}).Build(),
+		Recorder: recorder,
+	}
+
+	firstSeen := metav1.NewTime(time.Now().Add(-time.Hour))
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			DeployedWorkload: &yukv1.WorkloadReference{Kind: yukv1.WorkloadKindDeployment, Name: "my-app"},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.1.0", LatestTag: "v1.1.0", LatestTagFirstSeen: &firstSeen},
+	}
+
+	// Git is up to date, but the cluster still runs the previous tag
+	reconciler.recordDeployedTag(context.Background(), yukConfig)
+	if yukConfig.Status.DeployedTag != "v1.0.0" {
+		t.Fatalf("Expected deployed tag v1.0.0, got %q", yukConfig.Status.DeployedTag)
+	}
+	if lag := updateLag(yukConfig, time.Now()); lag < time.Hour {
+		t.Errorf("Expected the deployed tag to lag at least 1h, got %v", lag)
+	}
+	if behind, ok := versionsBehind(lagTag(yukConfig), yukConfig.Status.LatestTag); !ok || behind != 1 {
+		t.Errorf("Expected the deployed tag to be 1 version behind, got %d (%v)", behind, ok)
+	}
+
+	// A missing workload clears the deployed tag with a warning, and lag falls back to Git
+	yukConfig.Spec.DeployedWorkload.Name = "missing"
+	reconciler.recordDeployedTag(context.Background(), yukConfig)
+	if yukConfig.Status.DeployedTag != "" {
+		t.Errorf("Expected no deployed tag, got %q", yukConfig.Status.DeployedTag)
+	}
+	if event := <-recorder.Events; !strings.HasPrefix(event, "Warning "+EventReasonDeployedTagUnavailable) {
+		t.Errorf("Expected a %s warning, got %q", EventReasonDeployedTagUnavailable, event)
+	}
+	if lag := updateLag(yukConfig, time.Now()); lag != 0 {
+		t.Errorf("Expected no lag for the current tag, got %v", lag)
+	}
+}
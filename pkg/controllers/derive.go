@@ -0,0 +1,145 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+	"github.com/rebelopsio/yuk/pkg/validation"
+	"github.com/rebelopsio/yuk/pkg/yaml"
+)
+
+// deriveECRRepository fills in the region, repository name and registry ID of an ECR spec
+// repository that leaves them to be derived from the image reference at the first update
+// target, so an image of another account is looked up in that account's registry. The
+// derived repository is kept in the status, so the target is only read again when the
+// spec changes. A region, repository name or registry ID that is set must match the
+// derived one.
+func (r *YukConfigReconciler) deriveECRRepository(ctx context.Context, yukConfig *yukv1.YukConfig, creds *credentials) error {
+	repository := &yukConfig.Spec.Repository
+	if repository.Type != RepositoryTypeECR || repository.ECR == nil ||
+		(repository.ECR.Region != "" && repository.ECR.RepositoryName != "") {
+		yukConfig.Status.DerivedRepository = nil
+		return nil
+	}
+	if !validation.ECRRepositoryDerivable(yukConfig) {
+		return fmt.Errorf("the ECR region and repository name are required unless the first update target is an imageTagOnly target of the repository")
+	}
+
+	derived := yukConfig.Status.DerivedRepository
+	if derived == nil || derived.ObservedGeneration != yukConfig.Generation {
+		image, err := r.readTargetImage(ctx, yukConfig, creds)
+		if err != nil {
+			return err
+		}
+		uri, err := ecr.ParseImageURI(image)
+		if err != nil {
+			return fmt.Errorf("cannot derive the ECR repository from the first update target: %w", err)
+		}
+		derived = &yukv1.DerivedRepository{
+			Region:             uri.Region,
+			RepositoryName:     uri.RepositoryName,
+			RegistryID:         uri.AccountID,
+			ObservedGeneration: yukConfig.Generation,
+		}
+		log.FromContext(ctx).Info("Derived the ECR repository from the first update target",
+			"region", derived.Region, "repositoryName", derived.RepositoryName, "registryID", derived.RegistryID)
+	}
+
+	ecrConfig := *repository.ECR
+	if ecrConfig.Region != "" && ecrConfig.Region != derived.Region {
+		return fmt.Errorf("the ECR region %s does not match the region %s of the image at the first update target", ecrConfig.Region, derived.Region)
+	}
+	if ecrConfig.RepositoryName != "" && ecrConfig.RepositoryName != derived.RepositoryName {
+		return fmt.Errorf("the ECR repository name %s does not match the repository %s of the image at the first update target", ecrConfig.RepositoryName, derived.RepositoryName)
+	}
+	if ecrConfig.RegistryID != "" && ecrConfig.RegistryID != derived.RegistryID {
+		return fmt.Errorf("the ECR registry ID %s does not match the account %s of the image at the first update target", ecrConfig.RegistryID, derived.RegistryID)
+	}
+	ecrConfig.Region, ecrConfig.RepositoryName = derived.Region, derived.RepositoryName
+	ecrConfig.RegistryID = derived.RegistryID
+	repository.ECR = &ecrConfig
+	yukConfig.Status.DerivedRepository = derived
+	return nil
+}
+
+// readTargetImage clones the branch of the first update target and returns the image
+// reference at its path. With a file pattern or a wildcard, the first match is read. The
+// Git repository is locked while it is cloned, as a reused clone is shared with the
+// updates of other YukConfigs; errRepositoryBusy is returned when it is locked already.
+func (r *YukConfigReconciler) readTargetImage(ctx context.Context, yukConfig *yukv1.YukConfig, creds *credentials) (string, error) {
+	unlock, err := r.lockRepository(yukConfig)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	target := yukConfig.Spec.UpdateTargets[0]
+	gitClient := r.newGitClient(ctx, yukConfig, creds, targetBranch(yukConfig, target))
+	gitRepo := yukConfig.Spec.Git.Repository
+
+	cloneStart := time.Now()
+	repoPath, err := gitClient.Clone(ctx)
+	cloneResult := yukmetrics.GitOperationSuccess
+	if err != nil {
+		cloneResult = yukmetrics.GitOperationError
+	}
+	yukmetrics.GitOperations.With(prometheus.Labels{
+		"operation":  string(yukmetrics.GitOperationClone),
+		"repository": gitRepo,
+		"result":     string(cloneResult),
+	}).Inc()
+	yukmetrics.GitOperationDuration.With(prometheus.Labels{
+		"operation":  string(yukmetrics.GitOperationClone),
+		"repository": gitRepo,
+	}).Observe(time.Since(cloneStart).Seconds())
+	if err != nil {
+		return "", fmt.Errorf("failed to clone repository: %w", err)
+	}
+	defer gitClient.Cleanup(repoPath)
+
+	files, err := expandTargetFiles(repoPath, target.File)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no files match %s", target.File)
+	}
+
+	value, err := yaml.NewUpdater(yaml.WithPathSyntax(target.PathSyntax)).
+		GetValueAtPath(filepath.Join(repoPath, filepath.FromSlash(files[0])), target.YAMLPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the image at %s in %s: %w", target.YAMLPath, files[0], err)
+	}
+	if values, ok := value.([]interface{}); ok && len(values) > 0 {
+		value = values[0]
+	}
+	image, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("the value at %s in %s is not an image reference", target.YAMLPath, files[0])
+	}
+	return image, nil
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	"github.com/rebelopsio/yuk/pkg/git"
+)
+
+func TestYukConfigReconciler_Reconcile_DeriveECRRepository(t *testing.T) {
+	tests := []struct {
+		name          string
+		ecrConfig     yukv1.ECRConfig
+		image         string
+		expectedError string
+	}{
+		{
+			name:  "derived",
+			image: "123456789012.dkr.ecr.us-west-2.amazonaws.com/my-app:v1.0.0",
+		},
+		{
+			name:      "matching region",
+			ecrConfig: yukv1.ECRConfig{Region: "us-west-2"},
+			image:     "123456789012.dkr.ecr.us-west-2.amazonaws.com/my-app:v1.0.0",
+		},
+		{
+			name:          "mismatched region",
+			ecrConfig:     yukv1.ECRConfig{Region: "eu-west-1"},
+			image:         "123456789012.dkr.ecr.us-west-2.amazonaws.com/my-app:v1.0.0",
+			expectedError: "does not match the region us-west-2",
+		},
+		{
+			name:      "matching registry ID",
+			ecrConfig: yukv1.ECRConfig{RegistryID: "123456789012"},
+			image:     "123456789012.dkr.ecr.us-west-2.amazonaws.com/my-app:v1.0.0",
+		},
+		{
+			name:          "mismatched registry ID",
+			ecrConfig:     yukv1.ECRConfig{RegistryID: "210987654321"},
+			image:         "123456789012.dkr.ecr.us-west-2.amazonaws.com/my-app:v1.0.0",
+			expectedError: "does not match the account 123456789012",
+		},
+		{
+			name:          "not an ECR image",
+			image:         "ghcr.io/example/my-app:v1.0.0",
+			expectedError: "not an ECR image reference",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = clientgoscheme.AddToScheme(scheme)
+			_ = yukv1.AddToScheme(scheme)
+
+			name := fmt.Sprintf("derive-%d", i)
+			ecrConfig := tt.ecrConfig
+			yukConfig := &yukv1.YukConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: yukv1.YukConfigSpec{
+					Repository:    yukv1.RepositoryConfig{Type: RepositoryTypeECR, ECR: &ecrConfig},
+					Git:           yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+					UpdateTargets: []yukv1.UpdateTarget{{File: "values.yaml", YAMLPath: "image", ImageTagOnly: true}},
+				},
+				Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+			}
+
+			var regions []string
+			gitOperator := newFakeGitOperator(t, map[string]string{"values.yaml": "image: " + tt.image + "\n"})
+			reconciler := &YukConfigReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+					WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(10),
+				NewECRClient: func(region string, opts ...ecr.Option) TagResolver {
+					regions = append(regions, region)
+					return &fakeTagResolver{latestTags: map[string]string{"my-app:": "v1.1.0"}}
+				},
+				NewGitClient: func(config yukv1.GitConfig, opts ...git.Option) GitOperator {
+					return gitOperator
+				},
+			}
+
+			ctx := context.Background()
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: "default"}}
+			if _, err := reconciler.Reconcile(ctx, req); err != nil {
+				t.Fatalf("Reconcile failed: %v", err)
+			}
+
+			updated := &yukv1.YukConfig{}
+			if err := reconciler.Get(ctx, req.NamespacedName, updated); err != nil {
+				t.Fatalf("Failed to get YukConfig: %v", err)
+			}
+
+			if tt.expectedError != "" {
+				ready := meta.FindStatusCondition(updated.Status.Conditions, "Ready")
+				if ready == nil || !strings.Contains(ready.Message, tt.expectedError) {
+					t.Errorf("Expected a Ready condition with %q, got %+v", tt.expectedError, ready)
+				}
+				if len(regions) != 0 {
+					t.Errorf("Expected no repository check, got checks in %v", regions)
+				}
+				return
+			}
+
+			expected := yukv1.DerivedRepository{Region: "us-west-2", RepositoryName: "my-app", RegistryID: "123456789012", ObservedGeneration: updated.Generation}
+			if updated.Status.DerivedRepository == nil || *updated.Status.DerivedRepository != expected {
+				t.Errorf("Expected derived repository %+v, got %+v", expected, updated.Status.DerivedRepository)
+			}
+			if len(regions) == 0 || regions[0] != "us-west-2" {
+				t.Errorf("Expected the repository to be checked in us-west-2, got %v", regions)
+			}
+			if updated.Status.CurrentTag != "v1.1.0" {
+				t.Errorf("Expected the update to v1.1.0 to be pushed, got %q", updated.Status.CurrentTag)
+			}
+			if updated.Spec.Repository.ECR.RepositoryName != tt.ecrConfig.RepositoryName {
+				t.Errorf("Expected the spec to be left unchanged, got repository name %q", updated.Spec.Repository.ECR.RepositoryName)
+			}
+
+			// The derived repository is reused until the spec changes
+			clones := gitOperator.clones
+			if err := reconciler.deriveECRRepository(ctx, updated, &credentials{}); err != nil {
+				t.Fatalf("Failed to derive the ECR repository: %v", err)
+			}
+			if gitOperator.clones != clones {
+				t.Errorf("Expected no further clone, got %d clones", gitOperator.clones-clones)
+			}
+			if registryID := updated.Spec.Repository.ECR.RegistryID; registryID != "123456789012" {
+				t.Errorf("Expected the image's registry to be looked up, got registry ID %q", registryID)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_deriveECRRepository_RepositoryBusy(t *testing.T) {
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "derive", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository:    yukv1.RepositoryConfig{Type: RepositoryTypeECR, ECR: &yukv1.ECRConfig{}},
+			Git:           yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+			UpdateTargets: []yukv1.UpdateTarget{{File: "values.yaml", YAMLPath: "image", ImageTagOnly: true}},
+		},
+	}
+
+	gitOperator := newFakeGitOperator(t, map[string]string{
+		"values.yaml": "image: 123456789012.dkr.ecr.us-west-2.amazonaws.com/my-app:v1.0.0\n",
+	})
+	reconciler := &YukConfigReconciler{
+		RepositoryLocks: NewRepositoryLocks(),
+		NewGitClient: func(config yukv1.GitConfig, opts ...git.Option) GitOperator {
+			return gitOperator
+		},
+	}
+
+	// Another YukConfig updating the repository may share a reused clone, so it is not cloned
+	unlock, _ := reconciler.RepositoryLocks.TryLock(yukConfig.Spec.Git.Repository)
+	if err := reconciler.deriveECRRepository(context.Background(), yukConfig, &credentials{}); !errors.Is(err, errRepositoryBusy) {
+		t.Fatalf("Expected the repository to be busy, got %v", err)
+	}
+	if gitOperator.clones != 0 {
+		t.Errorf("Expected no clone while the repository is locked, got %d", gitOperator.clones)
+	}
+
+	unlock()
+	if err := reconciler.deriveECRRepository(context.Background(), yukConfig, &credentials{}); err != nil {
+		t.Fatalf("Failed to derive the ECR repository: %v", err)
+	}
+	if _, ok := reconciler.RepositoryLocks.TryLock(yukConfig.Spec.Git.Repository); !ok {
+		t.Error("Expected the lock to be released after the clone")
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+)
+
+// EventReasonCleanupFailed is emitted when the review branches of a deleted YukConfig
+// cannot be deleted
+const EventReasonCleanupFailed = "CleanupFailed"
+
+// finalize cleans up the external state of a deleted YukConfig: it deletes its review
+// branches, closing their pull requests, and removes its metrics. Transient failures are
+// returned so the cleanup is retried; permanent ones (e.g. revoked credentials) leave the
+// branches in place rather than blocking the deletion.
+func (r *YukConfigReconciler) finalize(ctx context.Context, yukConfig *yukv1.YukConfig) error {
+	logger := log.FromContext(ctx)
+
+	if branches := yukConfig.Status.ReviewBranches; len(branches) > 0 {
+		deleted, err := r.deleteReviewBranches(ctx, yukConfig)
+		if err != nil {
+			if isRetryable(err) {
+				return err
+			}
+			logger.Error(err, "Failed to delete review branches", "branches", branches)
+			r.recordEvent(yukConfig, corev1.EventTypeWarning, EventReasonCleanupFailed,
+				"Failed to delete review branches %v: %v", branches, err)
+		} else {
+			logger.Info("Deleted review branches", "branches", deleted)
+		}
+	}
+
+	yukmetrics.DeleteConfigMetrics(yukConfig.Namespace, yukConfig.Name)
+	return nil
+}
+
+// deleteReviewBranches deletes the review branches recorded in the status of a YukConfig
+// and returns the ones that still existed
+func (r *YukConfigReconciler) deleteReviewBranches(ctx context.Context, yukConfig *yukv1.YukConfig) ([]string, error) {
+	creds, err := r.resolveCredentials(ctx, yukConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve credentials: %w", err)
+	}
+
+	return r.newGitClient(ctx, yukConfig, creds, "").DeleteBranches(ctx, yukConfig.Status.ReviewBranches)
+}
+
+// recordReviewBranches adds the pushed review branches to the status of a YukConfig
+func recordReviewBranches(yukConfig *yukv1.YukConfig, branches []string) {
+	for _, branch := range branches {
+		if !slices.Contains(yukConfig.Status.ReviewBranches, branch) {
+			yukConfig.Status.ReviewBranches = append(yukConfig.Status.ReviewBranches, branch)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	"github.com/rebelopsio/yuk/pkg/git"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+)
+
+func TestYukConfigReconciler_Reconcile_AddsFinalizer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "new-config", Namespace: "default"},
+		Spec:       yukv1.YukConfigSpec{Disabled: true},
+	}
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme: scheme,
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "new-config", Namespace: "default"}}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	updated := &yukv1.YukConfig{}
+	if err := reconciler.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Failed to get YukConfig: %v", err)
+	}
+	if !controllerutil.ContainsFinalizer(updated, yukv1.CleanupFinalizer) {
+		t.Errorf("Expected finalizer %s, got %v", yukv1.CleanupFinalizer, updated.Finalizers)
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_DeletionCleansUp(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "review-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeECR,
+				ECR:  &yukv1.ECRConfig{Region: "us-west-2", RepositoryName: "my-app"},
+			},
+			Git: yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+			UpdateStrategy: yukv1.UpdateStrategyPullRequest,
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+	}
+
+	gitOperator := newFakeGitOperator(t, map[string]string{"values.yaml": "image:\n    tag: v1.0.0\n"})
+	if _, err := gitOperator.git(gitOperator.remote, "branch", "yuk/other-config-v1.1.0-0123abcd", "main"); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+		NewECRClient: func(region string, opts ...ecr.Option) TagResolver {
+			return &fakeTagResolver{latestTags: map[string]string{"my-app:": "v1.1.0"}}
+		},
+		NewGitClient: func(config yukv1.GitConfig, opts ...git.Option) GitOperator {
+			return gitOperator
+		},
+	}
+
+	// The proposed update is pushed to a review branch, which is recorded
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "review-config", Namespace: "default"}}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	updated := &yukv1.YukConfig{}
+	if err := reconciler.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Failed to get YukConfig: %v", err)
+	}
+	reviewBranch := reviewBranch(yukConfig, "v1.1.0", []string{"v1.1.0"})
+	if expected := []string{reviewBranch}; !reflect.DeepEqual(updated.Status.ReviewBranches, expected) {
+		t.Fatalf("Expected review branches %v, got %v", expected, updated.Status.ReviewBranches)
+	}
+	if _, err := gitOperator.git(gitOperator.remote, "rev-parse", "--verify", reviewBranch); err != nil {
+		t.Fatalf("Expected review branch %s to be pushed: %v", reviewBranch, err)
+	}
+
+	// Deleting the YukConfig deletes its review branch and metrics before releasing it
+	if err := reconciler.Delete(ctx, updated); err != nil {
+		t.Fatalf("Failed to delete YukConfig: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if _, err := gitOperator.git(gitOperator.remote, "rev-parse", "--verify", reviewBranch); err == nil {
+		t.Errorf("Expected review branch %s to be deleted", reviewBranch)
+	}
+	if _, err := gitOperator.git(gitOperator.remote, "rev-parse", "--verify", "yuk/other-config-v1.1.0-0123abcd"); err != nil {
+		t.Errorf("Expected the branch of another YukConfig to be kept: %v", err)
+	}
+
+	if err := reconciler.Get(ctx, req.NamespacedName, &yukv1.YukConfig{}); !errors.IsNotFound(err) {
+		t.Errorf("Expected the YukConfig to be removed, got: %v", err)
+	}
+
+	labels := prometheus.Labels{"namespace": "default", "name": "review-config"}
+	for name, metric := range map[string]interface {
+		DeletePartialMatch(labels prometheus.Labels) int
+	}{
+		"yuk_controller_reconciliation_total": yukmetrics.ReconciliationTotal,
+		"yuk_config_status":                   yukmetrics.ConfigStatus,
+		"yuk_current_version_info":            yukmetrics.CurrentVersion,
+	} {
+		if deleted := metric.DeletePartialMatch(labels); deleted != 0 {
+			t.Errorf("Expected %s of the deleted YukConfig to be removed, found %d series", name, deleted)
+		}
+	}
+}
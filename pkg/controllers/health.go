@@ -0,0 +1,140 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// HealthChecker tracks the checks scheduled for YukConfigs and reports the controller
+// unhealthy when a check is overdue by more than the staleness window and no check
+// succeeded within it, e.g. because the workers are stuck. Requeues that only wait for
+// the check interval are not checks, and the checker passes while no check is scheduled
+// (no YukConfigs, or all of them disabled or paused). Only the leader reconciles, so the
+// checker is run by the manager: the window starts when the replica becomes the leader,
+// and a standby replica is always healthy.
+type HealthChecker struct {
+	staleAfter time.Duration
+	now        func() time.Time
+
+	mu          sync.Mutex
+	leading     bool
+	lastSuccess time.Time
+	scheduled   map[types.NamespacedName]time.Time
+}
+
+// NewHealthChecker creates a health checker with the given staleness window
+func NewHealthChecker(staleAfter time.Duration) *HealthChecker {
+	h := &HealthChecker{
+		staleAfter: staleAfter,
+		now:        time.Now,
+		scheduled:  make(map[types.NamespacedName]time.Time),
+	}
+	h.lastSuccess = h.now()
+	return h
+}
+
+// Start starts the staleness window once this replica is the leader
+func (h *HealthChecker) Start(ctx context.Context) error {
+	h.mu.Lock()
+	h.leading = true
+	h.lastSuccess = h.now()
+	h.mu.Unlock()
+
+	<-ctx.Done()
+	return nil
+}
+
+// NeedLeaderElection only starts the checker on the leader
+func (h *HealthChecker) NeedLeaderElection() bool {
+	return true
+}
+
+// recordCheck records a check of a YukConfig and schedules the next one after the given
+// delay; without a delay, the YukConfig waits for a change and nothing is scheduled
+func (h *HealthChecker) recordCheck(key types.NamespacedName, succeeded bool, next time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if succeeded {
+		h.lastSuccess = h.now()
+	}
+	h.scheduleLocked(key, next)
+}
+
+// schedule records when the next check of a YukConfig is due, without a check
+func (h *HealthChecker) schedule(key types.NamespacedName, next time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.scheduleLocked(key, next)
+}
+
+// forget stops tracking a YukConfig that is deleted, disabled or paused
+func (h *HealthChecker) forget(key types.NamespacedName) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.scheduled, key)
+}
+
+// scheduleLocked records when the next check of a YukConfig is due. h.mu must be held.
+func (h *HealthChecker) scheduleLocked(key types.NamespacedName, next time.Duration) {
+	if next <= 0 {
+		delete(h.scheduled, key)
+		return
+	}
+	h.scheduled[key] = h.now().Add(next)
+}
+
+// Check implements healthz.Checker. It fails on the leader when the most overdue check
+// is late by more than the staleness window and no check succeeded within it.
+func (h *HealthChecker) Check(_ *http.Request) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// A standby replica does not reconcile until it is elected
+	if !h.leading {
+		return nil
+	}
+
+	now := h.now()
+	if now.Sub(h.lastSuccess) <= h.staleAfter {
+		return nil
+	}
+
+	// Find the most overdue check; nothing to do is healthy
+	var overdue types.NamespacedName
+	var due time.Time
+	for key, at := range h.scheduled {
+		if due.IsZero() || at.Before(due) {
+			overdue, due = key, at
+		}
+	}
+	if due.IsZero() || now.Sub(due) <= h.staleAfter {
+		return nil
+	}
+
+	return fmt.Errorf("check of %s overdue for %s and no successful reconcile since %s (staleness window %s)",
+		overdue, now.Sub(due).Round(time.Second), h.lastSuccess.UTC().Format(time.RFC3339), h.staleAfter)
+}
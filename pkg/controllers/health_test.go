@@ -0,0 +1,219 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+)
+
+func TestHealthChecker_Check(t *testing.T) {
+	now := time.Now()
+	health := NewHealthChecker(10 * time.Minute)
+	health.now = func() time.Time { return now }
+	health.leading = true
+	health.lastSuccess = now
+
+	key := types.NamespacedName{Name: "test-config", Namespace: "default"}
+	health.schedule(key, 5*time.Minute)
+
+	// Healthy while the check is not overdue by more than the window
+	now = now.Add(15 * time.Minute)
+	if err := health.Check(nil); err != nil {
+		t.Errorf("Expected healthy within the window, got: %v", err)
+	}
+
+	// Unhealthy once the check is overdue and no reconcile succeeded within the window
+	now = now.Add(time.Minute)
+	if err := health.Check(nil); err == nil {
+		t.Error("Expected unhealthy after the window but got no error")
+	}
+
+	// A successful check makes it healthy again
+	health.recordCheck(key, true, 5*time.Minute)
+	if err := health.Check(nil); err != nil {
+		t.Errorf("Expected healthy after a successful check, got: %v", err)
+	}
+
+	// A failed check reschedules the next one, so the workers are not stuck
+	now = now.Add(20 * time.Minute)
+	health.recordCheck(key, false, 5*time.Minute)
+	if err := health.Check(nil); err != nil {
+		t.Errorf("Expected healthy after a failed check, got: %v", err)
+	}
+}
+
+func TestHealthChecker_NothingScheduled(t *testing.T) {
+	now := time.Now()
+	health := NewHealthChecker(10 * time.Minute)
+	health.now = func() time.Time { return now }
+	health.leading = true
+	health.lastSuccess = now
+
+	// Without YukConfigs, the leader stays healthy
+	now = now.Add(time.Hour)
+	if err := health.Check(nil); err != nil {
+		t.Errorf("Expected healthy without YukConfigs, got: %v", err)
+	}
+
+	// Once the only YukConfig is paused or disabled, nothing is scheduled any more
+	key := types.NamespacedName{Name: "test-config", Namespace: "default"}
+	health.schedule(key, time.Minute)
+	health.forget(key)
+	now = now.Add(time.Hour)
+	if err := health.Check(nil); err != nil {
+		t.Errorf("Expected healthy without scheduled checks, got: %v", err)
+	}
+}
+
+func TestHealthChecker_Standby(t *testing.T) {
+	now := time.Now()
+	health := NewHealthChecker(10 * time.Minute)
+	health.now = func() time.Time { return now }
+	health.schedule(types.NamespacedName{Name: "test-config", Namespace: "default"}, time.Minute)
+
+	// A standby replica is healthy however long it waits for leadership
+	now = now.Add(time.Hour)
+	if err := health.Check(nil); err != nil {
+		t.Errorf("Expected standby to be healthy, got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = health.Start(ctx) }()
+
+	// The staleness window starts once the replica is elected
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		health.mu.Lock()
+		leading := health.leading
+		health.mu.Unlock()
+		if leading || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := health.Check(nil); err != nil {
+		t.Errorf("Expected healthy right after election, got: %v", err)
+	}
+
+	now = now.Add(11 * time.Minute)
+	if err := health.Check(nil); err == nil {
+		t.Error("Expected unhealthy once the leader did not reconcile within the window")
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_RecordsHealth(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	lastChecked := metav1.Now()
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeECR,
+				ECR:  &yukv1.ECRConfig{Region: "us-east-1", RepositoryName: "health-app"},
+			},
+			UpdateTargets: []yukv1.UpdateTarget{{File: "values.yaml", YAMLPath: "image.tag"}},
+		},
+		Status: yukv1.YukConfigStatus{LastChecked: &lastChecked, CurrentTag: "v1.0.0"},
+	}
+
+	now := time.Now().Add(-time.Hour)
+	health := NewHealthChecker(10 * time.Minute)
+	health.now = func() time.Time { return now }
+	health.leading = true
+	health.lastSuccess = now
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+		Health:   health,
+		NewECRClient: func(region string, opts ...ecr.Option) TagResolver {
+			return &fakeTagResolver{latestTags: map[string]string{"health-app:": "v1.0.0"}}
+		},
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+
+	// A reconcile waiting for the check interval only schedules the check
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if !health.lastSuccess.Equal(now) {
+		t.Error("Expected a reconcile before the check interval not to be recorded as a check")
+	}
+	if _, ok := health.scheduled[req.NamespacedName]; !ok {
+		t.Fatal("Expected the next check to be scheduled")
+	}
+
+	// The check is overdue an hour later
+	now = now.Add(time.Hour)
+	if err := health.Check(nil); err == nil {
+		t.Fatal("Expected unhealthy before the check but got no error")
+	}
+
+	// A successful check makes it healthy again
+	reconciler.Trigger = NewReconcileTrigger()
+	reconciler.Trigger.Enqueue(yukConfig)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if !health.lastSuccess.Equal(now) {
+		t.Error("Expected the check to be recorded as successful")
+	}
+	if err := health.Check(nil); err != nil {
+		t.Errorf("Expected healthy after a successful check, got: %v", err)
+	}
+
+	// A paused YukConfig is no longer tracked
+	paused := &yukv1.YukConfig{}
+	if err := reconciler.Get(ctx, req.NamespacedName, paused); err != nil {
+		t.Fatalf("Failed to get YukConfig: %v", err)
+	}
+	paused.Annotations = map[string]string{yukv1.PausedAnnotation: "true"}
+	if err := reconciler.Update(ctx, paused); err != nil {
+		t.Fatalf("Failed to pause YukConfig: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(health.scheduled) != 0 {
+		t.Errorf("Expected no scheduled checks once paused, got %v", health.scheduled)
+	}
+	now = now.Add(time.Hour)
+	if err := health.Check(nil); err != nil {
+		t.Errorf("Expected healthy with all YukConfigs paused, got: %v", err)
+	}
+}
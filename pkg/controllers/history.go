@@ -0,0 +1,60 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// defaultHistoryLimit is the number of updates kept in the status history by default
+const defaultHistoryLimit = 10
+
+// historyLimit returns the number of updates kept in the status history of a YukConfig
+func historyLimit(yukConfig *yukv1.YukConfig) int {
+	if yukConfig.Spec.HistoryLimit == nil {
+		return defaultHistoryLimit
+	}
+
+	limit := int(*yukConfig.Spec.HistoryLimit)
+	if limit < 0 {
+		return 0
+	}
+	return min(limit, yukv1.MaxHistoryLimit)
+}
+
+// recordHistory appends a pushed update to the status history of a YukConfig, dropping the
+// oldest entries beyond its history limit
+func recordHistory(yukConfig *yukv1.YukConfig, now metav1.Time, oldTag, newTag string, outcome *updateOutcome) {
+	history := append(yukConfig.Status.History, yukv1.UpdateRecord{
+		Time:         now,
+		OldTag:       oldTag,
+		NewTag:       newTag,
+		CommitSHA:    outcome.Commit,
+		FilesChanged: outcome.FilesChanged,
+	})
+
+	limit := historyLimit(yukConfig)
+	if len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	if len(history) == 0 {
+		history = nil
+	}
+	yukConfig.Status.History = history
+}
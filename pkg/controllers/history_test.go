@@ -0,0 +1,89 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestHistoryLimit(t *testing.T) {
+	limit := func(n int32) *int32 { return &n }
+
+	tests := []struct {
+		name     string
+		limit    *int32
+		expected int
+	}{
+		{name: "default", expected: defaultHistoryLimit},
+		{name: "configured", limit: limit(3), expected: 3},
+		{name: "disabled", limit: limit(0), expected: 0},
+		{name: "negative", limit: limit(-1), expected: 0},
+		{name: "capped", limit: limit(1000), expected: yukv1.MaxHistoryLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yukConfig := &yukv1.YukConfig{Spec: yukv1.YukConfigSpec{HistoryLimit: tt.limit}}
+			if got := historyLimit(yukConfig); got != tt.expected {
+				t.Errorf("Expected limit %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRecordHistory(t *testing.T) {
+	limit := int32(3)
+	yukConfig := &yukv1.YukConfig{Spec: yukv1.YukConfigSpec{HistoryLimit: &limit}}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= 5; i++ {
+		outcome := &updateOutcome{Commit: fmt.Sprintf("commit%d", i), FilesChanged: []string{"values.yaml"}}
+		recordHistory(yukConfig, metav1.NewTime(start.Add(time.Duration(i)*time.Hour)),
+			fmt.Sprintf("v1.%d.0", i-1), fmt.Sprintf("v1.%d.0", i), outcome)
+	}
+
+	// The two oldest updates were dropped past the limit
+	history := yukConfig.Status.History
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(history))
+	}
+	for i, entry := range history {
+		update := i + 3
+		if entry.NewTag != fmt.Sprintf("v1.%d.0", update) || entry.OldTag != fmt.Sprintf("v1.%d.0", update-1) {
+			t.Errorf("Expected entry %d to update v1.%d.0 to v1.%d.0, got %s to %s", i, update-1, update, entry.OldTag, entry.NewTag)
+		}
+		if entry.CommitSHA != fmt.Sprintf("commit%d", update) || len(entry.FilesChanged) != 1 {
+			t.Errorf("Expected entry %d to record commit%d and its file, got %s and %v", i, update, entry.CommitSHA, entry.FilesChanged)
+		}
+		if !entry.Time.Time.Equal(start.Add(time.Duration(update) * time.Hour)) {
+			t.Errorf("Expected entry %d at %s, got %s", i, start.Add(time.Duration(update)*time.Hour), entry.Time)
+		}
+	}
+
+	// Disabling the history clears it with the next update
+	limit = 0
+	recordHistory(yukConfig, metav1.NewTime(start), "v1.5.0", "v1.6.0", &updateOutcome{Commit: "commit6"})
+	if yukConfig.Status.History != nil {
+		t.Errorf("Expected no history when disabled, got %v", yukConfig.Status.History)
+	}
+}
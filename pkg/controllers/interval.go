@@ -0,0 +1,73 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"math/rand/v2"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// EventReasonInvalidCheckInterval is emitted when the check interval annotation is not a
+// positive duration
+const EventReasonInvalidCheckInterval = "InvalidCheckInterval"
+
+// checkInterval returns the interval between checks of the YukConfig, raised to the
+// configured minimum. The check interval annotation overrides the spec; an invalid value
+// is reported with a warning event and ignored.
+func (r *YukConfigReconciler) checkInterval(yukConfig *yukv1.YukConfig) time.Duration {
+	interval := yukv1.DefaultCheckInterval
+	if yukConfig.Spec.CheckInterval != nil {
+		interval = yukConfig.Spec.CheckInterval.Duration
+	}
+
+	if value, ok := yukConfig.Annotations[yukv1.CheckIntervalAnnotation]; ok {
+		if override, err := time.ParseDuration(value); err == nil && override > 0 {
+			interval = override
+		} else {
+			r.recordEvent(yukConfig, corev1.EventTypeWarning, EventReasonInvalidCheckInterval,
+				"Ignoring annotation %s: %q is not a positive duration, checking every %s",
+				yukv1.CheckIntervalAnnotation, value, max(interval, r.MinCheckInterval))
+		}
+	}
+
+	if interval < r.MinCheckInterval {
+		return r.MinCheckInterval
+	}
+	return interval
+}
+
+// jitterWindow returns how far a scheduled check may be moved from the interval
+func (r *YukConfigReconciler) jitterWindow(interval time.Duration) time.Duration {
+	if r.RequeueJitter <= 0 {
+		return 0
+	}
+	return time.Duration(r.RequeueJitter * float64(interval))
+}
+
+// withJitter moves the next check randomly within the jitter window around the interval,
+// so YukConfigs created together do not keep checking at the same time
+func (r *YukConfigReconciler) withJitter(interval time.Duration) time.Duration {
+	window := r.jitterWindow(interval)
+	if window <= 0 {
+		return interval
+	}
+	return interval - window + rand.N(2*window+1)
+}
@@ -0,0 +1,414 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestYukConfigReconciler_checkInterval(t *testing.T) {
+	tests := []struct {
+		name          string
+		checkInterval *metav1.Duration
+		minimum       time.Duration
+		expected      time.Duration
+	}{
+		{
+			name:     "default",
+			expected: yukv1.DefaultCheckInterval,
+		},
+		{
+			name:          "configured",
+			checkInterval: &metav1.Duration{Duration: time.Minute},
+			minimum:       10 * time.Second,
+			expected:      time.Minute,
+		},
+		{
+			name:          "raised to the minimum",
+			checkInterval: &metav1.Duration{Duration: 100 * time.Millisecond},
+			minimum:       10 * time.Second,
+			expected:      10 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reconciler := &YukConfigReconciler{MinCheckInterval: tt.minimum}
+			yukConfig := &yukv1.YukConfig{Spec: yukv1.YukConfigSpec{CheckInterval: tt.checkInterval}}
+			if interval := reconciler.checkInterval(yukConfig); interval != tt.expected {
+				t.Errorf("Expected interval %v, got %v", tt.expected, interval)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_checkInterval_Annotation(t *testing.T) {
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		expected      time.Duration
+		expectedEvent string
+	}{
+		{
+			name:     "absent",
+			expected: time.Minute,
+		},
+		{
+			name:        "override",
+			annotations: map[string]string{yukv1.CheckIntervalAnnotation: "30m"},
+			expected:    30 * time.Minute,
+		},
+		{
+			name:        "override raised to the minimum",
+			annotations: map[string]string{yukv1.CheckIntervalAnnotation: "1s"},
+			expected:    10 * time.Second,
+		},
+		{
+			name:          "malformed",
+			annotations:   map[string]string{yukv1.CheckIntervalAnnotation: "half an hour"},
+			expected:      time.Minute,
+			expectedEvent: `Warning InvalidCheckInterval Ignoring annotation yuk.rebelops.io/check-interval: "half an hour" is not a positive duration, checking every 1m0s`,
+		},
+		{
+			name:          "not positive",
+			annotations:   map[string]string{yukv1.CheckIntervalAnnotation: "0s"},
+			expected:      time.Minute,
+			expectedEvent: `Warning InvalidCheckInterval Ignoring annotation yuk.rebelops.io/check-interval: "0s" is not a positive duration, checking every 1m0s`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(1)
+			reconciler := &YukConfigReconciler{MinCheckInterval: 10 * time.Second, Recorder: recorder}
+			yukConfig := &yukv1.YukConfig{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+				Spec:       yukv1.YukConfigSpec{CheckInterval: &metav1.Duration{Duration: time.Minute}},
+			}
+			if interval := reconciler.checkInterval(yukConfig); interval != tt.expected {
+				t.Errorf("Expected interval %v, got %v", tt.expected, interval)
+			}
+
+			select {
+			case event := <-recorder.Events:
+				if event != tt.expectedEvent {
+					t.Errorf("Expected event %q, got %q", tt.expectedEvent, event)
+				}
+			default:
+				if tt.expectedEvent != "" {
+					t.Errorf("Expected event %q, got none", tt.expectedEvent)
+				}
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_withJitter(t *testing.T) {
+	// Without jitter the interval is used as is
+	if interval := (&YukConfigReconciler{}).withJitter(time.Minute); interval != time.Minute {
+		t.Errorf("Expected interval 1m0s without jitter, got %v", interval)
+	}
+
+	reconciler := &YukConfigReconciler{RequeueJitter: 0.1}
+	spread := false
+	for i := 0; i < 100; i++ {
+		interval := reconciler.withJitter(time.Minute)
+		if interval < 54*time.Second || interval > 66*time.Second {
+			t.Fatalf("Expected interval within 54s and 1m6s, got %v", interval)
+		}
+		if interval != time.Minute {
+			spread = true
+		}
+	}
+	if !spread {
+		t.Error("Expected jitter to spread the intervals")
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_Jitter(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "project/app", "tags": []string{"v1.0.0"}})
+	}))
+	defer registry.Close()
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					Insecure:       true,
+				},
+			},
+			Git:           yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+			CheckInterval: &metav1.Duration{Duration: 10 * time.Minute},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:        scheme,
+		Recorder:      record.NewFakeRecorder(10),
+		RequeueJitter: 0.1,
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	result, err := reconciler.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if result.RequeueAfter < 9*time.Minute || result.RequeueAfter > 11*time.Minute {
+		t.Errorf("Expected requeue within 9m0s and 11m0s, got %v", result.RequeueAfter)
+	}
+
+	// A check scheduled early by the jitter is not deferred as too early
+	updated := &yukv1.YukConfig{}
+	if err := reconciler.Get(context.Background(), req.NamespacedName, updated); err != nil {
+		t.Fatalf("Failed to get YukConfig: %v", err)
+	}
+	lastChecked := metav1.NewTime(time.Now().Add(-9*time.Minute - 30*time.Second))
+	updated.Status.LastChecked = &lastChecked
+	if err := reconciler.Status().Update(context.Background(), updated); err != nil {
+		t.Fatalf("Failed to update YukConfig status: %v", err)
+	}
+
+	result, err = reconciler.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if result.RequeueAfter < 9*time.Minute {
+		t.Errorf("Expected a check and a requeue of at least 9m0s, got %v", result.RequeueAfter)
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_PostUpdateRequeue(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "project/app", "tags": []string{"v1.1.0"}})
+	}))
+	defer registry.Close()
+
+	upstream := newUpstreamRepository(t, map[string]string{
+		"values.yaml": "image:\n    repository: my-app\n    tag: v1.0.0\n",
+	})
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					Insecure:       true,
+				},
+			},
+			Git: yukv1.GitConfig{
+				Repository: upstream,
+				Branch:     "main",
+				Email:      "test@example.com",
+				Name:       "Test User",
+			},
+			CheckInterval:     &metav1.Duration{Duration: 10 * time.Minute},
+			PostUpdateRequeue: &metav1.Duration{Duration: 30 * time.Second},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:       scheme,
+		CloneBaseDir: t.TempDir(),
+		Recorder:     record.NewFakeRecorder(10),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	getStatus := func() *yukv1.YukConfig {
+		updated := &yukv1.YukConfig{}
+		if err := reconciler.Get(context.Background(), req.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get YukConfig: %v", err)
+		}
+		return updated
+	}
+
+	// The update is verified after the post-update requeue
+	result, err := reconciler.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if result.RequeueAfter != 30*time.Second {
+		t.Errorf("Expected requeue after 30s following the update, got %v", result.RequeueAfter)
+	}
+	updated := getStatus()
+	if updated.Status.CurrentTag != "v1.1.0" || !updated.Status.VerificationPending {
+		t.Fatalf("Expected an update to v1.1.0 pending verification, got tag %q and pending %v",
+			updated.Status.CurrentTag, updated.Status.VerificationPending)
+	}
+
+	// An earlier reconcile waits for the verification check, not the check interval
+	result, err = reconciler.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if result.RequeueAfter <= 0 || result.RequeueAfter > 30*time.Second {
+		t.Errorf("Expected requeue within 30s before the verification check, got %v", result.RequeueAfter)
+	}
+
+	// The verification check returns to the check interval
+	lastChecked := metav1.NewTime(time.Now().Add(-31 * time.Second))
+	updated = getStatus()
+	updated.Status.LastChecked = &lastChecked
+	if err := reconciler.Status().Update(context.Background(), updated); err != nil {
+		t.Fatalf("Failed to update YukConfig status: %v", err)
+	}
+
+	result, err = reconciler.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if result.RequeueAfter != 10*time.Minute {
+		t.Errorf("Expected requeue after 10m0s following the verification check, got %v", result.RequeueAfter)
+	}
+	if updated := getStatus(); updated.Status.VerificationPending {
+		t.Error("Expected the verification check to clear the pending verification")
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_OutsideUpdateWindow(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "project/app", "tags": []string{"v1.1.0"}})
+	}))
+	defer registry.Close()
+
+	values := "image:\n    repository: my-app\n    tag: v1.0.0\n"
+	upstream := newUpstreamRepository(t, map[string]string{"values.yaml": values})
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	// A window of an hour, opening two to three hours from now
+	opens := time.Now().UTC().Truncate(time.Hour).Add(3 * time.Hour)
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					Insecure:       true,
+				},
+			},
+			Git: yukv1.GitConfig{
+				Repository: upstream,
+				Branch:     "main",
+				Email:      "test@example.com",
+				Name:       "Test User",
+			},
+			CheckInterval: &metav1.Duration{Duration: 24 * time.Hour},
+			UpdateWindow: &yukv1.UpdateWindow{
+				Start: opens.Format("15:04"),
+				End:   opens.Add(time.Hour).Format("15:04"),
+			},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:       scheme,
+		CloneBaseDir: t.TempDir(),
+		Recorder:     record.NewFakeRecorder(10),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	result, err := reconciler.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if result.RequeueAfter <= 2*time.Hour || result.RequeueAfter > 3*time.Hour {
+		t.Errorf("Expected requeue when the update window opens in 2h to 3h, got %v", result.RequeueAfter)
+	}
+
+	updated := &yukv1.YukConfig{}
+	if err := reconciler.Get(context.Background(), req.NamespacedName, updated); err != nil {
+		t.Fatalf("Failed to get YukConfig: %v", err)
+	}
+	if updated.Status.LatestTag != "v1.1.0" {
+		t.Errorf("Expected the latest tag v1.1.0 to be recorded, got %q", updated.Status.LatestTag)
+	}
+	if updated.Status.CurrentTag != "v1.0.0" {
+		t.Errorf("Expected the current tag to stay v1.0.0, got %q", updated.Status.CurrentTag)
+	}
+	if updated.Status.UpdateDeferredUntil == nil || !updated.Status.UpdateDeferredUntil.Time.Equal(opens) {
+		t.Errorf("Expected the update to be deferred until %v, got %v", opens, updated.Status.UpdateDeferredUntil)
+	}
+	for _, condition := range updated.Status.Conditions {
+		if condition.Type == "Ready" && condition.Reason != ReasonOutsideWindow {
+			t.Errorf("Expected Ready reason %s, got %s", ReasonOutsideWindow, condition.Reason)
+		}
+	}
+
+	out, err := exec.Command("git", "-C", upstream, "show", "main:values.yaml").Output()
+	if err != nil {
+		t.Fatalf("git show failed: %v", err)
+	}
+	if string(out) != values {
+		t.Errorf("Expected no push outside the update window, got:\n%s", out)
+	}
+}
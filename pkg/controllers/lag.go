@@ -0,0 +1,100 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+)
+
+// updateLag returns how long the current tag (or the deployed tag, see lagTag) has been
+// behind the latest tag, measured from when the latest tag was first observed. It is 0
+// while that tag is the latest one.
+func updateLag(yukConfig *yukv1.YukConfig, now time.Time) time.Duration {
+	status := yukConfig.Status
+	if status.LatestTag == "" || lagTag(yukConfig) == status.LatestTag || status.LatestTagFirstSeen == nil {
+		return 0
+	}
+
+	lag := now.Sub(status.LatestTagFirstSeen.Time)
+	if lag < 0 {
+		return 0
+	}
+	return lag
+}
+
+// recordTimeToUpdate records the time from the push of the image of a pushed update to its
+// commit in status.lastTimeToUpdate and yuk_time_to_update_seconds. Nothing is recorded
+// when the repository does not report the push time or it cannot be looked up.
+func (r *YukConfigReconciler) recordTimeToUpdate(ctx context.Context, yukConfig *yukv1.YukConfig, source imageSource, tag string, committedAt time.Time, creds *credentials) {
+	yukConfig.Status.LastTimeToUpdate = nil
+
+	details, err := r.getImageDetails(ctx, source.repository, tag, creds.repository(source.name))
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to look up the push time of the image", "tag", tag)
+		return
+	}
+	if details == nil || details.ImagePushedAt == nil {
+		return
+	}
+
+	timeToUpdate := max(committedAt.Sub(*details.ImagePushedAt), 0)
+	yukConfig.Status.LastTimeToUpdate = &metav1.Duration{Duration: timeToUpdate}
+	yukmetrics.TimeToUpdate.With(prometheus.Labels{
+		"namespace":       yukConfig.Namespace,
+		"name":            yukConfig.Name,
+		"repository_name": repositoryName(source.repository),
+	}).Observe(timeToUpdate.Seconds())
+}
+
+// versionsBehind returns how many versions the current tag is behind the latest tag, as the
+// difference of the most significant differing part (1.2.3 -> 1.4.0 is 2 minor versions,
+// 1.9.0 -> 2.0.0 is 1 major version). It returns false when either tag is not a semantic
+// version.
+func versionsBehind(currentTag, latestTag string) (uint64, bool) {
+	current, err := semver.NewVersion(currentTag)
+	if err != nil {
+		return 0, false
+	}
+	latest, err := semver.NewVersion(latestTag)
+	if err != nil {
+		return 0, false
+	}
+
+	if !latest.GreaterThan(current) {
+		return 0, true
+	}
+
+	switch {
+	case latest.Major() != current.Major():
+		return latest.Major() - current.Major(), true
+	case latest.Minor() != current.Minor():
+		return latest.Minor() - current.Minor(), true
+	case latest.Patch() != current.Patch():
+		return latest.Patch() - current.Patch(), true
+	}
+	// Only the prerelease differs
+	return 1, true
+}
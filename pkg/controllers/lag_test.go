@@ -0,0 +1,301 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	"github.com/rebelopsio/yuk/pkg/git"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+)
+
+func TestUpdateLag(t *testing.T) {
+	now := time.Now()
+	firstSeen := metav1.NewTime(now.Add(-90 * time.Minute))
+
+	tests := []struct {
+		name     string
+		status   yukv1.YukConfigStatus
+		expected time.Duration
+	}{
+		{
+			name:     "behind the latest tag",
+			status:   yukv1.YukConfigStatus{CurrentTag: "v1.0.0", LatestTag: "v1.1.0", LatestTagFirstSeen: &firstSeen},
+			expected: 90 * time.Minute,
+		},
+		{
+			name:   "up to date",
+			status: yukv1.YukConfigStatus{CurrentTag: "v1.1.0", LatestTag: "v1.1.0", LatestTagFirstSeen: &firstSeen},
+		},
+		{
+			name:   "latest tag not observed yet",
+			status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yukConfig := &yukv1.YukConfig{Status: tt.status}
+			if lag := updateLag(yukConfig, now); lag != tt.expected {
+				t.Errorf("Expected lag %v, got %v", tt.expected, lag)
+			}
+		})
+	}
+}
+
+func TestVersionsBehind(t *testing.T) {
+	tests := []struct {
+		current  string
+		latest   string
+		expected uint64
+		semver   bool
+	}{
+		{current: "v1.2.3", latest: "v1.2.5", expected: 2, semver: true},
+		{current: "1.2.3", latest: "1.4.0", expected: 2, semver: true},
+		{current: "v1.9.0", latest: "v2.0.0", expected: 1, semver: true},
+		{current: "v1.0.0-rc.1", latest: "v1.0.0", expected: 1, semver: true},
+		{current: "v1.1.0", latest: "v1.1.0", expected: 0, semver: true},
+		{current: "v1.2.0", latest: "v1.1.0", expected: 0, semver: true},
+		{current: "main-abc123", latest: "main-def456"},
+		{current: "v1.0.0", latest: "latest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.current+"->"+tt.latest, func(t *testing.T) {
+			behind, ok := versionsBehind(tt.current, tt.latest)
+			if ok != tt.semver {
+				t.Fatalf("Expected semver %v, got %v", tt.semver, ok)
+			}
+			if behind != tt.expected {
+				t.Errorf("Expected %d versions behind, got %d", tt.expected, behind)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_UpdateLagMetrics(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "lag-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeECR,
+				ECR:  &yukv1.ECRConfig{Region: "us-west-2", RepositoryName: "my-app"},
+			},
+			Git:           yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+			UpdateTargets: []yukv1.UpdateTarget{{File: "values.yaml", YAMLPath: "image.tag"}},
+			DryRun:        true,
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+	}
+
+	gitOperator := newFakeGitOperator(t, map[string]string{"values.yaml": "image:\n    tag: v1.0.0\n"})
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+		NewECRClient: func(region string, opts ...ecr.Option) TagResolver {
+			return &fakeTagResolver{latestTags: map[string]string{"my-app:": "v1.3.0"}}
+		},
+		NewGitClient: func(config yukv1.GitConfig, opts ...git.Option) GitOperator {
+			return gitOperator
+		},
+	}
+
+	labels := prometheus.Labels{"namespace": "default", "name": "lag-config", "repository_name": "my-app"}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "lag-config", Namespace: "default"}}
+	reconcile := func(mutate func(*yukv1.YukConfig)) {
+		t.Helper()
+		if mutate != nil {
+			current := &yukv1.YukConfig{}
+			if err := reconciler.Get(ctx, req.NamespacedName, current); err != nil {
+				t.Fatalf("Failed to get YukConfig: %v", err)
+			}
+			mutate(current)
+			// Force the next check regardless of the check interval
+			current.Status.LastChecked = nil
+			status := current.Status
+			if err := reconciler.Update(ctx, current); err != nil {
+				t.Fatalf("Failed to update YukConfig: %v", err)
+			}
+			current.Status = status
+			if err := reconciler.Status().Update(ctx, current); err != nil {
+				t.Fatalf("Failed to update status: %v", err)
+			}
+		}
+		if _, err := reconciler.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+	}
+
+	// The dry run leaves the current tag behind the newly observed latest tag
+	reconcile(nil)
+	if lag := testutil.ToFloat64(yukmetrics.UpdateLag.With(labels)); lag < 0 || lag > 60 {
+		t.Errorf("Expected a lag of less than a minute, got %v", lag)
+	}
+	if behind := testutil.ToFloat64(yukmetrics.VersionsBehind.With(labels)); behind != 3 {
+		t.Errorf("Expected 3 versions behind, got %v", behind)
+	}
+
+	// The lag keeps growing from when the latest tag was first seen
+	reconcile(func(current *yukv1.YukConfig) {
+		firstSeen := metav1.NewTime(time.Now().Add(-time.Hour))
+		current.Status.LatestTagFirstSeen = &firstSeen
+	})
+	if lag := testutil.ToFloat64(yukmetrics.UpdateLag.With(labels)); lag < 3600 || lag > 3660 {
+		t.Errorf("Expected a lag of about an hour, got %v", lag)
+	}
+
+	// Once the update is pushed, the lag drops to 0
+	reconcile(func(current *yukv1.YukConfig) {
+		current.Spec.DryRun = false
+	})
+	if lag := testutil.ToFloat64(yukmetrics.UpdateLag.With(labels)); lag != 0 {
+		t.Errorf("Expected no lag after the update, got %v", lag)
+	}
+	if behind := testutil.ToFloat64(yukmetrics.VersionsBehind.With(labels)); behind != 0 {
+		t.Errorf("Expected 0 versions behind after the update, got %v", behind)
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_TimeToUpdate(t *testing.T) {
+	pushedAt := time.Now().Add(-10 * time.Minute)
+
+	tests := []struct {
+		name     string
+		resolver TagResolver
+		expected bool
+	}{
+		{
+			name: "push time reported",
+			resolver: &fakeImageDetailsResolver{
+				fakeTagResolver: &fakeTagResolver{
+					latestTags: map[string]string{"my-app:": "v1.1.0"},
+					digests:    map[string]string{"my-app:v1.1.0": "sha256:" + strings.Repeat("b", 64)},
+				},
+				pushedAt: pushedAt,
+			},
+			expected: true,
+		},
+		{
+			name:     "push time unavailable",
+			resolver: &fakeTagResolver{latestTags: map[string]string{"my-app:": "v1.1.0"}},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = clientgoscheme.AddToScheme(scheme)
+			_ = yukv1.AddToScheme(scheme)
+
+			name := fmt.Sprintf("time-to-update-%d", i)
+			yukConfig := &yukv1.YukConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: yukv1.YukConfigSpec{
+					Repository: yukv1.RepositoryConfig{
+						Type: RepositoryTypeECR,
+						ECR:  &yukv1.ECRConfig{Region: "us-west-2", RepositoryName: "my-app"},
+					},
+					Git:           yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+					UpdateTargets: []yukv1.UpdateTarget{{File: "values.yaml", YAMLPath: "image.tag"}},
+				},
+				Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+			}
+
+			gitOperator := newFakeGitOperator(t, map[string]string{"values.yaml": "image:\n    tag: v1.0.0\n"})
+			reconciler := &YukConfigReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+					WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(10),
+				NewECRClient: func(region string, opts ...ecr.Option) TagResolver {
+					return tt.resolver
+				},
+				NewGitClient: func(config yukv1.GitConfig, opts ...git.Option) GitOperator {
+					return gitOperator
+				},
+			}
+
+			ctx := context.Background()
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: "default"}}
+			if _, err := reconciler.Reconcile(ctx, req); err != nil {
+				t.Fatalf("Reconcile failed: %v", err)
+			}
+
+			updated := &yukv1.YukConfig{}
+			if err := reconciler.Get(ctx, req.NamespacedName, updated); err != nil {
+				t.Fatalf("Failed to get YukConfig: %v", err)
+			}
+			if updated.Status.CurrentTag != "v1.1.0" {
+				t.Fatalf("Expected the update to v1.1.0 to be pushed, got %q", updated.Status.CurrentTag)
+			}
+
+			metric := &dto.Metric{}
+			histogram := yukmetrics.TimeToUpdate.With(prometheus.Labels{"namespace": "default", "name": name, "repository_name": "my-app"})
+			if err := histogram.(prometheus.Metric).Write(metric); err != nil {
+				t.Fatalf("Failed to read the histogram: %v", err)
+			}
+
+			if !tt.expected {
+				if updated.Status.LastTimeToUpdate != nil {
+					t.Errorf("Expected no time to update, got %v", updated.Status.LastTimeToUpdate.Duration)
+				}
+				if count := metric.GetHistogram().GetSampleCount(); count != 0 {
+					t.Errorf("Expected no observation, got %d", count)
+				}
+				return
+			}
+
+			// The commit follows the push by about ten minutes
+			if updated.Status.LastTimeToUpdate == nil {
+				t.Fatal("Expected the time to update to be recorded")
+			}
+			if d := updated.Status.LastTimeToUpdate.Duration; d < 10*time.Minute || d > 11*time.Minute {
+				t.Errorf("Expected a time to update of about 10m, got %v", d)
+			}
+			if count := metric.GetHistogram().GetSampleCount(); count != 1 {
+				t.Fatalf("Expected one observation, got %d", count)
+			}
+			if sum := metric.GetHistogram().GetSampleSum(); sum < 600 || sum > 660 {
+				t.Errorf("Expected an observation of about 600s, got %v", sum)
+			}
+		})
+	}
+}
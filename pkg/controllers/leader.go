@@ -0,0 +1,41 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+)
+
+// LeaderGauge reports whether this replica is the active leader through the yuk_leader
+// metric. The manager starts it once the replica is elected (or at startup without
+// leader election), so standby replicas report 0.
+type LeaderGauge struct{}
+
+// Start marks this replica as the leader until the manager stops
+func (g *LeaderGauge) Start(ctx context.Context) error {
+	yukmetrics.Leader.Set(1)
+	<-ctx.Done()
+	yukmetrics.Leader.Set(0)
+	return nil
+}
+
+// NeedLeaderElection only starts the gauge on the leader
+func (g *LeaderGauge) NeedLeaderElection() bool {
+	return true
+}
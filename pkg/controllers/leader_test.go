@@ -0,0 +1,57 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+)
+
+func TestLeaderGauge(t *testing.T) {
+	gauge := &LeaderGauge{}
+	if !gauge.NeedLeaderElection() {
+		t.Error("Expected the leader gauge to need leader election")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = gauge.Start(ctx)
+		close(done)
+	}()
+
+	// The gauge reports 1 while this replica leads
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(yukmetrics.Leader) != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := testutil.ToFloat64(yukmetrics.Leader); got != 1 {
+		t.Errorf("Expected yuk_leader 1 on the leader, got %v", got)
+	}
+
+	// And 0 once it stops leading
+	cancel()
+	<-done
+	if got := testutil.ToFloat64(yukmetrics.Leader); got != 0 {
+		t.Errorf("Expected yuk_leader 0 after stepping down, got %v", got)
+	}
+}
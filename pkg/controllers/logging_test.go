@@ -0,0 +1,100 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestYukConfigReconciler_Reconcile_LogCorrelation(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-config",
+			Namespace: "default",
+		},
+		Spec: yukv1.YukConfigSpec{
+			Disabled: true,
+			Git: yukv1.GitConfig{
+				Repository: "https://github.com/example/repo.git",
+			},
+		},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).Build(),
+		Scheme: scheme,
+	}
+
+	var buf bytes.Buffer
+	ctx := log.IntoContext(context.TODO(), zap.New(zap.WriteTo(&buf), zap.JSONEncoder()))
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("Expected at least two log lines, got %q", buf.String())
+	}
+
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to parse log line %q: %v", line, err)
+		}
+		for key, expected := range map[string]string{
+			"namespace":  "default",
+			"name":       "test-config",
+			"repository": "https://github.com/example/repo.git",
+		} {
+			if entry[key] != expected {
+				t.Errorf("Expected %s %q in log line %q, got %v", key, expected, line, entry[key])
+			}
+		}
+	}
+
+	var last map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil {
+		t.Fatalf("Failed to parse log line: %v", err)
+	}
+	if last["msg"] != "Reconcile finished" || last["result"] != "skipped" {
+		t.Errorf("Expected the final line to report the skipped result, got %v", last)
+	}
+	if _, ok := last["duration"]; !ok {
+		t.Errorf("Expected the final line to report the duration, got %v", last)
+	}
+}
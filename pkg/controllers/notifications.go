@@ -0,0 +1,84 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+	"github.com/rebelopsio/yuk/pkg/notify"
+)
+
+// notifiers returns the notifiers whose webhooks are in the credentials
+func (c *credentials) notifiers() []notify.Notifier {
+	var notifiers []notify.Notifier
+	if c.slackWebhookURL != "" {
+		notifiers = append(notifiers, notify.NewSlackNotifier(c.slackWebhookURL))
+	}
+	return notifiers
+}
+
+// notificationEnabled reports whether notifications are sent for the event type
+func notificationEnabled(yukConfig *yukv1.YukConfig, eventType notify.EventType) bool {
+	notifications := yukConfig.Spec.Notifications
+	if notifications == nil {
+		return false
+	}
+	if len(notifications.Events) == 0 {
+		return true
+	}
+
+	for _, event := range notifications.Events {
+		if event == string(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// notify sends the event to every notifier, filling in the YukConfig it is about.
+// Notification failures are logged and counted but never fail the reconcile.
+func (r *YukConfigReconciler) notify(ctx context.Context, yukConfig *yukv1.YukConfig, notifiers []notify.Notifier, event notify.Event) {
+	if len(notifiers) == 0 || !notificationEnabled(yukConfig, event.Type) {
+		return
+	}
+
+	logger := log.FromContext(ctx)
+
+	event.Namespace = yukConfig.Namespace
+	event.Name = yukConfig.Name
+	event.Repository = repositoryName(defaultSource(yukConfig).repository)
+
+	for _, notifier := range notifiers {
+		result := yukmetrics.NotificationSuccess
+		if err := notifier.Notify(ctx, event); err != nil {
+			logger.Error(err, "Failed to send notification", "notifier", notifier.Name(), "event", event.Type)
+			result = yukmetrics.NotificationError
+		}
+
+		yukmetrics.NotificationsTotal.With(prometheus.Labels{
+			"notifier":  notifier.Name(),
+			"namespace": yukConfig.Namespace,
+			"name":      yukConfig.Name,
+			"result":    string(result),
+		}).Inc()
+	}
+}
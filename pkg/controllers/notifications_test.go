@@ -0,0 +1,177 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+	"github.com/rebelopsio/yuk/pkg/notify"
+)
+
+func TestNotificationEnabled(t *testing.T) {
+	tests := []struct {
+		name          string
+		notifications *yukv1.NotificationsConfig
+		eventType     notify.EventType
+		expected      bool
+	}{
+		{
+			name:      "not configured",
+			eventType: notify.EventUpdate,
+		},
+		{
+			name:          "all events by default",
+			notifications: &yukv1.NotificationsConfig{},
+			eventType:     notify.EventError,
+			expected:      true,
+		},
+		{
+			name:          "selected event",
+			notifications: &yukv1.NotificationsConfig{Events: []string{"update"}},
+			eventType:     notify.EventUpdate,
+			expected:      true,
+		},
+		{
+			name:          "filtered event",
+			notifications: &yukv1.NotificationsConfig{Events: []string{"update"}},
+			eventType:     notify.EventError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yukConfig := &yukv1.YukConfig{Spec: yukv1.YukConfigSpec{Notifications: tt.notifications}}
+			if enabled := notificationEnabled(yukConfig, tt.eventType); enabled != tt.expected {
+				t.Errorf("Expected enabled %v, got %v", tt.expected, enabled)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_notify_Failure(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer webhook.Close()
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "notify-failure", Namespace: "default"},
+		Spec:       yukv1.YukConfigSpec{Notifications: &yukv1.NotificationsConfig{}},
+	}
+
+	reconciler := &YukConfigReconciler{}
+	creds := &credentials{slackWebhookURL: webhook.URL}
+
+	// A failed notification is only counted
+	reconciler.notify(context.Background(), yukConfig, creds.notifiers(), notify.Event{Type: notify.EventUpdate, NewTag: "v1.1.0"})
+
+	failures := testutil.ToFloat64(yukmetrics.NotificationsTotal.With(prometheus.Labels{
+		"notifier":  "slack",
+		"namespace": "default",
+		"name":      "notify-failure",
+		"result":    string(yukmetrics.NotificationError),
+	}))
+	if failures != 1 {
+		t.Errorf("Expected 1 failed notification, got %v", failures)
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_ErrorNotification(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer registry.Close()
+
+	var messages []string
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message struct {
+			Text string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&message)
+		messages = append(messages, message.Text)
+	}))
+	defer webhook.Close()
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "default"},
+		Data:       map[string][]byte{"webhookURL": []byte(webhook.URL + "\n")},
+	}
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					Insecure:       true,
+				},
+			},
+			Git: yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+			Notifications: &yukv1.NotificationsConfig{
+				Slack:  &yukv1.SlackConfig{WebhookURLRef: yukv1.SecretKeySelector{Name: "slack", Key: "webhookURL"}},
+				Events: []string{"error"},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig, secret).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme: scheme,
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 notification, got %d: %v", len(messages), messages)
+	}
+	for _, expected := range []string{"Update failed for default/test-config", "project/app", "`v1.0.0`", "Repository check failed"} {
+		if !strings.Contains(messages[0], expected) {
+			t.Errorf("Expected notification to contain %q, got:\n%s", expected, messages[0])
+		}
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/git"
+)
+
+// Default pull request templates
+const (
+	defaultPullRequestTitle = "Update container image to {{ .Tag }}"
+	defaultPullRequestBody  = "Automated update of {{ .Namespace }}/{{ .Name }} from {{ .PreviousTag }} to {{ .Tag }}."
+)
+
+// pullRequestData is the data available to the pull request templates
+type pullRequestData struct {
+	Tag         string
+	PreviousTag string
+	Name        string
+	Namespace   string
+	Branch      string
+}
+
+// pullRequestEnabled reports whether updates are proposed through pull requests
+func pullRequestEnabled(yukConfig *yukv1.YukConfig) bool {
+	return yukConfig.Spec.Git.PullRequest != nil && yukConfig.Spec.Git.PullRequest.Enabled
+}
+
+// pullRequestOptions renders the pull request for an update pushed to the given branch.
+// The pull request is opened against base, or the configured base branch when empty.
+func pullRequestOptions(yukConfig *yukv1.YukConfig, tag, branch, base string) (git.PullRequestOptions, error) {
+	config := yukConfig.Spec.Git.PullRequest
+
+	if base == "" {
+		base = config.BaseBranch
+	}
+	if base == "" {
+		base = yukConfig.Spec.Git.Branch
+	}
+	if base == "" {
+		base = yukv1.DefaultBranch
+	}
+
+	data := pullRequestData{
+		Tag:         tag,
+		PreviousTag: yukConfig.Status.CurrentTag,
+		Name:        yukConfig.Name,
+		Namespace:   yukConfig.Namespace,
+		Branch:      branch,
+	}
+
+	title, err := renderTemplate("title", config.TitleTemplate, defaultPullRequestTitle, data)
+	if err != nil {
+		return git.PullRequestOptions{}, err
+	}
+
+	body, err := renderTemplate("body", config.BodyTemplate, defaultPullRequestBody, data)
+	if err != nil {
+		return git.PullRequestOptions{}, err
+	}
+
+	return git.PullRequestOptions{
+		Head:  branch,
+		Base:  base,
+		Title: title,
+		Body:  body,
+	}, nil
+}
+
+// renderTemplate renders a pull request template, falling back to the default when empty
+func renderTemplate(name, text, defaultText string, data pullRequestData) (string, error) {
+	if text == "" {
+		text = defaultText
+	}
+
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid pull request %s template: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render pull request %s template: %w", name, err)
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestPullRequestOptions(t *testing.T) {
+	tests := []struct {
+		name          string
+		branch        string
+		config        yukv1.PullRequestConfig
+		expectedBase  string
+		expectedTitle string
+		expectedBody  string
+		shouldErr     bool
+	}{
+		{
+			name:          "defaults",
+			config:        yukv1.PullRequestConfig{Enabled: true},
+			expectedBase:  "main",
+			expectedTitle: "Update container image to v1.1.0",
+			expectedBody:  "Automated update of default/my-app from v1.0.0 to v1.1.0.",
+		},
+		{
+			name:          "configured branch",
+			branch:        "production",
+			config:        yukv1.PullRequestConfig{Enabled: true},
+			expectedBase:  "production",
+			expectedTitle: "Update container image to v1.1.0",
+			expectedBody:  "Automated update of default/my-app from v1.0.0 to v1.1.0.",
+		},
+		{
+			name:   "custom templates and base branch",
+			branch: "production",
+			config: yukv1.PullRequestConfig{
+				Enabled:       true,
+				BaseBranch:    "release",
+				TitleTemplate: "chore({{ .Name }}): bump to {{ .Tag }}",
+				BodyTemplate:  "Branch {{ .Branch }} updates {{ .PreviousTag }} to {{ .Tag }}",
+			},
+			expectedBase:  "release",
+			expectedTitle: "chore(my-app): bump to v1.1.0",
+			expectedBody:  "Branch yuk/my-app-v1.1.0 updates v1.0.0 to v1.1.0",
+		},
+		{
+			name:      "invalid template",
+			config:    yukv1.PullRequestConfig{Enabled: true, TitleTemplate: "{{ .Tag"},
+			shouldErr: true,
+		},
+		{
+			name:      "unknown field",
+			config:    yukv1.PullRequestConfig{Enabled: true, BodyTemplate: "{{ .Digest }}"},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			yukConfig := &yukv1.YukConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-app", Namespace: "default"},
+				Spec: yukv1.YukConfigSpec{
+					Git: yukv1.GitConfig{Branch: tt.branch, PullRequest: &config},
+				},
+				Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+			}
+
+			opts, err := pullRequestOptions(yukConfig, "v1.1.0", "yuk/my-app-v1.1.0", "")
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if opts.Head != "yuk/my-app-v1.1.0" {
+				t.Errorf("Expected head yuk/my-app-v1.1.0, got %s", opts.Head)
+			}
+			if opts.Base != tt.expectedBase {
+				t.Errorf("Expected base %s, got %s", tt.expectedBase, opts.Base)
+			}
+			if opts.Title != tt.expectedTitle {
+				t.Errorf("Expected title %q, got %q", tt.expectedTitle, opts.Title)
+			}
+			if opts.Body != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, opts.Body)
+			}
+		})
+	}
+}
+
+func TestUpdateStrategy(t *testing.T) {
+	tests := []struct {
+		name        string
+		strategy    string
+		dryRun      bool
+		pullRequest *yukv1.PullRequestConfig
+		expected    string
+	}{
+		{
+			name:     "not set",
+			expected: "",
+		},
+		{
+			name:        "pull requests enabled",
+			pullRequest: &yukv1.PullRequestConfig{Enabled: true},
+			expected:    yukv1.UpdateStrategyPullRequest,
+		},
+		{
+			name:        "pull requests disabled",
+			pullRequest: &yukv1.PullRequestConfig{},
+			expected:    "",
+		},
+		{
+			name:        "explicit strategy",
+			strategy:    yukv1.UpdateStrategyApproval,
+			pullRequest: &yukv1.PullRequestConfig{Enabled: true},
+			expected:    yukv1.UpdateStrategyApproval,
+		},
+		{
+			name:        "dry run",
+			strategy:    yukv1.UpdateStrategyAutoPush,
+			dryRun:      true,
+			pullRequest: &yukv1.PullRequestConfig{Enabled: true},
+			expected:    yukv1.UpdateStrategyDryRun,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yukConfig := &yukv1.YukConfig{
+				Spec: yukv1.YukConfigSpec{
+					UpdateStrategy: tt.strategy,
+					DryRun:         tt.dryRun,
+					Git:            yukv1.GitConfig{PullRequest: tt.pullRequest},
+				},
+			}
+
+			if strategy := updateStrategy(yukConfig); strategy != tt.expected {
+				t.Errorf("Expected strategy %q, got %q", tt.expected, strategy)
+			}
+		})
+	}
+}
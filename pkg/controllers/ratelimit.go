@@ -0,0 +1,122 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+)
+
+// CheckRateLimiter limits how often repositories are checked with token buckets: a global
+// bucket shared by all YukConfigs and one bucket per repository, so a YukConfig with a
+// short check interval cannot exhaust a registry quota shared with other namespaces.
+type CheckRateLimiter struct {
+	global    *rate.Limiter
+	repoLimit rate.Limit
+	repoBurst int
+	now       func() time.Time
+
+	mu           sync.Mutex
+	repositories map[string]*rate.Limiter
+}
+
+// NewCheckRateLimiter creates a rate limiter allowing globalRate checks per second across
+// all repositories and repoRate checks per second of each repository, with bursts of up
+// to the given number of checks. A rate of 0 disables the corresponding limit.
+func NewCheckRateLimiter(globalRate float64, globalBurst int, repoRate float64, repoBurst int) *CheckRateLimiter {
+	return &CheckRateLimiter{
+		global:       rate.NewLimiter(limitOrInf(globalRate), max(globalBurst, 1)),
+		repoLimit:    limitOrInf(repoRate),
+		repoBurst:    max(repoBurst, 1),
+		now:          time.Now,
+		repositories: make(map[string]*rate.Limiter),
+	}
+}
+
+// limitOrInf returns the rate limit for a rate per second, unlimited when it is not positive
+func limitOrInf(perSecond float64) rate.Limit {
+	if perSecond <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(perSecond)
+}
+
+// reserve takes a global token and a repository token for a check of each of the given
+// repositories. When any bucket is empty, no token is taken and the delay until the
+// checks are allowed is returned; otherwise it returns 0.
+func (l *CheckRateLimiter) reserve(repositories []string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var reservations []*rate.Reservation
+	var delay time.Duration
+	for _, repository := range repositories {
+		limiter, ok := l.repositories[repository]
+		if !ok {
+			limiter = rate.NewLimiter(l.repoLimit, l.repoBurst)
+			l.repositories[repository] = limiter
+		}
+
+		for _, reservation := range []*rate.Reservation{l.global.ReserveN(now, 1), limiter.ReserveN(now, 1)} {
+			reservations = append(reservations, reservation)
+			delay = max(delay, reservation.DelayFrom(now))
+		}
+	}
+
+	// Return the tokens when the checks have to wait
+	if delay > 0 {
+		for i := len(reservations) - 1; i >= 0; i-- {
+			reservations[i].CancelAt(now)
+		}
+	}
+
+	return delay
+}
+
+// rateLimitChecks reserves the checks of every source of the YukConfig with the check
+// rate limiter. It returns the delay until the checks are allowed, or 0 when they may
+// proceed now.
+func (r *YukConfigReconciler) rateLimitChecks(yukConfig *yukv1.YukConfig) time.Duration {
+	if r.CheckRateLimiter == nil {
+		return 0
+	}
+
+	sources := imageSources(yukConfig)
+	keys := make([]string, 0, len(sources))
+	for _, source := range sources {
+		keys = append(keys, repositoryKey(source.repository))
+	}
+
+	delay := r.CheckRateLimiter.reserve(keys)
+	if delay > 0 {
+		for _, source := range sources {
+			yukmetrics.RepositoryCheckRateLimited.With(prometheus.Labels{
+				"repository_type": source.repository.Type,
+				"repository_name": repositoryName(source.repository),
+			}).Inc()
+		}
+	}
+
+	return delay
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+)
+
+func TestCheckRateLimiter_reserve(t *testing.T) {
+	now := time.Now()
+
+	// One check per minute of each repository, unlimited globally
+	limiter := NewCheckRateLimiter(0, 0, 1.0/60, 1)
+	limiter.now = func() time.Time { return now }
+
+	if delay := limiter.reserve([]string{"ecr/us-east-1/my-app"}); delay != 0 {
+		t.Errorf("Expected the first check to be permitted, got delay %s", delay)
+	}
+	if delay := limiter.reserve([]string{"ecr/us-east-1/my-app"}); delay <= 0 || delay > time.Minute {
+		t.Errorf("Expected the second check to be deferred by up to a minute, got %s", delay)
+	}
+	if delay := limiter.reserve([]string{"ecr/us-east-1/other-app"}); delay != 0 {
+		t.Errorf("Expected a check of another repository to be permitted, got delay %s", delay)
+	}
+
+	// A deferred check takes no tokens, so other repositories are not held back by it
+	if delay := limiter.reserve([]string{"ecr/us-east-1/new-app", "ecr/us-east-1/my-app"}); delay == 0 {
+		t.Error("Expected checks including a limited repository to be deferred")
+	}
+	if delay := limiter.reserve([]string{"ecr/us-east-1/new-app"}); delay != 0 {
+		t.Errorf("Expected the tokens of a deferred check to be returned, got delay %s", delay)
+	}
+
+	// The bucket refills over time
+	now = now.Add(time.Minute)
+	if delay := limiter.reserve([]string{"ecr/us-east-1/my-app"}); delay != 0 {
+		t.Errorf("Expected the check to be permitted after a minute, got delay %s", delay)
+	}
+}
+
+func TestCheckRateLimiter_reserve_Global(t *testing.T) {
+	now := time.Now()
+
+	// Two checks per minute across all repositories, unlimited per repository
+	limiter := NewCheckRateLimiter(2.0/60, 2, 0, 0)
+	limiter.now = func() time.Time { return now }
+
+	for _, repository := range []string{"ecr/us-east-1/a", "ecr/us-east-1/b"} {
+		if delay := limiter.reserve([]string{repository}); delay != 0 {
+			t.Errorf("Expected the check of %s to be permitted, got delay %s", repository, delay)
+		}
+	}
+	if delay := limiter.reserve([]string{"ecr/us-east-1/c"}); delay <= 0 {
+		t.Errorf("Expected the check to be deferred once the global bucket is empty, got %s", delay)
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_RateLimited(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	// Two YukConfigs in different namespaces watching the same repository
+	var yukConfigs []*yukv1.YukConfig
+	for _, namespace := range []string{"team-a", "team-b"} {
+		yukConfigs = append(yukConfigs, &yukv1.YukConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "ratelimited-config", Namespace: namespace},
+			Spec: yukv1.YukConfigSpec{
+				Repository: yukv1.RepositoryConfig{
+					Type: RepositoryTypeECR,
+					ECR:  &yukv1.ECRConfig{Region: "us-east-1", RepositoryName: "ratelimited-app"},
+				},
+			},
+		})
+	}
+
+	resolver := &fakeTagResolver{}
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfigs[0], yukConfigs[1]).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:           scheme,
+		Recorder:         record.NewFakeRecorder(10),
+		CheckRateLimiter: NewCheckRateLimiter(0, 0, 1.0/60, 1),
+		NewECRClient: func(region string, opts ...ecr.Option) TagResolver {
+			resolver.regions = append(resolver.regions, region)
+			return resolver
+		},
+	}
+
+	ctx := context.Background()
+
+	// The first check reaches ECR
+	first := ctrl.Request{NamespacedName: types.NamespacedName{Name: "ratelimited-config", Namespace: "team-a"}}
+	if _, err := reconciler.Reconcile(ctx, first); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(resolver.regions) != 1 {
+		t.Fatalf("Expected 1 ECR lookup, got %d", len(resolver.regions))
+	}
+
+	// The second check of the same repository is deferred without calling ECR
+	second := ctrl.Request{NamespacedName: types.NamespacedName{Name: "ratelimited-config", Namespace: "team-b"}}
+	res, err := reconciler.Reconcile(ctx, second)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(resolver.regions) != 1 {
+		t.Errorf("Expected no further ECR lookup, got %d", len(resolver.regions))
+	}
+	if res.RequeueAfter <= 0 || res.RequeueAfter > time.Minute {
+		t.Errorf("Expected a requeue within a minute, got %s", res.RequeueAfter)
+	}
+
+	limited := yukmetrics.RepositoryCheckRateLimited.With(prometheus.Labels{
+		"repository_type": RepositoryTypeECR,
+		"repository_name": "ratelimited-app",
+	})
+	if got := testutil.ToFloat64(limited); got != 1 {
+		t.Errorf("Expected 1 rate limited check, got %v", got)
+	}
+
+	// The deferred check is not recorded as a check
+	updated := &yukv1.YukConfig{}
+	if err := reconciler.Get(ctx, second.NamespacedName, updated); err != nil {
+		t.Fatalf("Failed to get YukConfig: %v", err)
+	}
+	if updated.Status.LastChecked != nil {
+		t.Errorf("Expected the deferred check not to be recorded, got %v", updated.Status.LastChecked)
+	}
+}
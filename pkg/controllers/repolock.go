@@ -0,0 +1,110 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// repositoryBusyRetryDelay is how long a reconcile waits for the update of a Git repository
+// by another YukConfig before it is retried
+const repositoryBusyRetryDelay = 10 * time.Second
+
+// errRepositoryBusy is returned when another YukConfig is updating the same Git repository
+var errRepositoryBusy = errors.New("git repository is being updated by another YukConfig")
+
+// RepositoryLocks makes sure only one YukConfig updates a Git repository at a time, so
+// YukConfigs sharing a repository do not clone and push concurrently only to have their
+// pushes rejected. Repositories are identified by their normalized URL.
+type RepositoryLocks struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+// NewRepositoryLocks creates the locks of the Git repositories updated by the controller
+func NewRepositoryLocks() *RepositoryLocks {
+	return &RepositoryLocks{
+		held: make(map[string]bool),
+	}
+}
+
+// TryLock takes the lock of a Git repository without waiting. It returns the function
+// releasing the lock, or false when the repository is locked already.
+func (l *RepositoryLocks) TryLock(repository string) (func(), bool) {
+	key := normalizeRepositoryURL(repository)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.held[key] {
+		return nil, false
+	}
+	l.held[key] = true
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, key)
+	}, true
+}
+
+// normalizeRepositoryURL returns the host and path of a Git repository URL, so the HTTPS
+// and SSH URLs of a repository (e.g. "https://github.com/org/repo.git" and
+// "git@github.com:org/repo") are the same repository
+func normalizeRepositoryURL(repository string) string {
+	repository = strings.TrimSpace(repository)
+
+	var host, path string
+	if parsed, err := url.Parse(repository); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+		host, path = parsed.Hostname(), parsed.Path
+	} else if userHost, scpPath, found := strings.Cut(repository, ":"); found {
+		// scp-like syntax: [user@]host:path
+		_, host, _ = strings.Cut(userHost, "@")
+		if host == "" {
+			host = userHost
+		}
+		path = scpPath
+	} else {
+		path = repository
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" {
+		return path
+	}
+	return strings.ToLower(host) + "/" + path
+}
+
+// lockRepository takes the lock of the Git repository of the YukConfig. It returns the
+// function releasing it, or errRepositoryBusy when another YukConfig holds it.
+func (r *YukConfigReconciler) lockRepository(yukConfig *yukv1.YukConfig) (func(), error) {
+	if r.RepositoryLocks == nil {
+		return func() {}, nil
+	}
+
+	unlock, ok := r.RepositoryLocks.TryLock(yukConfig.Spec.Git.Repository)
+	if !ok {
+		return nil, errRepositoryBusy
+	}
+	return unlock, nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/yaml"
+)
+
+func TestNormalizeRepositoryURL(t *testing.T) {
+	tests := []struct {
+		repository string
+		expected   string
+	}{
+		{repository: "https://github.com/org/repo.git", expected: "github.com/org/repo"},
+		{repository: "https://token@GitHub.com/org/repo/", expected: "github.com/org/repo"},
+		{repository: "ssh://git@github.com:22/org/repo.git", expected: "github.com/org/repo"},
+		{repository: "git@github.com:org/repo.git", expected: "github.com/org/repo"},
+		{repository: "/srv/git/repo.git", expected: "srv/git/repo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repository, func(t *testing.T) {
+			if result := normalizeRepositoryURL(tt.repository); result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRepositoryLocks_TryLock(t *testing.T) {
+	locks := NewRepositoryLocks()
+
+	unlock, ok := locks.TryLock("https://github.com/org/repo.git")
+	if !ok {
+		t.Fatal("Expected the repository to be locked")
+	}
+
+	// The SSH URL of the same repository is locked too, other repositories are not
+	if _, ok := locks.TryLock("git@github.com:org/repo"); ok {
+		t.Error("Expected the SSH URL of the locked repository to be busy")
+	}
+	otherUnlock, ok := locks.TryLock("https://github.com/org/other.git")
+	if !ok {
+		t.Error("Expected another repository to be lockable")
+	} else {
+		otherUnlock()
+	}
+
+	unlock()
+	if _, ok := locks.TryLock("git@github.com:org/repo"); !ok {
+		t.Error("Expected the repository to be lockable once released")
+	}
+}
+
+// TestRepositoryLocks_Serialized updates shared state from two goroutines holding the lock
+// of the same repository through different URLs; run with -race to detect overlapping access
+func TestRepositoryLocks_Serialized(t *testing.T) {
+	locks := NewRepositoryLocks()
+	repositories := []string{"https://github.com/org/repo.git", "git@github.com:org/repo"}
+
+	var wg sync.WaitGroup
+	active, updates := 0, 0
+	for _, repository := range repositories {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				unlock, ok := locks.TryLock(repository)
+				for !ok {
+					runtime.Gosched()
+					unlock, ok = locks.TryLock(repository)
+				}
+
+				active++
+				if active != 1 {
+					t.Errorf("Expected one update of the repository at a time, got %d", active)
+				}
+				updates++
+				active--
+				unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if updates != 200 {
+		t.Errorf("Expected 200 updates, got %d", updates)
+	}
+}
+
+func TestYukConfigReconciler_updateFiles_RepositoryBusy(t *testing.T) {
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "busy-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Git:           yukv1.GitConfig{Repository: "https://github.com/org/repo.git"},
+			UpdateTargets: []yukv1.UpdateTarget{{File: "values.yaml", YAMLPath: "image.tag"}},
+		},
+	}
+
+	reconciler := &YukConfigReconciler{RepositoryLocks: NewRepositoryLocks()}
+	unlock, ok := reconciler.RepositoryLocks.TryLock("git@github.com:org/repo.git")
+	if !ok {
+		t.Fatal("Expected the repository to be locked")
+	}
+	defer unlock()
+
+	newGitClient := func(branch string) GitOperator {
+		t.Error("Expected the busy repository not to be cloned")
+		return nil
+	}
+	_, err := reconciler.updateFiles(context.Background(), yukConfig, newGitClient, yaml.NewUpdater(),
+		"v1.1.0", []string{"v1.1.0"}, nil, ActionPush)
+	if !errors.Is(err, errRepositoryBusy) {
+		t.Errorf("Expected errRepositoryBusy, got: %v", err)
+	}
+}
@@ -0,0 +1,628 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/acr"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	"github.com/rebelopsio/yuk/pkg/gar"
+	"github.com/rebelopsio/yuk/pkg/ghcr"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+	"github.com/rebelopsio/yuk/pkg/oci"
+	"github.com/rebelopsio/yuk/pkg/quay"
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+	"github.com/rebelopsio/yuk/pkg/validation"
+)
+
+// Repository types
+const (
+	RepositoryTypeECR  = yukv1.RepositoryTypeECR
+	RepositoryTypeOCI  = yukv1.RepositoryTypeOCI
+	RepositoryTypeGHCR = yukv1.RepositoryTypeGHCR
+	RepositoryTypeGAR  = yukv1.RepositoryTypeGAR
+	RepositoryTypeACR  = yukv1.RepositoryTypeACR
+	RepositoryTypeQuay = yukv1.RepositoryTypeQuay
+)
+
+// TagResolver resolves the tags of an ECR repository. It is implemented by ecr.Client and
+// can be replaced with a fake through YukConfigReconciler.NewECRClient in tests.
+type TagResolver interface {
+	// GetLatestTags returns the latest tag for each of the given tag filters, keyed by
+	// tag filter
+	GetLatestTags(ctx context.Context, repositoryName string, tagFilters []string) (map[string]string, error)
+
+	// GetImageDigest returns the digest of the image the tag points to
+	GetImageDigest(ctx context.Context, repositoryName, tag string) (string, error)
+}
+
+var _ TagResolver = (*ecr.Client)(nil)
+
+// newECRClient returns the resolver of ECR repositories in the region: the reconciler's
+// NewECRClient when set, an ecr.Client otherwise
+func (r *YukConfigReconciler) newECRClient(region string, opts ...ecr.Option) TagResolver {
+	if r.NewECRClient != nil {
+		return r.NewECRClient(region, opts...)
+	}
+	return ecr.NewClient(region, opts...)
+}
+
+// imageSource is a repository monitored by a YukConfig. The repository of the spec is
+// the source with an empty name.
+type imageSource struct {
+	name       string
+	repository *yukv1.RepositoryConfig
+}
+
+// imageSources returns the sources of the YukConfig: the spec repository, when set,
+// followed by the named sources
+func imageSources(yukConfig *yukv1.YukConfig) []imageSource {
+	var sources []imageSource
+	if yukConfig.Spec.Repository.Type != "" || len(yukConfig.Spec.Sources) == 0 {
+		sources = append(sources, imageSource{repository: &yukConfig.Spec.Repository})
+	}
+	for i := range yukConfig.Spec.Sources {
+		source := &yukConfig.Spec.Sources[i]
+		sources = append(sources, imageSource{name: source.Name, repository: &source.RepositoryConfig})
+	}
+	return sources
+}
+
+// defaultSource returns the source of targets that do not name one: the spec repository,
+// or the first named source when the repository is not set
+func defaultSource(yukConfig *yukv1.YukConfig) imageSource {
+	return imageSources(yukConfig)[0]
+}
+
+// targetSource returns the name of the source whose tag is written to a target
+func targetSource(yukConfig *yukv1.YukConfig, target yukv1.UpdateTarget) string {
+	if target.Source != "" {
+		return target.Source
+	}
+	return defaultSource(yukConfig).name
+}
+
+// sourceRepository returns the repository of the named source, or nil for an unknown source
+func sourceRepository(yukConfig *yukv1.YukConfig, name string) *yukv1.RepositoryConfig {
+	for _, source := range imageSources(yukConfig) {
+		if source.name == name {
+			return source.repository
+		}
+	}
+	return nil
+}
+
+// validateSources returns an error for unnamed or duplicate sources and for targets
+// naming an unknown source
+func validateSources(yukConfig *yukv1.YukConfig) error {
+	return validation.ValidateSources(yukConfig).ToAggregate()
+}
+
+// repositoryName returns the name of a monitored repository
+func repositoryName(repository *yukv1.RepositoryConfig) string {
+	switch {
+	case repository.Type == RepositoryTypeOCI && repository.OCI != nil:
+		return repository.OCI.RepositoryName
+	case repository.Type == RepositoryTypeGHCR && repository.GHCR != nil:
+		return ghcr.RepositoryName(repository.GHCR.Owner, repository.GHCR.Image)
+	case repository.Type == RepositoryTypeGAR && repository.GAR != nil:
+		return garImage(repository.GAR).RepositoryName()
+	case repository.Type == RepositoryTypeACR && repository.ACR != nil:
+		return repository.ACR.Image
+	case repository.Type == RepositoryTypeQuay && repository.Quay != nil:
+		return quay.RepositoryName(repository.Quay.Namespace, repository.Quay.Repository)
+	case repository.ECR != nil:
+		return repository.ECR.RepositoryName
+	default:
+		return ""
+	}
+}
+
+// repositoryKey identifies a monitored repository across YukConfigs, including its
+// registry or region
+func repositoryKey(repository *yukv1.RepositoryConfig) string {
+	switch {
+	case repository.Type == RepositoryTypeOCI && repository.OCI != nil:
+		return repository.Type + "/" + repository.OCI.Registry + "/" + repositoryName(repository)
+	case repository.Type == RepositoryTypeGAR && repository.GAR != nil:
+		return repository.Type + "/" + repository.GAR.Location + "/" + repositoryName(repository)
+	case repository.Type == RepositoryTypeACR && repository.ACR != nil:
+		return repository.Type + "/" + acr.LoginServer(repository.ACR.Registry) + "/" + repositoryName(repository)
+	case repository.Type == RepositoryTypeQuay && repository.Quay != nil:
+		return repository.Type + "/" + quay.Registry(repository.Quay.Registry) + "/" + repositoryName(repository)
+	case repository.ECR != nil:
+		return repository.Type + "/" + repository.ECR.Region + "/" + repositoryName(repository)
+	default:
+		return repository.Type + "/" + repositoryName(repository)
+	}
+}
+
+// garOptions returns the options shared by all Artifact Registry clients
+func (r *YukConfigReconciler) garOptions() []gar.Option {
+	if r.GARTokenSource == nil {
+		return nil
+	}
+	return []gar.Option{gar.WithTokenSource(r.GARTokenSource)}
+}
+
+// garImage returns the Artifact Registry image of a GAR configuration
+func garImage(config *yukv1.GARConfig) gar.Image {
+	return gar.Image{
+		Project:    config.Project,
+		Location:   config.Location,
+		Repository: config.Repository,
+		Name:       config.Image,
+	}
+}
+
+// repositoryTagFilter returns the tag filter of a monitored repository
+func repositoryTagFilter(repository *yukv1.RepositoryConfig) string {
+	switch {
+	case repository.Type == RepositoryTypeOCI && repository.OCI != nil:
+		return repository.OCI.TagFilter
+	case repository.Type == RepositoryTypeGHCR && repository.GHCR != nil:
+		return repository.GHCR.TagFilter
+	case repository.Type == RepositoryTypeGAR && repository.GAR != nil:
+		return repository.GAR.TagFilter
+	case repository.Type == RepositoryTypeACR && repository.ACR != nil:
+		return repository.ACR.TagFilter
+	case repository.Type == RepositoryTypeQuay && repository.Quay != nil:
+		return repository.Quay.TagFilter
+	case repository.ECR != nil:
+		return repository.ECR.TagFilter
+	default:
+		return ""
+	}
+}
+
+// repositorySortStrategy returns the sort strategy of a monitored repository
+func repositorySortStrategy(repository *yukv1.RepositoryConfig) string {
+	switch {
+	case repository.Type == RepositoryTypeOCI && repository.OCI != nil:
+		return repository.OCI.SortStrategy
+	case repository.Type == RepositoryTypeGHCR && repository.GHCR != nil:
+		return repository.GHCR.SortStrategy
+	case repository.Type == RepositoryTypeGAR && repository.GAR != nil:
+		return repository.GAR.SortStrategy
+	case repository.Type == RepositoryTypeACR && repository.ACR != nil:
+		return repository.ACR.SortStrategy
+	case repository.Type == RepositoryTypeQuay && repository.Quay != nil:
+		return repository.Quay.SortStrategy
+	case repository.ECR != nil:
+		return repository.ECR.SortStrategy
+	default:
+		return ""
+	}
+}
+
+// withSortStrategy returns a copy of the repository ordering tags by the given strategy
+func withSortStrategy(repository *yukv1.RepositoryConfig, strategy string) *yukv1.RepositoryConfig {
+	copied := *repository
+	switch {
+	case copied.Type == RepositoryTypeOCI && copied.OCI != nil:
+		config := *copied.OCI
+		config.SortStrategy = strategy
+		copied.OCI = &config
+	case copied.Type == RepositoryTypeGHCR && copied.GHCR != nil:
+		config := *copied.GHCR
+		config.SortStrategy = strategy
+		copied.GHCR = &config
+	case copied.Type == RepositoryTypeGAR && copied.GAR != nil:
+		config := *copied.GAR
+		config.SortStrategy = strategy
+		copied.GAR = &config
+	case copied.Type == RepositoryTypeACR && copied.ACR != nil:
+		config := *copied.ACR
+		config.SortStrategy = strategy
+		copied.ACR = &config
+	case copied.Type == RepositoryTypeQuay && copied.Quay != nil:
+		config := *copied.Quay
+		config.SortStrategy = strategy
+		copied.Quay = &config
+	case copied.ECR != nil:
+		config := *copied.ECR
+		config.SortStrategy = strategy
+		copied.ECR = &config
+	}
+	return &copied
+}
+
+// targetSortStrategy returns the sort strategy override of a target, or an empty string
+// when the target orders tags like its source repository
+func targetSortStrategy(yukConfig *yukv1.YukConfig, target yukv1.UpdateTarget) string {
+	strategy := tagsort.Strategy(target.SortStrategy)
+	if strategy == "" {
+		return ""
+	}
+	repositoryStrategy := tagsort.Strategy(repositorySortStrategy(sourceRepository(yukConfig, targetSource(yukConfig, target))))
+	if repositoryStrategy == "" {
+		repositoryStrategy = tagsort.Lexical
+	}
+	if strategy == repositoryStrategy {
+		return ""
+	}
+	return target.SortStrategy
+}
+
+// tagSelectionKey returns the key of the latest tag selected with a tag filter and a sort
+// strategy override in the result of getSourceTags
+func tagSelectionKey(filter, strategy string) string {
+	if strategy == "" {
+		return filter
+	}
+	return strategy + "\x00" + filter
+}
+
+// getSourceTags resolves the latest tags of every source. The result is keyed by source
+// name, then by tag selection key: the tag filter, prefixed by the sort strategy for
+// targets overriding it. With refresh, cached registry lookups are not reused.
+func (r *YukConfigReconciler) getSourceTags(ctx context.Context, yukConfig *yukv1.YukConfig, creds *credentials, refresh bool) (map[string]map[string]string, error) {
+	sourceTags := make(map[string]map[string]string)
+
+	for _, source := range imageSources(yukConfig) {
+		sourceTags[source.name] = make(map[string]string)
+
+		// Resolve the tags once per sort strategy used by the source or its targets
+		for _, strategy := range r.sortStrategies(yukConfig, source.name) {
+			repository := source.repository
+			if strategy != "" {
+				repository = withSortStrategy(repository, strategy)
+			}
+			latestTags, err := r.getLatestTags(ctx, repository, r.tagFilters(yukConfig, source.name, strategy), creds.repository(source.name), refresh)
+			if err != nil {
+				if source.name != "" {
+					return nil, fmt.Errorf("source %s: %w", source.name, err)
+				}
+				return nil, err
+			}
+			for filter, tag := range latestTags {
+				sourceTags[source.name][tagSelectionKey(filter, strategy)] = tag
+			}
+		}
+	}
+
+	return sourceTags, nil
+}
+
+// getLatestTags resolves the latest tag of the repository for each of the given tag
+// filters with a single lookup. The result is keyed by tag filter.
+func (r *YukConfigReconciler) getLatestTags(ctx context.Context, repository *yukv1.RepositoryConfig, filters []string, creds *repositoryCredentials, refresh bool) (map[string]string, error) {
+	var latestTags map[string]string
+	var err error
+	start := time.Now()
+
+	switch repository.Type {
+	case RepositoryTypeECR:
+		if repository.ECR == nil {
+			return nil, fmt.Errorf("ECR configuration is required when repository type is 'ecr'")
+		}
+
+		ecrOpts := append([]ecr.Option{
+			ecr.WithSelectExpression(repository.ECR.SelectExpression),
+			ecr.WithSortStrategy(tagsort.Strategy(repository.ECR.SortStrategy)),
+			ecr.WithVersionConstraint(repository.ECR.VersionConstraint),
+			ecr.WithTagPriority(repository.ECR.TagPriority),
+			ecr.WithExcludeTags(repository.ECR.ExcludeTags),
+			ecr.WithExcludePrereleases(repository.ECR.ExcludePrereleases),
+			ecr.WithManifestListsOnly(repository.ECR.ManifestListsOnly),
+			ecr.WithExcludeArtifacts(repository.ECR.ExcludeArtifacts),
+			ecr.WithAssumeRole(repository.ECR.RoleARN, repository.ECR.ExternalID),
+			ecr.WithRegistryID(repository.ECR.RegistryID),
+			ecr.WithPushAgeWindow(durationOrZero(repository.ECR.MinPushAge), durationOrZero(repository.ECR.MaxPushAge)),
+			ecr.WithCache(r.ECRCache, refresh),
+		}, creds.ecrOptions()...)
+		ecrClient := r.newECRClient(repository.ECR.Region, ecrOpts...)
+		latestTags, err = ecrClient.GetLatestTags(ctx, repository.ECR.RepositoryName, filters)
+
+	case RepositoryTypeOCI:
+		if repository.OCI == nil {
+			return nil, fmt.Errorf("OCI configuration is required when repository type is 'oci'")
+		}
+
+		ociOpts := append([]oci.Option{
+			oci.WithSortStrategy(tagsort.Strategy(repository.OCI.SortStrategy)),
+			oci.WithInsecure(repository.OCI.Insecure),
+		}, creds.ociOptions(repository.OCI)...)
+		ociClient := oci.NewClient(repository.OCI.Registry, ociOpts...)
+		latestTags, err = ociClient.GetLatestTags(ctx, repository.OCI.RepositoryName, filters)
+
+	case RepositoryTypeGHCR:
+		if repository.GHCR == nil {
+			return nil, fmt.Errorf("GHCR configuration is required when repository type is 'ghcr'")
+		}
+
+		ghcrOpts := append([]ghcr.Option{
+			ghcr.WithSortStrategy(tagsort.Strategy(repository.GHCR.SortStrategy)),
+		}, creds.ghcrOptions()...)
+		ghcrClient := ghcr.NewClient(ghcrOpts...)
+		latestTags, err = ghcrClient.GetLatestTags(ctx, repository.GHCR.Owner, repository.GHCR.Image, filters)
+
+	case RepositoryTypeGAR:
+		if repository.GAR == nil {
+			return nil, fmt.Errorf("GAR configuration is required when repository type is 'gar'")
+		}
+
+		garClient := gar.NewClient(append([]gar.Option{
+			gar.WithSortStrategy(tagsort.Strategy(repository.GAR.SortStrategy)),
+		}, r.garOptions()...)...)
+		latestTags, err = garClient.GetLatestTags(ctx, garImage(repository.GAR), filters)
+
+	case RepositoryTypeACR:
+		if repository.ACR == nil {
+			return nil, fmt.Errorf("ACR configuration is required when repository type is 'acr'")
+		}
+
+		acrClient := acr.NewClient(repository.ACR.Registry, append([]acr.Option{
+			acr.WithSortStrategy(tagsort.Strategy(repository.ACR.SortStrategy)),
+		}, creds.acrOptions(repository.ACR)...)...)
+		latestTags, err = acrClient.GetLatestTags(ctx, repository.ACR.Image, filters)
+
+	case RepositoryTypeQuay:
+		if repository.Quay == nil {
+			return nil, fmt.Errorf("Quay configuration is required when repository type is 'quay'")
+		}
+
+		quayClient := quay.NewClient(repository.Quay.Registry, append([]quay.Option{
+			quay.WithSortStrategy(tagsort.Strategy(repository.Quay.SortStrategy)),
+		}, creds.quayOptions(repository.Quay)...)...)
+		latestTags, err = quayClient.GetLatestTags(ctx, repositoryName(repository), filters)
+
+	default:
+		return nil, fmt.Errorf("unsupported repository type: %s", repository.Type)
+	}
+
+	// Record repository check metrics
+	repoResult := yukmetrics.RepositoryCheckSuccess
+	if err != nil {
+		repoResult = yukmetrics.RepositoryCheckError
+	}
+
+	yukmetrics.RepositoryChecks.With(prometheus.Labels{
+		"repository_type": repository.Type,
+		"repository_name": repositoryName(repository),
+		"result":          string(repoResult),
+	}).Inc()
+
+	yukmetrics.RepositoryCheckDuration.With(prometheus.Labels{
+		"repository_type": repository.Type,
+		"repository_name": repositoryName(repository),
+	}).Observe(time.Since(start).Seconds())
+
+	return latestTags, err
+}
+
+// sortStrategies returns the distinct sort strategies to resolve tags with for a source:
+// an empty string for the strategy of the source, followed by any overrides of the
+// targets using the source
+func (r *YukConfigReconciler) sortStrategies(yukConfig *yukv1.YukConfig, source string) []string {
+	strategies := []string{""}
+	seen := map[string]bool{"": true}
+
+	for _, target := range yukConfig.Spec.UpdateTargets {
+		if targetSource(yukConfig, target) != source {
+			continue
+		}
+		if strategy := targetSortStrategy(yukConfig, target); !seen[strategy] {
+			strategies = append(strategies, strategy)
+			seen[strategy] = true
+		}
+	}
+
+	return strategies
+}
+
+// tagFilters returns the distinct tag filters to resolve for a source with a sort
+// strategy override. Without override, these are the source's filter followed by any
+// overrides of the targets using the source; otherwise, the filters of the targets
+// overriding the sort strategy.
+func (r *YukConfigReconciler) tagFilters(yukConfig *yukv1.YukConfig, source, strategy string) []string {
+	sourceFilter := repositoryTagFilter(sourceRepository(yukConfig, source))
+	var filters []string
+	seen := make(map[string]bool)
+	if strategy == "" {
+		filters = append(filters, sourceFilter)
+		seen[sourceFilter] = true
+	}
+
+	for _, target := range yukConfig.Spec.UpdateTargets {
+		if targetSource(yukConfig, target) != source || targetSortStrategy(yukConfig, target) != strategy {
+			continue
+		}
+		filter := sourceFilter
+		if target.TagFilter != "" {
+			filter = target.TagFilter
+		}
+		if !seen[filter] {
+			filters = append(filters, filter)
+			seen[filter] = true
+		}
+	}
+
+	return filters
+}
+
+// resolveTargetTags returns the tag to write for each update target, in target order.
+// Targets use the latest tag of their source, honoring per-target tag filter and sort
+// strategy overrides.
+func (r *YukConfigReconciler) resolveTargetTags(yukConfig *yukv1.YukConfig, sourceTags map[string]map[string]string) []string {
+	sourceFilters := make(map[string]string)
+	for _, source := range imageSources(yukConfig) {
+		sourceFilters[source.name] = repositoryTagFilter(source.repository)
+	}
+
+	targetTags := make([]string, len(yukConfig.Spec.UpdateTargets))
+	for i, target := range yukConfig.Spec.UpdateTargets {
+		source := targetSource(yukConfig, target)
+		filter := sourceFilters[source]
+		if target.TagFilter != "" {
+			filter = target.TagFilter
+		}
+		targetTags[i] = sourceTags[source][tagSelectionKey(filter, targetSortStrategy(yukConfig, target))]
+	}
+
+	return targetTags
+}
+
+// resolveTargetDigests returns the digest of the tag to write for each update target,
+// in target order. Only targets with byDigest get a digest; each digest is looked up once
+// per source and tag.
+func (r *YukConfigReconciler) resolveTargetDigests(ctx context.Context, yukConfig *yukv1.YukConfig, targetTags []string, creds *credentials) ([]string, error) {
+	repositories := make(map[string]*yukv1.RepositoryConfig)
+	for _, source := range imageSources(yukConfig) {
+		repositories[source.name] = source.repository
+	}
+
+	digests := make(map[string]string)
+	targetDigests := make([]string, len(yukConfig.Spec.UpdateTargets))
+	for i, target := range yukConfig.Spec.UpdateTargets {
+		if !target.ByDigest || targetTags[i] == "" {
+			continue
+		}
+
+		source := targetSource(yukConfig, target)
+		key := source + ":" + targetTags[i]
+		if _, ok := digests[key]; !ok {
+			digest, err := r.getImageDigest(ctx, repositories[source], targetTags[i], creds.repository(source))
+			if err != nil {
+				if source != "" {
+					return nil, fmt.Errorf("source %s: %w", source, err)
+				}
+				return nil, err
+			}
+			digests[key] = digest
+		}
+		targetDigests[i] = digests[key]
+	}
+
+	return targetDigests, nil
+}
+
+// getImageDigest resolves the digest of a tag in the repository
+func (r *YukConfigReconciler) getImageDigest(ctx context.Context, repository *yukv1.RepositoryConfig, tag string, creds *repositoryCredentials) (string, error) {
+	switch repository.Type {
+	case RepositoryTypeECR:
+		if repository.ECR == nil {
+			return "", fmt.Errorf("ECR configuration is required when repository type is 'ecr'")
+		}
+
+		ecrOpts := append([]ecr.Option{
+			ecr.WithAssumeRole(repository.ECR.RoleARN, repository.ECR.ExternalID),
+			ecr.WithRegistryID(repository.ECR.RegistryID),
+		}, creds.ecrOptions()...)
+		return r.newECRClient(repository.ECR.Region, ecrOpts...).GetImageDigest(ctx, repository.ECR.RepositoryName, tag)
+
+	case RepositoryTypeOCI:
+		if repository.OCI == nil {
+			return "", fmt.Errorf("OCI configuration is required when repository type is 'oci'")
+		}
+
+		ociOpts := append([]oci.Option{
+			oci.WithInsecure(repository.OCI.Insecure),
+		}, creds.ociOptions(repository.OCI)...)
+		return oci.NewClient(repository.OCI.Registry, ociOpts...).GetImageDigest(ctx, repository.OCI.RepositoryName, tag)
+
+	case RepositoryTypeGHCR:
+		if repository.GHCR == nil {
+			return "", fmt.Errorf("GHCR configuration is required when repository type is 'ghcr'")
+		}
+
+		return ghcr.NewClient(creds.ghcrOptions()...).GetImageDigest(ctx, repository.GHCR.Owner, repository.GHCR.Image, tag)
+
+	case RepositoryTypeGAR:
+		if repository.GAR == nil {
+			return "", fmt.Errorf("GAR configuration is required when repository type is 'gar'")
+		}
+
+		return gar.NewClient(r.garOptions()...).GetImageDigest(ctx, garImage(repository.GAR), tag)
+
+	case RepositoryTypeACR:
+		if repository.ACR == nil {
+			return "", fmt.Errorf("ACR configuration is required when repository type is 'acr'")
+		}
+
+		return acr.NewClient(repository.ACR.Registry, creds.acrOptions(repository.ACR)...).GetImageDigest(ctx, repository.ACR.Image, tag)
+
+	case RepositoryTypeQuay:
+		if repository.Quay == nil {
+			return "", fmt.Errorf("Quay configuration is required when repository type is 'quay'")
+		}
+
+		return quay.NewClient(repository.Quay.Registry, creds.quayOptions(repository.Quay)...).GetImageDigest(ctx, repositoryName(repository), tag)
+
+	default:
+		return "", fmt.Errorf("unsupported repository type: %s", repository.Type)
+	}
+}
+
+// targetValues returns the value to write for each update target: its digest for
+// byDigest targets, its tag otherwise
+func targetValues(targetTags, targetDigests []string) []string {
+	values := make([]string, len(targetTags))
+	for i, tag := range targetTags {
+		values[i] = tag
+		if targetDigests[i] != "" {
+			values[i] = targetDigests[i]
+		}
+	}
+	return values
+}
+
+// updateSourceStatuses records the latest tag of each named source and reports whether
+// any of them differs from the tag last written
+func (r *YukConfigReconciler) updateSourceStatuses(yukConfig *yukv1.YukConfig, sourceTags map[string]map[string]string) bool {
+	var statuses []yukv1.SourceStatus
+	changed := false
+
+	for i := range yukConfig.Spec.Sources {
+		source := &yukConfig.Spec.Sources[i]
+		status := yukv1.SourceStatus{
+			Name:      source.Name,
+			LatestTag: sourceTags[source.Name][repositoryTagFilter(&source.RepositoryConfig)],
+		}
+
+		// Carry over the tag last written for this source
+		for _, existing := range yukConfig.Status.Sources {
+			if existing.Name == source.Name {
+				status.CurrentTag = existing.CurrentTag
+				break
+			}
+		}
+
+		if status.CurrentTag != status.LatestTag {
+			changed = true
+		}
+		statuses = append(statuses, status)
+	}
+
+	yukConfig.Status.Sources = statuses
+	return changed
+}
+
+// durationOrZero returns the duration, or zero when it is not set
+func durationOrZero(duration *metav1.Duration) time.Duration {
+	if duration == nil {
+		return 0
+	}
+	return duration.Duration
+}
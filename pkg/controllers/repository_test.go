@@ -0,0 +1,496 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestRepositoryNameAndTagFilter(t *testing.T) {
+	tests := []struct {
+		name           string
+		repository     yukv1.RepositoryConfig
+		expectedName   string
+		expectedFilter string
+	}{
+		{
+			name: "ecr",
+			repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeECR,
+				ECR:  &yukv1.ECRConfig{RepositoryName: "my-app", TagFilter: "^v"},
+			},
+			expectedName:   "my-app",
+			expectedFilter: "^v",
+		},
+		{
+			name: "oci",
+			repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI:  &yukv1.OCIConfig{Registry: "harbor.example.com", RepositoryName: "project/app", TagFilter: "^release-"},
+			},
+			expectedName:   "project/app",
+			expectedFilter: "^release-",
+		},
+		{
+			name: "ghcr",
+			repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeGHCR,
+				GHCR: &yukv1.GHCRConfig{Owner: "RebelOps", Image: "yuk", TagFilter: "^v"},
+			},
+			expectedName:   "rebelops/yuk",
+			expectedFilter: "^v",
+		},
+		{
+			name: "gar",
+			repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeGAR,
+				GAR: &yukv1.GARConfig{
+					Project:    "my-project",
+					Location:   "us-central1",
+					Repository: "my-repo",
+					Image:      "my-app",
+					TagFilter:  "^v",
+				},
+			},
+			expectedName:   "my-project/my-repo/my-app",
+			expectedFilter: "^v",
+		},
+		{
+			name: "acr",
+			repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeACR,
+				ACR:  &yukv1.ACRConfig{Registry: "myregistry", Image: "team/my-app", TagFilter: "^v"},
+			},
+			expectedName:   "team/my-app",
+			expectedFilter: "^v",
+		},
+		{
+			name: "quay",
+			repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeQuay,
+				Quay: &yukv1.QuayConfig{Namespace: "rebelops", Repository: "my-app", TagFilter: "^v"},
+			},
+			expectedName:   "rebelops/my-app",
+			expectedFilter: "^v",
+		},
+		{
+			name:       "missing configuration",
+			repository: yukv1.RepositoryConfig{Type: RepositoryTypeOCI},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if name := repositoryName(&tt.repository); name != tt.expectedName {
+				t.Errorf("Expected repository name %q, got %q", tt.expectedName, name)
+			}
+			if filter := repositoryTagFilter(&tt.repository); filter != tt.expectedFilter {
+				t.Errorf("Expected tag filter %q, got %q", tt.expectedFilter, filter)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_getSourceTags_OCI(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "robot$yuk" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Harbor"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v2/project/app/tags/list" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"name": "project/app",
+			"tags": []string{"v1.9.0", "v1.10.0", "worker-1.0", "latest"},
+		})
+	}))
+	defer registry.Close()
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "harbor-robot", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(),
+		Scheme: scheme,
+	}
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					TagFilter:      "^v",
+					SortStrategy:   "semver",
+					Insecure:       true,
+					Auth: yukv1.OCIAuthConfig{
+						Username:    "robot$yuk",
+						PasswordRef: &yukv1.SecretKeySelector{Name: "harbor-robot", Key: "password"},
+					},
+				},
+			},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "app.yaml", YAMLPath: "image.tag"},
+				{File: "worker.yaml", YAMLPath: "image.tag", TagFilter: "^worker-"},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	creds, err := reconciler.resolveCredentials(ctx, yukConfig)
+	if err != nil {
+		t.Fatalf("Failed to resolve credentials: %v", err)
+	}
+
+	sourceTags, err := reconciler.getSourceTags(ctx, yukConfig, creds, false)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	latestTags := sourceTags[""]
+	if latestTags["^v"] != "v1.10.0" {
+		t.Errorf("Expected latest tag v1.10.0, got %s", latestTags["^v"])
+	}
+	if latestTags["^worker-"] != "worker-1.0" {
+		t.Errorf("Expected latest worker tag worker-1.0, got %s", latestTags["^worker-"])
+	}
+}
+
+func TestYukConfigReconciler_getSourceTags_TargetSortStrategy(t *testing.T) {
+	var requests int
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/project/app/tags/list" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"name": "project/app",
+			"tags": []string{"v1.9.0", "v1.10.0", "worker-1.0", "worker-2.0"},
+		})
+	}))
+	defer registry.Close()
+
+	reconciler := &YukConfigReconciler{}
+	yukConfig := &yukv1.YukConfig{
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					TagFilter:      "^v",
+					SortStrategy:   "semver",
+					Insecure:       true,
+				},
+			},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "app.yaml", YAMLPath: "image.tag"},
+				{File: "legacy.yaml", YAMLPath: "image.tag", SortStrategy: "lexical"},
+				{File: "worker.yaml", YAMLPath: "image.tag", TagFilter: "^worker-", SortStrategy: "lexical"},
+				{File: "canary.yaml", YAMLPath: "image.tag", SortStrategy: "semver"},
+			},
+		},
+	}
+
+	if strategies := reconciler.sortStrategies(yukConfig, ""); len(strategies) != 2 || strategies[0] != "" || strategies[1] != "lexical" {
+		t.Fatalf("Expected sort strategies [ lexical], got %q", strategies)
+	}
+
+	ctx := context.Background()
+	sourceTags, err := reconciler.getSourceTags(ctx, yukConfig, &credentials{}, false)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected one tag listing per sort strategy, got %d", requests)
+	}
+
+	expected := []string{"v1.10.0", "v1.9.0", "worker-2.0", "v1.10.0"}
+	targetTags := reconciler.resolveTargetTags(yukConfig, sourceTags)
+	for i, tag := range expected {
+		if targetTags[i] != tag {
+			t.Errorf("Expected target %d to use tag %s, got %s", i, tag, targetTags[i])
+		}
+	}
+}
+
+func TestYukConfigReconciler_resolveTargetDigests(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	var requests int
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.URL.Path != "/v2/project/app/manifests/v1.1.0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests++
+		w.Header().Set("Docker-Content-Digest", digest)
+	}))
+	defer registry.Close()
+
+	reconciler := &YukConfigReconciler{}
+	yukConfig := &yukv1.YukConfig{
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					Insecure:       true,
+				},
+			},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "app.yaml", YAMLPath: "image.tag"},
+				{File: "app.yaml", YAMLPath: "image.digest", ByDigest: true},
+				{File: "worker.yaml", YAMLPath: "image.digest", ByDigest: true},
+			},
+		},
+	}
+
+	targetTags := []string{"v1.1.0", "v1.1.0", "v1.1.0"}
+	targetDigests, err := reconciler.resolveTargetDigests(context.Background(), yukConfig, targetTags, &credentials{})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(targetDigests) != 3 || targetDigests[0] != "" || targetDigests[1] != digest || targetDigests[2] != digest {
+		t.Fatalf("Expected digests only for byDigest targets, got %v", targetDigests)
+	}
+
+	// The digest of a tag is looked up once
+	if requests != 1 {
+		t.Errorf("Expected 1 digest lookup, got %d", requests)
+	}
+
+	values := targetValues(targetTags, targetDigests)
+	if values[0] != "v1.1.0" || values[1] != digest {
+		t.Errorf("Expected tag and digest values, got %v", values)
+	}
+
+	// A tag pushed again with a new digest is reported as changed
+	if changed := reconciler.updateTargetStatuses(yukConfig, targetTags, targetDigests); !changed {
+		t.Error("Expected new digest to be reported as changed")
+	}
+	if len(yukConfig.Status.Targets) != 2 {
+		t.Fatalf("Expected 2 target statuses, got %d", len(yukConfig.Status.Targets))
+	}
+	for i := range yukConfig.Status.Targets {
+		yukConfig.Status.Targets[i].CurrentTag = "v1.1.0"
+		yukConfig.Status.Targets[i].CurrentDigest = digest
+	}
+	if changed := reconciler.updateTargetStatuses(yukConfig, targetTags, targetDigests); changed {
+		t.Error("Expected up-to-date digest not to be reported as changed")
+	}
+
+	repushed := "sha256:" + strings.Repeat("b", 64)
+	if changed := reconciler.updateTargetStatuses(yukConfig, targetTags, []string{"", repushed, repushed}); !changed {
+		t.Error("Expected re-pushed tag to be reported as changed")
+	}
+}
+
+func TestValidateSources(t *testing.T) {
+	tests := []struct {
+		name        string
+		sources     []yukv1.ImageSource
+		targets     []yukv1.UpdateTarget
+		expectError bool
+	}{
+		{
+			name:    "targets use the spec repository",
+			targets: []yukv1.UpdateTarget{{File: "values.yaml", YAMLPath: "image.tag"}},
+		},
+		{
+			name: "targets reference named sources",
+			sources: []yukv1.ImageSource{
+				{Name: "frontend", RepositoryConfig: yukv1.RepositoryConfig{Type: RepositoryTypeECR}},
+				{Name: "backend", RepositoryConfig: yukv1.RepositoryConfig{Type: RepositoryTypeECR}},
+			},
+			targets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "frontend.image.tag", Source: "frontend"},
+				{File: "values.yaml", YAMLPath: "backend.image.tag", Source: "backend"},
+			},
+		},
+		{
+			name:        "unnamed source",
+			sources:     []yukv1.ImageSource{{RepositoryConfig: yukv1.RepositoryConfig{Type: RepositoryTypeECR}}},
+			expectError: true,
+		},
+		{
+			name: "duplicate source",
+			sources: []yukv1.ImageSource{
+				{Name: "frontend", RepositoryConfig: yukv1.RepositoryConfig{Type: RepositoryTypeECR}},
+				{Name: "frontend", RepositoryConfig: yukv1.RepositoryConfig{Type: RepositoryTypeOCI}},
+			},
+			expectError: true,
+		},
+		{
+			name:        "unknown source",
+			sources:     []yukv1.ImageSource{{Name: "frontend", RepositoryConfig: yukv1.RepositoryConfig{Type: RepositoryTypeECR}}},
+			targets:     []yukv1.UpdateTarget{{File: "values.yaml", YAMLPath: "image.tag", Source: "backend"}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yukConfig := &yukv1.YukConfig{
+				Spec: yukv1.YukConfigSpec{Sources: tt.sources, UpdateTargets: tt.targets},
+			}
+
+			err := validateSources(yukConfig)
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_resolveTargetTags_MultipleSources(t *testing.T) {
+	reconciler := &YukConfigReconciler{}
+
+	yukConfig := &yukv1.YukConfig{
+		Spec: yukv1.YukConfigSpec{
+			Sources: []yukv1.ImageSource{
+				{
+					Name: "frontend",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: RepositoryTypeECR,
+						ECR:  &yukv1.ECRConfig{RepositoryName: "frontend", TagFilter: "^v"},
+					},
+				},
+				{
+					Name: "backend",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: RepositoryTypeOCI,
+						OCI:  &yukv1.OCIConfig{Registry: "ghcr.io", RepositoryName: "org/backend", TagFilter: "^v"},
+					},
+				},
+			},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "frontend.image.tag"},
+				{File: "values.yaml", YAMLPath: "backend.image.tag", Source: "backend"},
+				{File: "values.yaml", YAMLPath: "migrations.image.tag", Source: "backend", TagFilter: "^migrate-"},
+			},
+		},
+	}
+
+	// Targets without a source use the first named source
+	if source := defaultSource(yukConfig); source.name != "frontend" {
+		t.Errorf("Expected default source frontend, got %q", source.name)
+	}
+
+	filters := reconciler.tagFilters(yukConfig, "backend", "")
+	if len(filters) != 2 || filters[0] != "^v" || filters[1] != "^migrate-" {
+		t.Errorf("Expected backend filters [^v ^migrate-], got %v", filters)
+	}
+
+	sourceTags := map[string]map[string]string{
+		"frontend": {"^v": "v2.0.0"},
+		"backend":  {"^v": "v1.4.0", "^migrate-": "migrate-7"},
+	}
+
+	targetTags := reconciler.resolveTargetTags(yukConfig, sourceTags)
+	expected := []string{"v2.0.0", "v1.4.0", "migrate-7"}
+	for i, tag := range expected {
+		if targetTags[i] != tag {
+			t.Errorf("Expected target %d tag %s, got %s", i, tag, targetTags[i])
+		}
+	}
+}
+
+func TestYukConfigReconciler_updateSourceStatuses(t *testing.T) {
+	reconciler := &YukConfigReconciler{}
+
+	yukConfig := &yukv1.YukConfig{
+		Spec: yukv1.YukConfigSpec{
+			Sources: []yukv1.ImageSource{
+				{
+					Name: "frontend",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: RepositoryTypeECR,
+						ECR:  &yukv1.ECRConfig{RepositoryName: "frontend"},
+					},
+				},
+				{
+					Name: "backend",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: RepositoryTypeECR,
+						ECR:  &yukv1.ECRConfig{RepositoryName: "backend"},
+					},
+				},
+			},
+		},
+		Status: yukv1.YukConfigStatus{
+			Sources: []yukv1.SourceStatus{
+				{Name: "frontend", CurrentTag: "v2.0.0", LatestTag: "v2.0.0"},
+				{Name: "removed", CurrentTag: "v0.1.0", LatestTag: "v0.1.0"},
+			},
+		},
+	}
+
+	sourceTags := map[string]map[string]string{
+		"frontend": {"": "v2.0.0"},
+		"backend":  {"": "v1.0.0"},
+	}
+
+	if !reconciler.updateSourceStatuses(yukConfig, sourceTags) {
+		t.Errorf("Expected a source update to be reported")
+	}
+
+	statuses := yukConfig.Status.Sources
+	if len(statuses) != 2 {
+		t.Fatalf("Expected 2 source statuses, got %d", len(statuses))
+	}
+	if statuses[0] != (yukv1.SourceStatus{Name: "frontend", CurrentTag: "v2.0.0", LatestTag: "v2.0.0"}) {
+		t.Errorf("Unexpected frontend status: %+v", statuses[0])
+	}
+	if statuses[1] != (yukv1.SourceStatus{Name: "backend", LatestTag: "v1.0.0"}) {
+		t.Errorf("Unexpected backend status: %+v", statuses[1])
+	}
+
+	// Once every source is written, no update is pending
+	statuses[1].CurrentTag = "v1.0.0"
+	if reconciler.updateSourceStatuses(yukConfig, sourceTags) {
+		t.Errorf("Expected no source update to be reported")
+	}
+}
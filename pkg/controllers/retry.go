@@ -0,0 +1,304 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/git"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+	"github.com/rebelopsio/yuk/pkg/verify"
+	"github.com/rebelopsio/yuk/pkg/yaml"
+)
+
+// Reasons of the Ready condition when a reconcile fails
+const (
+	// ReasonRetrying means the failure is transient and yuk retries with backoff
+	ReasonRetrying = "Retrying"
+
+	// ReasonFailed means the failure is permanent and needs user action (e.g. auth or config)
+	ReasonFailed = "Failed"
+
+	// ReasonValidationError means a target's current value did not match its expected value pattern
+	ReasonValidationError = "ValidationError"
+)
+
+// ConditionDegraded is set to True when checks stopped after Spec.MaxConsecutiveFailures
+// failed reconciles in a row
+const ConditionDegraded = "Degraded"
+
+// Reasons of the Degraded condition
+const (
+	// ReasonMaxFailuresReached means checks stopped until the spec changes or a reconcile is requested
+	ReasonMaxFailuresReached = "MaxFailuresReached"
+
+	// ReasonResumed means checks resumed after a spec change or a requested reconcile
+	ReasonResumed = "Resumed"
+)
+
+// retryBaseDelay is the delay before the first retry of a transient failure. It doubles
+// with every consecutive failure, up to the check interval.
+const retryBaseDelay = 30 * time.Second
+
+// isRetryable reports whether an error is transient: network errors, throttling and
+// transient API errors, server errors and push conflicts
+func isRetryable(err error) bool {
+	if errors.Is(err, git.ErrPushConflict) || errors.Is(err, git.ErrNetwork) {
+		return true
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if _, ok := retry.DefaultThrottleErrorCodes[apiErr.ErrorCode()]; ok {
+			return true
+		}
+		if _, ok := retry.DefaultRetryableErrorCodes[apiErr.ErrorCode()]; ok {
+			return true
+		}
+	}
+
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		status := statusErr.HTTPStatusCode()
+		return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+	}
+
+	return false
+}
+
+// recordPushRetry records a rejected push that was rebased onto the remote branch before
+// being retried
+func recordPushRetry(ctx context.Context, repository string, attempt int, err error) {
+	result := yukmetrics.GitOperationSuccess
+	if err != nil {
+		result = yukmetrics.GitOperationError
+	}
+
+	yukmetrics.GitOperations.With(prometheus.Labels{
+		"operation":  string(yukmetrics.GitOperationPushRetry),
+		"repository": repository,
+		"result":     string(result),
+	}).Inc()
+
+	log.FromContext(ctx).Info("Push rejected, retrying after rebase", "attempt", attempt, "error", err)
+}
+
+// fileUpdateErrorType returns the metrics error type of a failed file update: validation
+// for unexpected target values, yaml otherwise
+func fileUpdateErrorType(err error) yukmetrics.ErrorType {
+	if errors.Is(err, yaml.ErrUnexpectedValue) {
+		return yukmetrics.ErrorTypeValidation
+	}
+	return yukmetrics.ErrorTypeYAML
+}
+
+// repositoryErrorType returns the metrics error type of a failed repository check: auth
+// for rejected credentials, network for transient failures such as throttling that
+// outlasted the registry client's retries, repository otherwise
+func repositoryErrorType(err error) yukmetrics.ErrorType {
+	if isAuthError(err) {
+		return yukmetrics.ErrorTypeAuth
+	}
+	if isRetryable(err) {
+		return yukmetrics.ErrorTypeNetwork
+	}
+	return yukmetrics.ErrorTypeRepository
+}
+
+// retryBackoff returns the delay before retrying after the given number of consecutive
+// failures, doubling from retryBaseDelay and capped at the check interval
+func retryBackoff(failures int32, checkInterval time.Duration) time.Duration {
+	delay := retryBaseDelay
+	for i := int32(1); i < failures && delay < checkInterval; i++ {
+		delay *= 2
+	}
+
+	if delay > checkInterval {
+		return checkInterval
+	}
+	return delay
+}
+
+// failureBackoff returns the delay before checking again after the given number of
+// consecutive permanent failures: the check interval after the first failure, doubling
+// with every further one up to maxBackoff. Checks stay at the check interval when
+// maxBackoff is not longer than it.
+func failureBackoff(failures int32, checkInterval, maxBackoff time.Duration) time.Duration {
+	if maxBackoff <= checkInterval {
+		return checkInterval
+	}
+
+	delay := checkInterval
+	for i := int32(1); i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
+
+// recordFailure records a failed reconcile on the Ready condition and returns when to
+// requeue. Transient failures are retried with backoff and reported as Retrying with the
+// attempt count and next attempt time; permanent failures are reported as Failed (or
+// AuthError for missing or rejected credentials, ValidationError for unexpected target
+// values and unsigned images) and checked again at the check interval, backing off up to MaxFailureBackoff
+// while they persist. Credential failures also set the AuthReady condition to False. Once
+// Spec.MaxConsecutiveFailures is reached, the Degraded condition is set and no requeue is returned.
+func (r *YukConfigReconciler) recordFailure(yukConfig *yukv1.YukConfig, stage string, err error, checkInterval time.Duration, now time.Time) time.Duration {
+	yukConfig.Status.ConsecutiveFailures++
+	failures := yukConfig.Status.ConsecutiveFailures
+	defer r.setAuthFailure(yukConfig, fmt.Sprintf("%s: %v", stage, err), err)
+
+	if !isRetryable(err) {
+		reason := ReasonFailed
+		switch {
+		case isAuthError(err):
+			reason = ReasonAuthError
+		case errors.Is(err, yaml.ErrUnexpectedValue), errors.Is(err, verify.ErrVerificationFailed):
+			reason = ReasonValidationError
+		}
+
+		r.setFailure(yukConfig, reason, fmt.Sprintf("%s: %v", stage, err))
+		if r.stopChecks(yukConfig) {
+			return 0
+		}
+		return failureBackoff(failures, checkInterval, r.MaxFailureBackoff)
+	}
+
+	if limit := yukConfig.Spec.MaxConsecutiveFailures; limit != nil && failures >= *limit {
+		r.setFailure(yukConfig, ReasonRetrying, fmt.Sprintf("%s (attempt %d, no further attempts): %v", stage, failures, err))
+		r.stopChecks(yukConfig)
+		return 0
+	}
+	requeueAfter := retryBackoff(failures, checkInterval)
+	r.setFailure(yukConfig, ReasonRetrying,
+		fmt.Sprintf("%s (attempt %d, next attempt at %s): %v",
+			stage, failures, now.Add(requeueAfter).UTC().Format(time.RFC3339), err))
+	return requeueAfter
+}
+
+// stopChecks sets the Degraded condition and reports true once Spec.MaxConsecutiveFailures
+// reconciles failed in a row
+func (r *YukConfigReconciler) stopChecks(yukConfig *yukv1.YukConfig) bool {
+	limit := yukConfig.Spec.MaxConsecutiveFailures
+	if limit == nil || yukConfig.Status.ConsecutiveFailures < *limit {
+		return false
+	}
+
+	message := fmt.Sprintf("Stopped checking after %d consecutive failures; change the spec or request a reconcile to resume",
+		yukConfig.Status.ConsecutiveFailures)
+	r.setCondition(yukConfig, ConditionDegraded, metav1.ConditionTrue, ReasonMaxFailuresReached, message)
+	r.recordEvent(yukConfig, corev1.EventTypeWarning, ReasonMaxFailuresReached, "%s", message)
+	return true
+}
+
+// checksStopped reports whether the Degraded condition stopped the checks
+func checksStopped(yukConfig *yukv1.YukConfig) bool {
+	for _, condition := range yukConfig.Status.Conditions {
+		if condition.Type == ConditionDegraded {
+			return condition.Status == metav1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// resumeChecks ends a stop after Spec.MaxConsecutiveFailures, resetting the failure count
+// so the checks get as many attempts again
+func (r *YukConfigReconciler) resumeChecks(yukConfig *yukv1.YukConfig, why string) {
+	yukConfig.Status.ConsecutiveFailures = 0
+	r.setCondition(yukConfig, ConditionDegraded, metav1.ConditionFalse, ReasonResumed, "Checks resumed after "+why)
+}
+
+// setFailure sets the Ready condition to False and emits a warning event with the same
+// reason and message
+func (r *YukConfigReconciler) setFailure(yukConfig *yukv1.YukConfig, reason, message string) {
+	r.setCondition(yukConfig, "Ready", metav1.ConditionFalse, reason, message)
+	r.recordEvent(yukConfig, corev1.EventTypeWarning, reason, "%s", message)
+}
+
+// nextCheckInterval returns the interval between checks: the post-update requeue right
+// after a pushed update, the time until the update window opens when an update was deferred
+// to it sooner than the check interval, the retry backoff while a transient failure is being retried,
+// the failure backoff while a permanent failure persists, the check interval otherwise.
+// Spec changes end the failure backoff, as they may fix the failure.
+func (r *YukConfigReconciler) nextCheckInterval(yukConfig *yukv1.YukConfig, checkInterval time.Duration) time.Duration {
+	if requeue, ok := postUpdateRequeue(yukConfig); ok {
+		return requeue
+	}
+	if requeue, ok := deferredUpdateRequeue(yukConfig); ok && requeue < checkInterval {
+		return requeue
+	}
+
+	for _, condition := range yukConfig.Status.Conditions {
+		if condition.Type != "Ready" || condition.Status != metav1.ConditionFalse || yukConfig.Status.ConsecutiveFailures == 0 {
+			continue
+		}
+
+		switch condition.Reason {
+		case ReasonRetrying:
+			return retryBackoff(yukConfig.Status.ConsecutiveFailures, checkInterval)
+		case ReasonFailed, ReasonAuthError, ReasonValidationError:
+			if yukConfig.Generation == yukConfig.Status.ObservedGeneration {
+				return failureBackoff(yukConfig.Status.ConsecutiveFailures, checkInterval, r.MaxFailureBackoff)
+			}
+		}
+	}
+
+	return checkInterval
+}
+
+// postUpdateRequeue returns the delay of the check verifying a pushed update, and whether
+// that check is pending
+func postUpdateRequeue(yukConfig *yukv1.YukConfig) (time.Duration, bool) {
+	if !yukConfig.Status.VerificationPending || yukConfig.Spec.PostUpdateRequeue == nil {
+		return 0, false
+	}
+	return yukConfig.Spec.PostUpdateRequeue.Duration, true
+}
+
+// deferredUpdateRequeue returns the delay from the last check until the update window opens,
+// and whether an update is deferred to it
+func deferredUpdateRequeue(yukConfig *yukv1.YukConfig) (time.Duration, bool) {
+	if yukConfig.Status.UpdateDeferredUntil == nil || yukConfig.Status.LastChecked == nil {
+		return 0, false
+	}
+	return max(yukConfig.Status.UpdateDeferredUntil.Sub(yukConfig.Status.LastChecked.Time), 0), true
+}
@@ -0,0 +1,504 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/git"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+	"github.com/rebelopsio/yuk/pkg/yaml"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "push conflict",
+			err:      fmt.Errorf("failed to push changes: %w", git.ErrPushConflict),
+			expected: true,
+		},
+		{
+			name:     "git network error",
+			err:      fmt.Errorf("failed to clone repository: %w", git.ErrNetwork),
+			expected: true,
+		},
+		{
+			name:     "network error",
+			err:      fmt.Errorf("failed to describe images: %w", &net.DNSError{Err: "no such host", Name: "api.ecr.us-east-1.amazonaws.com"}),
+			expected: true,
+		},
+		{
+			name:     "throttling",
+			err:      fmt.Errorf("failed to describe images: %w", &smithy.GenericAPIError{Code: "ThrottlingException"}),
+			expected: true,
+		},
+		{
+			name:     "access denied",
+			err:      fmt.Errorf("failed to describe images: %w", &smithy.GenericAPIError{Code: "AccessDeniedException"}),
+			expected: false,
+		},
+		{
+			name:     "configuration error",
+			err:      errors.New("unsupported repository type: gcr"),
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.expected {
+				t.Errorf("Expected isRetryable to be %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		failures int32
+		expected time.Duration
+	}{
+		{failures: 1, expected: 30 * time.Second},
+		{failures: 2, expected: time.Minute},
+		{failures: 3, expected: 2 * time.Minute},
+		{failures: 4, expected: 4 * time.Minute},
+		{failures: 5, expected: 5 * time.Minute},
+		{failures: 50, expected: 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d failures", tt.failures), func(t *testing.T) {
+			if got := retryBackoff(tt.failures, 5*time.Minute); got != tt.expected {
+				t.Errorf("Expected backoff %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFailureBackoff(t *testing.T) {
+	tests := []struct {
+		failures   int32
+		maxBackoff time.Duration
+		expected   time.Duration
+	}{
+		{failures: 1, maxBackoff: time.Hour, expected: 5 * time.Minute},
+		{failures: 2, maxBackoff: time.Hour, expected: 10 * time.Minute},
+		{failures: 3, maxBackoff: time.Hour, expected: 20 * time.Minute},
+		{failures: 4, maxBackoff: time.Hour, expected: 40 * time.Minute},
+		{failures: 5, maxBackoff: time.Hour, expected: time.Hour},
+		{failures: 50, maxBackoff: time.Hour, expected: time.Hour},
+		{failures: 3, maxBackoff: 0, expected: 5 * time.Minute},
+		{failures: 3, maxBackoff: time.Minute, expected: 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d failures up to %s", tt.failures, tt.maxBackoff), func(t *testing.T) {
+			if got := failureBackoff(tt.failures, 5*time.Minute, tt.maxBackoff); got != tt.expected {
+				t.Errorf("Expected backoff %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_recordFailure(t *testing.T) {
+	reconciler := &YukConfigReconciler{}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	checkInterval := 5 * time.Minute
+
+	yukConfig := &yukv1.YukConfig{}
+	yukConfig.Status.ConsecutiveFailures = 1
+
+	// A transient failure is retried with backoff
+	requeueAfter := reconciler.recordFailure(yukConfig, "Update failed", git.ErrPushConflict, checkInterval, now)
+	if requeueAfter != time.Minute {
+		t.Errorf("Expected requeue after 1m, got %s", requeueAfter)
+	}
+	if yukConfig.Status.ConsecutiveFailures != 2 {
+		t.Errorf("Expected 2 consecutive failures, got %d", yukConfig.Status.ConsecutiveFailures)
+	}
+
+	ready := yukConfig.Status.Conditions[0]
+	if ready.Reason != ReasonRetrying {
+		t.Errorf("Expected reason %s, got %s", ReasonRetrying, ready.Reason)
+	}
+	if !strings.Contains(ready.Message, "attempt 2") || !strings.Contains(ready.Message, "2024-01-01T12:01:00Z") {
+		t.Errorf("Expected message with attempt count and next attempt time, got %q", ready.Message)
+	}
+	if got := reconciler.nextCheckInterval(yukConfig, checkInterval); got != time.Minute {
+		t.Errorf("Expected next check interval 1m while retrying, got %s", got)
+	}
+
+	// A permanent failure waits for the regular check interval
+	requeueAfter = reconciler.recordFailure(yukConfig, "Repository check failed", errors.New("invalid tag filter regex"), checkInterval, now)
+	if requeueAfter != checkInterval {
+		t.Errorf("Expected requeue after %s, got %s", checkInterval, requeueAfter)
+	}
+
+	ready = yukConfig.Status.Conditions[0]
+	if ready.Reason != ReasonFailed {
+		t.Errorf("Expected reason %s, got %s", ReasonFailed, ready.Reason)
+	}
+	if got := reconciler.nextCheckInterval(yukConfig, checkInterval); got != checkInterval {
+		t.Errorf("Expected next check interval %s after a permanent failure, got %s", checkInterval, got)
+	}
+}
+
+func TestYukConfigReconciler_nextCheckInterval_FailureBackoff(t *testing.T) {
+	reconciler := &YukConfigReconciler{MaxFailureBackoff: time.Hour}
+	checkInterval := 5 * time.Minute
+
+	yukConfig := &yukv1.YukConfig{}
+	yukConfig.Generation = 1
+	yukConfig.Status.ObservedGeneration = 1
+	yukConfig.Status.ConsecutiveFailures = 2
+	reconciler.recordFailure(yukConfig, "Repository check failed", errors.New("access denied"), checkInterval, time.Now())
+
+	// Three consecutive permanent failures back off to 20m
+	if got := reconciler.nextCheckInterval(yukConfig, checkInterval); got != 20*time.Minute {
+		t.Errorf("Expected next check interval 20m after 3 failures, got %s", got)
+	}
+
+	// A spec change may fix the failure, so it is checked at the regular interval
+	yukConfig.Generation = 2
+	if got := reconciler.nextCheckInterval(yukConfig, checkInterval); got != checkInterval {
+		t.Errorf("Expected next check interval %s after a spec change, got %s", checkInterval, got)
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_FailureBackoff(t *testing.T) {
+	failing := true
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors": [{"code": "DENIED", "message": "access denied"}]}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "project/app", "tags": []string{"v1.0.0"}})
+	}))
+	defer registry.Close()
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					Insecure:       true,
+				},
+			},
+			Git:           yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+			CheckInterval: &metav1.Duration{Duration: 5 * time.Minute},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+	}
+
+	trigger := NewReconcileTrigger()
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:            scheme,
+		Trigger:           trigger,
+		MaxFailureBackoff: 15 * time.Minute,
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	reconcile := func() time.Duration {
+		t.Helper()
+		// Trigger every reconcile so none is skipped as too early
+		trigger.Enqueue(yukConfig)
+		result, err := reconciler.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		return result.RequeueAfter
+	}
+
+	// The requeue delay doubles with every failing reconcile, up to the maximum
+	for i, expected := range []time.Duration{5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 15 * time.Minute} {
+		if requeueAfter := reconcile(); requeueAfter != expected {
+			t.Errorf("Expected requeue after %s on failure %d, got %s", expected, i+1, requeueAfter)
+		}
+	}
+
+	// A successful reconcile resets the failure count and the backoff
+	failing = false
+	if requeueAfter := reconcile(); requeueAfter != 5*time.Minute {
+		t.Errorf("Expected requeue after 5m on success, got %s", requeueAfter)
+	}
+	updated := &yukv1.YukConfig{}
+	if err := reconciler.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Failed to get YukConfig: %v", err)
+	}
+	if updated.Status.ConsecutiveFailures != 0 {
+		t.Errorf("Expected no consecutive failures after success, got %d", updated.Status.ConsecutiveFailures)
+	}
+
+	failing = true
+	if requeueAfter := reconcile(); requeueAfter != 5*time.Minute {
+		t.Errorf("Expected requeue after 5m on the first failure after success, got %s", requeueAfter)
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_MaxConsecutiveFailures(t *testing.T) {
+	failing := true
+	requests := 0
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if failing {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors": [{"code": "DENIED", "message": "access denied"}]}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "project/app", "tags": []string{"v1.0.0"}})
+	}))
+	defer registry.Close()
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	maxFailures := int32(3)
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					Insecure:       true,
+				},
+			},
+			Git:                    yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+			CheckInterval:          &metav1.Duration{Duration: 5 * time.Minute},
+			MaxConsecutiveFailures: &maxFailures,
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+	}
+
+	trigger := NewReconcileTrigger()
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:  scheme,
+		Trigger: trigger,
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	reconcile := func() time.Duration {
+		t.Helper()
+		// Trigger every reconcile so none is skipped as too early
+		trigger.Enqueue(yukConfig)
+		result, err := reconciler.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		return result.RequeueAfter
+	}
+	getStatus := func() *yukv1.YukConfig {
+		t.Helper()
+		updated := &yukv1.YukConfig{}
+		if err := reconciler.Get(ctx, req.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get YukConfig: %v", err)
+		}
+		return updated
+	}
+	degradedStatus := func(yukConfig *yukv1.YukConfig) metav1.ConditionStatus {
+		for _, condition := range yukConfig.Status.Conditions {
+			if condition.Type == ConditionDegraded {
+				return condition.Status
+			}
+		}
+		return ""
+	}
+
+	// Failures are checked again until the limit is reached
+	for i := 1; i < int(maxFailures); i++ {
+		if requeueAfter := reconcile(); requeueAfter != 5*time.Minute {
+			t.Errorf("Expected requeue after 5m on failure %d, got %s", i, requeueAfter)
+		}
+	}
+	if requeueAfter := reconcile(); requeueAfter != 0 {
+		t.Errorf("Expected no requeue after %d failures, got %s", maxFailures, requeueAfter)
+	}
+	updated := getStatus()
+	if status := degradedStatus(updated); status != metav1.ConditionTrue {
+		t.Errorf("Expected Degraded True after %d failures, got %q", maxFailures, status)
+	}
+	if updated.Status.ConsecutiveFailures != maxFailures {
+		t.Errorf("Expected %d consecutive failures, got %d", maxFailures, updated.Status.ConsecutiveFailures)
+	}
+
+	// Stopped checks neither call the registry nor requeue
+	calls := requests
+	if requeueAfter := reconcile(); requeueAfter != 0 {
+		t.Errorf("Expected no requeue while checks are stopped, got %s", requeueAfter)
+	}
+	if requests != calls {
+		t.Errorf("Expected no registry requests while checks are stopped, got %d", requests-calls)
+	}
+
+	// A requested reconcile resumes the checks and resets the failure count
+	failing = false
+	updated = getStatus()
+	updated.Annotations = map[string]string{yukv1.ReconcileRequestAnnotation: "now"}
+	if err := reconciler.Update(ctx, updated); err != nil {
+		t.Fatalf("Failed to update YukConfig: %v", err)
+	}
+	if requeueAfter := reconcile(); requeueAfter != 5*time.Minute {
+		t.Errorf("Expected requeue after 5m once resumed, got %s", requeueAfter)
+	}
+	updated = getStatus()
+	if status := degradedStatus(updated); status != metav1.ConditionFalse {
+		t.Errorf("Expected Degraded False once resumed, got %q", status)
+	}
+	if updated.Status.ConsecutiveFailures != 0 {
+		t.Errorf("Expected no consecutive failures once resumed, got %d", updated.Status.ConsecutiveFailures)
+	}
+}
+
+func TestYukConfigReconciler_recordFailure_Reasons(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedReason string
+	}{
+		{
+			name:           "permanent failure",
+			err:            errors.New("invalid tag filter regex"),
+			expectedReason: ReasonFailed,
+		},
+		{
+			name:           "missing credentials",
+			err:            fmt.Errorf("failed to resolve Git SSH key: %w", errMissingCredentials),
+			expectedReason: ReasonAuthError,
+		},
+		{
+			name:           "unexpected target value",
+			err:            fmt.Errorf("failed to update file values.yaml: %w", yaml.ErrUnexpectedValue),
+			expectedReason: ReasonValidationError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(1)
+			reconciler := &YukConfigReconciler{Recorder: recorder}
+			yukConfig := &yukv1.YukConfig{}
+
+			reconciler.recordFailure(yukConfig, "Update failed", tt.err, 5*time.Minute, time.Now())
+
+			ready := yukConfig.Status.Conditions[0]
+			if ready.Status != metav1.ConditionFalse {
+				t.Errorf("Expected Ready to be False, got %s", ready.Status)
+			}
+			if ready.Reason != tt.expectedReason {
+				t.Errorf("Expected reason %s, got %s", tt.expectedReason, ready.Reason)
+			}
+
+			// A warning event mirrors the condition
+			expectedEvent := fmt.Sprintf("Warning %s %s", tt.expectedReason, ready.Message)
+			if event := <-recorder.Events; event != expectedEvent {
+				t.Errorf("Expected event %q, got %q", expectedEvent, event)
+			}
+		})
+	}
+}
+
+func TestFileUpdateErrorType(t *testing.T) {
+	if errorType := fileUpdateErrorType(fmt.Errorf("failed: %w", yaml.ErrUnexpectedValue)); errorType != yukmetrics.ErrorTypeValidation {
+		t.Errorf("Expected error type %s, got %s", yukmetrics.ErrorTypeValidation, errorType)
+	}
+	if errorType := fileUpdateErrorType(yaml.ErrUpdateProducedInvalidYAML); errorType != yukmetrics.ErrorTypeYAML {
+		t.Errorf("Expected error type %s, got %s", yukmetrics.ErrorTypeYAML, errorType)
+	}
+}
+
+func TestRepositoryErrorType(t *testing.T) {
+	throttled := fmt.Errorf("failed to describe images: %w", &smithy.GenericAPIError{Code: "ProvisionedThroughputExceededException"})
+	if errorType := repositoryErrorType(throttled); errorType != yukmetrics.ErrorTypeNetwork {
+		t.Errorf("Expected error type %s, got %s", yukmetrics.ErrorTypeNetwork, errorType)
+	}
+	denied := fmt.Errorf("failed to describe images: %w", &smithy.GenericAPIError{Code: "AccessDeniedException"})
+	if errorType := repositoryErrorType(denied); errorType != yukmetrics.ErrorTypeAuth {
+		t.Errorf("Expected error type %s, got %s", yukmetrics.ErrorTypeAuth, errorType)
+	}
+	notFound := fmt.Errorf("failed to describe images: %w", &smithy.GenericAPIError{Code: "RepositoryNotFoundException"})
+	if errorType := repositoryErrorType(notFound); errorType != yukmetrics.ErrorTypeRepository {
+		t.Errorf("Expected error type %s, got %s", yukmetrics.ErrorTypeRepository, errorType)
+	}
+}
+
+func TestRecordPushRetry(t *testing.T) {
+	repository := "https://github.com/example/push-retry.git"
+	labels := func(result yukmetrics.GitOperationResult) prometheus.Labels {
+		return prometheus.Labels{
+			"operation":  string(yukmetrics.GitOperationPushRetry),
+			"repository": repository,
+			"result":     string(result),
+		}
+	}
+
+	recordPushRetry(context.Background(), repository, 1, nil)
+	recordPushRetry(context.Background(), repository, 2, git.ErrPushConflict)
+
+	if value := testutil.ToFloat64(yukmetrics.GitOperations.With(labels(yukmetrics.GitOperationSuccess))); value != 1 {
+		t.Errorf("Expected 1 successful push retry, got %v", value)
+	}
+	if value := testutil.ToFloat64(yukmetrics.GitOperations.With(labels(yukmetrics.GitOperationError))); value != 1 {
+		t.Errorf("Expected 1 failed push retry, got %v", value)
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+	"github.com/rebelopsio/yuk/pkg/notify"
+	"github.com/rebelopsio/yuk/pkg/yaml"
+)
+
+// errNoPreviousTag means a rollback was requested before an update replaced a tag
+var errNoPreviousTag = errors.New("no previous tag to roll back to")
+
+// rollbackRequested reports whether the rollback annotation is set
+func rollbackRequested(yukConfig *yukv1.YukConfig) bool {
+	return yukConfig.Annotations[yukv1.RollbackAnnotation] == "true"
+}
+
+// rollbackTargetTags returns the value to write to each update target for a rollback:
+// the previous tag for targets written with the default source's tag. Targets with their
+// own source, tag filter or digest are left unchanged, as their previous values are not
+// recorded.
+func rollbackTargetTags(yukConfig *yukv1.YukConfig) []string {
+	primary := defaultSource(yukConfig).name
+
+	targetTags := make([]string, len(yukConfig.Spec.UpdateTargets))
+	for i, target := range yukConfig.Spec.UpdateTargets {
+		if targetSource(yukConfig, target) == primary && target.TagFilter == "" && !target.ByDigest {
+			targetTags[i] = yukConfig.Status.PreviousTag
+		}
+	}
+	return targetTags
+}
+
+// reconcileRollback reverts the last pushed update to the previous tag with a revert
+// commit pushed to the configured branch, whatever the update strategy. The rollback
+// annotation is removed once the rollback is pushed and recorded in the status, or when
+// there is no previous tag; failed pushes are retried like updates, and a request for a
+// rollback that was recorded already is a no-op. It returns the result of the reconcile.
+func (r *YukConfigReconciler) reconcileRollback(ctx context.Context, yukConfig *yukv1.YukConfig, creds *credentials, notifiers []notify.Notifier, summary *ReconcileSummary, checkInterval time.Duration, now metav1.Time) (ctrl.Result, yukmetrics.ReconciliationResult, error) {
+	logger := log.FromContext(ctx)
+	rolledBack := yukConfig.Status.CurrentTag
+	previous := yukConfig.Status.PreviousTag
+
+	// The last rollback consumed the previous tag. Its annotation is only removed once the
+	// status is saved, so a request seen again, e.g. because removing the annotation failed,
+	// was handled already and must not be reported as failed.
+	if previous == "" && yukConfig.Status.RolledBackTag != "" {
+		logger.Info("Rollback already handled, removing the annotation", "rolledBack", yukConfig.Status.RolledBackTag)
+		r.updateStatusMetrics(yukConfig)
+		if err := r.updateStatus(ctx, yukConfig); err != nil {
+			return ctrl.Result{}, yukmetrics.ReconciliationError, err
+		}
+		if err := r.clearRollback(ctx, yukConfig); err != nil {
+			return ctrl.Result{}, yukmetrics.ReconciliationError, err
+		}
+		return ctrl.Result{RequeueAfter: r.withJitter(checkInterval)}, yukmetrics.ReconciliationSuccess, nil
+	}
+
+	logger.Info("Rolling back last update", "current", rolledBack, "previous", previous)
+
+	outcome, err := r.rollback(ctx, yukConfig, creds)
+	if errors.Is(err, errRepositoryBusy) {
+		logger.Info("Git repository busy, retrying the rollback", "repository", yukConfig.Spec.Git.Repository, "retryAfter", repositoryBusyRetryDelay)
+		return ctrl.Result{RequeueAfter: repositoryBusyRetryDelay}, yukmetrics.ReconciliationSkipped, nil
+	}
+	if err != nil {
+		logger.Error(err, "Failed to roll back")
+		errorType := gitErrorType(err)
+		if errors.Is(err, errNoPreviousTag) {
+			errorType = yukmetrics.ErrorTypeValidation
+		}
+		yukmetrics.ErrorsTotal.With(prometheus.Labels{
+			"error_type": string(errorType),
+			"namespace":  yukConfig.Namespace,
+			"name":       yukConfig.Name,
+		}).Inc()
+		requeueAfter := r.recordFailure(yukConfig, "Rollback failed", err, checkInterval, now.Time)
+		r.notify(ctx, yukConfig, notifiers, notify.Event{
+			Type:    notify.EventError,
+			OldTag:  rolledBack,
+			NewTag:  previous,
+			Message: fmt.Sprintf("Rollback failed: %v", err),
+		})
+		r.updateStatusMetrics(yukConfig)
+		if statusErr := r.updateStatus(ctx, yukConfig); statusErr != nil {
+			return ctrl.Result{}, yukmetrics.ReconciliationError, statusErr
+		}
+
+		// Without a previous tag, retrying cannot succeed
+		if errors.Is(err, errNoPreviousTag) {
+			if err := r.clearRollback(ctx, yukConfig); err != nil {
+				return ctrl.Result{}, yukmetrics.ReconciliationError, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: requeueAfter}, yukmetrics.ReconciliationError, nil
+	}
+
+	// The restored tag is current again; the reverted tag is not updated to again
+	yukConfig.Status.CurrentTag = previous
+	yukConfig.Status.PreviousTag = ""
+	yukConfig.Status.RolledBackTag = rolledBack
+	yukConfig.Status.LastUpdate = &now
+	yukConfig.Status.LastChangedFileCount = int32(len(outcome.FilesChanged))
+	yukConfig.Status.ConsecutiveFailures = 0
+	summary.NewTag = previous
+	summary.FilesChanged = outcome.FilesChanged
+	summary.Commit = outcome.Commit
+
+	message := fmt.Sprintf("Rolled back from %s to %s", rolledBack, previous)
+	if outcome.Commit != "" {
+		yukConfig.Status.LastCommitSHA = outcome.Commit
+		recordHistory(yukConfig, now, rolledBack, previous, outcome)
+		message = fmt.Sprintf("%s in commit %s", message, outcome.Commit)
+	}
+	r.recordEvent(yukConfig, corev1.EventTypeNormal, ReasonRolledBack, "%s", message)
+	r.checkUpToDate(yukConfig)
+	r.setCondition(yukConfig, "Ready", metav1.ConditionTrue, ReasonRolledBack, message)
+	r.setAuthenticated(yukConfig)
+	r.notify(ctx, yukConfig, notifiers, notify.Event{
+		Type:    notify.EventUpdate,
+		OldTag:  rolledBack,
+		NewTag:  previous,
+		Message: message,
+	})
+	logger.Info("Successfully rolled back", "newTag", previous, "commit", outcome.Commit)
+
+	r.updateStatusMetrics(yukConfig)
+	if err := r.updateStatus(ctx, yukConfig); err != nil {
+		return ctrl.Result{}, yukmetrics.ReconciliationError, err
+	}
+	if err := r.clearRollback(ctx, yukConfig); err != nil {
+		return ctrl.Result{}, yukmetrics.ReconciliationError, err
+	}
+
+	return ctrl.Result{RequeueAfter: r.withJitter(checkInterval)}, yukmetrics.ReconciliationSuccess, nil
+}
+
+// rollback writes the previous tag to the update targets and pushes a revert commit
+func (r *YukConfigReconciler) rollback(ctx context.Context, yukConfig *yukv1.YukConfig, creds *credentials) (*updateOutcome, error) {
+	if yukConfig.Status.PreviousTag == "" {
+		return nil, errNoPreviousTag
+	}
+
+	return r.updateFiles(ctx, yukConfig, r.gitClientFactory(ctx, yukConfig, creds), yaml.NewUpdater(), yukConfig.Status.PreviousTag,
+		rollbackTargetTags(yukConfig), nil, ActionRollback)
+}
+
+// clearRollback removes the rollback annotation. The status must be saved first, as the
+// patch response replaces it.
+func (r *YukConfigReconciler) clearRollback(ctx context.Context, yukConfig *yukv1.YukConfig) error {
+	return r.removeAnnotation(ctx, yukConfig, yukv1.RollbackAnnotation)
+}
+
+// removeAnnotation removes a one-shot request annotation once it is handled. The status
+// must be saved first, as the patch response replaces it.
+func (r *YukConfigReconciler) removeAnnotation(ctx context.Context, yukConfig *yukv1.YukConfig, annotation string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{annotation: nil},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s annotation patch: %w", annotation, err)
+	}
+	if err := r.Patch(ctx, yukConfig, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return fmt.Errorf("failed to remove %s annotation: %w", annotation, err)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestRollbackTargetTags(t *testing.T) {
+	yukConfig := &yukv1.YukConfig{
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{Type: RepositoryTypeECR, ECR: &yukv1.ECRConfig{RepositoryName: "app"}},
+			Sources: []yukv1.ImageSource{
+				{Name: "sidecar", RepositoryConfig: yukv1.RepositoryConfig{Type: RepositoryTypeECR, ECR: &yukv1.ECRConfig{RepositoryName: "sidecar"}}},
+			},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "app.yaml", YAMLPath: "image.tag"},
+				{File: "app.yaml", YAMLPath: "sidecar.tag", Source: "sidecar"},
+				{File: "migration.yaml", YAMLPath: "image.tag", TagFilter: "^migrate-"},
+				{File: "app.yaml", YAMLPath: "image.digest", ByDigest: true},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.1.0", PreviousTag: "v1.0.0"},
+	}
+
+	targetTags := rollbackTargetTags(yukConfig)
+	expected := []string{"v1.0.0", "", "", ""}
+	for i := range expected {
+		if targetTags[i] != expected[i] {
+			t.Errorf("Expected target tags %v, got %v", expected, targetTags)
+			break
+		}
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_Rollback(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "project/app", "tags": []string{"v1.0.0", "v1.1.0"}})
+	}))
+	defer registry.Close()
+
+	upstream := newUpstreamRepository(t, map[string]string{
+		"values.yaml": "image:\n    repository: my-app\n    tag: v1.0.0\n",
+	})
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					SortStrategy:   "semver",
+					Insecure:       true,
+				},
+			},
+			Git: yukv1.GitConfig{
+				Repository: upstream,
+				Branch:     "main",
+				Email:      "test@example.com",
+				Name:       "Test User",
+			},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:       scheme,
+		CloneBaseDir: t.TempDir(),
+		Recorder:     record.NewFakeRecorder(10),
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	reconcile := func() *yukv1.YukConfig {
+		t.Helper()
+		if _, err := reconciler.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		updated := &yukv1.YukConfig{}
+		if err := reconciler.Get(ctx, req.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get YukConfig: %v", err)
+		}
+		return updated
+	}
+	upstreamFile := func() string {
+		t.Helper()
+		out, err := exec.Command("git", "-C", upstream, "show", "main:values.yaml").Output()
+		if err != nil {
+			t.Fatalf("Failed to read upstream file: %v", err)
+		}
+		return string(out)
+	}
+
+	// The update to v1.1.0 records the replaced tag
+	updated := reconcile()
+	if updated.Status.CurrentTag != "v1.1.0" || updated.Status.PreviousTag != "v1.0.0" {
+		t.Fatalf("Expected current v1.1.0 and previous v1.0.0, got %s and %s", updated.Status.CurrentTag, updated.Status.PreviousTag)
+	}
+
+	// The rollback is applied right away, without waiting for the check interval
+	updated.Annotations = map[string]string{yukv1.RollbackAnnotation: "true"}
+	if err := reconciler.Update(ctx, updated); err != nil {
+		t.Fatalf("Failed to annotate YukConfig: %v", err)
+	}
+	updated = reconcile()
+
+	if !strings.Contains(upstreamFile(), "tag: v1.0.0") {
+		t.Errorf("Expected v1.0.0 to be restored upstream, got:\n%s", upstreamFile())
+	}
+	out, err := exec.Command("git", "-C", upstream, "log", "-1", "--format=%s", "main").Output()
+	if err != nil {
+		t.Fatalf("Failed to read upstream commit: %v", err)
+	}
+	if message := strings.TrimSpace(string(out)); message != "Revert container image from v1.1.0 to v1.0.0" {
+		t.Errorf("Expected a revert commit, got %q", message)
+	}
+
+	if updated.Status.CurrentTag != "v1.0.0" || updated.Status.RolledBackTag != "v1.1.0" || updated.Status.PreviousTag != "" {
+		t.Errorf("Expected current v1.0.0, rolled back v1.1.0 and no previous tag, got %s, %s and %q",
+			updated.Status.CurrentTag, updated.Status.RolledBackTag, updated.Status.PreviousTag)
+	}
+	if _, ok := updated.Annotations[yukv1.RollbackAnnotation]; ok {
+		t.Error("Expected the rollback annotation to be removed")
+	}
+
+	// A repeated request, e.g. when removing the annotation failed, is a no-op
+	head, err := exec.Command("git", "-C", upstream, "rev-parse", "main").Output()
+	if err != nil {
+		t.Fatalf("Failed to read upstream head: %v", err)
+	}
+	updated.Annotations = map[string]string{yukv1.RollbackAnnotation: "true"}
+	if err := reconciler.Update(ctx, updated); err != nil {
+		t.Fatalf("Failed to annotate YukConfig: %v", err)
+	}
+	updated = reconcile()
+
+	if after, _ := exec.Command("git", "-C", upstream, "rev-parse", "main").Output(); string(after) != string(head) {
+		t.Error("Expected no commit for a repeated rollback request")
+	}
+	if updated.Status.CurrentTag != "v1.0.0" || updated.Status.RolledBackTag != "v1.1.0" || updated.Status.ConsecutiveFailures != 0 {
+		t.Errorf("Expected the rollback status to be kept, got current %s, rolled back %s and %d failures",
+			updated.Status.CurrentTag, updated.Status.RolledBackTag, updated.Status.ConsecutiveFailures)
+	}
+	if _, ok := updated.Annotations[yukv1.RollbackAnnotation]; ok {
+		t.Error("Expected the repeated rollback annotation to be removed")
+	}
+
+	// The rolled back tag is not updated to again
+	lastChecked := metav1.NewTime(time.Now().Add(-time.Hour))
+	updated.Status.LastChecked = &lastChecked
+	if err := reconciler.Status().Update(ctx, updated); err != nil {
+		t.Fatalf("Failed to update YukConfig status: %v", err)
+	}
+	updated = reconcile()
+
+	if !strings.Contains(upstreamFile(), "tag: v1.0.0") {
+		t.Errorf("Expected v1.0.0 to stay upstream, got:\n%s", upstreamFile())
+	}
+	for _, condition := range updated.Status.Conditions {
+		if condition.Type == "Ready" && condition.Reason != ReasonRolledBack {
+			t.Errorf("Expected Ready reason %s, got %s", ReasonRolledBack, condition.Reason)
+		}
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_RollbackWithoutPreviousTag(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-config",
+			Namespace:   "default",
+			Annotations: map[string]string{yukv1.RollbackAnnotation: "true"},
+		},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{Type: RepositoryTypeOCI, OCI: &yukv1.OCIConfig{Registry: "registry.example.com", RepositoryName: "app"}},
+			Git:        yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	updated := &yukv1.YukConfig{}
+	if err := reconciler.Get(context.Background(), req.NamespacedName, updated); err != nil {
+		t.Fatalf("Failed to get YukConfig: %v", err)
+	}
+	if _, ok := updated.Annotations[yukv1.RollbackAnnotation]; ok {
+		t.Error("Expected the rollback annotation to be removed")
+	}
+	if updated.Status.CurrentTag != "v1.0.0" {
+		t.Errorf("Expected current tag v1.0.0 to be kept, got %s", updated.Status.CurrentTag)
+	}
+	for _, condition := range updated.Status.Conditions {
+		if condition.Type == "Ready" && (condition.Status != metav1.ConditionFalse || !strings.Contains(condition.Message, "no previous tag")) {
+			t.Errorf("Expected Ready to be False for the missing previous tag, got %s: %s", condition.Status, condition.Message)
+		}
+	}
+}
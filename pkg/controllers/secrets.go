@@ -0,0 +1,360 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/acr"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	"github.com/rebelopsio/yuk/pkg/ghcr"
+	"github.com/rebelopsio/yuk/pkg/git"
+	"github.com/rebelopsio/yuk/pkg/oci"
+	"github.com/rebelopsio/yuk/pkg/quay"
+)
+
+// ReasonAuthError is the reason of the Ready condition when credentials cannot be resolved
+const ReasonAuthError = "AuthError"
+
+// errMissingCredentials means a referenced secret or secret key does not exist
+var errMissingCredentials = errors.New("missing credentials")
+
+// credentials holds the secrets resolved for a YukConfig
+type credentials struct {
+	gitToken             string
+	gitSSHKey            []byte
+	gitKnownHosts        []byte
+	gitApp               *yukv1.GitHubAppAuth
+	gitAppPrivateKey     []byte
+	gitSigningKey        []byte
+	gitSigningPassphrase []byte
+	slackWebhookURL      string
+	signaturePublicKey   []byte
+
+	// repositories holds the credentials of each source, keyed by source name
+	repositories map[string]*repositoryCredentials
+}
+
+// repositoryCredentials holds the secrets resolved for a monitored repository
+type repositoryCredentials struct {
+	ecrAccessKeyID     string
+	ecrSecretAccessKey string
+	ociPassword        string
+	ghcrToken          string
+	acrClientSecret    string
+	quayPassword       string
+	tlsConfig          *tls.Config
+}
+
+// repository returns the credentials of a source
+func (c *credentials) repository(source string) *repositoryCredentials {
+	if creds, ok := c.repositories[source]; ok {
+		return creds
+	}
+	return &repositoryCredentials{}
+}
+
+// gitOptions returns the git client options authenticating with the credentials
+func (c *credentials) gitOptions() []git.Option {
+	var opts []git.Option
+	if c.gitToken != "" {
+		opts = append(opts, git.WithToken(c.gitToken))
+	}
+	if len(c.gitSSHKey) > 0 {
+		opts = append(opts, git.WithSSHKey(c.gitSSHKey))
+	}
+	if len(c.gitKnownHosts) > 0 {
+		opts = append(opts, git.WithKnownHosts(c.gitKnownHosts))
+	}
+	if c.gitApp != nil {
+		opts = append(opts, git.WithGitHubApp(c.gitApp.AppID, c.gitApp.InstallationID, c.gitAppPrivateKey))
+		if c.gitApp.APIURL != "" {
+			opts = append(opts, git.WithGitHubAPIURL(c.gitApp.APIURL))
+		}
+	}
+	if len(c.gitSigningKey) > 0 {
+		opts = append(opts, git.WithSigningKey(c.gitSigningKey, c.gitSigningPassphrase))
+	}
+	return opts
+}
+
+// ecrOptions returns the ECR client options authenticating with the credentials
+func (c *repositoryCredentials) ecrOptions() []ecr.Option {
+	if c.ecrAccessKeyID == "" {
+		return nil
+	}
+	return []ecr.Option{ecr.WithStaticCredentials(c.ecrAccessKeyID, c.ecrSecretAccessKey)}
+}
+
+// ociOptions returns the OCI client options authenticating with the credentials
+func (c *repositoryCredentials) ociOptions(config *yukv1.OCIConfig) []oci.Option {
+	var opts []oci.Option
+	if config.Auth.Username != "" {
+		opts = append(opts, oci.WithBasicAuth(config.Auth.Username, c.ociPassword))
+	}
+	if c.tlsConfig != nil {
+		opts = append(opts, oci.WithTLSConfig(c.tlsConfig))
+	}
+	return opts
+}
+
+// ghcrOptions returns the GHCR client options authenticating with the credentials
+func (c *repositoryCredentials) ghcrOptions() []ghcr.Option {
+	var opts []ghcr.Option
+	if c.ghcrToken != "" {
+		opts = append(opts, ghcr.WithToken(c.ghcrToken))
+	}
+	if c.tlsConfig != nil {
+		opts = append(opts, ghcr.WithTLSConfig(c.tlsConfig))
+	}
+	return opts
+}
+
+// acrOptions returns the ACR client options authenticating with the service principal of
+// the credentials, or with the managed identity selected by the client ID
+func (c *repositoryCredentials) acrOptions(config *yukv1.ACRConfig) []acr.Option {
+	if c.acrClientSecret != "" {
+		return []acr.Option{acr.WithServicePrincipal(config.Auth.ClientID, c.acrClientSecret)}
+	}
+	if config.Auth.ClientID != "" {
+		return []acr.Option{acr.WithTokenSource(acr.NewManagedIdentityTokenSource(config.Auth.ClientID))}
+	}
+	return nil
+}
+
+// quayOptions returns the Quay client options authenticating with the robot account of
+// the credentials
+func (c *repositoryCredentials) quayOptions(config *yukv1.QuayConfig) []quay.Option {
+	if config.Auth.Username == "" {
+		return nil
+	}
+	return []quay.Option{quay.WithBasicAuth(config.Auth.Username, c.quayPassword)}
+}
+
+// resolveCredentials reads the secrets referenced by the YukConfig from its namespace
+func (r *YukConfigReconciler) resolveCredentials(ctx context.Context, yukConfig *yukv1.YukConfig) (*credentials, error) {
+	creds := &credentials{repositories: make(map[string]*repositoryCredentials)}
+
+	// Git credentials
+	if ref := yukConfig.Spec.Git.Auth.PersonalAccessTokenRef; ref != nil {
+		token, err := r.resolveSecretKey(ctx, yukConfig.Namespace, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve Git personal access token: %w", err)
+		}
+		creds.gitToken = string(token)
+	}
+
+	if ref := yukConfig.Spec.Git.Auth.SSHKeyRef; ref != nil {
+		key, err := r.resolveSecretKey(ctx, yukConfig.Namespace, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve Git SSH key: %w", err)
+		}
+		creds.gitSSHKey = key
+	}
+
+	if ref := yukConfig.Spec.Git.Auth.KnownHostsRef; ref != nil {
+		knownHosts, err := r.resolveSecretKey(ctx, yukConfig.Namespace, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve Git known hosts: %w", err)
+		}
+		creds.gitKnownHosts = knownHosts
+	}
+
+	if app := yukConfig.Spec.Git.Auth.GitHubApp; app != nil {
+		key, err := r.resolveSecretKey(ctx, yukConfig.Namespace, &app.PrivateKeyRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve GitHub App private key: %w", err)
+		}
+		creds.gitApp = app
+		creds.gitAppPrivateKey = key
+	}
+
+	// Commit signing key
+	if ref := yukConfig.Spec.Git.SigningKeyRef; ref != nil {
+		key, err := r.resolveSecretKey(ctx, yukConfig.Namespace, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve Git signing key: %w", err)
+		}
+		creds.gitSigningKey = key
+
+		if ref := yukConfig.Spec.Git.SigningKeyPassphraseRef; ref != nil {
+			passphrase, err := r.resolveSecretKey(ctx, yukConfig.Namespace, ref)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve Git signing key passphrase: %w", err)
+			}
+			creds.gitSigningPassphrase = passphrase
+		}
+	}
+
+	// Notification webhooks
+	if notifications := yukConfig.Spec.Notifications; notifications != nil && notifications.Slack != nil {
+		webhookURL, err := r.resolveSecretKey(ctx, yukConfig.Namespace, &notifications.Slack.WebhookURLRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve Slack webhook URL: %w", err)
+		}
+		creds.slackWebhookURL = strings.TrimSpace(string(webhookURL))
+	}
+
+	// Public key image signatures are verified with
+	if verification := yukConfig.Spec.VerifySignature; verification != nil {
+		publicKey, err := r.resolveSecretKey(ctx, yukConfig.Namespace, &verification.PublicKeyRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve signature public key: %w", err)
+		}
+		creds.signaturePublicKey = publicKey
+	}
+
+	// Repository credentials of each source
+	for _, source := range imageSources(yukConfig) {
+		repositoryCreds, err := r.resolveRepositoryCredentials(ctx, yukConfig.Namespace, source.repository)
+		if err != nil {
+			if source.name != "" {
+				return nil, fmt.Errorf("source %s: %w", source.name, err)
+			}
+			return nil, err
+		}
+		creds.repositories[source.name] = repositoryCreds
+	}
+
+	return creds, nil
+}
+
+// resolveRepositoryCredentials reads the secrets referenced by a repository configuration
+func (r *YukConfigReconciler) resolveRepositoryCredentials(ctx context.Context, namespace string, repository *yukv1.RepositoryConfig) (*repositoryCredentials, error) {
+	creds := &repositoryCredentials{}
+
+	// ECR credentials; IRSA uses the default AWS credential chain
+	if ecrConfig := repository.ECR; ecrConfig != nil && !ecrConfig.Auth.UseIRSA && ecrConfig.Auth.SecretAccessKeyRef != nil {
+		if ecrConfig.Auth.AccessKeyID == "" {
+			return nil, fmt.Errorf("ECR accessKeyID is required with secretAccessKeyRef: %w", errMissingCredentials)
+		}
+
+		secretAccessKey, err := r.resolveSecretKey(ctx, namespace, ecrConfig.Auth.SecretAccessKeyRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve ECR secret access key: %w", err)
+		}
+		creds.ecrAccessKeyID = ecrConfig.Auth.AccessKeyID
+		creds.ecrSecretAccessKey = string(secretAccessKey)
+	}
+
+	// OCI registry credentials
+	if ociConfig := repository.OCI; ociConfig != nil && ociConfig.Auth.PasswordRef != nil {
+		if ociConfig.Auth.Username == "" {
+			return nil, fmt.Errorf("OCI username is required with passwordRef: %w", errMissingCredentials)
+		}
+
+		password, err := r.resolveSecretKey(ctx, namespace, ociConfig.Auth.PasswordRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve OCI registry password: %w", err)
+		}
+		creds.ociPassword = string(password)
+	}
+
+	// GHCR token; public images are read anonymously without one
+	if ghcrConfig := repository.GHCR; ghcrConfig != nil && ghcrConfig.TokenRef != nil {
+		token, err := r.resolveSecretKey(ctx, namespace, ghcrConfig.TokenRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve GHCR token: %w", err)
+		}
+		creds.ghcrToken = strings.TrimSpace(string(token))
+	}
+
+	// ACR service principal secret; the managed identity is used without one
+	if acrConfig := repository.ACR; acrConfig != nil && acrConfig.Auth.ClientSecretRef != nil {
+		if acrConfig.Auth.ClientID == "" {
+			return nil, fmt.Errorf("ACR clientID is required with clientSecretRef: %w", errMissingCredentials)
+		}
+
+		secret, err := r.resolveSecretKey(ctx, namespace, acrConfig.Auth.ClientSecretRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve ACR client secret: %w", err)
+		}
+		creds.acrClientSecret = strings.TrimSpace(string(secret))
+	}
+
+	// Quay robot account token; public repositories are read anonymously without one
+	if quayConfig := repository.Quay; quayConfig != nil && quayConfig.Auth.PasswordRef != nil {
+		if quayConfig.Auth.Username == "" {
+			return nil, fmt.Errorf("Quay username is required with passwordRef: %w", errMissingCredentials)
+		}
+
+		password, err := r.resolveSecretKey(ctx, namespace, quayConfig.Auth.PasswordRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve Quay robot token: %w", err)
+		}
+		creds.quayPassword = strings.TrimSpace(string(password))
+	}
+
+	// Registry TLS configuration, with the CA bundle of a private CA
+	var tlsConfig *yukv1.TLSConfig
+	switch {
+	case repository.Type == RepositoryTypeOCI && repository.OCI != nil:
+		tlsConfig = repository.OCI.TLS
+	case repository.Type == RepositoryTypeGHCR && repository.GHCR != nil:
+		tlsConfig = repository.GHCR.TLS
+	}
+	if tlsConfig != nil {
+		var caBundle []byte
+		if tlsConfig.CARef != nil {
+			var err error
+			if caBundle, err = r.resolveSecretKey(ctx, namespace, tlsConfig.CARef); err != nil {
+				return nil, fmt.Errorf("failed to resolve registry CA bundle: %w", err)
+			}
+		}
+		config, err := oci.NewTLSConfig(caBundle, tlsConfig.InsecureSkipVerify)
+		if err != nil {
+			return nil, fmt.Errorf("invalid registry CA bundle: %w", err)
+		}
+		creds.tlsConfig = config
+	}
+
+	return creds, nil
+}
+
+// resolveSecretKey returns the value of a key of a secret in the given namespace. The
+// secret is read from the API server rather than the cache, which would watch every
+// secret of the cluster.
+func (r *YukConfigReconciler) resolveSecretKey(ctx context.Context, namespace string, selector *yukv1.SecretKeySelector) ([]byte, error) {
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+
+	var secret corev1.Secret
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: selector.Name}, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("secret %s/%s not found: %w", namespace, selector.Name, errMissingCredentials)
+		}
+		return nil, fmt.Errorf("failed to get secret %s/%s: %w", namespace, selector.Name, err)
+	}
+
+	value, ok := secret.Data[selector.Key]
+	if !ok || len(value) == 0 {
+		return nil, fmt.Errorf("key %s not found in secret %s/%s: %w", selector.Key, namespace, selector.Name, errMissingCredentials)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,515 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/oci"
+)
+
+func TestYukConfigReconciler_resolveCredentials(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	secrets := []runtime.Object{
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "git-credentials", Namespace: "default"},
+			Data: map[string][]byte{
+				"token":      []byte("ghp_1234567890"),
+				"sshKey":     []byte("private-key"),
+				"knownHosts": []byte("github.com ssh-ed25519 AAAA"),
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "aws-credentials", Namespace: "default"},
+			Data:       map[string][]byte{"secretAccessKey": []byte("secret")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "git-credentials", Namespace: "other"},
+			Data:       map[string][]byte{"token": []byte("other-token")},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		git              yukv1.GitAuthConfig
+		ecr              yukv1.ECRAuthConfig
+		expected         credentials
+		expectedECR      repositoryCredentials
+		expectMissing    bool
+		expectOtherError bool
+	}{
+		{
+			name: "no references",
+		},
+		{
+			name: "git token, ssh key and known hosts",
+			git: yukv1.GitAuthConfig{
+				PersonalAccessTokenRef: &yukv1.SecretKeySelector{Name: "git-credentials", Key: "token"},
+				SSHKeyRef:              &yukv1.SecretKeySelector{Name: "git-credentials", Key: "sshKey"},
+				KnownHostsRef:          &yukv1.SecretKeySelector{Name: "git-credentials", Key: "knownHosts"},
+			},
+			expected: credentials{
+				gitToken:      "ghp_1234567890",
+				gitSSHKey:     []byte("private-key"),
+				gitKnownHosts: []byte("github.com ssh-ed25519 AAAA"),
+			},
+		},
+		{
+			name: "ecr static credentials",
+			ecr: yukv1.ECRAuthConfig{
+				AccessKeyID:        "AKIAEXAMPLE",
+				SecretAccessKeyRef: &yukv1.SecretKeySelector{Name: "aws-credentials", Key: "secretAccessKey"},
+			},
+			expectedECR: repositoryCredentials{ecrAccessKeyID: "AKIAEXAMPLE", ecrSecretAccessKey: "secret"},
+		},
+		{
+			name: "ecr IRSA ignores static credentials",
+			ecr: yukv1.ECRAuthConfig{
+				UseIRSA:            true,
+				AccessKeyID:        "AKIAEXAMPLE",
+				SecretAccessKeyRef: &yukv1.SecretKeySelector{Name: "missing", Key: "secretAccessKey"},
+			},
+		},
+		{
+			name: "missing secret",
+			git: yukv1.GitAuthConfig{
+				PersonalAccessTokenRef: &yukv1.SecretKeySelector{Name: "missing", Key: "token"},
+			},
+			expectMissing: true,
+		},
+		{
+			name: "missing key",
+			git: yukv1.GitAuthConfig{
+				PersonalAccessTokenRef: &yukv1.SecretKeySelector{Name: "git-credentials", Key: "password"},
+			},
+			expectMissing: true,
+		},
+		{
+			name: "ecr secret without access key ID",
+			ecr: yukv1.ECRAuthConfig{
+				SecretAccessKeyRef: &yukv1.SecretKeySelector{Name: "aws-credentials", Key: "secretAccessKey"},
+			},
+			expectMissing: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reconciler := &YukConfigReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(secrets...).Build(),
+				Scheme: scheme,
+			}
+
+			yukConfig := &yukv1.YukConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+				Spec: yukv1.YukConfigSpec{
+					Repository: yukv1.RepositoryConfig{
+						Type: "ecr",
+						ECR:  &yukv1.ECRConfig{Region: "us-east-1", RepositoryName: "test-repo", Auth: tt.ecr},
+					},
+					Git: yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Auth: tt.git},
+				},
+			}
+
+			creds, err := reconciler.resolveCredentials(context.Background(), yukConfig)
+			if tt.expectMissing {
+				if !errors.Is(err, errMissingCredentials) {
+					t.Errorf("Expected missing credentials error, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if creds.gitToken != tt.expected.gitToken {
+				t.Errorf("Expected git token %q, got %q", tt.expected.gitToken, creds.gitToken)
+			}
+			if string(creds.gitSSHKey) != string(tt.expected.gitSSHKey) {
+				t.Errorf("Expected git SSH key %q, got %q", tt.expected.gitSSHKey, creds.gitSSHKey)
+			}
+			if string(creds.gitKnownHosts) != string(tt.expected.gitKnownHosts) {
+				t.Errorf("Expected git known hosts %q, got %q", tt.expected.gitKnownHosts, creds.gitKnownHosts)
+			}
+			ecrCreds := creds.repository("")
+			if ecrCreds.ecrAccessKeyID != tt.expectedECR.ecrAccessKeyID {
+				t.Errorf("Expected ECR access key ID %q, got %q", tt.expectedECR.ecrAccessKeyID, ecrCreds.ecrAccessKeyID)
+			}
+			if ecrCreds.ecrSecretAccessKey != tt.expectedECR.ecrSecretAccessKey {
+				t.Errorf("Expected ECR secret access key %q, got %q", tt.expectedECR.ecrSecretAccessKey, ecrCreds.ecrSecretAccessKey)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_resolveCredentials_Sources(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-credentials", Namespace: "default"},
+		Data: map[string][]byte{
+			"secretAccessKey": []byte("secret"),
+			"password":        []byte("registry-password"),
+			"token":           []byte("ghp_token\n"),
+			"clientSecret":    []byte("sp-secret"),
+			"robotToken":      []byte("robot-token\n"),
+		},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(secret).Build(),
+		Scheme: scheme,
+	}
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Sources: []yukv1.ImageSource{
+				{
+					Name: "frontend",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: RepositoryTypeECR,
+						ECR: &yukv1.ECRConfig{
+							Region:         "us-east-1",
+							RepositoryName: "frontend",
+							Auth: yukv1.ECRAuthConfig{
+								AccessKeyID:        "AKIAEXAMPLE",
+								SecretAccessKeyRef: &yukv1.SecretKeySelector{Name: "registry-credentials", Key: "secretAccessKey"},
+							},
+						},
+					},
+				},
+				{
+					Name: "backend",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: RepositoryTypeOCI,
+						OCI: &yukv1.OCIConfig{
+							Registry:       "ghcr.io",
+							RepositoryName: "org/backend",
+							Auth: yukv1.OCIAuthConfig{
+								Username:    "bot",
+								PasswordRef: &yukv1.SecretKeySelector{Name: "registry-credentials", Key: "password"},
+							},
+						},
+					},
+				},
+				{
+					Name: "tools",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: RepositoryTypeGHCR,
+						GHCR: &yukv1.GHCRConfig{
+							Owner:    "org",
+							Image:    "tools",
+							TokenRef: &yukv1.SecretKeySelector{Name: "registry-credentials", Key: "token"},
+						},
+					},
+				},
+				{
+					Name: "azure",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: RepositoryTypeACR,
+						ACR: &yukv1.ACRConfig{
+							Registry: "myregistry",
+							Image:    "team/azure",
+							Auth: yukv1.ACRAuthConfig{
+								ClientID:        "sp-client",
+								ClientSecretRef: &yukv1.SecretKeySelector{Name: "registry-credentials", Key: "clientSecret"},
+							},
+						},
+					},
+				},
+				{
+					Name: "quay",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: RepositoryTypeQuay,
+						Quay: &yukv1.QuayConfig{
+							Namespace:  "rebelops",
+							Repository: "quay",
+							Auth: yukv1.QuayAuthConfig{
+								Username:    "rebelops+yuk",
+								PasswordRef: &yukv1.SecretKeySelector{Name: "registry-credentials", Key: "robotToken"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	creds, err := reconciler.resolveCredentials(context.Background(), yukConfig)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if _, ok := creds.repositories[""]; ok {
+		t.Errorf("Expected no credentials for the unset spec repository")
+	}
+	if key := creds.repository("frontend").ecrSecretAccessKey; key != "secret" {
+		t.Errorf("Expected frontend ECR secret access key %q, got %q", "secret", key)
+	}
+	if password := creds.repository("backend").ociPassword; password != "registry-password" {
+		t.Errorf("Expected backend OCI password %q, got %q", "registry-password", password)
+	}
+	if token := creds.repository("tools").ghcrToken; token != "ghp_token" {
+		t.Errorf("Expected tools GHCR token %q, got %q", "ghp_token", token)
+	}
+	if secret := creds.repository("azure").acrClientSecret; secret != "sp-secret" {
+		t.Errorf("Expected azure ACR client secret %q, got %q", "sp-secret", secret)
+	}
+	if password := creds.repository("quay").quayPassword; password != "robot-token" {
+		t.Errorf("Expected quay robot token %q, got %q", "robot-token", password)
+	}
+
+	// Errors name the source whose credentials are missing
+	yukConfig.Spec.Sources[1].OCI.Auth.Username = ""
+	_, err = reconciler.resolveCredentials(context.Background(), yukConfig)
+	if !errors.Is(err, errMissingCredentials) || !strings.Contains(err.Error(), "source backend") {
+		t.Errorf("Expected missing credentials error for source backend, got: %v", err)
+	}
+}
+
+func TestYukConfigReconciler_resolveCredentials_SigningKey(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "gpg-key", Namespace: "default"},
+		Data: map[string][]byte{
+			"key":        []byte("armored-key"),
+			"passphrase": []byte("s3cret"),
+		},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(secret).Build(),
+		Scheme: scheme,
+	}
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{Type: RepositoryTypeECR},
+			Git: yukv1.GitConfig{
+				Repository:              "https://github.com/example/repo.git",
+				SigningKeyRef:           &yukv1.SecretKeySelector{Name: "gpg-key", Key: "key"},
+				SigningKeyPassphraseRef: &yukv1.SecretKeySelector{Name: "gpg-key", Key: "passphrase"},
+			},
+		},
+	}
+
+	creds, err := reconciler.resolveCredentials(context.Background(), yukConfig)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if string(creds.gitSigningKey) != "armored-key" {
+		t.Errorf("Expected signing key %q, got %q", "armored-key", creds.gitSigningKey)
+	}
+	if string(creds.gitSigningPassphrase) != "s3cret" {
+		t.Errorf("Expected signing key passphrase %q, got %q", "s3cret", creds.gitSigningPassphrase)
+	}
+	if len(creds.gitOptions()) != 1 {
+		t.Errorf("Expected the signing key git option, got %d options", len(creds.gitOptions()))
+	}
+
+	yukConfig.Spec.Git.SigningKeyPassphraseRef.Key = "missing"
+	if _, err := reconciler.resolveCredentials(context.Background(), yukConfig); !errors.Is(err, errMissingCredentials) {
+		t.Errorf("Expected missing credentials error, got: %v", err)
+	}
+}
+
+func TestYukConfigReconciler_resolveCredentials_APIReader(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "git-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("ghp_test")},
+	}
+
+	// Secrets are read from the API reader, never from the cached client
+	reconciler := &YukConfigReconciler{
+		Client:    fake.NewClientBuilder().WithScheme(scheme).Build(),
+		APIReader: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(secret).Build(),
+		Scheme:    scheme,
+	}
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{Type: RepositoryTypeECR},
+			Git: yukv1.GitConfig{
+				Repository: "https://github.com/example/repo.git",
+				Auth: yukv1.GitAuthConfig{
+					PersonalAccessTokenRef: &yukv1.SecretKeySelector{Name: "git-token", Key: "token"},
+				},
+			},
+		},
+	}
+
+	creds, err := reconciler.resolveCredentials(context.Background(), yukConfig)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if creds.gitToken != "ghp_test" {
+		t.Errorf("Expected token %q, got %q", "ghp_test", creds.gitToken)
+	}
+}
+
+func TestYukConfigReconciler_resolveCredentials_GitHubApp(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "github-app", Namespace: "default"},
+		Data:       map[string][]byte{"private-key.pem": []byte("pem-key")},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(secret).Build(),
+		Scheme: scheme,
+	}
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{Type: RepositoryTypeECR},
+			Git: yukv1.GitConfig{
+				Repository: "https://github.com/example/repo.git",
+				Auth: yukv1.GitAuthConfig{
+					GitHubApp: &yukv1.GitHubAppAuth{
+						AppID:          12345,
+						InstallationID: 42,
+						PrivateKeyRef:  yukv1.SecretKeySelector{Name: "github-app", Key: "private-key.pem"},
+						APIURL:         "https://github.example.com/api/v3",
+					},
+				},
+			},
+		},
+	}
+
+	creds, err := reconciler.resolveCredentials(context.Background(), yukConfig)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if string(creds.gitAppPrivateKey) != "pem-key" {
+		t.Errorf("Expected private key %q, got %q", "pem-key", creds.gitAppPrivateKey)
+	}
+	if len(creds.gitOptions()) != 2 {
+		t.Errorf("Expected the GitHub App and API URL git options, got %d options", len(creds.gitOptions()))
+	}
+
+	yukConfig.Spec.Git.Auth.GitHubApp.PrivateKeyRef.Key = "missing"
+	if _, err := reconciler.resolveCredentials(context.Background(), yukConfig); !errors.Is(err, errMissingCredentials) {
+		t.Errorf("Expected missing credentials error, got: %v", err)
+	}
+}
+
+func TestYukConfigReconciler_resolveCredentials_RegistryTLS(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"name": "project/app", "tags": ["v1.0.0"]}`))
+	}))
+	defer server.Close()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-ca", Namespace: "default"},
+		Data: map[string][]byte{
+			"ca.crt": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}),
+		},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(secret).Build(),
+		Scheme: scheme,
+	}
+
+	ociConfig := &yukv1.OCIConfig{
+		Registry:       server.Listener.Addr().String(),
+		RepositoryName: "project/app",
+		TLS:            &yukv1.TLSConfig{CARef: &yukv1.SecretKeySelector{Name: "registry-ca", Key: "ca.crt"}},
+	}
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{Type: RepositoryTypeOCI, OCI: ociConfig},
+		},
+	}
+
+	// The CA bundle is trusted by the registry client
+	creds, err := reconciler.resolveCredentials(context.Background(), yukConfig)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	ociOpts := creds.repository("").ociOptions(ociConfig)
+	if _, err := oci.NewClient(ociConfig.Registry, ociOpts...).ListTags(context.Background(), "project/app"); err != nil {
+		t.Errorf("Expected the registry certificate to be trusted, got: %v", err)
+	}
+
+	// The system trust store is used without a TLS configuration
+	if _, err := oci.NewClient(ociConfig.Registry).ListTags(context.Background(), "project/app"); err == nil {
+		t.Error("Expected the registry certificate not to be trusted by default")
+	}
+
+	ociConfig.TLS.CARef.Key = "missing"
+	if _, err := reconciler.resolveCredentials(context.Background(), yukConfig); !errors.Is(err, errMissingCredentials) {
+		t.Errorf("Expected missing credentials error, got: %v", err)
+	}
+}
+
+func TestYukConfigReconciler_recordFailure_AuthError(t *testing.T) {
+	reconciler := &YukConfigReconciler{}
+	yukConfig := &yukv1.YukConfig{}
+
+	err := errors.Join(errors.New("secret default/git-credentials not found"), errMissingCredentials)
+	requeueAfter := reconciler.recordFailure(yukConfig, "Failed to resolve credentials", err, 5*time.Minute, time.Now())
+
+	if requeueAfter != 5*time.Minute {
+		t.Errorf("Expected requeue after the check interval, got %v", requeueAfter)
+	}
+
+	if len(yukConfig.Status.Conditions) != 2 || yukConfig.Status.Conditions[0].Reason != ReasonAuthError {
+		t.Fatalf("Expected Ready condition with reason %s, got %+v", ReasonAuthError, yukConfig.Status.Conditions)
+	}
+	if authReady := yukConfig.Status.Conditions[1]; authReady.Type != ConditionAuthReady || authReady.Reason != ReasonCredentialsMissing {
+		t.Errorf("Expected AuthReady condition with reason %s, got %+v", ReasonCredentialsMissing, authReady)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+	"github.com/rebelopsio/yuk/pkg/oci"
+	"github.com/rebelopsio/yuk/pkg/verify"
+)
+
+// ConditionVerified reports whether the images of the last update are signed with the key
+// of spec.verifySignature
+const ConditionVerified = "Verified"
+
+// Reasons of the Verified condition
+const (
+	// ReasonSignatureVerified means every image of the update has a valid signature
+	ReasonSignatureVerified = "SignatureVerified"
+
+	// ReasonSignatureInvalid means an image of the update is unsigned or signed with
+	// another key
+	ReasonSignatureInvalid = "SignatureInvalid"
+)
+
+// SignatureVerifier verifies the signature of an image. It is implemented by
+// verify.CosignVerifier and can be replaced with a fake through
+// YukConfigReconciler.NewSignatureVerifier in tests.
+type SignatureVerifier interface {
+	// Verify returns an error wrapping verify.ErrVerificationFailed when the image with
+	// the digest is not signed with the verifier's key
+	Verify(ctx context.Context, repositoryName, digest string) error
+}
+
+var _ SignatureVerifier = (*verify.CosignVerifier)(nil)
+
+// newSignatureVerifier returns the verifier of images signed with the public key: the
+// reconciler's NewSignatureVerifier when set, a verify.CosignVerifier otherwise
+func (r *YukConfigReconciler) newSignatureVerifier(publicKey []byte, fetcher verify.Fetcher) (SignatureVerifier, error) {
+	if r.NewSignatureVerifier != nil {
+		return r.NewSignatureVerifier(publicKey, fetcher)
+	}
+	return verify.NewCosignVerifier(publicKey, fetcher)
+}
+
+// signatureFetcher returns the client reading the signatures of images in the repository
+func (r *YukConfigReconciler) signatureFetcher(repository *yukv1.RepositoryConfig, creds *repositoryCredentials) (verify.Fetcher, error) {
+	switch {
+	case repository.Type == RepositoryTypeECR && repository.ECR != nil:
+		ecrOpts := append([]ecr.Option{
+			ecr.WithAssumeRole(repository.ECR.RoleARN, repository.ECR.ExternalID),
+			ecr.WithRegistryID(repository.ECR.RegistryID),
+		}, creds.ecrOptions()...)
+		if fetcher, ok := r.newECRClient(repository.ECR.Region, ecrOpts...).(verify.Fetcher); ok {
+			return fetcher, nil
+		}
+		return nil, fmt.Errorf("the ECR client does not support signature verification")
+
+	case repository.Type == RepositoryTypeOCI && repository.OCI != nil:
+		ociOpts := append([]oci.Option{
+			oci.WithInsecure(repository.OCI.Insecure),
+		}, creds.ociOptions(repository.OCI)...)
+		return oci.NewClient(repository.OCI.Registry, ociOpts...), nil
+
+	default:
+		return nil, fmt.Errorf("signature verification is not supported for repository type %s", repository.Type)
+	}
+}
+
+// verifySignatures verifies the signature of every image written to the update targets,
+// once per source and tag, and records the result on the Verified condition. Images are
+// verified by digest: the target digest for byDigest targets, the digest the tag points
+// to otherwise.
+func (r *YukConfigReconciler) verifySignatures(ctx context.Context, yukConfig *yukv1.YukConfig, targetTags, targetDigests []string, creds *credentials) error {
+	verified := make(map[string]bool)
+	for i, target := range yukConfig.Spec.UpdateTargets {
+		if targetTags[i] == "" {
+			continue
+		}
+
+		source := targetSource(yukConfig, target)
+		key := source + ":" + targetTags[i]
+		if verified[key] {
+			continue
+		}
+
+		if err := r.verifySignature(ctx, sourceRepository(yukConfig, source), targetTags[i], targetDigests[i], creds.signaturePublicKey, creds.repository(source)); err != nil {
+			if source != "" {
+				err = fmt.Errorf("source %s: %w", source, err)
+			}
+			if errors.Is(err, verify.ErrVerificationFailed) {
+				r.setCondition(yukConfig, ConditionVerified, metav1.ConditionFalse, ReasonSignatureInvalid, err.Error())
+			}
+			return err
+		}
+		verified[key] = true
+	}
+
+	r.setCondition(yukConfig, ConditionVerified, metav1.ConditionTrue, ReasonSignatureVerified,
+		fmt.Sprintf("Verified the signatures of %d image(s)", len(verified)))
+	return nil
+}
+
+// verifySignature verifies the signature of the image the tag or digest points to
+func (r *YukConfigReconciler) verifySignature(ctx context.Context, repository *yukv1.RepositoryConfig, tag, digest string, publicKey []byte, creds *repositoryCredentials) error {
+	fetcher, err := r.signatureFetcher(repository, creds)
+	if err != nil {
+		return err
+	}
+	verifier, err := r.newSignatureVerifier(publicKey, fetcher)
+	if err != nil {
+		return fmt.Errorf("invalid signature public key: %w", err)
+	}
+
+	if digest == "" {
+		if digest, err = r.getImageDigest(ctx, repository, tag, creds); err != nil {
+			return err
+		}
+	}
+
+	return verifier.Verify(ctx, repositoryName(repository), digest)
+}
+
+// verificationErrorType returns the metrics error type of a failed signature
+// verification: validation for images not signed with the key, the repository error type
+// for failures reading signatures
+func verificationErrorType(err error) yukmetrics.ErrorType {
+	if errors.Is(err, verify.ErrVerificationFailed) {
+		return yukmetrics.ErrorTypeValidation
+	}
+	return repositoryErrorType(err)
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	"github.com/rebelopsio/yuk/pkg/git"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+	"github.com/rebelopsio/yuk/pkg/verify"
+)
+
+// fakeSignatureResolver is an ECR tag resolver that can read signatures
+type fakeSignatureResolver struct {
+	*fakeTagResolver
+}
+
+func (f *fakeSignatureResolver) GetManifest(ctx context.Context, repositoryName, reference string) ([]byte, error) {
+	return nil, &ecr.ImageNotFoundError{RepositoryName: repositoryName, Reference: reference}
+}
+
+func (f *fakeSignatureResolver) GetBlob(ctx context.Context, repositoryName, digest string) ([]byte, error) {
+	return nil, fmt.Errorf("blob %s not found", digest)
+}
+
+// fakeSignatureVerifier accepts the images listed as signed, as "repository@digest"
+type fakeSignatureVerifier struct {
+	publicKey string
+	signed    map[string]bool
+	verified  []string
+}
+
+func (f *fakeSignatureVerifier) Verify(ctx context.Context, repositoryName, digest string) error {
+	f.verified = append(f.verified, repositoryName+"@"+digest)
+	if !f.signed[repositoryName+"@"+digest] {
+		return fmt.Errorf("%w: no signature found for %s@%s", verify.ErrVerificationFailed, repositoryName, digest)
+	}
+	return nil
+}
+
+func TestYukConfigReconciler_Reconcile_VerifySignature(t *testing.T) {
+	tests := []struct {
+		name         string
+		signed       map[string]bool
+		wantPush     bool
+		wantVerified metav1.ConditionStatus
+	}{
+		{
+			name:         "signed image",
+			signed:       map[string]bool{"my-app@sha256:new": true},
+			wantPush:     true,
+			wantVerified: metav1.ConditionTrue,
+		},
+		{
+			name:         "unsigned image",
+			wantVerified: metav1.ConditionFalse,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = clientgoscheme.AddToScheme(scheme)
+			_ = yukv1.AddToScheme(scheme)
+
+			name := fmt.Sprintf("signed-config-%d", i)
+			yukConfig := &yukv1.YukConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: yukv1.YukConfigSpec{
+					Repository: yukv1.RepositoryConfig{
+						Type: RepositoryTypeECR,
+						ECR:  &yukv1.ECRConfig{Region: "us-west-2", RepositoryName: "my-app"},
+					},
+					Git: yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+					UpdateTargets: []yukv1.UpdateTarget{
+						{File: "values.yaml", YAMLPath: "image.tag"},
+						{File: "values.yaml", YAMLPath: "image.digest", ByDigest: true},
+					},
+					VerifySignature: &yukv1.SignatureVerificationConfig{
+						PublicKeyRef: yukv1.SecretKeySelector{Name: "cosign", Key: "cosign.pub"},
+					},
+				},
+				Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "cosign", Namespace: "default"},
+				Data:       map[string][]byte{"cosign.pub": []byte("public key")},
+			}
+
+			resolver := &fakeSignatureResolver{&fakeTagResolver{
+				latestTags: map[string]string{"my-app:": "v1.1.0"},
+				digests:    map[string]string{"my-app:v1.1.0": "sha256:new"},
+			}}
+			verifier := &fakeSignatureVerifier{signed: tt.signed}
+			gitOperator := newFakeGitOperator(t, map[string]string{"values.yaml": "image:\n    tag: v1.0.0\n    digest: sha256:old\n"})
+			reconciler := &YukConfigReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig, secret).
+					WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(10),
+				NewECRClient: func(region string, opts ...ecr.Option) TagResolver {
+					return resolver
+				},
+				NewSignatureVerifier: func(publicKey []byte, fetcher verify.Fetcher) (SignatureVerifier, error) {
+					if fetcher != resolver {
+						t.Errorf("Expected signatures to be read from the ECR client, got %T", fetcher)
+					}
+					verifier.publicKey = string(publicKey)
+					return verifier, nil
+				},
+				NewGitClient: func(config yukv1.GitConfig, opts ...git.Option) GitOperator {
+					return gitOperator
+				},
+			}
+
+			validationErrors := yukmetrics.ErrorsTotal.With(prometheus.Labels{
+				"error_type": string(yukmetrics.ErrorTypeValidation),
+				"namespace":  "default",
+				"name":       name,
+			})
+			before := testutil.ToFloat64(validationErrors)
+
+			ctx := context.Background()
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: "default"}}
+			if _, err := reconciler.Reconcile(ctx, req); err != nil {
+				t.Fatalf("Reconcile failed: %v", err)
+			}
+
+			// The image is verified once by digest, with the key of the secret
+			if len(verifier.verified) != 1 || verifier.verified[0] != "my-app@sha256:new" {
+				t.Errorf("Expected my-app@sha256:new to be verified once, got %v", verifier.verified)
+			}
+			if verifier.publicKey != "public key" {
+				t.Errorf("Expected the public key of the secret, got %q", verifier.publicKey)
+			}
+
+			if pushed := gitOperator.pushes == 1; pushed != tt.wantPush {
+				t.Errorf("Expected push %v, got %d pushes", tt.wantPush, gitOperator.pushes)
+			}
+			if tt.wantPush && !strings.Contains(gitOperator.file("values.yaml"), "digest: sha256:new") {
+				t.Errorf("Expected the verified digest to be written, got:\n%s", gitOperator.file("values.yaml"))
+			}
+
+			updated := &yukv1.YukConfig{}
+			if err := reconciler.Get(ctx, req.NamespacedName, updated); err != nil {
+				t.Fatalf("Failed to get YukConfig: %v", err)
+			}
+			var verified, ready *metav1.Condition
+			for i := range updated.Status.Conditions {
+				switch updated.Status.Conditions[i].Type {
+				case ConditionVerified:
+					verified = &updated.Status.Conditions[i]
+				case "Ready":
+					ready = &updated.Status.Conditions[i]
+				}
+			}
+			if verified == nil || verified.Status != tt.wantVerified {
+				t.Fatalf("Expected Verified %s, got %+v", tt.wantVerified, verified)
+			}
+
+			if tt.wantPush {
+				if updated.Status.CurrentTag != "v1.1.0" {
+					t.Errorf("Expected current tag v1.1.0, got %s", updated.Status.CurrentTag)
+				}
+				return
+			}
+
+			if updated.Status.CurrentTag != "v1.0.0" {
+				t.Errorf("Expected the update to be skipped, got current tag %s", updated.Status.CurrentTag)
+			}
+			if verified.Reason != ReasonSignatureInvalid || !strings.Contains(verified.Message, "no signature found for my-app@sha256:new") {
+				t.Errorf("Expected reason %s with the missing signature, got %s: %s", ReasonSignatureInvalid, verified.Reason, verified.Message)
+			}
+			if ready == nil || ready.Status != metav1.ConditionFalse || ready.Reason != ReasonValidationError {
+				t.Errorf("Expected Ready False with reason %s, got %+v", ReasonValidationError, ready)
+			}
+			if got := testutil.ToFloat64(validationErrors) - before; got != 1 {
+				t.Errorf("Expected 1 validation error, got %v", got)
+			}
+		})
+	}
+}
+
+func TestVerificationErrorType(t *testing.T) {
+	if got := verificationErrorType(fmt.Errorf("source api: %w", verify.ErrVerificationFailed)); got != yukmetrics.ErrorTypeValidation {
+		t.Errorf("Expected validation error type for an unsigned image, got %s", got)
+	}
+	if got := verificationErrorType(&ecr.ImageNotFoundError{}); got != yukmetrics.ErrorTypeRepository {
+		t.Errorf("Expected repository error type for a registry error, got %s", got)
+	}
+	if got := verificationErrorType(statusCodeError(http.StatusServiceUnavailable)); got != yukmetrics.ErrorTypeNetwork {
+		t.Errorf("Expected network error type for an unavailable registry, got %s", got)
+	}
+}
+
+// statusCodeError is a registry error with an HTTP status code
+type statusCodeError int
+
+func (e statusCodeError) Error() string       { return fmt.Sprintf("registry returned %d", int(e)) }
+func (e statusCodeError) HTTPStatusCode() int { return int(e) }
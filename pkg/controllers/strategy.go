@@ -0,0 +1,259 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// UpdateAction is what a reconcile does about the latest tag
+type UpdateAction string
+
+const (
+	// ActionNone leaves the repository alone
+	ActionNone UpdateAction = "None"
+
+	// ActionPush commits the update and pushes it to the configured branch
+	ActionPush UpdateAction = "Push"
+
+	// ActionProposeBranch commits the update and pushes it to a review branch
+	ActionProposeBranch UpdateAction = "ProposeBranch"
+
+	// ActionDryRun applies the update to a clone without committing it
+	ActionDryRun UpdateAction = "DryRun"
+
+	// ActionInvalid means the configuration does not allow a decision
+	ActionInvalid UpdateAction = "Invalid"
+
+	// ActionRollback commits the previous tag and pushes it to the configured branch. It
+	// is requested with the rollback annotation rather than decided by a strategy.
+	ActionRollback UpdateAction = "Rollback"
+)
+
+// Reasons of the Ready condition set by the update strategies
+const (
+	ReasonSynchronized     = "Synchronized"
+	ReasonUpdateAvailable  = "UpdateAvailable"
+	ReasonAwaitingApproval = "AwaitingApproval"
+	ReasonUpdateProposed   = "UpdateProposed"
+	ReasonDryRun           = "DryRun"
+	ReasonRolledBack       = "RolledBack"
+	ReasonOutsideWindow    = "OutsideUpdateWindow"
+)
+
+// updateState is the input of the update strategy state machine
+type updateState struct {
+	// Strategy is the configured update strategy
+	Strategy string
+
+	// UpdateAvailable reports whether any target is behind its latest tag
+	UpdateAvailable bool
+
+	// LatestTag is the repository's latest tag
+	LatestTag string
+
+	// ApprovedTag is the tag approved through the approval annotation
+	ApprovedTag string
+
+	// LatestValues is the hash of the values computed for all targets, from every
+	// source, tag filter and digest
+	LatestValues string
+
+	// ProposedValues is the hash of the target values last proposed for review or
+	// previewed
+	ProposedValues string
+
+	// RolledBackTag is the tag reverted by the last rollback
+	RolledBackTag string
+
+	// Force updates the targets even when no update is available or the target values
+	// were proposed already, e.g. to correct manual edits of the target files
+	Force bool
+
+	// WindowOpens is when the update window opens next while it is closed, zero while it
+	// is open or no window is configured
+	WindowOpens time.Time
+}
+
+// updateDecision is the output of the update strategy state machine
+type updateDecision struct {
+	// Action is what to do about the latest tag
+	Action UpdateAction
+
+	// Reason and Message describe the resulting Ready condition
+	Reason  string
+	Message string
+}
+
+// decideUpdate is the update strategy state machine. It decides what to do about the
+// latest tag from the configured strategy and the observed state, without any IO.
+//
+//   - autoPush (default): push every update
+//   - pullRequest: push every update to a review branch, once per set of target values
+//   - approval: push an update once the approval annotation names its tag
+//   - audit: only report available updates
+//   - dryRun: apply every update to a clone without committing, once per set of target
+//     values
+//
+// A tag that was rolled back is never updated to again by any strategy. A forced update is
+// applied like an available update, and proposed or previewed again. Outside of the update
+// window, updates are neither pushed nor proposed unless forced.
+func decideUpdate(state updateState) updateDecision {
+	strategy := state.Strategy
+	if strategy == "" {
+		strategy = yukv1.UpdateStrategyAutoPush
+	}
+
+	switch strategy {
+	case yukv1.UpdateStrategyAutoPush, yukv1.UpdateStrategyPullRequest, yukv1.UpdateStrategyApproval,
+		yukv1.UpdateStrategyAudit, yukv1.UpdateStrategyDryRun:
+	default:
+		return updateDecision{
+			Action:  ActionInvalid,
+			Message: fmt.Sprintf("unsupported update strategy: %s", state.Strategy),
+		}
+	}
+
+	if !state.UpdateAvailable && !state.Force {
+		return updateDecision{
+			Action:  ActionNone,
+			Reason:  ReasonSynchronized,
+			Message: "Successfully synchronized with repository",
+		}
+	}
+
+	if state.RolledBackTag != "" && state.LatestTag == state.RolledBackTag {
+		return updateDecision{
+			Action:  ActionNone,
+			Reason:  ReasonRolledBack,
+			Message: fmt.Sprintf("Update to %s was rolled back; waiting for a newer tag", state.LatestTag),
+		}
+	}
+
+	switch strategy {
+	case yukv1.UpdateStrategyPullRequest:
+		if state.ProposedValues == state.LatestValues && !state.Force {
+			return updateDecision{
+				Action:  ActionNone,
+				Reason:  ReasonUpdateProposed,
+				Message: fmt.Sprintf("Update to %s is awaiting review", state.LatestTag),
+			}
+		}
+		if deferred, ok := deferToWindow(state); ok {
+			return deferred
+		}
+		return updateDecision{
+			Action:  ActionProposeBranch,
+			Reason:  ReasonUpdateProposed,
+			Message: fmt.Sprintf("Proposed update to %s for review", state.LatestTag),
+		}
+
+	case yukv1.UpdateStrategyApproval:
+		if state.ApprovedTag != state.LatestTag {
+			return updateDecision{
+				Action: ActionNone,
+				Reason: ReasonAwaitingApproval,
+				Message: fmt.Sprintf("Update to %s is awaiting approval; set the %s annotation to %s to apply it",
+					state.LatestTag, yukv1.ApprovedTagAnnotation, state.LatestTag),
+			}
+		}
+
+	case yukv1.UpdateStrategyAudit:
+		return updateDecision{
+			Action:  ActionNone,
+			Reason:  ReasonUpdateAvailable,
+			Message: fmt.Sprintf("Update to %s is available", state.LatestTag),
+		}
+
+	case yukv1.UpdateStrategyDryRun:
+		if state.ProposedValues == state.LatestValues && !state.Force {
+			return updateDecision{
+				Action:  ActionNone,
+				Reason:  ReasonDryRun,
+				Message: fmt.Sprintf("Dry run: would update to %s", state.LatestTag),
+			}
+		}
+		return updateDecision{
+			Action:  ActionDryRun,
+			Reason:  ReasonDryRun,
+			Message: fmt.Sprintf("Dry run: would update to %s", state.LatestTag),
+		}
+	}
+
+	if deferred, ok := deferToWindow(state); ok {
+		return deferred
+	}
+	return updateDecision{
+		Action:  ActionPush,
+		Reason:  ReasonSynchronized,
+		Message: "Successfully synchronized with repository",
+	}
+}
+
+// deferToWindow returns the decision deferring an update while the update window is
+// closed, and whether the update is deferred
+func deferToWindow(state updateState) (updateDecision, bool) {
+	if state.WindowOpens.IsZero() || state.Force {
+		return updateDecision{}, false
+	}
+	return updateDecision{
+		Action: ActionNone,
+		Reason: ReasonOutsideWindow,
+		Message: fmt.Sprintf("Update to %s is deferred until the update window opens at %s",
+			state.LatestTag, state.WindowOpens.UTC().Format(time.RFC3339)),
+	}, true
+}
+
+// updateStrategy returns the configured update strategy. DryRun selects the dryRun
+// strategy; enabling pull requests selects the pullRequest strategy when none is set.
+func updateStrategy(yukConfig *yukv1.YukConfig) string {
+	if yukConfig.Spec.DryRun {
+		return yukv1.UpdateStrategyDryRun
+	}
+	if yukConfig.Spec.UpdateStrategy == "" && pullRequestEnabled(yukConfig) {
+		return yukv1.UpdateStrategyPullRequest
+	}
+	return yukConfig.Spec.UpdateStrategy
+}
+
+// valuesHash returns a short hash of the values computed for the update targets, so
+// proposals change whenever any target value does, not only the default source's tag
+func valuesHash(values []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(values, "\n")))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// reviewBranch returns the branch the given target values are proposed on for review
+func reviewBranch(yukConfig *yukv1.YukConfig, tag string, values []string) string {
+	return fmt.Sprintf("yuk/%s-%s-%s", yukConfig.Name, tag, valuesHash(values))
+}
+
+// branchReviewBranch returns the review branch of the updates of a branch returned by
+// targetBranches: the review branch of the configured branch, suffixed with the name of
+// any other branch
+func branchReviewBranch(yukConfig *yukv1.YukConfig, branch, tag string, values []string) string {
+	if branch == "" {
+		return reviewBranch(yukConfig, tag, values)
+	}
+	return fmt.Sprintf("%s-%s", reviewBranch(yukConfig, tag, values), strings.ReplaceAll(branch, "/", "-"))
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestDecideUpdate(t *testing.T) {
+	windowOpens := time.Date(2024, 5, 20, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		state          updateState
+		expectedAction UpdateAction
+		expectedReason string
+	}{
+		{
+			name:           "default strategy pushes updates",
+			state:          updateState{UpdateAvailable: true, LatestTag: "v1.1.0"},
+			expectedAction: ActionPush,
+			expectedReason: ReasonSynchronized,
+		},
+		{
+			name:           "no update available",
+			state:          updateState{Strategy: yukv1.UpdateStrategyAudit, LatestTag: "v1.0.0"},
+			expectedAction: ActionNone,
+			expectedReason: ReasonSynchronized,
+		},
+		{
+			name:           "rolled back tag is not updated to again",
+			state:          updateState{UpdateAvailable: true, LatestTag: "v1.1.0", RolledBackTag: "v1.1.0"},
+			expectedAction: ActionNone,
+			expectedReason: ReasonRolledBack,
+		},
+		{
+			name:           "newer tag after rollback is pushed",
+			state:          updateState{UpdateAvailable: true, LatestTag: "v1.2.0", RolledBackTag: "v1.1.0"},
+			expectedAction: ActionPush,
+			expectedReason: ReasonSynchronized,
+		},
+		{
+			name:           "forced update without an available update",
+			state:          updateState{LatestTag: "v1.1.0", Force: true},
+			expectedAction: ActionPush,
+			expectedReason: ReasonSynchronized,
+		},
+		{
+			name:           "forced update is proposed again",
+			state:          updateState{Strategy: yukv1.UpdateStrategyPullRequest, LatestTag: "v1.1.0", LatestValues: "same", ProposedValues: "same", Force: true},
+			expectedAction: ActionProposeBranch,
+			expectedReason: ReasonUpdateProposed,
+		},
+		{
+			name:           "forced update does not override audit",
+			state:          updateState{Strategy: yukv1.UpdateStrategyAudit, LatestTag: "v1.1.0", Force: true},
+			expectedAction: ActionNone,
+			expectedReason: ReasonUpdateAvailable,
+		},
+		{
+			name:           "pull request proposes new tag",
+			state:          updateState{Strategy: yukv1.UpdateStrategyPullRequest, UpdateAvailable: true, LatestTag: "v1.1.0", LatestValues: "new", ProposedValues: "old"},
+			expectedAction: ActionProposeBranch,
+			expectedReason: ReasonUpdateProposed,
+		},
+		{
+			name:           "pull request does not repeat proposal",
+			state:          updateState{Strategy: yukv1.UpdateStrategyPullRequest, UpdateAvailable: true, LatestTag: "v1.1.0", LatestValues: "same", ProposedValues: "same"},
+			expectedAction: ActionNone,
+			expectedReason: ReasonUpdateProposed,
+		},
+		{
+			name:           "approval without annotation",
+			state:          updateState{Strategy: yukv1.UpdateStrategyApproval, UpdateAvailable: true, LatestTag: "v1.1.0"},
+			expectedAction: ActionNone,
+			expectedReason: ReasonAwaitingApproval,
+		},
+		{
+			name:           "approval of an older tag",
+			state:          updateState{Strategy: yukv1.UpdateStrategyApproval, UpdateAvailable: true, LatestTag: "v1.1.0", ApprovedTag: "v1.0.1"},
+			expectedAction: ActionNone,
+			expectedReason: ReasonAwaitingApproval,
+		},
+		{
+			name:           "approval of the latest tag",
+			state:          updateState{Strategy: yukv1.UpdateStrategyApproval, UpdateAvailable: true, LatestTag: "v1.1.0", ApprovedTag: "v1.1.0"},
+			expectedAction: ActionPush,
+			expectedReason: ReasonSynchronized,
+		},
+		{
+			name:           "audit reports updates",
+			state:          updateState{Strategy: yukv1.UpdateStrategyAudit, UpdateAvailable: true, LatestTag: "v1.1.0"},
+			expectedAction: ActionNone,
+			expectedReason: ReasonUpdateAvailable,
+		},
+		{
+			name:           "dry run previews new tag",
+			state:          updateState{Strategy: yukv1.UpdateStrategyDryRun, UpdateAvailable: true, LatestTag: "v1.1.0", LatestValues: "new"},
+			expectedAction: ActionDryRun,
+			expectedReason: ReasonDryRun,
+		},
+		{
+			name:           "dry run does not repeat preview",
+			state:          updateState{Strategy: yukv1.UpdateStrategyDryRun, UpdateAvailable: true, LatestTag: "v1.1.0", LatestValues: "same", ProposedValues: "same"},
+			expectedAction: ActionNone,
+			expectedReason: ReasonDryRun,
+		},
+		{
+			name:           "update deferred outside of the update window",
+			state:          updateState{UpdateAvailable: true, LatestTag: "v1.1.0", WindowOpens: windowOpens},
+			expectedAction: ActionNone,
+			expectedReason: ReasonOutsideWindow,
+		},
+		{
+			name:           "proposal deferred outside of the update window",
+			state:          updateState{Strategy: yukv1.UpdateStrategyPullRequest, UpdateAvailable: true, LatestTag: "v1.1.0", LatestValues: "new", WindowOpens: windowOpens},
+			expectedAction: ActionNone,
+			expectedReason: ReasonOutsideWindow,
+		},
+		{
+			name:           "dry run outside of the update window",
+			state:          updateState{Strategy: yukv1.UpdateStrategyDryRun, UpdateAvailable: true, LatestTag: "v1.1.0", LatestValues: "new", WindowOpens: windowOpens},
+			expectedAction: ActionDryRun,
+			expectedReason: ReasonDryRun,
+		},
+		{
+			name:           "forced update outside of the update window",
+			state:          updateState{LatestTag: "v1.1.0", Force: true, WindowOpens: windowOpens},
+			expectedAction: ActionPush,
+			expectedReason: ReasonSynchronized,
+		},
+		{
+			name:           "unknown strategy",
+			state:          updateState{Strategy: "yolo", UpdateAvailable: true, LatestTag: "v1.1.0"},
+			expectedAction: ActionInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := decideUpdate(tt.state)
+
+			if decision.Action != tt.expectedAction {
+				t.Errorf("Expected action %s, got %s", tt.expectedAction, decision.Action)
+			}
+			if decision.Reason != tt.expectedReason {
+				t.Errorf("Expected reason %s, got %s", tt.expectedReason, decision.Reason)
+			}
+			if decision.Message == "" {
+				t.Error("Expected a message, got none")
+			}
+		})
+	}
+}
+
+func TestDecideUpdate_TargetValues(t *testing.T) {
+	// The default source stays at v1.1.0 while a secondary source or a digest changes
+	proposed := targetValues([]string{"v1.1.0", "w2.0.0", "v1.1.0"}, []string{"", "", "sha256:aaa"})
+
+	tests := []struct {
+		name   string
+		values []string
+	}{
+		{
+			name:   "secondary source changed",
+			values: targetValues([]string{"v1.1.0", "w2.1.0", "v1.1.0"}, []string{"", "", "sha256:aaa"}),
+		},
+		{
+			name:   "digest re-pushed",
+			values: targetValues([]string{"v1.1.0", "w2.0.0", "v1.1.0"}, []string{"", "", "sha256:bbb"}),
+		},
+	}
+
+	for _, strategy := range []string{yukv1.UpdateStrategyPullRequest, yukv1.UpdateStrategyDryRun} {
+		for _, tt := range tests {
+			t.Run(strategy+" "+tt.name, func(t *testing.T) {
+				state := updateState{
+					Strategy:        strategy,
+					UpdateAvailable: true,
+					LatestTag:       "v1.1.0",
+					LatestValues:    valuesHash(tt.values),
+					ProposedValues:  valuesHash(proposed),
+				}
+
+				decision := decideUpdate(state)
+				if decision.Action == ActionNone {
+					t.Errorf("Expected the changed values to be proposed, got %s (%s)", decision.Action, decision.Message)
+				}
+
+				// The same values are not proposed twice
+				state.ProposedValues = state.LatestValues
+				if decision := decideUpdate(state); decision.Action != ActionNone {
+					t.Errorf("Expected the proposal not to be repeated, got %s", decision.Action)
+				}
+			})
+		}
+	}
+}
+
+func TestReviewBranch(t *testing.T) {
+	yukConfig := &yukv1.YukConfig{ObjectMeta: metav1.ObjectMeta{Name: "my-app"}}
+	values := []string{"v1.1.0", "w2.0.0"}
+
+	branch := reviewBranch(yukConfig, "v1.1.0", values)
+	if expected := "yuk/my-app-v1.1.0-" + valuesHash(values); branch != expected {
+		t.Errorf("Expected %s, got %s", expected, branch)
+	}
+
+	// Changing any target value proposes on a new branch
+	if other := reviewBranch(yukConfig, "v1.1.0", []string{"v1.1.0", "w2.1.0"}); other == branch {
+		t.Errorf("Expected a different branch for different target values, got %s", other)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// SummaryMarker prefixes reconcile summary lines so downstream tooling can tell them
+// apart from regular log output
+const SummaryMarker = "YUK_RECONCILE_SUMMARY"
+
+// ReconcileSummary is the machine-readable outcome of a single reconcile. Its JSON form
+// is a stable contract: fields may be added but are never renamed or removed.
+type ReconcileSummary struct {
+	// Config is the name of the YukConfig
+	Config string `json:"config"`
+
+	// Namespace is the namespace of the YukConfig
+	Namespace string `json:"ns"`
+
+	// Result is the reconciliation result (success, error, skipped or throttled)
+	Result string `json:"result"`
+
+	// OldTag is the tag deployed before the reconcile
+	OldTag string `json:"oldTag"`
+
+	// NewTag is the tag deployed after the reconcile
+	NewTag string `json:"newTag"`
+
+	// DurationMs is the duration of the reconcile in milliseconds
+	DurationMs int64 `json:"durationMs"`
+
+	// FilesChanged lists the files updated in the Git repository
+	FilesChanged []string `json:"filesChanged"`
+
+	// Commit is the hash of the commit pushed, if any
+	Commit string `json:"commit"`
+}
+
+// SummaryWriter writes one reconcile summary per line, prefixed with SummaryMarker
+type SummaryWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewSummaryWriter creates a summary writer writing to w (e.g. os.Stdout)
+func NewSummaryWriter(w io.Writer) *SummaryWriter {
+	return &SummaryWriter{w: w}
+}
+
+// Write writes the summary as a single line
+func (s *SummaryWriter) Write(summary ReconcileSummary) error {
+	if summary.FilesChanged == nil {
+		summary.FilesChanged = []string{}
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reconcile summary: %w", err)
+	}
+
+	// Serialize writes so concurrent reconciles never interleave lines
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := fmt.Fprintf(s.w, "%s %s\n", SummaryMarker, data); err != nil {
+		return fmt.Errorf("failed to write reconcile summary: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestSummaryWriter_Write(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewSummaryWriter(&buf)
+
+	err := writer.Write(ReconcileSummary{
+		Config:       "test-config",
+		Namespace:    "default",
+		Result:       "success",
+		OldTag:       "v1.0.0",
+		NewTag:       "v1.1.0",
+		DurationMs:   1500,
+		FilesChanged: []string{"deployment.yaml"},
+		Commit:       "abc123",
+	})
+	if err != nil {
+		t.Fatalf("Failed to write summary: %v", err)
+	}
+
+	line := buf.String()
+	if !strings.HasPrefix(line, SummaryMarker+" ") {
+		t.Fatalf("Expected line to start with marker, got %q", line)
+	}
+	if strings.Count(line, "\n") != 1 || !strings.HasSuffix(line, "\n") {
+		t.Errorf("Expected a single line, got %q", line)
+	}
+
+	expected := `{"config":"test-config","ns":"default","result":"success","oldTag":"v1.0.0","newTag":"v1.1.0",` +
+		`"durationMs":1500,"filesChanged":["deployment.yaml"],"commit":"abc123"}`
+	if got := strings.TrimSpace(strings.TrimPrefix(line, SummaryMarker)); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_Summary(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-config",
+			Namespace: "default",
+		},
+		Spec: yukv1.YukConfigSpec{
+			Disabled: true,
+		},
+		Status: yukv1.YukConfigStatus{
+			CurrentTag: "v1.0.0",
+		},
+	}
+
+	var buf bytes.Buffer
+	reconciler := &YukConfigReconciler{
+		Client:  fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).Build(),
+		Scheme:  scheme,
+		Summary: NewSummaryWriter(&buf),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	if _, err := reconciler.Reconcile(context.TODO(), req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	var summary ReconcileSummary
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(buf.String()), SummaryMarker+" ")), &summary); err != nil {
+		t.Fatalf("Failed to parse summary %q: %v", buf.String(), err)
+	}
+
+	if summary.Result != "skipped" {
+		t.Errorf("Expected result skipped, got %s", summary.Result)
+	}
+	if summary.OldTag != "v1.0.0" || summary.NewTag != "v1.0.0" {
+		t.Errorf("Expected unchanged tag v1.0.0, got %s -> %s", summary.OldTag, summary.NewTag)
+	}
+	if len(summary.FilesChanged) != 0 || summary.Commit != "" {
+		t.Errorf("Expected no changes, got files %v and commit %q", summary.FilesChanged, summary.Commit)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// isFilePattern reports whether the file of an update target is a glob pattern, e.g.
+// "overlays/*/deployment.yaml", rather than a single file
+func isFilePattern(file string) bool {
+	return strings.ContainsAny(file, "*?[")
+}
+
+// expandTargetFiles returns the files of the clone matching the file of an update target,
+// relative to the clone and sorted. A file that is not a pattern is returned as is, even
+// when it does not exist, so that reading it reports the missing file. Files of the .git
+// directory never match.
+func expandTargetFiles(repoPath, file string) ([]string, error) {
+	if !isFilePattern(file) {
+		return []string{file}, nil
+	}
+
+	matches, err := filepath.Glob(filepath.Join(repoPath, filepath.FromSlash(file)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid file pattern %s: %w", file, err)
+	}
+
+	var files []string
+	for _, match := range matches {
+		rel, err := filepath.Rel(repoPath, match)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == ".git" || strings.HasPrefix(rel, ".git/") {
+			continue
+		}
+		files = append(files, rel)
+	}
+	return files, nil
+}
+
+// sparseCheckoutSupported reports whether the files of the targets written to the branch
+// can be checked out sparsely. A pattern with a wildcard in its top-level directory (e.g.
+// "*/values.yaml") may match files in any directory, so it needs the whole tree.
+func sparseCheckoutSupported(yukConfig *yukv1.YukConfig, branch string) bool {
+	for _, target := range yukConfig.Spec.UpdateTargets {
+		if targetBranch(yukConfig, target) != branch {
+			continue
+		}
+		if dir, _, ok := strings.Cut(path.Clean(target.File), "/"); ok && isFilePattern(dir) {
+			return false
+		}
+	}
+	return true
+}
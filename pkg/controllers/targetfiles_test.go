@@ -0,0 +1,240 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/git"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+	"github.com/rebelopsio/yuk/pkg/yaml"
+)
+
+func TestExpandTargetFiles(t *testing.T) {
+	repoPath := t.TempDir()
+	for _, file := range []string{
+		"overlays/dev/deployment.yaml",
+		"overlays/prod/deployment.yaml",
+		"overlays/prod/service.yaml",
+		"overlays/README.md",
+		"base/deployment.yaml",
+		".git/deployment.yaml",
+	} {
+		path := filepath.Join(repoPath, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("image:\n    tag: v1.0.0\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		expected []string
+	}{
+		{
+			name:     "wildcard directory",
+			file:     "overlays/*/deployment.yaml",
+			expected: []string{"overlays/dev/deployment.yaml", "overlays/prod/deployment.yaml"},
+		},
+		{
+			name:     "wildcard file",
+			file:     "overlays/prod/*.yaml",
+			expected: []string{"overlays/prod/deployment.yaml", "overlays/prod/service.yaml"},
+		},
+		{
+			name:     "character class",
+			file:     "overlays/[dp]*/deployment.yaml",
+			expected: []string{"overlays/dev/deployment.yaml", "overlays/prod/deployment.yaml"},
+		},
+		{
+			name:     "git directory never matches",
+			file:     "*/deployment.yaml",
+			expected: []string{"base/deployment.yaml"},
+		},
+		{
+			name: "no matches",
+			file: "overlays/*/kustomization.yaml",
+		},
+		{
+			name:     "plain file",
+			file:     "overlays/missing/deployment.yaml",
+			expected: []string{"overlays/missing/deployment.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := expandTargetFiles(repoPath, tt.file)
+			if err != nil {
+				t.Fatalf("expandTargetFiles() error = %v", err)
+			}
+			if strings.Join(files, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected files %v, got %v", tt.expected, files)
+			}
+		})
+	}
+
+	if _, err := expandTargetFiles(repoPath, "overlays/[a-/deployment.yaml"); err == nil {
+		t.Error("Expected error for an invalid pattern")
+	}
+}
+
+func TestYukConfigReconciler_updateFiles_FilePattern(t *testing.T) {
+	upstream := newUpstreamRepository(t, map[string]string{
+		"overlays/dev/deployment.yaml":     "image:\n    tag: v1.0.0\n",
+		"overlays/staging/deployment.yaml": "image:\n    tag: v1.1.0\n",
+		"overlays/prod/deployment.yaml":    "image:\n    tag: v1.0.0\n",
+		"overlays/prod/service.yaml":       "image:\n    tag: v1.0.0\n",
+		"base/deployment.yaml":             "image:\n    tag: v1.0.0\n",
+	})
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "pattern-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Git: yukv1.GitConfig{Repository: upstream, Branch: "main", Email: "test@example.com", Name: "Test User"},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "overlays/*/deployment.yaml", YAMLPath: "image.tag"},
+			},
+		},
+	}
+
+	reconciler := &YukConfigReconciler{}
+	gitClient := git.NewClient(yukConfig.Spec.Git, git.WithBaseDir(t.TempDir()))
+
+	outcome, err := reconciler.updateFiles(context.Background(), yukConfig, staticGitClient(gitClient), yaml.NewUpdater(),
+		"v1.1.0", []string{"v1.1.0"}, nil, ActionPush)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// Every matching file is updated; the one already up to date is not counted
+	expected := "overlays/dev/deployment.yaml,overlays/prod/deployment.yaml"
+	if files := strings.Join(outcome.FilesChanged, ","); files != expected {
+		t.Errorf("Expected changed files %s, got %s", expected, files)
+	}
+
+	for file, tag := range map[string]string{
+		"overlays/dev/deployment.yaml":     "v1.1.0",
+		"overlays/staging/deployment.yaml": "v1.1.0",
+		"overlays/prod/deployment.yaml":    "v1.1.0",
+		"overlays/prod/service.yaml":       "v1.0.0",
+		"base/deployment.yaml":             "v1.0.0",
+	} {
+		out, err := exec.Command("git", "-C", upstream, "show", "main:"+file).Output()
+		if err != nil {
+			t.Fatalf("Failed to read upstream %s: %v", file, err)
+		}
+		if !strings.Contains(string(out), "tag: "+tag) {
+			t.Errorf("Expected %s to hold %s, got:\n%s", file, tag, out)
+		}
+	}
+
+	for _, file := range []string{"overlays/dev/deployment.yaml", "overlays/prod/deployment.yaml"} {
+		counter := yukmetrics.FilesUpdated.With(prometheus.Labels{
+			"namespace": yukConfig.Namespace,
+			"name":      yukConfig.Name,
+			"file_path": file,
+		})
+		if got := testutil.ToFloat64(counter); got != 1 {
+			t.Errorf("Expected 1 update of %s, got %v", file, got)
+		}
+	}
+}
+
+func TestYukConfigReconciler_updateFiles_FilePatternNoMatches(t *testing.T) {
+	upstream := newUpstreamRepository(t, map[string]string{
+		"overlays/dev/deployment.yaml": "image:\n    tag: v1.0.0\n",
+	})
+
+	tests := []struct {
+		name     string
+		optional bool
+	}{
+		{name: "required target"},
+		{name: "optional target", optional: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yukConfig := &yukv1.YukConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "pattern-config", Namespace: "default"},
+				Spec: yukv1.YukConfigSpec{
+					Git: yukv1.GitConfig{Repository: upstream, Branch: "main", Email: "test@example.com", Name: "Test User"},
+					UpdateTargets: []yukv1.UpdateTarget{
+						{File: "clusters/*/values.yaml", YAMLPath: "image.tag", Optional: tt.optional},
+					},
+				},
+			}
+
+			reconciler := &YukConfigReconciler{}
+			gitClient := git.NewClient(yukConfig.Spec.Git, git.WithBaseDir(t.TempDir()))
+
+			outcome, err := reconciler.updateFiles(context.Background(), yukConfig, staticGitClient(gitClient), yaml.NewUpdater(),
+				"v1.1.0", []string{"v1.1.0"}, nil, ActionDryRun)
+			if !tt.optional {
+				if err == nil || !strings.Contains(err.Error(), "no files match clusters/*/values.yaml") {
+					t.Errorf("Expected an error for the unmatched pattern, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(outcome.SkippedTargets) != 1 || outcome.SkippedTargets[0].Message != "no files match clusters/*/values.yaml" {
+				t.Errorf("Expected the optional target to be skipped, got %+v", outcome.SkippedTargets)
+			}
+		})
+	}
+}
+
+func TestSparseCheckoutSupported(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected bool
+	}{
+		{file: "overlays/dev/deployment.yaml", expected: true},
+		{file: "overlays/*/deployment.yaml", expected: true},
+		{file: "*.yaml", expected: true},
+		{file: "*/deployment.yaml", expected: false},
+		{file: "./env-*/values.yaml", expected: false},
+	}
+
+	for _, tt := range tests {
+		yukConfig := &yukv1.YukConfig{
+			Spec: yukv1.YukConfigSpec{
+				Git:           yukv1.GitConfig{Branch: "main"},
+				UpdateTargets: []yukv1.UpdateTarget{{File: tt.file}},
+			},
+		}
+		if got := sparseCheckoutSupported(yukConfig, ""); got != tt.expected {
+			t.Errorf("sparseCheckoutSupported(%s) = %v, want %v", tt.file, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+	"time"
+
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	"github.com/rebelopsio/yuk/pkg/yaml"
+)
+
+// targetImage is the image resolved for an update target, from which its further values
+// are rendered
+type targetImage struct {
+	Tag      string
+	Digest   string
+	PushedAt time.Time
+}
+
+// imageDetailsResolver is implemented by tag resolvers that report the push time of
+// images, such as the ECR client
+type imageDetailsResolver interface {
+	GetImageDetails(ctx context.Context, repositoryName, tag string) (*ecrtypes.ImageDetail, error)
+}
+
+// resolveTargetImages returns the image written to each update target with further values,
+// in target order. Targets without values, or without a tag, get an empty image. Digests
+// already resolved for byDigest targets are reused; others are looked up once per source
+// and tag.
+func (r *YukConfigReconciler) resolveTargetImages(ctx context.Context, yukConfig *yukv1.YukConfig, targetTags, targetDigests []string, creds *credentials) ([]targetImage, error) {
+	repositories := make(map[string]*yukv1.RepositoryConfig)
+	for _, source := range imageSources(yukConfig) {
+		repositories[source.name] = source.repository
+	}
+
+	images := make(map[string]targetImage)
+	targetImages := make([]targetImage, len(yukConfig.Spec.UpdateTargets))
+	for i, target := range yukConfig.Spec.UpdateTargets {
+		if len(target.Values) == 0 || targetTags[i] == "" {
+			continue
+		}
+
+		source := targetSource(yukConfig, target)
+		key := source + ":" + targetTags[i]
+		if _, ok := images[key]; !ok {
+			image, err := r.getTargetImage(ctx, repositories[source], targetTags[i], targetDigests[i], creds.repository(source))
+			if err != nil {
+				if source != "" {
+					return nil, fmt.Errorf("source %s: %w", source, err)
+				}
+				return nil, err
+			}
+			images[key] = image
+		}
+		targetImages[i] = images[key]
+	}
+
+	return targetImages, nil
+}
+
+// getTargetImage resolves the digest and, for ECR repositories, the push time of a tag
+func (r *YukConfigReconciler) getTargetImage(ctx context.Context, repository *yukv1.RepositoryConfig, tag, digest string, creds *repositoryCredentials) (targetImage, error) {
+	image := targetImage{Tag: tag, Digest: digest}
+
+	details, err := r.getImageDetails(ctx, repository, tag, creds)
+	if err != nil {
+		return image, err
+	}
+	if details != nil {
+		if details.ImageDigest != nil && image.Digest == "" {
+			image.Digest = *details.ImageDigest
+		}
+		if details.ImagePushedAt != nil {
+			image.PushedAt = *details.ImagePushedAt
+		}
+	}
+
+	if image.Digest == "" {
+		resolved, err := r.getImageDigest(ctx, repository, tag, creds)
+		if err != nil {
+			return image, err
+		}
+		image.Digest = resolved
+	}
+
+	return image, nil
+}
+
+// getImageDetails returns the details of the image of a tag, or nil when the repository
+// does not report them. Only ECR repositories do.
+func (r *YukConfigReconciler) getImageDetails(ctx context.Context, repository *yukv1.RepositoryConfig, tag string, creds *repositoryCredentials) (*ecrtypes.ImageDetail, error) {
+	if repository.Type != RepositoryTypeECR || repository.ECR == nil {
+		return nil, nil
+	}
+
+	ecrOpts := append([]ecr.Option{
+		ecr.WithAssumeRole(repository.ECR.RoleARN, repository.ECR.ExternalID),
+		ecr.WithRegistryID(repository.ECR.RegistryID),
+	}, creds.ecrOptions()...)
+	resolver := r.newECRClient(repository.ECR.Region, ecrOpts...)
+	detailsResolver, ok := resolver.(imageDetailsResolver)
+	if !ok {
+		return nil, nil
+	}
+	return detailsResolver.GetImageDetails(ctx, repository.ECR.RepositoryName, tag)
+}
+
+// targetValueData is the data available to the templates of target values
+type targetValueData struct {
+	// Tag is the tag written to the target
+	Tag string
+
+	// Digest is the digest of the tag
+	Digest string
+
+	// PushedAt is when the image was pushed in RFC 3339, empty when the repository does
+	// not report it
+	PushedAt string
+}
+
+// renderTargetValue renders the template of a target value for the image
+func renderTargetValue(text string, image targetImage) (string, error) {
+	tmpl, err := template.New("value").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid value template: %w", err)
+	}
+
+	data := targetValueData{Tag: image.Tag, Digest: image.Digest}
+	if !image.PushedAt.IsZero() {
+		data.PushedAt = image.PushedAt.UTC().Format(time.RFC3339)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render value template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// updateTargetValues writes the further values of a target to the file, and reports
+// whether any of them changed
+func updateTargetValues(updater *yaml.Updater, filePath, format string, target yukv1.UpdateTarget, image targetImage) (bool, error) {
+	var modified bool
+	for _, value := range target.Values {
+		rendered, err := renderTargetValue(value.Value, image)
+		if err != nil {
+			return modified, fmt.Errorf("value at %s: %w", value.YAMLPath, err)
+		}
+
+		changed, err := updater.UpdatePath(filePath, format, value.YAMLPath, rendered, false, "")
+		if err != nil {
+			return modified, fmt.Errorf("value at %s: %w", value.YAMLPath, err)
+		}
+		modified = modified || changed
+	}
+	return modified, nil
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	"github.com/rebelopsio/yuk/pkg/git"
+	"github.com/rebelopsio/yuk/pkg/yaml"
+)
+
+// fakeImageDetailsResolver resolves ECR tags like fakeTagResolver and reports a fixed push
+// time for every image
+type fakeImageDetailsResolver struct {
+	*fakeTagResolver
+	pushedAt time.Time
+	lookups  int
+}
+
+func (f *fakeImageDetailsResolver) GetImageDetails(ctx context.Context, repositoryName, tag string) (*ecrtypes.ImageDetail, error) {
+	f.lookups++
+	digest, err := f.GetImageDigest(ctx, repositoryName, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &ecrtypes.ImageDetail{ImageDigest: aws.String(digest), ImagePushedAt: aws.Time(f.pushedAt)}, nil
+}
+
+func TestYukConfigReconciler_resolveTargetImages(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	pushedAt := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	resolver := &fakeImageDetailsResolver{
+		fakeTagResolver: &fakeTagResolver{digests: map[string]string{"my-app:v1.1.0": digest}},
+		pushedAt:        pushedAt,
+	}
+	reconciler := &YukConfigReconciler{
+		NewECRClient: func(region string, opts ...ecr.Option) TagResolver { return resolver },
+	}
+
+	values := []yukv1.TargetValue{{YAMLPath: "metadata.annotations.digest", Value: "{{ .Digest }}"}}
+	yukConfig := &yukv1.YukConfig{
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeECR,
+				ECR:  &yukv1.ECRConfig{Region: "us-west-2", RepositoryName: "my-app"},
+			},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "deployment.yaml", YAMLPath: "spec.image", Values: values},
+				{File: "values.yaml", YAMLPath: "image.tag"},
+				{File: "job.yaml", YAMLPath: "spec.image", Values: values},
+			},
+		},
+	}
+
+	images, err := reconciler.resolveTargetImages(context.Background(), yukConfig,
+		[]string{"v1.1.0", "v1.1.0", "v1.1.0"}, []string{"", "", ""}, &credentials{})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	expected := targetImage{Tag: "v1.1.0", Digest: digest, PushedAt: pushedAt}
+	if images[0] != expected || images[2] != expected {
+		t.Errorf("Expected image %+v for the targets with values, got %+v", expected, images)
+	}
+	if images[1] != (targetImage{}) {
+		t.Errorf("Expected no image for the target without values, got %+v", images[1])
+	}
+	if resolver.lookups != 1 {
+		t.Errorf("Expected the image to be looked up once, got %d lookups", resolver.lookups)
+	}
+}
+
+func TestRenderTargetValue(t *testing.T) {
+	image := targetImage{Tag: "v1.1.0", Digest: "sha256:abc", PushedAt: time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name        string
+		template    string
+		image       targetImage
+		expected    string
+		expectError bool
+	}{
+		{name: "digest", template: "{{ .Digest }}", image: image, expected: "sha256:abc"},
+		{name: "combined", template: "{{ .Tag }}@{{ .Digest }}", image: image, expected: "v1.1.0@sha256:abc"},
+		{name: "push time", template: "{{ .PushedAt }}", image: image, expected: "2024-05-01T09:30:00Z"},
+		{name: "unknown push time", template: "{{ .PushedAt }}", image: targetImage{Tag: "v1.1.0"}, expected: ""},
+		{name: "unknown field", template: "{{ .Size }}", image: image, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := renderTargetValue(tt.template, tt.image)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected an error, got value %q", value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if value != tt.expected {
+				t.Errorf("Expected value %q, got %q", tt.expected, value)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_updateFiles_TargetValues(t *testing.T) {
+	oldDigest := "sha256:" + strings.Repeat("a", 64)
+	newDigest := "sha256:" + strings.Repeat("b", 64)
+	upstream := newUpstreamRepository(t, map[string]string{
+		"deployment.yaml": "metadata:\n    annotations:\n        example.com/image-digest: " + oldDigest + "\n" +
+			"        example.com/pushed-at: \"2024-04-01T00:00:00Z\"\n" +
+			"spec:\n    image: registry.example.com/my-app:v1.0.0\n",
+	})
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "values-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Git: yukv1.GitConfig{Repository: upstream, Branch: "main", Email: "test@example.com", Name: "Test User"},
+			UpdateTargets: []yukv1.UpdateTarget{{
+				File:         "deployment.yaml",
+				YAMLPath:     "spec.image",
+				ImageTagOnly: true,
+				Values: []yukv1.TargetValue{
+					{YAMLPath: `metadata.annotations["example.com/image-digest"]`, Value: "{{ .Digest }}"},
+					{YAMLPath: `metadata.annotations["example.com/pushed-at"]`, Value: "{{ .PushedAt }}"},
+				},
+			}},
+		},
+	}
+
+	reconciler := &YukConfigReconciler{}
+	gitClient := git.NewClient(yukConfig.Spec.Git, git.WithBaseDir(t.TempDir()))
+	images := []targetImage{{Tag: "v1.1.0", Digest: newDigest, PushedAt: time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)}}
+
+	outcome, err := reconciler.updateFiles(context.Background(), yukConfig, staticGitClient(gitClient), yaml.NewUpdater(),
+		"v1.1.0", []string{"v1.1.0"}, images, ActionPush)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if strings.Join(outcome.FilesChanged, ",") != "deployment.yaml" {
+		t.Errorf("Expected deployment.yaml to change, got %v", outcome.FilesChanged)
+	}
+
+	out, err := exec.Command("git", "-C", upstream, "show", "main:deployment.yaml").Output()
+	if err != nil {
+		t.Fatalf("Failed to read upstream deployment.yaml: %v", err)
+	}
+	for _, expected := range []string{
+		"image: registry.example.com/my-app:v1.1.0",
+		"example.com/image-digest: " + newDigest,
+		"example.com/pushed-at: \"2024-05-01T09:30:00Z\"",
+	} {
+		if !strings.Contains(string(out), expected) {
+			t.Errorf("Expected deployment.yaml to contain %q, got:\n%s", expected, out)
+		}
+	}
+}
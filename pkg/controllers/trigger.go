@@ -0,0 +1,89 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// triggerBufferSize is the number of pending trigger events buffered before Enqueue blocks
+const triggerBufferSize = 1024
+
+// ReconcileTrigger queues immediate reconciles of YukConfigs from outside the
+// controller, e.g. registry push notifications. Triggered reconciles bypass the
+// check interval.
+type ReconcileTrigger struct {
+	events chan event.GenericEvent
+
+	mu      sync.Mutex
+	pending map[types.NamespacedName]bool
+}
+
+// NewReconcileTrigger creates a new reconcile trigger
+func NewReconcileTrigger() *ReconcileTrigger {
+	return &ReconcileTrigger{
+		events:  make(chan event.GenericEvent, triggerBufferSize),
+		pending: make(map[types.NamespacedName]bool),
+	}
+}
+
+// Enqueue requests an immediate reconcile of the YukConfig
+func (t *ReconcileTrigger) Enqueue(yukConfig *yukv1.YukConfig) {
+	key := types.NamespacedName{Namespace: yukConfig.Namespace, Name: yukConfig.Name}
+
+	t.mu.Lock()
+	t.pending[key] = true
+	t.mu.Unlock()
+
+	t.events <- event.GenericEvent{Object: yukConfig}
+}
+
+// consume reports whether a reconcile was triggered for the YukConfig and clears it
+func (t *ReconcileTrigger) consume(key types.NamespacedName) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	triggered := t.pending[key]
+	delete(t.pending, key)
+	return triggered
+}
+
+// restore marks a consumed trigger as pending again without queueing a reconcile, so
+// the next reconcile of the YukConfig still bypasses the check interval
+func (t *ReconcileTrigger) restore(key types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending[key] = true
+}
+
+// reconcileRequested returns the value of the reconcile annotation and whether it
+// requests a check, i.e. it changed since the last handled request
+func reconcileRequested(yukConfig *yukv1.YukConfig) (string, bool) {
+	requestedAt := yukConfig.Annotations[yukv1.ReconcileRequestAnnotation]
+	return requestedAt, requestedAt != "" && requestedAt != yukConfig.Status.LastHandledReconcileAt
+}
+
+// forceUpdateRequested reports whether the force update annotation is set
+func forceUpdateRequested(yukConfig *yukv1.YukConfig) bool {
+	return yukConfig.Annotations[yukv1.ForceUpdateAnnotation] == "true"
+}
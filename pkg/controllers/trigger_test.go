@@ -0,0 +1,258 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	"github.com/rebelopsio/yuk/pkg/git"
+)
+
+func TestReconcileTrigger(t *testing.T) {
+	trigger := NewReconcileTrigger()
+	key := types.NamespacedName{Namespace: "default", Name: "test-config"}
+
+	if trigger.consume(key) {
+		t.Errorf("Expected no pending trigger before Enqueue")
+	}
+
+	trigger.Enqueue(&yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+	})
+
+	select {
+	case evt := <-trigger.events:
+		if evt.Object.GetName() != "test-config" {
+			t.Errorf("Expected event for test-config, got %s", evt.Object.GetName())
+		}
+	default:
+		t.Fatalf("Expected an event to be sent")
+	}
+
+	if !trigger.consume(key) {
+		t.Errorf("Expected pending trigger after Enqueue")
+	}
+	if trigger.consume(key) {
+		t.Errorf("Expected trigger to be cleared after consume")
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_ReconcileAnnotation(t *testing.T) {
+	var checks atomic.Int32
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "project/app", "tags": []string{"v1.0.0"}})
+	}))
+	defer registry.Close()
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	lastChecked := metav1.NewTime(time.Now().Add(-time.Minute))
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-config",
+			Namespace:   "default",
+			Annotations: map[string]string{yukv1.ReconcileRequestAnnotation: "2024-06-01T12:00:00Z"},
+		},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					Insecure:       true,
+				},
+			},
+			Git:           yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+			CheckInterval: &metav1.Duration{Duration: 10 * time.Minute},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0", LastChecked: &lastChecked},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	// The annotation bypasses the check interval despite the recent check
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if checks.Load() != 1 {
+		t.Fatalf("Expected the repository to be checked once, got %d checks", checks.Load())
+	}
+
+	updated := &yukv1.YukConfig{}
+	if err := reconciler.Get(context.Background(), req.NamespacedName, updated); err != nil {
+		t.Fatalf("Failed to get YukConfig: %v", err)
+	}
+	if updated.Status.LastHandledReconcileAt != "2024-06-01T12:00:00Z" {
+		t.Errorf("Expected handled reconcile request 2024-06-01T12:00:00Z, got %q", updated.Status.LastHandledReconcileAt)
+	}
+
+	// The same value does not bypass the check interval again
+	result, err := reconciler.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if checks.Load() != 1 {
+		t.Errorf("Expected no further repository check, got %d checks", checks.Load())
+	}
+	if result.RequeueAfter <= 0 {
+		t.Errorf("Expected a requeue for the next check, got %v", result.RequeueAfter)
+	}
+
+	// A new value requests another check
+	updated.Annotations[yukv1.ReconcileRequestAnnotation] = "2024-06-01T12:05:00Z"
+	if err := reconciler.Update(context.Background(), updated); err != nil {
+		t.Fatalf("Failed to update YukConfig: %v", err)
+	}
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if checks.Load() != 2 {
+		t.Errorf("Expected the repository to be checked again, got %d checks", checks.Load())
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_ForceUpdate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	// The file was edited by hand since v1.1.0 was pushed
+	lastChecked := metav1.NewTime(time.Now().Add(-time.Minute))
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-config",
+			Namespace:   "default",
+			Annotations: map[string]string{yukv1.ForceUpdateAnnotation: "true"},
+		},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeECR,
+				ECR:  &yukv1.ECRConfig{Region: "us-west-2", RepositoryName: "my-app"},
+			},
+			Git:           yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+			CheckInterval: &metav1.Duration{Duration: 10 * time.Minute},
+			UpdateTargets: []yukv1.UpdateTarget{{File: "values.yaml", YAMLPath: "image.tag"}},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.1.0", LatestTag: "v1.1.0", LastChecked: &lastChecked},
+	}
+
+	gitOperator := newFakeGitOperator(t, map[string]string{"values.yaml": "image:\n    tag: v1.0.0-hotfix\n"})
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+		NewECRClient: func(region string, opts ...ecr.Option) TagResolver {
+			return &fakeTagResolver{latestTags: map[string]string{"my-app:": "v1.1.0"}}
+		},
+		NewGitClient: func(config yukv1.GitConfig, opts ...git.Option) GitOperator {
+			return gitOperator
+		},
+	}
+
+	// The forced update rewrites the current tag despite the recent check
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if content := gitOperator.file("values.yaml"); !strings.Contains(content, "tag: v1.1.0") {
+		t.Errorf("Expected the manual edit to be corrected to v1.1.0, got:\n%s", content)
+	}
+	if gitOperator.pushes != 1 {
+		t.Errorf("Expected 1 push, got %d", gitOperator.pushes)
+	}
+
+	updated := &yukv1.YukConfig{}
+	if err := reconciler.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Failed to get YukConfig: %v", err)
+	}
+	if _, ok := updated.Annotations[yukv1.ForceUpdateAnnotation]; ok {
+		t.Error("Expected the force update annotation to be removed")
+	}
+	if updated.Status.CurrentTag != "v1.1.0" || updated.Status.PreviousTag != "" {
+		t.Errorf("Expected current tag v1.1.0 without a previous tag, got %s and %q", updated.Status.CurrentTag, updated.Status.PreviousTag)
+	}
+
+	// Without the annotation, the next reconcile waits for the check interval
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if gitOperator.clones != 1 {
+		t.Errorf("Expected no further clone, got %d clones", gitOperator.clones)
+	}
+}
+
+func TestReconcileRequested(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotation  string
+		handled     string
+		expectedReq bool
+	}{
+		{name: "no annotation"},
+		{name: "new request", annotation: "1", expectedReq: true},
+		{name: "changed request", annotation: "2", handled: "1", expectedReq: true},
+		{name: "handled request", annotation: "1", handled: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yukConfig := &yukv1.YukConfig{Status: yukv1.YukConfigStatus{LastHandledReconcileAt: tt.handled}}
+			if tt.annotation != "" {
+				yukConfig.Annotations = map[string]string{yukv1.ReconcileRequestAnnotation: tt.annotation}
+			}
+
+			requestedAt, requested := reconcileRequested(yukConfig)
+			if requested != tt.expectedReq {
+				t.Errorf("Expected requested %v, got %v", tt.expectedReq, requested)
+			}
+			if requestedAt != tt.annotation {
+				t.Errorf("Expected requestedAt %q, got %q", tt.annotation, requestedAt)
+			}
+		})
+	}
+}
@@ -18,21 +18,36 @@ package controllers
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/oauth2"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/diff"
 	"github.com/rebelopsio/yuk/pkg/ecr"
 	"github.com/rebelopsio/yuk/pkg/git"
 	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+	"github.com/rebelopsio/yuk/pkg/notify"
+	"github.com/rebelopsio/yuk/pkg/updatewindow"
+	"github.com/rebelopsio/yuk/pkg/verify"
 	"github.com/rebelopsio/yuk/pkg/yaml"
 )
 
@@ -40,23 +55,139 @@ import (
 type YukConfigReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// APIReader reads the referenced secrets from the API server, so that secrets are never
+	// cached and only need get access (default: the manager's API reader)
+	APIReader client.Reader
+
+	// CloneBaseDir is the directory repositories are cloned into (default: system temp dir)
+	CloneBaseDir string
+
+	// ReuseClones keeps clones in CloneBaseDir between reconciles and refreshes them with a
+	// fetch instead of cloning the repository again
+	ReuseClones bool
+
+	// Trigger queues immediate reconciles from outside the controller (optional)
+	Trigger *ReconcileTrigger
+
+	// Summary writes a machine-readable summary line per reconcile (optional)
+	Summary *SummaryWriter
+
+	// Recorder emits Kubernetes events for the YukConfig (default: the manager's recorder)
+	Recorder record.EventRecorder
+
+	// MaxConcurrentReconciles is how many YukConfigs are reconciled in parallel (default: 1)
+	MaxConcurrentReconciles int
+
+	// ECRCache shares ECR image lookups between YukConfigs (optional)
+	ECRCache *ecr.Cache
+
+	// GARTokenSource authenticates all Artifact Registry clients, so tokens are reused
+	// between reconciles (default: a new Application Default Credentials token source
+	// per client)
+	GARTokenSource oauth2.TokenSource
+
+	// NewECRClient creates the tag resolver of ECR repositories in a region (default:
+	// ecr.NewClient)
+	NewECRClient func(region string, opts ...ecr.Option) TagResolver
+
+	// GitHubAppCache shares GitHub App installation tokens and bot identities between
+	// reconciles (optional)
+	GitHubAppCache *git.AppCache
+
+	// SSHKnownHostsFile is the known_hosts file verifying the host keys of SSH repositories
+	// of YukConfigs without knownHostsRef (default: the ssh defaults)
+	SSHKnownHostsFile string
+
+	// NewSignatureVerifier creates the verifier of image signatures made with a public key,
+	// reading signatures through the fetcher (default: verify.NewCosignVerifier)
+	NewSignatureVerifier func(publicKey []byte, fetcher verify.Fetcher) (SignatureVerifier, error)
+
+	// NewGitClient creates the Git client of a YukConfig (default: git.NewClient)
+	NewGitClient func(config yukv1.GitConfig, opts ...git.Option) GitOperator
+
+	// MinCheckInterval is the shortest check interval; shorter intervals are raised to it
+	MinCheckInterval time.Duration
+
+	// RequeueJitter spreads scheduled checks by up to this fraction of the check interval
+	// in either direction, e.g. 0.1 for ±10% (default: 0, no jitter)
+	RequeueJitter float64
+
+	// Health tracks successful reconciles for the health and readiness probes (optional)
+	Health *HealthChecker
+
+	// CheckRateLimiter limits how often repositories are checked (optional)
+	CheckRateLimiter *CheckRateLimiter
+
+	// RepositoryLocks keeps YukConfigs sharing a Git repository from updating it at the
+	// same time; the reconciles finding it locked are retried shortly (optional)
+	RepositoryLocks *RepositoryLocks
+
+	// MaxFailureBackoff caps the backoff of checks while a permanent failure persists; the
+	// delay doubles from the check interval with every consecutive failure (default: 0, no
+	// backoff)
+	MaxFailureBackoff time.Duration
 }
 
+// Event reasons
+const (
+	// EventReasonUpdatePending is emitted when a dry run finds changes it would make
+	EventReasonUpdatePending = "UpdatePending"
+)
+
 //+kubebuilder:rbac:groups=yuk.rebelops.io,resources=yukconfigs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=yuk.rebelops.io,resources=yukconfigs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=yuk.rebelops.io,resources=yukconfigs/finalizers,verbs=update
-//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-func (r *YukConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	logger := log.FromContext(ctx)
+func (r *YukConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, err error) {
+	// Correlate every log line of the reconcile, including those of the helpers
+	// receiving the context, with the YukConfig
+	logger := log.FromContext(ctx).WithValues("namespace", req.Namespace, "name", req.Name)
+	ctx = log.IntoContext(ctx, logger)
 	startTime := time.Now()
 
 	// Track reconciliation metrics
 	var result yukmetrics.ReconciliationResult = yukmetrics.ReconciliationSuccess
+	summary := ReconcileSummary{
+		Config:    req.Name,
+		Namespace: req.Namespace,
+	}
+	checked, deleted := false, false
 	defer func() {
-		// Record reconciliation duration and total count
+		logger.Info("Reconcile finished", "result", string(result), "duration", time.Since(startTime).String())
+
+		// Track the checks of this YukConfig for the health probes. Reconciles waiting
+		// for the check interval or the rate limiter only schedule the next check.
+		if r.Health != nil {
+			switch {
+			case checked:
+				r.Health.recordCheck(req.NamespacedName, result == yukmetrics.ReconciliationSuccess, res.RequeueAfter)
+			case res.RequeueAfter > 0:
+				r.Health.schedule(req.NamespacedName, res.RequeueAfter)
+			default:
+				r.Health.forget(req.NamespacedName)
+			}
+		}
+
+		// Emit the reconcile summary
+		if r.Summary != nil {
+			summary.Result = string(result)
+			summary.DurationMs = time.Since(startTime).Milliseconds()
+			if err := r.Summary.Write(summary); err != nil {
+				logger.Error(err, "Failed to write reconcile summary")
+			}
+		}
+
+		// Record reconciliation duration and total count, unless the series of a deleted
+		// YukConfig were just removed
+		if deleted {
+			return
+		}
 		yukmetrics.ReconciliationDuration.With(prometheus.Labels{
 			"namespace": req.Namespace,
 			"name":      req.Name,
@@ -76,8 +207,9 @@ func (r *YukConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		if errors.IsNotFound(err) {
 			logger.Info("YukConfig resource not found. Ignoring since object must be deleted")
 			// Clean up metrics for deleted resource
-			r.cleanupMetrics(req.Namespace, req.Name)
+			yukmetrics.DeleteConfigMetrics(req.Namespace, req.Name)
 			result = yukmetrics.ReconciliationSkipped
+			deleted = true
 			return ctrl.Result{}, nil
 		}
 		logger.Error(err, "Failed to get YukConfig")
@@ -89,6 +221,35 @@ func (r *YukConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}).Inc()
 		return ctrl.Result{}, err
 	}
+	logger = logger.WithValues("repository", yukConfig.Spec.Git.Repository)
+	ctx = log.IntoContext(ctx, logger)
+
+	// Clean up the external state of a deleted YukConfig before releasing it
+	if !yukConfig.DeletionTimestamp.IsZero() {
+		result = yukmetrics.ReconciliationSkipped
+		if !controllerutil.ContainsFinalizer(&yukConfig, yukv1.CleanupFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.finalize(ctx, &yukConfig); err != nil {
+			logger.Error(err, "Failed to clean up deleted YukConfig")
+			result = yukmetrics.ReconciliationError
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(&yukConfig, yukv1.CleanupFinalizer)
+		if err := r.Update(ctx, &yukConfig); err != nil {
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+		deleted = true
+		return ctrl.Result{}, nil
+	}
+	if controllerutil.AddFinalizer(&yukConfig, yukv1.CleanupFinalizer) {
+		if err := r.Update(ctx, &yukConfig); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	summary.OldTag = yukConfig.Status.CurrentTag
+	summary.NewTag = yukConfig.Status.CurrentTag
 
 	// Skip processing if disabled
 	if yukConfig.Spec.Disabled {
@@ -98,118 +259,389 @@ func (r *YukConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, nil
 	}
 
+	// Skip processing if paused; removing the annotation triggers a new reconcile
+	if yukConfig.Annotations[yukv1.PausedAnnotation] == "true" {
+		logger.Info("YukConfig is paused, skipping processing")
+		result = yukmetrics.ReconciliationSkipped
+		r.updateStatusMetrics(&yukConfig)
+		return ctrl.Result{}, nil
+	}
+
 	// Determine check interval
-	checkInterval := 5 * time.Minute
-	if yukConfig.Spec.CheckInterval != nil {
-		checkInterval = yukConfig.Spec.CheckInterval.Duration
+	checkInterval := r.checkInterval(&yukConfig)
+
+	// Reconciles requested through the trigger (e.g. a registry push) or the reconcile
+	// annotation bypass the check interval
+	triggered := r.Trigger != nil && r.Trigger.consume(req.NamespacedName)
+	if triggered {
+		logger.Info("Reconcile triggered, bypassing check interval")
+	}
+	requestedAt, requested := reconcileRequested(&yukConfig)
+	if requested {
+		logger.Info("Reconcile requested by annotation, bypassing check interval", "requestedAt", requestedAt)
+		triggered = true
+	}
+	force := forceUpdateRequested(&yukConfig)
+	if force {
+		logger.Info("Forced update requested by annotation, bypassing check interval")
+		triggered = true
+	}
+	rollback := rollbackRequested(&yukConfig)
+
+	// Stay stopped after too many consecutive failures until the spec changes or a
+	// reconcile, forced update or rollback is requested. Resumed checks run right away.
+	resumed := false
+	if checksStopped(&yukConfig) {
+		resumed = true
+		switch {
+		case yukConfig.Generation != yukConfig.Status.ObservedGeneration:
+			logger.Info("Spec changed, resuming checks stopped after consecutive failures")
+			r.resumeChecks(&yukConfig, "a spec change")
+		case requested || force || rollback:
+			logger.Info("Reconcile requested, resuming checks stopped after consecutive failures")
+			r.resumeChecks(&yukConfig, "a requested reconcile")
+		default:
+			logger.Info("Checks stopped after consecutive failures, skipping processing",
+				"consecutiveFailures", yukConfig.Status.ConsecutiveFailures)
+			result = yukmetrics.ReconciliationSkipped
+			r.updateStatusMetrics(&yukConfig)
+			return ctrl.Result{}, nil
+		}
 	}
 
-	// Check if we need to process based on last check time
+	// Check if we need to process based on last check time. Transient failures are
+	// retried sooner than the check interval. Checks scheduled early by the jitter
+	// are accepted.
 	now := metav1.Now()
-	if yukConfig.Status.LastChecked != nil {
+	if yukConfig.Status.LastChecked != nil && !triggered && !rollback && !resumed {
+		interval := r.nextCheckInterval(&yukConfig, checkInterval)
 		timeSinceLastCheck := now.Time.Sub(yukConfig.Status.LastChecked.Time)
-		if timeSinceLastCheck < checkInterval {
+		if timeSinceLastCheck < interval-r.jitterWindow(interval) {
 			// Schedule next reconciliation
-			nextCheck := checkInterval - timeSinceLastCheck
+			nextCheck := interval - timeSinceLastCheck
 			logger.Info("Too early for next check", "nextCheck", nextCheck)
+			result = yukmetrics.ReconciliationThrottled
 			return ctrl.Result{RequeueAfter: nextCheck}, nil
 		}
 	}
 
-	// Update last checked timestamp
+	// Defer the check while the repositories are rate limited, without calling the
+	// registry. A triggered check stays triggered for the deferred reconcile.
+	if !rollback {
+		if delay := r.rateLimitChecks(&yukConfig); delay > 0 {
+			logger.Info("Repository checks rate limited", "retryAfter", delay)
+			if triggered && r.Trigger != nil {
+				r.Trigger.restore(req.NamespacedName)
+			}
+			result = yukmetrics.ReconciliationSkipped
+			return ctrl.Result{RequeueAfter: delay}, nil
+		}
+	}
+
+	// Update last checked timestamp and record the handled reconcile request, so the
+	// same annotation value does not bypass the check interval again
+	checked = true
 	yukConfig.Status.LastChecked = &now
+	yukConfig.Status.VerificationPending = false
+	yukConfig.Status.UpdateDeferredUntil = nil
+	if requested {
+		yukConfig.Status.LastHandledReconcileAt = requestedAt
+	}
 	yukConfig.Status.ObservedGeneration = yukConfig.Generation
 
-	// Check for new versions based on repository type
-	var latestTag string
-	var err error
-	repoCheckStart := time.Now()
-
-	switch yukConfig.Spec.Repository.Type {
-	case "ecr":
-		if yukConfig.Spec.Repository.ECR == nil {
-			err = fmt.Errorf("ECR configuration is required when repository type is 'ecr'")
-		} else {
-			ecrClient := ecr.NewClient(yukConfig.Spec.Repository.ECR.Region)
-			latestTag, err = ecrClient.GetLatestTag(ctx, yukConfig.Spec.Repository.ECR.RepositoryName, yukConfig.Spec.Repository.ECR.TagFilter)
-
-			// Record repository check metrics
-			repoResult := yukmetrics.RepositoryCheckSuccess
-			if err != nil {
-				repoResult = yukmetrics.RepositoryCheckError
-			}
-
-			yukmetrics.RepositoryChecks.With(prometheus.Labels{
-				"repository_type": "ecr",
-				"repository_name": yukConfig.Spec.Repository.ECR.RepositoryName,
-				"result":          string(repoResult),
-			}).Inc()
+	// Make sure update targets reference known sources and the update window is valid
+	configErr := validateSources(&yukConfig)
+	var window *updatewindow.Window
+	if configErr == nil && yukConfig.Spec.UpdateWindow != nil {
+		window, configErr = updatewindow.New(*yukConfig.Spec.UpdateWindow)
+	}
+	if err := configErr; err != nil {
+		logger.Error(err, "Invalid configuration")
+		result = yukmetrics.ReconciliationError
+		yukmetrics.ErrorsTotal.With(prometheus.Labels{
+			"error_type": string(yukmetrics.ErrorTypeValidation),
+			"namespace":  req.Namespace,
+			"name":       req.Name,
+		}).Inc()
+		requeueAfter := r.recordFailure(&yukConfig, "Invalid configuration", err, checkInterval, now.Time)
+		r.updateStatusMetrics(&yukConfig)
+		return ctrl.Result{RequeueAfter: requeueAfter}, r.updateStatus(ctx, &yukConfig)
+	}
 
-			yukmetrics.RepositoryCheckDuration.With(prometheus.Labels{
-				"repository_type": "ecr",
-				"repository_name": yukConfig.Spec.Repository.ECR.RepositoryName,
-			}).Observe(time.Since(repoCheckStart).Seconds())
+	// Resolve the credentials referenced by the configuration
+	creds, err := r.resolveCredentials(ctx, &yukConfig)
+	if err != nil {
+		logger.Error(err, "Failed to resolve credentials")
+		result = yukmetrics.ReconciliationError
+		yukmetrics.ErrorsTotal.With(prometheus.Labels{
+			"error_type": string(yukmetrics.ErrorTypeAuth),
+			"namespace":  req.Namespace,
+			"name":       req.Name,
+		}).Inc()
+		requeueAfter := r.recordFailure(&yukConfig, "Failed to resolve credentials", err, checkInterval, now.Time)
+		r.updateStatusMetrics(&yukConfig)
+		return ctrl.Result{RequeueAfter: requeueAfter}, r.updateStatus(ctx, &yukConfig)
+	}
+	notifiers := creds.notifiers()
+
+	// Fill in an ECR repository left to be derived from the image at the first update target
+	if err := r.deriveECRRepository(ctx, &yukConfig, creds); stderrors.Is(err, errRepositoryBusy) {
+		// Nothing was checked out; the check is retried once the repository is free
+		logger.Info("Git repository busy, retrying to derive the ECR repository", "retryAfter", repositoryBusyRetryDelay)
+		if triggered && r.Trigger != nil {
+			r.Trigger.restore(req.NamespacedName)
 		}
-	default:
-		err = fmt.Errorf("unsupported repository type: %s", yukConfig.Spec.Repository.Type)
+		checked = false
+		result = yukmetrics.ReconciliationSkipped
+		return ctrl.Result{RequeueAfter: repositoryBusyRetryDelay}, nil
+	} else if err != nil {
+		logger.Error(err, "Failed to derive the ECR repository")
+		result = yukmetrics.ReconciliationError
+		yukmetrics.ErrorsTotal.With(prometheus.Labels{
+			"error_type": string(yukmetrics.ErrorTypeValidation),
+			"namespace":  req.Namespace,
+			"name":       req.Name,
+		}).Inc()
+		requeueAfter := r.recordFailure(&yukConfig, "Failed to derive the ECR repository", err, checkInterval, now.Time)
+		r.updateStatusMetrics(&yukConfig)
+		return ctrl.Result{RequeueAfter: requeueAfter}, r.updateStatus(ctx, &yukConfig)
+	}
+
+	// Roll back the last pushed update when requested, instead of checking for new versions
+	if rollback {
+		var requeue ctrl.Result
+		requeue, result, err = r.reconcileRollback(ctx, &yukConfig, creds, notifiers, &summary, checkInterval, now)
+		checked = result != yukmetrics.ReconciliationSkipped
+		return requeue, err
+	}
+
+	// Check every source for new versions. A triggered reconcile (e.g. a registry push)
+	// must see the new tag, so it does not reuse cached lookups.
+	sourceTags, err := r.getSourceTags(ctx, &yukConfig, creds, triggered)
+	primary := defaultSource(&yukConfig)
+	latestTag := sourceTags[primary.name][repositoryTagFilter(primary.repository)]
+
+	// Resolve the tag for each target from its source, honoring per-target tag filters,
+	// and the digest of that tag for targets pinned by digest
+	var targetTags, targetDigests []string
+	var targetImages []targetImage
+	if err == nil {
+		targetTags = r.resolveTargetTags(&yukConfig, sourceTags)
+		targetDigests, err = r.resolveTargetDigests(ctx, &yukConfig, targetTags, creds)
+	}
+	if err == nil {
+		targetImages, err = r.resolveTargetImages(ctx, &yukConfig, targetTags, targetDigests, creds)
 	}
 
 	if err != nil {
 		logger.Error(err, "Failed to get latest tag from repository")
 		result = yukmetrics.ReconciliationError
 		yukmetrics.ErrorsTotal.With(prometheus.Labels{
-			"error_type": string(yukmetrics.ErrorTypeRepository),
+			"error_type": string(repositoryErrorType(err)),
 			"namespace":  req.Namespace,
 			"name":       req.Name,
 		}).Inc()
-		r.setCondition(&yukConfig, "Ready", metav1.ConditionFalse, "RepositoryError", err.Error())
+		requeueAfter := r.recordFailure(&yukConfig, "Repository check failed", err, checkInterval, now.Time)
+		r.notify(ctx, &yukConfig, notifiers, notify.Event{
+			Type:    notify.EventError,
+			OldTag:  summary.OldTag,
+			Message: fmt.Sprintf("Repository check failed: %v", err),
+		})
 		r.updateStatusMetrics(&yukConfig)
-		return ctrl.Result{RequeueAfter: checkInterval}, r.updateStatus(ctx, &yukConfig)
+		return ctrl.Result{RequeueAfter: requeueAfter}, r.updateStatus(ctx, &yukConfig)
+	}
+
+	// Announce a newly detected version once, when it first differs from the current tag
+	if latestTag != "" && latestTag != yukConfig.Status.LatestTag && latestTag != yukConfig.Status.CurrentTag {
+		r.recordEvent(&yukConfig, corev1.EventTypeNormal, ReasonUpdateAvailable,
+			"New version %s detected (current: %s)", latestTag, yukConfig.Status.CurrentTag)
 	}
 
+	// Track when the latest tag was first observed for pinned detection
+	if yukConfig.Status.LatestTag != latestTag || yukConfig.Status.LatestTagFirstSeen == nil {
+		yukConfig.Status.LatestTagFirstSeen = &now
+	}
 	yukConfig.Status.LatestTag = latestTag
+	r.checkPinned(&yukConfig, now.Time)
+	r.recordDeployedTag(ctx, &yukConfig)
+
+	sourcesChanged := r.updateSourceStatuses(&yukConfig, sourceTags)
+	targetsChanged := r.updateTargetStatuses(&yukConfig, targetTags, targetDigests)
+	r.checkUpToDate(&yukConfig)
+
+	// Decide what to do about the latest tag according to the update strategy, deferring
+	// updates while the update window is closed
+	values := targetValues(targetTags, targetDigests)
+	var windowOpens time.Time
+	if window != nil && !window.Open(now.Time) {
+		windowOpens = window.NextOpen(now.Time)
+	}
+	decision := decideUpdate(updateState{
+		Strategy:        updateStrategy(&yukConfig),
+		UpdateAvailable: yukConfig.Status.CurrentTag != latestTag || sourcesChanged || targetsChanged,
+		LatestTag:       latestTag,
+		LatestValues:    valuesHash(values),
+		ApprovedTag:     yukConfig.Annotations[yukv1.ApprovedTagAnnotation],
+		ProposedValues:  yukConfig.Status.ProposedValuesHash,
+		RolledBackTag:   yukConfig.Status.RolledBackTag,
+		Force:           force,
+		WindowOpens:     windowOpens,
+	})
+	if decision.Reason == ReasonOutsideWindow {
+		logger.Info("Outside of the update window, deferring the update", "latest", latestTag, "windowOpens", windowOpens)
+		yukConfig.Status.UpdateDeferredUntil = &metav1.Time{Time: windowOpens}
+	}
 
-	// Check if update is needed
-	if yukConfig.Status.CurrentTag != latestTag {
-		logger.Info("New version detected", "current", yukConfig.Status.CurrentTag, "latest", latestTag)
+	switch decision.Action {
+	case ActionInvalid:
+		logger.Error(nil, decision.Message)
+		result = yukmetrics.ReconciliationError
+		yukmetrics.ErrorsTotal.With(prometheus.Labels{
+			"error_type": string(yukmetrics.ErrorTypeValidation),
+			"namespace":  req.Namespace,
+			"name":       req.Name,
+		}).Inc()
+		requeueAfter := r.recordFailure(&yukConfig, "Invalid configuration", fmt.Errorf("%s", decision.Message), checkInterval, now.Time)
+		r.notify(ctx, &yukConfig, notifiers, notify.Event{
+			Type:    notify.EventError,
+			OldTag:  summary.OldTag,
+			NewTag:  latestTag,
+			Message: fmt.Sprintf("Invalid configuration: %s", decision.Message),
+		})
+		r.updateStatusMetrics(&yukConfig)
+		return ctrl.Result{RequeueAfter: requeueAfter}, r.updateStatus(ctx, &yukConfig)
+
+	case ActionPush, ActionProposeBranch, ActionDryRun:
+		logger.Info("New version detected", "current", yukConfig.Status.CurrentTag, "latest", latestTag, "action", decision.Action)
+
+		// Only promote images signed with the configured key
+		if yukConfig.Spec.VerifySignature != nil {
+			if err := r.verifySignatures(ctx, &yukConfig, targetTags, targetDigests, creds); err != nil {
+				logger.Error(err, "Failed to verify image signatures")
+				result = yukmetrics.ReconciliationError
+				yukmetrics.ErrorsTotal.With(prometheus.Labels{
+					"error_type": string(verificationErrorType(err)),
+					"namespace":  req.Namespace,
+					"name":       req.Name,
+				}).Inc()
+				requeueAfter := r.recordFailure(&yukConfig, "Signature verification failed", err, checkInterval, now.Time)
+				r.notify(ctx, &yukConfig, notifiers, notify.Event{
+					Type:    notify.EventError,
+					OldTag:  summary.OldTag,
+					NewTag:  latestTag,
+					Message: fmt.Sprintf("Signature verification failed: %v", err),
+				})
+				r.updateStatusMetrics(&yukConfig)
+				return ctrl.Result{RequeueAfter: requeueAfter}, r.updateStatus(ctx, &yukConfig)
+			}
+		}
 
 		// Perform Git operations to update files
-		gitClient := git.NewClient(yukConfig.Spec.Git)
 		yamlUpdater := yaml.NewUpdater()
-
-		if err := r.updateFiles(ctx, &yukConfig, gitClient, yamlUpdater, latestTag); err != nil {
+		outcome, err := r.updateFiles(ctx, &yukConfig, r.gitClientFactory(ctx, &yukConfig, creds), yamlUpdater, latestTag, values, targetImages, decision.Action)
+		if stderrors.Is(err, errRepositoryBusy) {
+			// Nothing was checked out; the update is retried once the repository is free
+			logger.Info("Git repository busy, retrying the update", "retryAfter", repositoryBusyRetryDelay)
+			if triggered && r.Trigger != nil {
+				r.Trigger.restore(req.NamespacedName)
+			}
+			checked = false
+			result = yukmetrics.ReconciliationSkipped
+			return ctrl.Result{RequeueAfter: repositoryBusyRetryDelay}, nil
+		}
+		if err != nil {
 			logger.Error(err, "Failed to update files")
 			result = yukmetrics.ReconciliationError
 			yukmetrics.ErrorsTotal.With(prometheus.Labels{
-				"error_type": string(yukmetrics.ErrorTypeGit),
+				"error_type": string(gitErrorType(err)),
 				"namespace":  req.Namespace,
 				"name":       req.Name,
 			}).Inc()
-			r.setCondition(&yukConfig, "Ready", metav1.ConditionFalse, "UpdateError", err.Error())
+			requeueAfter := r.recordFailure(&yukConfig, "Update failed", err, checkInterval, now.Time)
+			r.notify(ctx, &yukConfig, notifiers, notify.Event{
+				Type:    notify.EventError,
+				OldTag:  summary.OldTag,
+				NewTag:  latestTag,
+				Message: fmt.Sprintf("Update failed: %v", err),
+			})
 			r.updateStatusMetrics(&yukConfig)
-			return ctrl.Result{RequeueAfter: checkInterval}, r.updateStatus(ctx, &yukConfig)
+			return ctrl.Result{RequeueAfter: requeueAfter}, r.updateStatus(ctx, &yukConfig)
+		}
+
+		yukConfig.Status.SkippedTargets = outcome.SkippedTargets
+
+		// Proposals and previews are recorded so they are not repeated for the same values
+		if decision.Action != ActionPush {
+			yukConfig.Status.ProposedTag = latestTag
+			yukConfig.Status.ProposedValuesHash = valuesHash(values)
+			recordReviewBranches(&yukConfig, outcome.ReviewBranches)
+			if outcome.PullRequestURL != "" {
+				yukConfig.Status.PullRequestURL = outcome.PullRequestURL
+				decision.Message = fmt.Sprintf("Opened pull request %s for update to %s", outcome.PullRequestURL, latestTag)
+			}
+			if decision.Action == ActionDryRun {
+				yukConfig.Status.PendingChanges = outcome.PendingChanges
+				r.recordEvent(&yukConfig, corev1.EventTypeNormal, EventReasonUpdatePending,
+					"Dry run: update to %s would change %s", latestTag, strings.Join(outcome.FilesChanged, ", "))
+			} else if outcome.Commit != "" {
+				r.recordEvent(&yukConfig, corev1.EventTypeNormal, decision.Reason, "%s", decision.Message)
+			}
+			logger.Info("Proposed update", "newTag", latestTag, "action", decision.Action, "files", outcome.FilesChanged)
+			break
 		}
 
+		// Remember the replaced tag for rollbacks
+		if yukConfig.Status.CurrentTag != latestTag {
+			yukConfig.Status.PreviousTag = yukConfig.Status.CurrentTag
+			yukConfig.Status.RolledBackTag = ""
+		}
 		yukConfig.Status.CurrentTag = latestTag
 		yukConfig.Status.LastUpdate = &now
-
-		// Record successful update metrics
-		repositoryName := ""
-		if yukConfig.Spec.Repository.ECR != nil {
-			repositoryName = yukConfig.Spec.Repository.ECR.RepositoryName
+		yukConfig.Status.LastChangedFileCount = int32(len(outcome.FilesChanged))
+		yukConfig.Status.ProposedTag = ""
+		yukConfig.Status.ProposedValuesHash = ""
+		yukConfig.Status.PendingChanges = nil
+		summary.NewTag = latestTag
+		summary.FilesChanged = outcome.FilesChanged
+		summary.Commit = outcome.Commit
+		for i := range yukConfig.Status.Sources {
+			yukConfig.Status.Sources[i].CurrentTag = yukConfig.Status.Sources[i].LatestTag
+		}
+		for i := range yukConfig.Status.Targets {
+			yukConfig.Status.Targets[i].CurrentTag = yukConfig.Status.Targets[i].LatestTag
+			yukConfig.Status.Targets[i].CurrentDigest = yukConfig.Status.Targets[i].LatestDigest
 		}
 
+		// Record successful update metrics
 		yukmetrics.UpdatesPerformed.With(prometheus.Labels{
 			"namespace":       req.Namespace,
 			"name":            req.Name,
-			"repository_type": yukConfig.Spec.Repository.Type,
-			"repository_name": repositoryName,
+			"repository_type": primary.repository.Type,
+			"repository_name": repositoryName(primary.repository),
 		}).Inc()
 
-		logger.Info("Successfully updated files", "newTag", latestTag)
+		if outcome.Commit != "" {
+			yukConfig.Status.LastCommitSHA = outcome.Commit
+			yukConfig.Status.VerificationPending = yukConfig.Spec.PostUpdateRequeue != nil
+			recordHistory(&yukConfig, now, summary.OldTag, latestTag, outcome)
+			r.recordTimeToUpdate(ctx, &yukConfig, primary, latestTag, time.Now(), creds)
+			r.recordEvent(&yukConfig, corev1.EventTypeNormal, decision.Reason,
+				"Updated %s to %s and pushed commit %s", strings.Join(outcome.FilesChanged, ", "), latestTag, outcome.Commit)
+			r.notify(ctx, &yukConfig, notifiers, notify.Event{
+				Type:    notify.EventUpdate,
+				OldTag:  summary.OldTag,
+				NewTag:  latestTag,
+				Message: fmt.Sprintf("Updated %s in commit %s", strings.Join(outcome.FilesChanged, ", "), outcome.Commit),
+			})
+		}
+
+		logger.Info("Successfully updated files", "newTag", latestTag, "commit", outcome.Commit)
 	}
 
-	r.setCondition(&yukConfig, "Ready", metav1.ConditionTrue, "Synchronized", "Successfully synchronized with repository")
+	yukConfig.Status.ConsecutiveFailures = 0
+	r.checkUpToDate(&yukConfig)
+	r.setCondition(&yukConfig, "Ready", metav1.ConditionTrue, decision.Reason, decision.Message)
+	r.setAuthenticated(&yukConfig)
 
 	// Update status metrics
 	r.updateStatusMetrics(&yukConfig)
@@ -220,12 +652,171 @@ func (r *YukConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
-	// Schedule next reconciliation
-	return ctrl.Result{RequeueAfter: checkInterval}, nil
+	// The forced update is done; failed ones keep the annotation and are retried
+	if force {
+		if err := r.removeAnnotation(ctx, &yukConfig, yukv1.ForceUpdateAnnotation); err != nil {
+			result = yukmetrics.ReconciliationError
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Verify a pushed update after the post-update requeue
+	if requeue, ok := postUpdateRequeue(&yukConfig); ok {
+		logger.Info("Verifying the update", "requeueAfter", requeue)
+		return ctrl.Result{RequeueAfter: requeue}, nil
+	}
+
+	// Apply a deferred update when the update window opens, unless the next check comes first
+	if requeue, ok := deferredUpdateRequeue(&yukConfig); ok && requeue < checkInterval {
+		return ctrl.Result{RequeueAfter: requeue}, nil
+	}
+
+	// Schedule next reconciliation, spread out by the jitter
+	return ctrl.Result{RequeueAfter: r.withJitter(checkInterval)}, nil
+}
+
+// updateTargetStatuses records the latest tag of each target with a tag filter override,
+// and the latest digest of each target pinned by digest, and reports whether any of them
+// differs from the tag or digest last written
+func (r *YukConfigReconciler) updateTargetStatuses(yukConfig *yukv1.YukConfig, targetTags, targetDigests []string) bool {
+	var statuses []yukv1.TargetStatus
+	changed := false
+
+	for i, target := range yukConfig.Spec.UpdateTargets {
+		if target.TagFilter == "" && !target.ByDigest {
+			continue
+		}
+
+		status := yukv1.TargetStatus{
+			File:     target.File,
+			YAMLPath: target.YAMLPath,
+		}
+
+		// Carry over the tag last written to this target
+		for _, existing := range yukConfig.Status.Targets {
+			if existing.File == target.File && existing.YAMLPath == target.YAMLPath {
+				status.CurrentTag = existing.CurrentTag
+				status.CurrentDigest = existing.CurrentDigest
+				break
+			}
+		}
+
+		status.LatestTag = targetTags[i]
+		status.LatestDigest = targetDigests[i]
+		if status.CurrentTag != status.LatestTag || status.CurrentDigest != status.LatestDigest {
+			changed = true
+		}
+		statuses = append(statuses, status)
+	}
+
+	yukConfig.Status.Targets = statuses
+	return changed
+}
+
+// GitOperator clones the Git repository of a YukConfig and pushes updates to it. It is
+// implemented by git.Client and can be replaced with a fake through
+// YukConfigReconciler.NewGitClient in tests.
+type GitOperator interface {
+	// Clone clones the configured branch and returns the path of the clone
+	Clone(ctx context.Context) (string, error)
+
+	// CommitAndPush commits all changes of the clone and pushes them to the configured
+	// branch; it does nothing when there are no changes
+	CommitAndPush(ctx context.Context, repoPath, commitMessage string) error
+
+	// CommitAndPushToBranch commits all changes of the clone and force pushes them to the
+	// given branch
+	CommitAndPushToBranch(ctx context.Context, repoPath, commitMessage, branch string) error
+
+	// GetLastCommitHash returns the hash of the clone's HEAD commit
+	GetLastCommitHash(ctx context.Context, repoPath string) (string, error)
+
+	// CreatePullRequest opens a pull request, or returns the open one from the same branch
+	CreatePullRequest(ctx context.Context, opts git.PullRequestOptions) (*git.PullRequest, error)
+
+	// DeleteBranches deletes the given branches from the remote and returns the ones that
+	// existed
+	DeleteBranches(ctx context.Context, branches []string) ([]string, error)
+
+	// Cleanup removes the clone and any credentials written for it
+	Cleanup(repoPath string)
+}
+
+var _ GitOperator = (*git.Client)(nil)
+
+// gitClientFactory creates the Git client of a YukConfig cloning the given branch, or the
+// configured branch when empty
+type gitClientFactory func(branch string) GitOperator
+
+// newGitClient creates the Git client of a YukConfig cloning the given branch (default:
+// the configured branch), authenticated with its credentials
+func (r *YukConfigReconciler) newGitClient(ctx context.Context, yukConfig *yukv1.YukConfig, creds *credentials, branch string) GitOperator {
+	config := yukConfig.Spec.Git
+	if branch != "" {
+		config.Branch = branch
+	}
+
+	gitOpts := append([]git.Option{
+		git.WithBaseDir(r.CloneBaseDir),
+		git.WithReuseClones(r.ReuseClones),
+		git.WithKnownHostsFile(r.SSHKnownHostsFile),
+		git.WithAppCache(r.GitHubAppCache),
+		git.WithConfigRef(yukConfig.Namespace + "/" + yukConfig.Name),
+		git.WithPushRetryHook(func(attempt int, err error) {
+			recordPushRetry(ctx, yukConfig.Spec.Git.Repository, attempt, err)
+		}),
+	}, creds.gitOptions()...)
+	if pullRequestEnabled(yukConfig) && yukConfig.Spec.Git.PullRequest.APIURL != "" {
+		gitOpts = append(gitOpts, git.WithGitHubAPIURL(yukConfig.Spec.Git.PullRequest.APIURL))
+	}
+	if yukConfig.Spec.Git.SparseCheckout && sparseCheckoutSupported(yukConfig, branch) {
+		var files []string
+		for _, target := range yukConfig.Spec.UpdateTargets {
+			if targetBranch(yukConfig, target) == branch {
+				files = append(files, target.File)
+			}
+		}
+		gitOpts = append(gitOpts, git.WithSparseCheckout(git.SparseCheckoutPaths(files)))
+	}
+	if r.NewGitClient != nil {
+		return r.NewGitClient(config, gitOpts...)
+	}
+	return git.NewClient(config, gitOpts...)
 }
 
-// updateFiles updates the target files with the new image tag
-func (r *YukConfigReconciler) updateFiles(ctx context.Context, yukConfig *yukv1.YukConfig, gitClient *git.Client, yamlUpdater *yaml.Updater, newTag string) error {
+// gitClientFactory returns the factory of the Git clients of a YukConfig
+func (r *YukConfigReconciler) gitClientFactory(ctx context.Context, yukConfig *yukv1.YukConfig, creds *credentials) gitClientFactory {
+	return func(branch string) GitOperator {
+		return r.newGitClient(ctx, yukConfig, creds, branch)
+	}
+}
+
+// updateOutcome describes the changes made by updateFiles
+type updateOutcome struct {
+	// FilesChanged lists the files whose content changed, once per branch
+	FilesChanged []string
+
+	// Commit is the hash of the pushed commit, empty when there was nothing to commit.
+	// With several branches, it is the commit of the first branch with changes.
+	Commit string
+
+	// PullRequestURL is the URL of the pull request opened for the commit, or the first
+	// one with several branches
+	PullRequestURL string
+
+	// ReviewBranches lists the review branches a commit was pushed to
+	ReviewBranches []string
+
+	// PendingChanges holds the diff of each changed file for a dry run
+	PendingChanges []yukv1.PendingChange
+
+	// SkippedTargets lists the optional targets that do not exist
+	SkippedTargets []yukv1.SkippedTarget
+}
+
+// updateBranch updates the targets written to a branch returned by targetBranches, cloned
+// by gitClient, as described by updateFiles
+func (r *YukConfigReconciler) updateBranch(ctx context.Context, yukConfig *yukv1.YukConfig, gitClient GitOperator, yamlUpdater *yaml.Updater, branch, newTag string, targetTags []string, targetImages []targetImage, action UpdateAction) (*updateOutcome, error) {
 	logger := log.FromContext(ctx)
 	gitRepo := yukConfig.Spec.Git.Repository
 
@@ -251,41 +842,153 @@ func (r *YukConfigReconciler) updateFiles(ctx context.Context, yukConfig *yukv1.
 	}).Observe(time.Since(cloneStart).Seconds())
 
 	if err != nil {
-		return fmt.Errorf("failed to clone repository: %w", err)
+		return nil, fmt.Errorf("failed to clone repository: %w", err)
 	}
 	defer gitClient.Cleanup(repoPath)
 
+	// Remember the cloned commit to tell whether a new commit was pushed
+	baseCommit, err := gitClient.GetLastCommitHash(ctx, repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	outcome := &updateOutcome{}
+	changed := make(map[string]bool)
+
+	// A dry run diffs each file against its content before the first update
+	original := make(map[string][]byte)
+
 	// Update each target file
-	for _, target := range yukConfig.Spec.UpdateTargets {
-		logger.Info("Updating file", "file", target.File, "yamlPath", target.YAMLPath)
+	for i, target := range yukConfig.Spec.UpdateTargets {
+		targetTag := targetTags[i]
+		if targetTag == "" || targetBranch(yukConfig, target) != branch {
+			continue
+		}
 
-		filePath := fmt.Sprintf("%s/%s", repoPath, target.File)
-		if err := yamlUpdater.UpdateYAMLPath(filePath, target.YAMLPath, newTag, target.ImageTagOnly); err != nil {
-			yukmetrics.ErrorsTotal.With(prometheus.Labels{
-				"error_type": string(yukmetrics.ErrorTypeYAML),
-				"namespace":  yukConfig.Namespace,
-				"name":       yukConfig.Name,
-			}).Inc()
-			return fmt.Errorf("failed to update file %s: %w", target.File, err)
+		// A file pattern applies the target to every matching file of the clone
+		files, err := expandTargetFiles(repoPath, target.File)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			message := fmt.Sprintf("no files match %s", target.File)
+			if target.Optional {
+				outcome.skipTarget(ctx, yukConfig, target, branch, message)
+				continue
+			}
+			return nil, fmt.Errorf("failed to update file %s: %s", target.File, message)
 		}
 
-		// Record file update metric
-		yukmetrics.FilesUpdated.With(prometheus.Labels{
-			"namespace": yukConfig.Namespace,
-			"name":      yukConfig.Name,
-			"file_path": target.File,
-		}).Inc()
+		for _, file := range files {
+			// Status, metrics and errors refer to the matched file
+			target := target
+			target.File = file
+
+			logger.Info("Updating file", "file", target.File, "mode", target.Mode, "yamlPath", target.YAMLPath, "name", target.Name, "tag", targetTag)
+
+			filePath := fmt.Sprintf("%s/%s", repoPath, target.File)
+			if _, ok := original[target.File]; action == ActionDryRun && !ok {
+				content, err := os.ReadFile(filePath)
+				if target.Optional && missingTarget(err) {
+					outcome.skipTarget(ctx, yukConfig, target, branch, strings.ReplaceAll(err.Error(), repoPath+"/", ""))
+					continue
+				}
+				if err != nil {
+					return nil, fmt.Errorf("failed to read file %s: %w", target.File, err)
+				}
+				original[target.File] = content
+			}
+
+			var modified bool
+			yamlUpdater := yamlUpdater.With(yaml.WithPathSyntax(target.PathSyntax), yaml.WithExistingPathsOnly(target.Optional))
+			format, err := yaml.FileFormat(target.File, target.Format)
+			switch {
+			case err != nil:
+			case target.Mode == yukv1.UpdateModeArgoApplication && (format == yaml.FormatTOML || format == yaml.FormatEnv):
+				err = fmt.Errorf("argoApplication mode is not supported in %s files", strings.ToUpper(format))
+			case target.Mode == yukv1.UpdateModeArgoApplication:
+				modified, err = yamlUpdater.UpdateArgoApplication(filePath, target.Name, targetTag, target.ImageTagOnly)
+			case target.Mode == yukv1.UpdateModeHelmImage && format != yaml.FormatYAML:
+				err = fmt.Errorf("helmImage mode is not supported in %s files", strings.ToUpper(format))
+			case target.Mode == yukv1.UpdateModeKustomizeImage && format != yaml.FormatYAML:
+				err = fmt.Errorf("kustomizeImage mode is not supported in %s files", strings.ToUpper(format))
+			case target.Mode == yukv1.UpdateModeKustomizeImage:
+				modified, err = yamlUpdater.UpdateKustomizeImage(filePath, target.Name, targetTag)
+			case target.Mode == yukv1.UpdateModeHelmImage:
+				image := yaml.HelmImage{
+					Path:          target.YAMLPath,
+					TagKey:        target.TagKey,
+					RepositoryKey: target.RepositoryKey,
+					Repository:    repositoryName(sourceRepository(yukConfig, targetSource(yukConfig, target))),
+				}
+				modified, err = yamlUpdater.UpdateHelmImage(filePath, image, targetTag, target.ExpectedValuePattern)
+			case !isPathMode(target.Mode):
+				err = fmt.Errorf("unsupported update mode: %s", target.Mode)
+			case format != yaml.FormatYAML && target.NestedYAMLPath != "":
+				err = fmt.Errorf("nestedYAMLPath is not supported in %s files", strings.ToUpper(format))
+			case target.NestedYAMLPath != "":
+				modified, err = yamlUpdater.UpdateNestedYAMLPath(filePath, target.YAMLPath, target.NestedYAMLPath, targetTag, imageTagOnly(target), target.ExpectedValuePattern)
+			default:
+				modified, err = yamlUpdater.UpdatePath(filePath, format, target.YAMLPath, targetTag, imageTagOnly(target), target.ExpectedValuePattern)
+			}
+			if err == nil && targetImages != nil && len(target.Values) > 0 {
+				var valuesModified bool
+				valuesModified, err = updateTargetValues(yamlUpdater, filePath, format, target, targetImages[i])
+				modified = modified || valuesModified
+			}
+			if target.Optional && missingTarget(err) {
+				outcome.skipTarget(ctx, yukConfig, target, branch, strings.ReplaceAll(err.Error(), repoPath+"/", ""))
+				continue
+			}
+			if err != nil {
+				yukmetrics.ErrorsTotal.With(prometheus.Labels{
+					"error_type": string(fileUpdateErrorType(err)),
+					"namespace":  yukConfig.Namespace,
+					"name":       yukConfig.Name,
+				}).Inc()
+				return nil, fmt.Errorf("failed to update file %s: %w", target.File, err)
+			}
+
+			// Files already holding the value are neither rewritten nor counted
+			if !modified {
+				logger.Info("File already up to date", "file", target.File, "tag", targetTag)
+				continue
+			}
+
+			if !changed[target.File] {
+				changed[target.File] = true
+				outcome.FilesChanged = append(outcome.FilesChanged, target.File)
+			}
+		}
+	}
+
+	// A dry run stops before committing
+	if action == ActionDryRun {
+		for _, file := range outcome.FilesChanged {
+			content, err := os.ReadFile(fmt.Sprintf("%s/%s", repoPath, file))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file %s: %w", file, err)
+			}
+			if fileDiff := diff.Unified(file, original[file], content); fileDiff != "" {
+				outcome.PendingChanges = append(outcome.PendingChanges, yukv1.PendingChange{File: file, Branch: branch, Diff: fileDiff})
+			}
+		}
+		return outcome, nil
 	}
 
 	// Commit and push changes
-	commitMessage := yukConfig.Spec.Git.CommitMessage
-	if commitMessage == "" {
-		commitMessage = fmt.Sprintf("Update container image to %s", newTag)
+	commitMessage := r.commitMessage(ctx, yukConfig, newTag, targetTags, outcome.FilesChanged)
+	if action == ActionRollback {
+		commitMessage = fmt.Sprintf("Revert container image from %s to %s", yukConfig.Status.CurrentTag, newTag)
 	}
 
 	// Commit
 	commitStart := time.Now()
-	err = gitClient.CommitAndPush(ctx, repoPath, commitMessage)
+	if action == ActionProposeBranch {
+		err = gitClient.CommitAndPushToBranch(ctx, repoPath, commitMessage, branchReviewBranch(yukConfig, branch, newTag, targetTags))
+	} else {
+		err = gitClient.CommitAndPush(ctx, repoPath, commitMessage)
+	}
 
 	// Record commit/push metrics
 	pushResult := yukmetrics.GitOperationSuccess
@@ -305,10 +1008,159 @@ func (r *YukConfigReconciler) updateFiles(ctx context.Context, yukConfig *yukv1.
 	}).Observe(time.Since(commitStart).Seconds())
 
 	if err != nil {
-		return fmt.Errorf("failed to commit and push changes: %w", err)
+		return nil, fmt.Errorf("failed to commit and push changes: %w", err)
+	}
+
+	headCommit, err := gitClient.GetLastCommitHash(ctx, repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if headCommit != baseCommit {
+		outcome.Commit = headCommit
+		if action == ActionProposeBranch {
+			outcome.ReviewBranches = []string{branchReviewBranch(yukConfig, branch, newTag, targetTags)}
+		}
 	}
 
-	return nil
+	// Open a pull request for the review branch, against the updated branch
+	if action == ActionProposeBranch && pullRequestEnabled(yukConfig) && outcome.Commit != "" {
+		head := branchReviewBranch(yukConfig, branch, newTag, targetTags)
+		prOpts, err := pullRequestOptions(yukConfig, newTag, head, branch)
+		if err != nil {
+			return nil, err
+		}
+
+		prStart := time.Now()
+		pr, err := gitClient.CreatePullRequest(ctx, prOpts)
+
+		// Record pull request metrics
+		prResult := yukmetrics.GitOperationSuccess
+		if err != nil {
+			prResult = yukmetrics.GitOperationError
+		}
+
+		yukmetrics.GitOperations.With(prometheus.Labels{
+			"operation":  string(yukmetrics.GitOperationPullRequest),
+			"repository": gitRepo,
+			"result":     string(prResult),
+		}).Inc()
+
+		yukmetrics.GitOperationDuration.With(prometheus.Labels{
+			"operation":  string(yukmetrics.GitOperationPullRequest),
+			"repository": gitRepo,
+		}).Observe(time.Since(prStart).Seconds())
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to open pull request: %w", err)
+		}
+
+		logger.Info("Opened pull request", "url", pr.URL, "branch", head)
+		outcome.PullRequestURL = pr.URL
+	}
+
+	return outcome, nil
+}
+
+// missingTarget reports whether an update failed because the file, path or named entry
+// of its target does not exist
+func missingTarget(err error) bool {
+	return stderrors.Is(err, fs.ErrNotExist) || stderrors.Is(err, yaml.ErrPathNotFound)
+}
+
+// skipTarget records an optional target that does not exist in the branch, with the error
+// describing what is missing relative to the clone
+func (o *updateOutcome) skipTarget(ctx context.Context, yukConfig *yukv1.YukConfig, target yukv1.UpdateTarget, branch, message string) {
+	log.FromContext(ctx).Info("Skipping optional target", "file", target.File, "yamlPath", target.YAMLPath, "name", target.Name, "reason", message)
+
+	o.SkippedTargets = append(o.SkippedTargets, yukv1.SkippedTarget{
+		File:     target.File,
+		YAMLPath: target.YAMLPath,
+		Name:     target.Name,
+		Branch:   branch,
+		Message:  message,
+	})
+	yukmetrics.TargetsSkipped.With(prometheus.Labels{
+		"namespace": yukConfig.Namespace,
+		"name":      yukConfig.Name,
+		"file_path": target.File,
+	}).Inc()
+}
+
+// isPathMode reports whether the update mode writes the value at the YAMLPath of a target
+func isPathMode(mode string) bool {
+	switch mode {
+	case "", yukv1.UpdateModeYAMLPath, yukv1.UpdateModeImageTag, yukv1.UpdateModeLiteral:
+		return true
+	default:
+		return false
+	}
+}
+
+// imageTagOnly reports whether only the tag (or digest) of the image reference at the
+// YAMLPath of a target is updated. ImageTagOnly maps to imageTag mode for compatibility,
+// while literal mode always writes the value verbatim.
+func imageTagOnly(target yukv1.UpdateTarget) bool {
+	switch target.Mode {
+	case yukv1.UpdateModeImageTag:
+		return true
+	case yukv1.UpdateModeLiteral:
+		return false
+	default:
+		return target.ImageTagOnly
+	}
+}
+
+// checkPinned sets the PossiblyPinned condition when the latest tag has not changed
+// for longer than the configured pinned threshold
+func (r *YukConfigReconciler) checkPinned(yukConfig *yukv1.YukConfig, now time.Time) {
+	if yukConfig.Spec.PinnedThreshold == nil || yukConfig.Status.LatestTagFirstSeen == nil {
+		return
+	}
+
+	threshold := yukConfig.Spec.PinnedThreshold.Duration
+	unchangedFor := now.Sub(yukConfig.Status.LatestTagFirstSeen.Time)
+	if unchangedFor >= threshold {
+		r.setCondition(yukConfig, "PossiblyPinned", metav1.ConditionTrue, "TagUnchanged",
+			fmt.Sprintf("Latest tag %s has not changed for %s (threshold %s); check that the tag filter still matches new releases",
+				yukConfig.Status.LatestTag, unchangedFor.Round(time.Second), threshold))
+		return
+	}
+
+	r.setCondition(yukConfig, "PossiblyPinned", metav1.ConditionFalse, "TagAdvancing",
+		fmt.Sprintf("Latest tag %s first seen %s ago", yukConfig.Status.LatestTag, unchangedFor.Round(time.Second)))
+}
+
+// checkUpToDate sets the UpToDate condition: True when the current tag is the latest tag
+// and no source or target lags behind its own latest tag, False otherwise, e.g. while an
+// update awaits approval or in dry-run mode
+func (r *YukConfigReconciler) checkUpToDate(yukConfig *yukv1.YukConfig) {
+	status := &yukConfig.Status
+	if status.LatestTag == "" {
+		return
+	}
+
+	if status.CurrentTag != status.LatestTag {
+		r.setCondition(yukConfig, "UpToDate", metav1.ConditionFalse, "TagBehind",
+			fmt.Sprintf("Current tag %s is behind the latest tag %s", status.CurrentTag, status.LatestTag))
+		return
+	}
+	for _, source := range status.Sources {
+		if source.CurrentTag != source.LatestTag {
+			r.setCondition(yukConfig, "UpToDate", metav1.ConditionFalse, "TagBehind",
+				fmt.Sprintf("Current tag %s of source %s is behind the latest tag %s", source.CurrentTag, source.Name, source.LatestTag))
+			return
+		}
+	}
+	for _, target := range status.Targets {
+		if target.CurrentTag != target.LatestTag || target.CurrentDigest != target.LatestDigest {
+			r.setCondition(yukConfig, "UpToDate", metav1.ConditionFalse, "TagBehind",
+				fmt.Sprintf("Target %s at %s is behind the latest tag %s", target.File, target.YAMLPath, target.LatestTag))
+			return
+		}
+	}
+
+	r.setCondition(yukConfig, "UpToDate", metav1.ConditionTrue, "TagCurrent",
+		fmt.Sprintf("Current tag %s is the latest tag", status.CurrentTag))
 }
 
 // setCondition sets a condition on the YukConfig status
@@ -337,6 +1189,14 @@ func (r *YukConfigReconciler) setCondition(yukConfig *yukv1.YukConfig, condition
 	yukConfig.Status.Conditions = append(yukConfig.Status.Conditions, condition)
 }
 
+// recordEvent emits an event for the YukConfig when a recorder is configured
+func (r *YukConfigReconciler) recordEvent(yukConfig *yukv1.YukConfig, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(yukConfig, eventType, reason, messageFmt, args...)
+}
+
 // updateStatus updates the YukConfig status
 func (r *YukConfigReconciler) updateStatus(ctx context.Context, yukConfig *yukv1.YukConfig) error {
 	return r.Status().Update(ctx, yukConfig)
@@ -346,11 +1206,7 @@ func (r *YukConfigReconciler) updateStatus(ctx context.Context, yukConfig *yukv1
 func (r *YukConfigReconciler) updateStatusMetrics(yukConfig *yukv1.YukConfig) {
 	namespace := yukConfig.Namespace
 	name := yukConfig.Name
-	repositoryName := ""
-
-	if yukConfig.Spec.Repository.ECR != nil {
-		repositoryName = yukConfig.Spec.Repository.ECR.RepositoryName
-	}
+	repositoryName := repositoryName(defaultSource(yukConfig).repository)
 
 	// Update version information
 	yukmetrics.CurrentVersion.With(prometheus.Labels{
@@ -374,6 +1230,50 @@ func (r *YukConfigReconciler) updateStatusMetrics(yukConfig *yukv1.YukConfig) {
 		}).Set(value)
 	}
 
+	// Update pinned detection status
+	if yukConfig.Spec.PinnedThreshold != nil {
+		pinned := float64(0)
+		for _, condition := range yukConfig.Status.Conditions {
+			if condition.Type == "PossiblyPinned" && condition.Status == metav1.ConditionTrue {
+				pinned = 1
+			}
+		}
+		yukmetrics.PossiblyPinned.With(prometheus.Labels{
+			"namespace":       namespace,
+			"name":            name,
+			"repository_name": repositoryName,
+		}).Set(pinned)
+	}
+
+	// Update out-of-date status
+	for _, condition := range yukConfig.Status.Conditions {
+		if condition.Type != "UpToDate" {
+			continue
+		}
+		outOfDate := float64(0)
+		if condition.Status == metav1.ConditionFalse {
+			outOfDate = 1
+		}
+		yukmetrics.TagOutOfDate.With(prometheus.Labels{
+			"namespace":       namespace,
+			"name":            name,
+			"repository_name": repositoryName,
+		}).Set(outOfDate)
+	}
+
+	// Update the lag behind the latest tag
+	lagLabels := prometheus.Labels{
+		"namespace":       namespace,
+		"name":            name,
+		"repository_name": repositoryName,
+	}
+	yukmetrics.UpdateLag.With(lagLabels).Set(updateLag(yukConfig, time.Now()).Seconds())
+	if behind, ok := versionsBehind(lagTag(yukConfig), yukConfig.Status.LatestTag); ok {
+		yukmetrics.VersionsBehind.With(lagLabels).Set(float64(behind))
+	} else {
+		yukmetrics.VersionsBehind.Delete(lagLabels)
+	}
+
 	// Update timestamps
 	if yukConfig.Status.LastChecked != nil {
 		yukmetrics.LastCheckTimestamp.With(prometheus.Labels{
@@ -392,38 +1292,46 @@ func (r *YukConfigReconciler) updateStatusMetrics(yukConfig *yukv1.YukConfig) {
 	}
 }
 
-// cleanupMetrics removes metrics for a deleted YukConfig
-func (r *YukConfigReconciler) cleanupMetrics(namespace, name string) {
-	// This is a simplified cleanup - in production you might want to keep a registry
-	// of active resources to properly clean up all metrics
-
-	// Remove current version metric
-	yukmetrics.CurrentVersion.DeletePartialMatch(prometheus.Labels{
-		"namespace": namespace,
-		"name":      name,
-	})
-
-	// Remove config status metrics
-	yukmetrics.ConfigStatus.DeletePartialMatch(prometheus.Labels{
-		"namespace": namespace,
-		"name":      name,
-	})
-
-	// Remove timestamp metrics
-	yukmetrics.LastCheckTimestamp.DeletePartialMatch(prometheus.Labels{
-		"namespace": namespace,
-		"name":      name,
-	})
+// recordChangedFiles counts each distinct file changed by a committed update once, and
+// records how many files the update changed
+func recordChangedFiles(yukConfig *yukv1.YukConfig, files []string) {
+	for _, file := range files {
+		yukmetrics.FilesUpdated.With(prometheus.Labels{
+			"namespace": yukConfig.Namespace,
+			"name":      yukConfig.Name,
+			"file_path": file,
+		}).Inc()
+	}
 
-	yukmetrics.LastUpdateTimestamp.DeletePartialMatch(prometheus.Labels{
-		"namespace": namespace,
-		"name":      name,
-	})
+	yukmetrics.LastChangedFiles.With(prometheus.Labels{
+		"namespace": yukConfig.Namespace,
+		"name":      yukConfig.Name,
+	}).Set(float64(len(files)))
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *YukConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("yuk-controller")
+	}
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
+	}
+
+	// Only the leader checks repositories and emits the per-config metrics, so replicas
+	// never push the same update or double-count
+	needLeaderElection := true
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&yukv1.YukConfig{}).
-		Complete(r)
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			NeedLeaderElection:      &needLeaderElection,
+		})
+
+	// Reconcile immediately on externally triggered events
+	if r.Trigger != nil {
+		builder = builder.WatchesRawSource(source.Channel(r.Trigger.events, &handler.EnqueueRequestForObject{}))
+	}
+
+	return builder.Complete(r)
 }
@@ -17,18 +17,37 @@ limitations under the License.
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/ecr"
+	"github.com/rebelopsio/yuk/pkg/git"
+	yukmetrics "github.com/rebelopsio/yuk/pkg/metrics"
+	"github.com/rebelopsio/yuk/pkg/yaml"
 )
 
 func TestYukConfigReconciler_Reconcile(t *testing.T) {
@@ -225,6 +244,18 @@ func TestYukConfigReconciler_Reconcile_WithCheckInterval(t *testing.T) {
 		},
 	}
 
+	throttledCounter := yukmetrics.ReconciliationTotal.With(prometheus.Labels{
+		"namespace": "default",
+		"name":      "test-config",
+		"result":    string(yukmetrics.ReconciliationThrottled),
+	})
+	successCounter := yukmetrics.ReconciliationTotal.With(prometheus.Labels{
+		"namespace": "default",
+		"name":      "test-config",
+		"result":    string(yukmetrics.ReconciliationSuccess),
+	})
+	throttledBefore, successBefore := testutil.ToFloat64(throttledCounter), testutil.ToFloat64(successCounter)
+
 	ctx := context.Background()
 	result, err := reconciler.Reconcile(ctx, req)
 
@@ -236,4 +267,1464 @@ func TestYukConfigReconciler_Reconcile_WithCheckInterval(t *testing.T) {
 	if result.RequeueAfter <= 0 {
 		t.Errorf("Expected requeue after remaining interval, got %v", result.RequeueAfter)
 	}
+
+	// The early return is counted as throttled rather than as a successful check
+	if throttled := testutil.ToFloat64(throttledCounter) - throttledBefore; throttled != 1 {
+		t.Errorf("Expected 1 throttled reconcile, got %v", throttled)
+	}
+	if succeeded := testutil.ToFloat64(successCounter) - successBefore; succeeded != 0 {
+		t.Errorf("Expected no successful reconcile, got %v", succeeded)
+	}
+}
+
+func TestYukConfigReconciler_checkPinned(t *testing.T) {
+	reconciler := &YukConfigReconciler{}
+	now := time.Now()
+
+	tests := []struct {
+		name           string
+		threshold      *metav1.Duration
+		firstSeen      time.Time
+		expectedStatus metav1.ConditionStatus
+		expectedReason string
+	}{
+		{
+			name:           "tag unchanged beyond threshold",
+			threshold:      &metav1.Duration{Duration: 24 * time.Hour},
+			firstSeen:      now.Add(-48 * time.Hour),
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: "TagUnchanged",
+		},
+		{
+			name:           "tag recently changed",
+			threshold:      &metav1.Duration{Duration: 24 * time.Hour},
+			firstSeen:      now.Add(-1 * time.Hour),
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: "TagAdvancing",
+		},
+		{
+			name:      "detection disabled",
+			threshold: nil,
+			firstSeen: now.Add(-48 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yukConfig := &yukv1.YukConfig{
+				Spec: yukv1.YukConfigSpec{
+					PinnedThreshold: tt.threshold,
+				},
+				Status: yukv1.YukConfigStatus{
+					LatestTag:          "v1.0.0",
+					LatestTagFirstSeen: &metav1.Time{Time: tt.firstSeen},
+				},
+			}
+
+			reconciler.checkPinned(yukConfig, now)
+
+			if tt.threshold == nil {
+				if len(yukConfig.Status.Conditions) != 0 {
+					t.Errorf("Expected no conditions when detection is disabled, got %d", len(yukConfig.Status.Conditions))
+				}
+				return
+			}
+
+			if len(yukConfig.Status.Conditions) != 1 {
+				t.Fatalf("Expected 1 condition, got %d", len(yukConfig.Status.Conditions))
+			}
+
+			condition := yukConfig.Status.Conditions[0]
+			if condition.Type != "PossiblyPinned" {
+				t.Errorf("Expected condition type 'PossiblyPinned', got %s", condition.Type)
+			}
+
+			if condition.Status != tt.expectedStatus {
+				t.Errorf("Expected condition status %s, got %s", tt.expectedStatus, condition.Status)
+			}
+
+			if condition.Reason != tt.expectedReason {
+				t.Errorf("Expected condition reason %s, got %s", tt.expectedReason, condition.Reason)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_resolveTargetTags(t *testing.T) {
+	reconciler := &YukConfigReconciler{}
+
+	yukConfig := &yukv1.YukConfig{
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: "ecr",
+				ECR: &yukv1.ECRConfig{
+					Region:         "us-east-1",
+					RepositoryName: "test-repo",
+					TagFilter:      "^app-",
+				},
+			},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{
+					File:     "deployment.yaml",
+					YAMLPath: "spec.template.spec.containers[0].image",
+				},
+				{
+					File:      "migration-job.yaml",
+					YAMLPath:  "spec.template.spec.containers[0].image",
+					TagFilter: "^migrate-",
+				},
+			},
+		},
+		Status: yukv1.YukConfigStatus{
+			Targets: []yukv1.TargetStatus{
+				{
+					File:       "migration-job.yaml",
+					YAMLPath:   "spec.template.spec.containers[0].image",
+					CurrentTag: "migrate-1.0.0",
+				},
+			},
+		},
+	}
+
+	filters := reconciler.tagFilters(yukConfig, "", "")
+	if len(filters) != 2 || filters[0] != "^app-" || filters[1] != "^migrate-" {
+		t.Fatalf("Expected filters [^app- ^migrate-], got %v", filters)
+	}
+
+	sourceTags := map[string]map[string]string{
+		"": {
+			"^app-":     "app-1.1.0",
+			"^migrate-": "migrate-1.2.0",
+		},
+	}
+
+	targetTags := reconciler.resolveTargetTags(yukConfig, sourceTags)
+	if len(targetTags) != 2 || targetTags[0] != "app-1.1.0" || targetTags[1] != "migrate-1.2.0" {
+		t.Fatalf("Expected target tags [app-1.1.0 migrate-1.2.0], got %v", targetTags)
+	}
+
+	if changed := reconciler.updateTargetStatuses(yukConfig, targetTags, make([]string, len(targetTags))); !changed {
+		t.Error("Expected target with a new tag to be reported as changed")
+	}
+
+	if len(yukConfig.Status.Targets) != 1 {
+		t.Fatalf("Expected 1 target status, got %d", len(yukConfig.Status.Targets))
+	}
+
+	targetStatus := yukConfig.Status.Targets[0]
+	if targetStatus.CurrentTag != "migrate-1.0.0" || targetStatus.LatestTag != "migrate-1.2.0" {
+		t.Errorf("Expected current migrate-1.0.0 and latest migrate-1.2.0, got %s and %s", targetStatus.CurrentTag, targetStatus.LatestTag)
+	}
+
+	// Once written, the target is no longer reported as changed
+	yukConfig.Status.Targets[0].CurrentTag = "migrate-1.2.0"
+	if changed := reconciler.updateTargetStatuses(yukConfig, targetTags, make([]string, len(targetTags))); changed {
+		t.Error("Expected up-to-date target not to be reported as changed")
+	}
+}
+
+// newUpstreamRepository creates a local Git repository with the given files committed
+// on main and returns its path
+func newUpstreamRepository(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"init", "--initial-branch=main"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-m", "initial"},
+		// Allow pushes to the checked out branch
+		{"config", "receive.denyCurrentBranch", "updateInstead"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v, output: %s", args, err, output)
+		}
+	}
+
+	return dir
+}
+
+func TestYukConfigReconciler_updateFiles_DryRun(t *testing.T) {
+	upstream := newUpstreamRepository(t, map[string]string{
+		"values.yaml": "image:\n    repository: my-app\n    tag: v1.0.0\n",
+	})
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Git: yukv1.GitConfig{Repository: upstream, Branch: "main"},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+			DryRun: true,
+		},
+	}
+
+	reconciler := &YukConfigReconciler{}
+	gitClient := git.NewClient(yukConfig.Spec.Git, git.WithBaseDir(t.TempDir()))
+
+	outcome, err := reconciler.updateFiles(context.Background(), yukConfig, staticGitClient(gitClient), yaml.NewUpdater(),
+		"v1.1.0", []string{"v1.1.0"}, nil, ActionDryRun)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if outcome.Commit != "" {
+		t.Errorf("Expected no commit for a dry run, got %s", outcome.Commit)
+	}
+	if len(outcome.PendingChanges) != 1 {
+		t.Fatalf("Expected 1 pending change, got %d", len(outcome.PendingChanges))
+	}
+
+	change := outcome.PendingChanges[0]
+	if change.File != "values.yaml" {
+		t.Errorf("Expected pending change for values.yaml, got %s", change.File)
+	}
+	if !strings.Contains(change.Diff, " image:\n     repository: my-app\n-    tag: v1.0.0\n+    tag: v1.1.0\n") {
+		t.Errorf("Expected diff to replace the tag, got:\n%s", change.Diff)
+	}
+
+	// The upstream repository is untouched
+	content, err := os.ReadFile(filepath.Join(upstream, "values.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read upstream file: %v", err)
+	}
+	if !strings.Contains(string(content), "tag: v1.0.0") {
+		t.Errorf("Expected upstream file to be unchanged, got:\n%s", content)
+	}
+}
+
+func TestYukConfigReconciler_updateFiles_JSON(t *testing.T) {
+	upstream := newUpstreamRepository(t, map[string]string{
+		"params.json": "{\n  \"image\": {\n    \"tag\": \"v1.0.0\"\n  }\n}\n",
+		"values.yaml": "image:\n    tag: v1.0.0\n",
+	})
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Git: yukv1.GitConfig{Repository: upstream, Branch: "main"},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "params.json", YAMLPath: "image.tag"},
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+	}
+
+	reconciler := &YukConfigReconciler{}
+	gitClient := git.NewClient(yukConfig.Spec.Git, git.WithBaseDir(t.TempDir()))
+
+	outcome, err := reconciler.updateFiles(context.Background(), yukConfig, staticGitClient(gitClient), yaml.NewUpdater(),
+		"v1.1.0", []string{"v1.1.0", "v1.1.0"}, nil, ActionDryRun)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if len(outcome.PendingChanges) != 2 {
+		t.Fatalf("Expected 2 pending changes, got %d", len(outcome.PendingChanges))
+	}
+	if diff := outcome.PendingChanges[0].Diff; !strings.Contains(diff, "-    \"tag\": \"v1.0.0\"\n+    \"tag\": \"v1.1.0\"\n") {
+		t.Errorf("Expected JSON diff to replace the tag, got:\n%s", diff)
+	}
+}
+
+func TestYukConfigReconciler_updateFiles_Unchanged(t *testing.T) {
+	upstream := newUpstreamRepository(t, map[string]string{
+		"current.yaml": "image:\n    tag: v1.1.0\n",
+		"values.yaml":  "image:\n    tag: v1.0.0\n",
+	})
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "unchanged-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Git: yukv1.GitConfig{Repository: upstream, Branch: "main", Email: "test@example.com", Name: "Test User"},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "current.yaml", YAMLPath: "image.tag"},
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+	}
+
+	reconciler := &YukConfigReconciler{}
+	gitClient := git.NewClient(yukConfig.Spec.Git, git.WithBaseDir(t.TempDir()))
+
+	outcome, err := reconciler.updateFiles(context.Background(), yukConfig, staticGitClient(gitClient), yaml.NewUpdater(),
+		"v1.1.0", []string{"v1.1.0", "v1.1.0"}, nil, ActionPush)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// Only the file that actually changed is reported and counted
+	if len(outcome.FilesChanged) != 1 || outcome.FilesChanged[0] != "values.yaml" {
+		t.Errorf("Expected only values.yaml to change, got %v", outcome.FilesChanged)
+	}
+
+	tests := []struct {
+		file     string
+		expected float64
+	}{
+		{file: "current.yaml", expected: 0},
+		{file: "values.yaml", expected: 1},
+	}
+	for _, tt := range tests {
+		counter := yukmetrics.FilesUpdated.With(prometheus.Labels{
+			"namespace": yukConfig.Namespace,
+			"name":      yukConfig.Name,
+			"file_path": tt.file,
+		})
+		if got := testutil.ToFloat64(counter); got != tt.expected {
+			t.Errorf("Expected %v updates of %s, got %v", tt.expected, tt.file, got)
+		}
+	}
+}
+
+// staticGitClient returns a gitClientFactory returning gitClient for every branch
+func staticGitClient(gitClient GitOperator) gitClientFactory {
+	return func(string) GitOperator { return gitClient }
+}
+
+func TestYukConfigReconciler_updateFiles_Branches(t *testing.T) {
+	upstream := newUpstreamRepository(t, map[string]string{
+		"values.yaml": "image:\n    tag: v1.0.0\n",
+	})
+	for _, branch := range []string{"staging", "broken"} {
+		cmd := exec.Command("git", "branch", branch)
+		cmd.Dir = upstream
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git branch %s failed: %v, output: %s", branch, err, output)
+		}
+	}
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "branches-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Git: yukv1.GitConfig{Repository: upstream, Branch: "main", Email: "test@example.com", Name: "Test User"},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "missing.yaml", YAMLPath: "image.tag", Branch: "broken"},
+				{File: "values.yaml", YAMLPath: "image.tag", Branch: "staging"},
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+	}
+
+	reconciler := &YukConfigReconciler{}
+	baseDir := t.TempDir()
+	var cloned []string
+	newGitClient := func(branch string) GitOperator {
+		cloned = append(cloned, branch)
+		config := yukConfig.Spec.Git
+		if branch != "" {
+			config.Branch = branch
+		}
+		return git.NewClient(config, git.WithBaseDir(baseDir))
+	}
+
+	_, err := reconciler.updateFiles(context.Background(), yukConfig, newGitClient, yaml.NewUpdater(),
+		"v1.1.0", []string{"v1.1.0", "v1.1.0", "v1.1.0"}, nil, ActionPush)
+	if err == nil || !strings.Contains(err.Error(), "branch broken:") {
+		t.Fatalf("Expected an error for branch broken, got: %v", err)
+	}
+	if strings.Contains(err.Error(), "branch main") || strings.Contains(err.Error(), "branch staging") {
+		t.Errorf("Expected only branch broken to fail, got: %v", err)
+	}
+
+	// The configured branch is updated first, then the other branches in target order
+	if expected := []string{"", "broken", "staging"}; !reflect.DeepEqual(cloned, expected) {
+		t.Errorf("Expected branches %q to be cloned, got %q", expected, cloned)
+	}
+
+	// The failure on branch broken does not prevent pushing the other branches
+	for _, branch := range []string{"main", "staging"} {
+		cmd := exec.Command("git", "show", branch+":values.yaml")
+		cmd.Dir = upstream
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git show failed: %v, output: %s", err, output)
+		}
+		if !strings.Contains(string(output), "tag: v1.1.0") {
+			t.Errorf("Expected values.yaml to be updated on %s, got:\n%s", branch, output)
+		}
+	}
+}
+
+func TestYukConfigReconciler_updateFiles_ChangedFilesMetrics(t *testing.T) {
+	tests := []struct {
+		name            string
+		action          UpdateAction
+		expectedUpdates float64
+		expectedChanged float64
+	}{
+		{
+			name:            "two targets in one file count once",
+			action:          ActionPush,
+			expectedUpdates: 1,
+			expectedChanged: 1,
+		},
+		{
+			name:   "dry run counts nothing",
+			action: ActionDryRun,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := newUpstreamRepository(t, map[string]string{
+				"values.yaml": "image:\n    tag: v1.0.0\nsidecar:\n    tag: v1.0.0\n",
+			})
+
+			yukConfig := &yukv1.YukConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("changed-files-%d", i), Namespace: "default"},
+				Spec: yukv1.YukConfigSpec{
+					Git: yukv1.GitConfig{Repository: upstream, Branch: "main", Email: "test@example.com", Name: "Test User"},
+					UpdateTargets: []yukv1.UpdateTarget{
+						{File: "values.yaml", YAMLPath: "image.tag"},
+						{File: "values.yaml", YAMLPath: "sidecar.tag"},
+					},
+				},
+			}
+
+			reconciler := &YukConfigReconciler{}
+			gitClient := git.NewClient(yukConfig.Spec.Git, git.WithBaseDir(t.TempDir()))
+
+			outcome, err := reconciler.updateFiles(context.Background(), yukConfig, staticGitClient(gitClient), yaml.NewUpdater(),
+				"v1.1.0", []string{"v1.1.0", "v1.1.0"}, nil, tt.action)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(outcome.FilesChanged) != 1 {
+				t.Errorf("Expected 1 changed file, got %v", outcome.FilesChanged)
+			}
+
+			updates := yukmetrics.FilesUpdated.With(prometheus.Labels{
+				"namespace": yukConfig.Namespace,
+				"name":      yukConfig.Name,
+				"file_path": "values.yaml",
+			})
+			if got := testutil.ToFloat64(updates); got != tt.expectedUpdates {
+				t.Errorf("Expected %v updates of values.yaml, got %v", tt.expectedUpdates, got)
+			}
+
+			changed := yukmetrics.LastChangedFiles.With(prometheus.Labels{
+				"namespace": yukConfig.Namespace,
+				"name":      yukConfig.Name,
+			})
+			if got := testutil.ToFloat64(changed); got != tt.expectedChanged {
+				t.Errorf("Expected %v changed files, got %v", tt.expectedChanged, got)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_updateFiles_Modes(t *testing.T) {
+	upstream := newUpstreamRepository(t, map[string]string{
+		"values.yaml": "image: registry/my-app:v1.0.0\nsidecar: registry/proxy:v1.0.0\nchecksum: registry/my-app:v1.0.0\n",
+		"Chart.yaml":  "version: 1.0.0\n",
+	})
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Git: yukv1.GitConfig{Repository: upstream, Branch: "main"},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", Mode: yukv1.UpdateModeImageTag, YAMLPath: "image"},
+				{File: "values.yaml", YAMLPath: "sidecar", ImageTagOnly: true},
+				{File: "values.yaml", Mode: yukv1.UpdateModeLiteral, YAMLPath: "checksum"},
+				{File: "Chart.yaml", Mode: yukv1.UpdateModeLiteral, YAMLPath: "version"},
+			},
+		},
+	}
+
+	reconciler := &YukConfigReconciler{}
+	gitClient := git.NewClient(yukConfig.Spec.Git, git.WithBaseDir(t.TempDir()))
+
+	outcome, err := reconciler.updateFiles(context.Background(), yukConfig, staticGitClient(gitClient), yaml.NewUpdater(),
+		"v1.1.0", []string{"v1.1.0", "v1.1.0", "v1.1.0", "1.1.0"}, nil, ActionDryRun)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if len(outcome.PendingChanges) != 2 {
+		t.Fatalf("Expected 2 pending changes, got %d", len(outcome.PendingChanges))
+	}
+
+	diffs := map[string]string{}
+	for _, change := range outcome.PendingChanges {
+		diffs[change.File] = change.Diff
+	}
+
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		// imageTag mode replaces only the tag of the image reference
+		{file: "values.yaml", expected: "+image: registry/my-app:v1.1.0\n"},
+		// ImageTagOnly maps to imageTag mode
+		{file: "values.yaml", expected: "+sidecar: registry/proxy:v1.1.0\n"},
+		// literal mode writes the value verbatim, even over an image reference
+		{file: "values.yaml", expected: "+checksum: v1.1.0\n"},
+		{file: "Chart.yaml", expected: "+version: 1.1.0\n"},
+	}
+
+	for _, tt := range tests {
+		if !strings.Contains(diffs[tt.file], tt.expected) {
+			t.Errorf("Expected diff of %s to contain %q, got:\n%s", tt.file, tt.expected, diffs[tt.file])
+		}
+	}
+}
+
+func TestImageTagOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   yukv1.UpdateTarget
+		expected bool
+	}{
+		{name: "default mode", target: yukv1.UpdateTarget{}, expected: false},
+		{name: "default mode with imageTagOnly", target: yukv1.UpdateTarget{ImageTagOnly: true}, expected: true},
+		{name: "yamlPath mode with imageTagOnly", target: yukv1.UpdateTarget{Mode: yukv1.UpdateModeYAMLPath, ImageTagOnly: true}, expected: true},
+		{name: "imageTag mode", target: yukv1.UpdateTarget{Mode: yukv1.UpdateModeImageTag}, expected: true},
+		{name: "literal mode", target: yukv1.UpdateTarget{Mode: yukv1.UpdateModeLiteral}, expected: false},
+		{name: "literal mode with imageTagOnly", target: yukv1.UpdateTarget{Mode: yukv1.UpdateModeLiteral, ImageTagOnly: true}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := imageTagOnly(tt.target); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_Events(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"name": "project/app",
+			"tags": []string{"v1.0.0", "v1.1.0"},
+		})
+	}))
+	defer registry.Close()
+
+	upstream := newUpstreamRepository(t, map[string]string{
+		"values.yaml": "image:\n    repository: my-app\n    tag: v1.0.0\n",
+	})
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					Insecure:       true,
+				},
+			},
+			Git: yukv1.GitConfig{
+				Repository: upstream,
+				Branch:     "main",
+				Email:      "test@example.com",
+				Name:       "Test User",
+			},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0", LatestTag: "v1.0.0"},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:       scheme,
+		CloneBaseDir: t.TempDir(),
+		Recorder:     recorder,
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+
+	expected := []string{
+		"Normal UpdateAvailable New version v1.1.0 detected (current: v1.0.0)",
+		"Normal Synchronized Updated values.yaml to v1.1.0 and pushed commit ",
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %v", len(expected), len(events), events)
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(events[i], prefix) {
+			t.Errorf("Expected event %q, got %q", prefix, events[i])
+		}
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_LastCommitSHA(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "project/app", "tags": []string{"v1.1.0"}})
+	}))
+	defer registry.Close()
+
+	upstream := newUpstreamRepository(t, map[string]string{
+		"values.yaml": "image:\n    repository: my-app\n    tag: v1.0.0\n",
+	})
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					Insecure:       true,
+				},
+			},
+			Git: yukv1.GitConfig{
+				Repository: upstream,
+				Branch:     "main",
+				Email:      "test@example.com",
+				Name:       "Test User",
+			},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:       scheme,
+		CloneBaseDir: t.TempDir(),
+		Recorder:     record.NewFakeRecorder(10),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	out, err := exec.Command("git", "-C", upstream, "rev-parse", "main").Output()
+	if err != nil {
+		t.Fatalf("Failed to read upstream head: %v", err)
+	}
+	pushed := strings.TrimSpace(string(out))
+
+	updated := &yukv1.YukConfig{}
+	if err := reconciler.Get(context.Background(), req.NamespacedName, updated); err != nil {
+		t.Fatalf("Failed to get YukConfig: %v", err)
+	}
+	if updated.Status.LastCommitSHA != pushed {
+		t.Errorf("Expected last commit SHA %s, got %s", pushed, updated.Status.LastCommitSHA)
+	}
+	if updated.Status.LastChangedFileCount != 1 {
+		t.Errorf("Expected 1 changed file, got %d", updated.Status.LastChangedFileCount)
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_Concurrent(t *testing.T) {
+	const configs = 4
+
+	// The registry holds every tag list request until all configs are being checked,
+	// so the test only passes when the reconciles run in parallel
+	var mu sync.Mutex
+	pending := map[string]bool{}
+	allStarted := make(chan struct{})
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		pending[r.URL.Path] = true
+		if len(pending) == configs {
+			close(allStarted)
+		}
+		mu.Unlock()
+
+		select {
+		case <-allStarted:
+		case <-time.After(10 * time.Second):
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"tags": []string{"v1.0.0", "v1.1.0"}})
+	}))
+	defer registry.Close()
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	builder := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&yukv1.YukConfig{})
+	for i := 0; i < configs; i++ {
+		upstream := newUpstreamRepository(t, map[string]string{
+			"values.yaml": "image:\n    repository: my-app\n    tag: v1.0.0\n",
+		})
+		builder = builder.WithObjects(&yukv1.YukConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("config-%d", i), Namespace: "default"},
+			Spec: yukv1.YukConfigSpec{
+				Repository: yukv1.RepositoryConfig{
+					Type: RepositoryTypeOCI,
+					OCI: &yukv1.OCIConfig{
+						Registry:       strings.TrimPrefix(registry.URL, "http://"),
+						RepositoryName: fmt.Sprintf("project/app-%d", i),
+						Insecure:       true,
+					},
+				},
+				Git: yukv1.GitConfig{
+					Repository: upstream,
+					Branch:     "main",
+					Email:      "test@example.com",
+					Name:       "Test User",
+				},
+				UpdateTargets: []yukv1.UpdateTarget{
+					{File: "values.yaml", YAMLPath: "image.tag"},
+				},
+			},
+			Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0", LatestTag: "v1.0.0"},
+		})
+	}
+
+	// One reconciler shares its client, recorder, summary writer and metrics across workers
+	var buf bytes.Buffer
+	reconciler := &YukConfigReconciler{
+		Client:       builder.Build(),
+		Scheme:       scheme,
+		CloneBaseDir: t.TempDir(),
+		Summary:      NewSummaryWriter(&buf),
+		Recorder:     record.NewFakeRecorder(10 * configs),
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, configs)
+	for i := 0; i < configs; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: "default"}}
+			if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+				errs <- fmt.Errorf("%s: %w", name, err)
+			}
+		}(fmt.Sprintf("config-%d", i))
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Reconcile failed: %v", err)
+	}
+
+	for i := 0; i < configs; i++ {
+		updated := &yukv1.YukConfig{}
+		key := types.NamespacedName{Name: fmt.Sprintf("config-%d", i), Namespace: "default"}
+		if err := reconciler.Get(context.Background(), key, updated); err != nil {
+			t.Fatalf("Failed to get YukConfig: %v", err)
+		}
+		if updated.Status.CurrentTag != "v1.1.0" {
+			t.Errorf("Expected %s to be updated to v1.1.0, got %s", key.Name, updated.Status.CurrentTag)
+		}
+	}
+
+	if lines := strings.Count(buf.String(), "\n"); lines != configs {
+		t.Errorf("Expected %d summary lines, got %d", configs, lines)
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_Paused(t *testing.T) {
+	requests := 0
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "project/app", "tags": []string{"v1.1.0"}})
+	}))
+	defer registry.Close()
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-config",
+			Namespace:   "default",
+			Generation:  2,
+			Annotations: map[string]string{yukv1.PausedAnnotation: "true"},
+		},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					Insecure:       true,
+				},
+			},
+			Git: yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0", ObservedGeneration: 1},
+	}
+
+	var buf bytes.Buffer
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+		WithStatusSubresource(&yukv1.YukConfig{}).Build()
+	reconciler := &YukConfigReconciler{
+		Client:  fakeClient,
+		Scheme:  scheme,
+		Summary: NewSummaryWriter(&buf),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	result, err := reconciler.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if result.RequeueAfter != 0 {
+		t.Errorf("Expected no requeue, got requeue after %v", result.RequeueAfter)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no repository check, got %d registry requests", requests)
+	}
+	if !strings.Contains(buf.String(), `"result":"skipped"`) {
+		t.Errorf("Expected a skipped reconcile summary, got %s", buf.String())
+	}
+
+	var updated yukv1.YukConfig
+	if err := fakeClient.Get(context.Background(), req.NamespacedName, &updated); err != nil {
+		t.Fatalf("Failed to get YukConfig: %v", err)
+	}
+	if updated.Status.LastChecked != nil || updated.Status.ObservedGeneration != 1 {
+		t.Errorf("Expected status to be untouched, got %+v", updated.Status)
+	}
+}
+
+func TestYukConfigReconciler_checkUpToDate(t *testing.T) {
+	reconciler := &YukConfigReconciler{}
+
+	tests := []struct {
+		name           string
+		status         yukv1.YukConfigStatus
+		expectedStatus metav1.ConditionStatus
+		expectedReason string
+	}{
+		{
+			name:           "current tag is latest",
+			status:         yukv1.YukConfigStatus{CurrentTag: "v1.1.0", LatestTag: "v1.1.0"},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: "TagCurrent",
+		},
+		{
+			name:           "current tag behind",
+			status:         yukv1.YukConfigStatus{CurrentTag: "v1.0.0", LatestTag: "v1.1.0"},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: "TagBehind",
+		},
+		{
+			name: "source behind",
+			status: yukv1.YukConfigStatus{
+				CurrentTag: "v1.1.0",
+				LatestTag:  "v1.1.0",
+				Sources:    []yukv1.SourceStatus{{Name: "sidecar", CurrentTag: "v2.0.0", LatestTag: "v2.1.0"}},
+			},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: "TagBehind",
+		},
+		{
+			name: "target digest behind",
+			status: yukv1.YukConfigStatus{
+				CurrentTag: "v1.1.0",
+				LatestTag:  "v1.1.0",
+				Targets: []yukv1.TargetStatus{{
+					File: "values.yaml", YAMLPath: "image.digest",
+					CurrentTag: "v1.1.0", LatestTag: "v1.1.0",
+					CurrentDigest: "sha256:abc", LatestDigest: "sha256:def",
+				}},
+			},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: "TagBehind",
+		},
+		{
+			name:   "no latest tag yet",
+			status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yukConfig := &yukv1.YukConfig{Status: tt.status}
+
+			reconciler.checkUpToDate(yukConfig)
+
+			if tt.expectedReason == "" {
+				if len(yukConfig.Status.Conditions) != 0 {
+					t.Errorf("Expected no conditions before the first check, got %d", len(yukConfig.Status.Conditions))
+				}
+				return
+			}
+
+			if len(yukConfig.Status.Conditions) != 1 {
+				t.Fatalf("Expected 1 condition, got %d", len(yukConfig.Status.Conditions))
+			}
+			condition := yukConfig.Status.Conditions[0]
+			if condition.Type != "UpToDate" {
+				t.Errorf("Expected condition type 'UpToDate', got %s", condition.Type)
+			}
+			if condition.Status != tt.expectedStatus {
+				t.Errorf("Expected condition status %s, got %s", tt.expectedStatus, condition.Status)
+			}
+			if condition.Reason != tt.expectedReason {
+				t.Errorf("Expected condition reason %s, got %s", tt.expectedReason, condition.Reason)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_UpToDate(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "project/app", "tags": []string{"v1.0.0", "v1.1.0"}})
+	}))
+	defer registry.Close()
+
+	upstream := newUpstreamRepository(t, map[string]string{
+		"values.yaml": "image:\n    repository: my-app\n    tag: v1.0.0\n",
+	})
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeOCI,
+				OCI: &yukv1.OCIConfig{
+					Registry:       strings.TrimPrefix(registry.URL, "http://"),
+					RepositoryName: "project/app",
+					SortStrategy:   "semver",
+					Insecure:       true,
+				},
+			},
+			Git: yukv1.GitConfig{
+				Repository: upstream,
+				Branch:     "main",
+				Email:      "test@example.com",
+				Name:       "Test User",
+			},
+			UpdateStrategy: yukv1.UpdateStrategyApproval,
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+	}
+
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:       scheme,
+		CloneBaseDir: t.TempDir(),
+		Recorder:     record.NewFakeRecorder(10),
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	reconcile := func() *yukv1.YukConfig {
+		t.Helper()
+		if _, err := reconciler.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		updated := &yukv1.YukConfig{}
+		if err := reconciler.Get(ctx, req.NamespacedName, updated); err != nil {
+			t.Fatalf("Failed to get YukConfig: %v", err)
+		}
+		return updated
+	}
+	upToDate := func(yukConfig *yukv1.YukConfig) metav1.Condition {
+		t.Helper()
+		for _, condition := range yukConfig.Status.Conditions {
+			if condition.Type == "UpToDate" {
+				return condition
+			}
+		}
+		t.Fatalf("Expected an UpToDate condition, got %+v", yukConfig.Status.Conditions)
+		return metav1.Condition{}
+	}
+
+	// The new tag is detected but awaits approval
+	updated := reconcile()
+	if condition := upToDate(updated); condition.Status != metav1.ConditionFalse || condition.Reason != "TagBehind" {
+		t.Errorf("Expected UpToDate False with reason TagBehind after detection, got %s %s", condition.Status, condition.Reason)
+	}
+
+	// Approving the tag updates the target and brings the config up to date
+	updated.Annotations = map[string]string{
+		yukv1.ApprovedTagAnnotation:      "v1.1.0",
+		yukv1.ReconcileRequestAnnotation: "1",
+	}
+	if err := reconciler.Update(ctx, updated); err != nil {
+		t.Fatalf("Failed to annotate YukConfig: %v", err)
+	}
+	updated = reconcile()
+	if updated.Status.CurrentTag != "v1.1.0" {
+		t.Fatalf("Expected current tag v1.1.0, got %s", updated.Status.CurrentTag)
+	}
+	if condition := upToDate(updated); condition.Status != metav1.ConditionTrue || condition.Reason != "TagCurrent" {
+		t.Errorf("Expected UpToDate True with reason TagCurrent after the update, got %s %s", condition.Status, condition.Reason)
+	}
+}
+
+// fakeTagResolver resolves ECR tags from fixed values, recording the looked up regions
+type fakeTagResolver struct {
+	latestTags map[string]string
+	digests    map[string]string
+	regions    []string
+}
+
+func (f *fakeTagResolver) GetLatestTags(ctx context.Context, repositoryName string, tagFilters []string) (map[string]string, error) {
+	latestTags := make(map[string]string, len(tagFilters))
+	for _, tagFilter := range tagFilters {
+		tag, ok := f.latestTags[repositoryName+":"+tagFilter]
+		if !ok {
+			return nil, fmt.Errorf("no tags found matching filter in repository %s", repositoryName)
+		}
+		latestTags[tagFilter] = tag
+	}
+	return latestTags, nil
+}
+
+func (f *fakeTagResolver) GetImageDigest(ctx context.Context, repositoryName, tag string) (string, error) {
+	digest, ok := f.digests[repositoryName+":"+tag]
+	if !ok {
+		return "", fmt.Errorf("tag %s not found in repository %s", tag, repositoryName)
+	}
+	return digest, nil
+}
+
+func TestYukConfigReconciler_Reconcile_ECRUpdate(t *testing.T) {
+	upstream := newUpstreamRepository(t, map[string]string{
+		"values.yaml": "image:\n    repository: 123456789012.dkr.ecr.us-west-2.amazonaws.com/my-app\n    tag: v1.0.0\n    digest: sha256:old\n",
+	})
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeECR,
+				ECR: &yukv1.ECRConfig{
+					Region:         "us-west-2",
+					RepositoryName: "my-app",
+					TagFilter:      `^v`,
+				},
+			},
+			Git: yukv1.GitConfig{
+				Repository: upstream,
+				Branch:     "main",
+				Email:      "test@example.com",
+				Name:       "Test User",
+			},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+				{File: "values.yaml", YAMLPath: "image.digest", ByDigest: true},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+	}
+
+	resolver := &fakeTagResolver{
+		latestTags: map[string]string{"my-app:^v": "v1.1.0"},
+		digests:    map[string]string{"my-app:v1.1.0": "sha256:new"},
+	}
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:       scheme,
+		CloneBaseDir: t.TempDir(),
+		Recorder:     record.NewFakeRecorder(10),
+		NewECRClient: func(region string, opts ...ecr.Option) TagResolver {
+			resolver.regions = append(resolver.regions, region)
+			return resolver
+		},
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	// The latest tag and its digest are pushed to the Git repository
+	out, err := exec.Command("git", "-C", upstream, "show", "main:values.yaml").Output()
+	if err != nil {
+		t.Fatalf("Failed to read upstream file: %v", err)
+	}
+	if !strings.Contains(string(out), "tag: v1.1.0") || !strings.Contains(string(out), "digest: sha256:new") {
+		t.Errorf("Expected v1.1.0 and its digest upstream, got:\n%s", out)
+	}
+	for _, region := range resolver.regions {
+		if region != "us-west-2" {
+			t.Errorf("Expected lookups in us-west-2, got %s", region)
+		}
+	}
+
+	updated := &yukv1.YukConfig{}
+	if err := reconciler.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Failed to get YukConfig: %v", err)
+	}
+	if updated.Status.CurrentTag != "v1.1.0" || updated.Status.PreviousTag != "v1.0.0" {
+		t.Errorf("Expected current v1.1.0 and previous v1.0.0, got %s and %s", updated.Status.CurrentTag, updated.Status.PreviousTag)
+	}
+	if updated.Status.LastCommitSHA == "" {
+		t.Error("Expected the pushed commit to be recorded")
+	}
+	if history := updated.Status.History; len(history) != 1 || history[0].OldTag != "v1.0.0" || history[0].NewTag != "v1.1.0" ||
+		history[0].CommitSHA != updated.Status.LastCommitSHA {
+		t.Errorf("Expected the update to v1.1.0 in the history, got %+v", history)
+	}
+	for _, condition := range updated.Status.Conditions {
+		if condition.Type == "Ready" && (condition.Status != metav1.ConditionTrue || condition.Reason != "Synchronized") {
+			t.Errorf("Expected Ready True with reason Synchronized, got %s %s", condition.Status, condition.Reason)
+		}
+	}
+}
+
+// fakeGitOperator clones a local bare repository and pushes to it with plain git,
+// counting the calls of each operation
+type fakeGitOperator struct {
+	t      *testing.T
+	remote string
+
+	clones, pushes, cleanups int
+	messages                 []string
+}
+
+// newFakeGitOperator returns a fake Git client of a bare repository holding the files
+func newFakeGitOperator(t *testing.T, files map[string]string) *fakeGitOperator {
+	t.Helper()
+
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	if output, err := exec.Command("git", "clone", "--bare", newUpstreamRepository(t, files), remote).CombinedOutput(); err != nil {
+		t.Fatalf("Failed to create bare repository: %v, output: %s", err, output)
+	}
+	return &fakeGitOperator{t: t, remote: remote}
+}
+
+func (f *fakeGitOperator) git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %v failed: %w, output: %s", args, err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (f *fakeGitOperator) Clone(ctx context.Context) (string, error) {
+	f.clones++
+	dir := filepath.Join(f.t.TempDir(), "clone")
+	if _, err := f.git("", "clone", "--branch", "main", f.remote, dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func (f *fakeGitOperator) CommitAndPush(ctx context.Context, repoPath, commitMessage string) error {
+	return f.CommitAndPushToBranch(ctx, repoPath, commitMessage, "main")
+}
+
+func (f *fakeGitOperator) CommitAndPushToBranch(ctx context.Context, repoPath, commitMessage, branch string) error {
+	f.pushes++
+	f.messages = append(f.messages, commitMessage)
+	if _, err := f.git(repoPath, "commit", "-am", commitMessage); err != nil {
+		return err
+	}
+	_, err := f.git(repoPath, "push", "origin", "HEAD:"+branch)
+	return err
+}
+
+func (f *fakeGitOperator) GetLastCommitHash(ctx context.Context, repoPath string) (string, error) {
+	return f.git(repoPath, "rev-parse", "HEAD")
+}
+
+func (f *fakeGitOperator) CreatePullRequest(ctx context.Context, opts git.PullRequestOptions) (*git.PullRequest, error) {
+	return nil, errors.New("pull requests are not supported by the fake")
+}
+
+func (f *fakeGitOperator) DeleteBranches(ctx context.Context, branches []string) ([]string, error) {
+	var deleted []string
+	for _, branch := range branches {
+		if _, err := f.git(f.remote, "branch", "-D", branch); err == nil {
+			deleted = append(deleted, branch)
+		}
+	}
+	return deleted, nil
+}
+
+func (f *fakeGitOperator) Cleanup(repoPath string) {
+	f.cleanups++
+	_ = os.RemoveAll(repoPath)
+}
+
+// file returns the content of a file on the main branch of the remote
+func (f *fakeGitOperator) file(name string) string {
+	f.t.Helper()
+	content, err := f.git(f.remote, "show", "main:"+name)
+	if err != nil {
+		f.t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	return content
+}
+
+func TestYukConfigReconciler_Reconcile_OptionalTargets(t *testing.T) {
+	tests := []struct {
+		name            string
+		targets         []yukv1.UpdateTarget
+		expectedSkipped []string
+		expectedError   string
+	}{
+		{
+			name: "optional targets missing",
+			targets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+				{File: "overlays/dev/values.yaml", YAMLPath: "image.tag", Optional: true},
+				{File: "values.yaml", YAMLPath: "worker.image.tag", Optional: true},
+				{File: "values.yaml", YAMLPath: "image.digest", Optional: true},
+			},
+			expectedSkipped: []string{
+				"overlays/dev/values.yaml image.tag",
+				"values.yaml worker.image.tag",
+				"values.yaml image.digest",
+			},
+		},
+		{
+			name: "required target missing",
+			targets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+				{File: "overlays/dev/values.yaml", YAMLPath: "image.tag"},
+			},
+			expectedError: "failed to update file overlays/dev/values.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = clientgoscheme.AddToScheme(scheme)
+			_ = yukv1.AddToScheme(scheme)
+
+			name := "optional-" + strings.ReplaceAll(tt.name, " ", "-")
+			yukConfig := &yukv1.YukConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: yukv1.YukConfigSpec{
+					Repository: yukv1.RepositoryConfig{
+						Type: RepositoryTypeECR,
+						ECR:  &yukv1.ECRConfig{Region: "us-west-2", RepositoryName: "my-app"},
+					},
+					Git:           yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+					UpdateTargets: tt.targets,
+				},
+				Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+			}
+
+			gitOperator := newFakeGitOperator(t, map[string]string{"values.yaml": "image:\n    tag: v1.0.0\n"})
+			reconciler := &YukConfigReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+					WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(10),
+				NewECRClient: func(region string, opts ...ecr.Option) TagResolver {
+					return &fakeTagResolver{latestTags: map[string]string{"my-app:": "v1.1.0"}}
+				},
+				NewGitClient: func(config yukv1.GitConfig, opts ...git.Option) GitOperator {
+					return gitOperator
+				},
+			}
+
+			skipped := yukmetrics.TargetsSkipped.With(prometheus.Labels{"namespace": "default", "name": name, "file_path": "values.yaml"})
+			before := testutil.ToFloat64(skipped)
+
+			ctx := context.Background()
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: "default"}}
+			if _, err := reconciler.Reconcile(ctx, req); err != nil {
+				t.Fatalf("Reconcile failed: %v", err)
+			}
+
+			updated := &yukv1.YukConfig{}
+			if err := reconciler.Get(ctx, req.NamespacedName, updated); err != nil {
+				t.Fatalf("Failed to get YukConfig: %v", err)
+			}
+
+			if tt.expectedError != "" {
+				// A missing required target fails the whole update
+				if gitOperator.pushes != 0 {
+					t.Errorf("Expected no push, got %d", gitOperator.pushes)
+				}
+				for _, condition := range updated.Status.Conditions {
+					if condition.Type == "Ready" && (condition.Status != metav1.ConditionFalse || !strings.Contains(condition.Message, tt.expectedError)) {
+						t.Errorf("Expected Ready False with %q, got %s %s", tt.expectedError, condition.Status, condition.Message)
+					}
+				}
+				return
+			}
+
+			// The existing target is updated and the missing optional ones are skipped
+			if content := gitOperator.file("values.yaml"); content != "image:\n    tag: v1.1.0" {
+				t.Errorf("Expected only the tag to be updated, got:\n%s", content)
+			}
+			if updated.Status.CurrentTag != "v1.1.0" {
+				t.Errorf("Expected current tag v1.1.0, got %s", updated.Status.CurrentTag)
+			}
+
+			var skippedTargets []string
+			for _, target := range updated.Status.SkippedTargets {
+				skippedTargets = append(skippedTargets, target.File+" "+target.YAMLPath)
+				if strings.Contains(target.Message, gitOperator.remote) || strings.Contains(target.Message, os.TempDir()) {
+					t.Errorf("Expected the message to name files relative to the repository, got %q", target.Message)
+				}
+			}
+			if !reflect.DeepEqual(skippedTargets, tt.expectedSkipped) {
+				t.Errorf("Expected skipped targets %v, got %v", tt.expectedSkipped, skippedTargets)
+			}
+			if got := testutil.ToFloat64(skipped) - before; got != 2 {
+				t.Errorf("Expected 2 skipped targets of values.yaml to be counted, got %v", got)
+			}
+		})
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_PushesOnce(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfig := &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "default"},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: RepositoryTypeECR,
+				ECR:  &yukv1.ECRConfig{Region: "us-west-2", RepositoryName: "my-app"},
+			},
+			Git: yukv1.GitConfig{Repository: "https://github.com/example/repo.git", Branch: "main"},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "values.yaml", YAMLPath: "image.tag"},
+			},
+		},
+		Status: yukv1.YukConfigStatus{CurrentTag: "v1.0.0"},
+	}
+
+	gitOperator := newFakeGitOperator(t, map[string]string{"values.yaml": "image:\n    tag: v1.0.0\n"})
+	trigger := NewReconcileTrigger()
+	reconciler := &YukConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(yukConfig).
+			WithStatusSubresource(&yukv1.YukConfig{}).Build(),
+		Scheme:  scheme,
+		Trigger: trigger,
+		NewECRClient: func(region string, opts ...ecr.Option) TagResolver {
+			return &fakeTagResolver{latestTags: map[string]string{"my-app:": "v1.1.0"}}
+		},
+		NewGitClient: func(config yukv1.GitConfig, opts ...git.Option) GitOperator {
+			return gitOperator
+		},
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "test-config", Namespace: "default"}}
+	for i := 0; i < 2; i++ {
+		// Trigger every reconcile so none is skipped as too early
+		trigger.Enqueue(yukConfig)
+		if _, err := reconciler.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile %d failed: %v", i+1, err)
+		}
+	}
+
+	// Only the reconcile detecting the new tag clones and pushes
+	if gitOperator.clones != 1 || gitOperator.pushes != 1 || gitOperator.cleanups != 1 {
+		t.Errorf("Expected 1 clone, push and cleanup, got %d, %d and %d", gitOperator.clones, gitOperator.pushes, gitOperator.cleanups)
+	}
+	if len(gitOperator.messages) == 1 && gitOperator.messages[0] != "Update container image to v1.1.0" {
+		t.Errorf("Expected the default commit message, got %q", gitOperator.messages[0])
+	}
+	if content := gitOperator.file("values.yaml"); !strings.Contains(content, "tag: v1.1.0") {
+		t.Errorf("Expected v1.1.0 to be pushed, got:\n%s", content)
+	}
+
+	updated := &yukv1.YukConfig{}
+	if err := reconciler.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("Failed to get YukConfig: %v", err)
+	}
+	if updated.Status.CurrentTag != "v1.1.0" || updated.Status.LastCommitSHA == "" {
+		t.Errorf("Expected current tag v1.1.0 with the pushed commit, got %s and %q", updated.Status.CurrentTag, updated.Status.LastCommitSHA)
+	}
+}
+
+func TestYukConfigReconciler_Reconcile_DeletedCleansUpMetrics(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = yukv1.AddToScheme(scheme)
+
+	reconciler := &YukConfigReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	// Metrics left over from the reconciles of a YukConfig that was deleted since
+	labels := prometheus.Labels{"namespace": "default", "name": "deleted-config", "repository_name": "my-app"}
+	yukmetrics.PossiblyPinned.With(labels).Set(1)
+	yukmetrics.TagOutOfDate.With(labels).Set(1)
+	yukmetrics.LastCheckTimestamp.With(labels).Set(1)
+	yukmetrics.LastUpdateTimestamp.With(labels).Set(1)
+	configLabels := prometheus.Labels{"namespace": "default", "name": "deleted-config"}
+	yukmetrics.LastChangedFiles.With(configLabels).Set(2)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "deleted-config", Namespace: "default"}}
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	gauges := map[string]*prometheus.GaugeVec{
+		"yuk_possibly_pinned":               yukmetrics.PossiblyPinned,
+		"yuk_tag_out_of_date":               yukmetrics.TagOutOfDate,
+		"yuk_last_check_timestamp_seconds":  yukmetrics.LastCheckTimestamp,
+		"yuk_last_update_timestamp_seconds": yukmetrics.LastUpdateTimestamp,
+	}
+	for name, gauge := range gauges {
+		if gauge.Delete(labels) {
+			t.Errorf("Expected %s of the deleted YukConfig to be removed", name)
+		}
+	}
+	if yukmetrics.LastChangedFiles.Delete(configLabels) {
+		t.Error("Expected yuk_last_changed_files of the deleted YukConfig to be removed")
+	}
 }
@@ -0,0 +1,180 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContextLines is the number of unchanged lines shown around each change
+const ContextLines = 3
+
+// opKind is the kind of a line in an edit script
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+// op is a line of an edit script with its line numbers in the old and new file
+type op struct {
+	kind    opKind
+	line    string
+	oldLine int
+	newLine int
+}
+
+// Unified returns the unified diff of a file's old and new content, or an empty string
+// when the content is unchanged. The file name is used in the diff headers.
+func Unified(file string, oldContent, newContent []byte) string {
+	if string(oldContent) == string(newContent) {
+		return ""
+	}
+
+	ops := editScript(splitLines(string(oldContent)), splitLines(string(newContent)))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", file, file)
+	for _, h := range hunks(ops) {
+		writeHunk(&b, ops[h[0]:h[1]])
+	}
+
+	return b.String()
+}
+
+// splitLines splits content into lines, keeping a missing final newline visible
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		if strings.HasSuffix(line, "\n") {
+			lines[i] = strings.TrimSuffix(line, "\n")
+		} else {
+			lines[i] = line + "\n\\ No newline at end of file"
+		}
+	}
+
+	return lines
+}
+
+// editScript returns the shortest edit script turning a into b, from their longest
+// common subsequence. Target files are small, so the quadratic table is fine.
+func editScript(a, b []string) []op {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, op{kind: opEqual, line: a[i], oldLine: i + 1, newLine: j + 1})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{kind: opDelete, line: a[i], oldLine: i + 1, newLine: j})
+			i++
+		default:
+			ops = append(ops, op{kind: opInsert, line: b[j], oldLine: i, newLine: j + 1})
+			j++
+		}
+	}
+
+	return ops
+}
+
+// hunks returns the [start, end) ranges of the edit script to print, each change
+// surrounded by up to ContextLines unchanged lines. Close changes share a hunk.
+func hunks(ops []op) [][2]int {
+	var ranges [][2]int
+	for i, o := range ops {
+		if o.kind == opEqual {
+			continue
+		}
+
+		start := max(i-ContextLines, 0)
+		end := min(i+ContextLines+1, len(ops))
+		if n := len(ranges); n > 0 && start <= ranges[n-1][1] {
+			ranges[n-1][1] = end
+		} else {
+			ranges = append(ranges, [2]int{start, end})
+		}
+	}
+
+	return ranges
+}
+
+// writeHunk writes a hunk header and its lines
+func writeHunk(b *strings.Builder, ops []op) {
+	oldStart, newStart := ops[0].oldLine, ops[0].newLine
+	oldCount, newCount := 0, 0
+	for _, o := range ops {
+		if o.kind != opInsert {
+			oldCount++
+		}
+		if o.kind != opDelete {
+			newCount++
+		}
+	}
+
+	// An insertion at the very start has no old line; a deletion has no new line
+	if ops[0].kind == opInsert {
+		oldStart++
+	}
+	if ops[0].kind == opDelete {
+		newStart++
+	}
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, o := range ops {
+		fmt.Fprintf(b, "%c%s\n", o.kind, o.line)
+	}
+}
+
+// hunkRange formats the line range of a hunk header
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name       string
+		oldContent string
+		newContent string
+		expected   string
+	}{
+		{
+			name:       "unchanged",
+			oldContent: "image:\n  tag: v1.0.0\n",
+			newContent: "image:\n  tag: v1.0.0\n",
+			expected:   "",
+		},
+		{
+			name:       "changed line",
+			oldContent: "image:\n  repository: my-app\n  tag: v1.0.0\n",
+			newContent: "image:\n  repository: my-app\n  tag: v1.1.0\n",
+			expected: `--- a/values.yaml
++++ b/values.yaml
+@@ -1,3 +1,3 @@
+ image:
+   repository: my-app
+-  tag: v1.0.0
++  tag: v1.1.0
+`,
+		},
+		{
+			name:       "context is limited",
+			oldContent: "a\nb\nc\nd\ne\nf\ng\nh\n",
+			newContent: "a\nb\nc\nd\nE\nf\ng\nh\n",
+			expected: `--- a/values.yaml
++++ b/values.yaml
+@@ -2,7 +2,7 @@
+ b
+ c
+ d
+-e
++E
+ f
+ g
+ h
+`,
+		},
+		{
+			name:       "distant changes",
+			oldContent: "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n",
+			newContent: "A\nb\nc\nd\ne\nf\ng\nh\ni\nJ\n",
+			expected: `--- a/values.yaml
++++ b/values.yaml
+@@ -1,4 +1,4 @@
+-a
++A
+ b
+ c
+ d
+@@ -7,4 +7,4 @@
+ g
+ h
+ i
+-j
++J
+`,
+		},
+		{
+			name:       "missing final newline",
+			oldContent: "tag: v1.0.0",
+			newContent: "tag: v1.1.0\n",
+			expected: `--- a/values.yaml
++++ b/values.yaml
+@@ -1 +1 @@
+-tag: v1.0.0
+\ No newline at end of file
++tag: v1.1.0
+`,
+		},
+		{
+			name:       "new file",
+			oldContent: "",
+			newContent: "tag: v1.1.0\n",
+			expected: `--- a/values.yaml
++++ b/values.yaml
+@@ -0,0 +1 @@
++tag: v1.1.0
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unified("values.yaml", []byte(tt.oldContent), []byte(tt.newContent))
+			if got != tt.expected {
+				t.Errorf("Expected diff:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+// staleRetention is how long after listing expired images remain a fallback while ECR
+// throttles requests. Older entries, e.g. of repositories no longer monitored or rotated
+// access keys, are removed.
+const staleRetention = time.Hour
+
+// Cache shares the images of ECR repositories between clients for a short time, so
+// that configurations monitoring the same repository do not each call the ECR API.
+// Tag filters, constraints and sorting are applied to the cached images by each client.
+// A Cache is safe for concurrent use.
+type Cache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// cacheEntry holds the images of a repository and when they were listed
+type cacheEntry struct {
+	imageDetails []types.ImageDetail
+	listedAt     time.Time
+}
+
+// NewCache creates a cache whose entries are reused for the given TTL
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// get returns the cached images for the key. Expired entries are only returned when
+// stale is true, e.g. as a fallback while ECR throttles requests.
+func (c *Cache) get(key string, stale bool) ([]types.ImageDetail, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	age := c.now().Sub(entry.listedAt)
+	if (!stale && age >= c.ttl) || age >= c.retention() {
+		return nil, false
+	}
+
+	return entry.imageDetails, true
+}
+
+// set caches the images for the key and removes the entries past their stale retention
+func (c *Cache) set(key string, imageDetails []types.ImageDetail) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for k, entry := range c.entries {
+		if now.Sub(entry.listedAt) >= c.retention() {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cacheEntry{imageDetails: imageDetails, listedAt: now}
+}
+
+// retention returns how long entries are kept: the stale retention, or the TTL when longer
+func (c *Cache) retention() time.Duration {
+	return max(c.ttl, staleRetention)
+}
+
+// cacheKey identifies a repository. The access key, assumed role and registry ID are part
+// of the key because the same repository name refers to a different registry for each AWS
+// account.
+func cacheKey(region, accessKeyID, roleARN, registryID, repositoryName string) string {
+	return strings.Join([]string{region, accessKeyID, roleARN, registryID, repositoryName}, "/")
+}
+
+// isThrottlingError reports whether ECR rejected the request because of rate limiting
+func isThrottlingError(err error) bool {
+	return retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+)
+
+// fakeECR serves DescribeImages with the given tags of a single image, or throttles every
+// request while throttled is set and the next throttles requests
+type fakeECR struct {
+	tags      []string
+	digest    string
+	throttled atomic.Bool
+	throttles atomic.Int32
+	requests  atomic.Int32
+
+	// registryID is the registry ID of the last request
+	registryID string
+}
+
+func (f *fakeECR) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests.Add(1)
+	var input struct {
+		RegistryID string `json:"registryId"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&input)
+	f.registryID = input.RegistryID
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+
+	if f.throttled.Load() || f.throttles.Add(-1) >= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"__type":"ThrottlingException","message":"Rate exceeded"}`))
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"imageDetails": []map[string]interface{}{{"imageTags": f.tags, "imageDigest": f.digest}},
+	})
+}
+
+// newCachedClient creates a client listing images from the fake ECR API through the cache
+func newCachedClient(serverURL string, cache *Cache, refresh bool) *Client {
+	client := NewClient("us-east-1", WithCache(cache, refresh))
+	client.ecrClient = ecr.New(ecr.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(serverURL),
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		Retryer:      newRetryer(time.Millisecond),
+	})
+	return client
+}
+
+func TestClient_GetLatestTag_Cache(t *testing.T) {
+	fake := &fakeECR{tags: []string{"v1.0.0"}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	now := time.Now()
+	cache := NewCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	getLatestTag := func(refresh bool) string {
+		t.Helper()
+		// Each reconcile creates its own client; only the cache is shared
+		tag, err := newCachedClient(server.URL, cache, refresh).GetLatestTag(context.Background(), "my-app", "")
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		return tag
+	}
+
+	if tag := getLatestTag(false); tag != "v1.0.0" {
+		t.Errorf("Expected tag v1.0.0, got %s", tag)
+	}
+
+	// Within the TTL the cached images are reused
+	fake.tags = []string{"v1.0.0", "v1.1.0"}
+	if tag := getLatestTag(false); tag != "v1.0.0" {
+		t.Errorf("Expected cached tag v1.0.0, got %s", tag)
+	}
+	if requests := fake.requests.Load(); requests != 1 {
+		t.Errorf("Expected 1 request within the TTL, got %d", requests)
+	}
+
+	// After the TTL the images are listed again
+	now = now.Add(time.Minute)
+	if tag := getLatestTag(false); tag != "v1.1.0" {
+		t.Errorf("Expected tag v1.1.0 after expiry, got %s", tag)
+	}
+	if requests := fake.requests.Load(); requests != 2 {
+		t.Errorf("Expected 2 requests after expiry, got %d", requests)
+	}
+
+	// A refresh lists the images within the TTL and updates the cache
+	fake.tags = []string{"v1.0.0", "v1.1.0", "v1.2.0"}
+	if tag := getLatestTag(true); tag != "v1.2.0" {
+		t.Errorf("Expected refreshed tag v1.2.0, got %s", tag)
+	}
+	if tag := getLatestTag(false); tag != "v1.2.0" {
+		t.Errorf("Expected cached tag v1.2.0 after refresh, got %s", tag)
+	}
+	if requests := fake.requests.Load(); requests != 3 {
+		t.Errorf("Expected 3 requests after refresh, got %d", requests)
+	}
+
+	// While throttled, the expired images are used instead of failing
+	now = now.Add(time.Minute)
+	fake.throttled.Store(true)
+	if tag := getLatestTag(false); tag != "v1.2.0" {
+		t.Errorf("Expected stale tag v1.2.0 while throttled, got %s", tag)
+	}
+}
+
+func TestClient_GetLatestTag_CacheThrottledWithoutEntry(t *testing.T) {
+	fake := &fakeECR{}
+	fake.throttled.Store(true)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	_, err := newCachedClient(server.URL, NewCache(time.Minute), false).GetLatestTag(context.Background(), "my-app", "")
+	if err == nil {
+		t.Fatal("Expected error but got none")
+	}
+	if !isThrottlingError(err) {
+		t.Errorf("Expected a throttling error, got: %v", err)
+	}
+}
+
+func TestCache_RemovesExpiredEntries(t *testing.T) {
+	now := time.Now()
+	cache := NewCache(30 * time.Second)
+	cache.now = func() time.Time { return now }
+
+	cache.set("us-east-1/AKID//old-app", nil)
+	now = now.Add(10 * time.Minute)
+	cache.set("us-east-1/AKID//my-app", nil)
+
+	// Expired entries remain a fallback while ECR throttles
+	if _, ok := cache.get("us-east-1/AKID//old-app", true); !ok {
+		t.Error("Expected the expired entry to remain as a stale fallback")
+	}
+
+	now = now.Add(staleRetention)
+	cache.set("us-east-1/AKID//other-app", nil)
+	if len(cache.entries) != 1 {
+		t.Errorf("Expected the entries past their stale retention to be removed, got %d entries", len(cache.entries))
+	}
+	if _, ok := cache.get("us-east-1/AKID//other-app", false); !ok {
+		t.Error("Expected the new entry to be cached")
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	if cacheKey("us-east-1", "", "", "", "my-app") == cacheKey("us-east-1", "AKID", "", "", "my-app") {
+		t.Error("Expected different credentials to use different cache entries")
+	}
+	if cacheKey("us-east-1", "", "", "", "my-app") == cacheKey("us-east-1", "", "arn:aws:iam::123456789012:role/ecr-reader", "", "my-app") {
+		t.Error("Expected different roles to use different cache entries")
+	}
+	if cacheKey("us-east-1", "", "", "", "my-app") == cacheKey("eu-west-1", "", "", "", "my-app") {
+		t.Error("Expected different regions to use different cache entries")
+	}
+	if cacheKey("us-east-1", "", "", "", "my-app") == cacheKey("us-east-1", "", "", "123456789012", "my-app") {
+		t.Error("Expected different registries to use different cache entries")
+	}
+}
+
+func TestClient_GetLatestTag_RegistryID(t *testing.T) {
+	fake := &fakeECR{tags: []string{"v1.0.0"}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := newCachedClient(server.URL, nil, false)
+	if _, err := client.GetLatestTag(context.Background(), "my-app", ""); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if fake.registryID != "" {
+		t.Errorf("Expected the default registry, got %q", fake.registryID)
+	}
+
+	WithRegistryID("123456789012")(client)
+	if _, err := client.GetLatestTag(context.Background(), "my-app", ""); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if fake.registryID != "123456789012" {
+		t.Errorf("Expected registry 123456789012, got %q", fake.registryID)
+	}
+}
@@ -20,53 +20,350 @@ import (
 	"context"
 	"fmt"
 	"regexp"
-	"sort"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
 	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/google/cel-go/common/types/ref"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
 )
 
+// assumeRoleSessionName identifies Yuk in the CloudTrail events of assumed roles
+const assumeRoleSessionName = "yuk"
+
 // Client provides operations for interacting with AWS ECR
 type Client struct {
-	ecrClient *ecr.Client
-	region    string
+	ecrClient        *ecr.Client
+	region           string
+	selectExpression string
+	selector         *TagSelector
+	sortStrategy     tagsort.Strategy
+	constraint       string
+	constraints      *semver.Constraints
+	accessKeyID      string
+	secretAccessKey  string
+	roleARN          string
+	externalID       string
+	registryID       string
+	minPushAge       time.Duration
+	maxPushAge       time.Duration
+	excludePatterns  []string
+	excludePre       bool
+	manifestLists    bool
+	excludeArtifacts bool
+	tagPriority      []string
+	exclusion        *tagExclusion
+	cache            *Cache
+	refreshCache     bool
+}
+
+// Option configures optional behavior of a Client
+type Option func(*Client)
+
+// WithSelectExpression ranks tags by a CEL expression instead of lexical order.
+// See TagSelector for the expression environment.
+func WithSelectExpression(expression string) Option {
+	return func(c *Client) {
+		c.selectExpression = expression
+	}
+}
+
+// WithSortStrategy orders tags by the given strategy instead of lexical order. A select
+// expression takes precedence over the sort strategy.
+func WithSortStrategy(strategy tagsort.Strategy) Option {
+	return func(c *Client) {
+		c.sortStrategy = strategy
+	}
+}
+
+// WithVersionConstraint only considers tags that are semantic versions within the given
+// range (e.g. ">=1.2.0 <2.0.0"). Tags are then ordered by semantic version unless the
+// sort strategy is tagsort.PushTime or a select expression is set.
+func WithVersionConstraint(constraint string) Option {
+	return func(c *Client) {
+		c.constraint = constraint
+	}
+}
+
+// WithPushAgeWindow only considers images pushed at least minAge and at most maxAge ago,
+// e.g. to ignore old tags that would otherwise sort first. A zero bound is not applied.
+func WithPushAgeWindow(minAge, maxAge time.Duration) Option {
+	return func(c *Client) {
+		c.minPushAge = minAge
+		c.maxPushAge = maxAge
+	}
+}
+
+// WithExcludeTags never selects tags matching any of the given regex patterns, e.g.
+// floating tags such as "latest" or "stable". Patterns must match the whole tag.
+func WithExcludeTags(patterns []string) Option {
+	return func(c *Client) {
+		c.excludePatterns = patterns
+	}
+}
+
+// WithExcludePrereleases never selects tags that are semantic versions with a
+// pre-release (e.g. "1.2.0-rc.1")
+func WithExcludePrereleases(exclude bool) Option {
+	return func(c *Client) {
+		c.excludePre = exclude
+	}
+}
+
+// WithManifestListsOnly only selects tags of multi-platform images, i.e. Docker manifest
+// lists and OCI image indexes, ignoring the tags of single-platform images
+func WithManifestListsOnly(manifestListsOnly bool) Option {
+	return func(c *Client) {
+		c.manifestLists = manifestListsOnly
+	}
+}
+
+// WithExcludeArtifacts never selects the tags of attestations, signatures, SBOMs and other
+// artifacts that are not container images, e.g. cosign's "sha256-<digest>.att" tags
+func WithExcludeArtifacts(exclude bool) Option {
+	return func(c *Client) {
+		c.excludeArtifacts = exclude
+	}
+}
+
+// WithTagPriority selects the first of the given tags that exists in the repository
+// instead of sorting tags, e.g. for channel tags such as "stable" and "beta". Tags that
+// are not in the list are never selected. The priority takes precedence over a select
+// expression and the sort strategy.
+func WithTagPriority(tags []string) Option {
+	return func(c *Client) {
+		c.tagPriority = tags
+	}
+}
+
+// WithStaticCredentials authenticates with the given access key instead of the default
+// AWS credential chain (e.g. IRSA)
+func WithStaticCredentials(accessKeyID, secretAccessKey string) Option {
+	return func(c *Client) {
+		c.accessKeyID = accessKeyID
+		c.secretAccessKey = secretAccessKey
+	}
+}
+
+// WithAssumeRole accesses ECR as the given IAM role (e.g. in another account), assumed
+// with the base credentials from the default chain or WithStaticCredentials. The external
+// ID is optional.
+func WithAssumeRole(roleARN, externalID string) Option {
+	return func(c *Client) {
+		c.roleARN = roleARN
+		c.externalID = externalID
+	}
+}
+
+// WithRegistryID accesses the registry of the given AWS account instead of the default
+// registry of the credentials, e.g. a registry shared with the account through its
+// repository policy. An empty ID selects the default registry.
+func WithRegistryID(registryID string) Option {
+	return func(c *Client) {
+		c.registryID = registryID
+	}
+}
+
+// registry returns the registry ID of ECR requests, or nil for the default registry of
+// the credentials
+func (c *Client) registry() *string {
+	if c.registryID == "" {
+		return nil
+	}
+	return aws.String(c.registryID)
+}
+
+// WithCache reuses the images listed by other clients sharing the cache within its TTL.
+// With refresh, images are always listed and the cache is only updated, e.g. when a push
+// is known to have happened. When ECR still throttles a request after retries, the last
+// cached images are used even if expired. A nil cache disables caching.
+func WithCache(cache *Cache, refresh bool) Option {
+	return func(c *Client) {
+		c.cache = cache
+		c.refreshCache = refresh
+	}
 }
 
 // NewClient creates a new ECR client for the specified region
-func NewClient(region string) *Client {
-	return &Client{
+func NewClient(region string, opts ...Option) *Client {
+	c := &Client{
 		region: region,
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
-// GetLatestTag retrieves the latest tag from the specified ECR repository
+// GetLatestTag retrieves the latest tag from the specified ECR repository. Throttled and
+// transiently failing requests are retried with exponential backoff, a bounded number of
+// times; see IsTransientError.
 func (c *Client) GetLatestTag(ctx context.Context, repositoryName, tagFilter string) (string, error) {
+	latestTags, err := c.GetLatestTags(ctx, repositoryName, []string{tagFilter})
+	if err != nil {
+		return "", err
+	}
+
+	return latestTags[tagFilter], nil
+}
+
+// GetLatestTags retrieves the latest tag for each of the given tag filters, listing the
+// repository's images only once. The result is keyed by tag filter.
+func (c *Client) GetLatestTags(ctx context.Context, repositoryName string, tagFilters []string) (map[string]string, error) {
 	if c.ecrClient == nil {
 		if err := c.initClient(ctx); err != nil {
-			return "", fmt.Errorf("failed to initialize ECR client: %w", err)
+			return nil, fmt.Errorf("failed to initialize ECR client: %w", err)
+		}
+	}
+
+	// Validate the sort strategy and push age window and compile the select expression and
+	// version constraint before listing images so invalid configurations fail fast
+	if err := tagsort.Validate(c.sortStrategy, tagsort.Semver, tagsort.PushTime); err != nil {
+		return nil, err
+	}
+
+	if err := validatePushAgeWindow(c.minPushAge, c.maxPushAge); err != nil {
+		return nil, err
+	}
+
+	if c.selectExpression != "" && c.selector == nil {
+		selector, err := NewTagSelector(c.selectExpression)
+		if err != nil {
+			return nil, err
+		}
+		c.selector = selector
+	}
+
+	if c.constraint != "" && c.constraints == nil {
+		constraints, err := parseVersionConstraint(c.constraint)
+		if err != nil {
+			return nil, err
+		}
+		c.constraints = constraints
+	}
+
+	if (len(c.excludePatterns) > 0 || c.excludePre) && c.exclusion == nil {
+		exclusion, err := newTagExclusion(c.excludePatterns, c.excludePre)
+		if err != nil {
+			return nil, err
+		}
+		c.exclusion = exclusion
+	}
+
+	imageDetails, err := c.listImages(ctx, repositoryName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only consider images of the selected manifest types pushed within the push age
+	// window, and never excluded tags
+	imageDetails = filterByManifestType(imageDetails, c.manifestLists, c.excludeArtifacts)
+	imageDetails = filterByPushAge(imageDetails, time.Now(), c.minPushAge, c.maxPushAge)
+	imageDetails = excludeTags(imageDetails, c.exclusion)
+
+	latestTags := make(map[string]string, len(tagFilters))
+	for _, tagFilter := range tagFilters {
+		if _, done := latestTags[tagFilter]; done {
+			continue
+		}
+
+		var tag string
+		var err error
+		if len(c.tagPriority) > 0 {
+			tag, err = selectByPriority(imageDetails, repositoryName, tagFilter, c.tagPriority)
+		} else {
+			tag, err = selectLatestTag(imageDetails, repositoryName, tagFilter, c.selector, c.constraints, c.sortStrategy)
+		}
+		if err != nil {
+			return nil, err
 		}
+		latestTags[tagFilter] = tag
+	}
+
+	return latestTags, nil
+}
+
+// listImages lists all images in the specified ECR repository, reusing the cached images
+// when a cache is set
+func (c *Client) listImages(ctx context.Context, repositoryName string) ([]types.ImageDetail, error) {
+	if c.cache == nil {
+		return c.describeImages(ctx, repositoryName)
 	}
 
-	// List all image tags
+	key := cacheKey(c.region, c.accessKeyID, c.roleARN, c.registryID, repositoryName)
+	if imageDetails, ok := c.cache.get(key, false); ok && !c.refreshCache {
+		return imageDetails, nil
+	}
+
+	imageDetails, err := c.describeImages(ctx, repositoryName)
+	if err != nil {
+		// Fall back to the last known images rather than failing while throttled
+		if isThrottlingError(err) {
+			if cached, ok := c.cache.get(key, true); ok {
+				return cached, nil
+			}
+		}
+		return nil, err
+	}
+
+	c.cache.set(key, imageDetails)
+	return imageDetails, nil
+}
+
+// describeImages lists all images in the specified ECR repository, following pagination
+func (c *Client) describeImages(ctx context.Context, repositoryName string) ([]types.ImageDetail, error) {
 	input := &ecr.DescribeImagesInput{
+		RegistryId:     c.registry(),
 		RepositoryName: aws.String(repositoryName),
 		ImageIds:       []types.ImageIdentifier{},
 	}
 
-	result, err := c.ecrClient.DescribeImages(ctx, input)
-	if err != nil {
-		return "", fmt.Errorf("failed to describe images in repository %s: %w", repositoryName, err)
+	var imageDetails []types.ImageDetail
+	for {
+		result, err := c.ecrClient.DescribeImages(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe images in repository %s: %w", repositoryName, err)
+		}
+
+		imageDetails = append(imageDetails, result.ImageDetails...)
+
+		if result.NextToken == nil {
+			break
+		}
+		input.NextToken = result.NextToken
 	}
 
-	if len(result.ImageDetails) == 0 {
-		return "", fmt.Errorf("no images found in repository %s", repositoryName)
+	if len(imageDetails) == 0 {
+		return nil, fmt.Errorf("no images found in repository %s", repositoryName)
 	}
 
+	return imageDetails, nil
+}
+
+// tagCandidate is a tag matching the tag filter along with the details of its image
+type tagCandidate struct {
+	tag      string
+	groups   []string
+	pushedAt time.Time
+}
+
+// selectLatestTag filters the tags of the given images by the tag filter, then by the
+// version constraint when given, and returns the latest one. Tags are ranked by the
+// selector when given and by the sort strategy otherwise.
+func selectLatestTag(imageDetails []types.ImageDetail, repositoryName, tagFilter string, selector *TagSelector, constraints *semver.Constraints, sortStrategy tagsort.Strategy) (string, error) {
 	// Extract and filter tags
-	var tags []string
+	var candidates []tagCandidate
 	var tagRegex *regexp.Regexp
+	var err error
 
 	if tagFilter != "" {
 		tagRegex, err = regexp.Compile(tagFilter)
@@ -75,28 +372,85 @@ func (c *Client) GetLatestTag(ctx context.Context, repositoryName, tagFilter str
 		}
 	}
 
-	for _, imageDetail := range result.ImageDetails {
+	for _, imageDetail := range imageDetails {
+		var pushedAt time.Time
+		if imageDetail.ImagePushedAt != nil {
+			pushedAt = *imageDetail.ImagePushedAt
+		}
+
 		for _, tag := range imageDetail.ImageTags {
-			if tag != "" {
-				// Apply filter if specified
-				if tagRegex != nil && !tagRegex.MatchString(tag) {
+			if tag == "" {
+				continue
+			}
+
+			// Apply filter if specified, capturing its groups for the selector
+			groups := []string{tag}
+			if tagRegex != nil {
+				groups = tagRegex.FindStringSubmatch(tag)
+				if groups == nil {
 					continue
 				}
-				tags = append(tags, tag)
 			}
+
+			// Only keep semantic versions within the constraint
+			if constraints != nil && !satisfiesConstraint(tag, constraints) {
+				continue
+			}
+
+			candidates = append(candidates, tagCandidate{tag: tag, groups: groups, pushedAt: pushedAt})
 		}
 	}
 
-	if len(tags) == 0 {
+	if len(candidates) == 0 {
 		return "", fmt.Errorf("no tags found matching filter in repository %s", repositoryName)
 	}
 
-	// Sort tags to get the latest (this is a simple sort, you might want semantic versioning)
-	sort.Slice(tags, func(i, j int) bool {
-		return tags[i] > tags[j] // Descending order
-	})
+	if selector != nil {
+		return selectByExpression(candidates, selector)
+	}
+
+	// The highest version within a constraint wins unless push time is preferred
+	if constraints != nil && sortStrategy != tagsort.PushTime {
+		sortStrategy = tagsort.Semver
+	}
+
+	sortCandidates := make([]tagsort.Candidate, len(candidates))
+	for i, candidate := range candidates {
+		sortCandidates[i] = tagsort.Candidate{Tag: candidate.tag, SortKey: tagsort.SortKey(tagRegex, candidate.tag), PushedAt: candidate.pushedAt}
+	}
+
+	return tagsort.Latest(sortCandidates, sortStrategy), nil
+}
+
+// selectByExpression returns the candidate with the greatest select expression result.
+// Ties are broken by descending lexical order so the result is deterministic.
+func selectByExpression(candidates []tagCandidate, selector *TagSelector) (string, error) {
+	var best *tagCandidate
+	var bestKey ref.Val
+
+	for i := range candidates {
+		candidate := &candidates[i]
+
+		key, err := selector.Evaluate(candidate.tag, candidate.groups, candidate.pushedAt)
+		if err != nil {
+			return "", err
+		}
+
+		if best == nil {
+			best, bestKey = candidate, key
+			continue
+		}
+
+		cmp, err := compareSortKeys(key, bestKey)
+		if err != nil {
+			return "", err
+		}
+		if cmp > 0 || (cmp == 0 && candidate.tag > best.tag) {
+			best, bestKey = candidate, key
+		}
+	}
 
-	return tags[0], nil
+	return best.tag, nil
 }
 
 // GetImageDetails retrieves detailed information about images with the specified tag
@@ -108,6 +462,7 @@ func (c *Client) GetImageDetails(ctx context.Context, repositoryName, tag string
 	}
 
 	input := &ecr.DescribeImagesInput{
+		RegistryId:     c.registry(),
 		RepositoryName: aws.String(repositoryName),
 		ImageIds: []types.ImageIdentifier{
 			{
@@ -128,6 +483,21 @@ func (c *Client) GetImageDetails(ctx context.Context, repositoryName, tag string
 	return &result.ImageDetails[0], nil
 }
 
+// GetImageDigest returns the digest (e.g. "sha256:...") of the image with the given tag
+func (c *Client) GetImageDigest(ctx context.Context, repositoryName, tag string) (string, error) {
+	imageDetail, err := c.GetImageDetails(ctx, repositoryName, tag)
+	if err != nil {
+		return "", err
+	}
+
+	digest := aws.ToString(imageDetail.ImageDigest)
+	if digest == "" {
+		return "", fmt.Errorf("no digest returned for image %s:%s", repositoryName, tag)
+	}
+
+	return digest, nil
+}
+
 // ListRepositories lists all ECR repositories in the region
 func (c *Client) ListRepositories(ctx context.Context) ([]types.Repository, error) {
 	if c.ecrClient == nil {
@@ -147,11 +517,41 @@ func (c *Client) ListRepositories(ctx context.Context) ([]types.Repository, erro
 
 // initClient initializes the ECR client with AWS configuration
 func (c *Client) initClient(ctx context.Context) error {
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(c.region))
+	cfg, err := c.loadConfig(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to load AWS config: %w", err)
+		return err
 	}
 
 	c.ecrClient = ecr.NewFromConfig(cfg)
 	return nil
 }
+
+// loadConfig loads the AWS configuration, assuming the configured role on top of the
+// base credentials when set
+func (c *Client) loadConfig(ctx context.Context) (aws.Config, error) {
+	opts := []func(*config.LoadOptions) error{
+		config.WithRegion(c.region),
+		config.WithRetryer(func() aws.Retryer { return newRetryer(retryBaseDelay) }),
+	}
+	if c.accessKeyID != "" {
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(c.accessKeyID, c.secretAccessKey, "")))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	if c.roleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), c.roleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = assumeRoleSessionName
+			if c.externalID != "" {
+				o.ExternalID = aws.String(c.externalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	return cfg, nil
+}
@@ -17,7 +17,18 @@ limitations under the License.
 package ecr
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
 )
 
 func TestNewClient(t *testing.T) {
@@ -47,3 +58,199 @@ func TestClient_GetLatestTag_EmptyRepository(t *testing.T) {
 		t.Errorf("Expected region us-east-1, got %s", client.region)
 	}
 }
+
+func TestSelectLatestTag(t *testing.T) {
+	imageDetails := []types.ImageDetail{
+		{ImageTags: []string{"app-1.0.0", "latest"}},
+		{ImageTags: []string{"app-1.1.0"}},
+		{ImageTags: []string{"migrate-1.0.0"}},
+		{ImageTags: []string{"migrate-1.2.0", ""}},
+	}
+
+	tests := []struct {
+		name      string
+		tagFilter string
+		expected  string
+		shouldErr bool
+	}{
+		{
+			name:      "app filter",
+			tagFilter: "^app-",
+			expected:  "app-1.1.0",
+		},
+		{
+			name:      "migration filter",
+			tagFilter: "^migrate-",
+			expected:  "migrate-1.2.0",
+		},
+		{
+			name:      "no filter",
+			tagFilter: "",
+			expected:  "migrate-1.2.0",
+		},
+		{
+			name:      "no sort capture group",
+			tagFilter: `^[a-z]+-1\.[01]\.0$`,
+			expected:  "migrate-1.0.0",
+		},
+		{
+			name:      "sort capture group",
+			tagFilter: `^[a-z]+-(?P<sort>1\.[01]\.0)$`,
+			expected:  "app-1.1.0",
+		},
+		{
+			name:      "no matching tags",
+			tagFilter: "^worker-",
+			shouldErr: true,
+		},
+		{
+			name:      "invalid filter",
+			tagFilter: "(",
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, err := selectLatestTag(imageDetails, "test-repo", tt.tagFilter, nil, nil, tagsort.Lexical)
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if tag != tt.expected {
+				t.Errorf("Expected tag %s, got %s", tt.expected, tag)
+			}
+		})
+	}
+}
+
+func TestNewClient_WithStaticCredentials(t *testing.T) {
+	client := NewClient("us-east-1", WithStaticCredentials("AKIAEXAMPLE", "secret"))
+
+	if client.accessKeyID != "AKIAEXAMPLE" {
+		t.Errorf("Expected access key ID AKIAEXAMPLE, got %s", client.accessKeyID)
+	}
+
+	if client.secretAccessKey != "secret" {
+		t.Errorf("Expected secret access key secret, got %s", client.secretAccessKey)
+	}
+}
+
+func TestClient_loadConfig_AssumeRole(t *testing.T) {
+	// Keep the shared AWS config of the machine running the tests out of the way
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+
+	var form map[string]string
+	stsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse STS request: %v", err)
+		}
+		form = map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = w.Write([]byte(`<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+<AssumeRoleResult><Credentials>
+<AccessKeyId>ASIAASSUMED</AccessKeyId><SecretAccessKey>assumed</SecretAccessKey>
+<SessionToken>token</SessionToken><Expiration>2099-01-01T00:00:00Z</Expiration>
+</Credentials></AssumeRoleResult></AssumeRoleResponse>`))
+	}))
+	defer stsServer.Close()
+	t.Setenv("AWS_ENDPOINT_URL_STS", stsServer.URL)
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		assumeRole bool
+		expected   map[string]string
+	}{
+		{
+			name: "base credentials",
+			opts: []Option{WithStaticCredentials("AKIABASE", "secret")},
+		},
+		{
+			name:       "assume role",
+			opts:       []Option{WithStaticCredentials("AKIABASE", "secret"), WithAssumeRole("arn:aws:iam::123456789012:role/ecr-reader", "")},
+			assumeRole: true,
+			expected: map[string]string{
+				"RoleArn":         "arn:aws:iam::123456789012:role/ecr-reader",
+				"RoleSessionName": "yuk",
+			},
+		},
+		{
+			name:       "assume role with external ID",
+			opts:       []Option{WithStaticCredentials("AKIABASE", "secret"), WithAssumeRole("arn:aws:iam::123456789012:role/ecr-reader", "shared-ecr")},
+			assumeRole: true,
+			expected: map[string]string{
+				"RoleArn":    "arn:aws:iam::123456789012:role/ecr-reader",
+				"ExternalId": "shared-ecr",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form = nil
+			cfg, err := NewClient("us-east-1", tt.opts...).loadConfig(context.Background())
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			cache, ok := cfg.Credentials.(*aws.CredentialsCache)
+			if !ok {
+				t.Fatalf("Expected cached credentials, got %T", cfg.Credentials)
+			}
+			if assumeRole := cache.IsCredentialsProvider(&stscreds.AssumeRoleProvider{}); assumeRole != tt.assumeRole {
+				t.Errorf("Expected assume role provider %v, got %v", tt.assumeRole, assumeRole)
+			}
+
+			creds, err := cfg.Credentials.Retrieve(context.Background())
+			if err != nil {
+				t.Fatalf("Failed to retrieve credentials: %v", err)
+			}
+
+			if !tt.assumeRole {
+				if creds.AccessKeyID != "AKIABASE" {
+					t.Errorf("Expected base access key, got %s", creds.AccessKeyID)
+				}
+				if form != nil {
+					t.Errorf("Expected no STS request, got %v", form)
+				}
+				return
+			}
+
+			if creds.AccessKeyID != "ASIAASSUMED" {
+				t.Errorf("Expected assumed role access key, got %s", creds.AccessKeyID)
+			}
+			if form["Action"] != "AssumeRole" {
+				t.Errorf("Expected AssumeRole request, got %v", form)
+			}
+			for key, value := range tt.expected {
+				if form[key] != value {
+					t.Errorf("Expected %s %q, got %q", key, value, form[key])
+				}
+			}
+		})
+	}
+}
+
+func TestClient_GetImageDigest(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	server := httptest.NewServer(&fakeECR{tags: []string{"v1.0.0"}, digest: digest})
+	defer server.Close()
+
+	resolved, err := newCachedClient(server.URL, nil, false).GetImageDigest(context.Background(), "my-app", "v1.0.0")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if resolved != digest {
+		t.Errorf("Expected digest %s, got %s", digest, resolved)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+// maxContentSize bounds the manifests and blobs read from ECR
+const maxContentSize = 4 << 20
+
+// manifestMediaTypes are accepted when reading manifests
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// ImageNotFoundError means the repository has no image with the tag or digest
+type ImageNotFoundError struct {
+	RepositoryName string
+	Reference      string
+}
+
+// Error implements error
+func (e *ImageNotFoundError) Error() string {
+	return fmt.Sprintf("image not found: %s:%s", e.RepositoryName, e.Reference)
+}
+
+// HTTPStatusCode returns 404, as registries answer for unknown manifests
+func (e *ImageNotFoundError) HTTPStatusCode() int {
+	return http.StatusNotFound
+}
+
+// GetManifest returns the manifest the tag or digest points to, e.g. the manifest of a
+// cosign signature. It returns an *ImageNotFoundError when there is no such image.
+func (c *Client) GetManifest(ctx context.Context, repositoryName, reference string) ([]byte, error) {
+	if c.ecrClient == nil {
+		if err := c.initClient(ctx); err != nil {
+			return nil, fmt.Errorf("failed to initialize ECR client: %w", err)
+		}
+	}
+
+	imageID := types.ImageIdentifier{ImageTag: aws.String(reference)}
+	if strings.HasPrefix(reference, "sha256:") {
+		imageID = types.ImageIdentifier{ImageDigest: aws.String(reference)}
+	}
+	input := &ecr.BatchGetImageInput{
+		RegistryId:         c.registry(),
+		RepositoryName:     aws.String(repositoryName),
+		ImageIds:           []types.ImageIdentifier{imageID},
+		AcceptedMediaTypes: manifestMediaTypes,
+	}
+
+	result, err := c.ecrClient.BatchGetImage(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest of %s:%s: %w", repositoryName, reference, err)
+	}
+
+	if len(result.Images) == 0 || result.Images[0].ImageManifest == nil {
+		for _, failure := range result.Failures {
+			if failure.FailureCode != types.ImageFailureCodeImageNotFound {
+				return nil, fmt.Errorf("failed to get manifest of %s:%s: %s", repositoryName, reference, aws.ToString(failure.FailureReason))
+			}
+		}
+		return nil, &ImageNotFoundError{RepositoryName: repositoryName, Reference: reference}
+	}
+
+	return []byte(aws.ToString(result.Images[0].ImageManifest)), nil
+}
+
+// GetBlob returns the content of the layer or config blob with the digest, downloaded from
+// the pre-signed URL ECR returns for it
+func (c *Client) GetBlob(ctx context.Context, repositoryName, digest string) ([]byte, error) {
+	if c.ecrClient == nil {
+		if err := c.initClient(ctx); err != nil {
+			return nil, fmt.Errorf("failed to initialize ECR client: %w", err)
+		}
+	}
+
+	input := &ecr.GetDownloadUrlForLayerInput{
+		RegistryId:     c.registry(),
+		RepositoryName: aws.String(repositoryName),
+		LayerDigest:    aws.String(digest),
+	}
+
+	result, err := c.ecrClient.GetDownloadUrlForLayer(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob %s of %s: %w", digest, repositoryName, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, aws.ToString(result.DownloadUrl), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download blob %s of %s: %w", digest, repositoryName, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download blob %s of %s: status %d", digest, repositoryName, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxContentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download blob %s of %s: %w", digest, repositoryName, err)
+	}
+	if len(data) > maxContentSize {
+		return nil, fmt.Errorf("blob %s of %s exceeds %d bytes", digest, repositoryName, maxContentSize)
+	}
+
+	return data, nil
+}
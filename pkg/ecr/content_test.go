@@ -0,0 +1,124 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeECRContent serves the manifests of a repository and the blobs they reference
+// through pre-signed download URLs on the same server
+type fakeECRContent struct {
+	url       string
+	manifests map[string]string
+	blobs     map[string]string
+}
+
+func (f *fakeECRContent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if digest, ok := strings.CutPrefix(r.URL.Path, "/download/"); ok {
+		content, ok := f.blobs[digest]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+		return
+	}
+
+	var input struct {
+		ImageIDs []struct {
+			ImageTag    string `json:"imageTag"`
+			ImageDigest string `json:"imageDigest"`
+		} `json:"imageIds"`
+		LayerDigest string `json:"layerDigest"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&input)
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+
+	switch r.Header.Get("X-Amz-Target") {
+	case "AmazonEC2ContainerRegistry_V20150921.BatchGetImage":
+		id := input.ImageIDs[0]
+		reference := id.ImageTag + id.ImageDigest
+		manifest, ok := f.manifests[reference]
+		if !ok {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"failures": []map[string]interface{}{{"failureCode": "ImageNotFound", "failureReason": "Requested image not found"}},
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"images": []map[string]interface{}{{"imageManifest": manifest}},
+		})
+
+	case "AmazonEC2ContainerRegistry_V20150921.GetDownloadUrlForLayer":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"downloadUrl": f.url + "/download/" + input.LayerDigest,
+			"layerDigest": input.LayerDigest,
+		})
+
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestClient_GetManifestAndBlob(t *testing.T) {
+	signatureTag := "sha256-" + strings.Repeat("a", 64) + ".sig"
+	layerDigest := "sha256:" + strings.Repeat("b", 64)
+	manifest := `{"schemaVersion":2,"layers":[{"digest":"` + layerDigest + `"}]}`
+
+	fake := &fakeECRContent{
+		manifests: map[string]string{signatureTag: manifest},
+		blobs:     map[string]string{layerDigest: `{"critical":{}}`},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	fake.url = server.URL
+
+	client := newCachedClient(server.URL, nil, false)
+
+	data, err := client.GetManifest(context.Background(), "my-app", signatureTag)
+	if err != nil {
+		t.Fatalf("GetManifest() error = %v", err)
+	}
+	if string(data) != manifest {
+		t.Errorf("Expected manifest %s, got %s", manifest, data)
+	}
+
+	blob, err := client.GetBlob(context.Background(), "my-app", layerDigest)
+	if err != nil {
+		t.Fatalf("GetBlob() error = %v", err)
+	}
+	if string(blob) != `{"critical":{}}` {
+		t.Errorf("Unexpected blob %s", blob)
+	}
+
+	_, err = client.GetManifest(context.Background(), "my-app", "sha256-missing.sig")
+	var notFound *ImageNotFoundError
+	if !errors.As(err, &notFound) || notFound.HTTPStatusCode() != http.StatusNotFound {
+		t.Errorf("Expected ImageNotFoundError, got %v", err)
+	}
+
+	if _, err := client.GetBlob(context.Background(), "my-app", "sha256:"+strings.Repeat("c", 64)); err == nil {
+		t.Error("Expected error for an unknown blob")
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+// tagExclusion excludes tags from selection, e.g. floating tags such as "latest"
+type tagExclusion struct {
+	patterns    []*regexp.Regexp
+	prereleases bool
+}
+
+// newTagExclusion compiles the exclude patterns. Patterns must match the whole tag, so a
+// plain tag name (e.g. "latest") excludes exactly that tag. It returns nil when nothing
+// is excluded.
+func newTagExclusion(patterns []string, prereleases bool) (*tagExclusion, error) {
+	if len(patterns) == 0 && !prereleases {
+		return nil, nil
+	}
+
+	exclusion := &tagExclusion{prereleases: prereleases}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude tag pattern %q: %w", pattern, err)
+		}
+		exclusion.patterns = append(exclusion.patterns, re)
+	}
+
+	return exclusion, nil
+}
+
+// excludes reports whether the tag is excluded
+func (e *tagExclusion) excludes(tag string) bool {
+	for _, re := range e.patterns {
+		if re.MatchString(tag) {
+			return true
+		}
+	}
+
+	if e.prereleases {
+		if version, err := semver.NewVersion(tag); err == nil && version.Prerelease() != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// excludeTags returns the images with excluded tags removed. The given images are not
+// modified since they may be shared through the cache.
+func excludeTags(imageDetails []types.ImageDetail, exclusion *tagExclusion) []types.ImageDetail {
+	if exclusion == nil {
+		return imageDetails
+	}
+
+	filtered := make([]types.ImageDetail, 0, len(imageDetails))
+	for _, imageDetail := range imageDetails {
+		var tags []string
+		for _, tag := range imageDetail.ImageTags {
+			if !exclusion.excludes(tag) {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) == 0 {
+			continue
+		}
+
+		imageDetail.ImageTags = tags
+		filtered = append(filtered, imageDetail)
+	}
+
+	return filtered
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+func TestExcludeTags(t *testing.T) {
+	tests := []struct {
+		name         string
+		tags         []string
+		patterns     []string
+		prereleases  bool
+		sortStrategy tagsort.Strategy
+		expected     string
+	}{
+		{
+			name:         "floating tags excluded under lexical sort",
+			tags:         []string{"latest", "stable", "v1.9.0", "v1.10.0"},
+			patterns:     []string{"latest", "stable"},
+			sortStrategy: tagsort.Lexical,
+			expected:     "v1.9.0",
+		},
+		{
+			name:         "floating tags excluded under semver sort",
+			tags:         []string{"latest", "stable", "v1.9.0", "v1.10.0"},
+			patterns:     []string{"latest", "stable"},
+			sortStrategy: tagsort.Semver,
+			expected:     "v1.10.0",
+		},
+		{
+			name:         "patterns match the whole tag",
+			tags:         []string{"stable", "stable-2", "release"},
+			patterns:     []string{"stable"},
+			sortStrategy: tagsort.Lexical,
+			expected:     "stable-2",
+		},
+		{
+			name:         "regex pattern",
+			tags:         []string{"main-abc123", "main-def456", "v1.0.0"},
+			patterns:     []string{`main-[0-9a-f]+`},
+			sortStrategy: tagsort.Lexical,
+			expected:     "v1.0.0",
+		},
+		{
+			name:         "pre-releases excluded",
+			tags:         []string{"v1.3.0-rc.1", "v1.2.0", "v1.1.0"},
+			prereleases:  true,
+			sortStrategy: tagsort.Semver,
+			expected:     "v1.2.0",
+		},
+		{
+			name:         "pre-releases kept by default",
+			tags:         []string{"v1.3.0-rc.1", "v1.2.0", "v1.1.0"},
+			sortStrategy: tagsort.Semver,
+			expected:     "v1.3.0-rc.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exclusion, err := newTagExclusion(tt.patterns, tt.prereleases)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			imageDetails := excludeTags([]types.ImageDetail{{ImageTags: tt.tags}}, exclusion)
+
+			tag, err := selectLatestTag(imageDetails, "test-repo", "", nil, nil, tt.sortStrategy)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if tag != tt.expected {
+				t.Errorf("Expected tag %s, got %s", tt.expected, tag)
+			}
+		})
+	}
+}
+
+func TestExcludeTags_KeepsInput(t *testing.T) {
+	exclusion, err := newTagExclusion([]string{"latest"}, false)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	imageDetails := []types.ImageDetail{
+		{ImageTags: []string{"latest", "v1.0.0"}},
+		{ImageTags: []string{"latest"}},
+	}
+	filtered := excludeTags(imageDetails, exclusion)
+
+	// Images left without tags are dropped
+	if len(filtered) != 1 || len(filtered[0].ImageTags) != 1 || filtered[0].ImageTags[0] != "v1.0.0" {
+		t.Errorf("Expected only v1.0.0 to remain, got %v", filtered)
+	}
+
+	// The input may be shared through the cache and is left untouched
+	if len(imageDetails[0].ImageTags) != 2 {
+		t.Errorf("Expected the input images to be unchanged, got %v", imageDetails[0].ImageTags)
+	}
+}
+
+func TestNewTagExclusion_Invalid(t *testing.T) {
+	if _, err := newTagExclusion([]string{"(latest"}, false); err == nil {
+		t.Error("Expected an error for an invalid pattern but got none")
+	}
+
+	exclusion, err := newTagExclusion(nil, false)
+	if err != nil || exclusion != nil {
+		t.Errorf("Expected no exclusion without patterns, got %v (error: %v)", exclusion, err)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ErrNotECRImage is returned when parsing an image reference that is not in an ECR
+// private registry
+var ErrNotECRImage = errors.New("not an ECR image reference")
+
+// imageURIPattern matches <account>.dkr.ecr[-fips].<region>.amazonaws.com[.cn]/<repository>
+// with an optional tag and digest
+var imageURIPattern = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?/` +
+	`([a-z0-9]+(?:[._/-][a-z0-9]+)*)(?::([\w][\w.-]{0,127}))?(?:@(sha256:[a-f0-9]{64}))?$`)
+
+// ImageURI is an image reference in an ECR private registry
+type ImageURI struct {
+	// AccountID is the AWS account of the registry
+	AccountID string
+
+	// Region is the AWS region of the registry
+	Region string
+
+	// RepositoryName is the name of the repository
+	RepositoryName string
+
+	// Tag is the tag of the image, empty when the reference has none
+	Tag string
+
+	// Digest is the digest of the image, empty when the reference has none
+	Digest string
+}
+
+// ParseImageURI parses an ECR image reference, e.g.
+// "123456789012.dkr.ecr.us-west-2.amazonaws.com/team/my-app:v1.2.0". It returns an error
+// wrapping ErrNotECRImage for references to other registries.
+func ParseImageURI(image string) (ImageURI, error) {
+	match := imageURIPattern.FindStringSubmatch(image)
+	if match == nil {
+		return ImageURI{}, fmt.Errorf("%w: %q", ErrNotECRImage, image)
+	}
+	return ImageURI{
+		AccountID:      match[1],
+		Region:         match[2],
+		RepositoryName: match[3],
+		Tag:            match[4],
+		Digest:         match[5],
+	}, nil
+}
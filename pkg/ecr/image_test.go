@@ -0,0 +1,88 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseImageURI(t *testing.T) {
+	digest := "sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name     string
+		image    string
+		expected ImageURI
+		notECR   bool
+	}{
+		{
+			name:     "tag",
+			image:    "123456789012.dkr.ecr.us-west-2.amazonaws.com/my-app:v1.2.0",
+			expected: ImageURI{AccountID: "123456789012", Region: "us-west-2", RepositoryName: "my-app", Tag: "v1.2.0"},
+		},
+		{
+			name:     "namespaced repository",
+			image:    "123456789012.dkr.ecr.eu-central-1.amazonaws.com/team/backend/api:1.0",
+			expected: ImageURI{AccountID: "123456789012", Region: "eu-central-1", RepositoryName: "team/backend/api", Tag: "1.0"},
+		},
+		{
+			name:     "no tag",
+			image:    "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app",
+			expected: ImageURI{AccountID: "123456789012", Region: "us-east-1", RepositoryName: "my-app"},
+		},
+		{
+			name:     "tag and digest",
+			image:    "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:v1@" + digest,
+			expected: ImageURI{AccountID: "123456789012", Region: "us-east-1", RepositoryName: "my-app", Tag: "v1", Digest: digest},
+		},
+		{
+			name:     "FIPS endpoint",
+			image:    "123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com/my-app:v1",
+			expected: ImageURI{AccountID: "123456789012", Region: "us-gov-west-1", RepositoryName: "my-app", Tag: "v1"},
+		},
+		{
+			name:     "China region",
+			image:    "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn/my-app:v1",
+			expected: ImageURI{AccountID: "123456789012", Region: "cn-north-1", RepositoryName: "my-app", Tag: "v1"},
+		},
+		{name: "Docker Hub", image: "nginx:1.27", notECR: true},
+		{name: "GHCR", image: "ghcr.io/example/my-app:v1", notECR: true},
+		{name: "ECR Public", image: "public.ecr.aws/example/my-app:v1", notECR: true},
+		{name: "tag only", image: "v1.2.0", notECR: true},
+		{name: "empty", image: "", notECR: true},
+		{name: "short account", image: "12345.dkr.ecr.us-east-1.amazonaws.com/my-app:v1", notECR: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri, err := ParseImageURI(tt.image)
+			if tt.notECR {
+				if !errors.Is(err, ErrNotECRImage) {
+					t.Errorf("Expected ErrNotECRImage, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseImageURI() error = %v", err)
+			}
+			if uri != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, uri)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+// Media types of multi-platform images
+const (
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIImageIndex      = "application/vnd.oci.image.index.v1+json"
+)
+
+// imageConfigMediaTypes are the artifact media types of container images; other artifact
+// media types are attestations, signatures, SBOMs or other non-runnable artifacts
+var imageConfigMediaTypes = map[string]bool{
+	"application/vnd.docker.container.image.v1+json": true,
+	"application/vnd.oci.image.config.v1+json":       true,
+}
+
+// artifactTagRegex matches the tags cosign and similar tools give to the attestations,
+// signatures and SBOMs of an image, e.g. "sha256-<digest>.att"
+var artifactTagRegex = regexp.MustCompile(`^sha256-[0-9a-f]{64}\.(att|sig|sbom)$`)
+
+// isManifestList reports whether an image is a multi-platform manifest list or image index
+func isManifestList(imageDetail types.ImageDetail) bool {
+	if imageDetail.ImageManifestMediaType == nil {
+		return false
+	}
+
+	mediaType := *imageDetail.ImageManifestMediaType
+	return mediaType == mediaTypeDockerManifestList || mediaType == mediaTypeOCIImageIndex
+}
+
+// isArtifact reports whether an image is an artifact such as an attestation rather than a
+// container image. Images without an artifact media type, e.g. manifest lists, are not.
+func isArtifact(imageDetail types.ImageDetail) bool {
+	return imageDetail.ArtifactMediaType != nil && *imageDetail.ArtifactMediaType != "" &&
+		!imageConfigMediaTypes[*imageDetail.ArtifactMediaType]
+}
+
+// filterByManifestType returns the images that may be selected: only manifest lists when
+// manifestListsOnly is set, and without artifacts and their tags when excludeArtifacts is
+// set. The given images are not modified since they may be shared through the cache.
+func filterByManifestType(imageDetails []types.ImageDetail, manifestListsOnly, excludeArtifacts bool) []types.ImageDetail {
+	if !manifestListsOnly && !excludeArtifacts {
+		return imageDetails
+	}
+
+	filtered := make([]types.ImageDetail, 0, len(imageDetails))
+	for _, imageDetail := range imageDetails {
+		if manifestListsOnly && !isManifestList(imageDetail) {
+			continue
+		}
+		if !excludeArtifacts {
+			filtered = append(filtered, imageDetail)
+			continue
+		}
+		if isArtifact(imageDetail) {
+			continue
+		}
+
+		var tags []string
+		for _, tag := range imageDetail.ImageTags {
+			if !artifactTagRegex.MatchString(tag) {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) == 0 {
+			continue
+		}
+
+		imageDetail.ImageTags = tags
+		filtered = append(filtered, imageDetail)
+	}
+
+	return filtered
+}
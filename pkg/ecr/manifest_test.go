@@ -0,0 +1,137 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+// mixedImageDetails returns a multi-platform release, a newer single-platform build, and
+// the in-toto attestation cosign pushed last for the release
+func mixedImageDetails() []types.ImageDetail {
+	now := time.Now()
+	return []types.ImageDetail{
+		{
+			ImageTags:              []string{"v1.2.0"},
+			ImageManifestMediaType: aws.String(mediaTypeOCIImageIndex),
+			ImagePushedAt:          aws.Time(now.Add(-3 * time.Hour)),
+		},
+		{
+			ImageTags:              []string{"v1.1.0"},
+			ImageManifestMediaType: aws.String(mediaTypeDockerManifestList),
+			ImagePushedAt:          aws.Time(now.Add(-5 * time.Hour)),
+		},
+		{
+			ImageTags:              []string{"v1.3.0-amd64"},
+			ImageManifestMediaType: aws.String("application/vnd.docker.distribution.manifest.v2+json"),
+			ArtifactMediaType:      aws.String("application/vnd.docker.container.image.v1+json"),
+			ImagePushedAt:          aws.Time(now.Add(-2 * time.Hour)),
+		},
+		{
+			ImageTags:              []string{"sha256-" + strings.Repeat("a", 64) + ".att"},
+			ImageManifestMediaType: aws.String("application/vnd.oci.image.manifest.v1+json"),
+			ArtifactMediaType:      aws.String("application/vnd.dsse.envelope.v1+json"),
+			ImagePushedAt:          aws.Time(now.Add(-time.Hour)),
+		},
+	}
+}
+
+func TestFilterByManifestType(t *testing.T) {
+	tests := []struct {
+		name              string
+		imageDetails      []types.ImageDetail
+		manifestListsOnly bool
+		excludeArtifacts  bool
+		sortStrategy      tagsort.Strategy
+		expected          string
+	}{
+		{
+			name:         "every image by default",
+			imageDetails: mixedImageDetails(),
+			sortStrategy: tagsort.PushTime,
+			expected:     "sha256-" + strings.Repeat("a", 64) + ".att",
+		},
+		{
+			name:             "artifacts excluded",
+			imageDetails:     mixedImageDetails(),
+			excludeArtifacts: true,
+			sortStrategy:     tagsort.PushTime,
+			expected:         "v1.3.0-amd64",
+		},
+		{
+			name:              "manifest lists only",
+			imageDetails:      mixedImageDetails(),
+			manifestListsOnly: true,
+			sortStrategy:      tagsort.Semver,
+			expected:          "v1.2.0",
+		},
+		{
+			name:              "manifest lists only under push time sort",
+			imageDetails:      mixedImageDetails(),
+			manifestListsOnly: true,
+			sortStrategy:      tagsort.PushTime,
+			expected:          "v1.2.0",
+		},
+		{
+			name: "signature tags of an image without an artifact media type",
+			imageDetails: []types.ImageDetail{
+				{ImageTags: []string{"v1.0.0"}},
+				{ImageTags: []string{"sha256-" + strings.Repeat("b", 64) + ".sig"}},
+			},
+			excludeArtifacts: true,
+			sortStrategy:     tagsort.Lexical,
+			expected:         "v1.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imageDetails := filterByManifestType(tt.imageDetails, tt.manifestListsOnly, tt.excludeArtifacts)
+
+			tag, err := selectLatestTag(imageDetails, "test-repo", "", nil, nil, tt.sortStrategy)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if tag != tt.expected {
+				t.Errorf("Expected tag %s, got %s", tt.expected, tag)
+			}
+		})
+	}
+}
+
+func TestFilterByManifestType_KeepsInput(t *testing.T) {
+	imageDetails := []types.ImageDetail{
+		{ImageTags: []string{"v1.0.0", "sha256-" + strings.Repeat("c", 64) + ".sbom"}},
+	}
+	filtered := filterByManifestType(imageDetails, false, true)
+
+	if len(filtered) != 1 || len(filtered[0].ImageTags) != 1 || filtered[0].ImageTags[0] != "v1.0.0" {
+		t.Errorf("Expected only v1.0.0 to remain, got %v", filtered)
+	}
+
+	// The input may be shared through the cache and is left untouched
+	if len(imageDetails[0].ImageTags) != 2 {
+		t.Errorf("Expected the input images to be unchanged, got %v", imageDetails[0].ImageTags)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+// selectByPriority returns the first tag of the priority list that exists in the given
+// images and matches the tag filter. Tags that are not in the list are never selected.
+func selectByPriority(imageDetails []types.ImageDetail, repositoryName, tagFilter string, priority []string) (string, error) {
+	var tagRegex *regexp.Regexp
+	if tagFilter != "" {
+		var err error
+		tagRegex, err = regexp.Compile(tagFilter)
+		if err != nil {
+			return "", fmt.Errorf("invalid tag filter regex: %w", err)
+		}
+	}
+
+	existing := make(map[string]bool)
+	for _, imageDetail := range imageDetails {
+		for _, tag := range imageDetail.ImageTags {
+			if tag != "" && (tagRegex == nil || tagRegex.MatchString(tag)) {
+				existing[tag] = true
+			}
+		}
+	}
+
+	for _, tag := range priority {
+		if existing[tag] {
+			return tag, nil
+		}
+	}
+
+	return "", fmt.Errorf("no prioritized tags found in repository %s", repositoryName)
+}
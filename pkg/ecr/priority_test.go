@@ -0,0 +1,98 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+func TestSelectByPriority(t *testing.T) {
+	priority := []string{"stable", "beta", "edge"}
+
+	tests := []struct {
+		name        string
+		tags        []string
+		tagFilter   string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "all prioritized tags exist",
+			tags:     []string{"edge", "beta", "stable", "v1.2.0"},
+			expected: "stable",
+		},
+		{
+			name:     "highest priority tag missing",
+			tags:     []string{"edge", "beta", "v1.2.0"},
+			expected: "beta",
+		},
+		{
+			name:     "only lowest priority tag exists",
+			tags:     []string{"edge", "v1.2.0", "latest"},
+			expected: "edge",
+		},
+		{
+			name:        "no prioritized tags exist",
+			tags:        []string{"v1.2.0", "latest"},
+			expectError: true,
+		},
+		{
+			name:      "tag filter applied",
+			tags:      []string{"edge", "beta", "stable"},
+			tagFilter: "^(beta|edge)$",
+			expected:  "beta",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imageDetails := []types.ImageDetail{{ImageTags: tt.tags}}
+
+			tag, err := selectByPriority(imageDetails, "test-repo", tt.tagFilter, priority)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got tag %s", tag)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if tag != tt.expected {
+				t.Errorf("Expected tag %s, got %s", tt.expected, tag)
+			}
+		})
+	}
+}
+
+func TestSelectByPriority_TagsAcrossImages(t *testing.T) {
+	imageDetails := []types.ImageDetail{
+		{ImageTags: []string{"v1.3.0", "edge"}},
+		{ImageTags: []string{"v1.2.0", "beta"}},
+		{ImageTags: []string{""}},
+	}
+
+	tag, err := selectByPriority(imageDetails, "test-repo", "", []string{"stable", "beta", "edge"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if tag != "beta" {
+		t.Errorf("Expected tag beta, got %s", tag)
+	}
+}
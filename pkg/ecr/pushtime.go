@@ -0,0 +1,62 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+// filterByPushAge returns the images pushed at least minAge and at most maxAge before
+// now. A zero bound is not applied. Images without a push time are excluded as soon as
+// either bound is set.
+func filterByPushAge(imageDetails []types.ImageDetail, now time.Time, minAge, maxAge time.Duration) []types.ImageDetail {
+	if minAge == 0 && maxAge == 0 {
+		return imageDetails
+	}
+
+	var filtered []types.ImageDetail
+	for _, imageDetail := range imageDetails {
+		if imageDetail.ImagePushedAt == nil {
+			continue
+		}
+
+		age := now.Sub(*imageDetail.ImagePushedAt)
+		if minAge > 0 && age < minAge {
+			continue
+		}
+		if maxAge > 0 && age > maxAge {
+			continue
+		}
+		filtered = append(filtered, imageDetail)
+	}
+
+	return filtered
+}
+
+// validatePushAgeWindow reports an error for a window that cannot match any image
+func validatePushAgeWindow(minAge, maxAge time.Duration) error {
+	if minAge < 0 || maxAge < 0 {
+		return fmt.Errorf("push age bounds must not be negative")
+	}
+	if maxAge > 0 && minAge > maxAge {
+		return fmt.Errorf("minimum push age %s exceeds maximum push age %s", minAge, maxAge)
+	}
+	return nil
+}
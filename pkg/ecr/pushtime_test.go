@@ -0,0 +1,172 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+func TestSelectLatestTag_PushTime(t *testing.T) {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// Images listed out of push order, with tags that do not sort meaningfully
+	imageDetails := []types.ImageDetail{
+		{ImageTags: []string{"build-f00d"}, ImagePushedAt: aws.Time(base.Add(2 * time.Hour))},
+		{ImageTags: []string{"build-zzzz"}, ImagePushedAt: aws.Time(base)},
+		{ImageTags: []string{"build-abc1", "build-0bcd", "latest"}, ImagePushedAt: aws.Time(base.Add(5 * time.Hour))},
+		{ImageTags: []string{"build-9999"}, ImagePushedAt: aws.Time(base.Add(time.Hour))},
+		{ImageTags: []string{"build-ffff"}},
+	}
+
+	tests := []struct {
+		name      string
+		tagFilter string
+		expected  string
+	}{
+		{
+			name:     "newest image",
+			expected: "latest",
+		},
+		{
+			name:      "tags sharing the newest image",
+			tagFilter: "^build-",
+			expected:  "build-abc1",
+		},
+		{
+			name:      "filter applied before push time",
+			tagFilter: "^build-(f00d|9999|zzzz)$",
+			expected:  "build-f00d",
+		},
+		{
+			name:      "image without push time",
+			tagFilter: "^build-(ffff|zzzz)$",
+			expected:  "build-zzzz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, err := selectLatestTag(imageDetails, "test-repo", tt.tagFilter, nil, nil, tagsort.PushTime)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if tag != tt.expected {
+				t.Errorf("Expected tag %s, got %s", tt.expected, tag)
+			}
+		})
+	}
+}
+
+func TestFilterByPushAge(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// An old tag that sorts above the current releases, as seen after a lexical sort
+	imageDetails := []types.ImageDetail{
+		{ImageTags: []string{"v9.0.0-legacy"}, ImagePushedAt: aws.Time(now.Add(-3 * 365 * 24 * time.Hour))},
+		{ImageTags: []string{"v1.2.0"}, ImagePushedAt: aws.Time(now.Add(-48 * time.Hour))},
+		{ImageTags: []string{"v1.3.0"}, ImagePushedAt: aws.Time(now.Add(-2 * time.Hour))},
+		{ImageTags: []string{"v1.4.0"}, ImagePushedAt: aws.Time(now.Add(-10 * time.Minute))},
+		{ImageTags: []string{"v8.0.0-unknown"}},
+	}
+
+	tests := []struct {
+		name     string
+		minAge   time.Duration
+		maxAge   time.Duration
+		expected string
+	}{
+		{
+			name:     "no window",
+			expected: "v9.0.0-legacy",
+		},
+		{
+			name:     "maximum age excludes old tags",
+			maxAge:   30 * 24 * time.Hour,
+			expected: "v1.4.0",
+		},
+		{
+			name:     "minimum age excludes fresh tags",
+			minAge:   time.Hour,
+			maxAge:   30 * 24 * time.Hour,
+			expected: "v1.3.0",
+		},
+		{
+			name:     "minimum age only",
+			minAge:   24 * time.Hour,
+			expected: "v9.0.0-legacy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered := filterByPushAge(imageDetails, now, tt.minAge, tt.maxAge)
+			tag, err := selectLatestTag(filtered, "test-repo", "", nil, nil, tagsort.Lexical)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if tag != tt.expected {
+				t.Errorf("Expected tag %s, got %s", tt.expected, tag)
+			}
+		})
+	}
+}
+
+func TestFilterByPushAge_NoImagesInWindow(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	imageDetails := []types.ImageDetail{
+		{ImageTags: []string{"v1.0.0"}, ImagePushedAt: aws.Time(now.Add(-48 * time.Hour))},
+	}
+
+	filtered := filterByPushAge(imageDetails, now, 0, time.Hour)
+	if _, err := selectLatestTag(filtered, "test-repo", "", nil, nil, tagsort.Lexical); err == nil {
+		t.Error("Expected error when no image was pushed within the window")
+	}
+}
+
+func TestValidatePushAgeWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		minAge  time.Duration
+		maxAge  time.Duration
+		wantErr bool
+	}{
+		{name: "no window"},
+		{name: "minimum only", minAge: time.Hour},
+		{name: "maximum only", maxAge: time.Hour},
+		{name: "valid window", minAge: time.Hour, maxAge: 24 * time.Hour},
+		{name: "inverted window", minAge: 24 * time.Hour, maxAge: time.Hour, wantErr: true},
+		{name: "negative bound", minAge: -time.Hour, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePushAgeWindow(tt.minAge, tt.maxAge)
+			if tt.wantErr && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
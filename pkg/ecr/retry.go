@@ -0,0 +1,56 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+const (
+	// maxAttempts bounds the attempts of a throttled or transiently failing ECR request,
+	// including the first one
+	maxAttempts = 4
+
+	// retryBaseDelay is the delay before the first retry of an ECR request. It doubles with
+	// every attempt.
+	retryBaseDelay = 500 * time.Millisecond
+)
+
+// IsTransientError reports whether an ECR request failed because of throttling (e.g.
+// ThrottlingException) or another transient error such as a server or connection error
+func IsTransientError(err error) bool {
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
+
+// newRetryer returns the AWS SDK retryer of ECR requests: throttled and transiently failing
+// requests are attempted up to maxAttempts times, waiting baseDelay before the first retry and
+// doubling the delay with every further attempt. The SDK's retry quota is disabled so that
+// throttling across reconciles does not fail requests before they were retried.
+func newRetryer(baseDelay time.Duration) aws.Retryer {
+	return retry.NewStandard(func(o *retry.StandardOptions) {
+		o.MaxAttempts = maxAttempts
+		o.Retryables = retry.DefaultRetryables
+		o.Backoff = retry.BackoffDelayerFunc(func(attempt int, _ error) (time.Duration, error) {
+			return baseDelay << (attempt - 1), nil
+		})
+		o.RateLimiter = ratelimit.None
+	})
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_GetLatestTag_ThrottlingRetry(t *testing.T) {
+	fake := &fakeECR{tags: []string{"v1.0.0"}}
+	fake.throttles.Store(2)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	tag, err := newCachedClient(server.URL, nil, false).GetLatestTag(context.Background(), "my-app", "")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if tag != "v1.0.0" {
+		t.Errorf("Expected tag v1.0.0, got %s", tag)
+	}
+	if requests := fake.requests.Load(); requests != 3 {
+		t.Errorf("Expected 2 throttled requests and a successful one, got %d requests", requests)
+	}
+}
+
+func TestClient_GetLatestTag_ThrottlingExhausted(t *testing.T) {
+	fake := &fakeECR{}
+	fake.throttled.Store(true)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	_, err := newCachedClient(server.URL, nil, false).GetLatestTag(context.Background(), "my-app", "")
+	if err == nil {
+		t.Fatal("Expected error but got none")
+	}
+	if !IsTransientError(err) {
+		t.Errorf("Expected a transient error, got: %v", err)
+	}
+	if requests := fake.requests.Load(); requests != maxAttempts {
+		t.Errorf("Expected %d attempts, got %d", maxAttempts, requests)
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	if IsTransientError(errors.New("no images found in repository my-app")) {
+		t.Error("Expected a permanent error not to be transient")
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// TagSelector ranks tags by the result of a CEL expression. The expression is
+// evaluated per tag with the variables:
+//
+//   - tag: the tag string
+//   - groups: the tag filter's capture groups (groups[0] is the full match)
+//   - pushedAt: when the image was pushed
+//
+// It must return a comparable value (int, uint, double, string, bool, timestamp or
+// duration) or a list of them, compared element by element. The tag with the
+// greatest result is selected.
+type TagSelector struct {
+	expression string
+	program    cel.Program
+}
+
+// NewTagSelector compiles a tag select expression
+func NewTagSelector(expression string) (*TagSelector, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("tag", cel.StringType),
+		cel.Variable("groups", cel.ListType(cel.StringType)),
+		cel.Variable("pushedAt", cel.TimestampType),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid select expression: %w", issues.Err())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid select expression: %w", err)
+	}
+
+	return &TagSelector{
+		expression: expression,
+		program:    program,
+	}, nil
+}
+
+// Evaluate returns the sort key of a tag
+func (s *TagSelector) Evaluate(tag string, groups []string, pushedAt time.Time) (ref.Val, error) {
+	if groups == nil {
+		groups = []string{}
+	}
+
+	result, _, err := s.program.Eval(map[string]interface{}{
+		"tag":      tag,
+		"groups":   groups,
+		"pushedAt": pushedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate select expression for tag %s: %w", tag, err)
+	}
+
+	return result, nil
+}
+
+// compareSortKeys compares two expression results, returning -1, 0 or 1
+func compareSortKeys(a, b ref.Val) (int, error) {
+	aList, aIsList := a.(traits.Lister)
+	bList, bIsList := b.(traits.Lister)
+	if aIsList || bIsList {
+		if !aIsList || !bIsList {
+			return 0, fmt.Errorf("select expression returned mixed types %s and %s", a.Type().TypeName(), b.Type().TypeName())
+		}
+		return compareLists(aList, bList)
+	}
+
+	if a.Type() != b.Type() {
+		return 0, fmt.Errorf("select expression returned mixed types %s and %s", a.Type().TypeName(), b.Type().TypeName())
+	}
+
+	comparer, ok := a.(traits.Comparer)
+	if !ok {
+		return 0, fmt.Errorf("select expression returned non-comparable type %s", a.Type().TypeName())
+	}
+
+	result := comparer.Compare(b)
+	if types.IsError(result) {
+		return 0, fmt.Errorf("failed to compare select expression results: %v", result)
+	}
+
+	return int(result.(types.Int)), nil
+}
+
+// compareLists compares two lists element by element; a shorter list sorts first
+// when it is a prefix of the other
+func compareLists(a, b traits.Lister) (int, error) {
+	aSize := int(a.Size().(types.Int))
+	bSize := int(b.Size().(types.Int))
+
+	for i := 0; i < aSize && i < bSize; i++ {
+		cmp, err := compareSortKeys(a.Get(types.Int(i)), b.Get(types.Int(i)))
+		if err != nil {
+			return 0, err
+		}
+		if cmp != 0 {
+			return cmp, nil
+		}
+	}
+
+	switch {
+	case aSize < bSize:
+		return -1, nil
+	case aSize > bSize:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
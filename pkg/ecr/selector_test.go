@@ -0,0 +1,140 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+func TestNewTagSelector(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		shouldErr  bool
+	}{
+		{
+			name:       "valid expression",
+			expression: "int(groups[1])",
+		},
+		{
+			name:       "undeclared variable",
+			expression: "digest",
+			shouldErr:  true,
+		},
+		{
+			name:       "syntax error",
+			expression: "int(",
+			shouldErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTagSelector(tt.expression)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestSelectLatestTag_SelectExpression(t *testing.T) {
+	now := time.Now()
+	imageDetails := []types.ImageDetail{
+		{ImageTags: []string{"build-9-2024.01"}, ImagePushedAt: aws.Time(now.Add(-3 * time.Hour))},
+		{ImageTags: []string{"build-10-2023.12"}, ImagePushedAt: aws.Time(now.Add(-2 * time.Hour))},
+		{ImageTags: []string{"build-2-2024.02"}, ImagePushedAt: aws.Time(now.Add(-4 * time.Hour))},
+		{ImageTags: []string{"latest"}, ImagePushedAt: aws.Time(now.Add(-1 * time.Hour))},
+	}
+
+	tests := []struct {
+		name       string
+		tagFilter  string
+		expression string
+		expected   string
+		shouldErr  bool
+	}{
+		{
+			name:       "numeric capture group",
+			tagFilter:  `^build-(\d+)-`,
+			expression: "int(groups[1])",
+			expected:   "build-10-2023.12",
+		},
+		{
+			name:       "list of keys",
+			tagFilter:  `^build-(\d+)-(\d+)\.(\d+)$`,
+			expression: "[int(groups[2]), int(groups[3]), int(groups[1])]",
+			expected:   "build-2-2024.02",
+		},
+		{
+			name:       "push time",
+			tagFilter:  "",
+			expression: "pushedAt",
+			expected:   "latest",
+		},
+		{
+			name:       "ties broken lexically",
+			tagFilter:  "^build-",
+			expression: "-tag.size()",
+			expected:   "build-9-2024.01",
+		},
+		{
+			name:       "non-comparable result",
+			tagFilter:  "^build-",
+			expression: "{'tag': tag}",
+			shouldErr:  true,
+		},
+		{
+			name:       "evaluation error",
+			tagFilter:  "",
+			expression: "int(tag)",
+			shouldErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := NewTagSelector(tt.expression)
+			if err != nil {
+				t.Fatalf("Expected no error compiling expression but got: %v", err)
+			}
+
+			tag, err := selectLatestTag(imageDetails, "test-repo", tt.tagFilter, selector, nil, tagsort.Semver)
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if tag != tt.expected {
+				t.Errorf("Expected tag %s, got %s", tt.expected, tag)
+			}
+		})
+	}
+}
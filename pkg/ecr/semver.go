@@ -0,0 +1,42 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// parseVersionConstraint parses a semantic version range such as ">=1.2.0 <2.0.0"
+func parseVersionConstraint(constraint string) (*semver.Constraints, error) {
+	constraints, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint: %w", err)
+	}
+	return constraints, nil
+}
+
+// satisfiesConstraint reports whether a tag is a semantic version within the constraint.
+// Tags that are not valid semantic versions never satisfy it.
+func satisfiesConstraint(tag string, constraints *semver.Constraints) bool {
+	version, err := semver.NewVersion(tag)
+	if err != nil {
+		return false
+	}
+	return constraints.Check(version)
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+func TestSelectLatestTag_Semver(t *testing.T) {
+	// The ordering itself is covered by the tagsort package; this checks that the tags of
+	// all images are ranked after the tag filter is applied
+	imageDetails := []types.ImageDetail{
+		{ImageTags: []string{"v1.9.0", "latest"}},
+		{ImageTags: []string{"v2.0.0"}},
+		{ImageTags: []string{"v1.10.0-rc1"}},
+	}
+
+	tests := []struct {
+		name      string
+		tagFilter string
+		expected  string
+	}{
+		{
+			name:     "all images",
+			expected: "v2.0.0",
+		},
+		{
+			name:      "tag filter",
+			tagFilter: `^v1\.`,
+			expected:  "v1.10.0-rc1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, err := selectLatestTag(imageDetails, "test-repo", tt.tagFilter, nil, nil, tagsort.Semver)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if tag != tt.expected {
+				t.Errorf("Expected tag %s, got %s", tt.expected, tag)
+			}
+		})
+	}
+}
+
+func TestSelectLatestTag_VersionConstraint(t *testing.T) {
+	tests := []struct {
+		name        string
+		tags        []string
+		tagFilter   string
+		constraint  string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:       "upper bound excluded",
+			tags:       []string{"v1.2.0", "v1.9.3", "v2.0.0", "v2.1.0"},
+			constraint: ">=1.2.0 <2.0.0",
+			expected:   "v1.9.3",
+		},
+		{
+			name:       "lower bound included",
+			tags:       []string{"v1.1.9", "v1.2.0"},
+			constraint: ">=1.2.0 <2.0.0",
+			expected:   "v1.2.0",
+		},
+		{
+			name:       "highest version wins over lexical order",
+			tags:       []string{"v1.9.0", "v1.10.0"},
+			constraint: "1.x",
+			expected:   "v1.10.0",
+		},
+		{
+			name:       "non-semver tags excluded",
+			tags:       []string{"latest", "v1.5.0", "main-abc123", "zzz"},
+			constraint: ">=1.0.0",
+			expected:   "v1.5.0",
+		},
+		{
+			name:       "applied after tag filter",
+			tags:       []string{"v1.4.0", "v1.5.0-rc1", "v1.3.0"},
+			tagFilter:  `^v1\.[35]`,
+			constraint: "<2.0.0",
+			expected:   "v1.3.0",
+		},
+		{
+			name:        "no tag within constraint",
+			tags:        []string{"v2.0.0", "latest"},
+			constraint:  "<2.0.0",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			constraints, err := parseVersionConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("Failed to parse constraint: %v", err)
+			}
+
+			imageDetails := []types.ImageDetail{{ImageTags: tt.tags}}
+
+			tag, err := selectLatestTag(imageDetails, "test-repo", tt.tagFilter, nil, constraints, tagsort.Lexical)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got tag %s", tag)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if tag != tt.expected {
+				t.Errorf("Expected tag %s, got %s", tt.expected, tag)
+			}
+		})
+	}
+}
+
+func TestParseVersionConstraint_Invalid(t *testing.T) {
+	if _, err := parseVersionConstraint("not a range"); err == nil {
+		t.Errorf("Expected error for invalid constraint")
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gar
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// Scope is the OAuth2 scope requested for the Artifact Registry API
+const Scope = "https://www.googleapis.com/auth/cloud-platform.read-only"
+
+// TokenSource is a token source for Application Default Credentials meant to be shared
+// by all clients. The credentials are looked up on first use, so a controller outside
+// Google Cloud does not fail at startup, and tokens are reused until shortly before they
+// expire.
+type TokenSource struct {
+	mu          sync.Mutex
+	tokenSource oauth2.TokenSource
+}
+
+// NewTokenSource creates a shared Application Default Credentials token source
+func NewTokenSource() *TokenSource {
+	return &TokenSource{}
+}
+
+// Token implements oauth2.TokenSource
+func (s *TokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tokenSource == nil {
+		// The token source refreshes tokens in the background of later calls, so it must
+		// not be bound to the context of a single request
+		tokenSource, err := google.DefaultTokenSource(context.Background(), Scope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find Application Default Credentials: %w", err)
+		}
+		s.tokenSource = tokenSource
+	}
+
+	return s.tokenSource.Token()
+}
@@ -0,0 +1,325 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gar lists tags of Docker images in Google Artifact Registry, including gcr.io
+// repositories hosted by Artifact Registry
+package gar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+// DefaultEndpoint is the Artifact Registry API endpoint
+const DefaultEndpoint = "https://artifactregistry.googleapis.com"
+
+// pageSize is the number of versions requested per page
+const pageSize = 1000
+
+// Client lists tags of Docker images in Artifact Registry through the Artifact Registry
+// API, authenticating with Application Default Credentials unless another token source
+// is given
+type Client struct {
+	endpoint     string
+	httpClient   *http.Client
+	tokenSource  oauth2.TokenSource
+	sortStrategy tagsort.Strategy
+}
+
+// Option configures optional behavior of a Client
+type Option func(*Client)
+
+// WithSortStrategy orders tags by the given strategy instead of lexical order
+func WithSortStrategy(strategy tagsort.Strategy) Option {
+	return func(c *Client) {
+		c.sortStrategy = strategy
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to talk to the API
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTokenSource authenticates with the given token source instead of a new
+// Application Default Credentials token source, e.g. a TokenSource shared by all clients
+func WithTokenSource(tokenSource oauth2.TokenSource) Option {
+	return func(c *Client) {
+		c.tokenSource = tokenSource
+	}
+}
+
+// WithEndpoint talks to another API endpoint than DefaultEndpoint, e.g. a test server
+func WithEndpoint(endpoint string) Option {
+	return func(c *Client) {
+		c.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// NewClient creates a new Artifact Registry client
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		endpoint:   DefaultEndpoint,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.tokenSource == nil {
+		c.tokenSource = NewTokenSource()
+	}
+
+	return c
+}
+
+// Image identifies a Docker image in an Artifact Registry repository
+type Image struct {
+	// Project is the Google Cloud project of the repository
+	Project string
+
+	// Location is the region or multi-region of the repository (e.g. "us-central1")
+	Location string
+
+	// Repository is the name of the repository
+	Repository string
+
+	// Name is the name of the image in the repository, possibly with slashes
+	Name string
+}
+
+// RepositoryName returns the path of the image in its registry host, e.g.
+// "my-project/my-repo/my-app" for us-docker.pkg.dev/my-project/my-repo/my-app
+func (i Image) RepositoryName() string {
+	return i.Project + "/" + i.Repository + "/" + i.Name
+}
+
+// packagePath returns the API resource name of the image's package. Slashes in the image
+// name are escaped, as package IDs are a single path segment.
+func (i Image) packagePath() string {
+	return fmt.Sprintf("v1/projects/%s/locations/%s/repositories/%s/packages/%s",
+		url.PathEscape(i.Project), url.PathEscape(i.Location), url.PathEscape(i.Repository),
+		url.PathEscape(i.Name))
+}
+
+// imageVersion is an image version with the tags pointing to it
+type imageVersion struct {
+	digest     string
+	createTime time.Time
+	tags       []string
+}
+
+// GetLatestTag retrieves the latest tag of the image
+func (c *Client) GetLatestTag(ctx context.Context, image Image, tagFilter string) (string, error) {
+	latestTags, err := c.GetLatestTags(ctx, image, []string{tagFilter})
+	if err != nil {
+		return "", err
+	}
+
+	return latestTags[tagFilter], nil
+}
+
+// GetLatestTags retrieves the latest tag for each of the given tag filters, listing the
+// image's versions only once. The result is keyed by tag filter.
+func (c *Client) GetLatestTags(ctx context.Context, image Image, tagFilters []string) (map[string]string, error) {
+	// Validate the sort strategy before listing versions so invalid configurations fail fast
+	if err := tagsort.Validate(c.sortStrategy, tagsort.Semver, tagsort.PushTime); err != nil {
+		return nil, err
+	}
+
+	versions, err := c.listVersions(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []tagsort.Candidate
+	for _, version := range versions {
+		for _, tag := range version.tags {
+			candidates = append(candidates, tagsort.Candidate{Tag: tag, PushedAt: version.createTime})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no tags found in repository %s", image.RepositoryName())
+	}
+
+	latestTags := make(map[string]string, len(tagFilters))
+	for _, tagFilter := range tagFilters {
+		if _, done := latestTags[tagFilter]; done {
+			continue
+		}
+
+		tag, err := tagsort.Select(candidates, tagFilter, c.sortStrategy)
+		if err != nil {
+			return nil, fmt.Errorf("%w in repository %s", err, image.RepositoryName())
+		}
+		latestTags[tagFilter] = tag
+	}
+
+	return latestTags, nil
+}
+
+// GetImageDigest returns the digest of the image version the tag points to
+func (c *Client) GetImageDigest(ctx context.Context, image Image, tag string) (string, error) {
+	versions, err := c.listVersions(ctx, image)
+	if err != nil {
+		return "", err
+	}
+
+	for _, version := range versions {
+		for _, versionTag := range version.tags {
+			if versionTag == tag {
+				return version.digest, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("tag %s not found in repository %s", tag, image.RepositoryName())
+}
+
+// versionList is a page of the versions.list response
+type versionList struct {
+	Versions []struct {
+		Name        string    `json:"name"`
+		CreateTime  time.Time `json:"createTime"`
+		RelatedTags []struct {
+			Name string `json:"name"`
+		} `json:"relatedTags"`
+	} `json:"versions"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// listVersions lists all versions of the image with their tags, following page tokens
+func (c *Client) listVersions(ctx context.Context, image Image) ([]imageVersion, error) {
+	var versions []imageVersion
+	pageToken := ""
+
+	for {
+		query := url.Values{}
+		query.Set("view", "FULL")
+		query.Set("pageSize", fmt.Sprint(pageSize))
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		var page versionList
+		if err := c.get(ctx, image.packagePath()+"/versions?"+query.Encode(), &page); err != nil {
+			return nil, fmt.Errorf("failed to list versions of %s: %w", image.RepositoryName(), err)
+		}
+
+		for _, v := range page.Versions {
+			version := imageVersion{
+				digest:     lastSegment(v.Name, "/versions/"),
+				createTime: v.CreateTime,
+			}
+			for _, tag := range v.RelatedTags {
+				version.tags = append(version.tags, lastSegment(tag.Name, "/tags/"))
+			}
+			versions = append(versions, version)
+		}
+
+		if page.NextPageToken == "" {
+			return versions, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// lastSegment returns the unescaped part of a resource name after the given collection,
+// e.g. the tag of ".../tags/v1.2.0"
+func lastSegment(name, collection string) string {
+	if i := strings.LastIndex(name, collection); i >= 0 {
+		name = name[i+len(collection):]
+	}
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
+}
+
+// get requests an API path and decodes the JSON response into v
+func (c *Client) get(ctx context.Context, path string, v interface{}) error {
+	token, err := c.tokenSource.Token()
+	if err != nil {
+		return fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/"+path, nil)
+	if err != nil {
+		return err
+	}
+	token.SetAuthHeader(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// APIError is an unsuccessful response of the Artifact Registry API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("artifact registry returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("artifact registry returned %d: %s", e.StatusCode, e.Message)
+}
+
+// HTTPStatusCode returns the HTTP status code of the response
+func (e *APIError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
+// newAPIError reads the error details of an unsuccessful response
+func newAPIError(resp *http.Response) *APIError {
+	var body struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	_ = json.Unmarshal(data, &body)
+
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(body.Error.Status + " " + body.Error.Message),
+	}
+}
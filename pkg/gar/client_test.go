@@ -0,0 +1,236 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/oauth2"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+// fakeArtifactRegistry serves the versions of the image my-project/my-repo/team/my-app
+// over two pages, requiring the access token
+type fakeArtifactRegistry struct {
+	requests int
+}
+
+func (f *fakeArtifactRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests++
+
+	if r.Header.Get("Authorization") != "Bearer access-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error": {"code": 401, "status": "UNAUTHENTICATED", "message": "missing credentials"}}`))
+		return
+	}
+
+	const pkg = "/v1/projects/my-project/locations/us-central1/repositories/my-repo/packages/team%2Fmy-app"
+	if r.URL.EscapedPath() != pkg+"/versions" || r.URL.Query().Get("view") != "FULL" {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error": {"code": 404, "status": "NOT_FOUND", "message": "package not found"}}`))
+		return
+	}
+
+	name := "projects/my-project/locations/us-central1/repositories/my-repo/packages/team%2Fmy-app"
+	version := func(digest, createTime string, tags ...string) map[string]interface{} {
+		var relatedTags []map[string]string
+		for _, tag := range tags {
+			relatedTags = append(relatedTags, map[string]string{"name": name + "/tags/" + tag})
+		}
+		return map[string]interface{}{
+			"name":        name + "/versions/" + digest,
+			"createTime":  createTime,
+			"relatedTags": relatedTags,
+		}
+	}
+
+	if r.URL.Query().Get("pageToken") == "" {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"versions": []interface{}{
+				version("sha256:aaa", "2024-01-01T00:00:00Z", "v1.9.0"),
+				version("sha256:bbb", "2024-03-01T00:00:00Z", "v1.10.0", "latest"),
+			},
+			"nextPageToken": "page-2",
+		})
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"versions": []interface{}{
+			version("sha256:ccc", "2024-02-01T00:00:00Z", "v1.9.1"),
+			version("sha256:ddd", "2024-04-01T00:00:00Z"),
+		},
+	})
+}
+
+func newTestClient(t *testing.T, opts ...Option) (*Client, *fakeArtifactRegistry) {
+	t.Helper()
+
+	registry := &fakeArtifactRegistry{}
+	server := httptest.NewServer(registry)
+	t.Cleanup(server.Close)
+
+	opts = append([]Option{
+		WithEndpoint(server.URL),
+		WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "access-token"})),
+	}, opts...)
+	return NewClient(opts...), registry
+}
+
+var testImage = Image{Project: "my-project", Location: "us-central1", Repository: "my-repo", Name: "team/my-app"}
+
+func TestClient_GetLatestTags(t *testing.T) {
+	tests := []struct {
+		name         string
+		sortStrategy tagsort.Strategy
+		tagFilter    string
+		expected     string
+	}{
+		{
+			name:      "lexical",
+			tagFilter: `^v`,
+			expected:  "v1.9.1",
+		},
+		{
+			name:         "semver",
+			sortStrategy: tagsort.Semver,
+			tagFilter:    `^v`,
+			expected:     "v1.10.0",
+		},
+		{
+			name:         "push time",
+			sortStrategy: tagsort.PushTime,
+			expected:     "v1.10.0",
+		},
+		{
+			name:         "push time with filter",
+			sortStrategy: tagsort.PushTime,
+			tagFilter:    `^v1\.9\.`,
+			expected:     "v1.9.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, registry := newTestClient(t, WithSortStrategy(tt.sortStrategy))
+
+			latestTags, err := client.GetLatestTags(context.Background(), testImage, []string{tt.tagFilter, tt.tagFilter})
+			if err != nil {
+				t.Fatalf("GetLatestTags failed: %v", err)
+			}
+			if latestTags[tt.tagFilter] != tt.expected {
+				t.Errorf("Expected latest tag %s, got %s", tt.expected, latestTags[tt.tagFilter])
+			}
+
+			// Both pages are listed once for all filters
+			if registry.requests != 2 {
+				t.Errorf("Expected 2 requests, got %d", registry.requests)
+			}
+		})
+	}
+}
+
+func TestClient_GetLatestTag_NoMatch(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	_, err := client.GetLatestTag(context.Background(), testImage, `^release-`)
+	if err == nil || err.Error() != "no tags found matching filter in repository my-project/my-repo/team/my-app" {
+		t.Errorf("Expected a no matching tags error, got %v", err)
+	}
+}
+
+func TestClient_GetLatestTags_InvalidSortStrategy(t *testing.T) {
+	client, registry := newTestClient(t, WithSortStrategy("newest"))
+
+	if _, err := client.GetLatestTags(context.Background(), testImage, []string{""}); err == nil {
+		t.Error("Expected an error for an unsupported sort strategy")
+	}
+	if registry.requests != 0 {
+		t.Errorf("Expected no requests, got %d", registry.requests)
+	}
+}
+
+func TestClient_GetImageDigest(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	digest, err := client.GetImageDigest(context.Background(), testImage, "latest")
+	if err != nil {
+		t.Fatalf("GetImageDigest failed: %v", err)
+	}
+	if digest != "sha256:bbb" {
+		t.Errorf("Expected digest sha256:bbb, got %s", digest)
+	}
+
+	if _, err := client.GetImageDigest(context.Background(), testImage, "v0.1.0"); err == nil {
+		t.Error("Expected an error for an unknown tag")
+	}
+}
+
+func TestClient_APIError(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []Option
+		image          Image
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "unauthenticated",
+			opts:           []Option{WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "expired"}))},
+			image:          testImage,
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "artifact registry returned 401: UNAUTHENTICATED missing credentials",
+		},
+		{
+			name:           "unknown image",
+			image:          Image{Project: "my-project", Location: "us-central1", Repository: "my-repo", Name: "other"},
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "artifact registry returned 404: NOT_FOUND package not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t, tt.opts...)
+
+			_, err := client.GetLatestTag(context.Background(), tt.image, "")
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected an APIError, got %v", err)
+			}
+			if apiErr.HTTPStatusCode() != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, apiErr.HTTPStatusCode())
+			}
+			if apiErr.Error() != tt.expectedError {
+				t.Errorf("Expected error %q, got %q", tt.expectedError, apiErr.Error())
+			}
+		})
+	}
+}
+
+func TestImage_RepositoryName(t *testing.T) {
+	if name := testImage.RepositoryName(); name != "my-project/my-repo/team/my-app" {
+		t.Errorf("Expected repository name my-project/my-repo/team/my-app, got %s", name)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ghcr
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strings"
+
+	"github.com/rebelopsio/yuk/pkg/oci"
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+// Registry is the host of the GitHub Container Registry
+const Registry = "ghcr.io"
+
+// tokenUsername is sent with a personal access token when requesting registry tokens.
+// GHCR only checks the token, so any non-empty username works.
+const tokenUsername = "yuk"
+
+// Client lists tags of images in the GitHub Container Registry. Tags are listed with the
+// registry's token flow: a pull token is requested from ghcr.io/token, anonymously for
+// public images or with a personal access token for private ones.
+type Client struct {
+	registry string
+	ociOpts  []oci.Option
+	client   *oci.Client
+}
+
+// Option configures optional behavior of a Client
+type Option func(*Client)
+
+// WithToken authenticates with a GitHub personal access token (or GITHUB_TOKEN) with
+// the read:packages scope. Without a token, only public images can be read.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		if token != "" {
+			c.ociOpts = append(c.ociOpts, oci.WithBasicAuth(tokenUsername, token))
+		}
+	}
+}
+
+// WithSortStrategy orders tags by the given strategy instead of lexical order
+func WithSortStrategy(strategy tagsort.Strategy) Option {
+	return func(c *Client) {
+		c.ociOpts = append(c.ociOpts, oci.WithSortStrategy(strategy))
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to talk to the registry
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.ociOpts = append(c.ociOpts, oci.WithHTTPClient(httpClient))
+	}
+}
+
+// WithTLSConfig talks to the registry with the given TLS configuration, e.g. to trust the
+// CA of a TLS-intercepting proxy (see oci.NewTLSConfig)
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Client) {
+		c.ociOpts = append(c.ociOpts, oci.WithTLSConfig(config))
+	}
+}
+
+// WithRegistry talks to another registry host than ghcr.io, e.g. a test server
+func WithRegistry(registry string) Option {
+	return func(c *Client) {
+		c.registry = registry
+	}
+}
+
+// NewClient creates a new GitHub Container Registry client
+func NewClient(opts ...Option) *Client {
+	c := &Client{registry: Registry}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.client = oci.NewClient(c.registry, c.ociOpts...)
+
+	return c
+}
+
+// RepositoryName returns the repository of an image of a user or organization. GHCR
+// repository names are lowercase.
+func RepositoryName(owner, image string) string {
+	return strings.ToLower(owner + "/" + image)
+}
+
+// GetLatestTags retrieves the latest tag of the image for each of the given tag filters,
+// listing the image's tags only once. The result is keyed by tag filter.
+func (c *Client) GetLatestTags(ctx context.Context, owner, image string, tagFilters []string) (map[string]string, error) {
+	return c.client.GetLatestTags(ctx, RepositoryName(owner, image), tagFilters)
+}
+
+// GetImageDigest returns the digest of the manifest the tag of the image points to
+func (c *Client) GetImageDigest(ctx context.Context, owner, image, tag string) (string, error) {
+	return c.client.GetImageDigest(ctx, RepositoryName(owner, image), tag)
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ghcr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rebelopsio/yuk/pkg/oci"
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+// fakeGHCR mimics the GHCR token flow: tag lists require a bearer token for the image's
+// pull scope, which is issued anonymously for public images and for a valid personal
+// access token otherwise
+type fakeGHCR struct {
+	public bool
+	tags   []string
+}
+
+func (f *fakeGHCR) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/token":
+		if r.URL.Query().Get("scope") != "repository:my-org/my-app:pull" || r.URL.Query().Get("service") != "ghcr.io" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if _, password, ok := r.BasicAuth(); !f.public && (!ok || password != "ghp_token") {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"errors": [{"code": "UNAUTHORIZED", "message": "authentication required"}]}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "pull-token"})
+
+	case "/v2/my-org/my-app/tags/list":
+		if !f.authorized(w, r) {
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "my-org/my-app", "tags": f.tags})
+
+	case "/v2/my-org/my-app/manifests/v1.10.0":
+		if !f.authorized(w, r) {
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", "sha256:"+strings.Repeat("a", 64))
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// authorized checks the pull token of a request, challenging requests without one
+func (f *fakeGHCR) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Authorization") == "Bearer pull-token" {
+		return true
+	}
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="https://%s/token",service="ghcr.io",scope="repository:my-org/my-app:pull"`, r.Host))
+	w.WriteHeader(http.StatusUnauthorized)
+	return false
+}
+
+func TestClient_GetLatestTags(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.9.0", "v1.10.0", "latest"}
+
+	tests := []struct {
+		name         string
+		public       bool
+		token        string
+		sortStrategy tagsort.Strategy
+		expected     string
+		shouldErr    bool
+	}{
+		{
+			name:     "public image without token",
+			public:   true,
+			expected: "v1.9.0",
+		},
+		{
+			name:         "private image with token",
+			token:        "ghp_token",
+			sortStrategy: tagsort.Semver,
+			expected:     "v1.10.0",
+		},
+		{
+			name:      "private image without token",
+			shouldErr: true,
+		},
+		{
+			name:      "private image with invalid token",
+			token:     "ghp_wrong",
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(&fakeGHCR{public: tt.public, tags: tags})
+			defer server.Close()
+
+			client := NewClient(
+				WithRegistry(server.Listener.Addr().String()),
+				WithHTTPClient(server.Client()),
+				WithToken(tt.token),
+				WithSortStrategy(tt.sortStrategy),
+			)
+
+			// Owners are case-insensitive on GitHub but lowercase in GHCR
+			latestTags, err := client.GetLatestTags(context.Background(), "My-Org", "my-app", []string{`^v`})
+			if tt.shouldErr {
+				var apiErr *oci.APIError
+				if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode() != http.StatusUnauthorized {
+					t.Errorf("Expected unauthorized error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if latestTags[`^v`] != tt.expected {
+				t.Errorf("Expected latest tag %s, got %s", tt.expected, latestTags[`^v`])
+			}
+		})
+	}
+}
+
+func TestClient_GetImageDigest(t *testing.T) {
+	server := httptest.NewTLSServer(&fakeGHCR{public: true})
+	defer server.Close()
+
+	client := NewClient(WithRegistry(server.Listener.Addr().String()), WithHTTPClient(server.Client()))
+
+	digest, err := client.GetImageDigest(context.Background(), "my-org", "my-app", "v1.10.0")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if expected := "sha256:" + strings.Repeat("a", 64); digest != expected {
+		t.Errorf("Expected digest %s, got %s", expected, digest)
+	}
+}
+
+func TestRepositoryName(t *testing.T) {
+	if name := RepositoryName("RebelOps", "Yuk"); name != "rebelops/yuk" {
+		t.Errorf("Expected rebelops/yuk, got %s", name)
+	}
+}
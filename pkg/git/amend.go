@@ -0,0 +1,121 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// ConfigTrailer is the trailer identifying the YukConfig ("<namespace>/<name>") that made a
+// commit which may be amended by its later updates
+const ConfigTrailer = "Yuk-Config"
+
+// WithConfigRef sets the YukConfig ("<namespace>/<name>") commits are made for. With
+// AmendCommits, commits carry it in a ConfigTrailer trailer, and only commits of the same
+// YukConfig are amended.
+func WithConfigRef(ref string) Option {
+	return func(c *Client) {
+		c.configRef = ref
+	}
+}
+
+// amending reports whether commits to the configured branch amend the previous commit of
+// the YukConfig
+func (c *Client) amending() bool {
+	return c.config.AmendCommits && c.configRef != ""
+}
+
+// amendableHead returns the hash of the HEAD commit of a clone and whether the staged
+// changes may amend it: it was authored and committed by the commit identity of the
+// client, carries the ConfigTrailer of the same YukConfig and changed exactly the files
+// that are staged, so the work of others and of other YukConfigs is never rewritten
+func (c *Client) amendableHead(ctx context.Context, repoPath, branch string) (string, bool, error) {
+	output, err := c.gitOutput(ctx, repoPath, "log", "-1",
+		"--format=%H%x00%an%x00%ae%x00%cn%x00%ce%x00%(trailers:key="+ConfigTrailer+",valueonly,separator=%x2C)")
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read HEAD commit: %w", err)
+	}
+
+	fields := strings.Split(strings.TrimSpace(output), "\x00")
+	if len(fields) != 6 {
+		return "", false, fmt.Errorf("unexpected HEAD commit format: %q", output)
+	}
+
+	identity := c.CommitIdentity()
+	if fields[1] != identity.Name || fields[2] != identity.Email ||
+		fields[3] != identity.Name || fields[4] != identity.Email || fields[5] != c.configRef {
+		return fields[0], false, nil
+	}
+
+	staged, err := c.gitOutput(ctx, repoPath, "diff", "--cached", "--name-only", "HEAD")
+	if err != nil {
+		return "", false, fmt.Errorf("failed to list staged files: %w", err)
+	}
+
+	// A shallow clone lacks the parent of HEAD, which the files HEAD changed are listed
+	// against
+	if _, err := c.gitOutput(ctx, repoPath, "rev-parse", "--verify", "--quiet", "HEAD^"); err != nil {
+		cmd := exec.CommandContext(ctx, "git", "fetch", "--deepen=1", "origin", branch)
+		cmd.Dir = repoPath
+		cmd.Env = c.commandEnv()
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return "", false, fmt.Errorf("failed to fetch the parent of HEAD: %w, output: %s", classifyError(err, output), output)
+		}
+	}
+
+	changed, err := c.gitOutput(ctx, repoPath, "diff-tree", "--no-commit-id", "--name-only", "-r", "HEAD")
+	if err != nil {
+		return "", false, fmt.Errorf("failed to list files changed by HEAD: %w", err)
+	}
+
+	return fields[0], sortedLines(staged) == sortedLines(changed), nil
+}
+
+// gitOutput runs a local git command in a clone and returns its standard output
+func (c *Client) gitOutput(ctx context.Context, repoPath string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("%w, output: %s", err, exitErr.Stderr)
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+// sortedLines returns the lines of s, sorted and joined by newlines
+func sortedLines(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// pushAmended pushes an amended commit to the remote branch, replacing the commit it
+// amends. The push is rejected with ErrPushConflict when the branch moved past that
+// commit, so commits pushed by others in the meantime are never overwritten; the update
+// is then retried from a fresh clone.
+func (c *Client) pushAmended(ctx context.Context, repoPath, branch, amendedHead string) error {
+	return c.push(ctx, repoPath, branch, fmt.Sprintf("--force-with-lease=refs/heads/%s:%s", branch, amendedHead))
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// pushUpdate clones the upstream repository, sets the tag of the file and commits and
+// pushes it with the client
+func pushUpdate(t *testing.T, client *Client, file, tag string, beforePush func()) error {
+	t.Helper()
+
+	ctx := context.Background()
+	repoPath, err := client.Clone(ctx)
+	if err != nil {
+		t.Fatalf("Failed to clone: %v", err)
+	}
+	defer client.Cleanup(repoPath)
+
+	if beforePush != nil {
+		beforePush()
+	}
+
+	if err := os.WriteFile(filepath.Join(repoPath, file), []byte("tag: "+tag+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", file, err)
+	}
+	return client.CommitAndPush(ctx, repoPath, "Update container image to "+tag)
+}
+
+func TestClient_CommitAndPush_Amend(t *testing.T) {
+	upstream := newUpstream(t)
+	client := NewClient(yukv1.GitConfig{
+		Repository:   upstream,
+		Branch:       "main",
+		Name:         "Yuk",
+		Email:        "yuk@example.com",
+		AmendCommits: true,
+	}, WithBaseDir(t.TempDir()), WithConfigRef("default/app"))
+	client.pushRetryDelay = 0
+
+	log := func() []string {
+		t.Helper()
+		return strings.Split(strings.TrimSpace(runGit(t, upstream, "log", "--format=%s", "main")), "\n")
+	}
+
+	// The initial commit was made by someone else, so the first update adds a commit
+	if err := pushUpdate(t, client, "app.yaml", "v1.1.0", nil); err != nil {
+		t.Fatalf("Failed to push update: %v", err)
+	}
+	if commits := log(); len(commits) != 2 {
+		t.Fatalf("Expected the initial commit and the update, got %v", commits)
+	}
+
+	// The next update amends the commit of the previous one
+	if err := pushUpdate(t, client, "app.yaml", "v1.2.0", nil); err != nil {
+		t.Fatalf("Failed to push amended update: %v", err)
+	}
+	if commits := log(); len(commits) != 2 || commits[0] != "Update container image to v1.2.0" || commits[1] != "initial" {
+		t.Errorf("Expected the update to be amended, got %v", commits)
+	}
+	if content := runGit(t, upstream, "show", "main:app.yaml"); content != "tag: v1.2.0\n" {
+		t.Errorf("Expected app.yaml to be updated, got %q", content)
+	}
+	if trailer := runGit(t, upstream, "log", "-1", "--format=%(trailers:key=Yuk-Config)", "main"); strings.TrimSpace(trailer) != "Yuk-Config: default/app" {
+		t.Errorf("Expected the commit to identify the YukConfig, got %q", trailer)
+	}
+
+	// A commit by someone else is never amended
+	pushConcurrentChange(t, upstream, "worker.yaml", "tag: v1.0.1\n")
+	if err := pushUpdate(t, client, "app.yaml", "v1.3.0", nil); err != nil {
+		t.Fatalf("Failed to push update: %v", err)
+	}
+	if commits := log(); len(commits) != 4 || commits[1] != "concurrent change" {
+		t.Errorf("Expected a new commit after the concurrent change, got %v", commits)
+	}
+
+	// A commit pushed after the clone is not overwritten by the amended commit
+	err := pushUpdate(t, client, "app.yaml", "v1.4.0", func() {
+		pushConcurrentChange(t, upstream, "worker.yaml", "tag: v1.0.2\n")
+	})
+	if !errors.Is(err, ErrPushConflict) {
+		t.Fatalf("Expected a push conflict, got %v", err)
+	}
+	if content := runGit(t, upstream, "show", "main:worker.yaml"); content != "tag: v1.0.2\n" {
+		t.Errorf("Expected the concurrent change to be kept, got %q", content)
+	}
+}
+
+func TestClient_CommitAndPush_AmendDisabled(t *testing.T) {
+	upstream := newUpstream(t)
+	client := NewClient(yukv1.GitConfig{Repository: upstream, Branch: "main", Name: "Yuk", Email: "yuk@example.com"},
+		WithBaseDir(t.TempDir()))
+
+	for _, tag := range []string{"v1.1.0", "v1.2.0"} {
+		if err := pushUpdate(t, client, "app.yaml", tag, nil); err != nil {
+			t.Fatalf("Failed to push update: %v", err)
+		}
+	}
+
+	if count := strings.TrimSpace(runGit(t, upstream, "rev-list", "--count", "main")); count != "3" {
+		t.Errorf("Expected a commit per update, got %s commits", count)
+	}
+}
+
+func TestClient_CommitAndPush_AmendOnlySameConfigAndFiles(t *testing.T) {
+	upstream := newUpstream(t)
+	newAmendingClient := func(ref string) *Client {
+		client := NewClient(yukv1.GitConfig{
+			Repository:   upstream,
+			Branch:       "main",
+			Name:         "Yuk",
+			Email:        "yuk@example.com",
+			AmendCommits: true,
+		}, WithBaseDir(t.TempDir()), WithConfigRef(ref))
+		client.pushRetryDelay = 0
+		return client
+	}
+	app, worker := newAmendingClient("default/app"), newAmendingClient("default/worker")
+
+	count := func() string {
+		t.Helper()
+		return strings.TrimSpace(runGit(t, upstream, "rev-list", "--count", "main"))
+	}
+
+	if err := pushUpdate(t, app, "app.yaml", "v1.1.0", nil); err != nil {
+		t.Fatalf("Failed to push update: %v", err)
+	}
+
+	// The commit of another YukConfig with the same commit identity is not amended
+	if err := pushUpdate(t, worker, "app.yaml", "v1.2.0", nil); err != nil {
+		t.Fatalf("Failed to push update: %v", err)
+	}
+	if commits := count(); commits != "3" {
+		t.Errorf("Expected a new commit for another YukConfig, got %s commits", commits)
+	}
+
+	// A commit of the same YukConfig changing other files is not amended
+	if err := pushUpdate(t, worker, "worker.yaml", "v1.2.0", nil); err != nil {
+		t.Fatalf("Failed to push update: %v", err)
+	}
+	if commits := count(); commits != "4" {
+		t.Errorf("Expected a new commit for other files, got %s commits", commits)
+	}
+
+	if err := pushUpdate(t, worker, "worker.yaml", "v1.3.0", nil); err != nil {
+		t.Fatalf("Failed to push update: %v", err)
+	}
+	if commits := count(); commits != "4" {
+		t.Errorf("Expected the update of the same files to be amended, got %s commits", commits)
+	}
+}
+
+func TestClient_CommitAndPush_AmendWithoutConfigRef(t *testing.T) {
+	upstream := newUpstream(t)
+	client := NewClient(yukv1.GitConfig{Repository: upstream, Branch: "main", Name: "Yuk", Email: "yuk@example.com", AmendCommits: true},
+		WithBaseDir(t.TempDir()))
+
+	for _, tag := range []string{"v1.1.0", "v1.2.0"} {
+		if err := pushUpdate(t, client, "app.yaml", tag, nil); err != nil {
+			t.Fatalf("Failed to push update: %v", err)
+		}
+	}
+
+	if count := strings.TrimSpace(runGit(t, upstream, "rev-list", "--count", "main")); count != "3" {
+		t.Errorf("Expected a commit per update, got %s commits", count)
+	}
+}
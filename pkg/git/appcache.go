@@ -0,0 +1,105 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AppCache shares GitHub App installation tokens and bot identities between clients, so
+// that reconciles do not each mint a token and look up the bot user. Entries are keyed
+// by installation and by a digest of the private key, so a client only reuses a token
+// minted with the key it was given. An AppCache is safe for concurrent use.
+type AppCache struct {
+	mu         sync.Mutex
+	tokens     map[string]appToken
+	identities map[string]Identity
+}
+
+// appToken is an installation token and when it expires
+type appToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// NewAppCache creates an empty GitHub App cache
+func NewAppCache() *AppCache {
+	return &AppCache{
+		tokens:     make(map[string]appToken),
+		identities: make(map[string]Identity),
+	}
+}
+
+// WithAppCache shares the GitHub App installation token and bot identity through the
+// cache. A nil cache disables sharing.
+func WithAppCache(cache *AppCache) Option {
+	return func(c *Client) {
+		c.appCache = cache
+	}
+}
+
+// token returns the cached installation token for the key
+func (c *AppCache) token(key string) (appToken, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	token, ok := c.tokens[key]
+	return token, ok
+}
+
+// setToken caches the installation token for the key
+func (c *AppCache) setToken(key string, token appToken) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tokens[key] = token
+}
+
+// identity returns the cached bot identity for the key
+func (c *AppCache) identity(key string) (Identity, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	identity, ok := c.identities[key]
+	return identity, ok
+}
+
+// setIdentity caches the bot identity for the key
+func (c *AppCache) setIdentity(key string, identity Identity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.identities[key] = identity
+}
+
+// appCacheKey identifies an installation of a GitHub App on a GitHub instance. The
+// private key is part of the key, so a configuration holding another (e.g. a revoked)
+// key does not get the token minted for the installation by another configuration.
+func appCacheKey(apiURL string, app *githubApp) string {
+	digest := sha256.Sum256(app.privateKey)
+	return strings.Join([]string{
+		apiURL,
+		strconv.FormatInt(app.appID, 10),
+		strconv.FormatInt(app.installationID, 10),
+		hex.EncodeToString(digest[:]),
+	}, "/")
+}
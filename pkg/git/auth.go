@@ -0,0 +1,156 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// WithToken sets the personal access token used for HTTPS repositories
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithSSHKey sets the private key used for SSH repositories
+func WithSSHKey(key []byte) Option {
+	return func(c *Client) {
+		c.sshKey = key
+	}
+}
+
+// WithKnownHosts sets the known_hosts entries the host keys of SSH repositories are
+// verified against, e.g. from a secret
+func WithKnownHosts(knownHosts []byte) Option {
+	return func(c *Client) {
+		c.knownHosts = knownHosts
+	}
+}
+
+// WithKnownHostsFile sets the known_hosts file the host keys of SSH repositories are
+// verified against when no entries are set with WithKnownHosts (default: the ssh
+// defaults, e.g. ~/.ssh/known_hosts)
+func WithKnownHostsFile(path string) Option {
+	return func(c *Client) {
+		c.knownHostsPath = path
+	}
+}
+
+// writeSSHKey writes the SSH private key to a file only readable by the current user,
+// so it can be passed to ssh. The file is removed by Cleanup.
+func (c *Client) writeSSHKey() error {
+	if len(c.sshKey) == 0 || c.sshKeyFile != "" {
+		return nil
+	}
+
+	// os.CreateTemp creates the file with mode 0600
+	file, err := os.CreateTemp(c.baseDir, CloneDirPrefix+"ssh-key-")
+	if err != nil {
+		return fmt.Errorf("failed to create SSH key file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(c.sshKey); err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to write SSH key file: %w", err)
+	}
+
+	c.sshKeyFile = file.Name()
+	return nil
+}
+
+// writeKnownHosts writes the known_hosts entries to a file, so they can be passed to
+// ssh. The file is removed by Cleanup.
+func (c *Client) writeKnownHosts() error {
+	if len(c.knownHosts) == 0 || c.knownHostsFile != "" {
+		return nil
+	}
+
+	file, err := os.CreateTemp(c.baseDir, CloneDirPrefix+"known-hosts-")
+	if err != nil {
+		return fmt.Errorf("failed to create known hosts file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(c.knownHosts); err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to write known hosts file: %w", err)
+	}
+
+	c.knownHostsFile = file.Name()
+	return nil
+}
+
+// commandEnv returns the environment of git commands talking to the remote. Host keys
+// of SSH repositories must be known: unknown or changed keys are rejected. HTTPS requests
+// to the host of the repository are authenticated with the token through an extra header
+// set in the environment, so the token is neither part of the remote URL nor of the
+// command line.
+func (c *Client) commandEnv() []string {
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	if prefix := c.tokenURLPrefix(); prefix != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + c.token))
+		env = append(env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http."+prefix+".extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+credentials)
+	}
+
+	if c.sshKeyFile != "" {
+		command := fmt.Sprintf("ssh -i %s -o IdentitiesOnly=yes -o StrictHostKeyChecking=yes", c.sshKeyFile)
+		if knownHosts := c.knownHostsFileOrPath(); knownHosts != "" {
+			command += " -o UserKnownHostsFile=" + knownHosts
+		}
+		env = append(env, "GIT_SSH_COMMAND="+command)
+	}
+
+	return env
+}
+
+// tokenURLPrefix returns the URL prefix requests authenticated with the token are sent to:
+// the HTTPS host of the repository for a GitHub App installation token, github.com for a
+// personal access token. It returns an empty string when there is no token to send.
+func (c *Client) tokenURLPrefix() string {
+	if c.token == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(c.config.Repository)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return ""
+	}
+
+	prefix := "https://" + parsed.Host + "/"
+	if c.githubApp == nil && prefix != "https://github.com/" {
+		return ""
+	}
+	return prefix
+}
+
+// knownHostsFileOrPath returns the known_hosts file passed to ssh: the file of the
+// entries set with WithKnownHosts, or the file set with WithKnownHostsFile
+func (c *Client) knownHostsFileOrPath() string {
+	if c.knownHostsFile != "" {
+		return c.knownHostsFile
+	}
+	return c.knownHostsPath
+}
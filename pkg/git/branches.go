@@ -0,0 +1,92 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// DeleteBranches deletes the given branches from the remote repository without cloning
+// it, and returns the deleted ones. Branches that do not exist are ignored. On GitHub,
+// deleting the head branch of an open pull request closes the pull request.
+func (c *Client) DeleteBranches(ctx context.Context, branches []string) ([]string, error) {
+	if len(branches) == 0 {
+		return nil, nil
+	}
+
+	// git push needs a repository to run in, even to only delete remote branches
+	tmpDir, err := os.MkdirTemp(c.baseDir, CloneDirPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer c.Cleanup(tmpDir)
+
+	if _, err := c.refreshAppToken(ctx); err != nil {
+		return nil, err
+	}
+	repoURL, err := c.remoteURL()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository URL: %w", err)
+	}
+	if err := c.writeSSHKey(); err != nil {
+		return nil, err
+	}
+	if err := c.writeKnownHosts(); err != nil {
+		return nil, err
+	}
+
+	// Only delete the branches that still exist, as deleting a missing one fails the push
+	args := []string{"ls-remote", "--heads", repoURL}
+	for _, branch := range branches {
+		args = append(args, "refs/heads/"+branch)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = c.commandEnv()
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote branches: %w, output: %s", classifyError(err, output), output)
+	}
+
+	var existing []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if _, ref, ok := strings.Cut(line, "\t"); ok {
+			existing = append(existing, strings.TrimPrefix(ref, "refs/heads/"))
+		}
+	}
+	if len(existing) == 0 {
+		return nil, nil
+	}
+
+	cmd = exec.CommandContext(ctx, "git", "init", "--quiet")
+	cmd.Dir = tmpDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to initialize repository: %w, output: %s", err, output)
+	}
+
+	cmd = exec.CommandContext(ctx, "git", append([]string{"push", repoURL, "--delete"}, existing...)...)
+	cmd.Dir = tmpDir
+	cmd.Env = c.commandEnv()
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to delete branches: %w, output: %s", classifyError(err, output), output)
+	}
+
+	return existing, nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestClient_DeleteBranches(t *testing.T) {
+	upstream := newUpstream(t)
+	runGit(t, upstream, "branch", "yuk/my-app-v1.1.0-0123abcd")
+	runGit(t, upstream, "branch", "yuk/other-app-v1.1.0-4567cdef")
+
+	baseDir := t.TempDir()
+	client := NewClient(yukv1.GitConfig{Repository: upstream}, WithBaseDir(baseDir))
+
+	deleted, err := client.DeleteBranches(context.Background(), []string{"yuk/my-app-v1.1.0-0123abcd", "yuk/my-app-v1.0.0-89abcdef"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// Missing branches are ignored
+	if expected := []string{"yuk/my-app-v1.1.0-0123abcd"}; !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("Expected deleted branches %v, got %v", expected, deleted)
+	}
+
+	// Other branches are kept
+	branches := runGit(t, upstream, "branch", "--list", "yuk/*")
+	if strings.Contains(branches, "my-app") || !strings.Contains(branches, "yuk/other-app-v1.1.0-4567cdef") {
+		t.Errorf("Expected only yuk/other-app-v1.1.0-4567cdef to remain, got:\n%s", branches)
+	}
+
+	// The temporary repository is removed
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		t.Fatalf("Failed to read base directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected the temporary repository to be removed, got %d entries", len(entries))
+	}
+
+	// Deleting branches that no longer exist does nothing
+	deleted, err = client.DeleteBranches(context.Background(), []string{"yuk/my-app-v1.1.0-0123abcd"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("Expected no deleted branches, got %v", deleted)
+	}
+}
@@ -19,39 +19,98 @@ package git
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
 )
 
+// CloneDirPrefix is the prefix of the temporary directories repositories are cloned into
+const CloneDirPrefix = "yuk-git-"
+
 // Client provides operations for interacting with Git repositories
 type Client struct {
-	config yukv1.GitConfig
+	config       yukv1.GitConfig
+	baseDir      string
+	authIdentity Identity
+	token        string
+	sshKey       []byte
+	sshKeyFile   string
+	githubAPIURL string
+	githubApp    *githubApp
+	appCache     *AppCache
+
+	knownHosts     []byte
+	knownHostsFile string
+	knownHostsPath string
+
+	signingKey        []byte
+	signingPassphrase []byte
+	gnupgHome         string
+
+	pushRetryDelay time.Duration
+	onPushRetry    func(attempt int, err error)
+
+	sparseCheckout bool
+	sparsePaths    []string
+
+	reuseClones bool
+
+	configRef string
+}
+
+// Option configures optional behavior of a Client
+type Option func(*Client)
+
+// WithBaseDir sets the directory temporary clones are created in. When empty, the
+// default temporary directory is used (honoring TMPDIR).
+func WithBaseDir(dir string) Option {
+	return func(c *Client) {
+		c.baseDir = dir
+	}
 }
 
 // NewClient creates a new Git client with the specified configuration
-func NewClient(config yukv1.GitConfig) *Client {
-	return &Client{
-		config: config,
+func NewClient(config yukv1.GitConfig, opts ...Option) *Client {
+	c := &Client{
+		config:         config,
+		pushRetryDelay: pushRetryBaseDelay,
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
 }
 
-// Clone clones the repository to a temporary directory
+// Clone clones the branch of the repository to a temporary directory, shallowly unless
+// GitConfig.CloneDepth is 0. With WithReuseClones, the clone of a previous call is
+// refreshed instead when possible.
 func (c *Client) Clone(ctx context.Context) (string, error) {
-	// Create temporary directory
-	tmpDir, err := os.MkdirTemp("", "yuk-git-")
+	// Mint a GitHub App installation token and resolve the app's commit identity, then
+	// check the repository can be authenticated
+	if _, err := c.refreshAppToken(ctx); err != nil {
+		return "", err
+	}
+	if err := c.resolveAppIdentity(ctx); err != nil {
+		return "", err
+	}
+	repoURL, err := c.remoteURL()
 	if err != nil {
-		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+		return "", fmt.Errorf("failed to get repository URL: %w", err)
 	}
 
-	// Determine the repository URL with authentication
-	repoURL, err := c.getAuthenticatedRepoURL()
-	if err != nil {
-		os.RemoveAll(tmpDir)
-		return "", fmt.Errorf("failed to get authenticated repository URL: %w", err)
+	// Make the SSH key and known hosts available to ssh for the clone and later pushes
+	if err := c.writeSSHKey(); err != nil {
+		return "", err
+	}
+	if err := c.writeKnownHosts(); err != nil {
+		return "", err
 	}
 
 	// Clone the repository
@@ -60,12 +119,30 @@ func (c *Client) Clone(ctx context.Context) (string, error) {
 		branch = "main"
 	}
 
-	cmd := exec.CommandContext(ctx, "git", "clone", "--single-branch", "--branch", branch, repoURL, tmpDir)
-	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var tmpDir string
+	if c.reuseClones {
+		if tmpDir, err = c.cloneCached(ctx, branch, repoURL); err != nil {
+			return "", err
+		}
+	} else {
+		tmpDir, err = os.MkdirTemp(c.baseDir, CloneDirPrefix)
+		if err != nil {
+			return "", fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+
+		cmd := exec.CommandContext(ctx, "git", c.cloneArgs(branch, repoURL, tmpDir)...)
+		cmd.Env = c.commandEnv()
 
-	if output, err := cmd.CombinedOutput(); err != nil {
+		if output, err := cmd.CombinedOutput(); err != nil {
+			os.RemoveAll(tmpDir)
+			return "", fmt.Errorf("failed to clone repository: %w, output: %s", classifyError(err, output), output)
+		}
+	}
+
+	// Check out only the directories of the update targets
+	if err := c.configureSparseCheckout(ctx, tmpDir); err != nil {
 		os.RemoveAll(tmpDir)
-		return "", fmt.Errorf("failed to clone repository: %w, output: %s", err, output)
+		return "", err
 	}
 
 	// Configure git user for commits
@@ -74,11 +151,33 @@ func (c *Client) Clone(ctx context.Context) (string, error) {
 		return "", fmt.Errorf("failed to configure git user: %w", err)
 	}
 
+	// Sign commits when a signing key is configured
+	if err := c.configureSigning(ctx, tmpDir); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", err
+	}
+
 	return tmpDir, nil
 }
 
 // CommitAndPush commits changes and pushes them to the remote repository
 func (c *Client) CommitAndPush(ctx context.Context, repoPath, commitMessage string) error {
+	branch := c.config.Branch
+	if branch == "" {
+		branch = "main"
+	}
+
+	return c.commitAndPush(ctx, repoPath, commitMessage, branch, false)
+}
+
+// CommitAndPushToBranch commits changes and pushes them to the given branch instead of
+// the configured one. The branch is owned by yuk and is overwritten if it exists.
+func (c *Client) CommitAndPushToBranch(ctx context.Context, repoPath, commitMessage, branch string) error {
+	return c.commitAndPush(ctx, repoPath, commitMessage, branch, true)
+}
+
+// commitAndPush commits all changes and pushes HEAD to the given remote branch
+func (c *Client) commitAndPush(ctx context.Context, repoPath, commitMessage, branch string, force bool) error {
 	// Add all changes
 	cmd := exec.CommandContext(ctx, "git", "add", ".")
 	cmd.Dir = repoPath
@@ -94,52 +193,88 @@ func (c *Client) CommitAndPush(ctx context.Context, repoPath, commitMessage stri
 		return nil
 	}
 
+	// Amend the previous commit of the YukConfig instead of adding one when configured.
+	// Review branches are replaced anyway, so only commits of the configured branch are
+	// amended.
+	amend := !force && c.amending()
+	var amendedHead string
+	if amend {
+		head, amendable, err := c.amendableHead(ctx, repoPath, branch)
+		if err != nil {
+			return err
+		}
+		if amendable {
+			amendedHead = head
+		}
+	}
+
 	// Commit changes
-	cmd = exec.CommandContext(ctx, "git", "commit", "-m", commitMessage)
+	commitArgs := c.commitArgs(commitMessage, amendedHead != "")
+	if amend {
+		commitArgs = append(commitArgs, "--trailer", ConfigTrailer+": "+c.configRef)
+	}
+	cmd = exec.CommandContext(ctx, "git", commitArgs...)
 	cmd.Dir = repoPath
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to commit changes: %w, output: %s", err, output)
 	}
 
-	// Push changes
-	branch := c.config.Branch
-	if branch == "" {
-		branch = "main"
+	// Replace a GitHub App installation token that expires soon, e.g. when the clone was
+	// kept open for a long time before the push
+	if _, err := c.refreshAppToken(ctx); err != nil {
+		return err
 	}
 
-	cmd = exec.CommandContext(ctx, "git", "push", "origin", branch)
-	cmd.Dir = repoPath
-	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
-
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to push changes: %w, output: %s", err, output)
+	// Push changes. Force pushes replace the branch and cannot conflict; an amended
+	// commit only replaces the commit it amends.
+	switch {
+	case force:
+		return c.push(ctx, repoPath, branch, "--force")
+	case amendedHead != "":
+		return c.pushAmended(ctx, repoPath, branch, amendedHead)
 	}
-
-	return nil
+	return c.pushWithRetry(ctx, repoPath, branch)
 }
 
-// Cleanup removes the temporary repository directory
+// Cleanup removes the temporary repository directory, unless it is kept for reuse, and the
+// SSH key and known hosts files and GPG home
 func (c *Client) Cleanup(repoPath string) {
-	os.RemoveAll(repoPath)
+	if !c.isCachedClone(repoPath) {
+		os.RemoveAll(repoPath)
+	}
+
+	if c.sshKeyFile != "" {
+		os.Remove(c.sshKeyFile)
+		c.sshKeyFile = ""
+	}
+	if c.knownHostsFile != "" {
+		os.Remove(c.knownHostsFile)
+		c.knownHostsFile = ""
+	}
+
+	c.cleanupSigning()
 }
 
-// getAuthenticatedRepoURL returns the repository URL with authentication credentials
-func (c *Client) getAuthenticatedRepoURL() (string, error) {
+// remoteURL returns the repository URL remote commands use, after checking the configured
+// token is available. The URL never carries the token: commandEnv passes it to each
+// command instead, so it is not persisted in the configuration of (reused) clones.
+func (c *Client) remoteURL() (string, error) {
 	repoURL := c.config.Repository
 
-	// If using personal access token for GitHub
-	if c.config.Auth.PersonalAccessTokenRef != nil {
-		// In a real implementation, you would retrieve the token from the Kubernetes secret
-		// For now, we'll assume the token is provided via environment variable
-		token := os.Getenv("GITHUB_TOKEN")
-		if token == "" {
-			return "", fmt.Errorf("GitHub token not found in environment")
+	// If authenticating as a GitHub App installation
+	if c.githubApp != nil {
+		if c.token == "" {
+			return "", fmt.Errorf("GitHub App installation token not available")
 		}
-
-		// Convert https://github.com/owner/repo.git to https://token@github.com/owner/repo.git
-		if strings.HasPrefix(repoURL, "https://github.com/") {
-			repoURL = strings.Replace(repoURL, "https://github.com/", fmt.Sprintf("https://%s@github.com/", token), 1)
+		if parsed, err := url.Parse(repoURL); err != nil || parsed.Scheme != "https" {
+			return "", fmt.Errorf("GitHub App authentication requires an HTTPS repository URL, got %s", repoURL)
 		}
+		return repoURL, nil
+	}
+
+	// If using personal access token for GitHub
+	if c.config.Auth.PersonalAccessTokenRef != nil && c.token == "" {
+		return "", fmt.Errorf("personal access token not provided")
 	}
 
 	return repoURL, nil
@@ -147,15 +282,17 @@ func (c *Client) getAuthenticatedRepoURL() (string, error) {
 
 // configureGitUser configures the git user name and email for commits
 func (c *Client) configureGitUser(repoPath string) error {
+	identity := c.CommitIdentity()
+
 	// Set user name
-	cmd := exec.Command("git", "config", "user.name", c.config.Name)
+	cmd := exec.Command("git", "config", "user.name", identity.Name)
 	cmd.Dir = repoPath
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to set git user name: %w, output: %s", err, output)
 	}
 
 	// Set user email
-	cmd = exec.Command("git", "config", "user.email", c.config.Email)
+	cmd = exec.Command("git", "config", "user.email", identity.Email)
 	cmd.Dir = repoPath
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to set git user email: %w, output: %s", err, output)
@@ -45,13 +45,14 @@ func TestNewClient(t *testing.T) {
 	}
 }
 
-func TestClient_getAuthenticatedRepoURL(t *testing.T) {
+func TestClient_remoteURL(t *testing.T) {
 	tests := []struct {
 		name           string
 		repository     string
 		hasToken       bool
 		token          string
 		expectedPrefix string
+		shouldErr      bool
 	}{
 		{
 			name:           "github repo without token",
@@ -64,7 +65,13 @@ func TestClient_getAuthenticatedRepoURL(t *testing.T) {
 			repository:     "https://github.com/example/repo.git",
 			hasToken:       true,
 			token:          "ghp_1234567890",
-			expectedPrefix: "https://ghp_1234567890@github.com/",
+			expectedPrefix: "https://github.com/",
+		},
+		{
+			name:       "token reference without token",
+			repository: "https://github.com/example/repo.git",
+			hasToken:   true,
+			shouldErr:  true,
 		},
 		{
 			name:           "non-github repo",
@@ -80,16 +87,24 @@ func TestClient_getAuthenticatedRepoURL(t *testing.T) {
 				Repository: tt.repository,
 			}
 
+			var opts []Option
 			if tt.hasToken {
 				config.Auth.PersonalAccessTokenRef = &yukv1.SecretKeySelector{
 					Name: "github-token",
 					Key:  "token",
 				}
-				t.Setenv("GITHUB_TOKEN", tt.token)
+				opts = append(opts, WithToken(tt.token))
 			}
 
-			client := NewClient(config)
-			url, err := client.getAuthenticatedRepoURL()
+			client := NewClient(config, opts...)
+			url, err := client.remoteURL()
+
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
 
 			if err != nil && !tt.hasToken {
 				// Expected for cases without token
@@ -104,6 +119,9 @@ func TestClient_getAuthenticatedRepoURL(t *testing.T) {
 			if tt.hasToken && !contains(url, tt.expectedPrefix) {
 				t.Errorf("Expected URL to contain %s, got %s", tt.expectedPrefix, url)
 			}
+			if tt.token != "" && contains(url, tt.token) {
+				t.Errorf("Expected URL without the token, got %s", url)
+			}
 		})
 	}
 }
@@ -0,0 +1,108 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultCloneDepth is the number of commits cloned when GitConfig.CloneDepth is not set
+const DefaultCloneDepth = 1
+
+// WithSparseCheckout checks out only the given directories (and the files at the root of
+// the repository) instead of the whole tree. Blobs outside of them are not downloaded.
+func WithSparseCheckout(paths []string) Option {
+	return func(c *Client) {
+		c.sparseCheckout = true
+		c.sparsePaths = paths
+	}
+}
+
+// SparseCheckoutPaths returns the distinct directories containing the given files,
+// relative to the repository root. Files at the root are always checked out, so their
+// directory is omitted. For glob patterns (e.g. "overlays/*/values.yaml"), the directory
+// above the first component with a wildcard is checked out.
+func SparseCheckoutPaths(files []string) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, file := range files {
+		dir := path.Dir(path.Clean("/" + file))[1:]
+		if i := strings.IndexAny(dir, "*?["); i >= 0 {
+			dir = path.Dir("/" + dir[:i])[1:]
+		}
+		if dir == "" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		paths = append(paths, dir)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// cloneDepth returns the number of commits to clone, or 0 for the full history
+func (c *Client) cloneDepth() int {
+	if c.config.CloneDepth == nil {
+		return DefaultCloneDepth
+	}
+	return max(int(*c.config.CloneDepth), 0)
+}
+
+// cloneArgs returns the arguments of the git clone of the branch into dir
+func (c *Client) cloneArgs(branch, repoURL, dir string) []string {
+	args := []string{"clone", "--single-branch", "--branch", branch}
+	if depth := c.cloneDepth(); depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(depth))
+	}
+	if c.sparseCheckout {
+		args = append(args, "--sparse", "--filter=blob:none")
+	}
+	return append(args, repoURL, dir)
+}
+
+// sparseCheckoutArgs returns the arguments of the git sparse-checkout command selecting
+// the sparse checkout directories, or nil when only root files are checked out
+func (c *Client) sparseCheckoutArgs() []string {
+	if !c.sparseCheckout || len(c.sparsePaths) == 0 {
+		return nil
+	}
+	return append([]string{"sparse-checkout", "set", "--cone"}, c.sparsePaths...)
+}
+
+// configureSparseCheckout checks out the sparse checkout directories of a sparse clone
+func (c *Client) configureSparseCheckout(ctx context.Context, repoPath string) error {
+	args := c.sparseCheckoutArgs()
+	if args == nil {
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoPath
+	cmd.Env = c.commandEnv()
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to configure sparse checkout: %w, output: %s", classifyError(err, output), output)
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestClient_cloneArgs(t *testing.T) {
+	depth := func(d int32) *int32 { return &d }
+
+	tests := []struct {
+		name               string
+		cloneDepth         *int32
+		sparsePaths        []string
+		sparse             bool
+		expectedClone      string
+		expectedSparseArgs string
+	}{
+		{
+			name:          "shallow by default",
+			expectedClone: "clone --single-branch --branch main --depth 1 https://github.com/example/repo.git /tmp/clone",
+		},
+		{
+			name:          "custom depth",
+			cloneDepth:    depth(50),
+			expectedClone: "clone --single-branch --branch main --depth 50 https://github.com/example/repo.git /tmp/clone",
+		},
+		{
+			name:          "full history",
+			cloneDepth:    depth(0),
+			expectedClone: "clone --single-branch --branch main https://github.com/example/repo.git /tmp/clone",
+		},
+		{
+			name:               "sparse checkout",
+			sparse:             true,
+			sparsePaths:        []string{"apps/my-app", "clusters/prod"},
+			expectedClone:      "clone --single-branch --branch main --depth 1 --sparse --filter=blob:none https://github.com/example/repo.git /tmp/clone",
+			expectedSparseArgs: "sparse-checkout set --cone apps/my-app clusters/prod",
+		},
+		{
+			name:          "sparse checkout of root files",
+			sparse:        true,
+			expectedClone: "clone --single-branch --branch main --depth 1 --sparse --filter=blob:none https://github.com/example/repo.git /tmp/clone",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.sparse {
+				opts = append(opts, WithSparseCheckout(tt.sparsePaths))
+			}
+			client := NewClient(yukv1.GitConfig{Repository: "https://github.com/example/repo.git", CloneDepth: tt.cloneDepth}, opts...)
+
+			if args := strings.Join(client.cloneArgs("main", "https://github.com/example/repo.git", "/tmp/clone"), " "); args != tt.expectedClone {
+				t.Errorf("Expected clone args %q, got %q", tt.expectedClone, args)
+			}
+			if args := strings.Join(client.sparseCheckoutArgs(), " "); args != tt.expectedSparseArgs {
+				t.Errorf("Expected sparse checkout args %q, got %q", tt.expectedSparseArgs, args)
+			}
+		})
+	}
+}
+
+func TestSparseCheckoutPaths(t *testing.T) {
+	files := []string{
+		"apps/my-app/values.yaml",
+		"apps/my-app/deployment.yaml",
+		"./clusters/prod/kustomization.yaml",
+		"kustomization.yaml",
+		"apps/worker/values.yaml",
+		"overlays/*/deployment.yaml",
+		"envs/prod/*/values.yaml",
+		"base/*.yaml",
+	}
+
+	expected := "apps/my-app,apps/worker,base,clusters/prod,envs/prod,overlays"
+	if paths := strings.Join(SparseCheckoutPaths(files), ","); paths != expected {
+		t.Errorf("Expected paths %s, got %s", expected, paths)
+	}
+}
+
+func TestClient_Clone_ShallowSparse(t *testing.T) {
+	// An upstream repository with two commits and two apps
+	upstream := t.TempDir()
+	runGit(t, upstream, "init", "--initial-branch=main")
+	runGit(t, upstream, "config", "receive.denyCurrentBranch", "updateInstead")
+	runGit(t, upstream, "config", "uploadpack.allowFilter", "true")
+	for i, content := range []string{"tag: v1.0.0\n", "tag: v1.0.1\n"} {
+		for _, file := range []string{"README.md", "apps/frontend/values.yaml", "apps/backend/values.yaml"} {
+			writeFile(t, filepath.Join(upstream, file), content)
+		}
+		runGit(t, upstream, "add", ".")
+		runGit(t, upstream, "commit", "-m", fmt.Sprintf("commit %d", i+1))
+	}
+
+	client := NewClient(yukv1.GitConfig{Repository: "file://" + upstream, Branch: "main"},
+		WithBaseDir(t.TempDir()), WithSparseCheckout(SparseCheckoutPaths([]string{"apps/frontend/values.yaml"})))
+	client.pushRetryDelay = 0
+
+	ctx := context.Background()
+	repoPath, err := client.Clone(ctx)
+	if err != nil {
+		t.Fatalf("Failed to clone: %v", err)
+	}
+	defer client.Cleanup(repoPath)
+
+	if count := strings.TrimSpace(runGit(t, repoPath, "rev-list", "--count", "HEAD")); count != "1" {
+		t.Errorf("Expected a clone of depth 1, got %s commits", count)
+	}
+	for file, expected := range map[string]bool{"README.md": true, "apps/frontend/values.yaml": true, "apps/backend/values.yaml": false} {
+		if _, err := os.Stat(filepath.Join(repoPath, file)); (err == nil) != expected {
+			t.Errorf("Expected %s checked out to be %v", file, expected)
+		}
+	}
+
+	// Updates are pushed, rebasing onto changes outside of the sparse checkout
+	pushConcurrentChange(t, upstream, "apps/backend/values.yaml", "tag: v1.0.2\n")
+	writeFile(t, filepath.Join(repoPath, "apps/frontend/values.yaml"), "tag: v1.1.0\n")
+
+	if err := client.CommitAndPush(ctx, repoPath, "Update container image to v1.1.0"); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	for file, expected := range map[string]string{"apps/frontend/values.yaml": "tag: v1.1.0\n", "apps/backend/values.yaml": "tag: v1.0.2\n"} {
+		if content := runGit(t, upstream, "show", "main:"+file); content != expected {
+			t.Errorf("Expected %s to be %q, got %q", file, expected, content)
+		}
+	}
+}
+
+// writeFile writes a file, creating its directory
+func writeFile(t *testing.T, file, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatalf("Failed to create directory of %s: %v", file, err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", file, err)
+	}
+}
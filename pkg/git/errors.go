@@ -0,0 +1,97 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrPushConflict is returned when a push is rejected because the remote branch
+	// moved (e.g. a non-fast-forward update)
+	ErrPushConflict = errors.New("push rejected by remote")
+
+	// ErrNetwork is returned when a git operation fails to reach the remote
+	ErrNetwork = errors.New("network error")
+
+	// ErrAuthentication is returned when the remote rejects the credentials of a git
+	// operation, e.g. an expired token or a revoked deploy key
+	ErrAuthentication = errors.New("authentication failed")
+)
+
+// authMarkers are git output fragments indicating the remote rejected the credentials
+var authMarkers = []string{
+	"authentication failed",
+	"invalid username or password",
+	"could not read username",
+	"could not read password",
+	"permission denied (publickey",
+	"the requested url returned error: 401",
+	"the requested url returned error: 403",
+}
+
+// pushConflictMarkers are git output fragments indicating a rejected push
+var pushConflictMarkers = []string{
+	"[rejected]",
+	"non-fast-forward",
+	"fetch first",
+	"failed to update ref",
+	"cannot lock ref",
+}
+
+// networkMarkers are git output fragments indicating the remote could not be reached
+var networkMarkers = []string{
+	"could not resolve host",
+	"connection timed out",
+	"connection refused",
+	"connection reset",
+	"operation timed out",
+	"failed to connect",
+	"network is unreachable",
+	"rpc failed",
+	"early eof",
+	"the requested url returned error: 5",
+	"the requested url returned error: 429",
+}
+
+// classifyError wraps a failed git command's error with ErrPushConflict, ErrAuthentication
+// or ErrNetwork when its output identifies the failure as one of those classes
+func classifyError(err error, output []byte) error {
+	lowerOutput := strings.ToLower(string(output))
+
+	for _, marker := range authMarkers {
+		if strings.Contains(lowerOutput, marker) {
+			return fmt.Errorf("%w: %w", ErrAuthentication, err)
+		}
+	}
+
+	for _, marker := range pushConflictMarkers {
+		if strings.Contains(lowerOutput, marker) {
+			return fmt.Errorf("%w: %w", ErrPushConflict, err)
+		}
+	}
+
+	for _, marker := range networkMarkers {
+		if strings.Contains(lowerOutput, marker) {
+			return fmt.Errorf("%w: %w", ErrNetwork, err)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+
+	tests := []struct {
+		name     string
+		output   string
+		expected error
+	}{
+		{
+			name: "non-fast-forward push",
+			output: " ! [rejected]        main -> main (fetch first)\n" +
+				"error: failed to push some refs to 'https://github.com/example/repo.git'",
+			expected: ErrPushConflict,
+		},
+		{
+			name:     "unresolvable host",
+			output:   "fatal: unable to access 'https://github.com/example/repo.git/': Could not resolve host: github.com",
+			expected: ErrNetwork,
+		},
+		{
+			name:     "server error",
+			output:   "fatal: unable to access 'https://github.com/example/repo.git/': The requested URL returned error: 503",
+			expected: ErrNetwork,
+		},
+		{
+			name:     "authentication failure",
+			output:   "remote: Invalid username or password.\nfatal: Authentication failed for 'https://github.com/example/repo.git/'",
+			expected: ErrAuthentication,
+		},
+		{
+			name:     "forbidden",
+			output:   "fatal: unable to access 'https://github.com/example/repo.git/': The requested URL returned error: 403",
+			expected: ErrAuthentication,
+		},
+		{
+			name:     "rejected SSH key",
+			output:   "git@github.com: Permission denied (publickey).\nfatal: Could not read from remote repository.",
+			expected: ErrAuthentication,
+		},
+		{
+			name:   "missing branch",
+			output: "fatal: Remote branch release not found in upstream origin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyError(exitErr, []byte(tt.output))
+
+			if !errors.Is(err, exitErr) {
+				t.Errorf("Expected original error to be preserved, got %v", err)
+			}
+
+			for _, class := range []error{ErrPushConflict, ErrNetwork, ErrAuthentication} {
+				if errors.Is(err, class) != (class == tt.expected) {
+					t.Errorf("Expected errors.Is(err, %v) to be %v, got %v", class, class == tt.expected, !(class == tt.expected))
+				}
+			}
+		})
+	}
+}
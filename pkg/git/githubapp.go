@@ -0,0 +1,219 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// appTokenRefreshMargin is how long before it expires an installation token is replaced,
+// so it does not expire between a clone and the following push
+const appTokenRefreshMargin = 5 * time.Minute
+
+// githubApp holds the GitHub App credentials and the installation token minted with them
+type githubApp struct {
+	appID          int64
+	installationID int64
+	privateKey     []byte
+	now            func() time.Time
+
+	// expiresAt is when the installation token held in Client.token expires
+	expiresAt time.Time
+}
+
+// WithGitHubApp authenticates as an installation of a GitHub App instead of with a
+// personal access token. Short-lived installation tokens are minted with the app's PEM
+// encoded private key for HTTPS clones, pushes and pull requests.
+func WithGitHubApp(appID, installationID int64, privateKey []byte) Option {
+	return func(c *Client) {
+		c.githubApp = &githubApp{
+			appID:          appID,
+			installationID: installationID,
+			privateKey:     privateKey,
+			now:            time.Now,
+		}
+	}
+}
+
+// refreshAppToken mints a new installation token when a GitHub App is configured and the
+// current token is missing or about to expire, unless the AppCache holds a valid one. It
+// reports whether the token changed.
+func (c *Client) refreshAppToken(ctx context.Context) (bool, error) {
+	app := c.githubApp
+	if app == nil {
+		return false, nil
+	}
+
+	now := app.now()
+	if c.token != "" && now.Add(appTokenRefreshMargin).Before(app.expiresAt) {
+		return false, nil
+	}
+
+	// Reuse a token minted by another client for the installation
+	if c.appCache != nil {
+		if cached, ok := c.appCache.token(c.appCacheKey()); ok && now.Add(appTokenRefreshMargin).Before(cached.expiresAt) {
+			c.token = cached.token
+			app.expiresAt = cached.expiresAt
+			return true, nil
+		}
+	}
+
+	jwt, err := app.jwt()
+	if err != nil {
+		return false, err
+	}
+
+	var token struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	path := fmt.Sprintf("/app/installations/%d/access_tokens", app.installationID)
+	if err := c.githubRequestWithToken(ctx, http.MethodPost, path, jwt, struct{}{}, &token); err != nil {
+		return false, fmt.Errorf("failed to create GitHub App installation token: %w", err)
+	}
+	if token.Token == "" {
+		return false, fmt.Errorf("failed to create GitHub App installation token: empty token in response")
+	}
+
+	c.token = token.Token
+	app.expiresAt = token.ExpiresAt
+	if c.appCache != nil {
+		c.appCache.setToken(c.appCacheKey(), appToken{token: token.Token, expiresAt: token.ExpiresAt})
+	}
+	return true, nil
+}
+
+// resolveAppIdentity sets the authenticated identity to the GitHub App's bot user, so
+// commits are attributed to the app, unless the configuration sets the commit identity.
+// The identity is shared through the AppCache, as it does not change.
+func (c *Client) resolveAppIdentity(ctx context.Context) error {
+	if c.githubApp == nil || c.authIdentity != (Identity{}) || (c.config.Name != "" && c.config.Email != "") {
+		return nil
+	}
+	if c.appCache != nil {
+		if identity, ok := c.appCache.identity(c.appCacheKey()); ok {
+			c.authIdentity = identity
+			return nil
+		}
+	}
+
+	jwt, err := c.githubApp.jwt()
+	if err != nil {
+		return err
+	}
+
+	var app struct {
+		Slug string `json:"slug"`
+	}
+	if err := c.githubRequestWithToken(ctx, http.MethodGet, "/app", jwt, nil, &app); err != nil {
+		return fmt.Errorf("failed to get GitHub App: %w", err)
+	}
+
+	var user struct {
+		ID int64 `json:"id"`
+	}
+	if err := c.githubRequest(ctx, http.MethodGet, "/users/"+url.PathEscape(app.Slug+"[bot]"), nil, &user); err != nil {
+		return fmt.Errorf("failed to get GitHub App bot user: %w", err)
+	}
+
+	c.authIdentity = BotIdentity(app.Slug, user.ID)
+	if c.appCache != nil {
+		c.appCache.setIdentity(c.appCacheKey(), c.authIdentity)
+	}
+	return nil
+}
+
+// appCacheKey returns the key of the GitHub App installation in the AppCache
+func (c *Client) appCacheKey() string {
+	apiURL := c.githubAPIURL
+	if apiURL == "" {
+		apiURL = DefaultGitHubAPIURL
+	}
+	return appCacheKey(apiURL, c.githubApp)
+}
+
+// jwt returns a JSON Web Token authenticating as the GitHub App
+func (app *githubApp) jwt() (string, error) {
+	key, err := parsePrivateKey(app.privateKey)
+	if err != nil {
+		return "", err
+	}
+	return appJWT(app.appID, key, app.now())
+}
+
+// appJWT returns the JSON Web Token authenticating as the GitHub App, signed with RS256.
+// It is issued a minute in the past to allow for clock drift and expires after ten
+// minutes, the maximum accepted by GitHub.
+func appJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWT header: %w", err)
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWT claims: %w", err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parsePrivateKey parses a PEM encoded RSA private key in PKCS#1 (as downloaded from
+// GitHub) or PKCS#8 form
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: not an RSA key")
+	}
+	return rsaKey, nil
+}
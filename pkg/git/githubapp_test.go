@@ -0,0 +1,327 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// newAppKey generates a GitHub App private key and returns it with its PKCS#1 PEM encoding
+func newAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+// fakeGitHubApp serves the installation token endpoint of the GitHub API, minting a new
+// token on every request, and the app and bot user endpoints
+type fakeGitHubApp struct {
+	key         *rsa.PublicKey
+	expiresAt   time.Time
+	requests    atomic.Int32
+	appRequests atomic.Int32
+}
+
+func (f *fakeGitHubApp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The bot user is looked up with an installation token
+	if r.Method == http.MethodGet && r.URL.Path == "/users/yuk-updater[bot]" {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ghs_") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"login": "yuk-updater[bot]", "id": 123456})
+		return
+	}
+
+	if r.URL.Path != "/app" && (r.Method != http.MethodPost || r.URL.Path != "/app/installations/42/access_tokens") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	jwt, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || verifyJWT(jwt, f.key) != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message": "A JSON web token could not be decoded"}`))
+		return
+	}
+
+	if r.URL.Path == "/app" {
+		f.appRequests.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": 12345, "slug": "yuk-updater"})
+		return
+	}
+
+	requests := f.requests.Add(1)
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      fmt.Sprintf("ghs_token%d", requests),
+		"expires_at": f.expiresAt.Format(time.RFC3339),
+	})
+}
+
+// verifyJWT checks the RS256 signature of a JWT
+func verifyJWT(jwt string, key *rsa.PublicKey) error {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("expected 3 parts, got %d", len(parts))
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+}
+
+func TestAppJWT(t *testing.T) {
+	key, _ := newAppKey(t)
+	now := time.Unix(1700000000, 0)
+
+	jwt, err := appJWT(12345, key, now)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if err := verifyJWT(jwt, &key.PublicKey); err != nil {
+		t.Fatalf("Expected a valid signature, got: %v", err)
+	}
+
+	parts := strings.Split(jwt, ".")
+	var header map[string]string
+	data, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if err := json.Unmarshal(data, &header); err != nil {
+		t.Fatalf("Failed to decode header: %v", err)
+	}
+	if header["alg"] != "RS256" || header["typ"] != "JWT" {
+		t.Errorf("Expected RS256 JWT header, got %v", header)
+	}
+
+	var claims struct {
+		IssuedAt  int64  `json:"iat"`
+		ExpiresAt int64  `json:"exp"`
+		Issuer    string `json:"iss"`
+	}
+	data, _ = base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(data, &claims); err != nil {
+		t.Fatalf("Failed to decode claims: %v", err)
+	}
+	if claims.Issuer != "12345" {
+		t.Errorf("Expected issuer 12345, got %s", claims.Issuer)
+	}
+	if claims.IssuedAt != now.Unix()-60 {
+		t.Errorf("Expected iat %d, got %d", now.Unix()-60, claims.IssuedAt)
+	}
+	if claims.ExpiresAt-claims.IssuedAt > 600 {
+		t.Errorf("Expected a lifetime of at most 10 minutes, got %ds", claims.ExpiresAt-claims.IssuedAt)
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key, pkcs1 := newAppKey(t)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	for name, data := range map[string][]byte{"pkcs1": pkcs1, "pkcs8": pkcs8} {
+		parsed, err := parsePrivateKey(data)
+		if err != nil {
+			t.Errorf("%s: Expected no error but got: %v", name, err)
+			continue
+		}
+		if !parsed.Equal(key) {
+			t.Errorf("%s: Expected the generated key", name)
+		}
+	}
+
+	if _, err := parsePrivateKey([]byte("not a key")); err == nil {
+		t.Error("Expected error for invalid key but got none")
+	}
+}
+
+func TestClient_refreshAppToken(t *testing.T) {
+	key, privateKey := newAppKey(t)
+	now := time.Now()
+	fake := &fakeGitHubApp{key: &key.PublicKey, expiresAt: now.Add(time.Hour)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := NewClient(yukv1.GitConfig{Repository: "https://github.com/example/repo.git"},
+		WithGitHubAPIURL(server.URL), WithGitHubApp(12345, 42, privateKey))
+	client.githubApp.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	if refreshed, err := client.refreshAppToken(ctx); err != nil || !refreshed {
+		t.Fatalf("Expected a new token, got refreshed %v and error %v", refreshed, err)
+	}
+
+	repoURL, err := client.remoteURL()
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if expected := "https://github.com/example/repo.git"; repoURL != expected {
+		t.Errorf("Expected URL %s, got %s", expected, repoURL)
+	}
+	if client.token != "ghs_token1" {
+		t.Errorf("Expected token ghs_token1, got %s", client.token)
+	}
+
+	// The token is reused while it is valid
+	if refreshed, err := client.refreshAppToken(ctx); err != nil || refreshed {
+		t.Errorf("Expected the token to be reused, got refreshed %v and error %v", refreshed, err)
+	}
+	if requests := fake.requests.Load(); requests != 1 {
+		t.Errorf("Expected 1 token request, got %d", requests)
+	}
+
+	// Shortly before it expires, the token is replaced
+	now = now.Add(time.Hour - time.Minute)
+	if refreshed, err := client.refreshAppToken(ctx); err != nil || !refreshed {
+		t.Fatalf("Expected a new token, got refreshed %v and error %v", refreshed, err)
+	}
+	if client.token != "ghs_token2" {
+		t.Errorf("Expected token ghs_token2, got %s", client.token)
+	}
+}
+
+func TestClient_refreshAppToken_InvalidKey(t *testing.T) {
+	key, _ := newAppKey(t)
+	_, otherKey := newAppKey(t)
+	server := httptest.NewServer(&fakeGitHubApp{key: &key.PublicKey, expiresAt: time.Now().Add(time.Hour)})
+	defer server.Close()
+
+	client := NewClient(yukv1.GitConfig{Repository: "https://github.com/example/repo.git"},
+		WithGitHubAPIURL(server.URL), WithGitHubApp(12345, 42, otherKey))
+
+	if _, err := client.refreshAppToken(context.Background()); err == nil {
+		t.Fatal("Expected error but got none")
+	} else if !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected the API error, got: %v", err)
+	}
+}
+
+func TestClient_resolveAppIdentity(t *testing.T) {
+	key, privateKey := newAppKey(t)
+	server := httptest.NewServer(&fakeGitHubApp{key: &key.PublicKey, expiresAt: time.Now().Add(time.Hour)})
+	defer server.Close()
+
+	client := NewClient(yukv1.GitConfig{Repository: "https://github.com/example/repo.git"},
+		WithGitHubAPIURL(server.URL), WithGitHubApp(12345, 42, privateKey))
+
+	ctx := context.Background()
+	if _, err := client.refreshAppToken(ctx); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if err := client.resolveAppIdentity(ctx); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if identity := client.CommitIdentity(); identity != BotIdentity("yuk-updater", 123456) {
+		t.Errorf("Expected the bot identity, got %+v", identity)
+	}
+}
+
+func TestClient_AppCache(t *testing.T) {
+	key, privateKey := newAppKey(t)
+	now := time.Now()
+	fake := &fakeGitHubApp{key: &key.PublicKey, expiresAt: now.Add(time.Hour)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cache := NewAppCache()
+	newClient := func(privateKey []byte) *Client {
+		client := NewClient(yukv1.GitConfig{Repository: "https://github.com/example/repo.git"},
+			WithGitHubAPIURL(server.URL), WithGitHubApp(12345, 42, privateKey), WithAppCache(cache))
+		client.githubApp.now = func() time.Time { return now }
+		return client
+	}
+	resolve := func(client *Client) {
+		t.Helper()
+		if _, err := client.refreshAppToken(context.Background()); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if err := client.resolveAppIdentity(context.Background()); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	// Clients of later reconciles reuse the token and identity
+	for i := 0; i < 3; i++ {
+		client := newClient(privateKey)
+		resolve(client)
+		if client.token != "ghs_token1" {
+			t.Errorf("Expected the cached token ghs_token1, got %s", client.token)
+		}
+		if identity := client.CommitIdentity(); identity != BotIdentity("yuk-updater", 123456) {
+			t.Errorf("Expected the bot identity, got %+v", identity)
+		}
+	}
+	if requests := fake.requests.Load(); requests != 1 {
+		t.Errorf("Expected 1 token request, got %d", requests)
+	}
+	if requests := fake.appRequests.Load(); requests != 1 {
+		t.Errorf("Expected 1 app request, got %d", requests)
+	}
+
+	// A client with another private key does not get the cached token
+	_, otherKey := newAppKey(t)
+	if _, err := newClient(otherKey).refreshAppToken(context.Background()); err == nil {
+		t.Error("Expected the token request with another key to fail")
+	}
+
+	// Shortly before it expires, the cached token is replaced
+	now = now.Add(time.Hour - time.Minute)
+	client := newClient(privateKey)
+	resolve(client)
+	if client.token != "ghs_token2" {
+		t.Errorf("Expected a new token ghs_token2, got %s", client.token)
+	}
+	if requests := fake.appRequests.Load(); requests != 1 {
+		t.Errorf("Expected the identity to stay cached, got %d app requests", requests)
+	}
+}
+
+func TestClient_remoteURL_AppSSH(t *testing.T) {
+	client := NewClient(yukv1.GitConfig{Repository: "git@github.com:example/repo.git"}, WithToken("ghs_token"))
+	client.githubApp = &githubApp{}
+
+	if _, err := client.remoteURL(); err == nil {
+		t.Error("Expected error for SSH URL but got none")
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"os"
+)
+
+// Default commit identity used when neither the configuration, the authenticated
+// identity nor the environment provides one
+const (
+	DefaultCommitName  = "Yuk Controller"
+	DefaultCommitEmail = "yuk@rebelops.io"
+)
+
+// Environment variables overriding the default commit identity
+const (
+	defaultNameEnv  = "GIT_DEFAULT_NAME"
+	defaultEmailEnv = "GIT_DEFAULT_EMAIL"
+)
+
+// Identity is the author and committer identity used for commits
+type Identity struct {
+	Name  string
+	Email string
+}
+
+// BotIdentity returns the identity GitHub attributes to a GitHub App's bot user,
+// given the app slug and the numeric ID of the bot user
+func BotIdentity(appSlug string, botUserID int64) Identity {
+	login := appSlug + "[bot]"
+	return Identity{
+		Name:  login,
+		Email: fmt.Sprintf("%d+%s@users.noreply.github.com", botUserID, login),
+	}
+}
+
+// WithAuthIdentity sets the identity of the authenticated principal (e.g. a GitHub App
+// bot). It is used for commits when the configuration does not set a name or email.
+func WithAuthIdentity(identity Identity) Option {
+	return func(c *Client) {
+		c.authIdentity = identity
+	}
+}
+
+// CommitIdentity resolves the commit identity. Each of the name and email is taken from
+// the configuration, then the authenticated identity, then the GIT_DEFAULT_NAME and
+// GIT_DEFAULT_EMAIL environment variables, then the built-in defaults.
+func (c *Client) CommitIdentity() Identity {
+	return Identity{
+		Name:  firstNonEmpty(c.config.Name, c.authIdentity.Name, os.Getenv(defaultNameEnv), DefaultCommitName),
+		Email: firstNonEmpty(c.config.Email, c.authIdentity.Email, os.Getenv(defaultEmailEnv), DefaultCommitEmail),
+	}
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
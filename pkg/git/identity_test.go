@@ -0,0 +1,101 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"testing"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestBotIdentity(t *testing.T) {
+	identity := BotIdentity("yuk-updater", 123456)
+
+	if identity.Name != "yuk-updater[bot]" {
+		t.Errorf("Expected name yuk-updater[bot], got %s", identity.Name)
+	}
+
+	expectedEmail := "123456+yuk-updater[bot]@users.noreply.github.com"
+	if identity.Email != expectedEmail {
+		t.Errorf("Expected email %s, got %s", expectedEmail, identity.Email)
+	}
+}
+
+func TestClient_CommitIdentity(t *testing.T) {
+	botIdentity := BotIdentity("yuk-updater", 123456)
+
+	tests := []struct {
+		name          string
+		config        yukv1.GitConfig
+		opts          []Option
+		envName       string
+		envEmail      string
+		expectedName  string
+		expectedEmail string
+	}{
+		{
+			name:          "configured identity wins",
+			config:        yukv1.GitConfig{Name: "Test User", Email: "test@example.com"},
+			opts:          []Option{WithAuthIdentity(botIdentity)},
+			expectedName:  "Test User",
+			expectedEmail: "test@example.com",
+		},
+		{
+			name:          "derived from app identity",
+			opts:          []Option{WithAuthIdentity(botIdentity)},
+			envName:       "Env User",
+			envEmail:      "env@example.com",
+			expectedName:  botIdentity.Name,
+			expectedEmail: botIdentity.Email,
+		},
+		{
+			name:          "partially configured",
+			config:        yukv1.GitConfig{Name: "Test User"},
+			opts:          []Option{WithAuthIdentity(botIdentity)},
+			expectedName:  "Test User",
+			expectedEmail: botIdentity.Email,
+		},
+		{
+			name:          "environment defaults",
+			envName:       "Env User",
+			envEmail:      "env@example.com",
+			expectedName:  "Env User",
+			expectedEmail: "env@example.com",
+		},
+		{
+			name:          "built-in defaults",
+			expectedName:  DefaultCommitName,
+			expectedEmail: DefaultCommitEmail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(defaultNameEnv, tt.envName)
+			t.Setenv(defaultEmailEnv, tt.envEmail)
+
+			identity := NewClient(tt.config, tt.opts...).CommitIdentity()
+
+			if identity.Name != tt.expectedName {
+				t.Errorf("Expected name %s, got %s", tt.expectedName, identity.Name)
+			}
+			if identity.Email != tt.expectedEmail {
+				t.Errorf("Expected email %s, got %s", tt.expectedEmail, identity.Email)
+			}
+		})
+	}
+}
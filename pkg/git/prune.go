@@ -0,0 +1,139 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// StaleBranch is a remote branch found by StaleBranches
+type StaleBranch struct {
+	Name string
+
+	// LastCommit is when the head commit of the branch was committed
+	LastCommit time.Time
+
+	// PullRequest is the last pull request from the branch, nil when none was opened
+	PullRequest *PullRequest
+}
+
+// githubBranch is a branch listed by the GitHub API
+type githubBranch struct {
+	Name   string `json:"name"`
+	Commit struct {
+		SHA string `json:"sha"`
+	} `json:"commit"`
+}
+
+// githubCommit is a commit returned by the GitHub API
+type githubCommit struct {
+	Commit struct {
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+}
+
+// branchesPerPage is the page size of the branches listed by StaleBranches
+const branchesPerPage = 100
+
+// StaleBranches returns the branches of the GitHub repository starting with prefix that
+// have no open pull request, because their pull requests were merged or closed or none
+// was opened, and whose head commit is older than minAge. Branches with an open pull
+// request may still be merged and are never returned.
+func (c *Client) StaleBranches(ctx context.Context, prefix string, minAge time.Duration, now time.Time) ([]StaleBranch, error) {
+	if _, err := c.refreshAppToken(ctx); err != nil {
+		return nil, err
+	}
+	if c.token == "" {
+		return nil, fmt.Errorf("a personal access token or GitHub App is required to list branches")
+	}
+
+	owner, repo, err := githubRepository(c.config.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []StaleBranch
+	for page := 1; ; page++ {
+		var branches []githubBranch
+		path := fmt.Sprintf("/repos/%s/%s/branches?per_page=%d&page=%d", owner, repo, branchesPerPage, page)
+		if err := c.githubRequest(ctx, http.MethodGet, path, nil, &branches); err != nil {
+			return nil, fmt.Errorf("failed to list branches: %w", err)
+		}
+
+		for _, branch := range branches {
+			if !strings.HasPrefix(branch.Name, prefix) {
+				continue
+			}
+
+			pr, open, err := c.lastPullRequest(ctx, owner, repo, branch.Name)
+			if err != nil {
+				return nil, err
+			}
+			if open {
+				continue
+			}
+
+			var commit githubCommit
+			if err := c.githubRequest(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s/commits/%s", owner, repo, branch.Commit.SHA), nil, &commit); err != nil {
+				return nil, fmt.Errorf("failed to get head commit of branch %s: %w", branch.Name, err)
+			}
+			lastCommit := commit.Commit.Committer.Date
+			if now.Sub(lastCommit) < minAge {
+				continue
+			}
+
+			stale = append(stale, StaleBranch{Name: branch.Name, LastCommit: lastCommit, PullRequest: pr})
+		}
+
+		if len(branches) < branchesPerPage {
+			return stale, nil
+		}
+	}
+}
+
+// lastPullRequest returns the most recent pull request from the given branch, or nil,
+// and whether any pull request from the branch is still open
+func (c *Client) lastPullRequest(ctx context.Context, owner, repo, head string) (*PullRequest, bool, error) {
+	query := url.Values{
+		"head":      {owner + ":" + head},
+		"state":     {"all"},
+		"sort":      {"created"},
+		"direction": {"desc"},
+	}
+
+	var prs []PullRequest
+	if err := c.githubRequest(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s/pulls?%s", owner, repo, query.Encode()), nil, &prs); err != nil {
+		return nil, false, fmt.Errorf("failed to list pull requests of branch %s: %w", head, err)
+	}
+
+	for _, pr := range prs {
+		if pr.State == "open" {
+			return &pr, true, nil
+		}
+	}
+	if len(prs) == 0 {
+		return nil, false, nil
+	}
+	return &prs[0], false, nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestClient_StaleBranches(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	merged := now.Add(-5 * 24 * time.Hour)
+
+	// Head commit dates and pull requests by branch
+	commits := map[string]time.Time{
+		"main":          now.Add(-time.Hour),
+		"feature/login": now.Add(-30 * 24 * time.Hour),
+		"yuk/merged":    now.Add(-10 * 24 * time.Hour),
+		"yuk/open":      now.Add(-20 * 24 * time.Hour),
+		"yuk/abandoned": now.Add(-9 * 24 * time.Hour),
+		"yuk/recent":    now.Add(-2 * 24 * time.Hour),
+		"yuk/closed":    now.Add(-8 * 24 * time.Hour),
+	}
+	pulls := map[string][]PullRequest{
+		"yuk/merged": {{Number: 3, State: "closed", MergedAt: &merged}},
+		"yuk/open":   {{Number: 4, State: "closed"}, {Number: 2, State: "open"}},
+		"yuk/closed": {{Number: 5, State: "closed"}},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ghp_1234567890" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.URL.Path == "/repos/example/repo/branches":
+			var branches []map[string]interface{}
+			for _, name := range []string{"main", "feature/login", "yuk/merged", "yuk/open", "yuk/abandoned", "yuk/recent", "yuk/closed"} {
+				branches = append(branches, map[string]interface{}{"name": name, "commit": map[string]string{"sha": "sha-" + name}})
+			}
+			_ = json.NewEncoder(w).Encode(branches)
+
+		case r.URL.Path == "/repos/example/repo/pulls":
+			if r.URL.Query().Get("state") != "all" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			prs := pulls[strings.TrimPrefix(r.URL.Query().Get("head"), "example:")]
+			if prs == nil {
+				prs = []PullRequest{}
+			}
+			_ = json.NewEncoder(w).Encode(prs)
+
+		case strings.HasPrefix(r.URL.Path, "/repos/example/repo/commits/sha-"):
+			date, ok := commits[strings.TrimPrefix(r.URL.Path, "/repos/example/repo/commits/sha-")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"commit": map[string]interface{}{"committer": map[string]interface{}{"date": date}},
+			})
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(yukv1.GitConfig{Repository: "https://github.com/example/repo.git"},
+		WithToken("ghp_1234567890"), WithGitHubAPIURL(server.URL))
+
+	stale, err := client.StaleBranches(context.Background(), "yuk/", 7*24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	var names []string
+	for _, branch := range stale {
+		names = append(names, branch.Name)
+	}
+	if got := strings.Join(names, ","); got != "yuk/merged,yuk/abandoned,yuk/closed" {
+		t.Fatalf("Expected stale branches yuk/merged,yuk/abandoned,yuk/closed, got %s", got)
+	}
+
+	if pr := stale[0].PullRequest; pr == nil || pr.Number != 3 || pr.MergedAt == nil {
+		t.Errorf("Expected merged pull request #3, got %+v", pr)
+	}
+	if stale[1].PullRequest != nil {
+		t.Errorf("Expected no pull request for yuk/abandoned, got %+v", stale[1].PullRequest)
+	}
+	if !stale[1].LastCommit.Equal(commits["yuk/abandoned"]) {
+		t.Errorf("Expected last commit %v, got %v", commits["yuk/abandoned"], stale[1].LastCommit)
+	}
+}
+
+func TestClient_StaleBranches_RequiresToken(t *testing.T) {
+	client := NewClient(yukv1.GitConfig{Repository: "https://github.com/example/repo.git"})
+
+	if _, err := client.StaleBranches(context.Background(), "yuk/", 0, time.Now()); err == nil {
+		t.Fatal("Expected an error without a token")
+	}
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultGitHubAPIURL is the GitHub REST API used to open pull requests
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// PullRequest is a pull request opened (or found) by CreatePullRequest
+type PullRequest struct {
+	Number int    `json:"number"`
+	URL    string `json:"html_url"`
+
+	// State is "open" or "closed"; MergedAt is set for merged pull requests
+	State    string     `json:"state,omitempty"`
+	MergedAt *time.Time `json:"merged_at,omitempty"`
+}
+
+// PullRequestOptions describes a pull request to open
+type PullRequestOptions struct {
+	// Head is the branch with the changes
+	Head string
+
+	// Base is the branch the changes are merged into
+	Base string
+
+	Title string
+	Body  string
+}
+
+// APIError is an unsuccessful response of the GitHub API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	return fmt.Sprintf("GitHub API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// HTTPStatusCode returns the HTTP status code of the response
+func (e *APIError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
+// WithGitHubAPIURL sets the GitHub API used to open pull requests and mint GitHub App
+// installation tokens, e.g. for GitHub Enterprise Server ("https://github.example.com/api/v3")
+func WithGitHubAPIURL(apiURL string) Option {
+	return func(c *Client) {
+		c.githubAPIURL = strings.TrimSuffix(apiURL, "/")
+	}
+}
+
+// CreatePullRequest opens a pull request on GitHub with the configured token or GitHub
+// App. If an open pull request from the head branch already exists, it is returned instead.
+func (c *Client) CreatePullRequest(ctx context.Context, opts PullRequestOptions) (*PullRequest, error) {
+	if _, err := c.refreshAppToken(ctx); err != nil {
+		return nil, err
+	}
+	if c.token == "" {
+		return nil, fmt.Errorf("a personal access token or GitHub App is required to open pull requests")
+	}
+
+	owner, repo, err := githubRepository(c.config.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reuse an open pull request from the same branch
+	existing, err := c.findPullRequest(ctx, owner, repo, opts.Head)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	payload := map[string]string{
+		"title": opts.Title,
+		"head":  opts.Head,
+		"base":  opts.Base,
+		"body":  opts.Body,
+	}
+
+	var pr PullRequest
+	err = c.githubRequest(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/pulls", owner, repo), payload, &pr)
+	if err != nil {
+		// Another reconcile may have opened the pull request in the meantime
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnprocessableEntity {
+			if existing, findErr := c.findPullRequest(ctx, owner, repo, opts.Head); findErr == nil && existing != nil {
+				return existing, nil
+			}
+		}
+		return nil, fmt.Errorf("failed to create pull request: %w", err)
+	}
+
+	return &pr, nil
+}
+
+// findPullRequest returns the open pull request from the given branch, or nil
+func (c *Client) findPullRequest(ctx context.Context, owner, repo, head string) (*PullRequest, error) {
+	query := url.Values{
+		"head":  {owner + ":" + head},
+		"state": {"open"},
+	}
+
+	var prs []PullRequest
+	if err := c.githubRequest(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s/pulls?%s", owner, repo, query.Encode()), nil, &prs); err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return &prs[0], nil
+}
+
+// githubRequest sends a request to the GitHub API with the configured token and decodes
+// the JSON response into out
+func (c *Client) githubRequest(ctx context.Context, method, path string, payload, out interface{}) error {
+	return c.githubRequestWithToken(ctx, method, path, c.token, payload, out)
+}
+
+// githubRequestWithToken sends a request to the GitHub API with the given bearer token
+// and decodes the JSON response into out
+func (c *Client) githubRequestWithToken(ctx context.Context, method, path, token string, payload, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	apiURL := c.githubAPIURL
+	if apiURL == "" {
+		apiURL = DefaultGitHubAPIURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, apiURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNetwork, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(data, &apiErr)
+		return &APIError{StatusCode: resp.StatusCode, Message: apiErr.Message}
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
+
+// githubRepository returns the owner and name of a GitHub repository from its HTTPS
+// (https://github.com/owner/repo.git) or SSH (git@github.com:owner/repo.git) URL
+func githubRepository(repoURL string) (string, string, error) {
+	var path string
+	if strings.HasPrefix(repoURL, "git@") {
+		_, path, _ = strings.Cut(repoURL, ":")
+	} else {
+		parsed, err := url.Parse(repoURL)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid repository URL %s: %w", repoURL, err)
+		}
+		path = parsed.Path
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	owner, repo, found := strings.Cut(path, "/")
+	if !found || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", fmt.Errorf("cannot determine GitHub owner and repository from URL %s", repoURL)
+	}
+
+	return owner, repo, nil
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// fakeGitHub serves the pull request endpoints of the GitHub API for a single repository
+type fakeGitHub struct {
+	existing   []PullRequest
+	createCode int
+	created    map[string]string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer ghp_1234567890" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message": "Bad credentials"}`))
+		return
+	}
+
+	if r.URL.Path != "/repos/example/repo/pulls" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("head") != "example:yuk/my-app-v1.1.0" || r.URL.Query().Get("state") != "open" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(f.existing)
+
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&f.created); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if f.createCode != 0 {
+			w.WriteHeader(f.createCode)
+			_, _ = w.Write([]byte(`{"message": "Validation Failed"}`))
+			// The pull request was opened concurrently
+			f.existing = []PullRequest{{Number: 8, URL: "https://github.com/example/repo/pull/8"}}
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(PullRequest{Number: 7, URL: "https://github.com/example/repo/pull/7"})
+	}
+}
+
+func TestClient_CreatePullRequest(t *testing.T) {
+	opts := PullRequestOptions{
+		Head:  "yuk/my-app-v1.1.0",
+		Base:  "main",
+		Title: "Update container image to v1.1.0",
+		Body:  "Automated update",
+	}
+
+	tests := []struct {
+		name        string
+		github      *fakeGitHub
+		token       string
+		expectedURL string
+		shouldErr   bool
+	}{
+		{
+			name:        "opens new pull request",
+			github:      &fakeGitHub{},
+			token:       "ghp_1234567890",
+			expectedURL: "https://github.com/example/repo/pull/7",
+		},
+		{
+			name:        "reuses existing pull request",
+			github:      &fakeGitHub{existing: []PullRequest{{Number: 3, URL: "https://github.com/example/repo/pull/3"}}},
+			token:       "ghp_1234567890",
+			expectedURL: "https://github.com/example/repo/pull/3",
+		},
+		{
+			name:        "reuses concurrently opened pull request",
+			github:      &fakeGitHub{createCode: http.StatusUnprocessableEntity},
+			token:       "ghp_1234567890",
+			expectedURL: "https://github.com/example/repo/pull/8",
+		},
+		{
+			name:      "bad credentials",
+			github:    &fakeGitHub{},
+			token:     "wrong",
+			shouldErr: true,
+		},
+		{
+			name:      "no token",
+			github:    &fakeGitHub{},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.github)
+			defer server.Close()
+
+			client := NewClient(yukv1.GitConfig{Repository: "https://github.com/example/repo.git"},
+				WithToken(tt.token), WithGitHubAPIURL(server.URL+"/"))
+
+			pr, err := client.CreatePullRequest(context.Background(), opts)
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if pr.URL != tt.expectedURL {
+				t.Errorf("Expected pull request URL %s, got %s", tt.expectedURL, pr.URL)
+			}
+
+			if tt.github.created != nil && tt.github.created["base"] != "main" {
+				t.Errorf("Expected base branch main, got %s", tt.github.created["base"])
+			}
+		})
+	}
+}
+
+func TestClient_CreatePullRequest_APIErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := NewClient(yukv1.GitConfig{Repository: "https://github.com/example/repo.git"},
+		WithToken("ghp_1234567890"), WithGitHubAPIURL(server.URL))
+
+	_, err := client.CreatePullRequest(context.Background(), PullRequestOptions{Head: "yuk/my-app-v1.1.0", Base: "main"})
+
+	var statusErr interface{ HTTPStatusCode() int }
+	if !errors.As(err, &statusErr) || statusErr.HTTPStatusCode() != http.StatusBadGateway {
+		t.Errorf("Expected error with HTTP status 502, got %v", err)
+	}
+}
+
+func TestGithubRepository(t *testing.T) {
+	tests := []struct {
+		url           string
+		expectedOwner string
+		expectedRepo  string
+		shouldErr     bool
+	}{
+		{url: "https://github.com/example/repo.git", expectedOwner: "example", expectedRepo: "repo"},
+		{url: "https://github.com/example/repo", expectedOwner: "example", expectedRepo: "repo"},
+		{url: "git@github.com:example/repo.git", expectedOwner: "example", expectedRepo: "repo"},
+		{url: "https://github.com/example", shouldErr: true},
+		{url: "https://gitlab.com/group/subgroup/repo.git", shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			owner, repo, err := githubRepository(tt.url)
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if owner != tt.expectedOwner || repo != tt.expectedRepo {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.expectedOwner, tt.expectedRepo, owner, repo)
+			}
+		})
+	}
+}
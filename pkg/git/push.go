@@ -0,0 +1,122 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// DefaultMaxPushRetries is the number of times a rejected push is rebased and retried
+// when GitConfig.MaxPushRetries is not set
+const DefaultMaxPushRetries = 3
+
+// pushRetryBaseDelay is the delay before the first push retry. It doubles with every retry.
+const pushRetryBaseDelay = time.Second
+
+// WithPushRetryHook sets a function called after every rebase before a push retry, with
+// the retry attempt (starting at 1) and the rebase error, if any
+func WithPushRetryHook(hook func(attempt int, err error)) Option {
+	return func(c *Client) {
+		c.onPushRetry = hook
+	}
+}
+
+// maxPushRetries returns the number of times a rejected push is retried
+func (c *Client) maxPushRetries() int {
+	if c.config.MaxPushRetries == nil {
+		return DefaultMaxPushRetries
+	}
+	return max(int(*c.config.MaxPushRetries), 0)
+}
+
+// pushWithRetry pushes HEAD to the remote branch. When the push is rejected because the
+// branch moved, it waits with exponential backoff, rebases onto the remote branch and
+// pushes again, up to maxPushRetries times.
+func (c *Client) pushWithRetry(ctx context.Context, repoPath, branch string) error {
+	retries := c.maxPushRetries()
+	delay := c.pushRetryDelay
+
+	for attempt := 0; ; attempt++ {
+		err := c.push(ctx, repoPath, branch)
+		if err == nil || !errors.Is(err, ErrPushConflict) {
+			return err
+		}
+		if attempt >= retries {
+			if attempt > 0 {
+				return fmt.Errorf("push rejected after %d retries: %w", attempt, err)
+			}
+			return err
+		}
+
+		// Back off before rebasing onto the remote branch
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("push retry canceled: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+
+		err = c.rebase(ctx, repoPath, branch)
+		if c.onPushRetry != nil {
+			c.onPushRetry(attempt+1, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// push pushes HEAD to the given remote branch with the given push flags, e.g. --force
+func (c *Client) push(ctx context.Context, repoPath, branch string, flags ...string) error {
+	args := append([]string{"push", "origin", "HEAD:refs/heads/" + branch}, flags...)
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoPath
+	cmd.Env = c.commandEnv()
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to push changes: %w, output: %s", classifyError(err, output), output)
+	}
+
+	return nil
+}
+
+// rebase rebases the local commits onto the remote branch. A conflicting rebase is
+// aborted and reported as a push conflict, so the update is retried from a fresh clone.
+func (c *Client) rebase(ctx context.Context, repoPath, branch string) error {
+	cmd := exec.CommandContext(ctx, "git", "pull", "--rebase", "origin", branch)
+	cmd.Dir = repoPath
+	cmd.Env = c.commandEnv()
+
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+
+	abort := exec.CommandContext(ctx, "git", "rebase", "--abort")
+	abort.Dir = repoPath
+	_ = abort.Run()
+
+	if classified := classifyError(err, output); errors.Is(classified, ErrNetwork) {
+		return fmt.Errorf("failed to rebase onto %s: %w, output: %s", branch, classified, output)
+	}
+	return fmt.Errorf("%w: failed to rebase onto %s: %w, output: %s", ErrPushConflict, branch, err, output)
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// runGit runs a git command in dir, failing the test on error
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v, output: %s", args, err, output)
+	}
+	return string(output)
+}
+
+// newUpstream creates a repository on main with app.yaml and worker.yaml that accepts pushes
+func newUpstream(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "--initial-branch=main")
+	for name, content := range map[string]string{"app.yaml": "tag: v1.0.0\n", "worker.yaml": "tag: v1.0.0\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "initial")
+	runGit(t, dir, "config", "receive.denyCurrentBranch", "updateInstead")
+
+	return dir
+}
+
+// pushConcurrentChange commits a change to a file of the upstream repository from
+// another clone, moving main past the commit yuk cloned
+func pushConcurrentChange(t *testing.T, upstream, file, content string) {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "other")
+	runGit(t, t.TempDir(), "clone", upstream, dir)
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", file, err)
+	}
+	runGit(t, dir, "commit", "-am", "concurrent change")
+	runGit(t, dir, "push", "origin", "HEAD:main")
+}
+
+func TestClient_CommitAndPush_Retry(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxPushRetries *int32
+		concurrentFile string
+		expectedErr    bool
+		expectedHooks  int
+	}{
+		{
+			name:           "rebases onto a concurrent change",
+			concurrentFile: "worker.yaml",
+			expectedHooks:  1,
+		},
+		{
+			name:           "retries disabled",
+			maxPushRetries: new(int32),
+			concurrentFile: "worker.yaml",
+			expectedErr:    true,
+		},
+		{
+			name:           "conflicting concurrent change",
+			concurrentFile: "app.yaml",
+			expectedErr:    true,
+			expectedHooks:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := newUpstream(t)
+
+			var hookErrs []error
+			client := NewClient(yukv1.GitConfig{Repository: upstream, Branch: "main", MaxPushRetries: tt.maxPushRetries},
+				WithBaseDir(t.TempDir()), WithPushRetryHook(func(attempt int, err error) {
+					hookErrs = append(hookErrs, err)
+				}))
+			client.pushRetryDelay = 0
+
+			ctx := context.Background()
+			repoPath, err := client.Clone(ctx)
+			if err != nil {
+				t.Fatalf("Failed to clone: %v", err)
+			}
+			defer client.Cleanup(repoPath)
+
+			pushConcurrentChange(t, upstream, tt.concurrentFile, "tag: v1.0.1\n")
+
+			if err := os.WriteFile(filepath.Join(repoPath, "app.yaml"), []byte("tag: v1.1.0\n"), 0644); err != nil {
+				t.Fatalf("Failed to write app.yaml: %v", err)
+			}
+
+			err = client.CommitAndPush(ctx, repoPath, "Update container image to v1.1.0")
+			if len(hookErrs) != tt.expectedHooks {
+				t.Errorf("Expected %d push retries, got %d", tt.expectedHooks, len(hookErrs))
+			}
+
+			if tt.expectedErr {
+				if !errors.Is(err, ErrPushConflict) {
+					t.Fatalf("Expected push conflict, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			// Both the concurrent change and the update are on main
+			for file, expected := range map[string]string{"app.yaml": "tag: v1.1.0\n", "worker.yaml": "tag: v1.0.1\n"} {
+				content := runGit(t, upstream, "show", "main:"+file)
+				if content != expected {
+					t.Errorf("Expected %s to be %q, got %q", file, expected, content)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_maxPushRetries(t *testing.T) {
+	five, negative := int32(5), int32(-1)
+
+	tests := []struct {
+		name           string
+		maxPushRetries *int32
+		expected       int
+	}{
+		{name: "default", expected: DefaultMaxPushRetries},
+		{name: "configured", maxPushRetries: &five, expected: 5},
+		{name: "negative", maxPushRetries: &negative, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(yukv1.GitConfig{MaxPushRetries: tt.maxPushRetries})
+			if retries := client.maxPushRetries(); retries != tt.expected {
+				t.Errorf("Expected %d retries, got %d", tt.expected, retries)
+			}
+		})
+	}
+}
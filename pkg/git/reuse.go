@@ -0,0 +1,143 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CacheDirPrefix is the prefix of the directories reused clones are kept in. They are not
+// matched by CleanupOrphanedClones.
+const CacheDirPrefix = "yuk-cache-"
+
+// cloneSourceKey is the Git configuration key recording what a reused clone was cloned from
+const cloneSourceKey = "yuk.clonesource"
+
+// WithReuseClones keeps clones in the base directory after Cleanup and refreshes them with a
+// fetch and hard reset on the next Clone instead of cloning again. Callers must not clone
+// the same repository and branch concurrently.
+func WithReuseClones(reuse bool) Option {
+	return func(c *Client) {
+		c.reuseClones = reuse
+	}
+}
+
+// cloneSource describes what a clone of the branch contains and who may reuse it: the
+// repository, the branch, the clone depth, the sparse checkout directories, the namespace of
+// the YukConfig and its credentials. Clones are only reused for the same source, so a
+// YukConfig never reads a clone made with the credentials of another namespace.
+func (c *Client) cloneSource(branch string) string {
+	source := []string{c.config.Repository, branch, strconv.Itoa(c.cloneDepth())}
+	if c.sparseCheckout {
+		source = append(source, "sparse:"+strings.Join(c.sparsePaths, ","))
+	}
+	namespace, _, _ := strings.Cut(c.configRef, "/")
+	auth, _ := json.Marshal(c.config.Auth)
+	source = append(source, "namespace:"+namespace, "auth:"+string(auth))
+	return strings.Join(source, " ")
+}
+
+// cacheDir returns the directory the clone of the branch is kept in
+func (c *Client) cacheDir(branch string) string {
+	baseDir := c.baseDir
+	if baseDir == "" {
+		baseDir = os.TempDir()
+	}
+
+	sum := sha256.Sum256([]byte(c.cloneSource(branch)))
+	return filepath.Join(baseDir, CacheDirPrefix+hex.EncodeToString(sum[:8]))
+}
+
+// reusableClone reports whether dir holds a clone of the branch that can be refreshed
+// instead of cloning the repository again
+func (c *Client) reusableClone(ctx context.Context, dir, branch string) bool {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return false
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "config", "--get", cloneSourceKey)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(output)) == c.cloneSource(branch)
+}
+
+// cloneCached returns the clone of the branch kept in the base directory, refreshed to the
+// latest commit of the branch, or clones the branch there when it cannot be reused
+func (c *Client) cloneCached(ctx context.Context, branch, repoURL string) (string, error) {
+	dir := c.cacheDir(branch)
+	if c.reusableClone(ctx, dir, branch) && c.refreshClone(ctx, dir, branch, repoURL) == nil {
+		return dir, nil
+	}
+
+	os.RemoveAll(dir)
+	cmd := exec.CommandContext(ctx, "git", c.cloneArgs(branch, repoURL, dir)...)
+	cmd.Env = c.commandEnv()
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to clone repository: %w, output: %s", classifyError(err, output), output)
+	}
+
+	cmd = exec.CommandContext(ctx, "git", "config", cloneSourceKey, c.cloneSource(branch))
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to record clone source: %w, output: %s", err, output)
+	}
+
+	return dir, nil
+}
+
+// refreshClone fetches the branch into a reused clone and resets its working tree to it,
+// discarding local commits and changes left by a previous update
+func (c *Client) refreshClone(ctx context.Context, dir, branch, repoURL string) error {
+	fetch := []string{"fetch", "origin", branch}
+	if depth := c.cloneDepth(); depth > 0 {
+		fetch = []string{"fetch", "--depth", strconv.Itoa(depth), "origin", branch}
+	}
+
+	// The remote URL of clones made by earlier versions may carry a token; replace it with
+	// the plain repository URL, as commandEnv authenticates each command
+	for _, args := range [][]string{
+		{"remote", "set-url", "origin", repoURL},
+		fetch,
+		{"checkout", "--force", "-B", branch, "FETCH_HEAD"},
+		{"clean", "-ffdx"},
+	} {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = dir
+		cmd.Env = c.commandEnv()
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to refresh clone: git %s: %w, output: %s", args[0], classifyError(err, output), output)
+		}
+	}
+
+	return nil
+}
+
+// isCachedClone reports whether a clone is kept for reuse rather than removed by Cleanup
+func (c *Client) isCachedClone(repoPath string) bool {
+	return c.reuseClones && strings.HasPrefix(filepath.Base(repoPath), CacheDirPrefix)
+}
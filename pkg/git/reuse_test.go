@@ -0,0 +1,151 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestClient_reusableClone(t *testing.T) {
+	upstream := newUpstream(t)
+	baseDir := t.TempDir()
+	ctx := context.Background()
+
+	newClient := func(branch string, opts ...Option) *Client {
+		return NewClient(yukv1.GitConfig{Repository: "file://" + upstream, Branch: branch},
+			append([]Option{WithBaseDir(baseDir), WithReuseClones(true)}, opts...)...)
+	}
+
+	// Nothing to reuse before the first clone
+	client := newClient("main")
+	dir := client.cacheDir("main")
+	if client.reusableClone(ctx, dir, "main") {
+		t.Fatal("Expected a missing clone not to be reusable")
+	}
+
+	repoPath, err := client.Clone(ctx)
+	if err != nil {
+		t.Fatalf("Failed to clone: %v", err)
+	}
+	client.Cleanup(repoPath)
+	if repoPath != dir {
+		t.Errorf("Expected the clone in %s, got %s", dir, repoPath)
+	}
+
+	tests := []struct {
+		name     string
+		client   *Client
+		branch   string
+		expected bool
+	}{
+		{
+			name:     "same repository and branch",
+			client:   newClient("main"),
+			branch:   "main",
+			expected: true,
+		},
+		{
+			name:   "other branch",
+			client: newClient("release"),
+			branch: "release",
+		},
+		{
+			name:   "other sparse checkout",
+			client: newClient("main", WithSparseCheckout([]string{"apps"})),
+			branch: "main",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Only clones of the same source are reused; other sources are kept elsewhere
+			if reusable := tt.client.reusableClone(ctx, dir, tt.branch); reusable != tt.expected {
+				t.Errorf("Expected reusable to be %v, got %v", tt.expected, reusable)
+			}
+			if tt.client.cacheDir(tt.branch) == dir && !tt.expected {
+				t.Errorf("Expected another directory than %s", dir)
+			}
+		})
+	}
+
+	// A directory that is not a clone of the source is not reused
+	runGit(t, dir, "config", cloneSourceKey, "https://github.com/example/other.git main 1")
+	if client.reusableClone(ctx, dir, "main") {
+		t.Error("Expected a clone of another source not to be reusable")
+	}
+}
+
+func TestClient_Clone_Reuse(t *testing.T) {
+	upstream := newUpstream(t)
+	client := NewClient(yukv1.GitConfig{Repository: "file://" + upstream, Branch: "main"},
+		WithBaseDir(t.TempDir()), WithReuseClones(true))
+	client.pushRetryDelay = 0
+	ctx := context.Background()
+
+	repoPath, err := client.Clone(ctx)
+	if err != nil {
+		t.Fatalf("Failed to clone: %v", err)
+	}
+	writeFile(t, filepath.Join(repoPath, "app.yaml"), "tag: v1.1.0\n")
+	if err := client.CommitAndPush(ctx, repoPath, "Update container image to v1.1.0"); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+
+	// Leftovers of the update and a commit that was never pushed are discarded
+	writeFile(t, filepath.Join(repoPath, "untracked.yaml"), "tag: v0.0.1\n")
+	writeFile(t, filepath.Join(repoPath, "worker.yaml"), "tag: v0.0.1\n")
+	runGit(t, repoPath, "commit", "-am", "unpushed")
+	client.Cleanup(repoPath)
+	if _, err := os.Stat(repoPath); err != nil {
+		t.Fatalf("Expected the clone to be kept: %v", err)
+	}
+
+	// The next clone is refreshed with the changes of others
+	pushConcurrentChange(t, upstream, "worker.yaml", "tag: v1.2.0\n")
+	marker := filepath.Join(repoPath, ".git", "reused")
+	writeFile(t, marker, "")
+
+	reused, err := client.Clone(ctx)
+	if err != nil {
+		t.Fatalf("Failed to clone again: %v", err)
+	}
+	defer client.Cleanup(reused)
+
+	if reused != repoPath {
+		t.Errorf("Expected the clone in %s to be reused, got %s", repoPath, reused)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Error("Expected the existing clone to be refreshed instead of cloned again")
+	}
+	for file, expected := range map[string]string{"app.yaml": "tag: v1.1.0\n", "worker.yaml": "tag: v1.2.0\n"} {
+		if content, _ := os.ReadFile(filepath.Join(reused, file)); string(content) != expected {
+			t.Errorf("Expected %s to be %q, got %q", file, expected, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(reused, "untracked.yaml")); !os.IsNotExist(err) {
+		t.Error("Expected untracked files to be removed")
+	}
+	if head, upstreamHead := runGit(t, reused, "rev-parse", "HEAD"), runGit(t, upstream, "rev-parse", "main"); head != upstreamHead {
+		t.Errorf("Expected HEAD %s, got %s", strings.TrimSpace(upstreamHead), strings.TrimSpace(head))
+	}
+}
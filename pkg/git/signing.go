@@ -0,0 +1,178 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// WithSigningKey signs commits with the given armored GPG private key. The passphrase
+// unlocks a protected key and may be empty.
+func WithSigningKey(key, passphrase []byte) Option {
+	return func(c *Client) {
+		c.signingKey = key
+		c.signingPassphrase = passphrase
+	}
+}
+
+// signing reports whether commits are signed
+func (c *Client) signing() bool {
+	return len(c.signingKey) > 0
+}
+
+// commitArgs returns the arguments of the git command committing the staged changes,
+// amending the HEAD commit when amend is set
+func (c *Client) commitArgs(commitMessage string, amend bool) []string {
+	args := []string{"commit"}
+	if amend {
+		args = append(args, "--amend")
+	}
+	if c.signing() {
+		args = append(args, "-S")
+	}
+	return append(args, "-m", commitMessage)
+}
+
+// configureSigning imports the signing key into a GPG home only used by this client and
+// configures the clone to sign commits with it. The GPG home is removed by Cleanup.
+func (c *Client) configureSigning(ctx context.Context, repoPath string) error {
+	if !c.signing() {
+		return nil
+	}
+
+	if c.gnupgHome == "" {
+		// os.MkdirTemp creates the directory with mode 0700, as GPG requires
+		home, err := os.MkdirTemp(c.baseDir, CloneDirPrefix+"gnupg-")
+		if err != nil {
+			return fmt.Errorf("failed to create GPG home: %w", err)
+		}
+		c.gnupgHome = home
+
+		if err := c.importSigningKey(ctx); err != nil {
+			return err
+		}
+	}
+
+	fingerprint, err := c.signingKeyFingerprint(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Git runs GPG through a wrapper pointing it at the GPG home and passphrase
+	program, err := c.writeGPGProgram()
+	if err != nil {
+		return err
+	}
+
+	for _, setting := range [][2]string{
+		{"user.signingkey", fingerprint},
+		{"commit.gpgsign", "true"},
+		{"gpg.program", program},
+	} {
+		cmd := exec.CommandContext(ctx, "git", "config", setting[0], setting[1])
+		cmd.Dir = repoPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to set git %s: %w, output: %s", setting[0], err, output)
+		}
+	}
+
+	return nil
+}
+
+// importSigningKey imports the signing key into the GPG home
+func (c *Client) importSigningKey(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "gpg", c.gpgArgs("--import")...)
+	cmd.Stdin = bytes.NewReader(c.signingKey)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to import GPG signing key: %w, output: %s", err, output)
+	}
+
+	return nil
+}
+
+// signingKeyFingerprint returns the fingerprint of the imported secret key
+func (c *Client) signingKeyFingerprint(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "gpg", c.gpgArgs("--with-colons", "--list-secret-keys")...)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list GPG secret keys: %w", err)
+	}
+
+	// The first fpr record follows the primary key's sec record
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Split(line, ":")
+		if fields[0] == "fpr" && len(fields) > 9 {
+			return fields[9], nil
+		}
+	}
+
+	return "", fmt.Errorf("GPG signing key does not contain a secret key")
+}
+
+// writeGPGProgram writes the script git runs instead of gpg, and the passphrase file it
+// reads, to the GPG home
+func (c *Client) writeGPGProgram() (string, error) {
+	args := c.gpgArgs()
+
+	if len(c.signingPassphrase) > 0 {
+		passphraseFile := filepath.Join(c.gnupgHome, "passphrase")
+		if err := os.WriteFile(passphraseFile, c.signingPassphrase, 0600); err != nil {
+			return "", fmt.Errorf("failed to write GPG passphrase file: %w", err)
+		}
+		args = append(args, "--pinentry-mode", "loopback", "--passphrase-file", passphraseFile)
+	}
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+
+	program := filepath.Join(c.gnupgHome, "gpg-sign")
+	script := fmt.Sprintf("#!/bin/sh\nexec gpg %s \"$@\"\n", strings.Join(quoted, " "))
+	if err := os.WriteFile(program, []byte(script), 0700); err != nil {
+		return "", fmt.Errorf("failed to write GPG program: %w", err)
+	}
+
+	return program, nil
+}
+
+// gpgArgs returns the arguments of a non-interactive gpg command using the GPG home
+func (c *Client) gpgArgs(args ...string) []string {
+	return append([]string{"--homedir", c.gnupgHome, "--batch", "--no-tty"}, args...)
+}
+
+// cleanupSigning stops the GPG agent started for the GPG home and removes it
+func (c *Client) cleanupSigning() {
+	if c.gnupgHome == "" {
+		return
+	}
+
+	_ = exec.Command("gpgconf", "--homedir", c.gnupgHome, "--kill", "gpg-agent").Run()
+	os.RemoveAll(c.gnupgHome)
+	c.gnupgHome = ""
+}
+
+// shellQuote quotes a string for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// newSigningKey generates a GPG signing key protected by the given passphrase and
+// returns it armored
+func newSigningKey(t *testing.T, passphrase string) []byte {
+	t.Helper()
+
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+
+	home, err := os.MkdirTemp("", "gnupg-")
+	if err != nil {
+		t.Fatalf("Failed to create GPG home: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = exec.Command("gpgconf", "--homedir", home, "--kill", "gpg-agent").Run()
+		os.RemoveAll(home)
+	})
+
+	gpg := func(args ...string) []byte {
+		cmd := exec.Command("gpg", append([]string{"--homedir", home, "--batch", "--pinentry-mode", "loopback", "--passphrase", passphrase}, args...)...)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("gpg %v failed: %v", args, err)
+		}
+		return output
+	}
+
+	gpg("--quick-gen-key", "Yuk Test <yuk@example.com>", "ed25519", "sign", "never")
+	return gpg("--armor", "--export-secret-keys", "yuk@example.com")
+}
+
+func TestClient_commitArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		amend    bool
+		expected string
+	}{
+		{
+			name:     "unsigned",
+			expected: "commit -m update",
+		},
+		{
+			name:     "signed",
+			opts:     []Option{WithSigningKey([]byte("key"), nil)},
+			expected: "commit -S -m update",
+		},
+		{
+			name:     "signed amend",
+			opts:     []Option{WithSigningKey([]byte("key"), nil)},
+			amend:    true,
+			expected: "commit --amend -S -m update",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(yukv1.GitConfig{}, tt.opts...)
+			if args := strings.Join(client.commitArgs("update", tt.amend), " "); args != tt.expected {
+				t.Errorf("Expected commit args %q, got %q", tt.expected, args)
+			}
+		})
+	}
+}
+
+func TestClient_CommitAndPush_Signed(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+	}{
+		{name: "unprotected key"},
+		{name: "passphrase protected key", passphrase: "s3cret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := newSigningKey(t, tt.passphrase)
+			upstream := newUpstream(t)
+
+			// The GPG agent socket lives in the GPG home, and socket paths are limited to
+			// about 100 characters, too few for test temporary directories
+			baseDir, err := os.MkdirTemp("", "yuk-")
+			if err != nil {
+				t.Fatalf("Failed to create base directory: %v", err)
+			}
+			defer os.RemoveAll(baseDir)
+
+			ctx := context.Background()
+			client := NewClient(yukv1.GitConfig{Repository: upstream, Branch: "main"},
+				WithBaseDir(baseDir), WithSigningKey(key, []byte(tt.passphrase)))
+
+			repoPath, err := client.Clone(ctx)
+			if err != nil {
+				t.Fatalf("Clone failed: %v", err)
+			}
+			gnupgHome := client.gnupgHome
+
+			if value := strings.TrimSpace(runGit(t, repoPath, "config", "commit.gpgsign")); value != "true" {
+				t.Errorf("Expected commit.gpgsign true, got %q", value)
+			}
+			if value := strings.TrimSpace(runGit(t, repoPath, "config", "user.signingkey")); len(value) != 40 {
+				t.Errorf("Expected user.signingkey to be a key fingerprint, got %q", value)
+			}
+
+			if err := os.WriteFile(filepath.Join(repoPath, "app.yaml"), []byte("tag: v1.1.0\n"), 0644); err != nil {
+				t.Fatalf("Failed to write app.yaml: %v", err)
+			}
+			if err := client.CommitAndPush(ctx, repoPath, "Update app to v1.1.0"); err != nil {
+				t.Fatalf("CommitAndPush failed: %v", err)
+			}
+			client.Cleanup(repoPath)
+
+			if commit := runGit(t, upstream, "cat-file", "commit", "HEAD"); !strings.Contains(commit, "gpgsig ") {
+				t.Errorf("Expected pushed commit to be signed, got:\n%s", commit)
+			}
+			if _, err := os.Stat(gnupgHome); !os.IsNotExist(err) {
+				t.Errorf("Expected GPG home %s to be removed", gnupgHome)
+			}
+		})
+	}
+}
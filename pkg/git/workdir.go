@@ -0,0 +1,82 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ValidateBaseDir ensures the clone base directory exists and is writable.
+// An empty directory refers to the default temporary directory.
+func ValidateBaseDir(dir string) error {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create clone base directory %s: %w", dir, err)
+	}
+
+	// Verify we can actually create files in the directory
+	probe, err := os.CreateTemp(dir, ".yuk-write-check-")
+	if err != nil {
+		return fmt.Errorf("clone base directory %s is not writable: %w", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	return nil
+}
+
+// CleanupOrphanedClones removes leftover clone directories and SSH key files in the base
+// directory, e.g. from a previous run that crashed before Cleanup was called. Only entries
+// last modified more than olderThan ago are removed, so clones in use by another
+// process sharing the directory are left alone. It returns the number of entries removed.
+func CleanupOrphanedClones(dir string, olderThan time.Duration) (int, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, CloneDirPrefix+"*"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list clone directories in %s: %w", dir, err)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	removed := 0
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			continue
+		}
+
+		// Skip directories that may still be in use
+		if info.ModTime().After(cutoff) {
+			continue
+		}
+
+		if err := os.RemoveAll(match); err != nil {
+			return removed, fmt.Errorf("failed to remove clone directory %s: %w", match, err)
+		}
+		removed++
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestNewClient_WithBaseDir(t *testing.T) {
+	baseDir := t.TempDir()
+	client := NewClient(yukv1.GitConfig{}, WithBaseDir(baseDir))
+
+	if client.baseDir != baseDir {
+		t.Errorf("Expected base dir %s, got %s", baseDir, client.baseDir)
+	}
+}
+
+func TestValidateBaseDir(t *testing.T) {
+	// A missing directory is created
+	baseDir := filepath.Join(t.TempDir(), "clones")
+	if err := ValidateBaseDir(baseDir); err != nil {
+		t.Fatalf("Expected no error for creatable directory, got: %v", err)
+	}
+
+	if info, err := os.Stat(baseDir); err != nil || !info.IsDir() {
+		t.Errorf("Expected base directory %s to be created", baseDir)
+	}
+
+	// A path that is a file cannot be used
+	filePath := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(filePath, []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := ValidateBaseDir(filePath); err == nil {
+		t.Error("Expected error for non-directory base dir, got none")
+	}
+}
+
+func TestCleanupOrphanedClones(t *testing.T) {
+	baseDir := t.TempDir()
+
+	stale := time.Now().Add(-2 * time.Hour)
+
+	for _, name := range []string{CloneDirPrefix + "123", CloneDirPrefix + "456", CloneDirPrefix + "active", "other-dir"} {
+		dir := filepath.Join(baseDir, name)
+		if err := os.MkdirAll(filepath.Join(dir, ".git"), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if name == CloneDirPrefix+"active" {
+			continue
+		}
+		if err := os.Chtimes(dir, stale, stale); err != nil {
+			t.Fatalf("Failed to set directory times: %v", err)
+		}
+	}
+
+	removed, err := CleanupOrphanedClones(baseDir, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to clean up orphaned clones: %v", err)
+	}
+
+	if removed != 2 {
+		t.Errorf("Expected 2 directories removed, got %d", removed)
+	}
+
+	if _, err := os.Stat(filepath.Join(baseDir, CloneDirPrefix+"active")); err != nil {
+		t.Errorf("Expected recent clone directory to be preserved: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(baseDir, "other-dir")); err != nil {
+		t.Errorf("Expected unrelated directory to be preserved: %v", err)
+	}
+}
@@ -17,20 +17,33 @@ limitations under the License.
 package metrics
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/rebelopsio/yuk/pkg/version"
+)
+
+var (
+	// DefaultReconciliationBuckets are the default buckets of ReconciliationDuration, in seconds
+	DefaultReconciliationBuckets = []float64{0.1, 0.5, 1.0, 2.5, 5.0, 10.0, 30.0, 60.0}
+
+	// DefaultRepositoryCheckBuckets are the default buckets of RepositoryCheckDuration, in seconds
+	DefaultRepositoryCheckBuckets = []float64{0.1, 0.5, 1.0, 2.5, 5.0, 10.0, 30.0}
+
+	// DefaultGitOperationBuckets are the default buckets of GitOperationDuration, in seconds
+	DefaultGitOperationBuckets = []float64{0.5, 1.0, 2.5, 5.0, 10.0, 30.0, 60.0, 120.0}
+
+	// DefaultTimeToUpdateBuckets are the default buckets of TimeToUpdate, in seconds
+	DefaultTimeToUpdateBuckets = []float64{60, 300, 600, 1800, 3600, 7200, 21600, 86400}
 )
 
 var (
 	// ReconciliationDuration tracks the time taken for reconciliation
-	ReconciliationDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "yuk_controller_reconciliation_duration_seconds",
-			Help:    "Time taken for YukConfig reconciliation",
-			Buckets: []float64{0.1, 0.5, 1.0, 2.5, 5.0, 10.0, 30.0, 60.0},
-		},
-		[]string{"namespace", "name", "result"},
-	)
+	ReconciliationDuration = newReconciliationDuration(DefaultReconciliationBuckets)
 
 	// ReconciliationTotal tracks the total number of reconciliations
 	ReconciliationTotal = prometheus.NewCounterVec(
@@ -51,11 +64,13 @@ var (
 	)
 
 	// RepositoryCheckDuration tracks time taken for repository checks
-	RepositoryCheckDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "yuk_repository_check_duration_seconds",
-			Help:    "Time taken for repository checks",
-			Buckets: []float64{0.1, 0.5, 1.0, 2.5, 5.0, 10.0, 30.0},
+	RepositoryCheckDuration = newRepositoryCheckDuration(DefaultRepositoryCheckBuckets)
+
+	// RepositoryCheckRateLimited tracks repository checks deferred by the rate limiter
+	RepositoryCheckRateLimited = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "yuk_repository_check_ratelimited_total",
+			Help: "Total number of repository checks deferred by the check rate limiter",
 		},
 		[]string{"repository_type", "repository_name"},
 	)
@@ -70,14 +85,7 @@ var (
 	)
 
 	// GitOperationDuration tracks time taken for Git operations
-	GitOperationDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "yuk_git_operation_duration_seconds",
-			Help:    "Time taken for Git operations",
-			Buckets: []float64{0.5, 1.0, 2.5, 5.0, 10.0, 30.0, 60.0, 120.0},
-		},
-		[]string{"operation", "repository"},
-	)
+	GitOperationDuration = newGitOperationDuration(DefaultGitOperationBuckets)
 
 	// UpdatesPerformed tracks successful updates
 	UpdatesPerformed = prometheus.NewCounterVec(
@@ -97,6 +105,24 @@ var (
 		[]string{"namespace", "name", "file_path"},
 	)
 
+	// TargetsSkipped tracks the optional update targets skipped because they do not exist
+	TargetsSkipped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "yuk_targets_skipped_total",
+			Help: "Total number of optional update targets skipped because their file, path or entry does not exist",
+		},
+		[]string{"namespace", "name", "file_path"},
+	)
+
+	// LastChangedFiles tracks how many files the last committed update changed
+	LastChangedFiles = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "yuk_last_changed_files",
+			Help: "Number of distinct files changed by the last committed update",
+		},
+		[]string{"namespace", "name"},
+	)
+
 	// CurrentVersion tracks the current version being monitored
 	CurrentVersion = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -133,6 +159,53 @@ var (
 		[]string{"namespace", "name", "repository_name"},
 	)
 
+	// PossiblyPinned tracks whether the latest tag has stopped advancing
+	PossiblyPinned = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "yuk_possibly_pinned",
+			Help: "Whether the latest tag has been unchanged for longer than the pinned threshold (1=possibly pinned, 0=advancing)",
+		},
+		[]string{"namespace", "name", "repository_name"},
+	)
+
+	// TagOutOfDate tracks whether the current tag lags behind the latest tag
+	TagOutOfDate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "yuk_tag_out_of_date",
+			Help: "Whether the current tag is behind the latest tag (1=out of date, 0=up to date)",
+		},
+		[]string{"namespace", "name", "repository_name"},
+	)
+
+	// TimeToUpdate tracks the time from the push of an image to the commit updating to it
+	TimeToUpdate = newTimeToUpdate(DefaultTimeToUpdateBuckets)
+
+	// UpdateLag tracks how long the current tag has been behind the latest tag
+	UpdateLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "yuk_update_lag_seconds",
+			Help: "Time since the latest tag was first observed while the current tag differs from it (0 when up to date)",
+		},
+		[]string{"namespace", "name", "repository_name"},
+	)
+
+	// VersionsBehind tracks how many versions the current tag is behind the latest tag
+	VersionsBehind = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "yuk_versions_behind",
+			Help: "Difference of the most significant differing semantic version part of the current and latest tags (0 when up to date)",
+		},
+		[]string{"namespace", "name", "repository_name"},
+	)
+
+	// Leader tracks whether this replica is the active leader
+	Leader = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "yuk_leader",
+			Help: "Whether this replica is the active leader (1=leader, 0=standby)",
+		},
+	)
+
 	// QueueDepth tracks the controller's work queue depth
 	QueueDepth = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -142,6 +215,24 @@ var (
 		[]string{"controller"},
 	)
 
+	// NotificationsTotal tracks notifications sent about updates and failures
+	NotificationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "yuk_notifications_total",
+			Help: "Total number of notifications sent",
+		},
+		[]string{"notifier", "namespace", "name", "result"},
+	)
+
+	// BuildInfo exposes the version of the running controller
+	BuildInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "yuk_build_info",
+			Help: "Build information of the running controller (always 1)",
+		},
+		[]string{"version", "commit", "go_version"},
+	)
+
 	// ErrorsTotal tracks various types of errors
 	ErrorsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -152,26 +243,217 @@ var (
 	)
 )
 
-// RegisterMetrics registers all Yuk metrics with the controller-runtime metrics registry
-func RegisterMetrics() {
+func newReconciliationDuration(buckets []float64) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "yuk_controller_reconciliation_duration_seconds",
+			Help:    "Time taken for YukConfig reconciliation",
+			Buckets: buckets,
+		},
+		[]string{"namespace", "name", "result"},
+	)
+}
+
+func newRepositoryCheckDuration(buckets []float64) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "yuk_repository_check_duration_seconds",
+			Help:    "Time taken for repository checks",
+			Buckets: buckets,
+		},
+		[]string{"repository_type", "repository_name"},
+	)
+}
+
+func newGitOperationDuration(buckets []float64) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "yuk_git_operation_duration_seconds",
+			Help:    "Time taken for Git operations",
+			Buckets: buckets,
+		},
+		[]string{"operation", "repository"},
+	)
+}
+
+func newTimeToUpdate(buckets []float64) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "yuk_time_to_update_seconds",
+			Help:    "Time from the push of an image to the commit updating to it",
+			Buckets: buckets,
+		},
+		[]string{"namespace", "name", "repository_name"},
+	)
+}
+
+// options holds the histogram buckets of the metrics
+type options struct {
+	reconciliationBuckets  []float64
+	repositoryCheckBuckets []float64
+	gitOperationBuckets    []float64
+	timeToUpdateBuckets    []float64
+}
+
+// Option configures the metrics registered by RegisterMetrics
+type Option func(*options)
+
+// WithReconciliationBuckets sets the buckets of ReconciliationDuration, in seconds. An
+// empty list keeps the defaults.
+func WithReconciliationBuckets(buckets []float64) Option {
+	return func(o *options) {
+		if len(buckets) > 0 {
+			o.reconciliationBuckets = buckets
+		}
+	}
+}
+
+// WithRepositoryCheckBuckets sets the buckets of RepositoryCheckDuration, in seconds. An
+// empty list keeps the defaults.
+func WithRepositoryCheckBuckets(buckets []float64) Option {
+	return func(o *options) {
+		if len(buckets) > 0 {
+			o.repositoryCheckBuckets = buckets
+		}
+	}
+}
+
+// WithGitOperationBuckets sets the buckets of GitOperationDuration, in seconds. An empty
+// list keeps the defaults.
+func WithGitOperationBuckets(buckets []float64) Option {
+	return func(o *options) {
+		if len(buckets) > 0 {
+			o.gitOperationBuckets = buckets
+		}
+	}
+}
+
+// WithTimeToUpdateBuckets sets the buckets of TimeToUpdate, in seconds. An empty list
+// keeps the defaults.
+func WithTimeToUpdateBuckets(buckets []float64) Option {
+	return func(o *options) {
+		if len(buckets) > 0 {
+			o.timeToUpdateBuckets = buckets
+		}
+	}
+}
+
+// configure recreates the histograms with the buckets of the options. It must run before
+// the histograms are registered or observed.
+func configure(opts ...Option) {
+	o := options{
+		reconciliationBuckets:  DefaultReconciliationBuckets,
+		repositoryCheckBuckets: DefaultRepositoryCheckBuckets,
+		gitOperationBuckets:    DefaultGitOperationBuckets,
+		timeToUpdateBuckets:    DefaultTimeToUpdateBuckets,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ReconciliationDuration = newReconciliationDuration(o.reconciliationBuckets)
+	RepositoryCheckDuration = newRepositoryCheckDuration(o.repositoryCheckBuckets)
+	GitOperationDuration = newGitOperationDuration(o.gitOperationBuckets)
+	TimeToUpdate = newTimeToUpdate(o.timeToUpdateBuckets)
+}
+
+// ParseBuckets parses a comma-separated list of histogram bucket upper bounds in seconds,
+// e.g. "1,5,30,120,300". The bounds must be positive and increasing.
+func ParseBuckets(value string) ([]float64, error) {
+	var buckets []float64
+	for _, field := range strings.Split(value, ",") {
+		bucket, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket %q: %w", field, err)
+		}
+		if bucket <= 0 {
+			return nil, fmt.Errorf("invalid bucket %q: must be positive", field)
+		}
+		if len(buckets) > 0 && bucket <= buckets[len(buckets)-1] {
+			return nil, fmt.Errorf("invalid bucket %q: buckets must be increasing", field)
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, nil
+}
+
+// RegisterMetrics registers all Yuk metrics with the controller-runtime metrics registry,
+// with the histogram buckets of the options
+func RegisterMetrics(opts ...Option) {
+	configure(opts...)
+
+	BuildInfo.With(prometheus.Labels{
+		"version":    version.Version,
+		"commit":     version.Commit,
+		"go_version": version.GoVersion(),
+	}).Set(1)
+
 	metrics.Registry.MustRegister(
 		ReconciliationDuration,
 		ReconciliationTotal,
 		RepositoryChecks,
 		RepositoryCheckDuration,
+		RepositoryCheckRateLimited,
 		GitOperations,
 		GitOperationDuration,
 		UpdatesPerformed,
 		FilesUpdated,
+		TargetsSkipped,
+		LastChangedFiles,
 		CurrentVersion,
 		ConfigStatus,
 		LastCheckTimestamp,
 		LastUpdateTimestamp,
+		PossiblyPinned,
+		TagOutOfDate,
+		TimeToUpdate,
+		UpdateLag,
+		VersionsBehind,
+		Leader,
 		QueueDepth,
+		NotificationsTotal,
+		BuildInfo,
 		ErrorsTotal,
 	)
 }
 
+// configMetric is a metric with series labeled by the namespace and name of a YukConfig
+type configMetric interface {
+	DeletePartialMatch(labels prometheus.Labels) int
+}
+
+// configMetrics returns the metrics with series labeled by the namespace and name of a
+// YukConfig. The histograms are looked up on every call, as RegisterMetrics recreates them.
+func configMetrics() []configMetric {
+	return []configMetric{
+		ReconciliationDuration,
+		ReconciliationTotal,
+		UpdatesPerformed,
+		FilesUpdated,
+		TargetsSkipped,
+		LastChangedFiles,
+		CurrentVersion,
+		ConfigStatus,
+		LastCheckTimestamp,
+		LastUpdateTimestamp,
+		PossiblyPinned,
+		TagOutOfDate,
+		TimeToUpdate,
+		UpdateLag,
+		VersionsBehind,
+		NotificationsTotal,
+		ErrorsTotal,
+	}
+}
+
+// DeleteConfigMetrics removes all series of a deleted YukConfig
+func DeleteConfigMetrics(namespace, name string) {
+	labels := prometheus.Labels{"namespace": namespace, "name": name}
+	for _, metric := range configMetrics() {
+		metric.DeletePartialMatch(labels)
+	}
+}
+
 // ReconciliationResult represents the result of a reconciliation
 type ReconciliationResult string
 
@@ -179,6 +461,9 @@ const (
 	ReconciliationSuccess ReconciliationResult = "success"
 	ReconciliationError   ReconciliationResult = "error"
 	ReconciliationSkipped ReconciliationResult = "skipped"
+	// ReconciliationThrottled is a reconcile returning early because the check interval
+	// has not elapsed since the last check
+	ReconciliationThrottled ReconciliationResult = "throttled"
 )
 
 // RepositoryCheckResult represents the result of a repository check
@@ -193,9 +478,11 @@ const (
 type GitOperationType string
 
 const (
-	GitOperationClone  GitOperationType = "clone"
-	GitOperationCommit GitOperationType = "commit"
-	GitOperationPush   GitOperationType = "push"
+	GitOperationClone       GitOperationType = "clone"
+	GitOperationCommit      GitOperationType = "commit"
+	GitOperationPush        GitOperationType = "push"
+	GitOperationPullRequest GitOperationType = "pull_request"
+	GitOperationPushRetry   GitOperationType = "push_retry"
 )
 
 // GitOperationResult represents the result of a Git operation
@@ -206,6 +493,14 @@ const (
 	GitOperationError   GitOperationResult = "error"
 )
 
+// NotificationResult represents the result of sending a notification
+type NotificationResult string
+
+const (
+	NotificationSuccess NotificationResult = "success"
+	NotificationError   NotificationResult = "error"
+)
+
 // ErrorType represents different types of errors
 type ErrorType string
 
@@ -17,10 +17,16 @@ limitations under the License.
 package metrics
 
 import (
+	"reflect"
+	"runtime"
 	"testing"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/rebelopsio/yuk/pkg/version"
 )
 
 func TestRegisterMetrics(t *testing.T) {
@@ -47,6 +53,37 @@ func TestRegisterMetrics(t *testing.T) {
 	if GitOperations == nil {
 		t.Error("GitOperations metric is nil")
 	}
+
+	// The build info is registered with the version of the binary
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	var buildInfo *dto.MetricFamily
+	for _, family := range families {
+		if family.GetName() == "yuk_build_info" {
+			buildInfo = family
+		}
+	}
+	if buildInfo == nil || len(buildInfo.GetMetric()) != 1 {
+		t.Fatalf("Expected one yuk_build_info series, got %v", buildInfo)
+	}
+
+	labels := make(map[string]string)
+	for _, label := range buildInfo.GetMetric()[0].GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	expected := map[string]string{
+		"version":    version.Version,
+		"commit":     version.Commit,
+		"go_version": runtime.Version(),
+	}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("Expected build info labels %v, got %v", expected, labels)
+	}
+	if value := buildInfo.GetMetric()[0].GetGauge().GetValue(); value != 1 {
+		t.Errorf("Expected build info value 1, got %v", value)
+	}
 }
 
 func TestMetricConstants(t *testing.T) {
@@ -63,6 +100,10 @@ func TestMetricConstants(t *testing.T) {
 		t.Errorf("Expected ReconciliationSkipped to be 'skipped', got %s", ReconciliationSkipped)
 	}
 
+	if ReconciliationThrottled != "throttled" {
+		t.Errorf("Expected ReconciliationThrottled to be 'throttled', got %s", ReconciliationThrottled)
+	}
+
 	// Test GitOperationType constants
 	if GitOperationClone != "clone" {
 		t.Errorf("Expected GitOperationClone to be 'clone', got %s", GitOperationClone)
@@ -76,6 +117,14 @@ func TestMetricConstants(t *testing.T) {
 		t.Errorf("Expected GitOperationPush to be 'push', got %s", GitOperationPush)
 	}
 
+	if GitOperationPullRequest != "pull_request" {
+		t.Errorf("Expected GitOperationPullRequest to be 'pull_request', got %s", GitOperationPullRequest)
+	}
+
+	if GitOperationPushRetry != "push_retry" {
+		t.Errorf("Expected GitOperationPushRetry to be 'push_retry', got %s", GitOperationPushRetry)
+	}
+
 	// Test ErrorType constants
 	if ErrorTypeRepository != "repository" {
 		t.Errorf("Expected ErrorTypeRepository to be 'repository', got %s", ErrorTypeRepository)
@@ -109,3 +158,74 @@ func TestMetricLabels(t *testing.T) {
 		t.Errorf("Expected metric value to be 1.0, got %f", value)
 	}
 }
+
+// bucketBounds returns the bucket upper bounds of a histogram
+func bucketBounds(t *testing.T, histogram prometheus.Observer) []float64 {
+	t.Helper()
+
+	var metric dto.Metric
+	if err := histogram.(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatalf("Failed to write histogram: %v", err)
+	}
+	var bounds []float64
+	for _, bucket := range metric.GetHistogram().GetBucket() {
+		bounds = append(bounds, bucket.GetUpperBound())
+	}
+	return bounds
+}
+
+func TestConfigureBuckets(t *testing.T) {
+	t.Cleanup(func() { configure() })
+
+	configure(
+		WithGitOperationBuckets([]float64{1, 5, 30, 120, 300, 600}),
+		WithReconciliationBuckets(nil),
+		WithTimeToUpdateBuckets([]float64{60, 600, 3600}),
+	)
+
+	got := bucketBounds(t, GitOperationDuration.WithLabelValues("clone", "repo"))
+	if expected := []float64{1, 5, 30, 120, 300, 600}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected Git operation buckets %v, got %v", expected, got)
+	}
+	got = bucketBounds(t, TimeToUpdate.WithLabelValues("ns", "name", "repo"))
+	if expected := []float64{60, 600, 3600}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected time to update buckets %v, got %v", expected, got)
+	}
+
+	// Histograms without buckets keep their defaults
+	got = bucketBounds(t, ReconciliationDuration.WithLabelValues("ns", "name", "success"))
+	if !reflect.DeepEqual(got, DefaultReconciliationBuckets) {
+		t.Errorf("Expected reconciliation buckets %v, got %v", DefaultReconciliationBuckets, got)
+	}
+	got = bucketBounds(t, RepositoryCheckDuration.WithLabelValues("ecr", "repo"))
+	if !reflect.DeepEqual(got, DefaultRepositoryCheckBuckets) {
+		t.Errorf("Expected repository check buckets %v, got %v", DefaultRepositoryCheckBuckets, got)
+	}
+}
+
+func TestParseBuckets(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []float64
+		wantErr  bool
+	}{
+		{value: "1,5,30,120,300", expected: []float64{1, 5, 30, 120, 300}},
+		{value: "0.5, 2.5 ,10", expected: []float64{0.5, 2.5, 10}},
+		{value: "1,abc", wantErr: true},
+		{value: "0,1", wantErr: true},
+		{value: "5,1", wantErr: true},
+		{value: "1,1", wantErr: true},
+		{value: "1,", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBuckets(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBuckets(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ParseBuckets(%q) = %v, want %v", tt.value, got, tt.expected)
+		}
+	}
+}
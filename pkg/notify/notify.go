@@ -0,0 +1,58 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import "context"
+
+// EventType is the kind of event a notification is sent for
+type EventType string
+
+const (
+	// EventUpdate is sent when an update was pushed
+	EventUpdate EventType = "update"
+
+	// EventError is sent when a reconcile failed
+	EventError EventType = "error"
+)
+
+// Event describes what a notification reports
+type Event struct {
+	Type EventType
+
+	// Namespace and Name identify the YukConfig
+	Namespace string
+	Name      string
+
+	// Repository is the monitored image repository
+	Repository string
+
+	// OldTag and NewTag are the tags before and after the update
+	OldTag string
+	NewTag string
+
+	// Message describes the update or the error
+	Message string
+}
+
+// Notifier sends notifications to an external service
+type Notifier interface {
+	// Name identifies the notifier in logs and metrics
+	Name() string
+
+	// Notify sends a notification for the event
+	Notify(ctx context.Context, event Event) error
+}
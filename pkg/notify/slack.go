@@ -0,0 +1,126 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SlackNotifier posts notifications to a Slack incoming webhook
+type SlackNotifier struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+// SlackOption configures optional behavior of a SlackNotifier
+type SlackOption func(*SlackNotifier)
+
+// WithHTTPClient sets the HTTP client used to post to the webhook
+func WithHTTPClient(httpClient *http.Client) SlackOption {
+	return func(n *SlackNotifier) {
+		n.httpClient = httpClient
+	}
+}
+
+// NewSlackNotifier creates a notifier posting to the given Slack webhook URL
+func NewSlackNotifier(webhookURL string, opts ...SlackOption) *SlackNotifier {
+	n := &SlackNotifier{
+		webhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	return n
+}
+
+// Name identifies the notifier in logs and metrics
+func (n *SlackNotifier) Name() string {
+	return "slack"
+}
+
+// slackMessage is the payload of a Slack incoming webhook
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// Notify posts a message describing the event to the webhook
+func (n *SlackNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(slackMessage{Text: slackText(event)})
+	if err != nil {
+		return fmt.Errorf("failed to marshal Slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post Slack message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("slack webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
+
+// slackText formats an event as Slack mrkdwn
+func slackText(event Event) string {
+	var b strings.Builder
+
+	switch event.Type {
+	case EventUpdate:
+		fmt.Fprintf(&b, ":rocket: *Updated %s/%s*", event.Namespace, event.Name)
+	case EventError:
+		fmt.Fprintf(&b, ":warning: *Update failed for %s/%s*", event.Namespace, event.Name)
+	default:
+		fmt.Fprintf(&b, "*%s/%s*", event.Namespace, event.Name)
+	}
+
+	fmt.Fprintf(&b, "\n*Repository:* %s", event.Repository)
+	fmt.Fprintf(&b, "\n*Old tag:* %s", slackCode(event.OldTag))
+	fmt.Fprintf(&b, "\n*New tag:* %s", slackCode(event.NewTag))
+	if event.Message != "" {
+		fmt.Fprintf(&b, "\n%s", event.Message)
+	}
+
+	return b.String()
+}
+
+// slackCode formats a value as inline code, or a dash when it is empty
+func slackCode(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return "`" + value + "`"
+}
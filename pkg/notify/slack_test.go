@@ -0,0 +1,103 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSlackNotifier_Notify(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    Event
+		expected string
+	}{
+		{
+			name: "update",
+			event: Event{
+				Type:       EventUpdate,
+				Namespace:  "default",
+				Name:       "my-app",
+				Repository: "my-app",
+				OldTag:     "v1.0.0",
+				NewTag:     "v1.1.0",
+			},
+			expected: ":rocket: *Updated default/my-app*\n*Repository:* my-app\n*Old tag:* `v1.0.0`\n*New tag:* `v1.1.0`",
+		},
+		{
+			name: "error",
+			event: Event{
+				Type:       EventError,
+				Namespace:  "default",
+				Name:       "my-app",
+				Repository: "my-app",
+				OldTag:     "v1.0.0",
+				Message:    "Repository check failed: access denied",
+			},
+			expected: ":warning: *Update failed for default/my-app*\n*Repository:* my-app\n*Old tag:* `v1.0.0`\n*New tag:* -\nRepository check failed: access denied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received slackMessage
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("Expected POST, got %s", r.Method)
+				}
+				if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+					t.Errorf("Expected JSON content type, got %s", contentType)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("Failed to decode message: %v", err)
+				}
+				_, _ = w.Write([]byte("ok"))
+			}))
+			defer server.Close()
+
+			notifier := NewSlackNotifier(server.URL + "/services/T000/B000/XXX")
+			if err := notifier.Notify(context.Background(), tt.event); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if received.Text != tt.expected {
+				t.Errorf("Expected message:\n%s\ngot:\n%s", tt.expected, received.Text)
+			}
+		})
+	}
+}
+
+func TestSlackNotifier_Notify_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("no_service"))
+	}))
+	defer server.Close()
+
+	notifier := NewSlackNotifier(server.URL)
+	err := notifier.Notify(context.Background(), Event{Type: EventUpdate, Namespace: "default", Name: "my-app"})
+	if err == nil {
+		t.Fatal("Expected error but got none")
+	}
+	if err.Error() != "slack webhook returned status 404: no_service" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// challenge is a parsed WWW-Authenticate header
+type challenge struct {
+	scheme string
+	params map[string]string
+}
+
+// challengeParamPattern matches the key="value" parameters of a challenge
+var challengeParamPattern = regexp.MustCompile(`([a-zA-Z_]+)="([^"]*)"`)
+
+// parseChallenge parses a WWW-Authenticate header, e.g.
+// Bearer realm="https://auth.example.com/token",service="registry",scope="repository:app:pull"
+func parseChallenge(header string) challenge {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+
+	params := make(map[string]string)
+	for _, match := range challengeParamPattern.FindAllStringSubmatch(rest, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+
+	return challenge{scheme: strings.ToLower(scheme), params: params}
+}
+
+// authenticate answers the registry's authentication challenge with a token from the
+// registry's token service. Basic credentials are sent with every request, so a basic
+// challenge means they are missing or were rejected.
+func (c *Client) authenticate(ctx context.Context, header, repositoryName string) error {
+	ch := parseChallenge(header)
+
+	switch ch.scheme {
+	case "basic":
+		// Configured credentials are sent with every request, so they were rejected
+		if c.username == "" {
+			return &APIError{StatusCode: http.StatusUnauthorized, Message: "registry requires credentials"}
+		}
+		return &APIError{StatusCode: http.StatusUnauthorized, Message: "invalid credentials"}
+
+	case "bearer":
+		token, err := c.fetchToken(ctx, ch, repositoryName)
+		if err != nil {
+			return err
+		}
+		c.token = token
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported authentication challenge from registry %s: %q", c.registry, header)
+	}
+}
+
+// tokenResponse is the response of a registry token service
+type tokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+}
+
+// fetchToken requests a bearer token from the realm of the challenge, authenticating with
+// the configured credentials if any
+func (c *Client) fetchToken(ctx context.Context, ch challenge, repositoryName string) (string, error) {
+	realm := ch.params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("bearer challenge from registry %s has no realm", c.registry)
+	}
+
+	realmURL, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm %q: %w", realm, err)
+	}
+
+	scope := ch.params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", repositoryName)
+	}
+
+	query := realmURL.Query()
+	query.Set("scope", scope)
+	if service := ch.params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	realmURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realmURL.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to request token: %w", newAPIError(resp))
+	}
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to parse token response: %w", err)
+	}
+
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	if token.AccessToken != "" {
+		return token.AccessToken, nil
+	}
+	return "", fmt.Errorf("token service of registry %s returned no token", c.registry)
+}
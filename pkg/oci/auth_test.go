@@ -0,0 +1,72 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"testing"
+)
+
+func TestParseChallenge(t *testing.T) {
+	tests := []struct {
+		name           string
+		header         string
+		expectedScheme string
+		expectedParams map[string]string
+	}{
+		{
+			name:           "bearer",
+			header:         `Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:project/app:pull"`,
+			expectedScheme: "bearer",
+			expectedParams: map[string]string{
+				"realm":   "https://auth.example.com/token",
+				"service": "registry.example.com",
+				"scope":   "repository:project/app:pull",
+			},
+		},
+		{
+			name:           "basic",
+			header:         `Basic realm="Harbor"`,
+			expectedScheme: "basic",
+			expectedParams: map[string]string{"realm": "Harbor"},
+		},
+		{
+			name:           "empty",
+			header:         "",
+			expectedScheme: "",
+			expectedParams: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := parseChallenge(tt.header)
+
+			if ch.scheme != tt.expectedScheme {
+				t.Errorf("Expected scheme %q, got %q", tt.expectedScheme, ch.scheme)
+			}
+
+			if len(ch.params) != len(tt.expectedParams) {
+				t.Errorf("Expected %d params, got %d: %v", len(tt.expectedParams), len(ch.params), ch.params)
+			}
+			for key, expected := range tt.expectedParams {
+				if ch.params[key] != expected {
+					t.Errorf("Expected param %s to be %q, got %q", key, expected, ch.params[key])
+				}
+			}
+		})
+	}
+}
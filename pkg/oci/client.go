@@ -0,0 +1,388 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+// pageSize is the number of tags requested per page
+const pageSize = 1000
+
+// manifestMediaTypes are accepted when resolving a tag's digest. Indexes are preferred
+// so multi-platform images resolve to the digest of the index rather than one platform.
+var manifestMediaTypes = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+// Client lists tags of repositories in a registry implementing the OCI distribution spec
+type Client struct {
+	registry     string
+	scheme       string
+	httpClient   *http.Client
+	username     string
+	password     string
+	sortStrategy tagsort.Strategy
+	tlsConfig    *tls.Config
+
+	// token is the bearer token obtained from the registry's token service
+	token string
+}
+
+// Option configures optional behavior of a Client
+type Option func(*Client)
+
+// WithBasicAuth authenticates with the given username and password, either directly or
+// when requesting bearer tokens from the registry's token service
+func WithBasicAuth(username, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithInsecure talks to the registry over plain HTTP
+func WithInsecure(insecure bool) Option {
+	return func(c *Client) {
+		if insecure {
+			c.scheme = "http"
+		}
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to talk to the registry
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithSortStrategy orders tags by the given strategy instead of lexical order
+func WithSortStrategy(strategy tagsort.Strategy) Option {
+	return func(c *Client) {
+		c.sortStrategy = strategy
+	}
+}
+
+// NewClient creates a new client for the registry at the given host (e.g.
+// "harbor.example.com" or "registry.example.com:5000")
+func NewClient(registry string, opts ...Option) *Client {
+	c := &Client{
+		registry:   strings.TrimSuffix(registry, "/"),
+		scheme:     "https",
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.tlsConfig != nil {
+		c.httpClient = withTLSConfig(c.httpClient, c.tlsConfig)
+	}
+
+	return c
+}
+
+// GetLatestTag retrieves the latest tag from the specified repository
+func (c *Client) GetLatestTag(ctx context.Context, repositoryName, tagFilter string) (string, error) {
+	latestTags, err := c.GetLatestTags(ctx, repositoryName, []string{tagFilter})
+	if err != nil {
+		return "", err
+	}
+
+	return latestTags[tagFilter], nil
+}
+
+// GetLatestTags retrieves the latest tag for each of the given tag filters, listing the
+// repository's tags only once. The result is keyed by tag filter.
+func (c *Client) GetLatestTags(ctx context.Context, repositoryName string, tagFilters []string) (map[string]string, error) {
+	// Validate the sort strategy before listing tags so invalid configurations fail fast
+	if err := tagsort.Validate(c.sortStrategy, tagsort.Semver); err != nil {
+		return nil, err
+	}
+
+	tags, err := c.ListTags(ctx, repositoryName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("no tags found in repository %s", repositoryName)
+	}
+
+	latestTags := make(map[string]string, len(tagFilters))
+	for _, tagFilter := range tagFilters {
+		if _, done := latestTags[tagFilter]; done {
+			continue
+		}
+
+		tag, err := selectLatestTag(tags, repositoryName, tagFilter, c.sortStrategy)
+		if err != nil {
+			return nil, err
+		}
+		latestTags[tagFilter] = tag
+	}
+
+	return latestTags, nil
+}
+
+// tagList is the response of the tags/list endpoint
+type tagList struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// ListTags lists all tags of the specified repository, following Link header pagination
+func (c *Client) ListTags(ctx context.Context, repositoryName string) ([]string, error) {
+	next := &url.URL{
+		Scheme:   c.scheme,
+		Host:     c.registry,
+		Path:     fmt.Sprintf("/v2/%s/tags/list", repositoryName),
+		RawQuery: url.Values{"n": {fmt.Sprint(pageSize)}}.Encode(),
+	}
+
+	var tags []string
+	for next != nil {
+		resp, err := c.get(ctx, next, repositoryName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags in repository %s: %w", repositoryName, err)
+		}
+
+		var page tagList
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tags of repository %s: %w", repositoryName, err)
+		}
+
+		tags = append(tags, page.Tags...)
+
+		next, err = nextPage(next, resp.Header.Get("Link"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to follow pagination of repository %s: %w", repositoryName, err)
+		}
+	}
+
+	return tags, nil
+}
+
+// GetImageDigest returns the digest (e.g. "sha256:...") of the manifest the tag points to
+func (c *Client) GetImageDigest(ctx context.Context, repositoryName, tag string) (string, error) {
+	u := &url.URL{
+		Scheme: c.scheme,
+		Host:   c.registry,
+		Path:   fmt.Sprintf("/v2/%s/manifests/%s", repositoryName, tag),
+	}
+
+	resp, err := c.request(ctx, http.MethodHead, u, repositoryName, manifestMediaTypes)
+	if err != nil {
+		return "", fmt.Errorf("failed to get manifest of %s:%s: %w", repositoryName, tag, err)
+	}
+	resp.Body.Close()
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("no digest returned for image %s:%s", repositoryName, tag)
+	}
+
+	return digest, nil
+}
+
+// maxContentSize bounds the manifests and blobs read from the registry
+const maxContentSize = 4 << 20
+
+// GetManifest returns the manifest the tag or digest points to, e.g. the manifest of a
+// cosign signature
+func (c *Client) GetManifest(ctx context.Context, repositoryName, reference string) ([]byte, error) {
+	u := &url.URL{
+		Scheme: c.scheme,
+		Host:   c.registry,
+		Path:   fmt.Sprintf("/v2/%s/manifests/%s", repositoryName, reference),
+	}
+
+	resp, err := c.request(ctx, http.MethodGet, u, repositoryName, manifestMediaTypes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest of %s:%s: %w", repositoryName, reference, err)
+	}
+	defer resp.Body.Close()
+
+	return readContent(resp.Body)
+}
+
+// GetBlob returns the content of the blob with the digest
+func (c *Client) GetBlob(ctx context.Context, repositoryName, digest string) ([]byte, error) {
+	u := &url.URL{
+		Scheme: c.scheme,
+		Host:   c.registry,
+		Path:   fmt.Sprintf("/v2/%s/blobs/%s", repositoryName, digest),
+	}
+
+	resp, err := c.request(ctx, http.MethodGet, u, repositoryName, "*/*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob %s of %s: %w", digest, repositoryName, err)
+	}
+	defer resp.Body.Close()
+
+	return readContent(resp.Body)
+}
+
+// readContent reads a manifest or blob, failing when it exceeds maxContentSize
+func readContent(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxContentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(data) > maxContentSize {
+		return nil, fmt.Errorf("content exceeds %d bytes", maxContentSize)
+	}
+	return data, nil
+}
+
+// get sends an authenticated GET request, negotiating authentication from the registry's
+// WWW-Authenticate challenge when the request is rejected
+func (c *Client) get(ctx context.Context, u *url.URL, repositoryName string) (*http.Response, error) {
+	return c.request(ctx, http.MethodGet, u, repositoryName, "application/json")
+}
+
+// request sends an authenticated request accepting the given media types, negotiating
+// authentication from the registry's WWW-Authenticate challenge when it is rejected
+func (c *Client) request(ctx context.Context, method string, u *url.URL, repositoryName, accept string) (*http.Response, error) {
+	resp, err := c.do(ctx, method, u, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		if err := c.authenticate(ctx, challenge, repositoryName); err != nil {
+			return nil, err
+		}
+
+		resp, err = c.do(ctx, method, u, accept)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newAPIError(resp)
+	}
+
+	return resp, nil
+}
+
+// do sends a request with the current credentials
+func (c *Client) do(ctx context.Context, method string, u *url.URL, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+
+	switch {
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// APIError is an unsuccessful response of the registry
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("registry returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("registry returned %d: %s", e.StatusCode, e.Message)
+}
+
+// HTTPStatusCode returns the HTTP status code of the response
+func (e *APIError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
+// newAPIError reads the error details of an unsuccessful response
+func newAPIError(resp *http.Response) *APIError {
+	var body struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	_ = json.Unmarshal(data, &body)
+
+	var messages []string
+	for _, e := range body.Errors {
+		messages = append(messages, strings.TrimSpace(e.Code+" "+e.Message))
+	}
+
+	return &APIError{StatusCode: resp.StatusCode, Message: strings.Join(messages, "; ")}
+}
+
+// linkPattern matches the next page in a Link header, e.g.
+// </v2/app/tags/list?last=v1.2.0&n=1000>; rel="next"
+var linkPattern = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+
+// nextPage returns the URL of the next page from a Link header, resolved against the
+// current page, or nil when there is no next page
+func nextPage(current *url.URL, link string) (*url.URL, error) {
+	match := linkPattern.FindStringSubmatch(link)
+	if match == nil {
+		return nil, nil
+	}
+
+	ref, err := url.Parse(match[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid Link header %q: %w", link, err)
+	}
+
+	return current.ResolveReference(ref), nil
+}
+
+// selectLatestTag filters the tags and returns the latest one according to the sort strategy
+func selectLatestTag(tags []string, repositoryName, tagFilter string, sortStrategy tagsort.Strategy) (string, error) {
+	tag, err := tagsort.Select(tagsort.Candidates(tags), tagFilter, sortStrategy)
+	if err != nil {
+		return "", fmt.Errorf("%w in repository %s", err, repositoryName)
+	}
+	return tag, nil
+}
@@ -0,0 +1,399 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+// fakeRegistry serves the tags of a single repository two tags per page, the digests
+// and contents of its manifests and its blobs, requiring either basic auth or a bearer
+// token from its token service
+type fakeRegistry struct {
+	tags      []string
+	digests   map[string]string
+	manifests map[string]string
+	blobs     map[string]string
+	bearer    bool
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if tag, ok := strings.CutPrefix(r.URL.Path, "/v2/project/app/manifests/"); ok {
+		f.serveManifest(w, r, tag)
+		return
+	}
+	if digest, ok := strings.CutPrefix(r.URL.Path, "/v2/project/app/blobs/"); ok {
+		f.serveBlob(w, r, digest)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/token":
+		if username, password, ok := r.BasicAuth(); !ok || username != "robot" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("scope") != "repository:project/app:pull" || r.URL.Query().Get("service") != "registry" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(tokenResponse{Token: "registry-token"})
+
+	case "/v2/project/app/tags/list":
+		if !f.authorized(r) {
+			f.challenge(w, r)
+			return
+		}
+
+		// Paginate two tags at a time after the "last" tag
+		start := 0
+		if last := r.URL.Query().Get("last"); last != "" {
+			for i, tag := range f.tags {
+				if tag == last {
+					start = i + 1
+				}
+			}
+		}
+		end := start + 2
+		if end >= len(f.tags) {
+			end = len(f.tags)
+		} else {
+			w.Header().Set("Link", fmt.Sprintf(`</v2/project/app/tags/list?last=%s&n=2>; rel="next"`, f.tags[end-1]))
+		}
+		_ = json.NewEncoder(w).Encode(tagList{Name: "project/app", Tags: f.tags[start:end]})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors": [{"code": "NAME_UNKNOWN", "message": "repository name not known to registry"}]}`))
+	}
+}
+
+// serveManifest answers a manifest request with the digest of the tag
+func (f *fakeRegistry) serveManifest(w http.ResponseWriter, r *http.Request, tag string) {
+	if !f.authorized(r) {
+		f.challenge(w, r)
+		return
+	}
+
+	digest, ok := f.digests[tag]
+	if !ok || !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Docker-Content-Digest", digest)
+	_, _ = w.Write([]byte(f.manifests[tag]))
+}
+
+// serveBlob answers a blob request with the content of the blob
+func (f *fakeRegistry) serveBlob(w http.ResponseWriter, r *http.Request, digest string) {
+	if !f.authorized(r) {
+		f.challenge(w, r)
+		return
+	}
+
+	content, ok := f.blobs[digest]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors": [{"code": "BLOB_UNKNOWN", "message": "blob unknown to registry"}]}`))
+		return
+	}
+	_, _ = w.Write([]byte(content))
+}
+
+// challenge rejects an unauthorized request, asking for the configured authentication
+func (f *fakeRegistry) challenge(w http.ResponseWriter, r *http.Request) {
+	if f.bearer {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="https://%s/token",service="registry",scope="repository:project/app:pull"`, r.Host))
+	} else {
+		w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+	}
+	w.WriteHeader(http.StatusUnauthorized)
+	_, _ = w.Write([]byte(`{"errors": [{"code": "UNAUTHORIZED", "message": "authentication required"}]}`))
+}
+
+func (f *fakeRegistry) authorized(r *http.Request) bool {
+	if f.bearer {
+		return r.Header.Get("Authorization") == "Bearer registry-token"
+	}
+	username, password, ok := r.BasicAuth()
+	return ok && username == "robot" && password == "secret"
+}
+
+func TestClient_ListTags(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.1.0", "v1.9.0", "v1.10.0", "latest"}
+
+	tests := []struct {
+		name      string
+		registry  *fakeRegistry
+		username  string
+		password  string
+		shouldErr bool
+	}{
+		{
+			name:     "bearer token",
+			registry: &fakeRegistry{tags: tags, bearer: true},
+			username: "robot",
+			password: "secret",
+		},
+		{
+			name:     "basic auth",
+			registry: &fakeRegistry{tags: tags},
+			username: "robot",
+			password: "secret",
+		},
+		{
+			name:      "invalid credentials",
+			registry:  &fakeRegistry{tags: tags},
+			username:  "robot",
+			password:  "wrong",
+			shouldErr: true,
+		},
+		{
+			name:      "token service rejects credentials",
+			registry:  &fakeRegistry{tags: tags, bearer: true},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(tt.registry)
+			defer server.Close()
+
+			client := NewClient(server.Listener.Addr().String(),
+				WithHTTPClient(server.Client()), WithBasicAuth(tt.username, tt.password))
+
+			listed, err := client.ListTags(context.Background(), "project/app")
+			if tt.shouldErr {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode() != http.StatusUnauthorized {
+					t.Errorf("Expected unauthorized error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if strings.Join(listed, ",") != strings.Join(tags, ",") {
+				t.Errorf("Expected tags %v, got %v", tags, listed)
+			}
+		})
+	}
+}
+
+func TestClient_GetLatestTags(t *testing.T) {
+	server := httptest.NewTLSServer(&fakeRegistry{
+		tags:   []string{"v1.0.0", "v1.9.0", "v1.10.0", "v2.0.0-rc1", "worker-1.0", "worker-1.2"},
+		bearer: true,
+	})
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		sortStrategy tagsort.Strategy
+		expected     map[string]string
+		shouldErr    bool
+	}{
+		{
+			name:         "lexical",
+			sortStrategy: tagsort.Lexical,
+			expected:     map[string]string{`^v`: "v2.0.0-rc1", "^worker-": "worker-1.2"},
+		},
+		{
+			name:         "semver",
+			sortStrategy: tagsort.Semver,
+			expected:     map[string]string{`^v1\.`: "v1.10.0", "^worker-": "worker-1.2"},
+		},
+		{
+			name:         "unknown sort strategy",
+			sortStrategy: "calver",
+			shouldErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(server.Listener.Addr().String(), WithHTTPClient(server.Client()),
+				WithBasicAuth("robot", "secret"), WithSortStrategy(tt.sortStrategy))
+
+			var filters []string
+			for filter := range tt.expected {
+				filters = append(filters, filter)
+			}
+
+			latestTags, err := client.GetLatestTags(context.Background(), "project/app", filters)
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			for filter, expected := range tt.expected {
+				if latestTags[filter] != expected {
+					t.Errorf("Expected latest tag %s for filter %s, got %s", expected, filter, latestTags[filter])
+				}
+			}
+		})
+	}
+}
+
+func TestClient_GetImageDigest(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	server := httptest.NewTLSServer(&fakeRegistry{
+		digests: map[string]string{"v1.0.0": digest},
+		bearer:  true,
+	})
+	defer server.Close()
+
+	client := NewClient(server.Listener.Addr().String(), WithHTTPClient(server.Client()), WithBasicAuth("robot", "secret"))
+
+	resolved, err := client.GetImageDigest(context.Background(), "project/app", "v1.0.0")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if resolved != digest {
+		t.Errorf("Expected digest %s, got %s", digest, resolved)
+	}
+
+	if _, err := client.GetImageDigest(context.Background(), "project/app", "v9.9.9"); err == nil {
+		t.Error("Expected error for unknown tag")
+	}
+}
+
+func TestClient_GetManifestAndBlob(t *testing.T) {
+	signatureTag := "sha256-" + strings.Repeat("a", 64) + ".sig"
+	layerDigest := "sha256:" + strings.Repeat("b", 64)
+	manifest := `{"schemaVersion":2,"layers":[{"digest":"` + layerDigest + `"}]}`
+	server := httptest.NewTLSServer(&fakeRegistry{
+		digests:   map[string]string{signatureTag: "sha256:" + strings.Repeat("c", 64)},
+		manifests: map[string]string{signatureTag: manifest},
+		blobs:     map[string]string{layerDigest: `{"critical":{}}`},
+		bearer:    true,
+	})
+	defer server.Close()
+
+	client := NewClient(server.Listener.Addr().String(), WithHTTPClient(server.Client()), WithBasicAuth("robot", "secret"))
+
+	data, err := client.GetManifest(context.Background(), "project/app", signatureTag)
+	if err != nil {
+		t.Fatalf("GetManifest() error = %v", err)
+	}
+	if string(data) != manifest {
+		t.Errorf("Expected manifest %s, got %s", manifest, data)
+	}
+
+	blob, err := client.GetBlob(context.Background(), "project/app", layerDigest)
+	if err != nil {
+		t.Fatalf("GetBlob() error = %v", err)
+	}
+	if string(blob) != `{"critical":{}}` {
+		t.Errorf("Unexpected blob %s", blob)
+	}
+
+	_, err = client.GetManifest(context.Background(), "project/app", "sha256-missing.sig")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode() != http.StatusNotFound {
+		t.Errorf("Expected not found error for an unknown manifest, got %v", err)
+	}
+}
+
+func TestClient_ListTags_UnknownRepository(t *testing.T) {
+	server := httptest.NewTLSServer(&fakeRegistry{})
+	defer server.Close()
+
+	client := NewClient(server.Listener.Addr().String(), WithHTTPClient(server.Client()))
+
+	_, err := client.ListTags(context.Background(), "project/missing")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode() != http.StatusNotFound {
+		t.Fatalf("Expected not found error, got %v", err)
+	}
+	if !strings.Contains(apiErr.Error(), "NAME_UNKNOWN") {
+		t.Errorf("Expected error to include the registry error code, got %s", apiErr.Error())
+	}
+}
+
+func TestSelectLatestTag(t *testing.T) {
+	tags := []string{"latest", "v1.9.0", "v1.10.0", "v1.10.0-rc1", "main-abc123"}
+
+	tests := []struct {
+		name         string
+		tagFilter    string
+		sortStrategy tagsort.Strategy
+		expected     string
+		shouldErr    bool
+	}{
+		{
+			name:     "lexical",
+			expected: "v1.9.0",
+		},
+		{
+			name:         "semver with invalid tags",
+			sortStrategy: tagsort.Semver,
+			expected:     "v1.10.0",
+		},
+		{
+			name:         "semver only invalid tags",
+			tagFilter:    "^(latest|main-)",
+			sortStrategy: tagsort.Semver,
+			expected:     "main-abc123",
+		},
+		{
+			name:      "no matching tags",
+			tagFilter: "^worker-",
+			shouldErr: true,
+		},
+		{
+			name:      "invalid filter",
+			tagFilter: "(",
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, err := selectLatestTag(append([]string(nil), tags...), "project/app", tt.tagFilter, tt.sortStrategy)
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if tag != tt.expected {
+				t.Errorf("Expected tag %s, got %s", tt.expected, tag)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+)
+
+// NewTLSConfig returns the TLS configuration of a registry whose certificate is signed by
+// a private CA. The PEM-encoded CA certificates are trusted in addition to the system trust
+// store. insecureSkipVerify disables certificate verification entirely and should only
+// be used as an escape hatch.
+func NewTLSConfig(caBundle []byte, insecureSkipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecureSkipVerify, //nolint:gosec // explicitly requested by the configuration
+	}
+
+	if len(caBundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no PEM-encoded certificates found in the CA bundle")
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
+
+// WithTLSConfig talks to the registry with the given TLS configuration (see NewTLSConfig)
+// instead of the system trust store. It applies to the HTTP client set by WithHTTPClient
+// too, regardless of the order of the options.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Client) {
+		c.tlsConfig = config
+	}
+}
+
+// withTLSConfig returns a copy of the HTTP client whose transport uses the TLS
+// configuration
+func withTLSConfig(httpClient *http.Client, config *tls.Config) *http.Client {
+	transport, ok := httpClient.Transport.(*http.Transport)
+	if !ok || transport == nil {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+	transport = transport.Clone()
+	transport.TLSClientConfig = config
+
+	client := *httpClient
+	client.Transport = transport
+	return &client
+}
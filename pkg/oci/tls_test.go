@@ -0,0 +1,99 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	if _, err := NewTLSConfig([]byte("not a certificate"), false); err == nil {
+		t.Error("Expected an error for a CA bundle without certificates")
+	}
+
+	config, err := NewTLSConfig(nil, true)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !config.InsecureSkipVerify || config.RootCAs != nil {
+		t.Errorf("Expected only certificate verification to be disabled, got %+v", config)
+	}
+}
+
+func TestClient_TLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(&fakeRegistry{tags: []string{"v1.0.0"}})
+	defer server.Close()
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	newTLSConfig := func(caBundle []byte, insecureSkipVerify bool) *tls.Config {
+		t.Helper()
+		config, err := NewTLSConfig(caBundle, insecureSkipVerify)
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		return config
+	}
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		shouldErr bool
+	}{
+		{
+			name:      "system trust store",
+			shouldErr: true,
+		},
+		{
+			name: "custom CA",
+			opts: []Option{WithTLSConfig(newTLSConfig(caBundle, false))},
+		},
+		{
+			name: "insecure skip verify",
+			opts: []Option{WithTLSConfig(newTLSConfig(nil, true))},
+		},
+		{
+			name: "custom CA with an HTTP client set afterwards",
+			opts: []Option{WithTLSConfig(newTLSConfig(caBundle, false)), WithHTTPClient(&http.Client{Timeout: time.Second})},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithBasicAuth("robot", "secret")}, tt.opts...)
+			client := NewClient(server.Listener.Addr().String(), opts...)
+
+			_, err := client.ListTags(context.Background(), "project/app")
+			if tt.shouldErr {
+				var certErr *tls.CertificateVerificationError
+				if !errors.As(err, &certErr) {
+					t.Errorf("Expected a certificate verification error, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package quay lists tags of repositories on Quay
+package quay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+// DefaultRegistry is the host of the public Quay registry
+const DefaultRegistry = "quay.io"
+
+// pageSize is the number of tags requested per page, the maximum of the Quay API
+const pageSize = 100
+
+// Client lists tags of repositories on Quay through its REST API
+// (/api/v1/repository/<namespace>/<repository>/tag/). Private repositories are read with
+// the credentials of a robot account.
+type Client struct {
+	endpoint     string
+	httpClient   *http.Client
+	username     string
+	password     string
+	sortStrategy tagsort.Strategy
+}
+
+// Option configures optional behavior of a Client
+type Option func(*Client)
+
+// WithSortStrategy orders tags by the given strategy instead of lexical order
+func WithSortStrategy(strategy tagsort.Strategy) Option {
+	return func(c *Client) {
+		c.sortStrategy = strategy
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to talk to the registry
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithBasicAuth authenticates with the name and token of a robot account
+func WithBasicAuth(username, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithEndpoint talks to another URL than https://<registry>, e.g. a test server
+func WithEndpoint(endpoint string) Option {
+	return func(c *Client) {
+		c.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// NewClient creates a new client of the registry, quay.io when empty
+func NewClient(registry string, opts ...Option) *Client {
+	c := &Client{
+		endpoint:   "https://" + Registry(registry),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Registry returns the host of the registry, quay.io when empty
+func Registry(registry string) string {
+	registry = strings.ToLower(strings.TrimSpace(registry))
+	if registry == "" {
+		return DefaultRegistry
+	}
+	return registry
+}
+
+// RepositoryName returns the full name of a repository of a namespace
+func RepositoryName(namespace, repository string) string {
+	return namespace + "/" + repository
+}
+
+// tagCandidate is a tag with the time it was pushed
+type tagCandidate struct {
+	tag      string
+	digest   string
+	pushedAt time.Time
+}
+
+// GetLatestTag retrieves the latest tag of the repository
+func (c *Client) GetLatestTag(ctx context.Context, repository, tagFilter string) (string, error) {
+	latestTags, err := c.GetLatestTags(ctx, repository, []string{tagFilter})
+	if err != nil {
+		return "", err
+	}
+
+	return latestTags[tagFilter], nil
+}
+
+// GetLatestTags retrieves the latest tag for each of the given tag filters, listing the
+// repository's tags only once. The repository is given as "<namespace>/<repository>" and
+// the result is keyed by tag filter.
+func (c *Client) GetLatestTags(ctx context.Context, repository string, tagFilters []string) (map[string]string, error) {
+	// Validate the sort strategy before listing tags so invalid configurations fail fast
+	if err := tagsort.Validate(c.sortStrategy, tagsort.Semver, tagsort.PushTime); err != nil {
+		return nil, err
+	}
+
+	candidates, err := c.listTags(ctx, repository, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no tags found in repository %s", repository)
+	}
+
+	sortCandidates := make([]tagsort.Candidate, len(candidates))
+	for i, candidate := range candidates {
+		sortCandidates[i] = tagsort.Candidate{Tag: candidate.tag, PushedAt: candidate.pushedAt}
+	}
+
+	latestTags := make(map[string]string, len(tagFilters))
+	for _, tagFilter := range tagFilters {
+		if _, done := latestTags[tagFilter]; done {
+			continue
+		}
+
+		tag, err := tagsort.Select(sortCandidates, tagFilter, c.sortStrategy)
+		if err != nil {
+			return nil, fmt.Errorf("%w in repository %s", err, repository)
+		}
+		latestTags[tagFilter] = tag
+	}
+
+	return latestTags, nil
+}
+
+// GetImageDigest returns the digest of the manifest the tag of the repository points to
+func (c *Client) GetImageDigest(ctx context.Context, repository, tag string) (string, error) {
+	candidates, err := c.listTags(ctx, repository, tag)
+	if err != nil {
+		return "", err
+	}
+
+	for _, candidate := range candidates {
+		if candidate.tag == tag {
+			return candidate.digest, nil
+		}
+	}
+
+	return "", fmt.Errorf("tag %s not found in repository %s", tag, repository)
+}
+
+// tagList is a page of the tags API response
+type tagList struct {
+	Tags []struct {
+		Name           string `json:"name"`
+		ManifestDigest string `json:"manifest_digest"`
+		StartTS        int64  `json:"start_ts"`
+	} `json:"tags"`
+	HasAdditional bool `json:"has_additional"`
+}
+
+// listTags lists the active tags of the repository page by page, only the given tag
+// when not empty
+func (c *Client) listTags(ctx context.Context, repository, specificTag string) ([]tagCandidate, error) {
+	var candidates []tagCandidate
+
+	for page := 1; ; page++ {
+		query := url.Values{
+			"onlyActiveTags": {"true"},
+			"limit":          {strconv.Itoa(pageSize)},
+			"page":           {strconv.Itoa(page)},
+		}
+		if specificTag != "" {
+			query.Set("specificTag", specificTag)
+		}
+
+		endpoint := fmt.Sprintf("%s/api/v1/repository/%s/tag/?%s", c.endpoint, repository, query.Encode())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		if c.username != "" {
+			req.SetBasicAuth(c.username, c.password)
+		}
+
+		var list tagList
+		if err := c.do(req, &list); err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", repository, err)
+		}
+
+		for _, tag := range list.Tags {
+			candidates = append(candidates, tagCandidate{
+				tag:      tag.Name,
+				digest:   tag.ManifestDigest,
+				pushedAt: time.Unix(tag.StartTS, 0),
+			})
+		}
+
+		if !list.HasAdditional || len(list.Tags) == 0 {
+			return candidates, nil
+		}
+	}
+}
+
+// do sends a request and decodes the JSON response into v
+func (c *Client) do(req *http.Request, v interface{}) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// APIError is an unsuccessful response of the Quay API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("quay returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("quay returned %d: %s", e.StatusCode, e.Message)
+}
+
+// HTTPStatusCode returns the HTTP status code of the response
+func (e *APIError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
+// newAPIError reads the error details of an unsuccessful response
+func newAPIError(resp *http.Response) *APIError {
+	var body struct {
+		ErrorMessage string `json:"error_message"`
+		Detail       string `json:"detail"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	_ = json.Unmarshal(data, &body)
+
+	message := body.ErrorMessage
+	if message == "" {
+		message = body.Detail
+	}
+
+	return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(message)}
+}
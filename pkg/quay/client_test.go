@@ -0,0 +1,249 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+)
+
+// fakeQuay serves the tags of the private repository rebelops/my-app over two pages to
+// the robot account rebelops+yuk
+type fakeQuay struct {
+	tagRequests int
+}
+
+func (f *fakeQuay) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/v1/repository/rebelops/my-app/tag/" {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": 404, "error_message": "Not Found"})
+		return
+	}
+
+	f.tagRequests++
+	if username, password, ok := r.BasicAuth(); !ok || username != "rebelops+yuk" || password != "robot-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": 401, "detail": "Unauthorized"})
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("onlyActiveTags") != "true" || query.Get("limit") != "100" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	tag := func(name, digest string, startTS int64) map[string]interface{} {
+		return map[string]interface{}{"name": name, "manifest_digest": digest, "start_ts": startTS, "reversion": false}
+	}
+	pages := map[string][]interface{}{
+		"1": {
+			tag("latest", "sha256:bbb", 1709251200),
+			tag("v1.10.0", "sha256:bbb", 1709251200),
+		},
+		"2": {
+			tag("v1.9.0", "sha256:aaa", 1704067200),
+			tag("v1.9.1", "sha256:ccc", 1706745600),
+		},
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	tags := pages[query.Get("page")]
+	if specificTag := query.Get("specificTag"); specificTag != "" {
+		var matching []interface{}
+		for _, page := range pages {
+			for _, t := range page {
+				if t.(map[string]interface{})["name"] == specificTag {
+					matching = append(matching, t)
+				}
+			}
+		}
+		tags = nil
+		if page == 1 {
+			tags = matching
+		}
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"tags":           tags,
+		"page":           page,
+		"has_additional": page == 1 && query.Get("specificTag") == "",
+	})
+}
+
+func newTestClient(t *testing.T, opts ...Option) (*Client, *fakeQuay) {
+	t.Helper()
+
+	quay := &fakeQuay{}
+	server := httptest.NewServer(quay)
+	t.Cleanup(server.Close)
+
+	opts = append([]Option{
+		WithEndpoint(server.URL),
+		WithBasicAuth("rebelops+yuk", "robot-token"),
+	}, opts...)
+	return NewClient("", opts...), quay
+}
+
+func TestClient_GetLatestTags(t *testing.T) {
+	tests := []struct {
+		name         string
+		sortStrategy tagsort.Strategy
+		tagFilter    string
+		expected     string
+	}{
+		{
+			name:      "lexical",
+			tagFilter: `^v`,
+			expected:  "v1.9.1",
+		},
+		{
+			name:         "semver",
+			sortStrategy: tagsort.Semver,
+			tagFilter:    `^v`,
+			expected:     "v1.10.0",
+		},
+		{
+			name:         "push time with filter",
+			sortStrategy: tagsort.PushTime,
+			tagFilter:    `^v1\.9\.`,
+			expected:     "v1.9.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, quay := newTestClient(t, WithSortStrategy(tt.sortStrategy))
+
+			latestTags, err := client.GetLatestTags(context.Background(), "rebelops/my-app", []string{tt.tagFilter, tt.tagFilter})
+			if err != nil {
+				t.Fatalf("GetLatestTags failed: %v", err)
+			}
+			if latestTags[tt.tagFilter] != tt.expected {
+				t.Errorf("Expected latest tag %s, got %s", tt.expected, latestTags[tt.tagFilter])
+			}
+
+			// Both pages are listed once for all filters
+			if quay.tagRequests != 2 {
+				t.Errorf("Expected 2 tag requests, got %d", quay.tagRequests)
+			}
+		})
+	}
+}
+
+func TestClient_GetLatestTag_NoMatch(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	_, err := client.GetLatestTag(context.Background(), "rebelops/my-app", `^release-`)
+	if err == nil || err.Error() != "no tags found matching filter in repository rebelops/my-app" {
+		t.Errorf("Expected a no matching tags error, got %v", err)
+	}
+}
+
+func TestClient_GetLatestTags_InvalidSortStrategy(t *testing.T) {
+	client, quay := newTestClient(t, WithSortStrategy("newest"))
+
+	if _, err := client.GetLatestTags(context.Background(), "rebelops/my-app", []string{""}); err == nil {
+		t.Error("Expected an error for an unsupported sort strategy")
+	}
+	if quay.tagRequests != 0 {
+		t.Errorf("Expected no requests, got %d", quay.tagRequests)
+	}
+}
+
+func TestClient_GetImageDigest(t *testing.T) {
+	client, quay := newTestClient(t)
+
+	digest, err := client.GetImageDigest(context.Background(), "rebelops/my-app", "v1.9.1")
+	if err != nil {
+		t.Fatalf("GetImageDigest failed: %v", err)
+	}
+	if digest != "sha256:ccc" {
+		t.Errorf("Expected digest sha256:ccc, got %s", digest)
+	}
+	if quay.tagRequests != 1 {
+		t.Errorf("Expected the tag to be looked up with a single request, got %d", quay.tagRequests)
+	}
+
+	if _, err := client.GetImageDigest(context.Background(), "rebelops/my-app", "v0.1.0"); err == nil {
+		t.Error("Expected an error for an unknown tag")
+	}
+}
+
+func TestClient_APIError(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []Option
+		repository     string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "rejected robot token",
+			opts:           []Option{WithBasicAuth("rebelops+yuk", "wrong")},
+			repository:     "rebelops/my-app",
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "quay returned 401: Unauthorized",
+		},
+		{
+			name:           "unknown repository",
+			repository:     "rebelops/other",
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "quay returned 404: Not Found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t, tt.opts...)
+
+			_, err := client.GetLatestTag(context.Background(), tt.repository, "")
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected an APIError, got %v", err)
+			}
+			if apiErr.HTTPStatusCode() != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, apiErr.HTTPStatusCode())
+			}
+			if apiErr.Error() != tt.expectedError {
+				t.Errorf("Expected error %q, got %q", tt.expectedError, apiErr.Error())
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	tests := map[string]string{
+		"":                   "quay.io",
+		"Quay.Example.com":   "quay.example.com",
+		" quay.example.com ": "quay.example.com",
+	}
+
+	for registry, expected := range tests {
+		if host := Registry(registry); host != expected {
+			t.Errorf("Expected registry %s for %q, got %s", expected, registry, host)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receiver
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// maxBodySize limits the size of notification payloads
+const maxBodySize = 256 * 1024
+
+// SecretHeader is the request header carrying the shared secret
+const SecretHeader = "X-Yuk-Secret"
+
+// Enqueuer requests an immediate reconcile of a YukConfig
+type Enqueuer interface {
+	Enqueue(yukConfig *yukv1.YukConfig)
+}
+
+// Receiver is an HTTP server that receives image push notifications and enqueues
+// reconciles for the YukConfigs watching the pushed repository
+type Receiver struct {
+	addr     string
+	reader   client.Reader
+	enqueuer Enqueuer
+	sns      *snsVerifier
+	logger   logr.Logger
+
+	// allowedTopicARNs restricts accepted SNS topics (all topics when empty)
+	allowedTopicARNs map[string]bool
+
+	// secret is the shared secret requests must present; the receiver does not start
+	// without one
+	secret string
+}
+
+// Option configures optional behavior of a Receiver
+type Option func(*Receiver)
+
+// WithAllowedTopicARNs restricts accepted SNS notifications to the given topics
+func WithAllowedTopicARNs(topicARNs []string) Option {
+	return func(r *Receiver) {
+		for _, arn := range topicARNs {
+			if arn != "" {
+				r.allowedTopicARNs[arn] = true
+			}
+		}
+	}
+}
+
+// WithSharedSecret requires requests to present the shared secret, either in the
+// X-Yuk-Secret header or as the HTTP basic auth password (as sent by SNS for
+// subscription URLs like https://yuk:<secret>@host/ecr/sns)
+func WithSharedSecret(secret string) Option {
+	return func(r *Receiver) {
+		r.secret = secret
+	}
+}
+
+// NewReceiver creates a new notification receiver listening on addr
+func NewReceiver(addr string, reader client.Reader, enqueuer Enqueuer, opts ...Option) *Receiver {
+	r := &Receiver{
+		addr:             addr,
+		reader:           reader,
+		enqueuer:         enqueuer,
+		sns:              newSNSVerifier(),
+		logger:           log.Log.WithName("receiver"),
+		allowedTopicARNs: make(map[string]bool),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// ecrImageActionEvent is an EventBridge "ECR Image Action" event
+type ecrImageActionEvent struct {
+	DetailType string `json:"detail-type"`
+	Source     string `json:"source"`
+	Region     string `json:"region"`
+	Detail     struct {
+		ActionType     string `json:"action-type"`
+		Result         string `json:"result"`
+		RepositoryName string `json:"repository-name"`
+		ImageTag       string `json:"image-tag"`
+		ImageDigest    string `json:"image-digest"`
+	} `json:"detail"`
+}
+
+// ErrNoSharedSecret is returned when the receiver is started without a shared secret
+var ErrNoSharedSecret = errors.New("push notification receiver requires a shared secret")
+
+// Start runs the HTTP server until the context is cancelled. It implements manager.Runnable.
+func (r *Receiver) Start(ctx context.Context) error {
+	if r.secret == "" {
+		return ErrNoSharedSecret
+	}
+
+	server := &http.Server{
+		Addr:              r.addr,
+		Handler:           r.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		r.logger.Info("starting notification receiver", "addr", r.addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		return err
+	}
+}
+
+// NeedLeaderElection only runs the receiver on the leader, where the controller
+// consuming the enqueued reconciles is running
+func (r *Receiver) NeedLeaderElection() bool {
+	return true
+}
+
+// Handler returns the HTTP handler serving the notification and reconcile endpoints
+func (r *Receiver) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ecr/sns", r.handleSNS)
+	mux.HandleFunc("/reconcile", r.handleReconcile)
+	return mux
+}
+
+// handleSNS handles ECR EventBridge events delivered through an SNS topic subscription
+func (r *Receiver) handleSNS(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !r.authorized(req) {
+		r.logger.Info("rejecting notification without a valid shared secret", "remoteAddr", req.RemoteAddr)
+		http.Error(w, "invalid secret", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	var msg snsMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		http.Error(w, "invalid SNS message", http.StatusBadRequest)
+		return
+	}
+
+	if len(r.allowedTopicARNs) > 0 && !r.allowedTopicARNs[msg.TopicArn] {
+		r.logger.Info("rejecting SNS message from unexpected topic", "topicArn", msg.TopicArn)
+		http.Error(w, "topic not allowed", http.StatusForbidden)
+		return
+	}
+
+	if err := r.sns.verify(req.Context(), &msg); err != nil {
+		r.logger.Error(err, "rejecting SNS message with invalid signature", "topicArn", msg.TopicArn)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	switch msg.Type {
+	case snsTypeSubscriptionConfirmation:
+		if err := r.sns.confirmSubscription(req.Context(), &msg); err != nil {
+			r.logger.Error(err, "failed to confirm SNS subscription", "topicArn", msg.TopicArn)
+			http.Error(w, "failed to confirm subscription", http.StatusBadGateway)
+			return
+		}
+		r.logger.Info("confirmed SNS subscription", "topicArn", msg.TopicArn)
+	case snsTypeNotification:
+		if err := r.handleECREvent(req.Context(), []byte(msg.Message)); err != nil {
+			r.logger.Error(err, "failed to handle ECR event", "messageId", msg.MessageID)
+			http.Error(w, "failed to handle event", http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// authorized reports whether the request presents the shared secret. Without a
+// configured secret no request is authorized.
+func (r *Receiver) authorized(req *http.Request) bool {
+	if r.secret == "" {
+		return false
+	}
+
+	presented := req.Header.Get(SecretHeader)
+	if presented == "" {
+		_, presented, _ = req.BasicAuth()
+	}
+
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(r.secret)) == 1
+}
+
+// handleECREvent enqueues reconciles for a successful ECR image push event
+func (r *Receiver) handleECREvent(ctx context.Context, payload []byte) error {
+	var evt ecrImageActionEvent
+	if err := json.Unmarshal(payload, &evt); err != nil {
+		return fmt.Errorf("invalid ECR event: %w", err)
+	}
+
+	// Only successful pushes can produce new tags
+	if evt.Source != "aws.ecr" || evt.DetailType != "ECR Image Action" ||
+		evt.Detail.ActionType != "PUSH" || evt.Detail.Result != "SUCCESS" {
+		return nil
+	}
+
+	count, err := r.enqueueForECRRepository(ctx, evt.Region, evt.Detail.RepositoryName)
+	if err != nil {
+		return err
+	}
+
+	r.logger.Info("received ECR image push", "region", evt.Region, "repository", evt.Detail.RepositoryName,
+		"tag", evt.Detail.ImageTag, "enqueued", count)
+	return nil
+}
+
+// enqueueForECRRepository enqueues reconciles for all YukConfigs watching the ECR
+// repository, as their repository or one of their sources, and returns how many were
+// enqueued
+func (r *Receiver) enqueueForECRRepository(ctx context.Context, region, repositoryName string) (int, error) {
+	var yukConfigs yukv1.YukConfigList
+	if err := r.reader.List(ctx, &yukConfigs); err != nil {
+		return 0, fmt.Errorf("failed to list YukConfigs: %w", err)
+	}
+
+	count := 0
+	for i := range yukConfigs.Items {
+		yukConfig := &yukConfigs.Items[i]
+		if !watchesECRRepository(yukConfig, region, repositoryName) {
+			continue
+		}
+
+		r.enqueuer.Enqueue(yukConfig)
+		count++
+	}
+
+	return count, nil
+}
+
+// watchesECRRepository reports whether the repository or any source of the YukConfig
+// is the ECR repository. A repository derived from the image reference at the first
+// update target is matched by its derived region and name.
+func watchesECRRepository(yukConfig *yukv1.YukConfig, region, repositoryName string) bool {
+	if derived := yukConfig.Status.DerivedRepository; derived != nil && yukConfig.Spec.Repository.Type == "ecr" &&
+		derived.RepositoryName == repositoryName && (region == "" || derived.Region == region) {
+		return true
+	}
+
+	repositories := []*yukv1.RepositoryConfig{&yukConfig.Spec.Repository}
+	for i := range yukConfig.Spec.Sources {
+		repositories = append(repositories, &yukConfig.Spec.Sources[i].RepositoryConfig)
+	}
+
+	for _, repository := range repositories {
+		ecrConfig := repository.ECR
+		if repository.Type != "ecr" || ecrConfig == nil {
+			continue
+		}
+
+		if ecrConfig.RepositoryName == repositoryName && (region == "" || ecrConfig.Region == region) {
+			return true
+		}
+	}
+
+	return false
+}
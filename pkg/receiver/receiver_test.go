@@ -0,0 +1,484 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receiver
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// fakeEnqueuer records enqueued YukConfigs
+type fakeEnqueuer struct {
+	enqueued []string
+}
+
+func (f *fakeEnqueuer) Enqueue(yukConfig *yukv1.YukConfig) {
+	f.enqueued = append(f.enqueued, yukConfig.Namespace+"/"+yukConfig.Name)
+}
+
+// testSigner signs SNS messages with a self-signed certificate served over TLS
+type testSigner struct {
+	key     *rsa.PrivateKey
+	server  *httptest.Server
+	certURL string
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cert.pem":
+			_, _ = w.Write(certPEM)
+		case "/subscribe":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return &testSigner{key: key, server: server, certURL: server.URL + "/cert.pem"}
+}
+
+func (s *testSigner) sign(t *testing.T, msg *snsMessage) {
+	msg.SignatureVersion = "2"
+	msg.SigningCertURL = s.certURL
+
+	stringToSign, err := msg.stringToSign()
+	if err != nil {
+		t.Fatalf("Failed to build string to sign: %v", err)
+	}
+
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign message: %v", err)
+	}
+	msg.Signature = base64.StdEncoding.EncodeToString(signature)
+}
+
+// testSecret is the shared secret of test receivers
+const testSecret = "s3cret"
+
+func newTestReceiver(t *testing.T, signer *testSigner, enqueuer Enqueuer, opts ...Option) *Receiver {
+	scheme := runtime.NewScheme()
+	_ = yukv1.AddToScheme(scheme)
+
+	yukConfigs := []*yukv1.YukConfig{
+		newYukConfig("matching", "us-east-1", "my-app"),
+		newYukConfig("other-repo", "us-east-1", "other-app"),
+		newYukConfig("other-region", "eu-west-1", "my-app"),
+		newSourcesYukConfig("sources", "us-east-1", "worker-app"),
+		newYukConfig("derived", "", ""),
+	}
+	yukConfigs[4].Status.DerivedRepository = &yukv1.DerivedRepository{Region: "us-east-1", RepositoryName: "derived-app"}
+
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+	for _, yukConfig := range yukConfigs {
+		builder = builder.WithObjects(yukConfig)
+	}
+
+	opts = append([]Option{WithSharedSecret(testSecret)}, opts...)
+	r := NewReceiver(":0", builder.Build(), enqueuer, opts...)
+	r.sns.httpClient = signer.server.Client()
+	r.sns.hostPattern = regexp.MustCompile(`^127\.0\.0\.1:\d+$`)
+	return r
+}
+
+func newYukConfig(name, region, repositoryName string) *yukv1.YukConfig {
+	return &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: "ecr",
+				ECR: &yukv1.ECRConfig{
+					Region:         region,
+					RepositoryName: repositoryName,
+				},
+			},
+		},
+	}
+}
+
+func newSourcesYukConfig(name, region, repositoryName string) *yukv1.YukConfig {
+	return &yukv1.YukConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: yukv1.YukConfigSpec{
+			Sources: []yukv1.ImageSource{
+				{
+					Name: "api",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: "ecr",
+						ECR:  &yukv1.ECRConfig{Region: region, RepositoryName: "api-app"},
+					},
+				},
+				{
+					Name: "worker",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: "ecr",
+						ECR:  &yukv1.ECRConfig{Region: region, RepositoryName: repositoryName},
+					},
+				},
+			},
+		},
+	}
+}
+
+func ecrPushEvent(t *testing.T, region, repositoryName, result string) string {
+	event := map[string]interface{}{
+		"source":      "aws.ecr",
+		"detail-type": "ECR Image Action",
+		"region":      region,
+		"detail": map[string]string{
+			"action-type":     "PUSH",
+			"result":          result,
+			"repository-name": repositoryName,
+			"image-tag":       "v1.2.3",
+		},
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Failed to marshal event: %v", err)
+	}
+	return string(data)
+}
+
+func postSNS(t *testing.T, r *Receiver, msg *snsMessage) *httptest.ResponseRecorder {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Failed to marshal message: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/ecr/sns", bytes.NewReader(body))
+	req.Header.Set(SecretHeader, testSecret)
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReceiver_handleSNS(t *testing.T) {
+	signer := newTestSigner(t)
+
+	tests := []struct {
+		name           string
+		message        string
+		tamper         bool
+		topicArn       string
+		allowedTopics  []string
+		expectedStatus int
+		expectedQueued []string
+	}{
+		{
+			name:           "successful push enqueues matching config",
+			message:        ecrPushEvent(t, "us-east-1", "my-app", "SUCCESS"),
+			topicArn:       "arn:aws:sns:us-east-1:123456789012:ecr-push",
+			expectedStatus: http.StatusOK,
+			expectedQueued: []string{"default/matching"},
+		},
+		{
+			name:           "push to a source enqueues config without repository",
+			message:        ecrPushEvent(t, "us-east-1", "worker-app", "SUCCESS"),
+			topicArn:       "arn:aws:sns:us-east-1:123456789012:ecr-push",
+			expectedStatus: http.StatusOK,
+			expectedQueued: []string{"default/sources"},
+		},
+		{
+			name:           "push to a derived repository enqueues config",
+			message:        ecrPushEvent(t, "us-east-1", "derived-app", "SUCCESS"),
+			topicArn:       "arn:aws:sns:us-east-1:123456789012:ecr-push",
+			expectedStatus: http.StatusOK,
+			expectedQueued: []string{"default/derived"},
+		},
+		{
+			name:           "push to a source in another region is ignored",
+			message:        ecrPushEvent(t, "eu-west-1", "worker-app", "SUCCESS"),
+			topicArn:       "arn:aws:sns:us-east-1:123456789012:ecr-push",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "failed push is ignored",
+			message:        ecrPushEvent(t, "us-east-1", "my-app", "FAILURE"),
+			topicArn:       "arn:aws:sns:us-east-1:123456789012:ecr-push",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown repository enqueues nothing",
+			message:        ecrPushEvent(t, "us-east-1", "unknown", "SUCCESS"),
+			topicArn:       "arn:aws:sns:us-east-1:123456789012:ecr-push",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "tampered message is rejected",
+			message:        ecrPushEvent(t, "us-east-1", "my-app", "SUCCESS"),
+			tamper:         true,
+			topicArn:       "arn:aws:sns:us-east-1:123456789012:ecr-push",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unexpected topic is rejected",
+			message:        ecrPushEvent(t, "us-east-1", "my-app", "SUCCESS"),
+			topicArn:       "arn:aws:sns:us-east-1:123456789012:other",
+			allowedTopics:  []string{"arn:aws:sns:us-east-1:123456789012:ecr-push"},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enqueuer := &fakeEnqueuer{}
+			r := newTestReceiver(t, signer, enqueuer, WithAllowedTopicARNs(tt.allowedTopics))
+
+			msg := &snsMessage{
+				Type:      snsTypeNotification,
+				MessageID: "message-id",
+				TopicArn:  tt.topicArn,
+				Message:   tt.message,
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			}
+			signer.sign(t, msg)
+			if tt.tamper {
+				msg.Message = ecrPushEvent(t, "us-east-1", "other-app", "SUCCESS")
+			}
+
+			rec := postSNS(t, r, msg)
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+
+			if len(enqueuer.enqueued) != len(tt.expectedQueued) {
+				t.Fatalf("Expected %d enqueued configs, got %v", len(tt.expectedQueued), enqueuer.enqueued)
+			}
+			for i, key := range tt.expectedQueued {
+				if enqueuer.enqueued[i] != key {
+					t.Errorf("Expected enqueued config %s, got %s", key, enqueuer.enqueued[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReceiver_handleSNS_SharedSecret(t *testing.T) {
+	signer := newTestSigner(t)
+
+	tests := []struct {
+		name           string
+		header         string
+		basicAuth      string
+		expectedStatus int
+		expectedQueued int
+	}{
+		{
+			name:           "secret header is accepted",
+			header:         "s3cret",
+			expectedStatus: http.StatusOK,
+			expectedQueued: 1,
+		},
+		{
+			name:           "basic auth password is accepted",
+			basicAuth:      "s3cret",
+			expectedStatus: http.StatusOK,
+			expectedQueued: 1,
+		},
+		{
+			name:           "wrong secret is rejected",
+			header:         "guess",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing secret is rejected",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enqueuer := &fakeEnqueuer{}
+			r := newTestReceiver(t, signer, enqueuer)
+
+			msg := &snsMessage{
+				Type:      snsTypeNotification,
+				MessageID: "message-id",
+				TopicArn:  "arn:aws:sns:us-east-1:123456789012:ecr-push",
+				Message:   ecrPushEvent(t, "us-east-1", "my-app", "SUCCESS"),
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			}
+			signer.sign(t, msg)
+
+			body, err := json.Marshal(msg)
+			if err != nil {
+				t.Fatalf("Failed to marshal message: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/ecr/sns", bytes.NewReader(body))
+			if tt.header != "" {
+				req.Header.Set(SecretHeader, tt.header)
+			}
+			if tt.basicAuth != "" {
+				req.SetBasicAuth("yuk", tt.basicAuth)
+			}
+			rec := httptest.NewRecorder()
+			r.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if len(enqueuer.enqueued) != tt.expectedQueued {
+				t.Errorf("Expected %d enqueued configs, got %v", tt.expectedQueued, enqueuer.enqueued)
+			}
+		})
+	}
+}
+
+func TestReceiver_NoSharedSecret(t *testing.T) {
+	signer := newTestSigner(t)
+	enqueuer := &fakeEnqueuer{}
+	r := newTestReceiver(t, signer, enqueuer, WithSharedSecret(""))
+
+	if err := r.Start(context.Background()); !errors.Is(err, ErrNoSharedSecret) {
+		t.Errorf("Expected ErrNoSharedSecret, got %v", err)
+	}
+
+	// Requests are rejected even when the handler is served without Start
+	msg := &snsMessage{
+		Type:      snsTypeNotification,
+		MessageID: "message-id",
+		TopicArn:  "arn:aws:sns:us-east-1:123456789012:ecr-push",
+		Message:   ecrPushEvent(t, "us-east-1", "my-app", "SUCCESS"),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	signer.sign(t, msg)
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Failed to marshal message: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/ecr/sns", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if len(enqueuer.enqueued) != 0 {
+		t.Errorf("Expected no enqueued configs, got %v", enqueuer.enqueued)
+	}
+}
+
+func TestReceiver_handleSNS_SubscriptionConfirmation(t *testing.T) {
+	signer := newTestSigner(t)
+	r := newTestReceiver(t, signer, &fakeEnqueuer{})
+
+	msg := &snsMessage{
+		Type:         snsTypeSubscriptionConfirmation,
+		MessageID:    "message-id",
+		Token:        "token",
+		TopicArn:     "arn:aws:sns:us-east-1:123456789012:ecr-push",
+		Message:      "You have chosen to subscribe to the topic",
+		SubscribeURL: signer.server.URL + "/subscribe",
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+	}
+	signer.sign(t, msg)
+
+	rec := postSNS(t, r, msg)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestSNSVerifier_validateURL(t *testing.T) {
+	v := newSNSVerifier()
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{
+			name:    "SNS certificate URL",
+			url:     "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-abc.pem",
+			wantErr: false,
+		},
+		{
+			name:    "SNS China certificate URL",
+			url:     "https://sns.cn-north-1.amazonaws.com.cn/SimpleNotificationService-abc.pem",
+			wantErr: false,
+		},
+		{
+			name:    "plain http",
+			url:     "http://sns.us-east-1.amazonaws.com/SimpleNotificationService-abc.pem",
+			wantErr: true,
+		},
+		{
+			name:    "foreign host",
+			url:     "https://sns.us-east-1.amazonaws.com.evil.example/cert.pem",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.validateURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
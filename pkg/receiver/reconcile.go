@@ -0,0 +1,81 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receiver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// reconcileResponse lists the YukConfigs enqueued by a reconcile request
+type reconcileResponse struct {
+	// Enqueued are the enqueued YukConfigs as namespace/name, in list order
+	Enqueued []string `json:"enqueued"`
+}
+
+// handleReconcile enqueues immediate reconciles of all YukConfigs, or of those in the
+// namespace and matching the label selector of the query, e.g.
+// POST /reconcile?namespace=apps&labelSelector=team%3Dpayments
+func (r *Receiver) handleReconcile(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !r.authorized(req) {
+		r.logger.Info("rejecting reconcile request without a valid shared secret", "remoteAddr", req.RemoteAddr)
+		http.Error(w, "invalid secret", http.StatusUnauthorized)
+		return
+	}
+
+	query := req.URL.Query()
+	selector, err := labels.Parse(query.Get("labelSelector"))
+	if err != nil {
+		http.Error(w, "invalid label selector: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := []client.ListOption{client.MatchingLabelsSelector{Selector: selector}}
+	if namespace := query.Get("namespace"); namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+
+	var yukConfigs yukv1.YukConfigList
+	if err := r.reader.List(req.Context(), &yukConfigs, opts...); err != nil {
+		r.logger.Error(err, "failed to list YukConfigs")
+		http.Error(w, "failed to list YukConfigs", http.StatusInternalServerError)
+		return
+	}
+
+	response := reconcileResponse{Enqueued: []string{}}
+	for i := range yukConfigs.Items {
+		yukConfig := &yukConfigs.Items[i]
+		r.enqueuer.Enqueue(yukConfig)
+		response.Enqueued = append(response.Enqueued, yukConfig.Namespace+"/"+yukConfig.Name)
+	}
+
+	r.logger.Info("received reconcile request", "namespace", query.Get("namespace"),
+		"labelSelector", selector.String(), "enqueued", len(response.Enqueued))
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
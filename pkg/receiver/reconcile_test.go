@@ -0,0 +1,152 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receiver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func newReconcileTestReceiver(enqueuer Enqueuer) *Receiver {
+	scheme := runtime.NewScheme()
+	_ = yukv1.AddToScheme(scheme)
+
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+	for _, yukConfig := range []struct {
+		namespace, name, team string
+	}{
+		{"apps", "payments-api", "payments"},
+		{"apps", "payments-worker", "payments"},
+		{"apps", "search", "search"},
+		{"tools", "payments-cron", "payments"},
+	} {
+		builder = builder.WithObjects(&yukv1.YukConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      yukConfig.name,
+				Namespace: yukConfig.namespace,
+				Labels:    map[string]string{"team": yukConfig.team},
+			},
+		})
+	}
+
+	return NewReceiver(":0", builder.Build(), enqueuer, WithSharedSecret(testSecret))
+}
+
+func TestReceiver_handleReconcile(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		query            string
+		secret           string
+		expectedStatus   int
+		expectedEnqueued []string
+	}{
+		{
+			name:             "all YukConfigs",
+			secret:           testSecret,
+			expectedStatus:   http.StatusOK,
+			expectedEnqueued: []string{"apps/payments-api", "apps/payments-worker", "apps/search", "tools/payments-cron"},
+		},
+		{
+			name:             "label selector",
+			query:            "labelSelector=team%3Dpayments",
+			secret:           testSecret,
+			expectedStatus:   http.StatusOK,
+			expectedEnqueued: []string{"apps/payments-api", "apps/payments-worker", "tools/payments-cron"},
+		},
+		{
+			name:             "namespace and label selector",
+			query:            "namespace=apps&labelSelector=team+in+(payments)",
+			secret:           testSecret,
+			expectedStatus:   http.StatusOK,
+			expectedEnqueued: []string{"apps/payments-api", "apps/payments-worker"},
+		},
+		{
+			name:             "no matches",
+			query:            "namespace=empty",
+			secret:           testSecret,
+			expectedStatus:   http.StatusOK,
+			expectedEnqueued: []string{},
+		},
+		{
+			name:           "invalid label selector",
+			query:          "labelSelector=team%3D%3D%3D",
+			secret:         testSecret,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "wrong secret is rejected",
+			secret:         "guess",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing secret is rejected",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "GET is not allowed",
+			method:         http.MethodGet,
+			secret:         testSecret,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enqueuer := &fakeEnqueuer{}
+			r := newReconcileTestReceiver(enqueuer)
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			req := httptest.NewRequest(method, "/reconcile?"+tt.query, nil)
+			if tt.secret != "" {
+				req.Header.Set(SecretHeader, tt.secret)
+			}
+			rec := httptest.NewRecorder()
+			r.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if strings.Join(enqueuer.enqueued, ",") != strings.Join(tt.expectedEnqueued, ",") {
+				t.Errorf("Expected enqueued configs %v, got %v", tt.expectedEnqueued, enqueuer.enqueued)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response reconcileResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if strings.Join(response.Enqueued, ",") != strings.Join(tt.expectedEnqueued, ",") {
+				t.Errorf("Expected response %v, got %v", tt.expectedEnqueued, response.Enqueued)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tagsort orders image tags by the sort strategies shared by all registry clients
+package tagsort
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Strategy selects how tags are ordered
+type Strategy string
+
+const (
+	// Lexical orders tags by descending lexical order (default)
+	Lexical Strategy = "lexical"
+
+	// Semver orders tags by descending semantic version
+	Semver Strategy = "semver"
+
+	// PushTime orders tags by descending push time of their image
+	PushTime Strategy = "pushtime"
+)
+
+// SortGroup is the name of the tag filter capture group tags are sorted by, e.g.
+// `app-(?P<sort>\d{4}\.\d{2}\.\d{2})-.*`
+const SortGroup = "sort"
+
+// ErrNoMatch is returned by Select when no candidate matches the tag filter
+var ErrNoMatch = errors.New("no tags found matching filter")
+
+// Candidate is a tag along with the time its image was pushed, or the zero time when
+// the registry does not report it. Tags with a SortKey are sorted by it instead of the
+// whole tag.
+type Candidate struct {
+	Tag      string
+	SortKey  string
+	PushedAt time.Time
+}
+
+// SortKey returns the substring of the tag captured by the SortGroup group of the tag
+// filter, or an empty string when the filter has no such group or it did not match
+func SortKey(tagFilter *regexp.Regexp, tag string) string {
+	if tagFilter == nil {
+		return ""
+	}
+
+	index := tagFilter.SubexpIndex(SortGroup)
+	if index < 0 {
+		return ""
+	}
+
+	match := tagFilter.FindStringSubmatch(tag)
+	if match == nil {
+		return ""
+	}
+	return match[index]
+}
+
+// key returns the string the candidate is sorted by
+func (c Candidate) key() string {
+	if c.SortKey != "" {
+		return c.SortKey
+	}
+	return c.Tag
+}
+
+// Candidates returns candidates without push times for the given tags
+func Candidates(tags []string) []Candidate {
+	candidates := make([]Candidate, len(tags))
+	for i, tag := range tags {
+		candidates[i] = Candidate{Tag: tag}
+	}
+	return candidates
+}
+
+// Validate returns an error for a strategy that is not Lexical or one of the supported
+// strategies. An empty strategy means Lexical.
+func Validate(strategy Strategy, supported ...Strategy) error {
+	if strategy == "" || strategy == Lexical {
+		return nil
+	}
+	for _, s := range supported {
+		if strategy == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported sort strategy: %s", strategy)
+}
+
+// Latest orders the candidates by the strategy and returns the tag of the first one, or
+// an empty string when there are no candidates. Lexical and Semver order compare the sort
+// key of candidates that have one. Ties are broken by descending lexical order of the sort
+// key, then of the tag, so the result is deterministic.
+//
+// With Semver, a leading "v" is allowed, pre-releases rank below their release and build
+// metadata is ignored; tags that are not valid semantic versions rank below all valid
+// ones. With PushTime, images without a push time rank below all others.
+func Latest(candidates []Candidate, strategy Strategy) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	var compare func(a, b Candidate) int
+	switch strategy {
+	case Semver:
+		versions := make(map[string]*semver.Version, len(candidates))
+		for _, candidate := range candidates {
+			if version, err := semver.NewVersion(candidate.key()); err == nil {
+				versions[candidate.key()] = version
+			}
+		}
+		compare = func(a, b Candidate) int {
+			va, vb := versions[a.key()], versions[b.key()]
+			switch {
+			case va != nil && vb == nil:
+				return 1
+			case va == nil && vb != nil:
+				return -1
+			case va != nil && vb != nil:
+				return va.Compare(vb)
+			}
+			return 0
+		}
+	case PushTime:
+		compare = func(a, b Candidate) int {
+			return a.PushedAt.Compare(b.PushedAt)
+		}
+	default:
+		compare = func(a, b Candidate) int { return 0 }
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if cmp := compare(candidates[i], candidates[j]); cmp != 0 {
+			return cmp > 0
+		}
+		if ki, kj := candidates[i].key(), candidates[j].key(); ki != kj {
+			return ki > kj // Descending order
+		}
+		return candidates[i].Tag > candidates[j].Tag
+	})
+
+	return candidates[0].Tag
+}
+
+// Select returns the latest of the candidates whose tag matches the tag filter regular
+// expression, ordered by the strategy as by Latest. An empty filter matches all tags,
+// and candidates without a tag are skipped. The sort key of each matching candidate is
+// taken from the SortGroup group of the filter. It returns ErrNoMatch when no candidate
+// matches.
+func Select(candidates []Candidate, tagFilter string, strategy Strategy) (string, error) {
+	var tagRegex *regexp.Regexp
+	if tagFilter != "" {
+		var err error
+		tagRegex, err = regexp.Compile(tagFilter)
+		if err != nil {
+			return "", fmt.Errorf("invalid tag filter regex: %w", err)
+		}
+	}
+
+	var matches []Candidate
+	for _, candidate := range candidates {
+		if candidate.Tag == "" || (tagRegex != nil && !tagRegex.MatchString(candidate.Tag)) {
+			continue
+		}
+		candidate.SortKey = SortKey(tagRegex, candidate.Tag)
+		matches = append(matches, candidate)
+	}
+
+	if len(matches) == 0 {
+		return "", ErrNoMatch
+	}
+
+	return Latest(matches, strategy), nil
+}
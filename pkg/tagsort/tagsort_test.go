@@ -0,0 +1,274 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tagsort
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestLatest_Lexical(t *testing.T) {
+	tags := []string{"v1.9.0", "v1.10.0", "latest", "main-abc123"}
+
+	for _, strategy := range []Strategy{"", Lexical} {
+		if tag := Latest(Candidates(tags), strategy); tag != "v1.9.0" {
+			t.Errorf("Expected v1.9.0 with strategy %q, got %s", strategy, tag)
+		}
+	}
+}
+
+func TestLatest_Semver(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     []string
+		expected string
+	}{
+		{
+			name:     "numeric ordering",
+			tags:     []string{"v1.9.0", "v1.10.0", "v1.2.0"},
+			expected: "v1.10.0",
+		},
+		{
+			name:     "pre-release below release",
+			tags:     []string{"v1.2.0-rc1", "v1.2.0", "v1.1.9"},
+			expected: "v1.2.0",
+		},
+		{
+			name:     "pre-release above older release",
+			tags:     []string{"v1.2.0-rc1", "v1.1.9"},
+			expected: "v1.2.0-rc1",
+		},
+		{
+			name:     "build metadata ignored",
+			tags:     []string{"v1.2.0+build.9", "v1.2.0+build.10", "v1.1.0"},
+			expected: "v1.2.0+build.9",
+		},
+		{
+			name:     "mixed valid and invalid tags",
+			tags:     []string{"latest", "v1.9.0", "main-abc123", "v1.10.0", "zzz"},
+			expected: "v1.10.0",
+		},
+		{
+			name:     "with and without prefix",
+			tags:     []string{"1.10.0", "v1.9.0"},
+			expected: "1.10.0",
+		},
+		{
+			name:     "only invalid tags",
+			tags:     []string{"latest", "main-abc123", "stable"},
+			expected: "stable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tag := Latest(Candidates(tt.tags), Semver); tag != tt.expected {
+				t.Errorf("Expected tag %s, got %s", tt.expected, tag)
+			}
+		})
+	}
+}
+
+func TestLatest_PushTime(t *testing.T) {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		candidates []Candidate
+		expected   string
+	}{
+		{
+			name: "newest image",
+			candidates: []Candidate{
+				{Tag: "build-f00d", PushedAt: base.Add(2 * time.Hour)},
+				{Tag: "build-zzzz", PushedAt: base},
+				{Tag: "build-9999", PushedAt: base.Add(time.Hour)},
+			},
+			expected: "build-f00d",
+		},
+		{
+			name: "tags sharing the newest image",
+			candidates: []Candidate{
+				{Tag: "build-0bcd", PushedAt: base.Add(5 * time.Hour)},
+				{Tag: "build-abc1", PushedAt: base.Add(5 * time.Hour)},
+				{Tag: "build-zzzz", PushedAt: base},
+			},
+			expected: "build-abc1",
+		},
+		{
+			name: "image without push time",
+			candidates: []Candidate{
+				{Tag: "build-ffff"},
+				{Tag: "build-zzzz", PushedAt: base},
+			},
+			expected: "build-zzzz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tag := Latest(tt.candidates, PushTime); tag != tt.expected {
+				t.Errorf("Expected tag %s, got %s", tt.expected, tag)
+			}
+		})
+	}
+}
+
+func TestLatest_SortKey(t *testing.T) {
+	// The prefix orders the tags differently than the date they embed
+	tagFilter := regexp.MustCompile(`^[a-z]+-(?P<sort>\d{4}\.\d{2}\.\d{2})-[a-z0-9]+$`)
+	tags := []string{"zeta-2024.01.02-abcd", "alpha-2024.03.14-ef01", "beta-2023.12.31-0000"}
+
+	candidates := make([]Candidate, len(tags))
+	for i, tag := range tags {
+		candidates[i] = Candidate{Tag: tag, SortKey: SortKey(tagFilter, tag)}
+	}
+
+	if tag := Latest(candidates, Lexical); tag != "alpha-2024.03.14-ef01" {
+		t.Errorf("Expected the tag with the newest date, got %s", tag)
+	}
+
+	// Without a sort key, the whole tag is compared
+	if tag := Latest(Candidates(tags), Lexical); tag != "zeta-2024.01.02-abcd" {
+		t.Errorf("Expected zeta-2024.01.02-abcd, got %s", tag)
+	}
+
+	// Semantic versions are parsed from the sort key
+	semverFilter := regexp.MustCompile(`^build-\d+-(?P<sort>v\d+\.\d+\.\d+)$`)
+	semverTags := []string{"build-9-v1.9.0", "build-10-v1.10.0", "build-11-v1.2.0"}
+	semverCandidates := make([]Candidate, len(semverTags))
+	for i, tag := range semverTags {
+		semverCandidates[i] = Candidate{Tag: tag, SortKey: SortKey(semverFilter, tag)}
+	}
+	if tag := Latest(semverCandidates, Semver); tag != "build-10-v1.10.0" {
+		t.Errorf("Expected build-10-v1.10.0, got %s", tag)
+	}
+}
+
+func TestSortKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		tagFilter *regexp.Regexp
+		tag       string
+		expected  string
+	}{
+		{
+			name:      "sort group",
+			tagFilter: regexp.MustCompile(`^app-(?P<sort>\d+)-`),
+			tag:       "app-20240314-abcd",
+			expected:  "20240314",
+		},
+		{
+			name:      "unnamed group",
+			tagFilter: regexp.MustCompile(`^app-(\d+)-`),
+			tag:       "app-20240314-abcd",
+		},
+		{
+			name:      "optional sort group not matched",
+			tagFilter: regexp.MustCompile(`^app(-(?P<sort>\d+))?$`),
+			tag:       "app",
+		},
+		{
+			name: "no filter",
+			tag:  "app-20240314-abcd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if key := SortKey(tt.tagFilter, tt.tag); key != tt.expected {
+				t.Errorf("Expected sort key %q, got %q", tt.expected, key)
+			}
+		})
+	}
+}
+
+func TestLatest_NoCandidates(t *testing.T) {
+	if tag := Latest(nil, Semver); tag != "" {
+		t.Errorf("Expected no tag, got %s", tag)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	pushedAt := time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)
+	candidates := []Candidate{
+		{Tag: "v1.9.0", PushedAt: pushedAt},
+		{Tag: "v1.10.0", PushedAt: pushedAt.Add(-time.Hour)},
+		{Tag: "app-20240314-abcd", PushedAt: pushedAt.Add(-2 * time.Hour)},
+		{Tag: "app-20240301-ffff", PushedAt: pushedAt.Add(time.Hour)},
+		{Tag: ""},
+	}
+
+	tests := []struct {
+		name      string
+		tagFilter string
+		strategy  Strategy
+		expected  string
+		err       error
+	}{
+		{name: "no filter", expected: "v1.9.0"},
+		{name: "filter", tagFilter: `^v\d+`, strategy: Semver, expected: "v1.10.0"},
+		{name: "push time", tagFilter: `^v\d+`, strategy: PushTime, expected: "v1.9.0"},
+		{name: "sort group", tagFilter: `^app-(?P<sort>\d+)-`, expected: "app-20240314-abcd"},
+		{name: "no match", tagFilter: `^release-`, err: ErrNoMatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, err := Select(candidates, tt.tagFilter, tt.strategy)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if tag != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, tag)
+			}
+		})
+	}
+
+	if _, err := Select(candidates, "[", Lexical); err == nil {
+		t.Error("Expected an error for an invalid tag filter")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		strategy  Strategy
+		supported []Strategy
+		shouldErr bool
+	}{
+		{strategy: ""},
+		{strategy: Lexical},
+		{strategy: Semver, supported: []Strategy{Semver, PushTime}},
+		{strategy: PushTime, supported: []Strategy{Semver, PushTime}},
+		{strategy: PushTime, supported: []Strategy{Semver}, shouldErr: true},
+		{strategy: "calver", supported: []Strategy{Semver, PushTime}, shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			err := Validate(tt.strategy, tt.supported...)
+			if tt.shouldErr && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.shouldErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
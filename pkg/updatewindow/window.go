@@ -0,0 +1,150 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package updatewindow decides whether updates may be pushed at a given time
+package updatewindow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+// minutesPerDay is the number of minutes of a day, the latest end of a window
+const minutesPerDay = 24 * 60
+
+// Window is a parsed update window: a time range on some days of the week in a time zone
+type Window struct {
+	days     map[time.Weekday]bool
+	start    int
+	end      int
+	location *time.Location
+}
+
+// New parses an update window
+func New(config yukv1.UpdateWindow) (*Window, error) {
+	w := &Window{end: minutesPerDay, location: time.UTC}
+
+	if len(config.Days) > 0 {
+		w.days = make(map[time.Weekday]bool, len(config.Days))
+		for _, day := range config.Days {
+			weekday, err := ParseWeekday(day)
+			if err != nil {
+				return nil, err
+			}
+			w.days[weekday] = true
+		}
+	}
+
+	var err error
+	if config.Start != "" {
+		if w.start, err = ParseTimeOfDay(config.Start); err != nil {
+			return nil, err
+		}
+		if w.start == minutesPerDay {
+			return nil, fmt.Errorf("invalid start %q: must be before 24:00", config.Start)
+		}
+	}
+	if config.End != "" {
+		if w.end, err = ParseTimeOfDay(config.End); err != nil {
+			return nil, err
+		}
+	}
+	if config.TimeZone != "" {
+		if w.location, err = time.LoadLocation(config.TimeZone); err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", config.TimeZone, err)
+		}
+	}
+
+	return w, nil
+}
+
+// ParseWeekday parses the abbreviation (e.g. "Mon") or name (e.g. "Monday") of a day of the
+// week, ignoring case
+func ParseWeekday(value string) (time.Weekday, error) {
+	name := strings.TrimSpace(value)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(name, weekday.String()) || strings.EqualFold(name, weekday.String()[:3]) {
+			return weekday, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid day %q: must be one of Mon, Tue, Wed, Thu, Fri, Sat or Sun", value)
+}
+
+// ParseTimeOfDay parses a time of day "HH:MM" from 00:00 to 24:00 into minutes after
+// midnight
+func ParseTimeOfDay(value string) (int, error) {
+	hours, minutes, found := strings.Cut(value, ":")
+	h, hErr := strconv.Atoi(hours)
+	m, mErr := strconv.Atoi(minutes)
+	if !found || len(hours) != 2 || len(minutes) != 2 || hErr != nil || mErr != nil ||
+		h < 0 || m < 0 || m > 59 || h*60+m > minutesPerDay {
+		return 0, fmt.Errorf("invalid time of day %q: must be HH:MM between 00:00 and 24:00", value)
+	}
+	return h*60 + m, nil
+}
+
+// Open reports whether the window is open at the given time
+func (w *Window) Open(now time.Time) bool {
+	now = now.In(w.location)
+
+	// A window ending at or before its start opens the day before and is still open today
+	for _, daysAgo := range []int{0, 1} {
+		day := time.Date(now.Year(), now.Month(), now.Day()-daysAgo, 0, 0, 0, 0, w.location)
+		if !w.opensOn(day) {
+			continue
+		}
+		if start, end := w.opening(day); !now.Before(start) && now.Before(end) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NextOpen returns the time the window opens next after the given time, or the zero time
+// when it never opens
+func (w *Window) NextOpen(now time.Time) time.Time {
+	local := now.In(w.location)
+
+	for daysAhead := 0; daysAhead <= 7; daysAhead++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+daysAhead, 0, 0, 0, 0, w.location)
+		if start, _ := w.opening(day); w.opensOn(day) && start.After(now) {
+			return start
+		}
+	}
+
+	return time.Time{}
+}
+
+// opensOn reports whether the window opens on the day
+func (w *Window) opensOn(day time.Time) bool {
+	return w.days == nil || w.days[day.Weekday()]
+}
+
+// opening returns when the window opening on the day opens and closes
+func (w *Window) opening(day time.Time) (time.Time, time.Time) {
+	end := w.end
+	if end <= w.start {
+		end += minutesPerDay
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, w.start, 0, 0, w.location)
+	return start, time.Date(day.Year(), day.Month(), day.Day(), 0, end, 0, 0, w.location)
+}
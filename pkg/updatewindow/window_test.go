@@ -0,0 +1,147 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package updatewindow
+
+import (
+	"testing"
+	"time"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func TestWindow(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("Time zone data not available: %v", err)
+	}
+	at := func(value string) time.Time {
+		parsed, err := time.ParseInLocation("Mon 2006-01-02 15:04", value, berlin)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", value, err)
+		}
+		return parsed
+	}
+
+	businessHours := yukv1.UpdateWindow{
+		Days:     []string{"Mon", "tue", "Wednesday", "Thu", "Fri"},
+		Start:    "09:00",
+		End:      "17:00",
+		TimeZone: "Europe/Berlin",
+	}
+	overnight := yukv1.UpdateWindow{Days: []string{"Sat"}, Start: "22:00", End: "06:00", TimeZone: "Europe/Berlin"}
+
+	tests := []struct {
+		name         string
+		window       yukv1.UpdateWindow
+		now          time.Time
+		expectedOpen bool
+		expectedNext time.Time
+	}{
+		{
+			name:         "during business hours",
+			window:       businessHours,
+			now:          at("Wed 2024-05-15 10:30"),
+			expectedOpen: true,
+			expectedNext: at("Thu 2024-05-16 09:00"),
+		},
+		{
+			name:         "before business hours",
+			window:       businessHours,
+			now:          at("Wed 2024-05-15 08:59"),
+			expectedNext: at("Wed 2024-05-15 09:00"),
+		},
+		{
+			name:         "end is exclusive",
+			window:       businessHours,
+			now:          at("Fri 2024-05-17 17:00"),
+			expectedNext: at("Mon 2024-05-20 09:00"),
+		},
+		{
+			name:         "weekend",
+			window:       businessHours,
+			now:          at("Sat 2024-05-18 12:00"),
+			expectedNext: at("Mon 2024-05-20 09:00"),
+		},
+		{
+			name:         "other time zone",
+			window:       businessHours,
+			now:          time.Date(2024, 5, 15, 7, 30, 0, 0, time.UTC),
+			expectedOpen: true,
+			expectedNext: at("Thu 2024-05-16 09:00"),
+		},
+		{
+			name:         "overnight window after midnight",
+			window:       overnight,
+			now:          at("Sun 2024-05-19 05:00"),
+			expectedOpen: true,
+			expectedNext: at("Sat 2024-05-25 22:00"),
+		},
+		{
+			name:         "overnight window closed",
+			window:       overnight,
+			now:          at("Sun 2024-05-19 06:00"),
+			expectedNext: at("Sat 2024-05-25 22:00"),
+		},
+		{
+			name:         "every day by default",
+			window:       yukv1.UpdateWindow{Start: "02:00", End: "04:00"},
+			now:          time.Date(2024, 5, 15, 4, 0, 0, 0, time.UTC),
+			expectedNext: time.Date(2024, 5, 16, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "across a daylight saving time change",
+			window:       yukv1.UpdateWindow{Days: []string{"Sun"}, Start: "09:00", TimeZone: "Europe/Berlin"},
+			now:          at("Sat 2024-03-30 12:00"),
+			expectedNext: time.Date(2024, 3, 31, 7, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, err := New(tt.window)
+			if err != nil {
+				t.Fatalf("Failed to parse window: %v", err)
+			}
+
+			if open := window.Open(tt.now); open != tt.expectedOpen {
+				t.Errorf("Expected open to be %v, got %v", tt.expectedOpen, open)
+			}
+			if next := window.NextOpen(tt.now); !next.Equal(tt.expectedNext) {
+				t.Errorf("Expected the window to open next at %s, got %s", tt.expectedNext, next)
+			}
+		})
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := map[string]yukv1.UpdateWindow{
+		"unknown day":       {Days: []string{"Mo"}},
+		"invalid start":     {Start: "9:00"},
+		"start at midnight": {Start: "24:00"},
+		"invalid end":       {End: "24:01"},
+		"invalid minutes":   {End: "12:60"},
+		"unknown time zone": {TimeZone: "Mars/Olympus_Mons"},
+		"missing separator": {Start: "0900"},
+		"negative time":     {Start: "-1:00"},
+	}
+
+	for name, config := range tests {
+		if _, err := New(config); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,507 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package validation validates YukConfig resources before they are applied, e.g. from
+// an admission webhook or the yukctl CLI
+package validation
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"text/template"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+	"github.com/rebelopsio/yuk/pkg/tagsort"
+	"github.com/rebelopsio/yuk/pkg/updatewindow"
+	"github.com/rebelopsio/yuk/pkg/yaml"
+)
+
+// registryIDPattern matches the AWS account ID an ECR registry is identified by
+var registryIDPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
+// updateStrategies are the supported update strategies
+var updateStrategies = []string{
+	yukv1.UpdateStrategyAutoPush,
+	yukv1.UpdateStrategyPullRequest,
+	yukv1.UpdateStrategyApproval,
+	yukv1.UpdateStrategyAudit,
+	yukv1.UpdateStrategyDryRun,
+}
+
+// ValidateYukConfig returns the problems of a YukConfig: inconsistent repositories, tag
+// filters that do not compile, invalid sources and invalid update targets
+func ValidateYukConfig(yukConfig *yukv1.YukConfig) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	spec := &yukConfig.Spec
+
+	// The repository may be omitted when sources are set
+	repository := spec.Repository
+	if repository.Type != "" || repository.ECR != nil || repository.OCI != nil || repository.GHCR != nil || repository.GAR != nil || repository.ACR != nil || repository.Quay != nil || len(spec.Sources) == 0 {
+		allErrs = append(allErrs, validateRepository(&repository, ECRRepositoryDerivable(yukConfig), specPath.Child("repository"))...)
+	}
+	for i := range spec.Sources {
+		allErrs = append(allErrs, validateRepository(&spec.Sources[i].RepositoryConfig, false, specPath.Child("sources").Index(i))...)
+	}
+	allErrs = append(allErrs, ValidateSources(yukConfig)...)
+
+	if spec.Git.Repository == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("git", "repository"), ""))
+	}
+	allErrs = append(allErrs, validateTemplate(spec.Git.CommitMessage, specPath.Child("git", "commitMessage"))...)
+
+	if spec.UpdateStrategy != "" && !contains(updateStrategies, spec.UpdateStrategy) {
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("updateStrategy"), spec.UpdateStrategy, updateStrategies))
+	}
+	if spec.VerifySignature != nil {
+		allErrs = append(allErrs, validateSignatureVerification(yukConfig, specPath.Child("verifySignature"))...)
+	}
+	if limit := spec.HistoryLimit; limit != nil && (*limit < 0 || *limit > yukv1.MaxHistoryLimit) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("historyLimit"), *limit,
+			fmt.Sprintf("must be between 0 and %d", yukv1.MaxHistoryLimit)))
+	}
+	if limit := spec.MaxConsecutiveFailures; limit != nil && *limit < 1 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("maxConsecutiveFailures"), *limit, "must be at least 1"))
+	}
+	if spec.UpdateWindow != nil {
+		allErrs = append(allErrs, validateUpdateWindow(spec.UpdateWindow, specPath.Child("updateWindow"))...)
+	}
+	if workload := spec.DeployedWorkload; workload != nil {
+		workloadPath := specPath.Child("deployedWorkload")
+		if !contains(workloadKinds, workload.Kind) {
+			allErrs = append(allErrs, field.NotSupported(workloadPath.Child("kind"), workload.Kind, workloadKinds))
+		}
+		if workload.Name == "" {
+			allErrs = append(allErrs, field.Required(workloadPath.Child("name"), ""))
+		}
+	}
+
+	targetsPath := specPath.Child("updateTargets")
+	if len(spec.UpdateTargets) == 0 {
+		allErrs = append(allErrs, field.Required(targetsPath, "at least one update target is required"))
+	}
+	for i, target := range spec.UpdateTargets {
+		allErrs = append(allErrs, validateUpdateTarget(target, targetsPath.Index(i))...)
+		allErrs = append(allErrs, validateTargetSortStrategy(yukConfig, target, targetsPath.Index(i).Child("sortStrategy"))...)
+	}
+
+	return allErrs
+}
+
+// ValidateSources returns an error for unnamed or duplicate sources and for targets
+// naming an unknown source
+func ValidateSources(yukConfig *yukv1.YukConfig) field.ErrorList {
+	var allErrs field.ErrorList
+	sourcesPath := field.NewPath("spec", "sources")
+
+	names := make(map[string]bool)
+	for i, source := range yukConfig.Spec.Sources {
+		namePath := sourcesPath.Index(i).Child("name")
+		switch {
+		case source.Name == "":
+			allErrs = append(allErrs, field.Required(namePath, "sources must have a name"))
+		case names[source.Name]:
+			allErrs = append(allErrs, field.Duplicate(namePath, source.Name))
+		}
+		names[source.Name] = true
+	}
+
+	for i, target := range yukConfig.Spec.UpdateTargets {
+		if target.Source != "" && !names[target.Source] {
+			sourcePath := field.NewPath("spec", "updateTargets").Index(i).Child("source")
+			allErrs = append(allErrs, field.NotFound(sourcePath, target.Source))
+		}
+	}
+
+	return allErrs
+}
+
+// underivableECRRepository explains why the region or repository name of an ECR
+// repository is required
+const underivableECRRepository = "required unless derived from the image reference at the first update target, an imageTagOnly target of the repository"
+
+// ECRRepositoryDerivable reports whether the region and repository name of an ECR spec
+// repository can be derived from the image reference at the first update target: the
+// target is written with the tag of the spec repository and holds a whole image reference
+// (imageTagOnly) in a YAML or JSON file.
+func ECRRepositoryDerivable(yukConfig *yukv1.YukConfig) bool {
+	spec := &yukConfig.Spec
+	if len(spec.UpdateTargets) == 0 || (spec.Repository.Type == "" && len(spec.Sources) > 0) {
+		return false
+	}
+
+	target := spec.UpdateTargets[0]
+	if target.Source != "" || target.NestedYAMLPath != "" {
+		return false
+	}
+	switch target.Mode {
+	case yukv1.UpdateModeImageTag:
+	case "", yukv1.UpdateModeYAMLPath:
+		if !target.ImageTagOnly {
+			return false
+		}
+	default:
+		return false
+	}
+
+	format, err := yaml.FileFormat(target.File, target.Format)
+	return err == nil && (format == yaml.FormatYAML || format == yaml.FormatJSON)
+}
+
+// workloadKinds are the supported kinds of deployed workloads
+var workloadKinds = []string{yukv1.WorkloadKindDeployment, yukv1.WorkloadKindStatefulSet}
+
+// repositoryTypes are the supported repository types
+var repositoryTypes = []string{yukv1.RepositoryTypeECR, yukv1.RepositoryTypeOCI, yukv1.RepositoryTypeGHCR, yukv1.RepositoryTypeGAR, yukv1.RepositoryTypeACR, yukv1.RepositoryTypeQuay}
+
+// validateRepository checks that a repository configures its type and compiles its tag filter
+func validateRepository(repository *yukv1.RepositoryConfig, derivable bool, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	// Only the configuration of the repository type may be set
+	configured := map[string]bool{
+		yukv1.RepositoryTypeECR:  repository.ECR != nil,
+		yukv1.RepositoryTypeOCI:  repository.OCI != nil,
+		yukv1.RepositoryTypeGHCR: repository.GHCR != nil,
+		yukv1.RepositoryTypeGAR:  repository.GAR != nil,
+		yukv1.RepositoryTypeACR:  repository.ACR != nil,
+		yukv1.RepositoryTypeQuay: repository.Quay != nil,
+	}
+	if contains(repositoryTypes, repository.Type) {
+		for _, repositoryType := range repositoryTypes {
+			if repositoryType != repository.Type && configured[repositoryType] {
+				allErrs = append(allErrs, field.Forbidden(path.Child(repositoryType), fmt.Sprintf("must not be set when type is %q", repository.Type)))
+			}
+		}
+		if !configured[repository.Type] {
+			return append(allErrs, field.Required(path.Child(repository.Type), fmt.Sprintf("required when type is %q", repository.Type)))
+		}
+	}
+
+	switch repository.Type {
+	case yukv1.RepositoryTypeECR:
+		ecrPath := path.Child("ecr")
+		if repository.ECR.Region == "" && !derivable {
+			allErrs = append(allErrs, field.Required(ecrPath.Child("region"), underivableECRRepository))
+		}
+		if repository.ECR.RepositoryName == "" && !derivable {
+			allErrs = append(allErrs, field.Required(ecrPath.Child("repositoryName"), underivableECRRepository))
+		}
+		if repository.ECR.RegistryID != "" && !registryIDPattern.MatchString(repository.ECR.RegistryID) {
+			allErrs = append(allErrs, field.Invalid(ecrPath.Child("registryID"), repository.ECR.RegistryID, "must be a 12-digit AWS account ID"))
+		}
+		allErrs = append(allErrs, validatePattern(repository.ECR.TagFilter, ecrPath.Child("tagFilter"))...)
+		for i, pattern := range repository.ECR.ExcludeTags {
+			allErrs = append(allErrs, validatePattern(pattern, ecrPath.Child("excludeTags").Index(i))...)
+		}
+		allErrs = append(allErrs, validateTagPriority(repository.ECR, ecrPath)...)
+
+	case yukv1.RepositoryTypeOCI:
+		ociPath := path.Child("oci")
+		if repository.OCI.Registry == "" {
+			allErrs = append(allErrs, field.Required(ociPath.Child("registry"), ""))
+		}
+		if repository.OCI.RepositoryName == "" {
+			allErrs = append(allErrs, field.Required(ociPath.Child("repositoryName"), ""))
+		}
+		allErrs = append(allErrs, validatePattern(repository.OCI.TagFilter, ociPath.Child("tagFilter"))...)
+
+	case yukv1.RepositoryTypeGHCR:
+		ghcrPath := path.Child("ghcr")
+		if repository.GHCR.Owner == "" {
+			allErrs = append(allErrs, field.Required(ghcrPath.Child("owner"), ""))
+		}
+		if repository.GHCR.Image == "" {
+			allErrs = append(allErrs, field.Required(ghcrPath.Child("image"), ""))
+		}
+		allErrs = append(allErrs, validatePattern(repository.GHCR.TagFilter, ghcrPath.Child("tagFilter"))...)
+
+	case yukv1.RepositoryTypeGAR:
+		garPath := path.Child("gar")
+		if repository.GAR.Project == "" {
+			allErrs = append(allErrs, field.Required(garPath.Child("project"), ""))
+		}
+		if repository.GAR.Location == "" {
+			allErrs = append(allErrs, field.Required(garPath.Child("location"), ""))
+		}
+		if repository.GAR.Repository == "" {
+			allErrs = append(allErrs, field.Required(garPath.Child("repository"), ""))
+		}
+		if repository.GAR.Image == "" {
+			allErrs = append(allErrs, field.Required(garPath.Child("image"), ""))
+		}
+		allErrs = append(allErrs, validatePattern(repository.GAR.TagFilter, garPath.Child("tagFilter"))...)
+
+	case yukv1.RepositoryTypeACR:
+		acrPath := path.Child("acr")
+		if repository.ACR.Registry == "" {
+			allErrs = append(allErrs, field.Required(acrPath.Child("registry"), ""))
+		}
+		if repository.ACR.Image == "" {
+			allErrs = append(allErrs, field.Required(acrPath.Child("image"), ""))
+		}
+		if repository.ACR.Auth.ClientSecretRef != nil && repository.ACR.Auth.ClientID == "" {
+			allErrs = append(allErrs, field.Required(acrPath.Child("auth", "clientID"), "required with clientSecretRef"))
+		}
+		allErrs = append(allErrs, validatePattern(repository.ACR.TagFilter, acrPath.Child("tagFilter"))...)
+
+	case yukv1.RepositoryTypeQuay:
+		quayPath := path.Child("quay")
+		if repository.Quay.Namespace == "" {
+			allErrs = append(allErrs, field.Required(quayPath.Child("namespace"), ""))
+		}
+		if repository.Quay.Repository == "" {
+			allErrs = append(allErrs, field.Required(quayPath.Child("repository"), ""))
+		}
+		if repository.Quay.Auth.PasswordRef != nil && repository.Quay.Auth.Username == "" {
+			allErrs = append(allErrs, field.Required(quayPath.Child("auth", "username"), "required with passwordRef"))
+		}
+		allErrs = append(allErrs, validatePattern(repository.Quay.TagFilter, quayPath.Child("tagFilter"))...)
+
+	case "":
+		allErrs = append(allErrs, field.Required(path.Child("type"), ""))
+
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("type"), repository.Type, repositoryTypes))
+	}
+
+	return allErrs
+}
+
+// validateUpdateTarget checks the file, mode and paths of an update target
+func validateUpdateTarget(target yukv1.UpdateTarget, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	updater := yaml.NewUpdater(yaml.WithPathSyntax(target.PathSyntax))
+
+	if target.File == "" {
+		allErrs = append(allErrs, field.Required(path.Child("file"), ""))
+	} else if _, err := filepath.Match(target.File, ""); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("file"), target.File, "invalid file pattern"))
+	}
+
+	if target.Format != "" && !slices.Contains(yaml.Formats(), target.Format) {
+		allErrs = append(allErrs, field.NotSupported(path.Child("format"), target.Format, yaml.Formats()))
+	}
+
+	switch target.PathSyntax {
+	case "", yaml.PathSyntaxDotted, yaml.PathSyntaxJSONPath:
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("pathSyntax"), target.PathSyntax, []string{
+			yaml.PathSyntaxDotted, yaml.PathSyntaxJSONPath,
+		}))
+		updater = yaml.NewUpdater()
+	}
+
+	switch target.Mode {
+	case "", yukv1.UpdateModeYAMLPath, yukv1.UpdateModeImageTag, yukv1.UpdateModeLiteral, yukv1.UpdateModeHelmImage:
+		if err := updater.ValidateYAMLPath(target.YAMLPath); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("yamlPath"), target.YAMLPath, err.Error()))
+		}
+		if target.Mode == yukv1.UpdateModeLiteral && target.ImageTagOnly {
+			allErrs = append(allErrs, field.Invalid(path.Child("imageTagOnly"), target.ImageTagOnly, "cannot be set in literal mode"))
+		}
+	case yukv1.UpdateModeArgoApplication, yukv1.UpdateModeKustomizeImage:
+		if target.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("name"), fmt.Sprintf("required in %s mode", target.Mode)))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("mode"), target.Mode, []string{
+			yukv1.UpdateModeYAMLPath, yukv1.UpdateModeImageTag, yukv1.UpdateModeLiteral,
+			yukv1.UpdateModeArgoApplication, yukv1.UpdateModeHelmImage, yukv1.UpdateModeKustomizeImage,
+		}))
+	}
+
+	if target.NestedYAMLPath != "" {
+		if err := updater.ValidateYAMLPath(target.NestedYAMLPath); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("nestedYAMLPath"), target.NestedYAMLPath, err.Error()))
+		}
+	}
+
+	allErrs = append(allErrs, validatePattern(target.TagFilter, path.Child("tagFilter"))...)
+	allErrs = append(allErrs, validatePattern(target.ExpectedValuePattern, path.Child("expectedValuePattern"))...)
+	allErrs = append(allErrs, validateTemplate(target.CommitMessage, path.Child("commitMessage"))...)
+
+	for i, value := range target.Values {
+		valuePath := path.Child("values").Index(i)
+		if err := updater.ValidateYAMLPath(value.YAMLPath); err != nil {
+			allErrs = append(allErrs, field.Invalid(valuePath.Child("yamlPath"), value.YAMLPath, err.Error()))
+		}
+		if value.Value == "" {
+			allErrs = append(allErrs, field.Required(valuePath.Child("value"), ""))
+		}
+		allErrs = append(allErrs, validateTemplate(value.Value, valuePath.Child("value"))...)
+	}
+
+	return allErrs
+}
+
+// sortStrategies are the supported sort strategies by repository type
+var sortStrategies = map[string][]tagsort.Strategy{
+	yukv1.RepositoryTypeECR:  {tagsort.Lexical, tagsort.Semver, tagsort.PushTime},
+	yukv1.RepositoryTypeOCI:  {tagsort.Lexical, tagsort.Semver},
+	yukv1.RepositoryTypeGHCR: {tagsort.Lexical, tagsort.Semver},
+	yukv1.RepositoryTypeGAR:  {tagsort.Lexical, tagsort.Semver, tagsort.PushTime},
+	yukv1.RepositoryTypeACR:  {tagsort.Lexical, tagsort.Semver, tagsort.PushTime},
+	yukv1.RepositoryTypeQuay: {tagsort.Lexical, tagsort.Semver, tagsort.PushTime},
+}
+
+// validateTargetSortStrategy checks that the sort strategy override of a target is
+// supported by the repository type of its source
+func validateTargetSortStrategy(yukConfig *yukv1.YukConfig, target yukv1.UpdateTarget, path *field.Path) field.ErrorList {
+	if target.SortStrategy == "" {
+		return nil
+	}
+
+	repositoryType := yukConfig.Spec.Repository.Type
+	for _, source := range yukConfig.Spec.Sources {
+		if target.Source != "" && source.Name == target.Source {
+			repositoryType = source.Type
+		}
+	}
+	supported, ok := sortStrategies[repositoryType]
+	if !ok {
+		// The repository or source is reported as invalid already
+		return nil
+	}
+
+	if err := tagsort.Validate(tagsort.Strategy(target.SortStrategy), supported...); err != nil {
+		values := make([]string, len(supported))
+		for i, strategy := range supported {
+			values[i] = string(strategy)
+		}
+		return field.ErrorList{field.NotSupported(path, target.SortStrategy, values)}
+	}
+	return nil
+}
+
+// signatureRepositoryTypes are the repository types whose image signatures can be verified
+var signatureRepositoryTypes = []string{yukv1.RepositoryTypeECR, yukv1.RepositoryTypeOCI}
+
+// validateSignatureVerification checks that the public key is set and that the images
+// of every source can be verified
+func validateSignatureVerification(yukConfig *yukv1.YukConfig, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	keyPath := path.Child("publicKeyRef")
+	if yukConfig.Spec.VerifySignature.PublicKeyRef.Name == "" {
+		allErrs = append(allErrs, field.Required(keyPath.Child("name"), ""))
+	}
+	if yukConfig.Spec.VerifySignature.PublicKeyRef.Key == "" {
+		allErrs = append(allErrs, field.Required(keyPath.Child("key"), ""))
+	}
+
+	repository := yukConfig.Spec.Repository
+	if repository.Type != "" && !contains(signatureRepositoryTypes, repository.Type) {
+		allErrs = append(allErrs, field.Forbidden(path, fmt.Sprintf("not supported for repository type %q", repository.Type)))
+	}
+	for _, source := range yukConfig.Spec.Sources {
+		if source.Type != "" && !contains(signatureRepositoryTypes, source.Type) {
+			allErrs = append(allErrs, field.Forbidden(path, fmt.Sprintf("not supported for repository type %q of source %s", source.Type, source.Name)))
+		}
+	}
+	return allErrs
+}
+
+// validateUpdateWindow checks the days, times of day and time zone of an update window
+func validateUpdateWindow(window *yukv1.UpdateWindow, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, day := range window.Days {
+		if _, err := updatewindow.ParseWeekday(day); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("days").Index(i), day, "must be a day of the week, e.g. Mon"))
+		}
+	}
+	if window.Start != "" {
+		if start, err := updatewindow.ParseTimeOfDay(window.Start); err != nil || start == 24*60 {
+			allErrs = append(allErrs, field.Invalid(path.Child("start"), window.Start, "must be HH:MM between 00:00 and 23:59"))
+		}
+	}
+	if window.End != "" {
+		if _, err := updatewindow.ParseTimeOfDay(window.End); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("end"), window.End, "must be HH:MM between 00:00 and 24:00"))
+		}
+	}
+	if window.TimeZone != "" {
+		if _, err := time.LoadLocation(window.TimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("timeZone"), window.TimeZone, "must be an IANA time zone, e.g. Europe/Berlin"))
+		}
+	}
+	return allErrs
+}
+
+// validateTagPriority checks that the prioritized tags of an ECR repository are unique
+// and not combined with another way of selecting tags
+func validateTagPriority(config *yukv1.ECRConfig, path *field.Path) field.ErrorList {
+	if len(config.TagPriority) == 0 {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	priorityPath := path.Child("tagPriority")
+	if config.SelectExpression != "" {
+		allErrs = append(allErrs, field.Forbidden(priorityPath, "cannot be combined with selectExpression"))
+	}
+	if config.VersionConstraint != "" {
+		allErrs = append(allErrs, field.Forbidden(priorityPath, "cannot be combined with versionConstraint"))
+	}
+
+	seen := make(map[string]bool, len(config.TagPriority))
+	for i, tag := range config.TagPriority {
+		switch {
+		case tag == "":
+			allErrs = append(allErrs, field.Required(priorityPath.Index(i), ""))
+		case seen[tag]:
+			allErrs = append(allErrs, field.Duplicate(priorityPath.Index(i), tag))
+		}
+		seen[tag] = true
+	}
+	return allErrs
+}
+
+// validatePattern checks that an optional regex pattern compiles
+func validatePattern(pattern string, path *field.Path) field.ErrorList {
+	if pattern == "" {
+		return nil
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return field.ErrorList{field.Invalid(path, pattern, err.Error())}
+	}
+	return nil
+}
+
+// validateTemplate checks that an optional Go template parses
+func validateTemplate(text string, path *field.Path) field.ErrorList {
+	if text == "" {
+		return nil
+	}
+	if _, err := template.New(path.String()).Parse(text); err != nil {
+		return field.ErrorList{field.Invalid(path, text, err.Error())}
+	}
+	return nil
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
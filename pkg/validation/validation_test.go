@@ -0,0 +1,469 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	yukv1 "github.com/rebelopsio/yuk/apis/yuk/v1"
+)
+
+func validYukConfig() *yukv1.YukConfig {
+	return &yukv1.YukConfig{
+		Spec: yukv1.YukConfigSpec{
+			Repository: yukv1.RepositoryConfig{
+				Type: yukv1.RepositoryTypeECR,
+				ECR: &yukv1.ECRConfig{
+					Region:         "us-east-1",
+					RepositoryName: "my-app",
+					TagFilter:      `^v\d+\.\d+\.\d+$`,
+				},
+			},
+			Git: yukv1.GitConfig{Repository: "https://github.com/example/manifests.git"},
+			UpdateTargets: []yukv1.UpdateTarget{
+				{File: "deployment.yaml", YAMLPath: "spec.template.spec.containers[0].image", ImageTagOnly: true},
+			},
+		},
+	}
+}
+
+func TestValidateYukConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(yukConfig *yukv1.YukConfig)
+		expected []string
+	}{
+		{
+			name:   "valid",
+			modify: func(yukConfig *yukv1.YukConfig) {},
+		},
+		{
+			name: "valid with sources only",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Sources = []yukv1.ImageSource{{
+					Name: "frontend",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: yukv1.RepositoryTypeOCI,
+						OCI:  &yukv1.OCIConfig{Registry: "harbor.example.com", RepositoryName: "project/frontend"},
+					},
+				}}
+				yukConfig.Spec.Repository = yukv1.RepositoryConfig{}
+				yukConfig.Spec.UpdateTargets = append(yukConfig.Spec.UpdateTargets,
+					yukv1.UpdateTarget{File: "values.yaml", Mode: yukv1.UpdateModeHelmImage, YAMLPath: "frontend.image", Source: "frontend"},
+					yukv1.UpdateTarget{File: "application.yaml", Mode: yukv1.UpdateModeArgoApplication, Name: "image.tag"},
+					yukv1.UpdateTarget{File: "kustomization.yaml", Mode: yukv1.UpdateModeKustomizeImage, Name: "my-app"},
+				)
+			},
+		},
+		{
+			name: "missing repository type",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository = yukv1.RepositoryConfig{}
+			},
+			expected: []string{"spec.repository.type: Required value"},
+		},
+		{
+			name: "unsupported repository type",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository.Type = "gcr"
+			},
+			expected: []string{`spec.repository.type: Unsupported value: "gcr"`},
+		},
+		{
+			name: "type without its configuration",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository.Type = yukv1.RepositoryTypeOCI
+			},
+			expected: []string{
+				"spec.repository.ecr: Forbidden",
+				"spec.repository.oci: Required value",
+			},
+		},
+		{
+			name: "valid GHCR repository",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository = yukv1.RepositoryConfig{
+					Type: yukv1.RepositoryTypeGHCR,
+					GHCR: &yukv1.GHCRConfig{Owner: "rebelopsio", Image: "yuk", TagFilter: `^v`},
+				}
+			},
+		},
+		{
+			name: "missing GHCR fields",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository.Type = yukv1.RepositoryTypeGHCR
+				yukConfig.Spec.Repository.ECR = nil
+				yukConfig.Spec.Repository.GHCR = &yukv1.GHCRConfig{TagFilter: "(v"}
+			},
+			expected: []string{
+				"spec.repository.ghcr.owner: Required value",
+				"spec.repository.ghcr.image: Required value",
+				`spec.repository.ghcr.tagFilter: Invalid value: "(v"`,
+			},
+		},
+		{
+			name: "valid GAR repository",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository = yukv1.RepositoryConfig{
+					Type: yukv1.RepositoryTypeGAR,
+					GAR: &yukv1.GARConfig{
+						Project:    "my-project",
+						Location:   "us-central1",
+						Repository: "my-repo",
+						Image:      "my-app",
+					},
+				}
+			},
+		},
+		{
+			name: "missing GAR fields",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository.Type = yukv1.RepositoryTypeGAR
+				yukConfig.Spec.Repository.ECR = nil
+				yukConfig.Spec.Repository.GAR = &yukv1.GARConfig{Project: "my-project"}
+			},
+			expected: []string{
+				"spec.repository.gar.location: Required value",
+				"spec.repository.gar.repository: Required value",
+				"spec.repository.gar.image: Required value",
+			},
+		},
+		{
+			name: "valid ACR repository",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository = yukv1.RepositoryConfig{
+					Type: yukv1.RepositoryTypeACR,
+					ACR:  &yukv1.ACRConfig{Registry: "myregistry", Image: "team/my-app", SortStrategy: "pushtime"},
+				}
+			},
+		},
+		{
+			name: "missing ACR fields",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository.Type = yukv1.RepositoryTypeACR
+				yukConfig.Spec.Repository.ECR = nil
+				yukConfig.Spec.Repository.ACR = &yukv1.ACRConfig{
+					Auth: yukv1.ACRAuthConfig{ClientSecretRef: &yukv1.SecretKeySelector{Name: "acr", Key: "secret"}},
+				}
+			},
+			expected: []string{
+				"spec.repository.acr.registry: Required value",
+				"spec.repository.acr.image: Required value",
+				"spec.repository.acr.auth.clientID: Required value",
+			},
+		},
+		{
+			name: "valid Quay repository",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository = yukv1.RepositoryConfig{
+					Type: yukv1.RepositoryTypeQuay,
+					Quay: &yukv1.QuayConfig{Namespace: "rebelops", Repository: "my-app", SortStrategy: "pushtime"},
+				}
+			},
+		},
+		{
+			name: "missing Quay fields",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository.Type = yukv1.RepositoryTypeQuay
+				yukConfig.Spec.Repository.ECR = nil
+				yukConfig.Spec.Repository.Quay = &yukv1.QuayConfig{
+					TagFilter: "[",
+					Auth:      yukv1.QuayAuthConfig{PasswordRef: &yukv1.SecretKeySelector{Name: "quay", Key: "token"}},
+				}
+			},
+			expected: []string{
+				"spec.repository.quay.namespace: Required value",
+				"spec.repository.quay.repository: Required value",
+				"spec.repository.quay.auth.username: Required value",
+				"spec.repository.quay.tagFilter: Invalid value",
+			},
+		},
+		{
+			name: "invalid max consecutive failures",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				limit := int32(0)
+				yukConfig.Spec.MaxConsecutiveFailures = &limit
+			},
+			expected: []string{"spec.maxConsecutiveFailures: Invalid value: 0: must be at least 1"},
+		},
+		{
+			name: "valid update window",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.UpdateWindow = &yukv1.UpdateWindow{
+					Days:     []string{"mon", "Tuesday"},
+					Start:    "22:00",
+					End:      "24:00",
+					TimeZone: "Europe/Berlin",
+				}
+			},
+		},
+		{
+			name: "invalid update window",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.UpdateWindow = &yukv1.UpdateWindow{
+					Days:     []string{"Mon", "Funday"},
+					Start:    "24:00",
+					End:      "9:00",
+					TimeZone: "Mars/Olympus",
+				}
+			},
+			expected: []string{
+				`spec.updateWindow.days[1]: Invalid value: "Funday"`,
+				`spec.updateWindow.start: Invalid value: "24:00"`,
+				`spec.updateWindow.end: Invalid value: "9:00"`,
+				`spec.updateWindow.timeZone: Invalid value: "Mars/Olympus"`,
+			},
+		},
+		{
+			name: "missing ECR fields",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository.ECR = &yukv1.ECRConfig{}
+				yukConfig.Spec.UpdateTargets[0] = yukv1.UpdateTarget{File: "values.yaml", YAMLPath: "image.tag"}
+			},
+			expected: []string{
+				"spec.repository.ecr.region: Required value",
+				"spec.repository.ecr.repositoryName: Required value",
+			},
+		},
+		{
+			name: "ECR fields derived from the image reference",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository.ECR = &yukv1.ECRConfig{TagFilter: `^v`}
+				yukConfig.Spec.UpdateTargets = append(yukConfig.Spec.UpdateTargets,
+					yukv1.UpdateTarget{File: "values.yaml", YAMLPath: "image.tag"})
+			},
+		},
+		{
+			name: "invalid ECR registry ID",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository.ECR.RegistryID = "my-account"
+			},
+			expected: []string{`spec.repository.ecr.registryID: Invalid value: "my-account"`},
+		},
+		{
+			name: "ECR fields not derivable from a later target",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository.ECR = &yukv1.ECRConfig{Region: "us-east-1"}
+				yukConfig.Spec.UpdateTargets = append([]yukv1.UpdateTarget{{File: "values.yaml", YAMLPath: "image.tag"}},
+					yukConfig.Spec.UpdateTargets...)
+			},
+			expected: []string{"spec.repository.ecr.repositoryName: Required value"},
+		},
+		{
+			name: "ECR source fields not derivable",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Sources = []yukv1.ImageSource{{
+					Name: "worker",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: yukv1.RepositoryTypeECR,
+						ECR:  &yukv1.ECRConfig{},
+					},
+				}}
+				yukConfig.Spec.UpdateTargets[0].Source = "worker"
+			},
+			expected: []string{
+				"spec.sources[0].ecr.region: Required value",
+				"spec.sources[0].ecr.repositoryName: Required value",
+			},
+		},
+		{
+			name: "invalid tag filters",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository.ECR.TagFilter = "v[0-9"
+				yukConfig.Spec.Repository.ECR.ExcludeTags = []string{"latest", "(rc"}
+				yukConfig.Spec.UpdateTargets[0].TagFilter = "(beta"
+				yukConfig.Spec.UpdateTargets[0].ExpectedValuePattern = "*"
+			},
+			expected: []string{
+				`spec.repository.ecr.tagFilter: Invalid value: "v[0-9"`,
+				`spec.repository.ecr.excludeTags[1]: Invalid value: "(rc"`,
+				`spec.updateTargets[0].tagFilter: Invalid value: "(beta"`,
+				`spec.updateTargets[0].expectedValuePattern: Invalid value: "*"`,
+			},
+		},
+		{
+			name: "signature verification of unsupported repository",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.VerifySignature = &yukv1.SignatureVerificationConfig{
+					PublicKeyRef: yukv1.SecretKeySelector{Name: "cosign"},
+				}
+				yukConfig.Spec.Sources = []yukv1.ImageSource{{
+					Name:             "sidecar",
+					RepositoryConfig: yukv1.RepositoryConfig{Type: yukv1.RepositoryTypeGHCR, GHCR: &yukv1.GHCRConfig{Owner: "acme", Image: "sidecar"}},
+				}}
+			},
+			expected: []string{
+				"spec.verifySignature.publicKeyRef.key: Required value",
+				`spec.verifySignature: Forbidden: not supported for repository type "ghcr" of source sidecar`,
+			},
+		},
+		{
+			name: "invalid tag priority",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Repository.ECR.TagPriority = []string{"stable", "", "stable"}
+				yukConfig.Spec.Repository.ECR.VersionConstraint = ">=1.0.0"
+			},
+			expected: []string{
+				"spec.repository.ecr.tagPriority: Forbidden: cannot be combined with versionConstraint",
+				"spec.repository.ecr.tagPriority[1]: Required value",
+				`spec.repository.ecr.tagPriority[2]: Duplicate value: "stable"`,
+			},
+		},
+		{
+			name: "invalid update targets",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.UpdateTargets = []yukv1.UpdateTarget{
+					{YAMLPath: "spec..image"},
+					{File: "values.yaml", Mode: yukv1.UpdateModeHelmImage},
+					{File: "application.yaml", Mode: yukv1.UpdateModeArgoApplication},
+					{File: "values.yaml", Mode: "jsonPatch"},
+					{File: "configmap.yaml", YAMLPath: `data["config.yaml"]`, NestedYAMLPath: "image tag"},
+					{File: "Chart.yaml", Mode: yukv1.UpdateModeLiteral, YAMLPath: "version", ImageTagOnly: true},
+					{File: "deployment.yaml", PathSyntax: "xpath", YAMLPath: "spec.replicas"},
+					{File: "kustomization.yaml", Mode: yukv1.UpdateModeKustomizeImage},
+					{File: "deployment.yaml", PathSyntax: "jsonpath", YAMLPath: "$.spec.containers[?(@.name~='app')]"},
+					{File: "deployment.yaml", PathSyntax: "jsonpath", YAMLPath: `$.spec.containers[?(@.name=="app")].image`},
+					{File: "overlays/[a-/deployment.yaml", YAMLPath: "image.tag"},
+					{File: "config.xml", Format: "xml", YAMLPath: "image.tag"},
+					{File: "app.toml", Format: "toml", YAMLPath: "image.tag"},
+					{File: "deployment.yaml", YAMLPath: "spec.template.spec.containers[0].image", Values: []yukv1.TargetValue{
+						{YAMLPath: `metadata.annotations["example.com/image-digest"]`, Value: "{{ .Digest }}"},
+						{YAMLPath: "metadata..annotations", Value: "{{ .PushedAt"},
+						{YAMLPath: "metadata.labels.version"},
+					}},
+				}
+			},
+			expected: []string{
+				"spec.updateTargets[0].file: Required value",
+				`spec.updateTargets[0].yamlPath: Invalid value: "spec..image"`,
+				`spec.updateTargets[1].yamlPath: Invalid value: ""`,
+				"spec.updateTargets[2].name: Required value",
+				`spec.updateTargets[3].mode: Unsupported value: "jsonPatch"`,
+				`spec.updateTargets[4].nestedYAMLPath: Invalid value: "image tag"`,
+				"spec.updateTargets[5].imageTagOnly: Invalid value: true",
+				`spec.updateTargets[6].pathSyntax: Unsupported value: "xpath"`,
+				"spec.updateTargets[7].name: Required value",
+				`spec.updateTargets[8].yamlPath: Invalid value: "$.spec.containers[?(@.name~='app')]"`,
+				`spec.updateTargets[10].file: Invalid value: "overlays/[a-/deployment.yaml": invalid file pattern`,
+				`spec.updateTargets[11].format: Unsupported value: "xml": supported values: "env", "json", "toml", "yaml"`,
+				`spec.updateTargets[13].values[1].yamlPath: Invalid value: "metadata..annotations"`,
+				`spec.updateTargets[13].values[1].value: Invalid value: "{{ .PushedAt"`,
+				"spec.updateTargets[13].values[2].value: Required value",
+			},
+		},
+		{
+			name: "invalid commit message templates",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Git.CommitMessage = "Update to {{ .NewTag"
+				yukConfig.Spec.UpdateTargets[0].CommitMessage = "{{ if .OldTag }}Bump"
+			},
+			expected: []string{
+				`spec.git.commitMessage: Invalid value: "Update to {{ .NewTag"`,
+				`spec.updateTargets[0].commitMessage: Invalid value: "{{ if .OldTag }}Bump"`,
+			},
+		},
+		{
+			name: "no update targets",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.UpdateTargets = nil
+			},
+			expected: []string{"spec.updateTargets: Required value"},
+		},
+		{
+			name: "missing git repository and unknown strategy",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Git.Repository = ""
+				yukConfig.Spec.UpdateStrategy = "yolo"
+			},
+			expected: []string{
+				"spec.git.repository: Required value",
+				`spec.updateStrategy: Unsupported value: "yolo"`,
+			},
+		},
+		{
+			name: "history limit too large",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				limit := int32(100)
+				yukConfig.Spec.HistoryLimit = &limit
+			},
+			expected: []string{"spec.historyLimit: Invalid value: 100: must be between 0 and 50"},
+		},
+		{
+			name: "invalid deployed workload",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.DeployedWorkload = &yukv1.WorkloadReference{Kind: "DaemonSet"}
+			},
+			expected: []string{
+				`spec.deployedWorkload.kind: Unsupported value: "DaemonSet": supported values: "Deployment", "StatefulSet"`,
+				"spec.deployedWorkload.name: Required value",
+			},
+		},
+		{
+			name: "invalid sources",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				source := yukv1.RepositoryConfig{Type: yukv1.RepositoryTypeECR, ECR: &yukv1.ECRConfig{Region: "us-east-1", RepositoryName: "backend"}}
+				yukConfig.Spec.Sources = []yukv1.ImageSource{
+					{Name: "backend", RepositoryConfig: source},
+					{Name: "backend", RepositoryConfig: source},
+					{RepositoryConfig: source},
+				}
+				yukConfig.Spec.UpdateTargets[0].Source = "frontend"
+			},
+			expected: []string{
+				`spec.sources[1].name: Duplicate value: "backend"`,
+				"spec.sources[2].name: Required value",
+				`spec.updateTargets[0].source: Not found: "frontend"`,
+			},
+		},
+		{
+			name: "target sort strategies",
+			modify: func(yukConfig *yukv1.YukConfig) {
+				yukConfig.Spec.Sources = []yukv1.ImageSource{{
+					Name: "frontend",
+					RepositoryConfig: yukv1.RepositoryConfig{
+						Type: yukv1.RepositoryTypeOCI,
+						OCI:  &yukv1.OCIConfig{Registry: "harbor.example.com", RepositoryName: "project/frontend"},
+					},
+				}}
+				yukConfig.Spec.UpdateTargets[0].SortStrategy = "pushtime"
+				yukConfig.Spec.UpdateTargets = append(yukConfig.Spec.UpdateTargets,
+					yukv1.UpdateTarget{File: "values.yaml", YAMLPath: "frontend.tag", Source: "frontend", SortStrategy: "semver"},
+					yukv1.UpdateTarget{File: "values.yaml", YAMLPath: "frontend.date", Source: "frontend", SortStrategy: "pushtime"},
+				)
+			},
+			expected: []string{`spec.updateTargets[2].sortStrategy: Unsupported value: "pushtime"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yukConfig := validYukConfig()
+			tt.modify(yukConfig)
+
+			errs := ValidateYukConfig(yukConfig)
+			if len(errs) != len(tt.expected) {
+				t.Fatalf("Expected %d errors, got %d: %v", len(tt.expected), len(errs), errs)
+			}
+			for i, expected := range tt.expected {
+				if !strings.HasPrefix(errs[i].Error(), expected) {
+					t.Errorf("Expected error %d to start with %q, got %q", i, expected, errs[i].Error())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package verify verifies the cosign signatures of container images before Yuk promotes
+// them.
+package verify
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// SimpleSigningMediaType is the media type of the layers of a cosign signature
+	SimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+
+	// SignatureAnnotation holds the base64 signature of a signature layer's payload
+	SignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+	// signatureType is the type of the simple signing payloads of image signatures
+	signatureType = "cosign container image signature"
+)
+
+// ErrVerificationFailed means an image has no signature made with the public key
+var ErrVerificationFailed = errors.New("signature verification failed")
+
+// Fetcher reads manifests and blobs from the repository of an image. It is implemented by
+// the registry clients supporting signature verification.
+type Fetcher interface {
+	// GetManifest returns the manifest of the tag or digest
+	GetManifest(ctx context.Context, repositoryName, reference string) ([]byte, error)
+
+	// GetBlob returns the content of the blob with the digest
+	GetBlob(ctx context.Context, repositoryName, digest string) ([]byte, error)
+}
+
+// CosignVerifier verifies the cosign signatures of images against a public key. The
+// signatures are read from the "sha256-<digest>.sig" tag cosign pushes next to the image.
+type CosignVerifier struct {
+	publicKey crypto.PublicKey
+	fetcher   Fetcher
+}
+
+// NewCosignVerifier creates a verifier for the PEM-encoded public key (e.g. cosign.pub),
+// reading signatures through the fetcher. ECDSA, RSA and Ed25519 keys are supported.
+func NewCosignVerifier(publicKeyPEM []byte, fetcher Fetcher) (*CosignVerifier, error) {
+	block, _ := pem.Decode(publicKeyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key: no PEM data found")
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	switch publicKey.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+
+	return &CosignVerifier{publicKey: publicKey, fetcher: fetcher}, nil
+}
+
+// manifest is the part of a signature manifest holding its layers
+type manifest struct {
+	Layers []descriptor `json:"layers"`
+}
+
+// descriptor is a layer of a signature manifest
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// payload is the simple signing payload of an image signature
+type payload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// Verify checks that the image with the digest (e.g. "sha256:...") has at least one
+// signature made with the public key whose payload names that digest. Errors wrap
+// ErrVerificationFailed when the image is not signed with the key.
+func (v *CosignVerifier) Verify(ctx context.Context, repositoryName, digest string) error {
+	tag, err := SignatureTag(digest)
+	if err != nil {
+		return err
+	}
+
+	data, err := v.fetcher.GetManifest(ctx, repositoryName, tag)
+	if err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("%w: no signature found for %s@%s", ErrVerificationFailed, repositoryName, digest)
+		}
+		return fmt.Errorf("failed to get signature manifest of %s@%s: %w", repositoryName, digest, err)
+	}
+
+	var signatures manifest
+	if err := json.Unmarshal(data, &signatures); err != nil {
+		return fmt.Errorf("invalid signature manifest of %s@%s: %w", repositoryName, digest, err)
+	}
+
+	var reasons []string
+	for _, layer := range signatures.Layers {
+		if layer.MediaType != SimpleSigningMediaType {
+			continue
+		}
+
+		err := v.verifyLayer(ctx, repositoryName, digest, layer)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrVerificationFailed) {
+			return err
+		}
+		reasons = append(reasons, strings.TrimPrefix(err.Error(), ErrVerificationFailed.Error()+": "))
+	}
+
+	if len(reasons) == 0 {
+		return fmt.Errorf("%w: no signature found for %s@%s", ErrVerificationFailed, repositoryName, digest)
+	}
+	return fmt.Errorf("%w: no valid signature for %s@%s: %s", ErrVerificationFailed, repositoryName, digest, strings.Join(reasons, "; "))
+}
+
+// verifyLayer verifies one signature of the image
+func (v *CosignVerifier) verifyLayer(ctx context.Context, repositoryName, digest string, layer descriptor) error {
+	signature, err := base64.StdEncoding.DecodeString(layer.Annotations[SignatureAnnotation])
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("%w: signature %s has no valid signature annotation", ErrVerificationFailed, layer.Digest)
+	}
+
+	data, err := v.fetcher.GetBlob(ctx, repositoryName, layer.Digest)
+	if err != nil {
+		return fmt.Errorf("failed to get signature payload %s: %w", layer.Digest, err)
+	}
+
+	// The registry is not trusted to serve the payload the manifest refers to
+	sum := sha256.Sum256(data)
+	if layer.Digest != "sha256:"+hex.EncodeToString(sum[:]) {
+		return fmt.Errorf("%w: payload of signature %s does not match its digest", ErrVerificationFailed, layer.Digest)
+	}
+
+	if !v.verifySignature(data, sum[:], signature) {
+		return fmt.Errorf("%w: signature %s was not made with the public key", ErrVerificationFailed, layer.Digest)
+	}
+
+	var signed payload
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return fmt.Errorf("%w: invalid payload of signature %s: %v", ErrVerificationFailed, layer.Digest, err)
+	}
+	if signed.Critical.Type != signatureType || signed.Critical.Image.DockerManifestDigest != digest {
+		return fmt.Errorf("%w: signature %s was made for image %s", ErrVerificationFailed, layer.Digest, signed.Critical.Image.DockerManifestDigest)
+	}
+
+	return nil
+}
+
+// verifySignature reports whether the signature of the payload was made with the public
+// key. ECDSA and RSA signatures are made over the SHA-256 hash of the payload, Ed25519
+// signatures over the payload itself.
+func (v *CosignVerifier) verifySignature(data, hash, signature []byte) bool {
+	switch publicKey := v.publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(publicKey, hash, signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hash, signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(publicKey, data, signature)
+	default:
+		return false
+	}
+}
+
+// SignatureTag returns the tag cosign stores the signatures of the image with the digest
+// under, e.g. "sha256-<hex>.sig"
+func SignatureTag(digest string) (string, error) {
+	algorithm, hash, ok := strings.Cut(digest, ":")
+	if !ok || algorithm == "" || hash == "" {
+		return "", fmt.Errorf("invalid image digest %q", digest)
+	}
+	return algorithm + "-" + hash + ".sig", nil
+}
+
+// isNotFound reports whether the registry does not know the manifest
+func isNotFound(err error) bool {
+	var statusErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &statusErr) && statusErr.HTTPStatusCode() == http.StatusNotFound
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// statusError is a registry error with an HTTP status code
+type statusError int
+
+func (e statusError) Error() string       { return fmt.Sprintf("registry returned %d", int(e)) }
+func (e statusError) HTTPStatusCode() int { return int(e) }
+
+// fakeFetcher serves the manifests and blobs of a repository
+type fakeFetcher struct {
+	manifests map[string][]byte
+	blobs     map[string][]byte
+	err       error
+}
+
+func (f *fakeFetcher) GetManifest(ctx context.Context, repositoryName, reference string) ([]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	data, ok := f.manifests[repositoryName+":"+reference]
+	if !ok {
+		return nil, statusError(http.StatusNotFound)
+	}
+	return data, nil
+}
+
+func (f *fakeFetcher) GetBlob(ctx context.Context, repositoryName, digest string) ([]byte, error) {
+	data, ok := f.blobs[digest]
+	if !ok {
+		return nil, statusError(http.StatusNotFound)
+	}
+	return data, nil
+}
+
+// sign stores a cosign signature of the image made with the signer, for the image
+// digest named in the payload
+func (f *fakeFetcher) sign(t *testing.T, signer crypto.Signer, repositoryName, digest, payloadDigest string) {
+	t.Helper()
+
+	data := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"registry.example.com/%s"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`,
+		repositoryName, payloadDigest))
+	sum := sha256.Sum256(data)
+
+	var signature []byte
+	var err error
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		signature, err = signer.Sign(rand.Reader, data, crypto.Hash(0))
+	} else {
+		signature, err = signer.Sign(rand.Reader, sum[:], crypto.SHA256)
+	}
+	if err != nil {
+		t.Fatalf("Failed to sign payload: %v", err)
+	}
+
+	layerDigest := "sha256:" + hex.EncodeToString(sum[:])
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"layers": []map[string]interface{}{{
+			"mediaType":   SimpleSigningMediaType,
+			"digest":      layerDigest,
+			"size":        len(data),
+			"annotations": map[string]string{SignatureAnnotation: base64.StdEncoding.EncodeToString(signature)},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode manifest: %v", err)
+	}
+
+	tag, err := SignatureTag(digest)
+	if err != nil {
+		t.Fatalf("Failed to get signature tag: %v", err)
+	}
+	f.manifests[repositoryName+":"+tag] = manifest
+	f.blobs[layerDigest] = data
+}
+
+// publicKeyPEM encodes the public key of the signer as cosign does
+func publicKeyPEM(t *testing.T, signer crypto.Signer) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		t.Fatalf("Failed to encode public key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func newECDSAKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return key
+}
+
+func TestCosignVerifier_Verify(t *testing.T) {
+	key := newECDSAKey(t)
+	otherKey := newECDSAKey(t)
+
+	signed := "sha256:" + strings.Repeat("a", 64)
+	unsigned := "sha256:" + strings.Repeat("b", 64)
+	wrongKey := "sha256:" + strings.Repeat("c", 64)
+	wrongImage := "sha256:" + strings.Repeat("d", 64)
+
+	fetcher := &fakeFetcher{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+	fetcher.sign(t, key, "my-app", signed, signed)
+	fetcher.sign(t, otherKey, "my-app", wrongKey, wrongKey)
+	fetcher.sign(t, key, "my-app", wrongImage, signed)
+
+	verifier, err := NewCosignVerifier(publicKeyPEM(t, key), fetcher)
+	if err != nil {
+		t.Fatalf("NewCosignVerifier() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		digest  string
+		wantErr string
+	}{
+		{
+			name:   "signed image",
+			digest: signed,
+		},
+		{
+			name:    "unsigned image",
+			digest:  unsigned,
+			wantErr: "no signature found for my-app@" + unsigned,
+		},
+		{
+			name:    "signed with another key",
+			digest:  wrongKey,
+			wantErr: "was not made with the public key",
+		},
+		{
+			name:    "signature of another image",
+			digest:  wrongImage,
+			wantErr: "was made for image " + signed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifier.Verify(context.Background(), "my-app", tt.digest)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Verify() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrVerificationFailed) {
+				t.Fatalf("Expected ErrVerificationFailed, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCosignVerifier_Verify_TamperedPayload(t *testing.T) {
+	key := newECDSAKey(t)
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	fetcher := &fakeFetcher{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+	fetcher.sign(t, key, "my-app", digest, digest)
+	for layerDigest, data := range fetcher.blobs {
+		fetcher.blobs[layerDigest] = []byte(strings.Replace(string(data), "my-app", "evil-app", 1))
+	}
+
+	verifier, err := NewCosignVerifier(publicKeyPEM(t, key), fetcher)
+	if err != nil {
+		t.Fatalf("NewCosignVerifier() error = %v", err)
+	}
+
+	err = verifier.Verify(context.Background(), "my-app", digest)
+	if !errors.Is(err, ErrVerificationFailed) || !strings.Contains(err.Error(), "does not match its digest") {
+		t.Errorf("Expected a payload digest mismatch, got %v", err)
+	}
+}
+
+func TestCosignVerifier_Verify_Ed25519(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	fetcher := &fakeFetcher{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+	fetcher.sign(t, key, "my-app", digest, digest)
+
+	verifier, err := NewCosignVerifier(publicKeyPEM(t, key), fetcher)
+	if err != nil {
+		t.Fatalf("NewCosignVerifier() error = %v", err)
+	}
+	if err := verifier.Verify(context.Background(), "my-app", digest); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestCosignVerifier_Verify_RegistryError(t *testing.T) {
+	key := newECDSAKey(t)
+	fetcher := &fakeFetcher{err: statusError(http.StatusServiceUnavailable)}
+
+	verifier, err := NewCosignVerifier(publicKeyPEM(t, key), fetcher)
+	if err != nil {
+		t.Fatalf("NewCosignVerifier() error = %v", err)
+	}
+
+	// Registry failures are not verification failures, so they can be retried
+	err = verifier.Verify(context.Background(), "my-app", "sha256:"+strings.Repeat("a", 64))
+	if err == nil || errors.Is(err, ErrVerificationFailed) {
+		t.Errorf("Expected a registry error, got %v", err)
+	}
+}
+
+func TestNewCosignVerifier_InvalidKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  []byte
+	}{
+		{name: "not PEM", key: []byte("cosign.pub")},
+		{name: "not a public key", key: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("invalid")})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCosignVerifier(tt.key, &fakeFetcher{}); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
+
+func TestSignatureTag(t *testing.T) {
+	tag, err := SignatureTag("sha256:abc123")
+	if err != nil || tag != "sha256-abc123.sig" {
+		t.Errorf("SignatureTag() = %q, %v", tag, err)
+	}
+
+	if _, err := SignatureTag("abc123"); err == nil {
+		t.Error("Expected error for a digest without algorithm")
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package version holds the build information of the yuk binaries, injected at build
+// time with -ldflags, e.g.
+//
+//	go build -ldflags "-X github.com/rebelopsio/yuk/pkg/version.Version=v1.2.0
+//	  -X github.com/rebelopsio/yuk/pkg/version.Commit=$(git rev-parse HEAD)"
+package version
+
+import "runtime"
+
+var (
+	// Version is the released version of yuk (default: "dev")
+	Version = "dev"
+
+	// Commit is the Git commit yuk was built from (default: "unknown")
+	Commit = "unknown"
+)
+
+// GoVersion returns the version of Go yuk was built with
+func GoVersion() string {
+	return runtime.Version()
+}
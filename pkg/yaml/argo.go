@@ -0,0 +1,198 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UpdateArgoApplication updates the Helm parameter or Kustomize image with the given name
+// in the source of an Argo CD Application (spec.source, or each of spec.sources).
+//
+// Helm parameters are set to newValue, or have only their tag replaced when imageTagOnly
+// is set. Kustomize images (e.g. "nginx=registry/nginx:1.20") always have their tag replaced.
+// When newValue is a digest (e.g. "sha256:..."), the digest is replaced instead. It reports
+// whether any entry changed.
+func (u *Updater) UpdateArgoApplication(filePath, name, newValue string, imageTagOnly bool) (bool, error) {
+	if name == "" {
+		return false, fmt.Errorf("a name is required to update an Argo CD Application in file %s", filePath)
+	}
+
+	// Read the file
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	// Parse YAML
+	var yamlData interface{}
+	if err := yaml.Unmarshal(data, &yamlData); err != nil {
+		return false, fmt.Errorf("failed to parse YAML in file %s: %w", filePath, err)
+	}
+
+	// Serialize the document as parsed to tell whether the update changes it
+	before, err := yaml.Marshal(yamlData)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal YAML for file %s: %w", filePath, err)
+	}
+
+	sources, err := u.argoSources(yamlData)
+	if err != nil {
+		return false, fmt.Errorf("failed to read Argo CD Application in file %s: %w", filePath, err)
+	}
+
+	// Update every matching entry across all sources
+	updated := 0
+	for _, source := range sources {
+		updated += u.updateHelmParameters(source, name, newValue, imageTagOnly)
+		updated += u.updateKustomizeImages(source, name, newValue)
+	}
+
+	if updated == 0 {
+		return false, fmt.Errorf("no Helm parameter or Kustomize image named %s found in file %s: %w", name, filePath, ErrPathNotFound)
+	}
+
+	// Write back to file if the value changed, making sure the result still parses
+	return u.writeChanged(filePath, data, before, yamlData)
+}
+
+// argoSources returns the source maps of an Argo CD Application
+func (u *Updater) argoSources(data interface{}) ([]map[string]interface{}, error) {
+	spec, err := u.getValue(data, "spec")
+	if err != nil {
+		return nil, err
+	}
+
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("spec is not a map: %T", spec)
+	}
+
+	var sources []map[string]interface{}
+	if source, ok := specMap["source"].(map[string]interface{}); ok {
+		sources = append(sources, source)
+	}
+
+	if multiSources, ok := specMap["sources"].([]interface{}); ok {
+		for _, item := range multiSources {
+			if source, ok := item.(map[string]interface{}); ok {
+				sources = append(sources, source)
+			}
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("spec.source or spec.sources: %w", ErrPathNotFound)
+	}
+
+	return sources, nil
+}
+
+// updateHelmParameters updates the helm.parameters entries with the given name and
+// returns how many were updated
+func (u *Updater) updateHelmParameters(source map[string]interface{}, name, newValue string, imageTagOnly bool) int {
+	helm, ok := source["helm"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+
+	parameters, ok := helm["parameters"].([]interface{})
+	if !ok {
+		return 0
+	}
+
+	updated := 0
+	for _, item := range parameters {
+		parameter, ok := item.(map[string]interface{})
+		if !ok || parameter["name"] != name {
+			continue
+		}
+
+		if imageTagOnly {
+			currentValue, _ := parameter["value"].(string)
+			parameter["value"] = u.updateImageReference(currentValue, newValue)
+		} else {
+			parameter["value"] = newValue
+		}
+		updated++
+	}
+
+	return updated
+}
+
+// updateKustomizeImages replaces the tag of the kustomize.images entries with the given
+// name and returns how many were updated
+func (u *Updater) updateKustomizeImages(source map[string]interface{}, name, newTag string) int {
+	kustomize, ok := source["kustomize"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+
+	images, ok := kustomize["images"].([]interface{})
+	if !ok {
+		return 0
+	}
+
+	updated := 0
+	for i, item := range images {
+		image, ok := item.(string)
+		if !ok || kustomizeImageName(image) != name {
+			continue
+		}
+
+		images[i] = setKustomizeImageTag(image, newTag)
+		updated++
+	}
+
+	return updated
+}
+
+// kustomizeImageName returns the name an Argo CD Kustomize image override applies to.
+// Overrides have the form "name[=newName][:tag|@digest]".
+func kustomizeImageName(image string) string {
+	if name, _, found := strings.Cut(image, "="); found {
+		return name
+	}
+
+	return stripImageTag(image)
+}
+
+// setKustomizeImageTag replaces the tag or digest of a Kustomize image override with the
+// new tag, or pins it to the new value when that is a digest
+func setKustomizeImageTag(image, newTag string) string {
+	separator := ":"
+	if isDigest(newTag) {
+		separator = "@"
+	}
+
+	if name, newName, found := strings.Cut(image, "="); found {
+		return name + "=" + stripImageTag(newName) + separator + newTag
+	}
+
+	return stripImageTag(image) + separator + newTag
+}
+
+// stripImageTag removes the tag or digest from an image reference, leaving registry
+// ports (e.g. "registry:5000/image") intact
+func stripImageTag(image string) string {
+	repository, _, _ := SplitImageReference(image)
+	return repository
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdater_UpdateArgoApplication(t *testing.T) {
+	helmApplication := `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: my-app
+spec:
+  source:
+    repoURL: https://github.com/example/charts.git
+    path: charts/my-app
+    helm:
+      parameters:
+      - name: image.tag
+        value: "1.20"
+      - name: image.repository
+        value: registry.example.com/my-app
+`
+
+	kustomizeApplication := `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: my-app
+spec:
+  source:
+    repoURL: https://github.com/example/manifests.git
+    path: overlays/prod
+    kustomize:
+      images:
+      - my-app=registry.example.com:5000/my-app:1.20
+      - sidecar:2.0
+`
+
+	multiSourceApplication := `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: my-app
+spec:
+  sources:
+  - repoURL: https://github.com/example/charts.git
+    helm:
+      parameters:
+      - name: image
+        value: registry.example.com/my-app:1.20
+  - repoURL: https://github.com/example/manifests.git
+    kustomize:
+      images:
+      - image@sha256:abc
+`
+
+	tests := []struct {
+		name         string
+		content      string
+		entryName    string
+		imageTagOnly bool
+		path         string
+		expected     string
+		shouldErr    bool
+	}{
+		{
+			name:      "helm parameter",
+			content:   helmApplication,
+			entryName: "image.tag",
+			path:      "spec.source.helm.parameters[0].value",
+			expected:  "1.21",
+		},
+		{
+			name:      "kustomize image with new name and registry port",
+			content:   kustomizeApplication,
+			entryName: "my-app",
+			path:      "spec.source.kustomize.images[0]",
+			expected:  "my-app=registry.example.com:5000/my-app:1.21",
+		},
+		{
+			name:      "kustomize image without new name",
+			content:   kustomizeApplication,
+			entryName: "sidecar",
+			path:      "spec.source.kustomize.images[1]",
+			expected:  "sidecar:1.21",
+		},
+		{
+			name:         "helm parameter in multi-source application",
+			content:      multiSourceApplication,
+			entryName:    "image",
+			imageTagOnly: true,
+			path:         "spec.sources[0].helm.parameters[0].value",
+			expected:     "registry.example.com/my-app:1.21",
+		},
+		{
+			name:      "kustomize image digest in multi-source application",
+			content:   multiSourceApplication,
+			entryName: "image",
+			path:      "spec.sources[1].kustomize.images[0]",
+			expected:  "image:1.21",
+		},
+		{
+			name:      "unknown name",
+			content:   helmApplication,
+			entryName: "worker.tag",
+			shouldErr: true,
+		},
+		{
+			name:      "not an application",
+			content:   "apiVersion: v1\nkind: ConfigMap\ndata:\n  key: value\n",
+			entryName: "image.tag",
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updater := NewUpdater()
+
+			tmpFile := filepath.Join(t.TempDir(), "application.yaml")
+			if err := os.WriteFile(tmpFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			_, err := updater.UpdateArgoApplication(tmpFile, tt.entryName, "1.21", tt.imageTagOnly)
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to update Argo CD Application: %v", err)
+			}
+
+			value, err := updater.GetValueAtPath(tmpFile, tt.path)
+			if err != nil {
+				t.Fatalf("Failed to get value at path: %v", err)
+			}
+
+			if value != tt.expected {
+				t.Errorf("Expected %s, got %v", tt.expected, value)
+			}
+		})
+	}
+}
+
+func TestSetKustomizeImageTag_Digest(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("b", 64)
+
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{image: "my-app=registry.example.com:5000/my-app:1.20", expected: "my-app=registry.example.com:5000/my-app@" + digest},
+		{image: "sidecar:2.0", expected: "sidecar@" + digest},
+		{image: "image@sha256:abc", expected: "image@" + digest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if result := setKustomizeImageTag(tt.image, digest); result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
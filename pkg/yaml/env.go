@@ -0,0 +1,178 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envLine is a line of an env file assigning the variable being updated
+type envLine struct {
+	index int
+	// start and end delimit the value in the line, including its quotes
+	start, end int
+	quote      byte
+	value      string
+}
+
+// UpdateEnvKey updates the value of a variable in a dotenv file of KEY=value lines (e.g.
+// IMAGE_TAG=v1.0.0, optionally prefixed with "export"). Only the value is replaced,
+// keeping its quotes, inline comments and all other lines, and every assignment of the
+// variable is updated. A missing variable is appended unless WithExistingPathsOnly is set.
+// When expectedValuePattern is set, the current value must match it. It reports whether
+// the value changed.
+func (u *Updater) UpdateEnvKey(filePath, key, newValue string, imageTagOnly bool, expectedValuePattern string) (bool, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	assignments, err := findEnvAssignments(lines, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse env file %s: %w", filePath, err)
+	}
+
+	current := make([]string, len(assignments))
+	for i, assignment := range assignments {
+		current[i] = assignment.value
+	}
+	if len(assignments) == 0 && expectedValuePattern != "" {
+		return false, fmt.Errorf("failed to validate key %s in file %s: %w: key '%s' not found: %w",
+			key, filePath, ErrUnexpectedValue, key, ErrPathNotFound)
+	}
+	if err := checkExpectedValues(current, expectedValuePattern); err != nil {
+		return false, fmt.Errorf("failed to validate key %s in file %s: %w", key, filePath, err)
+	}
+
+	if len(assignments) == 0 {
+		if u.existingPathsOnly {
+			return false, fmt.Errorf("failed to update key %s in file %s: key '%s' not found: %w", key, filePath, key, ErrPathNotFound)
+		}
+		if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+			data = append(data, '\n')
+		}
+		data = append(data, key+"="+quoteEnvValue(newValue, 0)+"\n"...)
+		if err := u.writeFile(filePath, data); err != nil {
+			return false, fmt.Errorf("failed to write updated env file %s: %w", filePath, err)
+		}
+		return true, nil
+	}
+
+	for _, assignment := range assignments {
+		value := newValue
+		if imageTagOnly {
+			value = u.updateImageReference(assignment.value, newValue)
+		}
+		line := lines[assignment.index]
+		lines[assignment.index] = line[:assignment.start] + quoteEnvValue(value, assignment.quote) + line[assignment.end:]
+	}
+
+	updated := []byte(strings.Join(lines, "\n"))
+	if bytes.Equal(updated, data) {
+		return false, nil
+	}
+	if err := u.writeFile(filePath, updated); err != nil {
+		return false, fmt.Errorf("failed to write updated env file %s: %w", filePath, err)
+	}
+	return true, nil
+}
+
+// findEnvAssignments returns the lines assigning the variable, skipping comments and
+// blank lines
+func findEnvAssignments(lines []string, key string) ([]envLine, error) {
+	var assignments []envLine
+	for i, line := range lines {
+		rest := strings.TrimLeft(line, " \t")
+		if rest == "" || strings.HasPrefix(rest, "#") {
+			continue
+		}
+		if after, ok := strings.CutPrefix(rest, "export "); ok {
+			rest = strings.TrimLeft(after, " \t")
+		}
+
+		name, value, ok := strings.Cut(rest, "=")
+		if !ok || strings.TrimSpace(name) != key {
+			continue
+		}
+
+		value = strings.TrimLeft(value, " \t")
+		start := len(line) - len(value)
+		value = strings.TrimRight(value, "\r")
+
+		assignment := envLine{index: i, start: start}
+		switch {
+		case strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'"):
+			assignment.quote = value[0]
+			end := closingQuote(value, assignment.quote)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value of %s", i+1, key)
+			}
+			assignment.end = start + end + 1
+			assignment.value = value[1:end]
+			if assignment.quote == '"' {
+				assignment.value = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(assignment.value)
+			}
+		default:
+			// An unquoted value ends at an inline comment
+			end := len(value)
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				end = comment
+			}
+			if comment := strings.Index(value, "\t#"); comment >= 0 && comment < end {
+				end = comment
+			}
+			assignment.value = strings.TrimRight(value[:end], " \t")
+			assignment.end = start + len(assignment.value)
+		}
+		assignments = append(assignments, assignment)
+	}
+	return assignments, nil
+}
+
+// closingQuote returns the index of the quote closing the value opened by its first
+// character, or -1. Double quoted values may escape quotes with a backslash.
+func closingQuote(value string, quote byte) int {
+	for i := 1; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && quote == '"':
+			i++
+		case value[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// quoteEnvValue formats a value with the quote of the value it replaces. Unquoted values
+// are double quoted when they contain whitespace, quotes or comment characters.
+func quoteEnvValue(value string, quote byte) string {
+	if quote == 0 && strings.ContainsAny(value, " \t\"'#") {
+		quote = '"'
+	}
+	switch {
+	case quote == '\'' && !strings.Contains(value, "'"):
+		return "'" + value + "'"
+	case quote != 0:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	default:
+		return value
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdater_UpdateEnvKey(t *testing.T) {
+	content := `# Deployment settings
+APP_NAME=my-app
+IMAGE_TAG=v1.0.0 # bumped by yuk
+export IMAGE="registry.example.com/app:v1.0.0"
+WORKER_TAG='v1.0.0'
+IMAGE_TAG_SUFFIX=-alpine
+`
+
+	tests := []struct {
+		name         string
+		key          string
+		imageTagOnly bool
+		expected     string
+	}{
+		{
+			name: "unquoted value with comment",
+			key:  "IMAGE_TAG",
+			expected: `# Deployment settings
+APP_NAME=my-app
+IMAGE_TAG=v1.1.0 # bumped by yuk
+export IMAGE="registry.example.com/app:v1.0.0"
+WORKER_TAG='v1.0.0'
+IMAGE_TAG_SUFFIX=-alpine
+`,
+		},
+		{
+			name:         "exported double quoted image",
+			key:          "IMAGE",
+			imageTagOnly: true,
+			expected: `# Deployment settings
+APP_NAME=my-app
+IMAGE_TAG=v1.0.0 # bumped by yuk
+export IMAGE="registry.example.com/app:v1.1.0"
+WORKER_TAG='v1.0.0'
+IMAGE_TAG_SUFFIX=-alpine
+`,
+		},
+		{
+			name: "single quoted value",
+			key:  "WORKER_TAG",
+			expected: `# Deployment settings
+APP_NAME=my-app
+IMAGE_TAG=v1.0.0 # bumped by yuk
+export IMAGE="registry.example.com/app:v1.0.0"
+WORKER_TAG='v1.1.0'
+IMAGE_TAG_SUFFIX=-alpine
+`,
+		},
+		{
+			name: "missing key appended",
+			key:  "SIDECAR_TAG",
+			expected: `# Deployment settings
+APP_NAME=my-app
+IMAGE_TAG=v1.0.0 # bumped by yuk
+export IMAGE="registry.example.com/app:v1.0.0"
+WORKER_TAG='v1.0.0'
+IMAGE_TAG_SUFFIX=-alpine
+SIDECAR_TAG=v1.1.0
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			modified, err := NewUpdater().UpdateEnvKey(filePath, tt.key, "v1.1.0", tt.imageTagOnly, "")
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if !modified {
+				t.Error("Expected the file to be modified")
+			}
+
+			updated, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatalf("Failed to read updated file: %v", err)
+			}
+			if string(updated) != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, updated)
+			}
+		})
+	}
+}
+
+func TestUpdater_UpdateEnvKey_Unchanged(t *testing.T) {
+	content := "IMAGE_TAG=v1.0.0"
+
+	filePath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	modified, err := NewUpdater().UpdateEnvKey(filePath, "IMAGE_TAG", "v1.0.0", false, "")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if modified {
+		t.Error("Expected an unchanged value to report no modification")
+	}
+}
+
+func TestUpdater_UpdateEnvKey_Errors(t *testing.T) {
+	tests := []struct {
+		name                 string
+		content              string
+		options              []Option
+		expectedValuePattern string
+		expectedErr          error
+	}{
+		{
+			name:        "missing key with existing paths only",
+			content:     "APP_NAME=my-app\n",
+			options:     []Option{WithExistingPathsOnly(true)},
+			expectedErr: ErrPathNotFound,
+		},
+		{
+			name:                 "unexpected current value",
+			content:              "IMAGE_TAG=latest\n",
+			expectedValuePattern: `^v\d+`,
+			expectedErr:          ErrUnexpectedValue,
+		},
+		{
+			name:    "unterminated quote",
+			content: "IMAGE_TAG=\"v1.0.0\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(filePath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			_, err := NewUpdater(tt.options...).UpdateEnvKey(filePath, "IMAGE_TAG", "v1.1.0", false, tt.expectedValuePattern)
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			if tt.expectedErr != nil && !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Expected %v, got: %v", tt.expectedErr, err)
+			}
+
+			// The file is left unchanged
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatalf("Failed to read file: %v", err)
+			}
+			if string(content) != tt.content {
+				t.Errorf("Expected file to be unchanged, got:\n%s", content)
+			}
+		})
+	}
+}
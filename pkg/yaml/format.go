@@ -0,0 +1,111 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// File formats
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatTOML = "toml"
+	FormatEnv  = "env"
+)
+
+// formatHandler updates the value at a path of a file in one format, reporting whether
+// the value changed
+type formatHandler func(u *Updater, filePath, path, newValue string, imageTagOnly bool, expectedValuePattern string) (bool, error)
+
+// formatHandlers are the handlers of the supported file formats
+var formatHandlers = map[string]formatHandler{
+	FormatYAML: (*Updater).UpdateYAMLPath,
+	FormatJSON: (*Updater).UpdateJSONPath,
+	FormatTOML: (*Updater).UpdateTOMLPath,
+	FormatEnv:  (*Updater).UpdateEnvKey,
+}
+
+// formatExtensions are the formats detected from file extensions, compared
+// case-insensitively. Files with other extensions are YAML.
+var formatExtensions = map[string]string{
+	".json": FormatJSON,
+	".toml": FormatTOML,
+	".env":  FormatEnv,
+}
+
+// Formats returns the supported file formats, sorted
+func Formats() []string {
+	formats := make([]string, 0, len(formatHandlers))
+	for format := range formatHandlers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// FileFormat returns the format of a file: the explicit format when set, otherwise the
+// format of its extension (e.g. FormatTOML for .toml files and FormatEnv for .env files)
+// and FormatYAML for anything else
+func FileFormat(filePath, format string) (string, error) {
+	if format != "" {
+		if _, ok := formatHandlers[format]; !ok {
+			return "", fmt.Errorf("unsupported file format: %s", format)
+		}
+		return format, nil
+	}
+
+	if format, ok := formatExtensions[strings.ToLower(filepath.Ext(filePath))]; ok {
+		return format, nil
+	}
+	return FormatYAML, nil
+}
+
+// UpdatePath updates the value at a path of a file with the handler of its format, as
+// returned by FileFormat. The path is a YAML path for YAML, JSON and TOML files and the
+// variable name for env files. It reports whether the value changed.
+func (u *Updater) UpdatePath(filePath, format, path, newValue string, imageTagOnly bool, expectedValuePattern string) (bool, error) {
+	handler, ok := formatHandlers[format]
+	if !ok {
+		return false, fmt.Errorf("unsupported file format: %s", format)
+	}
+	return handler(u, filePath, path, newValue, imageTagOnly, expectedValuePattern)
+}
+
+// checkExpectedValues verifies that the current values found by a line-based format
+// handler match expectedValuePattern
+func checkExpectedValues(values []string, expectedValuePattern string) error {
+	if expectedValuePattern == "" {
+		return nil
+	}
+
+	pattern, err := regexp.Compile(expectedValuePattern)
+	if err != nil {
+		return fmt.Errorf("invalid expected value pattern %q: %w", expectedValuePattern, err)
+	}
+
+	for _, current := range values {
+		if !pattern.MatchString(current) {
+			return fmt.Errorf("%w: %q does not match %q", ErrUnexpectedValue, current, expectedValuePattern)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileFormat(t *testing.T) {
+	tests := []struct {
+		file      string
+		format    string
+		expected  string
+		shouldErr bool
+	}{
+		{file: "values.yaml", expected: FormatYAML},
+		{file: "deploy/app.yml", expected: FormatYAML},
+		{file: "tekton/params.json", expected: FormatJSON},
+		{file: "PARAMS.JSON", expected: FormatJSON},
+		{file: "params.txt", format: FormatJSON, expected: FormatJSON},
+		{file: "params.json", format: FormatYAML, expected: FormatYAML},
+		{file: "config/app.toml", expected: FormatTOML},
+		{file: "deploy/.env", expected: FormatEnv},
+		{file: "deploy/prod.env", expected: FormatEnv},
+		{file: "deploy/.env.production", expected: FormatYAML},
+		{file: "deploy/.env.production", format: FormatEnv, expected: FormatEnv},
+		{file: "params.xml", format: "xml", shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			format, err := FileFormat(tt.file, tt.format)
+			if tt.shouldErr {
+				if err == nil {
+					t.Errorf("Expected error for format %q", tt.format)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if format != tt.expected {
+				t.Errorf("Expected format %s, got %s", tt.expected, format)
+			}
+		})
+	}
+}
+
+func TestUpdater_UpdatePath(t *testing.T) {
+	tests := []struct {
+		file     string
+		content  string
+		path     string
+		expected string
+	}{
+		{file: "values.yaml", content: "image:\n  tag: v1.0.0\n", path: "image.tag", expected: "image:\n    tag: v1.1.0\n"},
+		{file: "params.json", content: `{"image": {"tag": "v1.0.0"}}`, path: "image.tag", expected: "{\n  \"image\": {\n    \"tag\": \"v1.1.0\"\n  }\n}\n"},
+		{file: "app.toml", content: "[image]\ntag = \"v1.0.0\"\n", path: "image.tag", expected: "[image]\ntag = \"v1.1.0\"\n"},
+		{file: "app.env", content: "IMAGE_TAG=v1.0.0\n", path: "IMAGE_TAG", expected: "IMAGE_TAG=v1.1.0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(filePath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			format, err := FileFormat(filePath, "")
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if _, err := NewUpdater().UpdatePath(filePath, format, tt.path, "v1.1.0", false, ""); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			updated, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatalf("Failed to read updated file: %v", err)
+			}
+			if string(updated) != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, updated)
+			}
+		})
+	}
+
+	if _, err := NewUpdater().UpdatePath("values.xml", "xml", "image.tag", "v1.1.0", false, ""); err == nil {
+		t.Error("Expected error for an unsupported format")
+	}
+}
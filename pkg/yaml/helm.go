@@ -0,0 +1,134 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultHelmTagKey is the key of the tag in a Helm image block
+const DefaultHelmTagKey = "tag"
+
+// HelmImage selects an image block of Helm values that splits the image reference into
+// separate keys, e.g.:
+//
+//	image:
+//	  repository: registry.example.com/my-app
+//	  tag: "1.20"
+type HelmImage struct {
+	// Path selects the image block (e.g. "image" or "workers[*].image")
+	Path string
+
+	// TagKey is the key of the tag in the image block (default: "tag")
+	TagKey string
+
+	// RepositoryKey is the key of the repository in the image block. When set, its value
+	// must name Repository, either exactly or as the last path segments of the reference.
+	RepositoryKey string
+
+	// Repository is the name of the repository the tag belongs to
+	Repository string
+}
+
+// UpdateHelmImage sets the tag of the image block(s) selected by image to newValue.
+// When expectedValuePattern is set, the current tag must match it. It reports whether any
+// tag changed.
+func (u *Updater) UpdateHelmImage(filePath string, image HelmImage, newValue, expectedValuePattern string) (bool, error) {
+	if image.Path == "" {
+		return false, fmt.Errorf("a path is required to update a Helm image in file %s", filePath)
+	}
+
+	tagKey := image.TagKey
+	if tagKey == "" {
+		tagKey = DefaultHelmTagKey
+	}
+
+	// Read the file
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	// Parse YAML
+	var yamlData interface{}
+	if err := yaml.Unmarshal(data, &yamlData); err != nil {
+		return false, fmt.Errorf("failed to parse YAML in file %s: %w", filePath, err)
+	}
+
+	// Serialize the document as parsed to tell whether the update changes it
+	before, err := yaml.Marshal(yamlData)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal YAML for file %s: %w", filePath, err)
+	}
+
+	imageParts, err := u.splitPath(image.Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse Helm image %s in file %s: %w", image.Path, filePath, err)
+	}
+	tagParts := append(append([]string{}, imageParts...), tagKey)
+
+	// Make sure the image block belongs to the repository
+	if image.RepositoryKey != "" {
+		repositoryParts := append(append([]string{}, imageParts...), image.RepositoryKey)
+		if err := u.checkRepositoryAtParts(yamlData, repositoryParts, image.Repository); err != nil {
+			return false, fmt.Errorf("failed to validate Helm image %s in file %s: %w", image.Path, filePath, err)
+		}
+	}
+
+	// Make sure the tag is still the expected value
+	if err := u.checkValueAtParts(yamlData, tagParts, expectedValuePattern); err != nil {
+		return false, fmt.Errorf("failed to validate Helm image %s in file %s: %w", image.Path, filePath, err)
+	}
+
+	// Update the tag of each selected image block
+	if err := u.updateValueAtParts(yamlData, tagParts, newValue, false); err != nil {
+		return false, fmt.Errorf("failed to update Helm image %s in file %s: %w", image.Path, filePath, err)
+	}
+
+	// Write back to file if the value changed, making sure the result still parses
+	return u.writeChanged(filePath, data, before, yamlData)
+}
+
+// checkRepositoryAtParts verifies that the values at the path parts name repository
+func (u *Updater) checkRepositoryAtParts(data interface{}, parts []string, repository string) error {
+	values, err := u.getValuesAtParts(data, parts)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnexpectedValue, err)
+	}
+
+	for _, value := range values {
+		current, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%w: found %T, expected repository %q", ErrUnexpectedValue, value, repository)
+		}
+		if !matchesRepository(current, repository) {
+			return fmt.Errorf("%w: repository %q does not match %q", ErrUnexpectedValue, current, repository)
+		}
+	}
+
+	return nil
+}
+
+// matchesRepository reports whether a repository reference (e.g.
+// "registry.example.com/team/my-app") names the repository (e.g. "team/my-app")
+func matchesRepository(reference, repository string) bool {
+	return reference == repository || strings.HasSuffix(reference, "/"+repository)
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdater_UpdateHelmImage(t *testing.T) {
+	values := `replicaCount: 2
+image:
+  repository: 123456789012.dkr.ecr.us-west-2.amazonaws.com/team/my-app
+  tag: "1.20"
+  pullPolicy: IfNotPresent
+workers:
+- name: default
+  image:
+    registry: registry.example.com
+    name: team/my-worker
+    version: "1.20"
+- name: batch
+  image:
+    registry: registry.example.com
+    name: team/my-worker
+    version: "1.19"
+sidecar:
+  image:
+    repository: registry.example.com/other-app
+    tag: "2.0"
+`
+
+	tests := []struct {
+		name                 string
+		image                HelmImage
+		expectedValuePattern string
+		paths                []string
+		shouldErr            bool
+		unexpectedValue      bool
+	}{
+		{
+			name:  "default tag key",
+			image: HelmImage{Path: "image"},
+			paths: []string{"image.tag"},
+		},
+		{
+			name:  "matching repository",
+			image: HelmImage{Path: "image", RepositoryKey: "repository", Repository: "team/my-app"},
+			paths: []string{"image.tag"},
+		},
+		{
+			name:                 "matching expected tag",
+			image:                HelmImage{Path: "image"},
+			expectedValuePattern: `^1\.\d+$`,
+			paths:                []string{"image.tag"},
+		},
+		{
+			name:  "custom keys with wildcard",
+			image: HelmImage{Path: "workers[*].image", TagKey: "version", RepositoryKey: "name", Repository: "team/my-worker"},
+			paths: []string{"workers[0].image.version", "workers[1].image.version"},
+		},
+		{
+			name:            "repository mismatch",
+			image:           HelmImage{Path: "sidecar.image", RepositoryKey: "repository", Repository: "team/my-app"},
+			shouldErr:       true,
+			unexpectedValue: true,
+		},
+		{
+			name:            "missing repository key",
+			image:           HelmImage{Path: "image", RepositoryKey: "image", Repository: "team/my-app"},
+			shouldErr:       true,
+			unexpectedValue: true,
+		},
+		{
+			name:                 "unexpected tag",
+			image:                HelmImage{Path: "image"},
+			expectedValuePattern: `^2\.`,
+			shouldErr:            true,
+			unexpectedValue:      true,
+		},
+		{
+			name:      "missing image block",
+			image:     HelmImage{Path: "worker.image"},
+			shouldErr: true,
+		},
+		{
+			name:      "missing path",
+			image:     HelmImage{},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updater := NewUpdater()
+
+			tmpFile := filepath.Join(t.TempDir(), "values.yaml")
+			if err := os.WriteFile(tmpFile, []byte(values), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			_, err := updater.UpdateHelmImage(tmpFile, tt.image, "1.21", tt.expectedValuePattern)
+			if tt.shouldErr {
+				if err == nil {
+					t.Fatal("Expected error but got none")
+				}
+				if errors.Is(err, ErrUnexpectedValue) != tt.unexpectedValue {
+					t.Errorf("Expected ErrUnexpectedValue to be %v, got %v", tt.unexpectedValue, err)
+				}
+
+				// A failed update leaves the file untouched
+				content, err := os.ReadFile(tmpFile)
+				if err != nil {
+					t.Fatalf("Failed to read test file: %v", err)
+				}
+				if string(content) != values {
+					t.Errorf("Expected file to be unchanged, got:\n%s", content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to update Helm image: %v", err)
+			}
+
+			for _, path := range tt.paths {
+				value, err := updater.GetValueAtPath(tmpFile, path)
+				if err != nil {
+					t.Fatalf("Failed to get value at path %s: %v", path, err)
+				}
+				if value != "1.21" {
+					t.Errorf("Expected 1.21 at %s, got %v", path, value)
+				}
+			}
+
+			// Other image blocks are left as is
+			value, err := updater.GetValueAtPath(tmpFile, "sidecar.image.tag")
+			if err != nil {
+				t.Fatalf("Failed to get value at path: %v", err)
+			}
+			if value != "2.0" {
+				t.Errorf("Expected sidecar tag 2.0, got %v", value)
+			}
+		})
+	}
+}
+
+func TestMatchesRepository(t *testing.T) {
+	tests := []struct {
+		reference  string
+		repository string
+		expected   bool
+	}{
+		{reference: "my-app", repository: "my-app", expected: true},
+		{reference: "registry.example.com/team/my-app", repository: "team/my-app", expected: true},
+		{reference: "registry.example.com/team/my-app", repository: "my-app", expected: true},
+		{reference: "registry.example.com/team/not-my-app", repository: "my-app", expected: false},
+		{reference: "registry.example.com/team/my-app", repository: "other/my-app", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reference, func(t *testing.T) {
+			if result := matchesRepository(tt.reference, tt.repository); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
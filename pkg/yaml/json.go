@@ -0,0 +1,95 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// DefaultJSONIndent is the indentation of JSON files written by the updater
+const DefaultJSONIndent = "  "
+
+// UpdateJSONPath updates a specific path in a JSON file with a new value, using the same
+// path syntax as UpdateYAMLPath. When expectedValuePattern is set, the current value must
+// match it before it is replaced. The file is re-serialized with the updater's JSON
+// indent and object keys in sorted order, unless the value is unchanged. It reports
+// whether the value changed.
+func (u *Updater) UpdateJSONPath(filePath, path, newValue string, imageTagOnly bool, expectedValuePattern string) (bool, error) {
+	// Read the file
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	// Parse JSON, keeping numbers as written
+	var jsonData interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&jsonData); err != nil {
+		return false, fmt.Errorf("failed to parse JSON in file %s: %w", filePath, err)
+	}
+
+	// Serialize the document as parsed to tell whether the update changes it
+	before, err := u.marshalJSON(jsonData)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal JSON for file %s: %w", filePath, err)
+	}
+
+	// Make sure the path still points at the expected value
+	if err := u.checkValueAtPath(jsonData, path, expectedValuePattern); err != nil {
+		return false, fmt.Errorf("failed to validate JSON path %s in file %s: %w", path, filePath, err)
+	}
+
+	// Update the value at the specified path
+	if err := u.updateValueAtPath(jsonData, path, newValue, imageTagOnly); err != nil {
+		return false, fmt.Errorf("failed to update JSON path %s in file %s: %w", path, filePath, err)
+	}
+
+	// Marshal back to JSON
+	updatedData, err := u.marshalJSON(jsonData)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal updated JSON for file %s: %w", filePath, err)
+	}
+
+	// Leave files already holding the value untouched
+	if bytes.Equal(before, updatedData) {
+		return false, nil
+	}
+
+	// Write back to file
+	if err := u.writeFile(filePath, updatedData); err != nil {
+		return false, fmt.Errorf("failed to write updated JSON to file %s: %w", filePath, err)
+	}
+
+	return true, nil
+}
+
+// marshalJSON serializes a JSON document with the updater's indent, without escaping HTML
+// characters
+func (u *Updater) marshalJSON(jsonData interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", u.jsonIndent)
+	if err := encoder.Encode(jsonData); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
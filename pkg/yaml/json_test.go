@@ -0,0 +1,229 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdater_UpdateJSONPath(t *testing.T) {
+	content := `{
+  "params": {
+    "replicas": 3,
+    "images": [
+      {"name": "app", "image": "registry.example.com/app:v1.0.0"},
+      {"name": "worker", "image": "registry.example.com/worker:v1.0.0"}
+    ],
+    "tag": "v1.0.0",
+    "url": "https://example.com/?a=1&b=2"
+  }
+}
+`
+
+	tests := []struct {
+		name         string
+		path         string
+		newValue     string
+		imageTagOnly bool
+		indent       string
+		expected     string
+	}{
+		{
+			name:     "nested value",
+			path:     "params.tag",
+			newValue: "v1.1.0",
+			expected: `{
+  "params": {
+    "images": [
+      {
+        "image": "registry.example.com/app:v1.0.0",
+        "name": "app"
+      },
+      {
+        "image": "registry.example.com/worker:v1.0.0",
+        "name": "worker"
+      }
+    ],
+    "replicas": 3,
+    "tag": "v1.1.0",
+    "url": "https://example.com/?a=1&b=2"
+  }
+}
+`,
+		},
+		{
+			name:         "image tag in array",
+			path:         "params.images[1].image",
+			newValue:     "v1.1.0",
+			imageTagOnly: true,
+			indent:       "\t",
+			expected: `{
+	"params": {
+		"images": [
+			{
+				"image": "registry.example.com/app:v1.0.0",
+				"name": "app"
+			},
+			{
+				"image": "registry.example.com/worker:v1.1.0",
+				"name": "worker"
+			}
+		],
+		"replicas": 3,
+		"tag": "v1.0.0",
+		"url": "https://example.com/?a=1&b=2"
+	}
+}
+`,
+		},
+		{
+			name:         "wildcard index",
+			path:         "params.images[*].image",
+			newValue:     "v2.0.0",
+			imageTagOnly: true,
+			indent:       "    ",
+			expected: `{
+    "params": {
+        "images": [
+            {
+                "image": "registry.example.com/app:v2.0.0",
+                "name": "app"
+            },
+            {
+                "image": "registry.example.com/worker:v2.0.0",
+                "name": "worker"
+            }
+        ],
+        "replicas": 3,
+        "tag": "v1.0.0",
+        "url": "https://example.com/?a=1&b=2"
+    }
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "params.json")
+			if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			var opts []Option
+			if tt.indent != "" {
+				opts = append(opts, WithJSONIndent(tt.indent))
+			}
+			updater := NewUpdater(opts...)
+
+			if _, err := updater.UpdateJSONPath(filePath, tt.path, tt.newValue, tt.imageTagOnly, ""); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			updated, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatalf("Failed to read updated file: %v", err)
+			}
+			if string(updated) != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, updated)
+			}
+		})
+	}
+}
+
+func TestUpdater_UpdateJSONPath_Unchanged(t *testing.T) {
+	// Compact JSON that re-serializing would indent
+	content := `{"image":{"tag":"v1.0.0"}}`
+
+	filePath := filepath.Join(t.TempDir(), "params.json")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	modified, err := NewUpdater().UpdateJSONPath(filePath, "image.tag", "v1.0.0", false, "")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if modified {
+		t.Error("Expected an unchanged value to report no modification")
+	}
+
+	updated, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(updated) != content {
+		t.Errorf("Expected the file not to be rewritten, got:\n%s", updated)
+	}
+}
+
+func TestUpdater_UpdateJSONPath_Errors(t *testing.T) {
+	tests := []struct {
+		name                 string
+		content              string
+		path                 string
+		expectedValuePattern string
+		expectUnexpected     bool
+	}{
+		{
+			name:    "invalid JSON",
+			content: `{"tag": `,
+			path:    "tag",
+		},
+		{
+			name:    "index out of bounds",
+			content: `{"images": ["app:v1.0.0"]}`,
+			path:    "images[1]",
+		},
+		{
+			name:                 "unexpected current value",
+			content:              `{"tag": "latest"}`,
+			path:                 "tag",
+			expectedValuePattern: `^v\d+`,
+			expectUnexpected:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "params.json")
+			if err := os.WriteFile(filePath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			_, err := NewUpdater().UpdateJSONPath(filePath, tt.path, "v1.1.0", false, tt.expectedValuePattern)
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			if tt.expectUnexpected && !errors.Is(err, ErrUnexpectedValue) {
+				t.Errorf("Expected ErrUnexpectedValue, got: %v", err)
+			}
+
+			// The file is left unchanged
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatalf("Failed to read file: %v", err)
+			}
+			if string(content) != tt.content {
+				t.Errorf("Expected file to be unchanged, got:\n%s", content)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Path syntaxes
+const (
+	// PathSyntaxDotted is the dotted path syntax, e.g. "spec.containers[0].image" (default)
+	PathSyntaxDotted = "dotted"
+
+	// PathSyntaxJSONPath is a JSONPath subset, e.g. `$.spec.containers[?(@.name=="app")].image`
+	PathSyntaxJSONPath = "jsonpath"
+)
+
+// filterPrefix starts the path part produced by a [?(...)] filter, selecting every element
+// of an array whose field equals a value
+const filterPrefix = "?(@."
+
+// WithPathSyntax sets the syntax of the paths passed to the updater (default:
+// PathSyntaxDotted)
+func WithPathSyntax(syntax string) Option {
+	return func(u *Updater) {
+		u.pathSyntax = syntax
+	}
+}
+
+// With returns a copy of the updater with the options applied
+func (u *Updater) With(opts ...Option) *Updater {
+	updater := *u
+	for _, opt := range opts {
+		opt(&updater)
+	}
+	return &updater
+}
+
+// splitPath parses a path into parts using the path syntax of the updater
+func (u *Updater) splitPath(path string) ([]string, error) {
+	switch u.pathSyntax {
+	case "", PathSyntaxDotted:
+		return u.parsePath(path), nil
+	case PathSyntaxJSONPath:
+		return parseJSONPath(path)
+	default:
+		return nil, fmt.Errorf("unsupported path syntax: %s", u.pathSyntax)
+	}
+}
+
+// filterExpression matches the filter of a [?(...)] selector, comparing a field of each
+// element with a quoted string, a number or a boolean
+var filterExpression = regexp.MustCompile(`^\?\(\s*@\.([A-Za-z_][\w-]*(?:\.[A-Za-z_][\w-]*)*)\s*==\s*(?:'([^']*)'|"([^"]*)"|(-?\d+(?:\.\d+)?|true|false))\s*\)$`)
+
+// jsonPathKey matches a key following a dot in a JSONPath expression
+var jsonPathKey = regexp.MustCompile(`^[^.\[\]]+`)
+
+// parseJSONPath parses a JSONPath expression like `$.spec.containers[?(@.name=="app")].image`
+// into parts. It supports child keys (.key, ['key'] and ["key"]), array indices, the [*]
+// and .* wildcards and equality filters; the leading "$" is optional.
+func parseJSONPath(path string) ([]string, error) {
+	expression := strings.TrimPrefix(path, "$")
+	if expression == "" {
+		return nil, fmt.Errorf("JSONPath expression %q selects no value", path)
+	}
+	if !strings.HasPrefix(expression, ".") && !strings.HasPrefix(expression, "[") {
+		expression = "." + expression
+	}
+
+	var parts []string
+	for expression != "" {
+		switch expression[0] {
+		case '.':
+			expression = expression[1:]
+			if strings.HasPrefix(expression, ".") {
+				return nil, fmt.Errorf("recursive descent is not supported in JSONPath expression %q", path)
+			}
+			key := jsonPathKey.FindString(expression)
+			if key == "" {
+				return nil, fmt.Errorf("missing key in JSONPath expression %q", path)
+			}
+			parts = append(parts, key)
+			expression = expression[len(key):]
+
+		case '[':
+			end := closingBracket(expression)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated bracket in JSONPath expression %q", path)
+			}
+			part, err := parseJSONPathSelector(strings.TrimSpace(expression[1:end]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSONPath expression %q: %w", path, err)
+			}
+			parts = append(parts, part)
+			expression = expression[end+1:]
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q in JSONPath expression %q", expression[0], path)
+		}
+	}
+
+	return parts, nil
+}
+
+// closingBracket returns the index of the bracket closing the selector at the start of
+// expression, skipping brackets inside quoted strings, or -1 if it is not closed
+func closingBracket(expression string) int {
+	var quote byte
+	for i := 1; i < len(expression); i++ {
+		switch c := expression[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseJSONPathSelector parses the content of a bracket selector into a path part
+func parseJSONPathSelector(selector string) (string, error) {
+	switch {
+	case selector == wildcard:
+		return wildcard, nil
+
+	case strings.HasPrefix(selector, "?"):
+		match := filterExpression.FindStringSubmatch(selector)
+		if match == nil {
+			return "", fmt.Errorf("unsupported filter %s, expected [?(@.key==\"value\")]", selector)
+		}
+		return filterPart(match[1], match[2]+match[3]+match[4]), nil
+
+	case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+		return selector[1 : len(selector)-1], nil
+
+	default:
+		if _, err := strconv.ParseUint(selector, 10, 0); err != nil {
+			return "", fmt.Errorf("unsupported selector [%s]", selector)
+		}
+		return selector, nil
+	}
+}
+
+// filterPart returns the path part of a filter selecting the elements whose field at key
+// (a dotted path) equals value
+func filterPart(key, value string) string {
+	return filterPrefix + key + "==" + strconv.Quote(value) + ")"
+}
+
+// parseFilterPart returns the key and value of a filter path part
+func parseFilterPart(part string) (key, value string, ok bool) {
+	if !strings.HasPrefix(part, filterPrefix) || !strings.HasSuffix(part, ")") {
+		return "", "", false
+	}
+	key, quoted, found := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(part, filterPrefix), ")"), "==")
+	if !found {
+		return "", "", false
+	}
+	value, err := strconv.Unquote(quoted)
+	if err != nil {
+		return "", "", false
+	}
+	return key, value, true
+}
+
+// isSelector reports whether a path part selects several array elements or map values
+func isSelector(part string) bool {
+	_, _, isFilter := parseFilterPart(part)
+	return part == wildcard || isFilter
+}
+
+// selectIndices returns the indices of the array elements (or the keys of the map values)
+// a wildcard selects, or the indices of the array elements a filter path part selects
+func (u *Updater) selectIndices(data interface{}, part string) ([]string, error) {
+	key, value, ok := parseFilterPart(part)
+	if !ok {
+		return u.wildcardKeys(data)
+	}
+
+	array, isArray := data.([]interface{})
+	if !isArray {
+		return nil, fmt.Errorf("cannot apply filter %s to non-array type: %T", part, data)
+	}
+
+	var indices []string
+	for i, element := range array {
+		field, err := u.getValuesAtParts(element, strings.Split(key, "."))
+		if err != nil {
+			continue
+		}
+		if fmt.Sprint(field[0]) == value {
+			indices = append(indices, strconv.Itoa(i))
+		}
+	}
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("filter %s matched no elements", part)
+	}
+	return indices, nil
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "dotted keys",
+			path:     "$.spec.replicas",
+			expected: []string{"spec", "replicas"},
+		},
+		{
+			name:     "without root",
+			path:     "spec.containers[0].image",
+			expected: []string{"spec", "containers", "0", "image"},
+		},
+		{
+			name:     "bracket keys",
+			path:     `$['data']["config.yaml"]`,
+			expected: []string{"data", "config.yaml"},
+		},
+		{
+			name:     "wildcards",
+			path:     "$.spec.containers[*].ports.*",
+			expected: []string{"spec", "containers", "*", "ports", "*"},
+		},
+		{
+			name:     "filter",
+			path:     `$.spec.containers[?(@.name=="app")].image`,
+			expected: []string{"spec", "containers", `?(@.name=="app")`, "image"},
+		},
+		{
+			name:     "filter with single quotes and a closing bracket",
+			path:     `$.env[?( @.meta.name == 'a]b' )].value`,
+			expected: []string{"env", `?(@.meta.name=="a]b")`, "value"},
+		},
+		{
+			name:    "recursive descent",
+			path:    "$..image",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported filter operator",
+			path:    `$.containers[?(@.name!="app")]`,
+			wantErr: true,
+		},
+		{
+			name:    "unterminated bracket",
+			path:    "$.containers[0",
+			wantErr: true,
+		},
+		{
+			name:    "root only",
+			path:    "$",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := parseJSONPath(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got parts %q", parts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(parts, tt.expected) {
+				t.Errorf("Expected parts %q, got %q", tt.expected, parts)
+			}
+		})
+	}
+}
+
+func TestUpdater_UpdateYAMLPath_JSONPathFilter(t *testing.T) {
+	updater := NewUpdater(WithPathSyntax(PathSyntaxJSONPath))
+
+	yamlContent := `apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: sidecar
+        image: envoyproxy/envoy:v1.29.0
+      - name: app
+        image: 123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:v1.0.0
+`
+
+	tmpFile := filepath.Join(t.TempDir(), "deployment.yaml")
+	if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	path := `$.spec.template.spec.containers[?(@.name=="app")].image`
+	modified, err := updater.UpdateYAMLPath(tmpFile, path, "v1.1.0", true, `:v1\.0\.0$`)
+	if err != nil {
+		t.Fatalf("Failed to update YAML path: %v", err)
+	}
+	if !modified {
+		t.Error("Expected the file to be modified")
+	}
+
+	value, err := updater.GetValueAtPath(tmpFile, path)
+	if err != nil {
+		t.Fatalf("Failed to get value at path: %v", err)
+	}
+	expected := []interface{}{"123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:v1.1.0"}
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("Expected %v, got %v", expected, value)
+	}
+
+	// The container not selected by the filter is untouched
+	sidecar, err := updater.GetValueAtPath(tmpFile, "$.spec.template.spec.containers[0].image")
+	if err != nil {
+		t.Fatalf("Failed to get value at path: %v", err)
+	}
+	if sidecar != "envoyproxy/envoy:v1.29.0" {
+		t.Errorf("Expected the sidecar image to be untouched, got %v", sidecar)
+	}
+
+	// A filter matching no container fails the update
+	if _, err := updater.UpdateYAMLPath(tmpFile, `$.spec.template.spec.containers[?(@.name=="worker")].image`, "v1.1.0", true, ""); err == nil {
+		t.Error("Expected error for a filter matching no elements")
+	}
+}
+
+func TestUpdater_UpdateJSONPath_JSONPathFilter(t *testing.T) {
+	updater := NewUpdater().With(WithPathSyntax(PathSyntaxJSONPath))
+
+	jsonContent := `{
+  "params": [
+    {"name": "replicas", "value": 2},
+    {"name": "image", "value": "my-app:v1.0.0"}
+  ]
+}
+`
+
+	tmpFile := filepath.Join(t.TempDir(), "params.json")
+	if err := os.WriteFile(tmpFile, []byte(jsonContent), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if _, err := updater.UpdateJSONPath(tmpFile, `$.params[?(@.name=='image')].value`, "v1.1.0", true, ""); err != nil {
+		t.Fatalf("Failed to update JSON path: %v", err)
+	}
+
+	data, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	expected := `{
+  "params": [
+    {
+      "name": "replicas",
+      "value": 2
+    },
+    {
+      "name": "image",
+      "value": "my-app:v1.1.0"
+    }
+  ]
+}
+`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, data)
+	}
+}
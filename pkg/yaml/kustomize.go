@@ -0,0 +1,96 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UpdateKustomizeImage sets the tag of the entry with the given name in the images list of
+// a kustomization.yaml, keeping its newName. When newValue is a digest (e.g. "sha256:..."),
+// the digest of the entry is set instead and its newTag removed, and vice versa. An entry
+// is added when none has the name. It reports whether the file changed.
+func (u *Updater) UpdateKustomizeImage(filePath, name, newValue string) (bool, error) {
+	if name == "" {
+		return false, fmt.Errorf("an image name is required to update a Kustomization in file %s", filePath)
+	}
+
+	// Read the file
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	// Parse YAML
+	var yamlData interface{}
+	if err := yaml.Unmarshal(data, &yamlData); err != nil {
+		return false, fmt.Errorf("failed to parse YAML in file %s: %w", filePath, err)
+	}
+
+	// Serialize the document as parsed to tell whether the update changes it
+	before, err := yaml.Marshal(yamlData)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal YAML for file %s: %w", filePath, err)
+	}
+
+	kustomization, ok := yamlData.(map[string]interface{})
+	if !ok {
+		return false, fmt.Errorf("kustomization in file %s is not a map: %T", filePath, yamlData)
+	}
+
+	images, ok := kustomization["images"].([]interface{})
+	if !ok && kustomization["images"] != nil {
+		return false, fmt.Errorf("images in file %s is not a list: %T", filePath, kustomization["images"])
+	}
+
+	// Update every entry with the name, adding one if there is none
+	updated := 0
+	for _, item := range images {
+		image, ok := item.(map[string]interface{})
+		if !ok || image["name"] != name {
+			continue
+		}
+
+		setKustomizationImageTag(image, newValue)
+		updated++
+	}
+
+	if updated == 0 {
+		image := map[string]interface{}{"name": name}
+		setKustomizationImageTag(image, newValue)
+		kustomization["images"] = append(images, image)
+	}
+
+	// Write back to file if the value changed, making sure the result still parses
+	return u.writeChanged(filePath, data, before, yamlData)
+}
+
+// setKustomizationImageTag sets the newTag of a Kustomization image entry, or its digest
+// when newValue is a digest
+func setKustomizationImageTag(image map[string]interface{}, newValue string) {
+	if isDigest(newValue) {
+		image["digest"] = newValue
+		delete(image, "newTag")
+		return
+	}
+
+	image["newTag"] = newValue
+	delete(image, "digest")
+}
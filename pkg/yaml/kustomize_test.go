@@ -0,0 +1,167 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUpdater_UpdateKustomizeImage(t *testing.T) {
+	kustomization := `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- deployment.yaml
+images:
+- name: my-app
+  newName: registry.example.com:5000/team/my-app
+  newTag: "1.20"
+- name: sidecar
+  digest: sha256:abc
+`
+	digest := "sha256:" + strings.Repeat("b", 64)
+
+	tests := []struct {
+		name      string
+		content   string
+		imageName string
+		newValue  string
+		expected  []interface{}
+		modified  bool
+		shouldErr bool
+	}{
+		{
+			name:      "existing entry keeps its new name",
+			content:   kustomization,
+			imageName: "my-app",
+			newValue:  "1.21",
+			expected: []interface{}{
+				map[string]interface{}{"name": "my-app", "newName": "registry.example.com:5000/team/my-app", "newTag": "1.21"},
+				map[string]interface{}{"name": "sidecar", "digest": "sha256:abc"},
+			},
+			modified: true,
+		},
+		{
+			name:      "existing entry already up to date",
+			content:   kustomization,
+			imageName: "my-app",
+			newValue:  "1.20",
+			expected: []interface{}{
+				map[string]interface{}{"name": "my-app", "newName": "registry.example.com:5000/team/my-app", "newTag": "1.20"},
+				map[string]interface{}{"name": "sidecar", "digest": "sha256:abc"},
+			},
+		},
+		{
+			name:      "digest replaces the tag",
+			content:   kustomization,
+			imageName: "my-app",
+			newValue:  digest,
+			expected: []interface{}{
+				map[string]interface{}{"name": "my-app", "newName": "registry.example.com:5000/team/my-app", "digest": digest},
+				map[string]interface{}{"name": "sidecar", "digest": "sha256:abc"},
+			},
+			modified: true,
+		},
+		{
+			name:      "tag replaces the digest",
+			content:   kustomization,
+			imageName: "sidecar",
+			newValue:  "2.1",
+			expected: []interface{}{
+				map[string]interface{}{"name": "my-app", "newName": "registry.example.com:5000/team/my-app", "newTag": "1.20"},
+				map[string]interface{}{"name": "sidecar", "newTag": "2.1"},
+			},
+			modified: true,
+		},
+		{
+			name:      "missing entry is added",
+			content:   kustomization,
+			imageName: "worker",
+			newValue:  "3.0",
+			expected: []interface{}{
+				map[string]interface{}{"name": "my-app", "newName": "registry.example.com:5000/team/my-app", "newTag": "1.20"},
+				map[string]interface{}{"name": "sidecar", "digest": "sha256:abc"},
+				map[string]interface{}{"name": "worker", "newTag": "3.0"},
+			},
+			modified: true,
+		},
+		{
+			name:      "missing images list is added",
+			content:   "kind: Kustomization\nresources:\n- deployment.yaml\n",
+			imageName: "my-app",
+			newValue:  "1.21",
+			expected: []interface{}{
+				map[string]interface{}{"name": "my-app", "newTag": "1.21"},
+			},
+			modified: true,
+		},
+		{
+			name:      "images is not a list",
+			content:   "kind: Kustomization\nimages: my-app\n",
+			imageName: "my-app",
+			newValue:  "1.21",
+			shouldErr: true,
+		},
+		{
+			name:      "missing image name",
+			content:   kustomization,
+			newValue:  "1.21",
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updater := NewUpdater()
+
+			tmpFile := filepath.Join(t.TempDir(), "kustomization.yaml")
+			if err := os.WriteFile(tmpFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			modified, err := updater.UpdateKustomizeImage(tmpFile, tt.imageName, tt.newValue)
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to update Kustomization: %v", err)
+			}
+			if modified != tt.modified {
+				t.Errorf("Expected modified %v, got %v", tt.modified, modified)
+			}
+
+			images, err := updater.GetValueAtPath(tmpFile, "images")
+			if err != nil {
+				t.Fatalf("Failed to get value at path: %v", err)
+			}
+			if !reflect.DeepEqual(images, tt.expected) {
+				t.Errorf("Expected images %v, got %v", tt.expected, images)
+			}
+
+			// The rest of the kustomization is untouched
+			if kind, err := updater.GetValueAtPath(tmpFile, "kind"); err != nil || kind != "Kustomization" {
+				t.Errorf("Expected kind Kustomization, got %v (%v)", kind, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"reflect"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// marshalUpdated serializes updated, the document decoded from original and then
+// updated, by applying the changes to the nodes parsed from original. Comments, key
+// order, anchors and aliases are kept: a value changed through an alias is changed on
+// the anchored node, so every alias of it follows.
+func marshalUpdated(original []byte, updated interface{}) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(original, &document); err != nil {
+		return nil, err
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		return yaml.Marshal(updated)
+	}
+
+	var before interface{}
+	if err := document.Decode(&before); err != nil {
+		return nil, err
+	}
+	if err := applyChanges(document.Content[0], before, updated); err != nil {
+		return nil, err
+	}
+	untagMergeKeys(&document)
+	return yaml.Marshal(&document)
+}
+
+// untagMergeKeys clears the tag of merge keys ("<<"), which the encoder would otherwise
+// write as "!!merge <<"
+func untagMergeKeys(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!merge" {
+		node.Tag = ""
+	}
+	for _, child := range node.Content {
+		untagMergeKeys(child)
+	}
+}
+
+// applyChanges changes node, which decodes to before, so it decodes to after
+func applyChanges(node *yaml.Node, before, after interface{}) error {
+	if reflect.DeepEqual(before, after) {
+		return nil
+	}
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch after := after.(type) {
+	case map[string]interface{}:
+		before, ok := before.(map[string]interface{})
+		if !ok || node.Kind != yaml.MappingNode {
+			break
+		}
+
+		keys := make([]string, 0, len(after))
+		for key := range after {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value, existed := before[key]
+			if existed && reflect.DeepEqual(value, after[key]) {
+				continue
+			}
+
+			// Keys merged from another mapping ("<<") are overridden in this one
+			valueNode := mappingValue(node, key)
+			if valueNode == nil {
+				keyNode, newNode := &yaml.Node{}, &yaml.Node{}
+				if err := keyNode.Encode(key); err != nil {
+					return err
+				}
+				if err := newNode.Encode(after[key]); err != nil {
+					return err
+				}
+				node.Content = append(node.Content, keyNode, newNode)
+				continue
+			}
+			if err := applyChanges(valueNode, value, after[key]); err != nil {
+				return err
+			}
+		}
+
+		// Remove deleted keys
+		for key := range before {
+			if _, exists := after[key]; !exists {
+				removeMappingKey(node, key)
+			}
+		}
+		return nil
+
+	case []interface{}:
+		before, ok := before.([]interface{})
+		if !ok || node.Kind != yaml.SequenceNode || len(before) != len(after) || len(node.Content) != len(after) {
+			break
+		}
+		for i := range after {
+			if err := applyChanges(node.Content[i], before[i], after[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case string:
+		// Keep the style of the scalar; the encoder quotes strings that would not
+		// read back as strings
+		if node.Kind == yaml.ScalarNode {
+			node.Tag = "!!str"
+			node.Value = after
+			return nil
+		}
+	}
+
+	return replaceNode(node, after)
+}
+
+// mappingValue returns the value node of a key of a mapping node, or nil when the mapping
+// does not hold the key itself
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Kind == yaml.ScalarNode && node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// removeMappingKey removes a key and its value from a mapping node
+func removeMappingKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Kind == yaml.ScalarNode && node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// replaceNode replaces the content of node with value, keeping its anchor and comments
+func replaceNode(node *yaml.Node, value interface{}) error {
+	var replacement yaml.Node
+	if err := replacement.Encode(value); err != nil {
+		return err
+	}
+
+	replacement.Anchor = node.Anchor
+	replacement.HeadComment = node.HeadComment
+	replacement.LineComment = node.LineComment
+	replacement.FootComment = node.FootComment
+	*node = replacement
+	return nil
+}
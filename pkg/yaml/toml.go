@@ -0,0 +1,343 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// tomlBareKey matches a TOML key that does not need quotes
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlEntry is a table header or key/value line of a TOML file
+type tomlEntry struct {
+	index int
+	// key is the table of a header, and the full key of a key/value line including its table
+	key    []string
+	header bool
+	// arrayTable marks the headers of arrays of tables, which end the previous table
+	arrayTable bool
+	// start and end delimit a single-line string value in the line, including its quotes;
+	// end is 0 for values of other types
+	start, end int
+	literal    bool
+	value      string
+}
+
+// UpdateTOMLPath updates the string at a key path in a TOML file, e.g. "image.tag" for
+// the tag key of the [image] table or the dotted key image.tag. Only the value is
+// replaced, keeping its quotes, comments and all other lines. A missing key is added at
+// the end of its table, which must exist, unless WithExistingPathsOnly is set. Arrays of
+// tables and multi-line strings are not supported. When expectedValuePattern is set, the
+// current value must match it. It reports whether the value changed.
+func (u *Updater) UpdateTOMLPath(filePath, path, newValue string, imageTagOnly bool, expectedValuePattern string) (bool, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	parts, err := u.splitPath(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to update TOML path %s in file %s: %w", path, filePath, err)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	entries, err := parseTOMLEntries(lines)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse TOML in file %s: %w", filePath, err)
+	}
+
+	var matches []tomlEntry
+	for _, entry := range entries {
+		if entry.header || !slices.Equal(entry.key, parts) {
+			continue
+		}
+		if entry.end == 0 {
+			return false, fmt.Errorf("failed to update TOML path %s in file %s: value on line %d is not a single-line string",
+				path, filePath, entry.index+1)
+		}
+		matches = append(matches, entry)
+	}
+
+	current := make([]string, len(matches))
+	for i, match := range matches {
+		current[i] = match.value
+	}
+	if len(matches) == 0 && expectedValuePattern != "" {
+		return false, fmt.Errorf("failed to validate TOML path %s in file %s: %w: key '%s' not found: %w",
+			path, filePath, ErrUnexpectedValue, path, ErrPathNotFound)
+	}
+	if err := checkExpectedValues(current, expectedValuePattern); err != nil {
+		return false, fmt.Errorf("failed to validate TOML path %s in file %s: %w", path, filePath, err)
+	}
+
+	if len(matches) == 0 {
+		if u.existingPathsOnly {
+			return false, fmt.Errorf("failed to update TOML path %s in file %s: key '%s' not found: %w", path, filePath, path, ErrPathNotFound)
+		}
+		if lines, err = insertTOMLKey(lines, entries, parts, newValue, bytes.Contains(data, []byte("\r\n"))); err != nil {
+			return false, fmt.Errorf("failed to update TOML path %s in file %s: %w", path, filePath, err)
+		}
+	}
+
+	for _, match := range matches {
+		value := newValue
+		if imageTagOnly {
+			value = u.updateImageReference(match.value, newValue)
+		}
+		line := lines[match.index]
+		lines[match.index] = line[:match.start] + quoteTOMLString(value, match.literal) + line[match.end:]
+	}
+
+	updated := []byte(strings.Join(lines, "\n"))
+	if bytes.Equal(updated, data) {
+		return false, nil
+	}
+	if err := u.writeFile(filePath, updated); err != nil {
+		return false, fmt.Errorf("failed to write updated TOML to file %s: %w", filePath, err)
+	}
+	return true, nil
+}
+
+// parseTOMLEntries returns the table headers and key/value lines of a TOML file. Keys of
+// arrays of tables are not returned, and lines continuing multi-line strings and arrays
+// are skipped.
+func parseTOMLEntries(lines []string) ([]tomlEntry, error) {
+	var entries []tomlEntry
+	var table []string
+	inArrayTable := false
+	multiline := ""
+	arrayDepth := 0
+
+	for i, line := range lines {
+		// Skip the continuation of a multi-line string or array
+		if multiline != "" {
+			if strings.Contains(line, multiline) {
+				multiline = ""
+			}
+			continue
+		}
+		if arrayDepth > 0 {
+			arrayDepth += bracketDepth(line)
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+
+		case strings.HasPrefix(trimmed, "[["):
+			inArrayTable = true
+			entries = append(entries, tomlEntry{index: i, header: true, arrayTable: true})
+			continue
+
+		case strings.HasPrefix(trimmed, "["):
+			end := strings.Index(trimmed, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated table header", i+1)
+			}
+			key, err := parseTOMLKey(trimmed[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			table = key
+			inArrayTable = false
+			entries = append(entries, tomlEntry{index: i, key: key, header: true})
+			continue
+		}
+
+		eq := keyEnd(line)
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected a key/value pair", i+1)
+		}
+		key, err := parseTOMLKey(line[:eq])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		value := strings.TrimLeft(line[eq+1:], " \t")
+		entry := tomlEntry{index: i, key: append(slices.Clone(table), key...), start: len(line) - len(value)}
+		switch {
+		case strings.HasPrefix(value, `"""`) || strings.HasPrefix(value, "'''"):
+			if !strings.Contains(value[3:], value[:3]) {
+				multiline = value[:3]
+			}
+		case strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'"):
+			end := closingQuote(value, value[0])
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated string", i+1)
+			}
+			entry.end = entry.start + end + 1
+			entry.literal = value[0] == '\''
+			entry.value = value[1:end]
+			if !entry.literal {
+				if unquoted, err := strconv.Unquote(value[:end+1]); err == nil {
+					entry.value = unquoted
+				}
+			}
+		case strings.HasPrefix(value, "["):
+			arrayDepth = bracketDepth(value)
+		}
+
+		if !inArrayTable {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// keyEnd returns the index of the "=" separating the key of a key/value line from its
+// value, outside of quoted keys, or -1
+func keyEnd(line string) int {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"', '\'':
+			end := closingQuote(line[i:], line[i])
+			if end < 0 {
+				return -1
+			}
+			i += end
+		case '=':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseTOMLKey splits a bare, quoted or dotted key into its parts
+func parseTOMLKey(key string) ([]string, error) {
+	var parts []string
+	rest := strings.TrimSpace(key)
+	for {
+		var part string
+		switch {
+		case strings.HasPrefix(rest, `"`) || strings.HasPrefix(rest, "'"):
+			end := closingQuote(rest, rest[0])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted key %s", key)
+			}
+			part = rest[1:end]
+			if rest[0] == '"' {
+				unquoted, err := strconv.Unquote(rest[:end+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid quoted key %s: %w", key, err)
+				}
+				part = unquoted
+			}
+			rest = strings.TrimSpace(rest[end+1:])
+		default:
+			end := strings.Index(rest, ".")
+			if end < 0 {
+				end = len(rest)
+			}
+			part = strings.TrimSpace(rest[:end])
+			if !tomlBareKey.MatchString(part) {
+				return nil, fmt.Errorf("invalid key %s", key)
+			}
+			rest = rest[end:]
+		}
+		parts = append(parts, part)
+
+		if rest == "" {
+			return parts, nil
+		}
+		if !strings.HasPrefix(rest, ".") {
+			return nil, fmt.Errorf("invalid key %s", key)
+		}
+		rest = strings.TrimSpace(rest[1:])
+	}
+}
+
+// bracketDepth returns the number of opening minus closing brackets of a line outside of
+// strings and comments
+func bracketDepth(line string) int {
+	depth := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"', '\'':
+			end := closingQuote(line[i:], line[i])
+			if end < 0 {
+				return depth
+			}
+			i += end
+		case '#':
+			return depth
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+	}
+	return depth
+}
+
+// insertTOMLKey adds a key/value line for the key path after the last line of its table,
+// the root table for a single key
+func insertTOMLKey(lines []string, entries []tomlEntry, parts []string, value string, crlf bool) ([]string, error) {
+	table := parts[:len(parts)-1]
+	start, end := 0, len(lines)
+	found := len(table) == 0
+	for _, entry := range entries {
+		if !entry.header {
+			continue
+		}
+		if found {
+			end = entry.index
+			break
+		}
+		if !entry.arrayTable && slices.Equal(entry.key, table) {
+			start = entry.index + 1
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("table [%s] not found: %w", strings.Join(table, "."), ErrPathNotFound)
+	}
+
+	// Insert after the last non-blank line of the table
+	at := end
+	for at > start && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+
+	key := parts[len(parts)-1]
+	if !tomlBareKey.MatchString(key) {
+		key = quoteTOMLString(key, false)
+	}
+	line := key + " = " + quoteTOMLString(value, false)
+	if crlf {
+		line += "\r"
+	}
+	return slices.Insert(lines, at, line), nil
+}
+
+// quoteTOMLString formats a string value as a literal string when the value it replaces
+// is one and the value has no single quotes, and as a basic string otherwise
+func quoteTOMLString(value string, literal bool) string {
+	if literal && !strings.ContainsAny(value, "'\n") {
+		return "'" + value + "'"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
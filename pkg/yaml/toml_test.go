@@ -0,0 +1,289 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yaml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdater_UpdateTOMLPath(t *testing.T) {
+	content := `# Deployment settings
+title = "my-app"
+
+[image]
+repository = "registry.example.com/app"
+tag = "v1.0.0" # bumped by yuk
+reference = 'registry.example.com/app:v1.0.0'
+args = [
+  "tag = v0.9.0",
+]
+
+[worker]
+image.tag = "v1.0.0"
+replicas = 2
+
+[[sidecars]]
+tag = "v1.0.0"
+`
+
+	tests := []struct {
+		name         string
+		path         string
+		imageTagOnly bool
+		options      []Option
+		expected     string
+	}{
+		{
+			name: "table key",
+			path: "image.tag",
+			expected: `# Deployment settings
+title = "my-app"
+
+[image]
+repository = "registry.example.com/app"
+tag = "v1.1.0" # bumped by yuk
+reference = 'registry.example.com/app:v1.0.0'
+args = [
+  "tag = v0.9.0",
+]
+
+[worker]
+image.tag = "v1.0.0"
+replicas = 2
+
+[[sidecars]]
+tag = "v1.0.0"
+`,
+		},
+		{
+			name:         "literal string image tag",
+			path:         "image.reference",
+			imageTagOnly: true,
+			expected: `# Deployment settings
+title = "my-app"
+
+[image]
+repository = "registry.example.com/app"
+tag = "v1.0.0" # bumped by yuk
+reference = 'registry.example.com/app:v1.1.0'
+args = [
+  "tag = v0.9.0",
+]
+
+[worker]
+image.tag = "v1.0.0"
+replicas = 2
+
+[[sidecars]]
+tag = "v1.0.0"
+`,
+		},
+		{
+			name: "dotted key in table",
+			path: "worker.image.tag",
+			expected: `# Deployment settings
+title = "my-app"
+
+[image]
+repository = "registry.example.com/app"
+tag = "v1.0.0" # bumped by yuk
+reference = 'registry.example.com/app:v1.0.0'
+args = [
+  "tag = v0.9.0",
+]
+
+[worker]
+image.tag = "v1.1.0"
+replicas = 2
+
+[[sidecars]]
+tag = "v1.0.0"
+`,
+		},
+		{
+			name: "missing key added to its table",
+			path: "worker.version",
+			expected: `# Deployment settings
+title = "my-app"
+
+[image]
+repository = "registry.example.com/app"
+tag = "v1.0.0" # bumped by yuk
+reference = 'registry.example.com/app:v1.0.0'
+args = [
+  "tag = v0.9.0",
+]
+
+[worker]
+image.tag = "v1.0.0"
+replicas = 2
+version = "v1.1.0"
+
+[[sidecars]]
+tag = "v1.0.0"
+`,
+		},
+		{
+			name: "missing root key",
+			path: "version",
+			expected: `# Deployment settings
+title = "my-app"
+version = "v1.1.0"
+
+[image]
+repository = "registry.example.com/app"
+tag = "v1.0.0" # bumped by yuk
+reference = 'registry.example.com/app:v1.0.0'
+args = [
+  "tag = v0.9.0",
+]
+
+[worker]
+image.tag = "v1.0.0"
+replicas = 2
+
+[[sidecars]]
+tag = "v1.0.0"
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "app.toml")
+			if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			modified, err := NewUpdater(tt.options...).UpdateTOMLPath(filePath, tt.path, "v1.1.0", tt.imageTagOnly, "")
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if !modified {
+				t.Error("Expected the file to be modified")
+			}
+
+			updated, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatalf("Failed to read updated file: %v", err)
+			}
+			if string(updated) != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, updated)
+			}
+		})
+	}
+}
+
+func TestUpdater_UpdateTOMLPath_Unchanged(t *testing.T) {
+	content := "[image]\ntag   =   \"v1.0.0\"\n"
+
+	filePath := filepath.Join(t.TempDir(), "app.toml")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	modified, err := NewUpdater().UpdateTOMLPath(filePath, "image.tag", "v1.0.0", false, "")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if modified {
+		t.Error("Expected an unchanged value to report no modification")
+	}
+}
+
+func TestUpdater_UpdateTOMLPath_Errors(t *testing.T) {
+	tests := []struct {
+		name                 string
+		content              string
+		path                 string
+		options              []Option
+		expectedValuePattern string
+		expectedErr          error
+	}{
+		{
+			name:    "not a string",
+			content: "[image]\nreplicas = 3\n",
+			path:    "image.replicas",
+		},
+		{
+			name:    "multi-line string",
+			content: "[image]\ntag = \"\"\"\nv1.0.0\n\"\"\"\n",
+			path:    "image.tag",
+		},
+		{
+			name:        "missing table",
+			content:     "[image]\ntag = \"v1.0.0\"\n",
+			path:        "worker.tag",
+			expectedErr: ErrPathNotFound,
+		},
+		{
+			name:        "missing key with existing paths only",
+			content:     "[image]\ntag = \"v1.0.0\"\n",
+			path:        "image.digest",
+			options:     []Option{WithExistingPathsOnly(true)},
+			expectedErr: ErrPathNotFound,
+		},
+		{
+			name:        "array of tables",
+			content:     "[[image]]\ntag = \"v1.0.0\"\n",
+			path:        "image.tag",
+			options:     []Option{WithExistingPathsOnly(true)},
+			expectedErr: ErrPathNotFound,
+		},
+		{
+			name:                 "unexpected current value",
+			content:              "[image]\ntag = \"latest\"\n",
+			path:                 "image.tag",
+			expectedValuePattern: `^v\d+`,
+			expectedErr:          ErrUnexpectedValue,
+		},
+		{
+			name:    "invalid TOML",
+			content: "[image\ntag = \"v1.0.0\"\n",
+			path:    "image.tag",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "app.toml")
+			if err := os.WriteFile(filePath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			_, err := NewUpdater(tt.options...).UpdateTOMLPath(filePath, tt.path, "v1.1.0", false, tt.expectedValuePattern)
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			if tt.expectedErr != nil && !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Expected %v, got: %v", tt.expectedErr, err)
+			}
+
+			// The file is left unchanged
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatalf("Failed to read file: %v", err)
+			}
+			if string(content) != tt.content {
+				t.Errorf("Expected file to be unchanged, got:\n%s", content)
+			}
+		})
+	}
+}
@@ -17,82 +17,389 @@ limitations under the License.
 package yaml
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
-// Updater provides functionality to update YAML files
-type Updater struct{}
+// ErrUpdateProducedInvalidYAML is returned when an updated file no longer parses as YAML.
+// The original file content is restored before it is returned.
+var ErrUpdateProducedInvalidYAML = errors.New("update produced invalid YAML")
+
+// wildcard is the path part produced by a [*] index or a * key, selecting every element of
+// an array or every value of a map
+const wildcard = "*"
+
+// ErrUnexpectedValue is returned when the current value at a YAML path does not match the
+// expected value pattern. The file is left unchanged.
+var ErrUnexpectedValue = errors.New("unexpected current value")
+
+// ErrPathNotFound is returned when a path, or the entry a mode updates, does not exist in
+// a file. Missing last keys of a path are only an error with WithExistingPathsOnly.
+var ErrPathNotFound = errors.New("path not found")
+
+// DefaultFileMode is the mode of files created by the updater. Existing files keep their mode.
+const DefaultFileMode os.FileMode = 0644
+
+// Updater provides functionality to update YAML, JSON, TOML and env files
+type Updater struct {
+	jsonIndent string
+	pathSyntax string
+	fileMode   os.FileMode
+
+	existingPathsOnly bool
+}
+
+// Option configures optional behavior of an Updater
+type Option func(*Updater)
+
+// WithJSONIndent sets the indentation of JSON files written by the updater
+// (default: DefaultJSONIndent)
+func WithJSONIndent(indent string) Option {
+	return func(u *Updater) {
+		u.jsonIndent = indent
+	}
+}
+
+// WithFileMode sets the mode of files created by the updater (default: DefaultFileMode).
+// Updated files keep their mode, e.g. 0600 for sensitive manifests.
+func WithFileMode(mode os.FileMode) Option {
+	return func(u *Updater) {
+		u.fileMode = mode
+	}
+}
+
+// WithExistingPathsOnly fails updates with ErrPathNotFound when the last key of a path is
+// missing, instead of adding it
+func WithExistingPathsOnly(existingPathsOnly bool) Option {
+	return func(u *Updater) {
+		u.existingPathsOnly = existingPathsOnly
+	}
+}
 
 // NewUpdater creates a new YAML updater
-func NewUpdater() *Updater {
-	return &Updater{}
+func NewUpdater(opts ...Option) *Updater {
+	u := &Updater{
+		jsonIndent: DefaultJSONIndent,
+		fileMode:   DefaultFileMode,
+	}
+
+	for _, opt := range opts {
+		opt(u)
+	}
+
+	return u
 }
 
-// UpdateYAMLPath updates a specific path in a YAML file with a new value
-func (u *Updater) UpdateYAMLPath(filePath, yamlPath, newValue string, imageTagOnly bool) error {
+// UpdateYAMLPath updates a specific path in a YAML file with a new value. When
+// expectedValuePattern is set, the current value must match it before it is replaced.
+// It reports whether the value changed; a file already holding the value is not rewritten.
+func (u *Updater) UpdateYAMLPath(filePath, yamlPath, newValue string, imageTagOnly bool, expectedValuePattern string) (bool, error) {
 	// Read the file
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read file %s: %w", filePath, err)
+		return false, fmt.Errorf("failed to read file %s: %w", filePath, err)
 	}
 
 	// Parse YAML
 	var yamlData interface{}
 	if err := yaml.Unmarshal(data, &yamlData); err != nil {
-		return fmt.Errorf("failed to parse YAML in file %s: %w", filePath, err)
+		return false, fmt.Errorf("failed to parse YAML in file %s: %w", filePath, err)
+	}
+
+	// Serialize the document as parsed to tell whether the update changes it
+	before, err := yaml.Marshal(yamlData)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal YAML for file %s: %w", filePath, err)
+	}
+
+	// Make sure the path still points at the expected value
+	if err := u.checkValueAtPath(yamlData, yamlPath, expectedValuePattern); err != nil {
+		return false, fmt.Errorf("failed to validate YAML path %s in file %s: %w", yamlPath, filePath, err)
 	}
 
 	// Update the value at the specified path
 	if err := u.updateValueAtPath(yamlData, yamlPath, newValue, imageTagOnly); err != nil {
-		return fmt.Errorf("failed to update YAML path %s in file %s: %w", yamlPath, filePath, err)
+		return false, fmt.Errorf("failed to update YAML path %s in file %s: %w", yamlPath, filePath, err)
 	}
 
-	// Marshal back to YAML
+	// Write back to file if the value changed, making sure the result still parses
+	return u.writeChanged(filePath, data, before, yamlData)
+}
+
+// UpdateNestedYAMLPath updates a path inside a YAML document that is embedded as a
+// string scalar (e.g. a ConfigMap data key). yamlPath selects the string scalar and
+// nestedPath is applied to the document parsed from it. The embedded document is
+// re-serialized back into the scalar, which is written using literal block style.
+// When expectedValuePattern is set, the current value at nestedPath must match it. Like
+// UpdateYAMLPath, it reports whether the value changed.
+func (u *Updater) UpdateNestedYAMLPath(filePath, yamlPath, nestedPath, newValue string, imageTagOnly bool, expectedValuePattern string) (bool, error) {
+	// Read the file
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	// Parse YAML
+	var yamlData interface{}
+	if err := yaml.Unmarshal(data, &yamlData); err != nil {
+		return false, fmt.Errorf("failed to parse YAML in file %s: %w", filePath, err)
+	}
+
+	// Serialize the document as parsed to tell whether the update changes it
+	before, err := yaml.Marshal(yamlData)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal YAML for file %s: %w", filePath, err)
+	}
+
+	// Locate the embedded document
+	embedded, err := u.getValueAtPath(yamlData, yamlPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to get YAML path %s in file %s: %w", yamlPath, filePath, err)
+	}
+
+	embeddedStr, ok := embedded.(string)
+	if !ok {
+		return false, fmt.Errorf("value at YAML path %s in file %s is not a string: %T", yamlPath, filePath, embedded)
+	}
+
+	// Parse and update the embedded document
+	var nestedData interface{}
+	if err := yaml.Unmarshal([]byte(embeddedStr), &nestedData); err != nil {
+		return false, fmt.Errorf("failed to parse embedded YAML at path %s in file %s: %w", yamlPath, filePath, err)
+	}
+
+	if err := u.checkValueAtPath(nestedData, nestedPath, expectedValuePattern); err != nil {
+		return false, fmt.Errorf("failed to validate nested YAML path %s at %s in file %s: %w", nestedPath, yamlPath, filePath, err)
+	}
+
+	if err := u.updateValueAtPath(nestedData, nestedPath, newValue, imageTagOnly); err != nil {
+		return false, fmt.Errorf("failed to update nested YAML path %s at %s in file %s: %w", nestedPath, yamlPath, filePath, err)
+	}
+
+	updatedNested, err := marshalUpdated([]byte(embeddedStr), nestedData)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal embedded YAML at path %s in file %s: %w", yamlPath, filePath, err)
+	}
+
+	// Write the embedded document back into the scalar. yaml.v3 emits multi-line
+	// strings in literal block style, so the scalar keeps its "|" form.
+	if err := u.updateValueAtPath(yamlData, yamlPath, string(updatedNested), false); err != nil {
+		return false, fmt.Errorf("failed to update YAML path %s in file %s: %w", yamlPath, filePath, err)
+	}
+
+	// Write back to file if the value changed, making sure the result still parses
+	return u.writeChanged(filePath, data, before, yamlData)
+}
+
+// writeChanged marshals the updated document and writes it unless it is unchanged from
+// before, the document as marshaled before the update, so files already holding the value
+// keep their formatting. The changes are applied to the nodes of the original file, keeping
+// its anchors and aliases. It reports whether the file was written.
+func (u *Updater) writeChanged(filePath string, original, before []byte, yamlData interface{}) (bool, error) {
 	updatedData, err := yaml.Marshal(yamlData)
 	if err != nil {
-		return fmt.Errorf("failed to marshal updated YAML for file %s: %w", filePath, err)
+		return false, fmt.Errorf("failed to marshal updated YAML for file %s: %w", filePath, err)
+	}
+
+	if bytes.Equal(before, updatedData) {
+		return false, nil
+	}
+
+	if updatedData, err = marshalUpdated(original, yamlData); err != nil {
+		return false, fmt.Errorf("failed to marshal updated YAML for file %s: %w", filePath, err)
 	}
 
-	// Write back to file
-	if err := os.WriteFile(filePath, updatedData, 0644); err != nil {
+	if err := u.writeVerified(filePath, original, updatedData); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// writeVerified writes the updated content and reads it back to verify it still parses.
+// If it does not, the original content is restored and ErrUpdateProducedInvalidYAML is returned.
+func (u *Updater) writeVerified(filePath string, original, updated []byte) error {
+	if err := u.writeFile(filePath, updated); err != nil {
 		return fmt.Errorf("failed to write updated YAML to file %s: %w", filePath, err)
 	}
 
+	written, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read back updated file %s: %w", filePath, err)
+	}
+
+	var parsed interface{}
+	if parseErr := yaml.Unmarshal(written, &parsed); parseErr != nil {
+		if err := u.writeFile(filePath, original); err != nil {
+			return fmt.Errorf("failed to restore file %s after invalid update: %w", filePath, err)
+		}
+		return fmt.Errorf("%w in file %s: %v", ErrUpdateProducedInvalidYAML, filePath, parseErr)
+	}
+
 	return nil
 }
 
-// updateValueAtPath updates a value at a specific path in the YAML structure
+// writeFile writes data to a file, keeping the permissions of an existing file. A new
+// file is created with the updater's file mode, regardless of the umask.
+func (u *Updater) writeFile(filePath string, data []byte) error {
+	mode := u.fileMode
+	if info, err := os.Stat(filePath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if err := os.WriteFile(filePath, data, mode); err != nil {
+		return err
+	}
+	return os.Chmod(filePath, mode)
+}
+
+// updateValueAtPath updates a value at a specific path in the YAML structure. A [*]
+// index (or a JSONPath filter) updates the path under every selected element of the array,
+// a * key under every value of the map.
 func (u *Updater) updateValueAtPath(data interface{}, path, newValue string, imageTagOnly bool) error {
-	pathParts := u.parsePath(path)
+	parts, err := u.splitPath(path)
+	if err != nil {
+		return err
+	}
+	return u.updateValueAtParts(data, parts, newValue, imageTagOnly)
+}
 
-	current := data
-	for i, part := range pathParts {
-		if i == len(pathParts)-1 {
-			// Last part - update the value
-			return u.setValue(current, part, newValue, imageTagOnly)
-		}
+// updateValueAtParts updates the value at the remaining path parts below data
+func (u *Updater) updateValueAtParts(data interface{}, parts []string, newValue string, imageTagOnly bool) error {
+	part := parts[0]
 
-		// Navigate to the next level
-		next, err := u.getValue(current, part)
+	// Expand a wildcard or filter into each selected array index or map key
+	if isSelector(part) {
+		indices, err := u.selectIndices(data, part)
 		if err != nil {
-			return fmt.Errorf("failed to navigate to path part '%s': %w", part, err)
+			return err
+		}
+		for _, index := range indices {
+			if err := u.updateValueAtParts(data, append([]string{index}, parts[1:]...), newValue, imageTagOnly); err != nil {
+				return fmt.Errorf("failed to update array index %s: %w", index, err)
+			}
+		}
+		return nil
+	}
+
+	if len(parts) == 1 {
+		// Last part - update the value
+		return u.setValue(data, part, newValue, imageTagOnly)
+	}
+
+	// Navigate to the next level
+	next, err := u.getValue(data, part)
+	if err != nil {
+		return fmt.Errorf("failed to navigate to path part '%s': %w", part, err)
+	}
+	return u.updateValueAtParts(next, parts[1:], newValue, imageTagOnly)
+}
+
+// wildcardKeys returns the indices of an array, or the keys of a map in sorted order, a
+// wildcard expands to. A map key named like the wildcard itself is not expanded.
+func (u *Updater) wildcardKeys(data interface{}) ([]string, error) {
+	var keys []string
+	switch v := data.(type) {
+	case []interface{}:
+		for i := range v {
+			keys = append(keys, strconv.Itoa(i))
+		}
+	case map[string]interface{}:
+		for key := range v {
+			if key != wildcard {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+	default:
+		return nil, fmt.Errorf("cannot expand wildcard in non-map/non-array type: %T", data)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("wildcard matched no elements")
+	}
+	return keys, nil
+}
+
+// checkValueAtPath verifies that the current value at a path matches the expected value
+// pattern. An empty pattern accepts any value, including a missing one.
+func (u *Updater) checkValueAtPath(data interface{}, path, expectedValuePattern string) error {
+	parts, err := u.splitPath(path)
+	if err != nil {
+		return err
+	}
+	return u.checkValueAtParts(data, parts, expectedValuePattern)
+}
+
+// checkValueAtParts verifies that the values at the path parts match expectedValuePattern
+func (u *Updater) checkValueAtParts(data interface{}, parts []string, expectedValuePattern string) error {
+	if expectedValuePattern == "" {
+		return nil
+	}
+
+	pattern, err := regexp.Compile(expectedValuePattern)
+	if err != nil {
+		return fmt.Errorf("invalid expected value pattern %q: %w", expectedValuePattern, err)
+	}
+
+	values, err := u.getValuesAtParts(data, parts)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUnexpectedValue, err)
+	}
+
+	for _, value := range values {
+		var current string
+		switch v := value.(type) {
+		case nil:
+		case map[string]interface{}, []interface{}:
+			return fmt.Errorf("%w: found %T, expected a value matching %q", ErrUnexpectedValue, value, expectedValuePattern)
+		default:
+			current = fmt.Sprint(v)
+		}
+
+		if !pattern.MatchString(current) {
+			return fmt.Errorf("%w: %q does not match %q", ErrUnexpectedValue, current, expectedValuePattern)
 		}
-		current = next
 	}
 
 	return nil
 }
 
-// parsePath parses a YAML path like "spec.template.spec.containers[0].image" into parts
+// parsePath parses a YAML path like "spec.template.spec.containers[0].image" into parts.
+// Keys containing dots or other special characters can be quoted, e.g. data["config.yaml"].
 func (u *Updater) parsePath(path string) []string {
-	// Handle array indices like containers[0]
-	arrayRegex := regexp.MustCompile(`(\w+)\[(\d+)\]`)
+	var parts []string
+
+	// Handle quoted keys like data["config.yaml"] before splitting on dots
+	quotedRegex := regexp.MustCompile(`\["([^"]+)"\]`)
+	for path != "" {
+		loc := quotedRegex.FindStringSubmatchIndex(path)
+		if loc == nil {
+			parts = append(parts, u.parseDottedPath(path)...)
+			break
+		}
+
+		if prefix := strings.TrimSuffix(path[:loc[0]], "."); prefix != "" {
+			parts = append(parts, u.parseDottedPath(prefix)...)
+		}
+		parts = append(parts, path[loc[2]:loc[3]])
+		path = strings.TrimPrefix(path[loc[1]:], ".")
+	}
+
+	return parts
+}
+
+// parseDottedPath parses an unquoted dotted path segment into parts
+func (u *Updater) parseDottedPath(path string) []string {
+	// Handle array indices like containers[0] and wildcards like containers[*]
+	arrayRegex := regexp.MustCompile(`(\w+)\[(\d+|\*)\]`)
 	path = arrayRegex.ReplaceAllString(path, "$1.$2")
 
 	return strings.Split(path, ".")
@@ -105,7 +412,7 @@ func (u *Updater) getValue(data interface{}, key string) (interface{}, error) {
 		if value, exists := v[key]; exists {
 			return value, nil
 		}
-		return nil, fmt.Errorf("key '%s' not found in map", key)
+		return nil, fmt.Errorf("key '%s' not found in map: %w", key, ErrPathNotFound)
 
 	case []interface{}:
 		index, err := strconv.Atoi(key)
@@ -113,7 +420,7 @@ func (u *Updater) getValue(data interface{}, key string) (interface{}, error) {
 			return nil, fmt.Errorf("invalid array index '%s': %w", key, err)
 		}
 		if index < 0 || index >= len(v) {
-			return nil, fmt.Errorf("array index %d out of bounds (length: %d)", index, len(v))
+			return nil, fmt.Errorf("array index %d out of bounds (length: %d): %w", index, len(v), ErrPathNotFound)
 		}
 		return v[index], nil
 
@@ -126,12 +433,15 @@ func (u *Updater) getValue(data interface{}, key string) (interface{}, error) {
 func (u *Updater) setValue(data interface{}, key, newValue string, imageTagOnly bool) error {
 	switch v := data.(type) {
 	case map[string]interface{}:
+		if _, exists := v[key]; !exists && u.existingPathsOnly {
+			return fmt.Errorf("key '%s' not found in map: %w", key, ErrPathNotFound)
+		}
 		if imageTagOnly {
 			// If updating only the tag part of an image reference
 			currentValue, exists := v[key]
 			if exists {
 				if currentStr, ok := currentValue.(string); ok {
-					updatedValue := u.updateImageTag(currentStr, newValue)
+					updatedValue := u.updateImageReference(currentStr, newValue)
 					v[key] = updatedValue
 					return nil
 				}
@@ -146,13 +456,13 @@ func (u *Updater) setValue(data interface{}, key, newValue string, imageTagOnly
 			return fmt.Errorf("invalid array index '%s': %w", key, err)
 		}
 		if index < 0 || index >= len(v) {
-			return fmt.Errorf("array index %d out of bounds (length: %d)", index, len(v))
+			return fmt.Errorf("array index %d out of bounds (length: %d): %w", index, len(v), ErrPathNotFound)
 		}
 
 		if imageTagOnly {
 			// If updating only the tag part of an image reference
 			if currentStr, ok := v[index].(string); ok {
-				updatedValue := u.updateImageTag(currentStr, newValue)
+				updatedValue := u.updateImageReference(currentStr, newValue)
 				v[index] = updatedValue
 				return nil
 			}
@@ -165,32 +475,88 @@ func (u *Updater) setValue(data interface{}, key, newValue string, imageTagOnly
 	}
 }
 
-// updateImageTag updates only the tag portion of a container image reference
+// digestPattern matches an image digest (e.g. "sha256:...")
+var digestPattern = regexp.MustCompile(`^sha(256|512):[a-f0-9]{64,128}$`)
+
+// isDigest reports whether the value is an image digest rather than a tag
+func isDigest(value string) bool {
+	return digestPattern.MatchString(value)
+}
+
+// updateImageReference updates the digest of a container image reference when the new
+// value is a digest, and its tag otherwise
+func (u *Updater) updateImageReference(currentImage, newValue string) string {
+	if isDigest(newValue) {
+		return u.updateImageDigest(currentImage, newValue)
+	}
+	return u.updateImageTag(currentImage, newValue)
+}
+
+// updateImageDigest updates only the digest portion of a container image reference,
+// keeping the repository and an optional tag
+func (u *Updater) updateImageDigest(currentImage, digest string) string {
+	// Handle formats like:
+	// - image@sha256:old -> image@sha256:new
+	// - registry/image:tag@sha256:old -> registry/image:tag@sha256:new
+	// - registry:5000/image:tag -> registry:5000/image:tag@sha256:new
+	// - sha256:old -> sha256:new
+
+	if at := strings.Index(currentImage, "@"); at >= 0 {
+		return currentImage[:at] + "@" + digest
+	}
+
+	// A value holding only a digest (e.g. a chart's image.digest) is replaced
+	if isDigest(currentImage) || currentImage == "" {
+		return digest
+	}
+
+	// If no digest exists, pin the reference by appending it
+	return currentImage + "@" + digest
+}
+
+// updateImageTag updates only the tag portion of a container image reference. A digest
+// pinned to the old tag is dropped, since it would no longer match the new tag
 func (u *Updater) updateImageTag(currentImage, newTag string) string {
 	// Handle formats like:
 	// - image:tag -> image:newTag
 	// - registry/image:tag -> registry/image:newTag
-	// - registry/namespace/image:tag -> registry/namespace/image:newTag
+	// - registry:5000/image -> registry:5000/image:newTag
+	// - registry:5000/image:tag@sha256:old -> registry:5000/image:newTag
+
+	repository, _, _ := SplitImageReference(currentImage)
+	return repository + ":" + newTag
+}
+
+// SplitImageReference splits a container image reference into its repository, tag and
+// digest. A colon only separates a tag when it follows the last path separator, so
+// registry ports (e.g. "registry:5000/image") stay part of the repository
+func SplitImageReference(image string) (repository, tag, digest string) {
+	repository = image
+	if at := strings.Index(repository, "@"); at >= 0 {
+		repository, digest = repository[:at], repository[at+1:]
+	}
 
-	parts := strings.Split(currentImage, ":")
-	if len(parts) >= 2 {
-		// Replace the last part (tag) with the new tag
-		parts[len(parts)-1] = newTag
-		return strings.Join(parts, ":")
+	if colon := strings.LastIndex(repository, ":"); colon > strings.LastIndex(repository, "/") {
+		repository, tag = repository[:colon], repository[colon+1:]
 	}
 
-	// If no tag exists, append it
-	return currentImage + ":" + newTag
+	return repository, tag, digest
 }
 
-// ValidateYAMLPath validates that a YAML path is correctly formatted
+// ValidateYAMLPath validates that a YAML path is correctly formatted in the path syntax of
+// the updater
 func (u *Updater) ValidateYAMLPath(path string) error {
 	if path == "" {
 		return fmt.Errorf("YAML path cannot be empty")
 	}
 
+	if u.pathSyntax != "" && u.pathSyntax != PathSyntaxDotted {
+		_, err := u.splitPath(path)
+		return err
+	}
+
 	// Basic validation - check for valid path format
-	pathRegex := regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*|\[\d+\])*$`)
+	pathRegex := regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*|\*)(\.([a-zA-Z_][a-zA-Z0-9_]*|\*)|\[(\d+|\*)\]|\["[^"]+"\])*$`)
 	if !pathRegex.MatchString(path) {
 		return fmt.Errorf("invalid YAML path format: %s", path)
 	}
@@ -213,16 +579,59 @@ func (u *Updater) GetValueAtPath(filePath, yamlPath string) (interface{}, error)
 	}
 
 	// Navigate to the specified path
-	pathParts := u.parsePath(yamlPath)
-	current := yamlData
+	return u.getValueAtPath(yamlData, yamlPath)
+}
+
+// getValueAtPath retrieves a value at a specific path in the YAML structure. A path
+// with a wildcard (or a JSONPath filter) returns the values under every selected element
+// of the array or value of the map as a list.
+func (u *Updater) getValueAtPath(data interface{}, path string) (interface{}, error) {
+	pathParts, err := u.splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := u.getValuesAtParts(data, pathParts)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, part := range pathParts {
-		next, err := u.getValue(current, part)
+		if isSelector(part) {
+			return values, nil
+		}
+	}
+	return values[0], nil
+}
+
+// getValuesAtParts retrieves the values at the remaining path parts below data,
+// expanding wildcards and filters into every selected array element or map value
+func (u *Updater) getValuesAtParts(data interface{}, parts []string) ([]interface{}, error) {
+	if len(parts) == 0 {
+		return []interface{}{data}, nil
+	}
+
+	part := parts[0]
+	if isSelector(part) {
+		indices, err := u.selectIndices(data, part)
 		if err != nil {
-			return nil, fmt.Errorf("failed to navigate to path part '%s': %w", part, err)
+			return nil, err
 		}
-		current = next
+
+		var values []interface{}
+		for _, index := range indices {
+			indexValues, err := u.getValuesAtParts(data, append([]string{index}, parts[1:]...))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get array index %s: %w", index, err)
+			}
+			values = append(values, indexValues...)
+		}
+		return values, nil
 	}
 
-	return current, nil
+	next, err := u.getValue(data, part)
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate to path part '%s': %w", part, err)
+	}
+	return u.getValuesAtParts(next, parts[1:])
 }
@@ -17,8 +17,11 @@ limitations under the License.
 package yaml
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -52,6 +55,16 @@ func TestUpdater_ParsePath(t *testing.T) {
 			path:     "spec.template.spec.containers[0].image",
 			expected: []string{"spec", "template", "spec", "containers", "0", "image"},
 		},
+		{
+			name:     "path with quoted key",
+			path:     `data["config.yaml"].app`,
+			expected: []string{"data", "config.yaml", "app"},
+		},
+		{
+			name:     "path with wildcard index",
+			path:     "spec.containers[*].image",
+			expected: []string{"spec", "containers", "*", "image"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -103,6 +116,36 @@ func TestUpdater_UpdateImageTag(t *testing.T) {
 			newTag:       "1.21",
 			expected:     "nginx:1.21",
 		},
+		{
+			name:         "registry with port and tag",
+			currentImage: "registry.local:5000/app:1.0",
+			newTag:       "1.1",
+			expected:     "registry.local:5000/app:1.1",
+		},
+		{
+			name:         "registry with port without tag",
+			currentImage: "registry.local:5000/app",
+			newTag:       "1.1",
+			expected:     "registry.local:5000/app:1.1",
+		},
+		{
+			name:         "registry with port and namespace",
+			currentImage: "registry.local:5000/team/app:1.0",
+			newTag:       "1.1",
+			expected:     "registry.local:5000/team/app:1.1",
+		},
+		{
+			name:         "image with tag and digest",
+			currentImage: "registry.local:5000/app:1.0@sha256:" + strings.Repeat("a", 64),
+			newTag:       "1.1",
+			expected:     "registry.local:5000/app:1.1",
+		},
+		{
+			name:         "image with digest only",
+			currentImage: "registry.local:5000/app@sha256:" + strings.Repeat("a", 64),
+			newTag:       "1.1",
+			expected:     "registry.local:5000/app:1.1",
+		},
 	}
 
 	for _, tt := range tests {
@@ -115,6 +158,116 @@ func TestUpdater_UpdateImageTag(t *testing.T) {
 	}
 }
 
+func TestUpdater_UpdateImageDigest(t *testing.T) {
+	updater := NewUpdater()
+
+	oldDigest := "sha256:" + strings.Repeat("a", 64)
+	newDigest := "sha256:" + strings.Repeat("b", 64)
+
+	tests := []struct {
+		name         string
+		currentImage string
+		expected     string
+	}{
+		{
+			name:         "image with digest",
+			currentImage: "nginx@" + oldDigest,
+			expected:     "nginx@" + newDigest,
+		},
+		{
+			name:         "ecr image with digest",
+			currentImage: "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app@" + oldDigest,
+			expected:     "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app@" + newDigest,
+		},
+		{
+			name:         "image with tag and digest",
+			currentImage: "docker.io/nginx:1.20@" + oldDigest,
+			expected:     "docker.io/nginx:1.20@" + newDigest,
+		},
+		{
+			name:         "registry with port and tag",
+			currentImage: "registry:5000/team/app:v1.0.0",
+			expected:     "registry:5000/team/app:v1.0.0@" + newDigest,
+		},
+		{
+			name:         "image without tag",
+			currentImage: "nginx",
+			expected:     "nginx@" + newDigest,
+		},
+		{
+			name:         "digest only",
+			currentImage: oldDigest,
+			expected:     newDigest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := updater.updateImageReference(tt.currentImage, newDigest)
+			if result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSplitImageReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	tests := []struct {
+		image      string
+		repository string
+		tag        string
+		digest     string
+	}{
+		{image: "nginx", repository: "nginx"},
+		{image: "nginx:1.21", repository: "nginx", tag: "1.21"},
+		{image: "registry.local:5000/app", repository: "registry.local:5000/app"},
+		{image: "registry.local:5000/app:1.0", repository: "registry.local:5000/app", tag: "1.0"},
+		{image: "registry.local:5000/app@" + digest, repository: "registry.local:5000/app", digest: digest},
+		{image: "registry.local:5000/app:1.0@" + digest, repository: "registry.local:5000/app", tag: "1.0", digest: digest},
+		{image: "localhost:5000/team/app:v2", repository: "localhost:5000/team/app", tag: "v2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			repository, tag, digest := SplitImageReference(tt.image)
+			if repository != tt.repository || tag != tt.tag || digest != tt.digest {
+				t.Errorf("Expected (%q, %q, %q), got (%q, %q, %q)", tt.repository, tt.tag, tt.digest, repository, tag, digest)
+			}
+		})
+	}
+}
+
+func TestUpdater_UpdateYAMLPath_Digest(t *testing.T) {
+	updater := NewUpdater()
+	newDigest := "sha256:" + strings.Repeat("c", 64)
+
+	filePath := filepath.Join(t.TempDir(), "values.yaml")
+	content := "image:\n    digest: sha256:" + strings.Repeat("a", 64) + "\n    repository: registry.example.com/my-app\n" +
+		"sidecar: registry.example.com/proxy:v2@sha256:" + strings.Repeat("a", 64) + "\n"
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if _, err := updater.UpdateYAMLPath(filePath, "image.digest", newDigest, false, ""); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := updater.UpdateYAMLPath(filePath, "sidecar", newDigest, true, ""); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	expected := "image:\n    digest: " + newDigest + "\n    repository: registry.example.com/my-app\n" +
+		"sidecar: registry.example.com/proxy:v2@" + newDigest + "\n"
+	updated, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(updated) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, updated)
+	}
+}
+
 func TestUpdater_ValidateYAMLPath(t *testing.T) {
 	updater := NewUpdater()
 
@@ -138,6 +291,26 @@ func TestUpdater_ValidateYAMLPath(t *testing.T) {
 			path:      "",
 			shouldErr: true,
 		},
+		{
+			name:      "valid path with wildcard",
+			path:      "spec.containers[*].image",
+			shouldErr: false,
+		},
+		{
+			name:      "valid path with map wildcard",
+			path:      "services.*.image",
+			shouldErr: false,
+		},
+		{
+			name:      "invalid array index",
+			path:      "spec.containers[x].image",
+			shouldErr: true,
+		},
+		{
+			name:      "valid path with quoted key",
+			path:      `data["config.yaml"]`,
+			shouldErr: false,
+		},
 		{
 			name:      "invalid characters",
 			path:      "spec.template-spec",
@@ -184,7 +357,7 @@ spec:
 	}
 
 	// Test updating the image tag
-	err := updater.UpdateYAMLPath(tmpFile, "spec.template.spec.containers[0].image", "nginx:1.21", false)
+	_, err := updater.UpdateYAMLPath(tmpFile, "spec.template.spec.containers[0].image", "nginx:1.21", false, "")
 	if err != nil {
 		t.Fatalf("Failed to update YAML path: %v", err)
 	}
@@ -200,6 +373,44 @@ spec:
 	}
 }
 
+func TestUpdater_UpdateYAMLPath_Unchanged(t *testing.T) {
+	updater := NewUpdater()
+
+	// Formatting that re-serializing would change
+	yamlContent := "# Application values\nimage:\n  repository: my-app  # pinned\n  tag: \"v1.0.0\"\n"
+
+	tmpFile := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// The value is already current, so the file is left untouched
+	modified, err := updater.UpdateYAMLPath(tmpFile, "image.tag", "v1.0.0", false, "")
+	if err != nil {
+		t.Fatalf("Failed to update YAML path: %v", err)
+	}
+	if modified {
+		t.Error("Expected an unchanged value to report no modification")
+	}
+
+	content, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != yamlContent {
+		t.Errorf("Expected the file not to be rewritten, got:\n%s", content)
+	}
+
+	// A new value is written and reported
+	modified, err = updater.UpdateYAMLPath(tmpFile, "image.tag", "v1.1.0", false, "")
+	if err != nil {
+		t.Fatalf("Failed to update YAML path: %v", err)
+	}
+	if !modified {
+		t.Error("Expected a new value to report a modification")
+	}
+}
+
 func TestUpdater_UpdateYAMLPath_ImageTagOnly(t *testing.T) {
 	updater := NewUpdater()
 
@@ -226,7 +437,7 @@ spec:
 	}
 
 	// Test updating only the image tag
-	err := updater.UpdateYAMLPath(tmpFile, "spec.template.spec.containers[0].image", "1.21", true)
+	_, err := updater.UpdateYAMLPath(tmpFile, "spec.template.spec.containers[0].image", "1.21", true, "")
 	if err != nil {
 		t.Fatalf("Failed to update YAML path: %v", err)
 	}
@@ -241,3 +452,588 @@ spec:
 		t.Errorf("Expected docker.io/nginx:1.21, got %v", value)
 	}
 }
+
+func TestUpdater_UpdateYAMLPath_Wildcard(t *testing.T) {
+	updater := NewUpdater()
+
+	yamlContent := `apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: 123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:v1.0.0
+      - name: worker
+        image: 123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:v1.0.0
+`
+
+	tmpFile := filepath.Join(t.TempDir(), "deployment.yaml")
+	if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	_, err := updater.UpdateYAMLPath(tmpFile, "spec.template.spec.containers[*].image", "v1.1.0", true, `:v1\.0\.0$`)
+	if err != nil {
+		t.Fatalf("Failed to update YAML path: %v", err)
+	}
+
+	value, err := updater.GetValueAtPath(tmpFile, "spec.template.spec.containers[*].image")
+	if err != nil {
+		t.Fatalf("Failed to get value at path: %v", err)
+	}
+
+	images, ok := value.([]interface{})
+	if !ok || len(images) != 2 {
+		t.Fatalf("Expected 2 images, got %v", value)
+	}
+	for i, image := range images {
+		if image != "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:v1.1.0" {
+			t.Errorf("Expected container %d image to be updated, got %v", i, image)
+		}
+	}
+
+	// The other container fields are untouched
+	name, err := updater.GetValueAtPath(tmpFile, "spec.template.spec.containers[1].name")
+	if err != nil {
+		t.Fatalf("Failed to get value at path: %v", err)
+	}
+	if name != "worker" {
+		t.Errorf("Expected worker, got %v", name)
+	}
+}
+
+func TestUpdater_UpdateYAMLPath_MapWildcard(t *testing.T) {
+	yamlContent := `services:
+  api:
+    image: registry.example.com/api:v1.0.0
+    replicas: 2
+  web:
+    image: registry.example.com/web:v1.0.0
+  worker:
+    image: registry.example.com/worker:v1.0.0
+`
+
+	tests := []struct {
+		name                 string
+		pathSyntax           string
+		yamlPath             string
+		expectedValuePattern string
+		expectError          bool
+	}{
+		{name: "dotted", yamlPath: "services.*.image"},
+		{name: "jsonpath", pathSyntax: PathSyntaxJSONPath, yamlPath: "$.services.*.image"},
+		{name: "expected value", yamlPath: "services.*.image", expectedValuePattern: `^registry\.example\.com/`},
+		{name: "unexpected value", yamlPath: "services.*.image", expectedValuePattern: `/(api|web):`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpFile := filepath.Join(t.TempDir(), "values.yaml")
+			if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			updater := NewUpdater(WithPathSyntax(tt.pathSyntax))
+			_, err := updater.UpdateYAMLPath(tmpFile, tt.yamlPath, "v1.1.0", true, tt.expectedValuePattern)
+			if tt.expectError {
+				if !errors.Is(err, ErrUnexpectedValue) {
+					t.Errorf("Expected ErrUnexpectedValue, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to update YAML path: %v", err)
+			}
+
+			value, err := updater.GetValueAtPath(tmpFile, tt.yamlPath)
+			if err != nil {
+				t.Fatalf("Failed to get value at path: %v", err)
+			}
+			expected := []interface{}{
+				"registry.example.com/api:v1.1.0",
+				"registry.example.com/web:v1.1.0",
+				"registry.example.com/worker:v1.1.0",
+			}
+			if !reflect.DeepEqual(value, expected) {
+				t.Errorf("Expected images %v, got %v", expected, value)
+			}
+
+			// The other service fields are untouched
+			replicas, err := NewUpdater().GetValueAtPath(tmpFile, "services.api.replicas")
+			if err != nil {
+				t.Fatalf("Failed to get value at path: %v", err)
+			}
+			if replicas != 2 {
+				t.Errorf("Expected 2 replicas, got %v", replicas)
+			}
+		})
+	}
+}
+
+func TestUpdater_UpdateYAMLPath_Anchors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		path     string
+		expected string
+	}{
+		{
+			name:     "through an alias",
+			content:  "defaults:\n  image: &img my-app:v1.0.0 # shared\napp:\n  image: *img\nworker:\n  image: *img\n",
+			path:     "app.image",
+			expected: "defaults:\n    image: &img my-app:v1.1.0 # shared\napp:\n    image: *img\nworker:\n    image: *img\n",
+		},
+		{
+			name:     "at the anchor",
+			content:  "defaults:\n  image: &img my-app:v1.0.0\napp:\n  image: *img\n",
+			path:     "defaults.image",
+			expected: "defaults:\n    image: &img my-app:v1.1.0\napp:\n    image: *img\n",
+		},
+		{
+			name:     "through an aliased mapping",
+			content:  "base: &base\n  image: my-app:v1.0.0\n  replicas: 2\napp: *base\n",
+			path:     "app.image",
+			expected: "base: &base\n    image: my-app:v1.1.0\n    replicas: 2\napp: *base\n",
+		},
+		{
+			name:     "merged key",
+			content:  "base: &base\n  image: my-app:v1.0.0\n  replicas: 2\napp:\n  <<: *base\n",
+			path:     "app.image",
+			expected: "base: &base\n    image: my-app:v1.0.0\n    replicas: 2\napp:\n    <<: *base\n    image: my-app:v1.1.0\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpFile := filepath.Join(t.TempDir(), "values.yaml")
+			if err := os.WriteFile(tmpFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			modified, err := NewUpdater().UpdateYAMLPath(tmpFile, tt.path, "v1.1.0", true, "")
+			if err != nil {
+				t.Fatalf("Failed to update YAML path: %v", err)
+			}
+			if !modified {
+				t.Error("Expected the update to report a modification")
+			}
+
+			content, err := os.ReadFile(tmpFile)
+			if err != nil {
+				t.Fatalf("Failed to read file: %v", err)
+			}
+			if string(content) != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, content)
+			}
+		})
+	}
+}
+
+func TestUpdater_UpdateYAMLPath_WildcardErrors(t *testing.T) {
+	updater := NewUpdater()
+
+	yamlContent := `spec:
+  containers: []
+  volumes: {}
+  template:
+    image: my-app:v1.0.0
+`
+
+	tests := []struct {
+		name     string
+		yamlPath string
+	}{
+		{
+			name:     "empty array",
+			yamlPath: "spec.containers[*].image",
+		},
+		{
+			name:     "not an array or map",
+			yamlPath: "spec.template.image[*]",
+		},
+		{
+			name:     "empty map",
+			yamlPath: "spec.volumes.*.image",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpFile := filepath.Join(t.TempDir(), "deployment.yaml")
+			if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			if _, err := updater.UpdateYAMLPath(tmpFile, tt.yamlPath, "v1.1.0", true, ""); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
+
+func TestUpdater_UpdateYAMLPath_ExistingPathsOnly(t *testing.T) {
+	yamlContent := `spec:
+  template:
+    image: my-app:v1.0.0
+`
+
+	tests := []struct {
+		name         string
+		yamlPath     string
+		existingOnly bool
+		wantNotFound bool
+		wantChanged  bool
+	}{
+		{
+			name:        "missing key is added by default",
+			yamlPath:    "spec.template.tag",
+			wantChanged: true,
+		},
+		{
+			name:         "missing key with existing paths only",
+			yamlPath:     "spec.template.tag",
+			existingOnly: true,
+			wantNotFound: true,
+		},
+		{
+			name:         "missing intermediate key",
+			yamlPath:     "spec.missing.tag",
+			wantNotFound: true,
+		},
+		{
+			name:         "existing key with existing paths only",
+			yamlPath:     "spec.template.image",
+			existingOnly: true,
+			wantChanged:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpFile := filepath.Join(t.TempDir(), "deployment.yaml")
+			if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			updater := NewUpdater(WithExistingPathsOnly(tt.existingOnly))
+			changed, err := updater.UpdateYAMLPath(tmpFile, tt.yamlPath, "my-app:v1.1.0", false, "")
+			if tt.wantNotFound {
+				if !errors.Is(err, ErrPathNotFound) {
+					t.Fatalf("Expected ErrPathNotFound, got %v", err)
+				}
+				content, _ := os.ReadFile(tmpFile)
+				if string(content) != yamlContent {
+					t.Errorf("File was modified:\n%s", content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateYAMLPath() error = %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestUpdater_UpdateYAMLPath_ExpectedValuePattern(t *testing.T) {
+	updater := NewUpdater()
+
+	yamlContent := `image:
+    repository: my-app
+    tag: v1.0.0
+replicas: 3
+`
+
+	tests := []struct {
+		name                 string
+		yamlPath             string
+		expectedValuePattern string
+		unexpectedValue      bool
+		shouldErr            bool
+	}{
+		{
+			name:                 "match",
+			yamlPath:             "image.tag",
+			expectedValuePattern: `^v\d+\.\d+\.\d+$`,
+		},
+		{
+			name:                 "mismatch",
+			yamlPath:             "replicas",
+			expectedValuePattern: `^v\d+\.\d+\.\d+$`,
+			unexpectedValue:      true,
+			shouldErr:            true,
+		},
+		{
+			name:                 "not a scalar",
+			yamlPath:             "image",
+			expectedValuePattern: ".*",
+			unexpectedValue:      true,
+			shouldErr:            true,
+		},
+		{
+			name:                 "missing path",
+			yamlPath:             "image.version",
+			expectedValuePattern: `^v`,
+			unexpectedValue:      true,
+			shouldErr:            true,
+		},
+		{
+			name:                 "invalid pattern",
+			yamlPath:             "image.tag",
+			expectedValuePattern: "(",
+			shouldErr:            true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpFile := filepath.Join(t.TempDir(), "values.yaml")
+			if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			_, err := updater.UpdateYAMLPath(tmpFile, tt.yamlPath, "v1.1.0", false, tt.expectedValuePattern)
+			if !tt.shouldErr {
+				if err != nil {
+					t.Fatalf("Expected no error but got: %v", err)
+				}
+				value, err := updater.GetValueAtPath(tmpFile, tt.yamlPath)
+				if err != nil {
+					t.Fatalf("Failed to get value at path: %v", err)
+				}
+				if value != "v1.1.0" {
+					t.Errorf("Expected v1.1.0, got %v", value)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			if errors.Is(err, ErrUnexpectedValue) != tt.unexpectedValue {
+				t.Errorf("Expected ErrUnexpectedValue to be %v, got %v", tt.unexpectedValue, err)
+			}
+
+			// The file is left unchanged
+			content, err := os.ReadFile(tmpFile)
+			if err != nil {
+				t.Fatalf("Failed to read test file: %v", err)
+			}
+			if string(content) != yamlContent {
+				t.Errorf("Expected file to be unchanged, got:\n%s", content)
+			}
+		})
+	}
+}
+
+func TestUpdater_UpdateNestedYAMLPath_ExpectedValuePattern(t *testing.T) {
+	updater := NewUpdater()
+
+	yamlContent := `data:
+  config.yaml: |
+    app:
+      image: docker.io/my-app:1.0.0
+`
+
+	tmpFile := filepath.Join(t.TempDir(), "configmap.yaml")
+	if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	_, err := updater.UpdateNestedYAMLPath(tmpFile, `data["config.yaml"]`, "app.image", "1.1.0", true, `^docker\.io/other:`)
+	if !errors.Is(err, ErrUnexpectedValue) {
+		t.Fatalf("Expected ErrUnexpectedValue, got %v", err)
+	}
+
+	if _, err := updater.UpdateNestedYAMLPath(tmpFile, `data["config.yaml"]`, "app.image", "1.1.0", true, `^docker\.io/my-app:`); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+}
+
+func TestUpdater_UpdateNestedYAMLPath(t *testing.T) {
+	updater := NewUpdater()
+
+	// Create a ConfigMap with an embedded YAML document
+	yamlContent := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  config.yaml: |
+    app:
+      image: docker.io/my-app:1.0.0
+      replicas: 2
+`
+
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "configmap.yaml")
+	if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// Test updating the image tag inside the embedded document
+	_, err := updater.UpdateNestedYAMLPath(tmpFile, `data["config.yaml"]`, "app.image", "1.1.0", true, "")
+	if err != nil {
+		t.Fatalf("Failed to update nested YAML path: %v", err)
+	}
+
+	// Verify the embedded document was updated
+	value, err := updater.GetValueAtPath(tmpFile, `data["config.yaml"]`)
+	if err != nil {
+		t.Fatalf("Failed to get value at path: %v", err)
+	}
+
+	expected := "app:\n    image: docker.io/my-app:1.1.0\n    replicas: 2\n"
+	if value != expected {
+		t.Errorf("Expected %q, got %q", expected, value)
+	}
+
+	// Verify the scalar is still written in block style
+	content, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	if !strings.Contains(string(content), "config.yaml: |") {
+		t.Errorf("Expected embedded document in literal block style, got:\n%s", content)
+	}
+}
+
+func TestUpdater_UpdateNestedYAMLPath_NotString(t *testing.T) {
+	updater := NewUpdater()
+
+	yamlContent := `data:
+  replicas: 2
+`
+
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "configmap.yaml")
+	if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if _, err := updater.UpdateNestedYAMLPath(tmpFile, "data.replicas", "app.image", "1.1.0", false, ""); err == nil {
+		t.Error("Expected error for non-string embedded value, got none")
+	}
+}
+
+func TestUpdater_writeVerified(t *testing.T) {
+	updater := NewUpdater()
+	original := []byte("image: nginx:1.20\n")
+
+	tests := []struct {
+		name        string
+		updated     string
+		expectedErr bool
+	}{
+		{
+			name:    "valid update",
+			updated: "image: nginx:1.21\n",
+		},
+		{
+			name:        "invalid update",
+			updated:     "image: [nginx:1.21\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpFile := filepath.Join(t.TempDir(), "test.yaml")
+			if err := os.WriteFile(tmpFile, original, 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			err := updater.writeVerified(tmpFile, original, []byte(tt.updated))
+
+			content, readErr := os.ReadFile(tmpFile)
+			if readErr != nil {
+				t.Fatalf("Failed to read test file: %v", readErr)
+			}
+
+			if tt.expectedErr {
+				if !errors.Is(err, ErrUpdateProducedInvalidYAML) {
+					t.Errorf("Expected ErrUpdateProducedInvalidYAML, got %v", err)
+				}
+				if string(content) != string(original) {
+					t.Errorf("Expected original content to be restored, got %q", content)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if string(content) != tt.updated {
+				t.Errorf("Expected updated content %q, got %q", tt.updated, content)
+			}
+		})
+	}
+}
+
+func TestUpdater_FileMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		update  func(u *Updater, filePath string) (bool, error)
+	}{
+		{
+			name:    "YAML",
+			file:    "values.yaml",
+			content: "image:\n  tag: v1.0.0\n",
+			update: func(u *Updater, filePath string) (bool, error) {
+				return u.UpdateYAMLPath(filePath, "image.tag", "v1.1.0", false, "")
+			},
+		},
+		{
+			name:    "JSON",
+			file:    "values.json",
+			content: "{\"image\": {\"tag\": \"v1.0.0\"}}\n",
+			update: func(u *Updater, filePath string) (bool, error) {
+				return u.UpdateJSONPath(filePath, "image.tag", "v1.1.0", false, "")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpFile := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(tmpFile, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			modified, err := tt.update(NewUpdater(WithFileMode(0644)), tmpFile)
+			if err != nil || !modified {
+				t.Fatalf("Expected the file to be updated, got modified %v (error: %v)", modified, err)
+			}
+
+			// The stricter mode of the existing file is kept
+			info, err := os.Stat(tmpFile)
+			if err != nil {
+				t.Fatalf("Failed to stat file: %v", err)
+			}
+			if mode := info.Mode().Perm(); mode != 0600 {
+				t.Errorf("Expected mode 0600, got %#o", mode)
+			}
+		})
+	}
+}
+
+func TestUpdater_writeFile_NewFile(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "values.yaml")
+
+	if err := NewUpdater(WithFileMode(0640)).writeFile(tmpFile, []byte("image: nginx\n")); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	info, err := os.Stat(tmpFile)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0640 {
+		t.Errorf("Expected mode 0640, got %#o", mode)
+	}
+}